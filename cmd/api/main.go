@@ -9,17 +9,84 @@
 //
 // Environment variables:
 //
+//	CONFIG_FILE       - YAML or JSON file of settings, overridden by these variables (optional)
 //	PORT              - Server port (default: 8080)
 //	ENV               - Environment: development, staging, production (default: development)
 //	DATABASE_URL      - PostgreSQL connection string
 //	REDIS_URL         - Redis connection string
 //	HIVE_API_KEY      - Hive AI API key for detection
+//	SIGHTENGINE_API_KEY - Sightengine credential as api_user:api_secret (images)
+//	AIORNOT_API_KEY   - AIorNot API key (images)
+//	ANTHROPIC_API_KEY - Anthropic API key for Claude vision (images)
+//	ORIGINALITY_API_KEY - Originality.ai API key (text)
+//	WINSTON_API_KEY   - Winston AI API key (text)
+//	*_FILE            - Read a credential (API keys, DATABASE_URL, REDIS_URL, WEBHOOK_SECRET, ADMIN_TOKEN, CERTIFICATE_KEY) from a file, e.g. HIVE_API_KEY_FILE
+//	PROVIDER_WEIGHTS  - Detector weight overrides, e.g. "winston=1.3,openai=0.5"
+//	DETECTOR_TIMEOUT  - Time each call to a detection provider may take (default: 30s, 0 = no limit)
+//	PROVIDER_URLS     - Provider API base URL overrides, e.g. "winston=https://gateway.internal/winston" (optional)
+//	OUTBOUND_PROXY    - Proxy for provider calls (default: HTTPS_PROXY, HTTP_PROXY and NO_PROXY)
+//	OUTBOUND_CA_FILE  - PEM bundle of CAs trusted for outbound TLS besides the system's (optional)
+//	OUTBOUND_INSECURE_SKIP_VERIFY - Accept any TLS certificate on outbound calls (default: false, not in production)
+//	OUTBOUND_MAX_IDLE_CONNS - Idle provider connections kept (default: 100)
+//	OUTBOUND_MAX_IDLE_CONNS_PER_HOST - Idle connections kept per provider (default: 10)
+//	OUTBOUND_MAX_CONNS_PER_HOST - Connections per provider (default: 0 = no limit)
 //	LOG_LEVEL         - Logging level: debug, info, warn, error (default: info)
+//	LOG_FORMAT        - Log format: json or text (default: json in production, text otherwise)
+//	LOG_TIME_FORMAT   - Log record times: rfc3339 or rfc3339nano (default: RFC 3339 with milliseconds)
+//	LOG_REDACT_KEYS   - Log attribute keys masked besides the built-in ones (optional)
+//	LOG_MAX_VALUE_LENGTH - Longer string values in logs are truncated (default: 8192, 0 = no limit)
+//	LOG_LEVELS        - Per-component levels, e.g. "video=debug,http=warn" (optional)
+//	LOG_SAMPLE_BURST  - Identical log records written each second before sampling (default: 0 = no sampling)
+//	LOG_SAMPLE_RATE   - 1 in N identical records kept past the burst (default: 100)
 //	MAX_UPLOAD_SIZE   - Maximum upload size in bytes (default: 104857600 = 100MB)
+//	MAX_UPLOAD_FILES  - Files one multipart upload may hold (default: 10)
+//	UPLOAD_MEMORY_LIMIT - Largest upload kept in memory, bigger ones go to a temp file (default: 8388608 = 8MB)
+//	API_KEY_REQUIRED  - Reject requests without an API key (default: false, always true in production)
+//	ACCESS_DENY_IPS   - Client IPs or CIDR ranges refused with 403 (optional)
+//	ACCESS_ALLOW_IPS  - Client IPs or CIDR ranges exempt from rate limiting (optional)
+//	ACCESS_DENY_KEYS  - API key IDs refused with 403 (optional)
+//	ACCESS_LIST_FILE  - JSON file of more access lists, re-read on SIGHUP (optional)
+//	TRUSTED_PROXIES   - Proxy IPs or CIDR ranges whose X-Forwarded-For is believed (optional)
+//	AI_PHRASES_FILE   - JSON AI phrase dictionary replacing the built-in one, re-read on SIGHUP (optional)
+//	AUDIT_LOG_FILE    - File every verdict is appended to as a JSON line, reopened on SIGHUP (optional)
+//	ADMIN_TOKEN       - Bearer token for the /admin/keys endpoints (optional - at least 32 characters)
+//	RATE_LIMIT_PER_MINUTE - Requests per minute per client IP or API key (default: 60)
+//	RATE_LIMIT_BURST  - Requests a client can make at once (default: 0 = RATE_LIMIT_PER_MINUTE)
+//	RATE_LIMIT_MAX_CLIENTS - Clients the rate limiter tracks at once (default: 100000)
+//	RATE_LIMITS       - Per-route limits, e.g. "POST /verify/batch=10,POST /verify=60" (optional)
+//	REQUEST_TIMEOUT   - Time a request may take before a 504 (default: 30s, 0 = no limit)
+//	REQUEST_TIMEOUT_BUDGETS - Longer timeouts per content type (default: "video=2m,audio=1m")
+//	MAX_CONCURRENT_REQUESTS - Verifications analyzed at once (default: 64, 0 = no limit)
+//	MAX_CONCURRENT_WAIT - Wait for a free slot before a 503 (default: 2s)
+//	MAX_CONCURRENT_POOLS - Per-content-type caps (default: "video=4,audio=8")
+//	USAGE_MONTHLY_QUOTA - Verifications per API key per UTC month (default: 0 = unlimited)
+//	RETENTION_DAYS    - Days verification results are kept (default: 0 = forever)
+//	ALLOW_PRIVATE_URLS - Allow URL inputs to reach private networks (default: false)
+//	MAX_REDIRECTS     - Maximum redirects followed for URL inputs (default: 5)
+//	ASYNC_WORKERS     - Background workers for async verification (default: 4)
+//	ASYNC_QUEUE_SIZE  - Async jobs that may wait for a worker (default: 100)
+//	ASYNC_THRESHOLD_BYTES - Requests larger than this run async (default: 52428800 = 50MB)
+//	WEBHOOK_SECRET    - HMAC secret for job-completion callbacks (optional - enables callback_url)
+//	CERTIFICATE_KEY   - Base64 Ed25519 key signing result certificates (optional - generated at startup)
+//	SHUTDOWN_TIMEOUT  - Time shutdown waits for in-flight requests and async jobs (default: 30s)
+//	CERTIFICATE_OLD_KEYS - Base64 public keys of retired certificate keys, still published (optional)
+//	AGGREGATION_STRATEGY - How detector scores combine: weighted-mean, max, median, veto (default: weighted-mean)
+//	LOCAL_ONLY_CONFIDENCE_CAP - Highest confidence when only the local analyzer ran (default: 0.6, 0 = no cap)
+//	PANIC_LOG_BODY_BYTES - Request body bytes logged with a panic (default: 0 = none)
+//	VALIDATE_RESPONSE_SCHEMA - Check /verify responses against their schema (default: false, not in production)
+//	ALLOWED_ORIGINS   - CORS origins, e.g. "https://app.example.com,https://*.example.com" (default: * outside production)
+//	CORS_ALLOWED_METHODS - Methods browsers may use (default: GET,POST,DELETE,OPTIONS)
+//	CORS_ALLOWED_HEADERS - Request headers browsers may send (default: Content-Type,Authorization,X-API-Key,X-Request-ID)
+//	CORS_EXPOSED_HEADERS - Response headers browser scripts may read (default: X-Request-ID, Retry-After and the rate limit headers)
+//	CORS_ALLOW_CREDENTIALS - Allow cookies and HTTP auth cross-origin (default: false, needs explicit origins)
+//	CONTENT_SECURITY_POLICY - Content-Security-Policy header (default: loads nothing, forbids framing)
+//	REFERRER_POLICY   - Referrer-Policy header (default: no-referrer)
+//	OTEL_EXPORTER_OTLP_ENDPOINT - Where to send traces (optional - only in builds with -tags otel)
 package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"net/http"
@@ -28,12 +95,19 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/humanmark/humanmark/internal/audit"
 	"github.com/humanmark/humanmark/internal/config"
 	"github.com/humanmark/humanmark/internal/handler"
 	"github.com/humanmark/humanmark/internal/middleware"
 	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/server"
 	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/internal/webhook"
+	"github.com/humanmark/humanmark/internal/worker"
+	"github.com/humanmark/humanmark/pkg/cert"
 	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/tracing"
 )
 
 // Version information - set at build time via ldflags
@@ -67,6 +141,32 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Now the logging settings are known, log with them
+	logOptions := logger.Options{
+		RedactKeys:     cfg.LogRedactKeys,
+		MaxValueLength: cfg.LogMaxValueLength,
+		Levels:         cfg.LogLevels,
+	}
+	switch {
+	case cfg.LogFormat != "":
+		logOptions.Format = logger.Format(cfg.LogFormat)
+	case cfg.IsProduction():
+		logOptions.Format = logger.FormatJSON
+	default:
+		logOptions.Format = logger.FormatText
+	}
+	switch cfg.LogTimeFormat {
+	case "rfc3339":
+		logOptions.TimeFormat = time.RFC3339
+	case "rfc3339nano":
+		logOptions.TimeFormat = time.RFC3339Nano
+	}
+	if cfg.LogSampleBurst > 0 {
+		logOptions.Sampling = &logger.Sampling{Burst: cfg.LogSampleBurst, Thereafter: cfg.LogSampleRate}
+	}
+	log = logger.NewWithOptions(cfg.LogLevel, os.Stdout, logOptions)
+	log.Info("configuration loaded", "config", cfg)
+
 	// Initialize dependencies
 	// We use dependency injection for testability and flexibility
 	app, err := initializeApp(cfg, log)
@@ -74,23 +174,39 @@ func main() {
 		log.Error("failed to initialize application", "error", err)
 		os.Exit(1)
 	}
-	defer app.Cleanup()
 
-	// Build the HTTP server with all middleware and routes
-	server := buildServer(cfg, app, log)
+	// Build the HTTP server with all middleware and routes; the server
+	// owns the background components so it can stop them in order
+	srv := server.New(server.Config{
+		HTTP:        buildServer(cfg, app, log),
+		Worker:      app.Worker,
+		Sweeper:     app.Sweeper,
+		RateLimiter: app.RateLimiter,
+		AuditLog:    app.AuditLog,
+		Repository:  app.Repository,
+		FlushTraces: app.shutdownTracer,
+		Logger:      log,
+	})
 
-	// Start server in a goroutine so we can handle graceful shutdown
-	go func() {
-		log.Info("server listening", "port", cfg.Port, "env", cfg.Environment)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Error("server error", "error", err)
-			os.Exit(1)
-		}
-	}()
+	// Register for shutdown signals before serving, so none are missed
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	if err := srv.Start(); err != nil {
+		log.Error("failed to start server", "error", err)
+		srv.Shutdown(context.Background())
+		os.Exit(1)
+	}
+	log.Info("server listening", "port", cfg.Port, "env", cfg.Environment)
+
+	// Re-read the access list and detection config files on SIGHUP
+	go reloadOnHangup(app)
 
 	// Wait for interrupt signal for graceful shutdown
-	// This allows in-flight requests to complete before shutting down
-	gracefulShutdown(server, log, 30*time.Second)
+	// This allows in-flight requests and async jobs to complete first
+	if err := gracefulShutdown(srv, quit, log, cfg.ShutdownTimeout); err != nil {
+		os.Exit(1)
+	}
 }
 
 // App holds all application dependencies.
@@ -100,60 +216,202 @@ type App struct {
 	Logger     *logger.Logger
 	Repository repository.Repository
 	Detector   service.Detector
+	Worker     *worker.Pool
+	Sweeper    *worker.Sweeper
 	Handler    *handler.Handler
-}
+	Metrics    *metrics.PromRegistry
+	Tracer     tracing.Tracer
 
-// Cleanup releases all resources held by the application.
-// Always defer this after creating an App instance.
-func (a *App) Cleanup() {
-	a.Logger.Info("cleaning up resources")
-	
-	if a.Repository != nil {
-		if err := a.Repository.Close(); err != nil {
-			a.Logger.Error("error closing repository", "error", err)
-		}
-	}
+	// RateLimiter holds the rate limit buckets; the server stops its
+	// sweeper on shutdown
+	RateLimiter *middleware.RateLimiter
+
+	// AccessControl holds the IP and API key access lists
+	AccessControl *middleware.AccessControl
+
+	// ReloadDetector re-reads the detection config files and swaps them
+	// into Detector
+	ReloadDetector func() error
+
+	// AuditLog records verdicts (nil without AUDIT_LOG_FILE)
+	AuditLog *audit.JSONLogger
+
+	// shutdownTracer flushes buffered spans (nil when there are none)
+	shutdownTracer func(context.Context) error
 }
 
 // initializeApp creates and wires all application dependencies.
 // This is the composition root where we decide which implementations to use.
 func initializeApp(cfg *config.Config, log *logger.Logger) (*App, error) {
 	// Initialize repository (database layer)
-	// For now, we use an in-memory repository for simplicity
-	// In production, swap this with PostgreSQL implementation
-	repo, err := repository.NewPostgres(cfg.DatabaseURL)
+	// PostgreSQL when DATABASE_URL is set; an unreachable database or failed
+	// migration stops startup instead of silently dropping results
+	var repo repository.Repository
+	var err error
+	if cfg.DatabaseURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		repo, err = repository.NewPostgres(ctx, cfg.DatabaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		log.Info("using PostgreSQL storage")
+	} else {
+		log.Warn("database not configured, using in-memory storage",
+			"max_jobs", cfg.MemoryMaxJobs,
+			"max_age", cfg.MemoryMaxAge.String(),
+		)
+		repo = repository.NewMemoryWithOptions(repository.MemoryOptions{
+			MaxJobs: cfg.MemoryMaxJobs,
+			MaxAge:  cfg.MemoryMaxAge,
+		})
+	}
+
+	// Metrics are collected in-process and served on /metrics
+	reg := metrics.NewRegistry()
+
+	// Traces go to an OpenTelemetry collector, in builds that include it
+	tracer, shutdownTracer, err := newTracer(context.Background())
 	if err != nil {
-		// Fall back to in-memory if no database configured
-		log.Warn("database not configured, using in-memory storage")
-		repo = repository.NewMemory()
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
 	}
 
 	// Initialize detection service
-	// This orchestrates multiple detection backends
-	detector, err := service.NewDetector(service.DetectorConfig{
-		HiveAPIKey:    cfg.HiveAPIKey,
-		OpenAIAPIKey:  cfg.OpenAIAPIKey,
-		GPTZeroAPIKey: cfg.GPTZeroAPIKey,
-		Timeout:       30 * time.Second,
-	}, log)
+	// This orchestrates multiple detection backends. The config files are
+	// loaded here so a bad file fails startup.
+	// Provider calls share one client, set up for the egress network
+	httpClient, err := service.NewOutboundClient(service.OutboundConfig{
+		ProxyURL:            cfg.OutboundProxy,
+		CAFile:              cfg.OutboundCAFile,
+		InsecureSkipVerify:  cfg.OutboundInsecureSkipVerify,
+		MaxIdleConns:        cfg.OutboundMaxIdleConns,
+		MaxIdleConnsPerHost: cfg.OutboundMaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.OutboundMaxConnsPerHost,
+	}, cfg.DetectorTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure outbound HTTP: %w", err)
+	}
+	detectorConfig, err := loadDetectorConfig(cfg, httpClient, reg)
+	if err != nil {
+		return nil, err
+	}
+	detector, err := service.NewDetector(detectorConfig, log)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create detector: %w", err)
 	}
+	reload := func() error {
+		return reloadDetector(cfg, httpClient, detector, reg, log)
+	}
+	profiles := analyzerProfiles(cfg)
 
 	// Initialize HTTP handler
+	// Webhooks are only available with a signing secret
+	var notifier worker.Notifier
+	if cfg.WebhookSecret != "" {
+		notifier = webhook.New(webhook.Config{
+			Secret: cfg.WebhookSecret,
+			Policy: service.FetchPolicy{
+				AllowPrivateNetworks: cfg.AllowPrivateURLs,
+				MaxRedirects:         cfg.MaxRedirects,
+			},
+			Logger: log,
+		})
+	}
+
+	// Result certificates and their published keys
+	certificates, err := certificateSigner(cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate keys: %w", err)
+	}
+
+	// Initialize background workers for async verification (started with
+	// the server)
+	// Verdicts are recorded for compliance when AUDIT_LOG_FILE is set
+	var auditFile *audit.JSONLogger
+	var auditLog audit.Logger
+	if cfg.AuditLogFile != "" {
+		auditFile, err = audit.OpenFile(cfg.AuditLogFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		auditLog = auditFile
+	}
+
+	pool := worker.New(worker.Config{
+		Detector:   detector,
+		Repository: repo,
+		Logger:     log,
+		Notifier:   notifier,
+		AuditLog:   auditLog,
+		Workers:    cfg.AsyncWorkers,
+		QueueSize:  cfg.AsyncQueueSize,
+	})
+
+	// Delete results past the retention period, and purge those deleted
+	// through the API, in the background
+	sweeper := worker.NewSweeper(worker.SweeperConfig{
+		Repository: repo,
+		Logger:     log,
+		Retention:  time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+	})
+
 	h := handler.New(handler.Config{
-		Detector:      detector,
-		Repository:    repo,
-		Logger:        log,
-		MaxUploadSize: cfg.MaxUploadSize,
+		Detector:          detector,
+		Repository:        repo,
+		Logger:            log,
+		MaxUploadSize:     cfg.MaxUploadSize,
+		MaxFiles:          cfg.MaxUploadFiles,
+		UploadMemoryLimit: cfg.UploadMemoryLimit,
+		Queue:             pool,
+		AsyncThreshold:    cfg.AsyncThresholdBytes,
+
+		CallbacksEnabled:      notifier != nil,
+		RequireHTTPSCallbacks: cfg.IsProduction(),
+		ProvidersConfigured:   cfg.HasDetectionAPIKey(),
+		Metrics:               reg,
+		PageDetector:          service.NewPageDetector(detector, detectorConfig, log),
+		MonthlyQuota:          cfg.UsageMonthlyQuota,
+		Certificates:          certificates,
+		Reload:                reload,
+		AuditLog:              auditLog,
+		Profiles:              profiles,
 	})
 
+	// Rate limit buckets live as long as the app; rules are most specific
+	// first, whatever the map order
+	var rateLimits []middleware.RateLimitRule
+	for pattern, limit := range cfg.RateLimits {
+		rateLimits = append(rateLimits, middleware.RateLimitRule{Pattern: pattern, Limit: limit})
+	}
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimitPerMinute, cfg.RateLimitBurst, rateLimits, cfg.RateLimitMaxClients)
+
+	accessList, err := loadAccessList(cfg)
+	if err != nil {
+		rateLimiter.Close()
+		return nil, fmt.Errorf("failed to load access list: %w", err)
+	}
+	accessControl, err := middleware.NewAccessControl(accessList)
+	if err != nil {
+		rateLimiter.Close()
+		return nil, fmt.Errorf("invalid access list: %w", err)
+	}
+
 	return &App{
 		Config:     cfg,
 		Logger:     log,
 		Repository: repo,
 		Detector:   detector,
+		Worker:     pool,
+		Sweeper:    sweeper,
 		Handler:    h,
+		Metrics:    reg,
+		Tracer:     tracer,
+
+		RateLimiter:    rateLimiter,
+		AccessControl:  accessControl,
+		ReloadDetector: reload,
+		AuditLog:       auditFile,
+		shutdownTracer: shutdownTracer,
 	}, nil
 }
 
@@ -166,20 +424,118 @@ func buildServer(cfg *config.Config, app *App, log *logger.Logger) *http.Server
 	// Register routes
 	// Health check - no auth required
 	mux.HandleFunc("GET /health", app.Handler.Health)
+	mux.HandleFunc("GET /health/live", app.Handler.Live)
+	mux.HandleFunc("GET /health/ready", app.Handler.Ready)
+	mux.HandleFunc("GET /metrics", app.Handler.Metrics)
 	mux.HandleFunc("GET /", app.Handler.Index)
 
+	// Public keys for checking result certificates - no auth required
+	mux.HandleFunc("GET /.well-known/humanmark.json", app.Handler.WellKnown)
+
+	// API description - no auth required; the Swagger UI page only in
+	// development
+	mux.HandleFunc("GET /openapi.json", app.Handler.OpenAPI)
+	if cfg.IsDevelopment() {
+		mux.HandleFunc("GET /docs", app.Handler.Docs)
+	}
+
 	// Main API endpoint - the core of HumanMark
 	// POST /verify - accepts URL or file upload, returns human/non-human verdict
-	mux.HandleFunc("POST /verify", app.Handler.Verify)
+	// In development/staging, catch malformed responses before clients do
+	var verify http.Handler = http.HandlerFunc(app.Handler.Verify)
+	if cfg.ValidateResponseSchema {
+		verify = middleware.ValidateResponse(handler.VerifyResponseSchema(), log)(verify)
+	}
+	// Cap concurrent analyses so simultaneous large uploads can't exhaust
+	// memory
+	verify = middleware.MaxConcurrent(cfg.MaxConcurrent, cfg.MaxConcurrentWait, cfg.MaxConcurrentPools, app.Metrics)(verify)
+	mux.Handle("POST /verify", verify)
 
 	// Async job status (for large files)
 	mux.HandleFunc("GET /verify/{id}", app.Handler.GetResult)
 
+	// Lookups by content hash, never running detection. GET
+	// /verify/hash/{sha256} would conflict with the /verify/{id}/ routes,
+	// so the handler checks the "hash" segment itself.
+	mux.HandleFunc("GET /verify/{kind}/{sha256}", app.Handler.GetResultByHash)
+
+	// Deletion on request (e.g. GDPR erasure)
+	mux.HandleFunc("DELETE /verify/{id}", app.Handler.DeleteResult)
+
+	// Result reports for compliance records
+	mux.HandleFunc("GET /verify/{id}/report.json", app.Handler.GetReport)
+	mux.HandleFunc("GET /verify/{id}/report.csv", app.Handler.GetReport)
+	mux.HandleFunc("GET /verify/{id}/report.html", app.Handler.GetReport)
+
+	// Signed certificates and badges publishers can show third parties
+	mux.HandleFunc("GET /verify/{id}/certificate", app.Handler.GetCertificate)
+
+	// Stored results, newest first, with cursor pagination and filters
+	mux.HandleFunc("GET /verify", app.Handler.ListResults)
+
+	// Verification history as CSV or JSONL, streamed (see Bypass below)
+	mux.HandleFunc("GET /verify/export", app.Handler.ExportResults)
+
+	// Blockchain proof of a result's content hash. Only detectors built
+	// with an Anchor (see service/anchor.go) anchor, so here it's always 404
+	mux.HandleFunc("GET /jobs/{id}/proof", app.Handler.GetProof)
+
+	// The calling API key's usage this month
+	mux.HandleFunc("GET /usage", app.Handler.Usage)
+
+	// API key management - bootstrap admin token required (404 when unset)
+	admin := middleware.RequireAdminToken(cfg.AdminToken)
+	mux.Handle("POST /admin/keys", admin(http.HandlerFunc(app.Handler.CreateAPIKey)))
+	mux.Handle("GET /admin/keys", admin(http.HandlerFunc(app.Handler.ListAPIKeys)))
+	mux.Handle("DELETE /admin/keys/{id}", admin(http.HandlerFunc(app.Handler.RevokeAPIKey)))
+
+	// Aggregate statistics for an operator dashboard
+	mux.Handle("GET /admin/stats", admin(http.HandlerFunc(app.Handler.GetStats)))
+
+	// Re-read the detection config files, as SIGHUP does
+	mux.Handle("POST /admin/reload", admin(http.HandlerFunc(app.Handler.ReloadConfig)))
+
 	// Apply middleware stack (order matters - first is outermost)
 	var handler http.Handler = mux
 
-	// Recovery middleware - catch panics, return 500 instead of crashing
-	handler = middleware.Recovery(log)(handler)
+	// Response size middleware - truncate oversized bodies (innermost, so
+	// it sees what the handlers wrote)
+	handler = middleware.MaxResponseSize(cfg.MaxResponseSize)(handler)
+
+	// Timeout middleware - cancel requests that run too long, e.g. on a
+	// hung provider, with longer budgets for slow content types
+	handler = middleware.Timeout(cfg.RequestTimeout, cfg.RequestTimeoutBudgets, app.Metrics)(handler)
+
+	// Exports stream for as long as they take, so they skip the two above,
+	// which hold the response until the handler returns
+	handler = middleware.Bypass(mux, mux, "GET /verify/export")(handler)
+
+	// Rate limiting middleware - prevent abuse, per API key or client IP,
+	// with tighter limits on expensive routes
+	handler = app.RateLimiter.Middleware(app.Metrics)(handler)
+
+	// Access control middleware - refuse denylisted IPs and API keys before
+	// they use up rate limit tokens, and let allowlisted IPs skip the limit
+	handler = app.AccessControl.Middleware(app.Metrics)(handler)
+
+	// Auth middleware - identify the API key (required when API_KEY_REQUIRED);
+	// runs before access control and rate limiting so keys can be denied
+	// and get their own limits
+	if cfg.APIKeyRequired && cfg.AdminToken == "" {
+		log.Warn("API keys are required but ADMIN_TOKEN is unset, so none can be issued")
+	}
+	handler = middleware.Auth(app.Repository, cfg.APIKeyRequired, log)(handler)
+
+	// Recovery middleware - catch panics, return 500 instead of crashing,
+	// and log where they happened
+	handler = middleware.RecoveryWithConfig(log, middleware.RecoveryConfig{
+		BodyCaptureBytes: cfg.PanicBodyBytes,
+		Metrics:          app.Metrics,
+	})(handler)
+
+	// Compression middleware - gzip large responses (detailed results run
+	// to hundreds of KB) for clients that accept it
+	handler = middleware.Compression()(handler)
 
 	// Request ID middleware - add unique ID to each request for tracing
 	handler = middleware.RequestID()(handler)
@@ -187,11 +543,34 @@ func buildServer(cfg *config.Config, app *App, log *logger.Logger) *http.Server
 	// Logging middleware - log all requests with timing
 	handler = middleware.Logging(log)(handler)
 
-	// CORS middleware - allow cross-origin requests for browser clients
-	handler = middleware.CORS(cfg.AllowedOrigins)(handler)
+	// Security headers middleware - nosniff, no framing, CSP and
+	// Referrer-Policy on every response, errors included
+	handler = middleware.SecurityHeaders(cfg.ContentSecurityPolicy, cfg.ReferrerPolicy)(handler)
 
-	// Rate limiting middleware - prevent abuse
-	handler = middleware.RateLimit(cfg.RateLimitPerMinute)(handler)
+	// CORS middleware - allow cross-origin requests for browser clients
+	handler = middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+	})(handler)
+
+	// Real IP middleware - work out the client IP, believing forwarding
+	// headers only from trusted proxies, before anything identifies clients
+	handler = middleware.RealIP(cfg.TrustedProxies)(handler)
+
+	// Tracing middleware - a span per request, joining the caller's trace
+	// (outermost, so the span covers everything)
+	handler = middleware.Tracing(app.Tracer, mux)(handler)
+
+	// The write timeout has to outlast the longest request timeout, or the
+	// connection closes before the response (or the 504) is written
+	writeTimeout := 60 * time.Second
+	for _, d := range cfg.RequestTimeoutBudgets {
+		writeTimeout = max(writeTimeout, d+10*time.Second)
+	}
+	writeTimeout = max(writeTimeout, cfg.RequestTimeout+10*time.Second)
 
 	// Create server with sensible timeouts
 	// These timeouts prevent slow clients from holding connections forever
@@ -200,24 +579,217 @@ func buildServer(cfg *config.Config, app *App, log *logger.Logger) *http.Server
 		Handler:           handler,
 		ReadTimeout:       15 * time.Second,            // Max time to read request
 		ReadHeaderTimeout: 5 * time.Second,             // Max time to read headers
-		WriteTimeout:      60 * time.Second,            // Max time to write response (longer for file uploads)
+		WriteTimeout:      writeTimeout,                // Max time to write response (longer for file uploads)
 		IdleTimeout:       120 * time.Second,           // Max time for keep-alive connections
 		MaxHeaderBytes:    1 << 20,                     // 1 MB max header size
 	}
 }
 
-// gracefulShutdown waits for interrupt signal and gracefully shuts down the server.
-// It gives in-flight requests time to complete before forcing shutdown.
-func gracefulShutdown(server *http.Server, log *logger.Logger, timeout time.Duration) {
-	// Create channel to receive OS signals
-	quit := make(chan os.Signal, 1)
-	
-	// Notify on SIGINT (Ctrl+C) and SIGTERM (docker stop, kill)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+// certificateSigner returns the signer for verification certificates,
+// with a key generated for this run if CERTIFICATE_KEY isn't set.
+func certificateSigner(cfg *config.Config, log *logger.Logger) (*cert.Signer, error) {
+	var previous []ed25519.PublicKey
+	for _, s := range cfg.CertificateOldKeys {
+		key, err := cert.ParsePublicKey(s)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+
+	var key ed25519.PrivateKey
+	var err error
+	if cfg.CertificateKey != "" {
+		key, err = cert.ParsePrivateKey(cfg.CertificateKey)
+	} else {
+		key, err = cert.GenerateKey()
+		if err == nil {
+			log.Warn("CERTIFICATE_KEY not set; certificates issued now won't verify after a restart")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cert.NewSigner("HumanMark", key, previous...), nil
+}
+
+// loadAccessList combines the access lists from the environment with
+// ACCESS_LIST_FILE, if set.
+func loadAccessList(cfg *config.Config) (middleware.AccessList, error) {
+	list := middleware.AccessList{
+		DenyIPs:  cfg.AccessDenyIPs,
+		AllowIPs: cfg.AccessAllowIPs,
+		DenyKeys: cfg.AccessDenyKeys,
+	}
+	if cfg.AccessListFile == "" {
+		return list, nil
+	}
+	fromFile, err := middleware.LoadAccessList(cfg.AccessListFile)
+	if err != nil {
+		return middleware.AccessList{}, err
+	}
+	return list.Merge(fromFile), nil
+}
+
+// reloadOnHangup re-reads the access lists and the detection config files
+// and reopens the audit log on every SIGHUP. A file that can't be read or
+// parsed is logged and the current configuration stays in force.
+func reloadOnHangup(app *App) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
 
+	for range hangup {
+		if app.Config.AccessListFile != "" {
+			reloadAccessList(app.Config, app.AccessControl, app.Logger)
+		}
+		if err := app.ReloadDetector(); err != nil {
+			app.Logger.Error("failed to reload detection config", "error", err)
+		}
+		// After rotation, records go to a new file
+		if app.AuditLog != nil {
+			if err := app.AuditLog.Reopen(); err != nil {
+				app.Logger.Error("failed to reopen audit log", "file", app.Config.AuditLogFile, "error", err)
+			}
+		}
+	}
+}
+
+// reloadAccessList re-reads the access lists and swaps them into ac.
+func reloadAccessList(cfg *config.Config, ac *middleware.AccessControl, log *logger.Logger) {
+	list, err := loadAccessList(cfg)
+	if err == nil {
+		err = ac.Update(list)
+	}
+	if err != nil {
+		log.Error("failed to reload access list", "file", cfg.AccessListFile, "error", err)
+		return
+	}
+	log.Info("reloaded access list",
+		"file", cfg.AccessListFile,
+		"deny_ips", len(list.DenyIPs),
+		"allow_ips", len(list.AllowIPs),
+		"deny_keys", len(list.DenyKeys),
+	)
+}
+
+// loadDetectorConfig builds the detection config from cfg, reading the
+// host reputation and AI phrase files it names. Providers are called with
+// httpClient.
+func loadDetectorConfig(cfg *config.Config, httpClient *http.Client, reg metrics.Registry) (service.DetectorConfig, error) {
+	var hostRules []service.HostRule
+	if cfg.HostReputationFile != "" {
+		rules, err := service.LoadHostRules(cfg.HostReputationFile)
+		if err != nil {
+			return service.DetectorConfig{}, fmt.Errorf("failed to load host reputation file: %w", err)
+		}
+		hostRules = rules
+	}
+	var phrases []service.AIPhrase
+	if cfg.AIPhrasesFile != "" {
+		loaded, err := service.LoadAIPhrases(cfg.AIPhrasesFile)
+		if err != nil {
+			return service.DetectorConfig{}, fmt.Errorf("failed to load AI phrases file: %w", err)
+		}
+		phrases = loaded
+	}
+
+	return service.DetectorConfig{
+		HiveAPIKey:    cfg.HiveAPIKey,
+		OpenAIAPIKey:  cfg.OpenAIAPIKey,
+		GPTZeroAPIKey: cfg.GPTZeroAPIKey,
+		Timeout:       cfg.DetectorTimeout,
+		HTTPClient:    httpClient,
+		ProviderURLs:  cfg.ProviderURLs,
+
+		SightengineAPIKey: cfg.SightengineAPIKey,
+		AIorNotAPIKey:     cfg.AIorNotAPIKey,
+		AnthropicAPIKey:   cfg.AnthropicAPIKey,
+		OriginalityAPIKey: cfg.OriginalityAPIKey,
+		WinstonAPIKey:     cfg.WinstonAPIKey,
+
+		AllowPrivateNetworks: cfg.AllowPrivateURLs,
+		MaxRedirects:         cfg.MaxRedirects,
+		Aggregation:          service.AggregationStrategy(cfg.AggregationStrategy),
+		ProviderWeights:      cfg.ProviderWeights,
+		ProviderTimeouts:     cfg.ProviderTimeouts,
+		Decision: service.DecisionPolicy{
+			AIThreshold:     cfg.AIThreshold,
+			HumanThreshold:  cfg.HumanThreshold,
+			UncertaintyBand: cfg.UncertaintyThreshold,
+		},
+		DisagreementThreshold:  cfg.DisagreementThreshold,
+		LocalOnlyConfidenceCap: cfg.LocalOnlyConfidenceCap,
+		HostRules:              hostRules,
+		AIPhrases:              phrases,
+		Metrics:                reg,
+
+		PageMaxAssets:  cfg.PageMaxAssets,
+		PageByteBudget: cfg.PageByteBudget,
+	}, nil
+}
+
+// analyzerProfiles converts the config file's analyzer profiles for the
+// handler. Config.Validate has checked the detectors they name.
+func analyzerProfiles(cfg *config.Config) map[string]handler.Profile {
+	profiles := make(map[string]handler.Profile, len(cfg.Profiles))
+	for name, p := range cfg.Profiles {
+		profiles[name] = handler.Profile{
+			EmailMode:        p.Mode == "email",
+			AcademicMode:     p.Mode == "academic",
+			Detectors:        p.Detectors,
+			ExcludeDetectors: p.ExcludeDetectors,
+			StopPhrases:      p.StopPhrases,
+			CommonWords:      p.CommonWords,
+		}
+	}
+	return profiles
+}
+
+// reloadDetector re-reads the detection config files and swaps the
+// configuration into detector. Detections already running finish with the
+// configuration they started with.
+func reloadDetector(cfg *config.Config, httpClient *http.Client, detector service.Detector, reg metrics.Registry, log *logger.Logger) error {
+	reloader, ok := detector.(service.Reloader)
+	if !ok {
+		return errors.New("detector can't be reloaded")
+	}
+	detectorConfig, err := loadDetectorConfig(cfg, httpClient, reg)
+	if err != nil {
+		return err
+	}
+	if err := reloader.Reload(detectorConfig); err != nil {
+		return fmt.Errorf("invalid detection config: %w", err)
+	}
+
+	var fingerprint string
+	if f, ok := detector.(service.ConfigFingerprinter); ok {
+		fingerprint = f.ConfigFingerprint()
+	}
+	log.Info("reloaded detection config",
+		"ai_phrases_file", cfg.AIPhrasesFile,
+		"host_reputation_file", cfg.HostReputationFile,
+		"config_fingerprint", fingerprint,
+	)
+	return nil
+}
+
+// gracefulShutdown waits for a signal on quit (SIGINT from Ctrl+C, SIGTERM
+// from docker stop or kill), or for the server to fail, and shuts the
+// server down. It gives in-flight requests and async jobs up to timeout to
+// complete before cancelling them. The error is the server's failure or
+// the shutdown's.
+func gracefulShutdown(srv *server.Server, quit chan os.Signal, log *logger.Logger, timeout time.Duration) error {
 	// Block until we receive a signal
-	sig := <-quit
-	log.Info("received shutdown signal", "signal", sig.String())
+	var serveErr error
+	select {
+	case sig := <-quit:
+		log.Info("received shutdown signal", "signal", sig.String())
+	case serveErr = <-srv.Errors():
+		log.Error("server error", "error", serveErr)
+	}
+
+	// A second signal stops the process without waiting
+	signal.Reset(syscall.SIGINT, syscall.SIGTERM)
 
 	// Create context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -226,9 +798,11 @@ func gracefulShutdown(server *http.Server, log *logger.Logger, timeout time.Dura
 	// Attempt graceful shutdown
 	// This stops accepting new connections and waits for existing ones to finish
 	log.Info("shutting down server", "timeout", timeout.String())
-	if err := server.Shutdown(ctx); err != nil {
+	err := srv.Shutdown(ctx)
+	if err != nil {
 		log.Error("server forced to shutdown", "error", err)
 	}
 
 	log.Info("server stopped")
+	return errors.Join(serveErr, err)
 }
@@ -0,0 +1,15 @@
+//go:build !otel
+
+package main
+
+import (
+	"context"
+
+	"github.com/humanmark/humanmark/pkg/tracing"
+)
+
+// newTracer returns the tracer for requests. Without -tags otel there's
+// nowhere to send spans, so they're discarded.
+func newTracer(ctx context.Context) (tracing.Tracer, func(context.Context) error, error) {
+	return tracing.Discard, nil, nil
+}
@@ -0,0 +1,17 @@
+//go:build otel
+
+package main
+
+import (
+	"context"
+
+	"github.com/humanmark/humanmark/pkg/tracing"
+	"github.com/humanmark/humanmark/pkg/tracing/oteltracing"
+)
+
+// newTracer exports spans to OpenTelemetry when an OTLP endpoint is set;
+// see oteltracing.NewFromEnv. Building with -tags otel needs a workspace
+// that includes the oteltracing module.
+func newTracer(ctx context.Context) (tracing.Tracer, func(context.Context) error, error) {
+	return oteltracing.NewFromEnv(ctx)
+}
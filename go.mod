@@ -7,7 +7,9 @@ module github.com/humanmark/humanmark
 
 go 1.22
 
-// No external dependencies for core functionality.
+// The only external dependencies are pgx, for PostgreSQL storage,
+// golang.org/x/text, for Unicode normalization of text before analysis, and
+// gopkg.in/yaml.v3, for the optional config file (CONFIG_FILE).
 // This keeps the project simple and reduces supply chain risk.
 //
 // The standard library provides everything we need:
@@ -19,5 +21,20 @@ go 1.22
 // - sync for thread-safe in-memory storage
 //
 // Optional dependencies (add when needed):
-// - github.com/jackc/pgx/v5 for PostgreSQL
 // - github.com/redis/go-redis/v9 for Redis caching
+// - go.opentelemetry.io/otel for tracing (required by the separate
+//   pkg/tracing/oteltracing module; build with -tags otel, see its docs)
+
+require (
+	github.com/jackc/pgx/v5 v5.7.4
+	golang.org/x/text v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+)
@@ -0,0 +1,180 @@
+// Package audit keeps an append-only record of verification decisions.
+//
+// Every verdict the API hands out is written as a Record, with enough
+// context to reconstruct who asked, about what, and what the detector
+// decided - but never the content itself, only its hash. The record is
+// kept apart from the operational logs, which can be sampled, filtered or
+// rotated away.
+//
+// By default records go to a file as JSON lines (see OpenFile). Anything
+// implementing Logger can take their place, e.g. to ship them to a SIEM.
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+)
+
+// Record is one verification decision.
+type Record struct {
+	// Time is when the verdict was handed out
+	Time time.Time `json:"time"`
+
+	// RequestID is the request the verdict answered (empty for async jobs,
+	// which finish after their request)
+	RequestID string `json:"request_id,omitempty"`
+
+	// JobID is the stored result (empty if storing it failed)
+	JobID string `json:"job_id,omitempty"`
+
+	// ParentID is the page job an embedded asset was verified with
+	ParentID string `json:"parent_id,omitempty"`
+
+	// APIKeyID is the key that made the request (empty for anonymous
+	// requests and async jobs)
+	APIKeyID string `json:"api_key_id,omitempty"`
+
+	// ClientIP is the address the request came from (empty for async jobs)
+	ClientIP string `json:"client_ip,omitempty"`
+
+	// ContentHash is the SHA-256 of the content; the content itself is
+	// never recorded
+	ContentHash string `json:"content_hash,omitempty"`
+
+	ContentType string   `json:"content_type"`
+	Verdict     string   `json:"verdict"`
+	Confidence  float64  `json:"confidence"`
+	Detectors   []string `json:"detectors"`
+
+	// AnalyzerVersion and ConfigFingerprint identify the detection logic
+	// and configuration that decided
+	AnalyzerVersion   string `json:"analyzer_version,omitempty"`
+	ConfigFingerprint string `json:"config_fingerprint,omitempty"`
+
+	// Reused is true when the verdict was a stored result for the same
+	// content rather than a new detection
+	Reused bool `json:"reused,omitempty"`
+
+	// Async is true for verdicts reached by a background job
+	Async bool `json:"async,omitempty"`
+}
+
+// NewRecord returns the record of job's verdict, timestamped now. Callers
+// add what they know of the request.
+func NewRecord(job *repository.Job) Record {
+	detectors := job.Detectors
+	if detectors == nil {
+		detectors = []string{}
+	}
+	return Record{
+		Time:              time.Now().UTC(),
+		JobID:             job.ID,
+		ParentID:          job.ParentID,
+		ContentHash:       job.ContentHash,
+		ContentType:       job.ContentType,
+		Verdict:           job.Verdict,
+		Confidence:        job.Confidence,
+		Detectors:         detectors,
+		AnalyzerVersion:   job.AnalyzerVersion,
+		ConfigFingerprint: job.ConfigFingerprint,
+	}
+}
+
+// Logger records verification decisions. Log must be safe for concurrent
+// use; an error means the record was lost.
+type Logger interface {
+	Log(record Record) error
+}
+
+// JSONLogger writes each Record as a line of JSON. It's safe for
+// concurrent use; lines are never interleaved.
+type JSONLogger struct {
+	mu   sync.Mutex
+	w    io.Writer
+	file *os.File
+
+	// path is the file Reopen opens again ("" for plain writers)
+	path string
+}
+
+// NewJSONLogger returns a JSONLogger writing to w.
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{w: w}
+}
+
+// OpenFile returns a JSONLogger appending to the file at path, creating it
+// if needed. Records are only ever appended, and Reopen lets log rotation
+// move the file away.
+func OpenFile(path string) (*JSONLogger, error) {
+	f, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLogger{w: f, file: f, path: path}, nil
+}
+
+// openAppend opens path for appending, readable only by its owner and
+// group.
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+}
+
+// Log writes record as one line.
+func (l *JSONLogger) Log(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		return errors.New("audit log is closed")
+	}
+	_, err = l.w.Write(line)
+	return err
+}
+
+// Reopen opens the file again by name, so records go to a new file once
+// log rotation has moved the old one away. If the file can't be opened,
+// records keep going to the old one. Reopen does nothing for a JSONLogger
+// made with NewJSONLogger.
+func (l *JSONLogger) Reopen() error {
+	if l.path == "" {
+		return nil
+	}
+	f, err := openAppend(l.path)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	old := l.file
+	l.w, l.file = f, f
+	l.mu.Unlock()
+
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// Close closes the file; later records fail. Close does nothing for a
+// JSONLogger made with NewJSONLogger.
+func (l *JSONLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.w, l.file = nil, nil
+	return err
+}
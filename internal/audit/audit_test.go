@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/humanmark/humanmark/internal/repository"
+)
+
+// readLines returns the records in a JSON lines file, failing on any line
+// that isn't one.
+func readLines(t *testing.T, data []byte) []Record {
+	t.Helper()
+
+	var records []Record
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record Record
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("malformed line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestJSONLogger tests that each record is written as one line, even when
+// logged concurrently.
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewJSONLogger(&buf)
+
+	job := &repository.Job{
+		ID:                "job-1",
+		ContentType:       "text",
+		Verdict:           "ai",
+		Confidence:        0.92,
+		Detectors:         []string{"humanmark", "gptzero"},
+		ContentHash:       "abc123",
+		AnalyzerVersion:   "1.9.0",
+		ConfigFingerprint: "4be1",
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record := NewRecord(job)
+			record.RequestID = "req-1"
+			if err := l.Log(record); err != nil {
+				t.Errorf("Log() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records := readLines(t, buf.Bytes())
+	if len(records) != 50 {
+		t.Fatalf("expected 50 lines, got %d", len(records))
+	}
+	r := records[0]
+	if r.JobID != "job-1" || r.Verdict != "ai" || r.Confidence != 0.92 || r.ContentHash != "abc123" ||
+		r.ConfigFingerprint != "4be1" || len(r.Detectors) != 2 || r.RequestID != "req-1" || r.Time.IsZero() {
+		t.Errorf("record = %+v", r)
+	}
+
+	t.Run("detectors are never null", func(t *testing.T) {
+		var buf bytes.Buffer
+		NewJSONLogger(&buf).Log(NewRecord(&repository.Job{Verdict: "uncertain"}))
+		if !strings.Contains(buf.String(), `"detectors":[]`) {
+			t.Errorf("line = %s", buf.String())
+		}
+	})
+}
+
+// TestOpenFile tests appending to a file, and reopening it after it was
+// rotated away.
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"time":"2024-01-01T00:00:00Z","content_type":"text","verdict":"human","confidence":0.9,"detectors":[]}`+"\n"), 0o640); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	l, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer l.Close()
+
+	if err := l.Log(NewRecord(&repository.Job{ID: "before", Verdict: "ai"})); err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+
+	// Rotation moves the file away, then tells the server to reopen it
+	rotated := filepath.Join(dir, "audit.jsonl.1")
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err := l.Reopen(); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	if err := l.Log(NewRecord(&repository.Job{ID: "after", Verdict: "human"})); err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+
+	old, _ := os.ReadFile(rotated)
+	if records := readLines(t, old); len(records) != 2 || records[1].JobID != "before" {
+		t.Errorf("expected the existing line kept and one appended, got %+v", records)
+	}
+	current, _ := os.ReadFile(path)
+	if records := readLines(t, current); len(records) != 1 || records[0].JobID != "after" {
+		t.Errorf("expected the new file to hold the later record, got %+v", records)
+	}
+
+	t.Run("closed", func(t *testing.T) {
+		l, err := OpenFile(filepath.Join(dir, "closed.jsonl"))
+		if err != nil {
+			t.Fatalf("OpenFile() error = %v", err)
+		}
+		l.Close()
+		if err := l.Log(NewRecord(&repository.Job{})); err == nil {
+			t.Error("expected an error logging to a closed file")
+		}
+	})
+}
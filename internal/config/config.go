@@ -2,21 +2,37 @@
 //
 // Configuration is loaded from environment variables following the 12-factor app methodology.
 // This makes the application easy to configure in different environments (local, staging, production)
-// without code changes.
+// without code changes. An optional YAML or JSON file (CONFIG_FILE) can hold the same settings,
+// with environment variables taking precedence over it.
 //
 // All configuration is validated at startup to fail fast if misconfigured.
 package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/cert"
 )
 
 // Config holds all application configuration.
 // Fields are documented with their environment variable names and defaults.
+// Credentials (API keys, DATABASE_URL, REDIS_URL, WEBHOOK_SECRET,
+// ADMIN_TOKEN, CERTIFICATE_KEY) can also be read from the file NAME_FILE
+// names, see secrets.go.
 type Config struct {
+	// ConfigFile is a YAML or JSON file of settings, read before the
+	// environment, which overrides it. See file.go for its layout.
+	// Env var: CONFIG_FILE (optional)
+	ConfigFile string
+
 	// Environment is the deployment environment: development, staging, production
 	// Env var: ENV (default: development)
 	Environment string
@@ -47,44 +63,486 @@ type Config struct {
 	// Env var: GPTZERO_API_KEY (optional - improves text detection)
 	GPTZeroAPIKey string
 
+	// SightengineAPIKey is the Sightengine credential as "api_user:api_secret"
+	// Env var: SIGHTENGINE_API_KEY (optional - improves image detection)
+	SightengineAPIKey string
+
+	// AIorNotAPIKey is the API key for AIorNot detection
+	// Env var: AIORNOT_API_KEY (optional - improves image detection)
+	AIorNotAPIKey string
+
+	// AnthropicAPIKey is the API key for Claude vision detection
+	// Env var: ANTHROPIC_API_KEY (optional - improves image detection)
+	AnthropicAPIKey string
+
+	// OriginalityAPIKey is the API key for Originality.ai detection
+	// Env var: ORIGINALITY_API_KEY (optional - improves text detection)
+	OriginalityAPIKey string
+
+	// WinstonAPIKey is the API key for Winston AI detection
+	// Env var: WINSTON_API_KEY (optional - improves text detection)
+	WinstonAPIKey string
+
+	// ProviderWeights overrides detector reliability weights
+	// Env var: PROVIDER_WEIGHTS (optional, e.g. "winston=1.3,openai=0.5")
+	ProviderWeights map[string]float64
+
+	// DetectorTimeout caps each call to an external detection provider
+	// Env var: DETECTOR_TIMEOUT (default: 30s, 0 = no limit)
+	DetectorTimeout time.Duration
+
+	// ProviderTimeouts are shorter timeouts for some providers, by name
+	// Config file only: detectors.providers.NAME.timeout
+	ProviderTimeouts map[string]time.Duration
+
+	// ProviderURLs overrides external providers' API base URLs by name,
+	// for self-hosted gateways and test servers
+	// Env var: PROVIDER_URLS (optional, e.g. "winston=https://gateway.internal/winston")
+	ProviderURLs map[string]string
+
+	// OutboundProxy sends provider calls through this proxy; URL inputs
+	// are never fetched through a proxy
+	// Env var: OUTBOUND_PROXY (default: from HTTPS_PROXY, HTTP_PROXY and NO_PROXY)
+	OutboundProxy string
+
+	// OutboundCAFile is a PEM bundle of CAs trusted for outbound TLS
+	// besides the system's, e.g. a proxy's private CA
+	// Env var: OUTBOUND_CA_FILE (optional)
+	OutboundCAFile string
+
+	// OutboundInsecureSkipVerify accepts any TLS certificate on outbound
+	// calls
+	// Env var: OUTBOUND_INSECURE_SKIP_VERIFY (default: false, never allowed in production)
+	OutboundInsecureSkipVerify bool
+
+	// OutboundMaxIdleConns caps idle provider connections kept in total
+	// Env var: OUTBOUND_MAX_IDLE_CONNS (default: 100, 0 = no limit)
+	OutboundMaxIdleConns int
+
+	// OutboundMaxIdleConnsPerHost caps idle connections kept per provider
+	// Env var: OUTBOUND_MAX_IDLE_CONNS_PER_HOST (default: 10)
+	OutboundMaxIdleConnsPerHost int
+
+	// OutboundMaxConnsPerHost caps connections per provider, in use or not
+	// Env var: OUTBOUND_MAX_CONNS_PER_HOST (default: 0 = no limit)
+	OutboundMaxConnsPerHost int
+
+	// Profiles are named sets of analyzer options, picked per request with
+	// ?profile=NAME
+	// Config file only: profiles
+	Profiles map[string]Profile
+
 	// MaxUploadSize is the maximum file upload size in bytes
 	// Env var: MAX_UPLOAD_SIZE (default: 104857600 = 100MB)
 	MaxUploadSize int64
 
+	// MaxUploadFiles is how many files one multipart upload may hold;
+	// together they're limited to MaxUploadSize
+	// Env var: MAX_UPLOAD_FILES (default: 10)
+	MaxUploadFiles int
+
+	// UploadMemoryLimit is the largest uploaded file kept in memory; bigger
+	// ones are streamed to a temp file
+	// Env var: UPLOAD_MEMORY_LIMIT (default: 8388608 = 8MB)
+	UploadMemoryLimit int64
+
+	// MaxResponseSize truncates larger response bodies (X-Response-Truncated: true)
+	// Env var: MAX_RESPONSE_SIZE (default: 1048576 = 1MB, 0 = no limit)
+	MaxResponseSize int64
+
+	// PanicBodyBytes is how much of the request body to log with a panic,
+	// for reproducing it; bodies can hold personal data
+	// Env var: PANIC_LOG_BODY_BYTES (default: 0 = none, at most 65536)
+	PanicBodyBytes int
+
+	// LogLevel is the minimum level logged: debug, info, warn, error
+	// Env var: LOG_LEVEL (default: info)
+	LogLevel string
+
+	// LogFormat is how logs are written: json or text
+	// Env var: LOG_FORMAT (default: json in production, text otherwise)
+	LogFormat string
+
+	// LogTimeFormat is how log record times are written: rfc3339 or
+	// rfc3339nano, for aggregators that need nanoseconds
+	// Env var: LOG_TIME_FORMAT (default: RFC 3339 with milliseconds)
+	LogTimeFormat string
+
+	// LogRedactKeys are log attribute keys whose values are masked, as well
+	// as the built-in ones (api_key, authorization, text, token, ...). URL
+	// query parameters with these names are masked too.
+	// Env var: LOG_REDACT_KEYS (optional, e.g. "email,phone")
+	LogRedactKeys []string
+
+	// LogMaxValueLength truncates longer string values in logs, in bytes
+	// Env var: LOG_MAX_VALUE_LENGTH (default: 8192, 0 = no limit)
+	LogMaxValueLength int
+
+	// LogLevels sets the logging level of components: http, text, image,
+	// audio, video, document, page. Others log at LOG_LEVEL.
+	// Env var: LOG_LEVELS (optional, e.g. "video=debug,http=warn")
+	LogLevels map[string]string
+
+	// LogSampleBurst is how many identical log records (same level and
+	// message) are written each second before sampling starts; errors are
+	// never sampled
+	// Env var: LOG_SAMPLE_BURST (default: 0 = no sampling)
+	LogSampleBurst int
+
+	// LogSampleRate keeps 1 in LogSampleRate identical records past the
+	// burst
+	// Env var: LOG_SAMPLE_RATE (default: 100, 0 = drop them all)
+	LogSampleRate int
+
 	// RateLimitPerMinute is the maximum requests per minute per IP/API key
 	// Env var: RATE_LIMIT_PER_MINUTE (default: 60)
 	RateLimitPerMinute int
 
+	// RateLimitBurst is how many requests a client can make at once before
+	// being held to the per-minute rate
+	// Env var: RATE_LIMIT_BURST (default: 0 = the client's per-minute limit)
+	RateLimitBurst int
+
+	// RateLimitMaxClients caps the clients the rate limiter tracks; past
+	// it, the clients closest to a full bucket are forgotten
+	// Env var: RATE_LIMIT_MAX_CLIENTS (default: 100000, 0 = the default)
+	RateLimitMaxClients int
+
+	// RateLimits sets per-route limits in requests per minute, keyed by
+	// "[METHOD ]PATH" pattern; the most specific match wins
+	// Env var: RATE_LIMITS (optional, e.g. "POST /verify/batch=10,POST /verify=60")
+	RateLimits map[string]int
+
+	// RequestTimeout is how long a request may take before it's cancelled
+	// with a 504
+	// Env var: REQUEST_TIMEOUT (default: 30s, 0 = no limit)
+	RequestTimeout time.Duration
+
+	// RequestTimeoutBudgets replaces RequestTimeout for slow content types
+	// Env var: REQUEST_TIMEOUT_BUDGETS (default: "video=2m,audio=1m")
+	RequestTimeoutBudgets map[string]time.Duration
+
+	// MaxConcurrent is how many verifications may run at once
+	// Env var: MAX_CONCURRENT_REQUESTS (default: 64, 0 = no limit)
+	MaxConcurrent int
+
+	// MaxConcurrentWait is how long a verification waits for a free slot
+	// before a 503
+	// Env var: MAX_CONCURRENT_WAIT (default: 2s, 0 = don't wait)
+	MaxConcurrentWait time.Duration
+
+	// MaxConcurrentPools caps expensive content types separately, within
+	// MaxConcurrent
+	// Env var: MAX_CONCURRENT_POOLS (default: "video=4,audio=8")
+	MaxConcurrentPools map[string]int
+
+	// ShutdownTimeout is how long shutdown waits for in-flight requests and
+	// async jobs to finish before cancelling them
+	// Env var: SHUTDOWN_TIMEOUT (default: 30s)
+	ShutdownTimeout time.Duration
+
 	// AllowedOrigins is a comma-separated list of allowed CORS origins
 	// Env var: ALLOWED_ORIGINS (default: * in development, must be set in production)
 	AllowedOrigins []string
 
+	// CORSAllowedMethods are the methods browsers may use cross-origin
+	// Env var: CORS_ALLOWED_METHODS (default: GET,POST,DELETE,OPTIONS)
+	CORSAllowedMethods []string
+
+	// CORSAllowedHeaders are the request headers browsers may send
+	// cross-origin
+	// Env var: CORS_ALLOWED_HEADERS (default: Content-Type,Authorization,X-API-Key,X-Request-ID)
+	CORSAllowedHeaders []string
+
+	// CORSExposedHeaders are the response headers browser scripts may read
+	// Env var: CORS_EXPOSED_HEADERS (default: X-Request-ID,Retry-After,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset)
+	CORSExposedHeaders []string
+
+	// CORSAllowCredentials lets browsers send cookies and HTTP auth
+	// cross-origin; needs explicit ALLOWED_ORIGINS
+	// Env var: CORS_ALLOW_CREDENTIALS (default: false)
+	CORSAllowCredentials bool
+
+	// ContentSecurityPolicy is sent on every response ("" = none)
+	// Env var: CONTENT_SECURITY_POLICY (default: a policy that loads nothing and forbids framing)
+	ContentSecurityPolicy string
+
+	// ReferrerPolicy is sent on every response
+	// Env var: REFERRER_POLICY (default: no-referrer)
+	ReferrerPolicy string
+
 	// APIKeyRequired determines if API key authentication is required
 	// Env var: API_KEY_REQUIRED (default: false in development, true in production)
 	APIKeyRequired bool
+
+	// AccessDenyIPs are client IPs or CIDR ranges refused with 403
+	// Env var: ACCESS_DENY_IPS (optional, e.g. "203.0.113.0/24,2001:db8::/32")
+	AccessDenyIPs []string
+
+	// AccessAllowIPs are client IPs or CIDR ranges exempt from rate
+	// limiting; the deny lists still apply
+	// Env var: ACCESS_ALLOW_IPS (optional)
+	AccessAllowIPs []string
+
+	// AccessDenyKeys are API key IDs refused with 403
+	// Env var: ACCESS_DENY_KEYS (optional)
+	AccessDenyKeys []string
+
+	// AccessListFile is a JSON access list added to the ones above, re-read
+	// on SIGHUP
+	// Env var: ACCESS_LIST_FILE (optional)
+	AccessListFile string
+
+	// TrustedProxies are the IPs or CIDR ranges of the load balancers and
+	// reverse proxies in front of the server. X-Forwarded-For and X-Real-IP
+	// are only believed from them; other clients are identified by the
+	// address they connect from.
+	// Env var: TRUSTED_PROXIES (optional, e.g. "10.0.0.0/8")
+	TrustedProxies []string
+
+	// AdminToken authorizes the /admin/keys endpoints (Authorization: Bearer)
+	// Env var: ADMIN_TOKEN (optional - admin endpoints are disabled when unset)
+	AdminToken string
+
+	// UsageMonthlyQuota is how many verifications each API key may make per UTC month
+	// Env var: USAGE_MONTHLY_QUOTA (default: 0 = unlimited)
+	UsageMonthlyQuota int64
+
+	// AllowPrivateURLs lets URL inputs reach private, loopback and link-local addresses
+	// Env var: ALLOW_PRIVATE_URLS (default: false, never allowed in production)
+	AllowPrivateURLs bool
+
+	// MaxRedirects is the maximum number of redirects followed when fetching URL inputs
+	// Env var: MAX_REDIRECTS (default: 5)
+	MaxRedirects int
+
+	// AsyncWorkers is the number of background workers processing async jobs
+	// Env var: ASYNC_WORKERS (default: 4)
+	AsyncWorkers int
+
+	// AsyncQueueSize is the number of async jobs that may wait for a worker
+	// Env var: ASYNC_QUEUE_SIZE (default: 100)
+	AsyncQueueSize int
+
+	// AsyncThresholdBytes switches larger requests to async mode automatically
+	// Env var: ASYNC_THRESHOLD_BYTES (default: 52428800 = 50MB, 0 = only on ?async=true)
+	AsyncThresholdBytes int64
+
+	// RetentionDays is how long verification results are kept before the retention
+	// sweep deletes them; the sweep also purges results deleted through the API
+	// Env var: RETENTION_DAYS (default: 0 = keep forever)
+	RetentionDays int
+
+	// MemoryMaxJobs caps the jobs kept by in-memory storage; the oldest are evicted first
+	// Env var: MEMORY_MAX_JOBS (default: 100000, 0 = no limit)
+	MemoryMaxJobs int
+
+	// MemoryMaxAge is how long in-memory storage keeps a job
+	// Env var: MEMORY_MAX_AGE (default: 24h, 0 = no limit)
+	MemoryMaxAge time.Duration
+
+	// PageMaxAssets caps the images and videos verified per page in mode=page
+	// Env var: PAGE_MAX_ASSETS (default: 20)
+	PageMaxAssets int
+
+	// PageByteBudget caps the bytes fetched per page in mode=page
+	// Env var: PAGE_BYTE_BUDGET (default: 104857600 = 100MB)
+	PageByteBudget int64
+
+	// WebhookSecret signs job-completion callbacks (HMAC-SHA256)
+	// Env var: WEBHOOK_SECRET (optional - callback_url is rejected when unset)
+	WebhookSecret string
+
+	// CertificateKey signs verification certificates: a base64 Ed25519
+	// seed or private key
+	// Env var: CERTIFICATE_KEY (optional - a key is generated at startup,
+	// and its certificates stop verifying after a restart)
+	CertificateKey string
+
+	// CertificateOldKeys are base64 Ed25519 public keys of retired
+	// certificate keys, still published so their certificates verify
+	// Env var: CERTIFICATE_OLD_KEYS (comma-separated, optional)
+	CertificateOldKeys []string
+
+	// AggregationStrategy combines detector scores: weighted-mean, max, median, veto
+	// Env var: AGGREGATION_STRATEGY (default: weighted-mean)
+	AggregationStrategy string
+
+	// AIThreshold is the AI score at or above which content is judged AI
+	// Env var: AI_THRESHOLD (default: 0.5)
+	AIThreshold float64
+
+	// HumanThreshold is the AI score below which content is judged human;
+	// scores between the two thresholds are uncertain
+	// Env var: HUMAN_THRESHOLD (default: 0.5)
+	HumanThreshold float64
+
+	// UncertaintyThreshold makes AI scores this close to the split uncertain
+	// when AI_THRESHOLD and HUMAN_THRESHOLD are the same; otherwise the
+	// scores between them are uncertain and it has no effect
+	// Env var: UNCERTAINTY_THRESHOLD (default: 0.15, 0 = disabled)
+	UncertaintyThreshold float64
+
+	// DisagreementThreshold flags results for review when detector scores
+	// have a larger weighted standard deviation
+	// Env var: DISAGREEMENT_THRESHOLD (default: 0.25, 0 = disabled)
+	DisagreementThreshold float64
+
+	// LocalOnlyConfidenceCap is the highest confidence reported when only
+	// the local analyzer scored the content (no external API keys apply)
+	// Env var: LOCAL_ONLY_CONFIDENCE_CAP (default: 0.6, 0 = disabled)
+	LocalOnlyConfidenceCap float64
+
+	// HostReputationFile is a JSON file of URL host rules that add to or
+	// replace the built-in table
+	// Env var: HOST_REPUTATION_FILE (optional)
+	HostReputationFile string
+
+	// AIPhrasesFile is a JSON phrase dictionary that replaces the built-in
+	// one. It and HostReputationFile are re-read on SIGHUP and
+	// POST /admin/reload.
+	// Env var: AI_PHRASES_FILE (optional)
+	AIPhrasesFile string
+
+	// AuditLogFile receives a JSON line for every verdict handed out,
+	// apart from the operational logs. It's reopened on SIGHUP, so it can
+	// be rotated.
+	// Env var: AUDIT_LOG_FILE (optional - no audit log when unset)
+	AuditLogFile string
+
+	// ValidateResponseSchema checks /verify responses against their schema
+	// Env var: VALIDATE_RESPONSE_SCHEMA (default: false, never allowed in production)
+	ValidateResponseSchema bool
+
+	// sources says where settings that didn't come from their environment
+	// variable came from, by variable, so Validate can point there
+	sources map[string]string
 }
 
-// Load reads configuration from environment variables.
-// Missing optional values get sensible defaults.
-// This function never returns an error - use Validate() to check required fields.
+// Load reads configuration from environment variables, over the config
+// file if CONFIG_FILE names one. Missing optional values get sensible
+// defaults. It only fails on values that can't be parsed (PROVIDER_WEIGHTS,
+// or a malformed config file) - use Validate() to check required fields.
 func Load() (*Config, error) {
 	cfg := &Config{
-		Environment:        getEnvOrDefault("ENV", "development"),
-		Port:               getEnvAsInt("PORT", 8080),
-		DatabaseURL:        os.Getenv("DATABASE_URL"),
-		RedisURL:           os.Getenv("REDIS_URL"),
-		HiveAPIKey:         os.Getenv("HIVE_API_KEY"),
-		OpenAIAPIKey:       os.Getenv("OPENAI_API_KEY"),
-		GPTZeroAPIKey:      os.Getenv("GPTZERO_API_KEY"),
-		MaxUploadSize:      getEnvAsInt64("MAX_UPLOAD_SIZE", 100*1024*1024), // 100MB
-		RateLimitPerMinute: getEnvAsInt("RATE_LIMIT_PER_MINUTE", 60),
-		AllowedOrigins:     getEnvAsSlice("ALLOWED_ORIGINS", []string{"*"}),
-		APIKeyRequired:     getEnvAsBool("API_KEY_REQUIRED", false),
+		Environment:                 getEnvOrDefault("ENV", "development"),
+		Port:                        getEnvAsInt("PORT", 8080),
+		DatabaseURL:                 os.Getenv("DATABASE_URL"),
+		RedisURL:                    os.Getenv("REDIS_URL"),
+		HiveAPIKey:                  os.Getenv("HIVE_API_KEY"),
+		OpenAIAPIKey:                os.Getenv("OPENAI_API_KEY"),
+		GPTZeroAPIKey:               os.Getenv("GPTZERO_API_KEY"),
+		SightengineAPIKey:           os.Getenv("SIGHTENGINE_API_KEY"),
+		AIorNotAPIKey:               os.Getenv("AIORNOT_API_KEY"),
+		AnthropicAPIKey:             os.Getenv("ANTHROPIC_API_KEY"),
+		OriginalityAPIKey:           os.Getenv("ORIGINALITY_API_KEY"),
+		WinstonAPIKey:               os.Getenv("WINSTON_API_KEY"),
+		DetectorTimeout:             getEnvAsDuration("DETECTOR_TIMEOUT", 30*time.Second),
+		OutboundProxy:               os.Getenv("OUTBOUND_PROXY"),
+		OutboundCAFile:              os.Getenv("OUTBOUND_CA_FILE"),
+		OutboundMaxIdleConns:        getEnvAsInt("OUTBOUND_MAX_IDLE_CONNS", 100),
+		OutboundMaxConnsPerHost:     getEnvAsInt("OUTBOUND_MAX_CONNS_PER_HOST", 0),
+		OutboundMaxIdleConnsPerHost: getEnvAsInt("OUTBOUND_MAX_IDLE_CONNS_PER_HOST", 10),
+		OutboundInsecureSkipVerify:  getEnvAsBool("OUTBOUND_INSECURE_SKIP_VERIFY", false),
+		MaxUploadSize:               getEnvAsInt64("MAX_UPLOAD_SIZE", 100*1024*1024), // 100MB
+		MaxResponseSize:             getEnvAsInt64("MAX_RESPONSE_SIZE", 1024*1024),   // 1MB
+		MaxUploadFiles:              getEnvAsInt("MAX_UPLOAD_FILES", 10),
+		UploadMemoryLimit:           getEnvAsInt64("UPLOAD_MEMORY_LIMIT", 8*1024*1024), // 8MB
+		PanicBodyBytes:              getEnvAsInt("PANIC_LOG_BODY_BYTES", 0),
+		LogLevel:                    getEnvOrDefault("LOG_LEVEL", "info"),
+		LogFormat:                   os.Getenv("LOG_FORMAT"),
+		LogTimeFormat:               os.Getenv("LOG_TIME_FORMAT"),
+		LogRedactKeys:               getEnvAsSlice("LOG_REDACT_KEYS", nil),
+		LogMaxValueLength:           getEnvAsInt("LOG_MAX_VALUE_LENGTH", 8192),
+		LogSampleBurst:              getEnvAsInt("LOG_SAMPLE_BURST", 0),
+		LogSampleRate:               getEnvAsInt("LOG_SAMPLE_RATE", 100),
+		RateLimitPerMinute:          getEnvAsInt("RATE_LIMIT_PER_MINUTE", 60),
+		RateLimitBurst:              getEnvAsInt("RATE_LIMIT_BURST", 0),
+		RateLimitMaxClients:         getEnvAsInt("RATE_LIMIT_MAX_CLIENTS", 100000),
+		RequestTimeout:              getEnvAsDuration("REQUEST_TIMEOUT", 30*time.Second),
+		MaxConcurrent:               getEnvAsInt("MAX_CONCURRENT_REQUESTS", 64),
+		MaxConcurrentWait:           getEnvAsDuration("MAX_CONCURRENT_WAIT", 2*time.Second),
+		ShutdownTimeout:             getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		AllowedOrigins:              getEnvAsSlice("ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedMethods:          getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:          getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID"}),
+		CORSExposedHeaders:          getEnvAsSlice("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}),
+		CORSAllowCredentials:        getEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
+		ContentSecurityPolicy:       getEnvOrDefault("CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy),
+		ReferrerPolicy:              getEnvOrDefault("REFERRER_POLICY", "no-referrer"),
+		APIKeyRequired:              getEnvAsBool("API_KEY_REQUIRED", false),
+		AccessDenyIPs:               getEnvAsSlice("ACCESS_DENY_IPS", nil),
+		AccessAllowIPs:              getEnvAsSlice("ACCESS_ALLOW_IPS", nil),
+		AccessDenyKeys:              getEnvAsSlice("ACCESS_DENY_KEYS", nil),
+		AccessListFile:              os.Getenv("ACCESS_LIST_FILE"),
+		TrustedProxies:              getEnvAsSlice("TRUSTED_PROXIES", nil),
+		AdminToken:                  os.Getenv("ADMIN_TOKEN"),
+		UsageMonthlyQuota:           getEnvAsInt64("USAGE_MONTHLY_QUOTA", 0),
+		AllowPrivateURLs:            getEnvAsBool("ALLOW_PRIVATE_URLS", false),
+		MaxRedirects:                getEnvAsInt("MAX_REDIRECTS", 5),
+		AsyncWorkers:                getEnvAsInt("ASYNC_WORKERS", 4),
+		AsyncQueueSize:              getEnvAsInt("ASYNC_QUEUE_SIZE", 100),
+		AsyncThresholdBytes:         getEnvAsInt64("ASYNC_THRESHOLD_BYTES", 50*1024*1024), // 50MB
+		RetentionDays:               getEnvAsInt("RETENTION_DAYS", 0),
+		MemoryMaxJobs:               getEnvAsInt("MEMORY_MAX_JOBS", 100000),
+		MemoryMaxAge:                getEnvAsDuration("MEMORY_MAX_AGE", 24*time.Hour),
+		PageMaxAssets:               getEnvAsInt("PAGE_MAX_ASSETS", 20),
+		PageByteBudget:              getEnvAsInt64("PAGE_BYTE_BUDGET", 100*1024*1024), // 100MB
+		WebhookSecret:               os.Getenv("WEBHOOK_SECRET"),
+		CertificateKey:              os.Getenv("CERTIFICATE_KEY"),
+		CertificateOldKeys:          getEnvAsSlice("CERTIFICATE_OLD_KEYS", nil),
+		AggregationStrategy:         getEnvOrDefault("AGGREGATION_STRATEGY", "weighted-mean"),
+		AIThreshold:                 getEnvAsFloat("AI_THRESHOLD", 0.5),
+		HumanThreshold:              getEnvAsFloat("HUMAN_THRESHOLD", 0.5),
+		UncertaintyThreshold:        getEnvAsFloat("UNCERTAINTY_THRESHOLD", 0.15),
+		DisagreementThreshold:       getEnvAsFloat("DISAGREEMENT_THRESHOLD", 0.25),
+		LocalOnlyConfidenceCap:      getEnvAsFloat("LOCAL_ONLY_CONFIDENCE_CAP", 0.6),
+		HostReputationFile:          os.Getenv("HOST_REPUTATION_FILE"),
+		AIPhrasesFile:               os.Getenv("AI_PHRASES_FILE"),
+		AuditLogFile:                os.Getenv("AUDIT_LOG_FILE"),
+		ValidateResponseSchema:      getEnvAsBool("VALIDATE_RESPONSE_SCHEMA", false),
+	}
+
+	// Lists and maps are parsed here so a typo fails startup; every bad
+	// one is reported, not just the first
+	var parseErrors []string
+	parse := func(env string, err error) {
+		if err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("invalid %s: %v", env, err))
+		}
+	}
+	var err error
+	cfg.ProviderWeights, err = parseWeights(os.Getenv("PROVIDER_WEIGHTS"))
+	parse("PROVIDER_WEIGHTS", err)
+	cfg.ProviderURLs, err = parseURLs(os.Getenv("PROVIDER_URLS"))
+	parse("PROVIDER_URLS", err)
+	cfg.RateLimits, err = parseRateLimits(os.Getenv("RATE_LIMITS"))
+	parse("RATE_LIMITS", err)
+	cfg.RequestTimeoutBudgets, err = parseDurations(getEnvOrDefault("REQUEST_TIMEOUT_BUDGETS", "video=2m,audio=1m"))
+	parse("REQUEST_TIMEOUT_BUDGETS", err)
+	cfg.MaxConcurrentPools, err = parseCounts(getEnvOrDefault("MAX_CONCURRENT_POOLS", "video=4,audio=8"))
+	parse("MAX_CONCURRENT_POOLS", err)
+	cfg.LogLevels, err = parseLevels(os.Getenv("LOG_LEVELS"))
+	parse("LOG_LEVELS", err)
+	if len(parseErrors) > 0 {
+		return nil, fmt.Errorf("configuration errors:\n  - %s", strings.Join(parseErrors, "\n  - "))
+	}
+
+	// Secrets can be mounted as files instead, e.g. HIVE_API_KEY_FILE
+	if err := readSecretFiles(cfg); err != nil {
+		return nil, err
+	}
+
+	// The file fills in what the environment leaves unset
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := applyFile(cfg, path); err != nil {
+			return nil, fmt.Errorf("invalid CONFIG_FILE: %w", err)
+		}
 	}
 
 	// Production defaults
 	if cfg.IsProduction() {
-		if cfg.AllowedOrigins[0] == "*" {
+		if len(cfg.AllowedOrigins) == 0 || cfg.AllowedOrigins[0] == "*" {
 			cfg.AllowedOrigins = []string{} // Require explicit origins in production
 		}
 		cfg.APIKeyRequired = true
@@ -93,6 +551,20 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// defaultContentSecurityPolicy suits a JSON API: nothing may load and no
+// page may frame a response. Inline styles are allowed for HTML reports.
+const defaultContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+
+// validReferrerPolicies are the Referrer-Policy values browsers accept.
+var validReferrerPolicies = map[string]bool{
+	"no-referrer": true, "no-referrer-when-downgrade": true, "origin": true,
+	"origin-when-cross-origin": true, "same-origin": true, "strict-origin": true,
+	"strict-origin-when-cross-origin": true, "unsafe-url": true,
+}
+
+// minAdminTokenLength is the shortest ADMIN_TOKEN Validate accepts.
+const minAdminTokenLength = 32
+
 // Validate checks that all required configuration is present and valid.
 // Returns an error describing what's missing or invalid.
 func (c *Config) Validate() error {
@@ -100,13 +572,13 @@ func (c *Config) Validate() error {
 
 	// Port must be valid
 	if c.Port < 1 || c.Port > 65535 {
-		errors = append(errors, fmt.Sprintf("invalid port: %d (must be 1-65535)", c.Port))
+		errors = append(errors, fmt.Sprintf("invalid PORT: %d (must be 1-65535)", c.Port))
 	}
 
 	// Environment must be recognized
 	validEnvs := map[string]bool{"development": true, "staging": true, "production": true}
 	if !validEnvs[c.Environment] {
-		errors = append(errors, fmt.Sprintf("invalid environment: %s (must be development, staging, or production)", c.Environment))
+		errors = append(errors, fmt.Sprintf("invalid ENV: %s (must be development, staging, or production)", c.Environment))
 	}
 
 	// Production requirements
@@ -117,14 +589,288 @@ func (c *Config) Validate() error {
 		if len(c.AllowedOrigins) == 0 {
 			errors = append(errors, "ALLOWED_ORIGINS must be set in production (not *)")
 		}
+		if c.AllowPrivateURLs {
+			errors = append(errors, "ALLOW_PRIVATE_URLS must not be enabled in production")
+		}
+		if c.ValidateResponseSchema {
+			errors = append(errors, "VALIDATE_RESPONSE_SCHEMA must not be enabled in production")
+		}
+		if c.OutboundInsecureSkipVerify {
+			errors = append(errors, "OUTBOUND_INSECURE_SKIP_VERIFY must not be enabled in production")
+		}
+	}
+
+	// CORS origins must be origins, and credentials need explicit ones
+	for _, origin := range c.AllowedOrigins {
+		if origin != "*" && !validOrigin(origin) {
+			errors = append(errors, fmt.Sprintf("invalid ALLOWED_ORIGINS: %q (must be *, scheme://host[:port], or scheme://*.host[:port])", origin))
+		}
+	}
+	if c.CORSAllowCredentials && slices.Contains(c.AllowedOrigins, "*") {
+		errors = append(errors, "CORS_ALLOW_CREDENTIALS needs explicit ALLOWED_ORIGINS (not *)")
+	}
+	for _, method := range c.CORSAllowedMethods {
+		if !isToken(method) {
+			errors = append(errors, fmt.Sprintf("invalid CORS_ALLOWED_METHODS: %q", method))
+		}
+	}
+	for _, list := range []struct {
+		env     string
+		headers []string
+	}{
+		{"CORS_ALLOWED_HEADERS", c.CORSAllowedHeaders},
+		{"CORS_EXPOSED_HEADERS", c.CORSExposedHeaders},
+	} {
+		for _, header := range list.headers {
+			if !isToken(header) {
+				errors = append(errors, fmt.Sprintf("invalid %s: %q (not a header name)", list.env, header))
+			}
+		}
+	}
+	if c.ReferrerPolicy != "" && !validReferrerPolicies[c.ReferrerPolicy] {
+		errors = append(errors, fmt.Sprintf("invalid REFERRER_POLICY: %s", c.ReferrerPolicy))
+	}
+	if strings.ContainsAny(c.ContentSecurityPolicy, "\r\n") {
+		errors = append(errors, "invalid CONTENT_SECURITY_POLICY: must be a single line")
+	}
+	for _, list := range []struct {
+		env string
+		ips []string
+	}{
+		{"ACCESS_DENY_IPS", c.AccessDenyIPs},
+		{"ACCESS_ALLOW_IPS", c.AccessAllowIPs},
+		{"TRUSTED_PROXIES", c.TrustedProxies},
+	} {
+		for _, ip := range list.ips {
+			if !validIPOrPrefix(ip) {
+				errors = append(errors, fmt.Sprintf("invalid %s: %q (not an IP or CIDR range)", list.env, ip))
+			}
+		}
+	}
+
+	// Redirect cap must be sane
+	if c.MaxRedirects < 0 || c.MaxRedirects > 20 {
+		errors = append(errors, fmt.Sprintf("invalid MAX_REDIRECTS: %d (must be 0-20)", c.MaxRedirects))
+	}
+
+	// Async pool sizes can't be negative (0 means use the worker default)
+	if c.AsyncWorkers < 0 {
+		errors = append(errors, fmt.Sprintf("invalid ASYNC_WORKERS: %d (must not be negative)", c.AsyncWorkers))
+	}
+	if c.AsyncQueueSize < 0 {
+		errors = append(errors, fmt.Sprintf("invalid ASYNC_QUEUE_SIZE: %d (must not be negative)", c.AsyncQueueSize))
+	}
+	if c.AsyncThresholdBytes < 0 {
+		errors = append(errors, fmt.Sprintf("invalid ASYNC_THRESHOLD_BYTES: %d (must not be negative)", c.AsyncThresholdBytes))
+	}
+
+	// Response size limit can't be negative (0 means no limit)
+	if c.MaxResponseSize < 0 {
+		errors = append(errors, fmt.Sprintf("invalid MAX_RESPONSE_SIZE: %d (must not be negative)", c.MaxResponseSize))
+	}
+
+	// A captured body is logged whole, so keep it small
+	if c.PanicBodyBytes < 0 || c.PanicBodyBytes > 64*1024 {
+		errors = append(errors, fmt.Sprintf("invalid PANIC_LOG_BODY_BYTES: %d (must be 0-65536)", c.PanicBodyBytes))
+	}
+
+	if c.LogFormat != "" && c.LogFormat != "json" && c.LogFormat != "text" {
+		errors = append(errors, fmt.Sprintf("invalid LOG_FORMAT: %s (must be json or text)", c.LogFormat))
+	}
+	if c.LogTimeFormat != "" && c.LogTimeFormat != "rfc3339" && c.LogTimeFormat != "rfc3339nano" {
+		errors = append(errors, fmt.Sprintf("invalid LOG_TIME_FORMAT: %s (must be rfc3339 or rfc3339nano)", c.LogTimeFormat))
+	}
+
+	if c.LogMaxValueLength < 0 {
+		errors = append(errors, fmt.Sprintf("invalid LOG_MAX_VALUE_LENGTH: %d (must not be negative, 0 = no limit)", c.LogMaxValueLength))
+	}
+
+	if c.LogSampleBurst < 0 || c.LogSampleRate < 0 {
+		errors = append(errors, fmt.Sprintf("invalid LOG_SAMPLE_BURST/LOG_SAMPLE_RATE: %d/%d (must not be negative)", c.LogSampleBurst, c.LogSampleRate))
+	}
+
+	if c.RateLimitBurst < 0 {
+		errors = append(errors, fmt.Sprintf("invalid RATE_LIMIT_BURST: %d (must not be negative, 0 = the per-minute limit)", c.RateLimitBurst))
+	}
+	if c.RateLimitMaxClients < 0 {
+		errors = append(errors, fmt.Sprintf("invalid RATE_LIMIT_MAX_CLIENTS: %d (must not be negative)", c.RateLimitMaxClients))
+	}
+	if c.RequestTimeout < 0 {
+		errors = append(errors, fmt.Sprintf("invalid REQUEST_TIMEOUT: %s (must not be negative, 0 = no limit)", c.RequestTimeout))
+	}
+	for _, contentType := range sortedKeys(c.RequestTimeoutBudgets) {
+		if d := c.RequestTimeoutBudgets[contentType]; d <= 0 {
+			errors = append(errors, fmt.Sprintf("invalid REQUEST_TIMEOUT_BUDGETS: %s=%s (must be positive)", contentType, d))
+		}
+	}
+	if c.MaxConcurrent < 0 {
+		errors = append(errors, fmt.Sprintf("invalid MAX_CONCURRENT_REQUESTS: %d (must not be negative, 0 = no limit)", c.MaxConcurrent))
+	}
+	if c.MaxConcurrentWait < 0 {
+		errors = append(errors, fmt.Sprintf("invalid MAX_CONCURRENT_WAIT: %s (must not be negative)", c.MaxConcurrentWait))
+	}
+	if c.ShutdownTimeout < 0 {
+		errors = append(errors, fmt.Sprintf("invalid SHUTDOWN_TIMEOUT: %s (must not be negative)", c.ShutdownTimeout))
+	}
+	for _, contentType := range sortedKeys(c.MaxConcurrentPools) {
+		if n := c.MaxConcurrentPools[contentType]; n <= 0 {
+			errors = append(errors, fmt.Sprintf("invalid MAX_CONCURRENT_POOLS: %s=%d (must be positive)", contentType, n))
+		}
+	}
+	for _, pattern := range sortedKeys(c.RateLimits) {
+		if limit := c.RateLimits[pattern]; limit <= 0 {
+			errors = append(errors, fmt.Sprintf("invalid RATE_LIMITS: %s=%d (must be positive)", pattern, limit))
+		}
+	}
+
+	if c.RetentionDays < 0 {
+		errors = append(errors, fmt.Sprintf("invalid RETENTION_DAYS: %d (must not be negative, 0 = keep forever)", c.RetentionDays))
+	}
+
+	// Memory storage limits can't be negative (0 means no limit)
+	if c.MemoryMaxJobs < 0 {
+		errors = append(errors, fmt.Sprintf("invalid MEMORY_MAX_JOBS: %d (must not be negative)", c.MemoryMaxJobs))
+	}
+	if c.MemoryMaxAge < 0 {
+		errors = append(errors, fmt.Sprintf("invalid MEMORY_MAX_AGE: %s (must not be negative)", c.MemoryMaxAge))
+	}
+
+	// Page limits can't be negative (0 means use the service default)
+	if c.PageMaxAssets < 0 {
+		errors = append(errors, fmt.Sprintf("invalid PAGE_MAX_ASSETS: %d (must not be negative)", c.PageMaxAssets))
+	}
+	if c.PageByteBudget < 0 {
+		errors = append(errors, fmt.Sprintf("invalid PAGE_BYTE_BUDGET: %d (must not be negative)", c.PageByteBudget))
+	}
+
+	if c.UsageMonthlyQuota < 0 {
+		errors = append(errors, fmt.Sprintf("invalid USAGE_MONTHLY_QUOTA: %d (must not be negative, 0 = unlimited)", c.UsageMonthlyQuota))
+	}
+
+	// A short admin token could be guessed
+	if c.AdminToken != "" && len(c.AdminToken) < minAdminTokenLength {
+		errors = append(errors, fmt.Sprintf("ADMIN_TOKEN must be at least %d characters", minAdminTokenLength))
+	}
+
+	// Sightengine needs both halves of its credential
+	if c.SightengineAPIKey != "" && !strings.Contains(c.SightengineAPIKey, ":") {
+		errors = append(errors, "SIGHTENGINE_API_KEY must be api_user:api_secret")
+	}
+
+	// Aggregation strategy must be one the detector knows
+	validStrategies := map[string]bool{"": true, "weighted-mean": true, "max": true, "median": true, "veto": true}
+	if !validStrategies[c.AggregationStrategy] {
+		errors = append(errors, fmt.Sprintf("invalid AGGREGATION_STRATEGY: %s (must be weighted-mean, max, median, or veto)", c.AggregationStrategy))
+	}
+
+	// Thresholds are AI scores, and the uncertain band can't be inverted.
+	// The ranges are written so NaN, which compares false, fails them.
+	if !(c.AIThreshold >= 0 && c.AIThreshold <= 1) {
+		errors = append(errors, fmt.Sprintf("invalid AI_THRESHOLD: %g (must be 0-1)", c.AIThreshold))
+	}
+	if !(c.HumanThreshold >= 0 && c.HumanThreshold <= 1) {
+		errors = append(errors, fmt.Sprintf("invalid HUMAN_THRESHOLD: %g (must be 0-1)", c.HumanThreshold))
+	}
+	if c.HumanThreshold > c.AIThreshold {
+		errors = append(errors, fmt.Sprintf("HUMAN_THRESHOLD (%g) must not be above AI_THRESHOLD (%g)", c.HumanThreshold, c.AIThreshold))
+	}
+	if !(c.UncertaintyThreshold >= 0 && c.UncertaintyThreshold <= 0.5) {
+		errors = append(errors, fmt.Sprintf("invalid UNCERTAINTY_THRESHOLD: %g (must be 0-0.5)", c.UncertaintyThreshold))
+	}
+	if !(c.DisagreementThreshold >= 0 && c.DisagreementThreshold <= 0.5) {
+		errors = append(errors, fmt.Sprintf("invalid DISAGREEMENT_THRESHOLD: %g (must be 0-0.5)", c.DisagreementThreshold))
+	}
+	if !(c.LocalOnlyConfidenceCap >= 0 && c.LocalOnlyConfidenceCap <= 1) {
+		errors = append(errors, fmt.Sprintf("invalid LOCAL_ONLY_CONFIDENCE_CAP: %g (must be 0-1)", c.LocalOnlyConfidenceCap))
+	}
+
+	// Provider weights must be finite and positive, and for a detector
+	// that exists; a misspelt name would otherwise be ignored
+	for _, name := range sortedKeys(c.ProviderWeights) {
+		if !knownDetector(name) {
+			errors = append(errors, fmt.Sprintf("invalid PROVIDER_WEIGHTS: unknown detector %q (must be one of %s)", name, strings.Join(service.DetectorNames(), ", ")))
+		} else if w := c.ProviderWeights[name]; !(w > 0 && w <= 10) {
+			errors = append(errors, fmt.Sprintf("invalid PROVIDER_WEIGHTS: %s=%g (must be greater than 0 and at most 10)", name, w))
+		}
+	}
+
+	// A provider's own timeout can only shorten the shared one
+	if c.DetectorTimeout < 0 {
+		errors = append(errors, fmt.Sprintf("invalid DETECTOR_TIMEOUT: %s (must not be negative, 0 = no limit)", c.DetectorTimeout))
+	}
+	for _, name := range sortedKeys(c.ProviderTimeouts) {
+		if d := c.ProviderTimeouts[name]; d <= 0 || (c.DetectorTimeout > 0 && d > c.DetectorTimeout) {
+			errors = append(errors, fmt.Sprintf("invalid detectors.providers.%s.timeout in %s: %s (must be positive and at most DETECTOR_TIMEOUT, %s)", name, c.ConfigFile, d, c.DetectorTimeout))
+		}
+	}
+	for _, name := range sortedKeys(c.ProviderURLs) {
+		if _, ok := providerKeyEnv[name]; !ok {
+			errors = append(errors, fmt.Sprintf("invalid PROVIDER_URLS: unknown provider %q", name))
+		} else if u, err := url.Parse(c.ProviderURLs[name]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errors = append(errors, fmt.Sprintf("invalid PROVIDER_URLS: %s=%s (must be an absolute http or https URL)", name, c.ProviderURLs[name]))
+		}
+	}
+
+	// Outbound connections go through at most one proxy, with sane pools
+	if c.OutboundProxy != "" {
+		u, err := url.Parse(c.OutboundProxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+			errors = append(errors, "invalid OUTBOUND_PROXY: must be an http, https or socks5 URL")
+		}
+	}
+	if c.OutboundMaxIdleConns < 0 || c.OutboundMaxIdleConnsPerHost < 0 || c.OutboundMaxConnsPerHost < 0 {
+		errors = append(errors, fmt.Sprintf("invalid OUTBOUND_MAX_IDLE_CONNS/OUTBOUND_MAX_IDLE_CONNS_PER_HOST/OUTBOUND_MAX_CONNS_PER_HOST: %d/%d/%d (must not be negative)",
+			c.OutboundMaxIdleConns, c.OutboundMaxIdleConnsPerHost, c.OutboundMaxConnsPerHost))
+	}
+
+	// Profiles are picked by name in a query parameter, and may only name
+	// detectors that exist
+	for _, name := range sortedKeys(c.Profiles) {
+		p := c.Profiles[name]
+		if !isToken(name) {
+			errors = append(errors, fmt.Sprintf("invalid profile name in %s: %q (must be a single word)", c.ConfigFile, name))
+		}
+		if p.Mode != "" && p.Mode != "email" && p.Mode != "academic" {
+			errors = append(errors, fmt.Sprintf("invalid profiles.%s.mode in %s: %s (must be email or academic)", name, c.ConfigFile, p.Mode))
+		}
+		for _, list := range []struct {
+			key   string
+			names []string
+		}{
+			{"detectors", p.Detectors},
+			{"exclude_detectors", p.ExcludeDetectors},
+		} {
+			for _, detector := range list.names {
+				if !knownDetector(detector) {
+					errors = append(errors, fmt.Sprintf("invalid profiles.%s.%s in %s: unknown detector %q", name, list.key, c.ConfigFile, detector))
+				}
+			}
+		}
+	}
+
+	// A short secret makes signatures guessable
+	if c.WebhookSecret != "" && len(c.WebhookSecret) < 16 {
+		errors = append(errors, "WEBHOOK_SECRET too short (minimum 16 characters)")
+	}
+
+	// Certificate keys are parsed again at startup; a bad one fails here
+	if c.CertificateKey != "" {
+		if _, err := cert.ParsePrivateKey(c.CertificateKey); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid CERTIFICATE_KEY: %v", err))
+		}
+	}
+	for _, key := range c.CertificateOldKeys {
+		if _, err := cert.ParsePublicKey(key); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid CERTIFICATE_OLD_KEYS: %v", err))
+		}
 	}
 
 	// At least one detection backend should be configured
-	if c.HiveAPIKey == "" && c.OpenAIAPIKey == "" && c.GPTZeroAPIKey == "" {
+	if !c.HasDetectionAPIKey() {
 		// This is a warning, not an error - we can still run with mock detection
 		// In production this would be an error
 		if c.IsProduction() {
-			errors = append(errors, "at least one detection API key is required (HIVE_API_KEY, OPENAI_API_KEY, or GPTZERO_API_KEY)")
+			errors = append(errors, "at least one detection API key is required (HIVE_API_KEY, OPENAI_API_KEY, GPTZERO_API_KEY, SIGHTENGINE_API_KEY, AIORNOT_API_KEY, ANTHROPIC_API_KEY, ORIGINALITY_API_KEY, or WINSTON_API_KEY)")
 		}
 	}
 
@@ -135,14 +881,47 @@ func (c *Config) Validate() error {
 	if c.MaxUploadSize > 1024*1024*1024 { // More than 1GB
 		errors = append(errors, fmt.Sprintf("MAX_UPLOAD_SIZE too large: %d (maximum 1GB)", c.MaxUploadSize))
 	}
+	if c.MaxUploadFiles < 0 || c.MaxUploadFiles > 100 {
+		errors = append(errors, fmt.Sprintf("invalid MAX_UPLOAD_FILES: %d (must be 0-100, 0 = the default)", c.MaxUploadFiles))
+	}
+	if c.UploadMemoryLimit < 0 {
+		errors = append(errors, fmt.Sprintf("invalid UPLOAD_MEMORY_LIMIT: %d (must not be negative)", c.UploadMemoryLimit))
+	}
 
 	if len(errors) > 0 {
+		for i, msg := range errors {
+			errors[i] = c.pointAtSource(msg)
+		}
 		return fmt.Errorf("configuration errors:\n  - %s", strings.Join(errors, "\n  - "))
 	}
 
 	return nil
 }
 
+// knownDetector reports whether name is a detector the service can run.
+func knownDetector(name string) bool {
+	return service.ValidateDetectorNames([]string{name}) == nil
+}
+
+// sortedKeys returns m's keys in order, so problems are reported in a
+// stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// HasDetectionAPIKey returns true if at least one external detection
+// provider is configured.
+func (c *Config) HasDetectionAPIKey() bool {
+	return c.HiveAPIKey != "" || c.OpenAIAPIKey != "" || c.GPTZeroAPIKey != "" ||
+		c.SightengineAPIKey != "" || c.AIorNotAPIKey != "" || c.AnthropicAPIKey != "" ||
+		c.OriginalityAPIKey != "" || c.WinstonAPIKey != ""
+}
+
 // IsProduction returns true if running in production environment.
 func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
@@ -181,6 +960,27 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+// getEnvAsDuration returns the environment variable as a time.Duration or a default if not set/invalid.
+// Accepts Go duration strings, e.g. 90s, 30m, 24h.
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsFloat returns the environment variable as a float64 or a default if not set/invalid.
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 // getEnvAsBool returns the environment variable as a boolean or a default if not set.
 // Accepts: true, false, 1, 0, yes, no (case-insensitive)
 func getEnvAsBool(key string, defaultValue bool) bool {
@@ -211,3 +1011,200 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	}
 	return defaultValue
 }
+
+// parseWeights parses "name=weight,name=weight" into a map.
+func parseWeights(value string) (map[string]float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=weight", part)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q has an invalid weight", part)
+		}
+		weights[strings.ToLower(strings.TrimSpace(name))] = w
+	}
+	return weights, nil
+}
+
+// parseRateLimits parses "PATTERN=limit,PATTERN=limit" into a map, where
+// PATTERN is "[METHOD ]PATH" as registered with http.ServeMux.
+func parseRateLimits(value string) (map[string]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	limits := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// Paths can't contain "=", so the limit follows the last one
+		i := strings.LastIndex(part, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not pattern=limit", part)
+		}
+		fields := strings.Fields(part[:i])
+		switch {
+		case len(fields) == 0 || len(fields) > 2:
+			return nil, fmt.Errorf("%q is not [METHOD ]PATH=limit", part)
+		case len(fields) == 2 && strings.ToUpper(fields[0]) != fields[0]:
+			return nil, fmt.Errorf("%q has a lower case method", part)
+		case !strings.HasPrefix(fields[len(fields)-1], "/"):
+			return nil, fmt.Errorf("%q has a path not starting with /", part)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(part[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%q has an invalid limit", part)
+		}
+		limits[strings.Join(fields, " ")] = limit
+	}
+	return limits, nil
+}
+
+// parseDurations parses "name=duration,name=duration" into a map.
+func parseDurations(value string) (map[string]time.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	durations := make(map[string]time.Duration)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=duration", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%q has an invalid duration", part)
+		}
+		durations[strings.ToLower(strings.TrimSpace(name))] = d
+	}
+	return durations, nil
+}
+
+// parseLevels parses "component=level,component=level" into a map.
+func parseLevels(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	levels := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, level, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not component=level", part)
+		}
+		level = strings.ToLower(strings.TrimSpace(level))
+		switch level {
+		case "debug", "info", "warn", "warning", "error":
+		default:
+			return nil, fmt.Errorf("%q has an invalid level (must be debug, info, warn or error)", part)
+		}
+		levels[strings.ToLower(strings.TrimSpace(name))] = level
+	}
+	return levels, nil
+}
+
+// parseURLs parses "name=url,name=url" into a map.
+func parseURLs(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	urls := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// URLs can contain "=", so the name ends at the first one
+		name, raw, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(raw) == "" {
+			return nil, fmt.Errorf("%q is not name=url", part)
+		}
+		urls[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(raw)
+	}
+	return urls, nil
+}
+
+// parseCounts parses "name=n,name=n" into a map.
+func parseCounts(value string) (map[string]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	counts := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=count", part)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%q has an invalid count", part)
+		}
+		counts[strings.ToLower(strings.TrimSpace(name))] = n
+	}
+	return counts, nil
+}
+
+// validOrigin reports whether origin is scheme://host[:port], where the
+// host may start with a "*." subdomain wildcard.
+func validOrigin(origin string) bool {
+	// The wildcard stands in for a subdomain
+	candidate := strings.Replace(origin, "://*.", "://wildcard.", 1)
+	u, err := url.Parse(candidate)
+	if err != nil || u.Scheme == "" || u.Host == "" || strings.Contains(u.Host, "*") {
+		return false
+	}
+	// Nothing but the scheme and host: no path, query or user info
+	return u.Scheme+"://"+u.Host == candidate
+}
+
+// validIPOrPrefix reports whether s is an IP address or a CIDR range.
+func validIPOrPrefix(s string) bool {
+	if strings.Contains(s, "/") {
+		_, err := netip.ParsePrefix(s)
+		return err == nil
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// isToken reports whether s is an HTTP token (RFC 9110), as method and
+// header names must be.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"math"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestLoad verifies that configuration loads correctly from environment variables.
@@ -29,7 +32,61 @@ func TestLoad(t *testing.T) {
 		assertEqual(t, "Environment", cfg.Environment, "development")
 		assertEqual(t, "Port", cfg.Port, 8080)
 		assertEqual(t, "MaxUploadSize", cfg.MaxUploadSize, int64(100*1024*1024))
+		assertEqual(t, "UploadMemoryLimit", cfg.UploadMemoryLimit, int64(8*1024*1024))
 		assertEqual(t, "RateLimitPerMinute", cfg.RateLimitPerMinute, 60)
+		assertEqual(t, "RateLimitBurst", cfg.RateLimitBurst, 0)
+		assertEqual(t, "RateLimitMaxClients", cfg.RateLimitMaxClients, 100000)
+		assertEqual(t, "RequestTimeout", cfg.RequestTimeout, 30*time.Second)
+		assertEqual(t, "video budget", cfg.RequestTimeoutBudgets["video"], 2*time.Minute)
+		assertEqual(t, "audio budget", cfg.RequestTimeoutBudgets["audio"], time.Minute)
+		assertEqual(t, "MaxConcurrent", cfg.MaxConcurrent, 64)
+		assertEqual(t, "MaxConcurrentWait", cfg.MaxConcurrentWait, 2*time.Second)
+		assertEqual(t, "ShutdownTimeout", cfg.ShutdownTimeout, 30*time.Second)
+		assertEqual(t, "video pool", cfg.MaxConcurrentPools["video"], 4)
+		assertEqual(t, "audio pool", cfg.MaxConcurrentPools["audio"], 8)
+		assertEqual(t, "AllowPrivateURLs", cfg.AllowPrivateURLs, false)
+		assertEqual(t, "MaxRedirects", cfg.MaxRedirects, 5)
+		assertEqual(t, "AsyncWorkers", cfg.AsyncWorkers, 4)
+		assertEqual(t, "AsyncQueueSize", cfg.AsyncQueueSize, 100)
+		assertEqual(t, "AsyncThresholdBytes", cfg.AsyncThresholdBytes, int64(50*1024*1024))
+		assertEqual(t, "MaxResponseSize", cfg.MaxResponseSize, int64(1024*1024))
+		assertEqual(t, "PanicBodyBytes", cfg.PanicBodyBytes, 0)
+		assertEqual(t, "LogRedactKeys", len(cfg.LogRedactKeys), 0)
+		assertEqual(t, "LogMaxValueLength", cfg.LogMaxValueLength, 8192)
+		assertEqual(t, "LogLevels", len(cfg.LogLevels), 0)
+		assertEqual(t, "LogSampleBurst", cfg.LogSampleBurst, 0)
+		assertEqual(t, "LogSampleRate", cfg.LogSampleRate, 100)
+		assertEqual(t, "LogLevel", cfg.LogLevel, "info")
+		assertEqual(t, "LogFormat", cfg.LogFormat, "")
+		assertEqual(t, "LogTimeFormat", cfg.LogTimeFormat, "")
+		assertEqual(t, "DetectorTimeout", cfg.DetectorTimeout, 30*time.Second)
+		assertEqual(t, "ConfigFile", cfg.ConfigFile, "")
+		assertEqual(t, "ProviderURLs", len(cfg.ProviderURLs), 0)
+		assertEqual(t, "OutboundProxy", cfg.OutboundProxy, "")
+		assertEqual(t, "OutboundInsecureSkipVerify", cfg.OutboundInsecureSkipVerify, false)
+		assertEqual(t, "OutboundMaxIdleConns", cfg.OutboundMaxIdleConns, 100)
+		assertEqual(t, "OutboundMaxIdleConnsPerHost", cfg.OutboundMaxIdleConnsPerHost, 10)
+		assertEqual(t, "OutboundMaxConnsPerHost", cfg.OutboundMaxConnsPerHost, 0)
+		assertEqual(t, "MaxUploadFiles", cfg.MaxUploadFiles, 10)
+		assertEqual(t, "MemoryMaxJobs", cfg.MemoryMaxJobs, 100000)
+		assertEqual(t, "MemoryMaxAge", cfg.MemoryMaxAge, 24*time.Hour)
+		assertEqual(t, "AggregationStrategy", cfg.AggregationStrategy, "weighted-mean")
+		assertEqual(t, "AIThreshold", cfg.AIThreshold, 0.5)
+		assertEqual(t, "HumanThreshold", cfg.HumanThreshold, 0.5)
+		assertEqual(t, "UncertaintyThreshold", cfg.UncertaintyThreshold, 0.15)
+		assertEqual(t, "DisagreementThreshold", cfg.DisagreementThreshold, 0.25)
+		assertEqual(t, "LocalOnlyConfidenceCap", cfg.LocalOnlyConfidenceCap, 0.6)
+		assertEqual(t, "ValidateResponseSchema", cfg.ValidateResponseSchema, false)
+		assertEqual(t, "CORSAllowedMethods", strings.Join(cfg.CORSAllowedMethods, ","), "GET,POST,DELETE,OPTIONS")
+		assertEqual(t, "CORSExposedHeaders", cfg.CORSExposedHeaders[0], "X-Request-ID")
+		assertEqual(t, "CORSAllowCredentials", cfg.CORSAllowCredentials, false)
+		assertEqual(t, "ReferrerPolicy", cfg.ReferrerPolicy, "no-referrer")
+		if !strings.Contains(cfg.ContentSecurityPolicy, "frame-ancestors 'none'") {
+			t.Errorf("ContentSecurityPolicy = %q, want framing forbidden", cfg.ContentSecurityPolicy)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("defaults don't validate: %v", err)
+		}
 	})
 
 	t.Run("loads values from environment", func(t *testing.T) {
@@ -40,7 +97,48 @@ func TestLoad(t *testing.T) {
 		os.Setenv("HIVE_API_KEY", "test-hive-key")
 		os.Setenv("MAX_UPLOAD_SIZE", "52428800") // 50MB
 		os.Setenv("RATE_LIMIT_PER_MINUTE", "120")
+		os.Setenv("RATE_LIMIT_BURST", "20")
+		os.Setenv("RATE_LIMIT_MAX_CLIENTS", "5000")
+		os.Setenv("REQUEST_TIMEOUT", "45s")
+		os.Setenv("REQUEST_TIMEOUT_BUDGETS", "Video=5m, document=90s")
+		os.Setenv("MAX_CONCURRENT_REQUESTS", "16")
+		os.Setenv("MAX_CONCURRENT_WAIT", "0s")
+		os.Setenv("SHUTDOWN_TIMEOUT", "1m")
+		os.Setenv("MAX_CONCURRENT_POOLS", "video=2")
 		os.Setenv("ALLOWED_ORIGINS", "https://example.com,https://app.example.com")
+		os.Setenv("CORS_ALLOWED_METHODS", "GET,POST")
+		os.Setenv("CORS_ALLOWED_HEADERS", "Content-Type, X-API-Key")
+		os.Setenv("CORS_EXPOSED_HEADERS", "X-Request-ID")
+		os.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+		os.Setenv("CONTENT_SECURITY_POLICY", "default-src 'none'")
+		os.Setenv("REFERRER_POLICY", "strict-origin")
+		os.Setenv("ACCESS_DENY_IPS", "203.0.113.0/24, 198.51.100.7")
+		os.Setenv("ACCESS_ALLOW_IPS", "10.0.0.0/8")
+		os.Setenv("ACCESS_DENY_KEYS", "key-1")
+		os.Setenv("ACCESS_LIST_FILE", "/etc/humanmark/access.json")
+		os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 2001:db8::1")
+		os.Setenv("AI_PHRASES_FILE", "/etc/humanmark/phrases.json")
+		os.Setenv("AUDIT_LOG_FILE", "/var/log/humanmark/audit.jsonl")
+		os.Setenv("PANIC_LOG_BODY_BYTES", "4096")
+		os.Setenv("LOG_REDACT_KEYS", "email, phone")
+		os.Setenv("LOG_MAX_VALUE_LENGTH", "2048")
+		os.Setenv("LOG_LEVELS", "Video=DEBUG, http=warn")
+		os.Setenv("LOG_SAMPLE_BURST", "20")
+		os.Setenv("LOG_SAMPLE_RATE", "50")
+		os.Setenv("LOG_LEVEL", "debug")
+		os.Setenv("LOG_FORMAT", "text")
+		os.Setenv("LOG_TIME_FORMAT", "rfc3339nano")
+		os.Setenv("DETECTOR_TIMEOUT", "10s")
+		os.Setenv("PROVIDER_URLS", "Winston=https://gateway.internal/winston?route=a=b, hive=http://hive.test")
+		os.Setenv("OUTBOUND_PROXY", "http://proxy.internal:3128")
+		os.Setenv("OUTBOUND_CA_FILE", "/etc/ssl/corp-ca.pem")
+		os.Setenv("OUTBOUND_MAX_IDLE_CONNS", "50")
+		os.Setenv("OUTBOUND_MAX_IDLE_CONNS_PER_HOST", "5")
+		os.Setenv("OUTBOUND_MAX_CONNS_PER_HOST", "20")
+		os.Setenv("MAX_UPLOAD_FILES", "5")
+		os.Setenv("UPLOAD_MEMORY_LIMIT", "1048576")
+		os.Setenv("CERTIFICATE_KEY", "hPwNAOmgqmGjmb6MOjg35mKD6QwEUhAG2IKvUmVaJBY=")
+		os.Setenv("CERTIFICATE_OLD_KEYS", "O2onvM62pC1io6jQKm8Nc2UyFXcd4kOmOsBIoYtZ2ik=")
 
 		cfg, err := Load()
 		if err != nil {
@@ -52,7 +150,53 @@ func TestLoad(t *testing.T) {
 		assertEqual(t, "DatabaseURL", cfg.DatabaseURL, "postgres://localhost/test")
 		assertEqual(t, "HiveAPIKey", cfg.HiveAPIKey, "test-hive-key")
 		assertEqual(t, "MaxUploadSize", cfg.MaxUploadSize, int64(52428800))
+		assertEqual(t, "UploadMemoryLimit", cfg.UploadMemoryLimit, int64(1048576))
 		assertEqual(t, "RateLimitPerMinute", cfg.RateLimitPerMinute, 120)
+		assertEqual(t, "RateLimitBurst", cfg.RateLimitBurst, 20)
+		assertEqual(t, "RateLimitMaxClients", cfg.RateLimitMaxClients, 5000)
+		assertEqual(t, "RequestTimeout", cfg.RequestTimeout, 45*time.Second)
+		assertEqual(t, "budget count", len(cfg.RequestTimeoutBudgets), 2)
+		assertEqual(t, "video budget", cfg.RequestTimeoutBudgets["video"], 5*time.Minute)
+		assertEqual(t, "document budget", cfg.RequestTimeoutBudgets["document"], 90*time.Second)
+		assertEqual(t, "MaxConcurrent", cfg.MaxConcurrent, 16)
+		assertEqual(t, "MaxConcurrentWait", cfg.MaxConcurrentWait, time.Duration(0))
+		assertEqual(t, "ShutdownTimeout", cfg.ShutdownTimeout, time.Minute)
+		assertEqual(t, "pool count", len(cfg.MaxConcurrentPools), 1)
+		assertEqual(t, "video pool", cfg.MaxConcurrentPools["video"], 2)
+		assertEqual(t, "CORSAllowedMethods", strings.Join(cfg.CORSAllowedMethods, ","), "GET,POST")
+		assertEqual(t, "CORSAllowedHeaders", strings.Join(cfg.CORSAllowedHeaders, ","), "Content-Type,X-API-Key")
+		assertEqual(t, "CORSExposedHeaders", strings.Join(cfg.CORSExposedHeaders, ","), "X-Request-ID")
+		assertEqual(t, "CORSAllowCredentials", cfg.CORSAllowCredentials, true)
+		assertEqual(t, "ContentSecurityPolicy", cfg.ContentSecurityPolicy, "default-src 'none'")
+		assertEqual(t, "ReferrerPolicy", cfg.ReferrerPolicy, "strict-origin")
+		assertEqual(t, "AccessDenyIPs", strings.Join(cfg.AccessDenyIPs, ","), "203.0.113.0/24,198.51.100.7")
+		assertEqual(t, "AccessAllowIPs", strings.Join(cfg.AccessAllowIPs, ","), "10.0.0.0/8")
+		assertEqual(t, "AccessDenyKeys", strings.Join(cfg.AccessDenyKeys, ","), "key-1")
+		assertEqual(t, "AccessListFile", cfg.AccessListFile, "/etc/humanmark/access.json")
+		assertEqual(t, "TrustedProxies", strings.Join(cfg.TrustedProxies, ","), "10.0.0.0/8,2001:db8::1")
+		assertEqual(t, "AIPhrasesFile", cfg.AIPhrasesFile, "/etc/humanmark/phrases.json")
+		assertEqual(t, "AuditLogFile", cfg.AuditLogFile, "/var/log/humanmark/audit.jsonl")
+		assertEqual(t, "PanicBodyBytes", cfg.PanicBodyBytes, 4096)
+		assertEqual(t, "LogRedactKeys", strings.Join(cfg.LogRedactKeys, ","), "email,phone")
+		assertEqual(t, "LogMaxValueLength", cfg.LogMaxValueLength, 2048)
+		assertEqual(t, "video log level", cfg.LogLevels["video"], "debug")
+		assertEqual(t, "http log level", cfg.LogLevels["http"], "warn")
+		assertEqual(t, "LogSampleBurst", cfg.LogSampleBurst, 20)
+		assertEqual(t, "LogSampleRate", cfg.LogSampleRate, 50)
+		assertEqual(t, "LogLevel", cfg.LogLevel, "debug")
+		assertEqual(t, "LogFormat", cfg.LogFormat, "text")
+		assertEqual(t, "LogTimeFormat", cfg.LogTimeFormat, "rfc3339nano")
+		assertEqual(t, "DetectorTimeout", cfg.DetectorTimeout, 10*time.Second)
+		assertEqual(t, "winston URL", cfg.ProviderURLs["winston"], "https://gateway.internal/winston?route=a=b")
+		assertEqual(t, "hive URL", cfg.ProviderURLs["hive"], "http://hive.test")
+		assertEqual(t, "OutboundProxy", cfg.OutboundProxy, "http://proxy.internal:3128")
+		assertEqual(t, "OutboundCAFile", cfg.OutboundCAFile, "/etc/ssl/corp-ca.pem")
+		assertEqual(t, "OutboundMaxIdleConns", cfg.OutboundMaxIdleConns, 50)
+		assertEqual(t, "OutboundMaxIdleConnsPerHost", cfg.OutboundMaxIdleConnsPerHost, 5)
+		assertEqual(t, "OutboundMaxConnsPerHost", cfg.OutboundMaxConnsPerHost, 20)
+		assertEqual(t, "MaxUploadFiles", cfg.MaxUploadFiles, 5)
+		assertEqual(t, "CertificateKey", cfg.CertificateKey, "hPwNAOmgqmGjmb6MOjg35mKD6QwEUhAG2IKvUmVaJBY=")
+		assertEqual(t, "CertificateOldKeys", strings.Join(cfg.CertificateOldKeys, ","), "O2onvM62pC1io6jQKm8Nc2UyFXcd4kOmOsBIoYtZ2ik=")
 
 		if len(cfg.AllowedOrigins) != 2 {
 			t.Errorf("AllowedOrigins: expected 2, got %d", len(cfg.AllowedOrigins))
@@ -76,6 +220,139 @@ func TestLoad(t *testing.T) {
 		assertEqual(t, "Port", cfg.Port, 8080)
 		assertEqual(t, "MaxUploadSize", cfg.MaxUploadSize, int64(100*1024*1024))
 	})
+
+	t.Run("parses provider weights", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("PROVIDER_WEIGHTS", "winston=1.3, OpenAI=0.5")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		assertEqual(t, "winston weight", cfg.ProviderWeights["winston"], 1.3)
+		assertEqual(t, "openai weight", cfg.ProviderWeights["openai"], 0.5)
+	})
+
+	t.Run("parses rate limit rules", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("RATE_LIMITS", "POST /verify/batch=10, POST  /verify=60,/admin/=5")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		assertEqual(t, "rule count", len(cfg.RateLimits), 3)
+		assertEqual(t, "batch limit", cfg.RateLimits["POST /verify/batch"], 10)
+		assertEqual(t, "verify limit", cfg.RateLimits["POST /verify"], 60)
+		assertEqual(t, "admin limit", cfg.RateLimits["/admin/"], 5)
+	})
+
+	t.Run("loads decision thresholds", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("AI_THRESHOLD", "0.8")
+		os.Setenv("HUMAN_THRESHOLD", "0.25")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		assertEqual(t, "AIThreshold", cfg.AIThreshold, 0.8)
+		assertEqual(t, "HumanThreshold", cfg.HumanThreshold, 0.25)
+	})
+
+	t.Run("loads memory storage limits", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("MEMORY_MAX_JOBS", "500")
+		os.Setenv("MEMORY_MAX_AGE", "90m")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		assertEqual(t, "MemoryMaxJobs", cfg.MemoryMaxJobs, 500)
+		assertEqual(t, "MemoryMaxAge", cfg.MemoryMaxAge, 90*time.Minute)
+
+		// An unparseable duration falls back to the default
+		os.Setenv("MEMORY_MAX_AGE", "a day")
+		cfg, err = Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+		assertEqual(t, "MemoryMaxAge", cfg.MemoryMaxAge, 24*time.Hour)
+	})
+
+	t.Run("rejects malformed provider weights", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("PROVIDER_WEIGHTS", "winston:1.3")
+
+		if _, err := Load(); err == nil {
+			t.Error("Load() should reject malformed PROVIDER_WEIGHTS")
+		}
+	})
+
+	t.Run("rejects malformed timeout budgets", func(t *testing.T) {
+		for _, value := range []string{"video", "video=2 minutes"} {
+			os.Clearenv()
+			os.Setenv("REQUEST_TIMEOUT_BUDGETS", value)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() should reject REQUEST_TIMEOUT_BUDGETS=%q", value)
+			}
+		}
+	})
+
+	t.Run("rejects malformed concurrency pools", func(t *testing.T) {
+		for _, value := range []string{"video", "video=four"} {
+			os.Clearenv()
+			os.Setenv("MAX_CONCURRENT_POOLS", value)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() should reject MAX_CONCURRENT_POOLS=%q", value)
+			}
+		}
+	})
+
+	t.Run("rejects malformed log levels", func(t *testing.T) {
+		for _, value := range []string{"video", "video=verbose"} {
+			os.Clearenv()
+			os.Setenv("LOG_LEVELS", value)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() should reject LOG_LEVELS=%q", value)
+			}
+		}
+	})
+
+	t.Run("reports every malformed setting", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("PROVIDER_WEIGHTS", "winston:1.3")
+		os.Setenv("LOG_LEVELS", "video=verbose")
+
+		_, err := Load()
+		if err == nil {
+			t.Fatal("Load() should reject malformed settings")
+		}
+		for _, want := range []string{"invalid PROVIDER_WEIGHTS", "invalid LOG_LEVELS"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error should mention %q: %v", want, err)
+			}
+		}
+	})
+
+	t.Run("rejects malformed rate limit rules", func(t *testing.T) {
+		for _, value := range []string{"POST /verify", "verify=10", "post /verify=10", "POST /verify=ten", "GET POST /verify=10"} {
+			os.Clearenv()
+			os.Setenv("RATE_LIMITS", value)
+
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() should reject RATE_LIMITS=%q", value)
+			}
+		}
+	})
 }
 
 // TestValidate verifies configuration validation.
@@ -156,6 +433,528 @@ func TestValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("rejects private URLs in production", func(t *testing.T) {
+		cfg := &Config{
+			Environment:      "production",
+			Port:             8080,
+			MaxUploadSize:    100 * 1024 * 1024,
+			DatabaseURL:      "postgres://localhost/test",
+			AllowedOrigins:   []string{"https://example.com"},
+			HiveAPIKey:       "test-key",
+			AllowPrivateURLs: true,
+		}
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Error("Validate() should reject ALLOW_PRIVATE_URLS in production")
+		}
+	})
+
+	t.Run("rejects unknown aggregation strategy", func(t *testing.T) {
+		cfg := &Config{
+			Environment:         "development",
+			Port:                8080,
+			MaxUploadSize:       100 * 1024 * 1024,
+			AggregationStrategy: "average",
+		}
+
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject unknown AGGREGATION_STRATEGY")
+		}
+	})
+
+	t.Run("accepts uncertain band", func(t *testing.T) {
+		cfg := &Config{
+			Environment:    "development",
+			Port:           8080,
+			MaxUploadSize:  100 * 1024 * 1024,
+			AIThreshold:    0.8,
+			HumanThreshold: 0.3,
+		}
+
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() returned error for valid thresholds: %v", err)
+		}
+	})
+
+	t.Run("rejects invalid thresholds", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			ai, human float64
+		}{
+			{"AI threshold above 1", 1.5, 0.5},
+			{"negative human threshold", 0.5, -0.1},
+			{"human above AI", 0.4, 0.6},
+			{"NaN AI threshold", math.NaN(), 0.5},
+			{"NaN human threshold", 0.5, math.NaN()},
+		}
+
+		for _, tt := range tests {
+			cfg := &Config{
+				Environment:    "development",
+				Port:           8080,
+				MaxUploadSize:  100 * 1024 * 1024,
+				AIThreshold:    tt.ai,
+				HumanThreshold: tt.human,
+			}
+
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject %s", tt.name)
+			}
+		}
+	})
+
+	t.Run("rejects out of range uncertainty threshold", func(t *testing.T) {
+		cfg := &Config{
+			Environment:          "development",
+			Port:                 8080,
+			MaxUploadSize:        100 * 1024 * 1024,
+			UncertaintyThreshold: 0.7,
+		}
+
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject UNCERTAINTY_THRESHOLD above 0.5")
+		}
+	})
+
+	t.Run("rejects out of range local-only confidence cap", func(t *testing.T) {
+		cfg := &Config{
+			Environment:            "development",
+			Port:                   8080,
+			MaxUploadSize:          100 * 1024 * 1024,
+			LocalOnlyConfidenceCap: 1.2,
+		}
+
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject LOCAL_ONLY_CONFIDENCE_CAP above 1")
+		}
+	})
+
+	t.Run("rejects negative memory storage limits", func(t *testing.T) {
+		cfg := &Config{
+			Environment:   "development",
+			Port:          8080,
+			MaxUploadSize: 100 * 1024 * 1024,
+			MemoryMaxJobs: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject negative MEMORY_MAX_JOBS")
+		}
+
+		cfg.MemoryMaxJobs = 0
+		cfg.MemoryMaxAge = -time.Hour
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject negative MEMORY_MAX_AGE")
+		}
+	})
+
+	t.Run("rejects short admin token", func(t *testing.T) {
+		cfg := &Config{
+			Environment:   "development",
+			Port:          8080,
+			MaxUploadSize: 100 * 1024 * 1024,
+			AdminToken:    "hunter2",
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject an ADMIN_TOKEN shorter than 32 characters")
+		}
+
+		cfg.AdminToken = strings.Repeat("x", 32)
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() rejected a 32-character ADMIN_TOKEN: %v", err)
+		}
+	})
+
+	t.Run("rejects negative usage quota", func(t *testing.T) {
+		cfg := &Config{
+			Environment:       "development",
+			Port:              8080,
+			MaxUploadSize:     100 * 1024 * 1024,
+			UsageMonthlyQuota: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject negative USAGE_MONTHLY_QUOTA")
+		}
+	})
+
+	t.Run("rejects negative rate limit burst", func(t *testing.T) {
+		cfg := &Config{
+			Environment:    "development",
+			Port:           8080,
+			MaxUploadSize:  100 * 1024 * 1024,
+			RateLimitBurst: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject negative RATE_LIMIT_BURST")
+		}
+	})
+
+	t.Run("rejects negative rate limit client cap", func(t *testing.T) {
+		cfg := &Config{
+			Environment:         "development",
+			Port:                8080,
+			MaxUploadSize:       100 * 1024 * 1024,
+			RateLimitMaxClients: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject negative RATE_LIMIT_MAX_CLIENTS")
+		}
+	})
+
+	t.Run("rejects negative request timeout", func(t *testing.T) {
+		cfg := &Config{
+			Environment:    "development",
+			Port:           8080,
+			MaxUploadSize:  100 * 1024 * 1024,
+			RequestTimeout: -time.Second,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject negative REQUEST_TIMEOUT")
+		}
+	})
+
+	t.Run("rejects non-positive timeout budget", func(t *testing.T) {
+		cfg := &Config{
+			Environment:           "development",
+			Port:                  8080,
+			MaxUploadSize:         100 * 1024 * 1024,
+			RequestTimeoutBudgets: map[string]time.Duration{"video": 0},
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a zero REQUEST_TIMEOUT_BUDGETS duration")
+		}
+	})
+
+	t.Run("rejects negative concurrency limits", func(t *testing.T) {
+		for name, cfg := range map[string]*Config{
+			"MAX_CONCURRENT_REQUESTS": {MaxConcurrent: -1},
+			"MAX_CONCURRENT_WAIT":     {MaxConcurrentWait: -time.Second},
+			"MAX_CONCURRENT_POOLS":    {MaxConcurrentPools: map[string]int{"video": 0}},
+			"SHUTDOWN_TIMEOUT":        {ShutdownTimeout: -time.Second},
+		} {
+			cfg.Environment = "development"
+			cfg.Port = 8080
+			cfg.MaxUploadSize = 100 * 1024 * 1024
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject an invalid %s", name)
+			}
+		}
+	})
+
+	t.Run("validates CORS and security header settings", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			cfg   Config
+			valid bool
+		}{
+			{"wildcard subdomain", Config{AllowedOrigins: []string{"https://*.example.com", "http://localhost:3000"}}, true},
+			{"credentials with explicit origins", Config{AllowedOrigins: []string{"https://app.example.com"}, CORSAllowCredentials: true}, true},
+			{"credentials with any origin", Config{AllowedOrigins: []string{"*"}, CORSAllowCredentials: true}, false},
+			{"origin with a path", Config{AllowedOrigins: []string{"https://example.com/app"}}, false},
+			{"origin without a scheme", Config{AllowedOrigins: []string{"example.com"}}, false},
+			{"wildcard mid-host", Config{AllowedOrigins: []string{"https://app.*.example.com"}}, false},
+			{"bare wildcard host", Config{AllowedOrigins: []string{"https://*"}}, false},
+			{"method with a space", Config{CORSAllowedMethods: []string{"GET POST"}}, false},
+			{"allowed header with a colon", Config{CORSAllowedHeaders: []string{"X-API-Key:"}}, false},
+			{"exposed header with a slash", Config{CORSExposedHeaders: []string{"X/Request"}}, false},
+			{"unknown referrer policy", Config{ReferrerPolicy: "never"}, false},
+			{"multi-line CSP", Config{ContentSecurityPolicy: "default-src 'none'\nX-Injected: 1"}, false},
+		}
+		for _, tt := range tests {
+			tt.cfg.Environment = "development"
+			tt.cfg.Port = 8080
+			tt.cfg.MaxUploadSize = 100 * 1024 * 1024
+			if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+				t.Errorf("%s: Validate() error = %v, want valid = %v", tt.name, err, tt.valid)
+			}
+		}
+	})
+
+	t.Run("rejects out of range upload file count", func(t *testing.T) {
+		for _, n := range []int{-1, 101} {
+			cfg := &Config{
+				Environment:    "development",
+				Port:           8080,
+				MaxUploadSize:  100 * 1024 * 1024,
+				MaxUploadFiles: n,
+			}
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject MAX_UPLOAD_FILES=%d", n)
+			}
+		}
+	})
+
+	t.Run("rejects negative upload memory limit", func(t *testing.T) {
+		cfg := &Config{
+			Environment:       "development",
+			Port:              8080,
+			MaxUploadSize:     100 * 1024 * 1024,
+			UploadMemoryLimit: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a negative UPLOAD_MEMORY_LIMIT")
+		}
+	})
+
+	t.Run("rejects out of range panic body capture", func(t *testing.T) {
+		for _, n := range []int{-1, 64*1024 + 1} {
+			cfg := &Config{
+				Environment:    "development",
+				Port:           8080,
+				MaxUploadSize:  100 * 1024 * 1024,
+				PanicBodyBytes: n,
+			}
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject PANIC_LOG_BODY_BYTES=%d", n)
+			}
+		}
+	})
+
+	t.Run("rejects negative log value length", func(t *testing.T) {
+		cfg := &Config{
+			Environment:       "development",
+			Port:              8080,
+			MaxUploadSize:     100 * 1024 * 1024,
+			LogMaxValueLength: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a negative LOG_MAX_VALUE_LENGTH")
+		}
+	})
+
+	t.Run("rejects unknown log formats", func(t *testing.T) {
+		cfg := &Config{
+			Environment:   "development",
+			Port:          8080,
+			MaxUploadSize: 100 * 1024 * 1024,
+			LogFormat:     "logfmt",
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject LOG_FORMAT=logfmt")
+		}
+
+		cfg.LogFormat = "json"
+		cfg.LogTimeFormat = "unix"
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject LOG_TIME_FORMAT=unix")
+		}
+	})
+
+	t.Run("rejects negative log sampling", func(t *testing.T) {
+		cfg := &Config{
+			Environment:    "development",
+			Port:           8080,
+			MaxUploadSize:  100 * 1024 * 1024,
+			LogSampleBurst: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a negative LOG_SAMPLE_BURST")
+		}
+	})
+
+	t.Run("rejects invalid access list IPs", func(t *testing.T) {
+		for name, cfg := range map[string]*Config{
+			"ACCESS_DENY_IPS":  {AccessDenyIPs: []string{"203.0.113.0/24", "example.com"}},
+			"ACCESS_ALLOW_IPS": {AccessAllowIPs: []string{"10.0.0.0/33"}},
+			"TRUSTED_PROXIES":  {TrustedProxies: []string{"lb.internal"}},
+		} {
+			cfg.Environment = "development"
+			cfg.Port = 8080
+			cfg.MaxUploadSize = 100 * 1024 * 1024
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject an invalid %s", name)
+			}
+		}
+	})
+
+	t.Run("rejects invalid certificate keys", func(t *testing.T) {
+		for name, cfg := range map[string]*Config{
+			"CERTIFICATE_KEY":      {CertificateKey: "c2hvcnQ="},
+			"CERTIFICATE_OLD_KEYS": {CertificateOldKeys: []string{"not base64!"}},
+		} {
+			cfg.Environment = "development"
+			cfg.Port = 8080
+			cfg.MaxUploadSize = 100 * 1024 * 1024
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject an invalid %s", name)
+			}
+		}
+	})
+
+	t.Run("rejects non-positive rate limit rule", func(t *testing.T) {
+		cfg := &Config{
+			Environment:   "development",
+			Port:          8080,
+			MaxUploadSize: 100 * 1024 * 1024,
+			RateLimits:    map[string]int{"POST /verify": 0},
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a zero RATE_LIMITS limit")
+		}
+	})
+
+	t.Run("rejects negative retention", func(t *testing.T) {
+		cfg := &Config{
+			Environment:   "development",
+			Port:          8080,
+			MaxUploadSize: 100 * 1024 * 1024,
+			RetentionDays: -1,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject negative RETENTION_DAYS")
+		}
+	})
+
+	t.Run("rejects non-positive provider weight", func(t *testing.T) {
+		cfg := &Config{
+			Environment:     "development",
+			Port:            8080,
+			MaxUploadSize:   100 * 1024 * 1024,
+			ProviderWeights: map[string]float64{"winston": 0},
+		}
+
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a zero provider weight")
+		}
+	})
+
+	t.Run("rejects invalid provider weights", func(t *testing.T) {
+		for name, weights := range map[string]map[string]float64{
+			"NaN weight":       {"winston": math.NaN()},
+			"infinite weight":  {"winston": math.Inf(1)},
+			"negative weight":  {"winston": -1},
+			"unknown detector": {"wintson": 1.2},
+		} {
+			cfg := &Config{
+				Environment:     "development",
+				Port:            8080,
+				MaxUploadSize:   100 * 1024 * 1024,
+				ProviderWeights: weights,
+			}
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject %s", name)
+			}
+		}
+
+		cfg := &Config{
+			Environment:     "development",
+			Port:            8080,
+			MaxUploadSize:   100 * 1024 * 1024,
+			ProviderWeights: map[string]float64{"humanmark": 1.5, "metadata": 0.5},
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() returned error for local detector weights: %v", err)
+		}
+	})
+
+	t.Run("validates profiles", func(t *testing.T) {
+		valid := func() *Config {
+			return &Config{
+				Environment:   "development",
+				Port:          8080,
+				MaxUploadSize: 100 * 1024 * 1024,
+				ConfigFile:    "humanmark.yaml",
+				Profiles: map[string]Profile{
+					"legal": {Mode: "academic", Detectors: []string{"humanmark", "winston"}, ExcludeDetectors: []string{"metadata"}},
+				},
+			}
+		}
+		if err := valid().Validate(); err != nil {
+			t.Fatalf("Validate() returned error: %v", err)
+		}
+
+		for name, mutate := range map[string]func(c *Config){
+			"unknown detector":          func(c *Config) { c.Profiles["legal"] = Profile{Detectors: []string{"local"}} },
+			"unknown excluded detector": func(c *Config) { c.Profiles["legal"] = Profile{ExcludeDetectors: []string{"local"}} },
+			"unknown mode":              func(c *Config) { c.Profiles["legal"] = Profile{Mode: "legal"} },
+			"name with a space":         func(c *Config) { c.Profiles["legal review"] = Profile{} },
+			"empty name":                func(c *Config) { c.Profiles[""] = Profile{} },
+		} {
+			cfg := valid()
+			mutate(cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject %s", name)
+			}
+		}
+	})
+
+	t.Run("lists every problem", func(t *testing.T) {
+		cfg := &Config{
+			Environment:     "development",
+			Port:            0,
+			MaxUploadSize:   100 * 1024 * 1024,
+			AIThreshold:     math.NaN(),
+			ProviderWeights: map[string]float64{"wintson": 1.2, "winston": 0},
+		}
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Fatal("Validate() should reject an invalid config")
+		}
+		for _, want := range []string{"invalid PORT", "invalid AI_THRESHOLD", `unknown detector "wintson"`, "winston=0"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error should mention %q: %v", want, err)
+			}
+		}
+	})
+
+	t.Run("rejects invalid detector timeouts", func(t *testing.T) {
+		cfg := &Config{
+			Environment:     "development",
+			Port:            8080,
+			MaxUploadSize:   100 * 1024 * 1024,
+			DetectorTimeout: -time.Second,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a negative DETECTOR_TIMEOUT")
+		}
+
+		cfg.DetectorTimeout = 10 * time.Second
+		cfg.ProviderTimeouts = map[string]time.Duration{"winston": 20 * time.Second}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a provider timeout above DETECTOR_TIMEOUT")
+		}
+		cfg.ProviderTimeouts["winston"] = 0
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should reject a zero provider timeout")
+		}
+	})
+
+	t.Run("validates outbound HTTP settings", func(t *testing.T) {
+		valid := func() *Config {
+			return &Config{
+				Environment:   "development",
+				Port:          8080,
+				MaxUploadSize: 100 * 1024 * 1024,
+				OutboundProxy: "socks5://proxy.internal:1080",
+				ProviderURLs:  map[string]string{"winston": "https://gateway.internal/winston"},
+			}
+		}
+		if err := valid().Validate(); err != nil {
+			t.Fatalf("Validate() returned error: %v", err)
+		}
+
+		for name, mutate := range map[string]func(c *Config){
+			"proxy without a scheme": func(c *Config) { c.OutboundProxy = "proxy.internal:3128" },
+			"unknown provider URL":   func(c *Config) { c.ProviderURLs["acme"] = "https://acme.test" },
+			"relative provider URL":  func(c *Config) { c.ProviderURLs["winston"] = "/winston" },
+			"negative pool limit":    func(c *Config) { c.OutboundMaxConnsPerHost = -1 },
+			"insecure in production": func(c *Config) {
+				c.Environment = "production"
+				c.DatabaseURL = "postgres://localhost/test"
+				c.AllowedOrigins = []string{"https://example.com"}
+				c.HiveAPIKey = "key"
+				c.OutboundInsecureSkipVerify = true
+			},
+		} {
+			cfg := valid()
+			mutate(cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Errorf("Validate() should reject %s", name)
+			}
+		}
+	})
+
 	t.Run("rejects too small MaxUploadSize", func(t *testing.T) {
 		cfg := &Config{
 			Environment:   "development",
@@ -0,0 +1,466 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The config file (CONFIG_FILE) holds the same settings as the environment
+// variables, grouped into sections, plus ones that don't fit in a flat
+// string: per-provider timeouts and analyzer profiles. It's YAML, or JSON,
+// which is read the same way:
+//
+//	port: 8080
+//	outbound:
+//	  proxy: http://proxy.internal:3128
+//	  ca_file: /etc/ssl/corp-ca.pem
+//	detectors:
+//	  timeout: 30s
+//	  providers:
+//	    winston: {api_key: "...", weight: 1.3, timeout: 10s}
+//	decision:
+//	  ai_threshold: 0.6
+//	rate_limits:
+//	  per_minute: 120
+//	  routes: {"POST /verify/batch": 10}
+//	profiles:
+//	  legal: {mode: academic, common_words: [plaintiff, tort]}
+//
+// An environment variable that's set wins over the file, so a deployment
+// can keep its settings in a file and override one or two, e.g. secrets,
+// per instance. Unknown keys are errors, so a typo can't go unnoticed.
+
+// fileConfig is the layout of the config file. Fields are pointers, or
+// slices and maps left nil, so settings the file leaves out can be told
+// from ones it sets to zero.
+type fileConfig struct {
+	Environment            *string `yaml:"environment"`
+	Port                   *int    `yaml:"port"`
+	DatabaseURL            *string `yaml:"database_url"`
+	RedisURL               *string `yaml:"redis_url"`
+	AdminToken             *string `yaml:"admin_token"`
+	WebhookSecret          *string `yaml:"webhook_secret"`
+	APIKeyRequired         *bool   `yaml:"api_key_required"`
+	UsageMonthlyQuota      *int64  `yaml:"usage_monthly_quota"`
+	ValidateResponseSchema *bool   `yaml:"validate_response_schema"`
+	ContentSecurityPolicy  *string `yaml:"content_security_policy"`
+	ReferrerPolicy         *string `yaml:"referrer_policy"`
+	AuditLogFile           *string `yaml:"audit_log_file"`
+
+	Detectors struct {
+		Aggregation        *string                 `yaml:"aggregation"`
+		Timeout            *time.Duration          `yaml:"timeout"`
+		AllowPrivateURLs   *bool                   `yaml:"allow_private_urls"`
+		MaxRedirects       *int                    `yaml:"max_redirects"`
+		HostReputationFile *string                 `yaml:"host_reputation_file"`
+		AIPhrasesFile      *string                 `yaml:"ai_phrases_file"`
+		Providers          map[string]fileProvider `yaml:"providers"`
+	} `yaml:"detectors"`
+
+	Outbound struct {
+		Proxy               *string `yaml:"proxy"`
+		CAFile              *string `yaml:"ca_file"`
+		InsecureSkipVerify  *bool   `yaml:"insecure_skip_verify"`
+		MaxIdleConns        *int    `yaml:"max_idle_conns"`
+		MaxIdleConnsPerHost *int    `yaml:"max_idle_conns_per_host"`
+		MaxConnsPerHost     *int    `yaml:"max_conns_per_host"`
+	} `yaml:"outbound"`
+
+	Decision struct {
+		AIThreshold            *float64 `yaml:"ai_threshold"`
+		HumanThreshold         *float64 `yaml:"human_threshold"`
+		UncertaintyThreshold   *float64 `yaml:"uncertainty_threshold"`
+		DisagreementThreshold  *float64 `yaml:"disagreement_threshold"`
+		LocalOnlyConfidenceCap *float64 `yaml:"local_only_confidence_cap"`
+	} `yaml:"decision"`
+
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	RateLimits struct {
+		PerMinute  *int           `yaml:"per_minute"`
+		Burst      *int           `yaml:"burst"`
+		MaxClients *int           `yaml:"max_clients"`
+		Routes     map[string]int `yaml:"routes"`
+	} `yaml:"rate_limits"`
+
+	Requests struct {
+		Timeout            *time.Duration           `yaml:"timeout"`
+		TimeoutBudgets     map[string]time.Duration `yaml:"timeout_budgets"`
+		MaxConcurrent      *int                     `yaml:"max_concurrent"`
+		MaxConcurrentWait  *time.Duration           `yaml:"max_concurrent_wait"`
+		MaxConcurrentPools map[string]int           `yaml:"max_concurrent_pools"`
+		MaxUploadSize      *int64                   `yaml:"max_upload_size"`
+		MaxUploadFiles     *int                     `yaml:"max_upload_files"`
+		UploadMemoryLimit  *int64                   `yaml:"upload_memory_limit"`
+		MaxResponseSize    *int64                   `yaml:"max_response_size"`
+		ShutdownTimeout    *time.Duration           `yaml:"shutdown_timeout"`
+	} `yaml:"requests"`
+
+	CORS struct {
+		AllowedOrigins   []string `yaml:"allowed_origins"`
+		AllowedMethods   []string `yaml:"allowed_methods"`
+		AllowedHeaders   []string `yaml:"allowed_headers"`
+		ExposedHeaders   []string `yaml:"exposed_headers"`
+		AllowCredentials *bool    `yaml:"allow_credentials"`
+	} `yaml:"cors"`
+
+	Access struct {
+		DenyIPs  []string `yaml:"deny_ips"`
+		AllowIPs []string `yaml:"allow_ips"`
+		DenyKeys []string `yaml:"deny_keys"`
+		ListFile *string  `yaml:"list_file"`
+
+		TrustedProxies []string `yaml:"trusted_proxies"`
+	} `yaml:"access"`
+
+	Async struct {
+		Workers        *int   `yaml:"workers"`
+		QueueSize      *int   `yaml:"queue_size"`
+		ThresholdBytes *int64 `yaml:"threshold_bytes"`
+	} `yaml:"async"`
+
+	Storage struct {
+		RetentionDays *int           `yaml:"retention_days"`
+		MemoryMaxJobs *int           `yaml:"memory_max_jobs"`
+		MemoryMaxAge  *time.Duration `yaml:"memory_max_age"`
+	} `yaml:"storage"`
+
+	Page struct {
+		MaxAssets  *int   `yaml:"max_assets"`
+		ByteBudget *int64 `yaml:"byte_budget"`
+	} `yaml:"page"`
+
+	Certificates struct {
+		Key     *string  `yaml:"key"`
+		OldKeys []string `yaml:"old_keys"`
+	} `yaml:"certificates"`
+
+	Log struct {
+		Level          *string           `yaml:"level"`
+		Format         *string           `yaml:"format"`
+		TimeFormat     *string           `yaml:"time_format"`
+		Levels         map[string]string `yaml:"levels"`
+		RedactKeys     []string          `yaml:"redact_keys"`
+		MaxValueLength *int              `yaml:"max_value_length"`
+		SampleBurst    *int              `yaml:"sample_burst"`
+		SampleRate     *int              `yaml:"sample_rate"`
+		PanicBodyBytes *int              `yaml:"panic_body_bytes"`
+	} `yaml:"log"`
+}
+
+// Profile is a named set of analyzer options that a request can pick with
+// ?profile=NAME instead of spelling them out. The request's own options
+// take precedence.
+type Profile struct {
+	// Mode analyzes text as "email" or "academic" writing (optional)
+	Mode string `yaml:"mode"`
+
+	// Detectors and ExcludeDetectors pick detectors, as in a request
+	Detectors        []string `yaml:"detectors"`
+	ExcludeDetectors []string `yaml:"exclude_detectors"`
+
+	// StopPhrases and CommonWords add to the text analyzer's lists
+	StopPhrases []string `yaml:"stop_phrases"`
+	CommonWords []string `yaml:"common_words"`
+}
+
+// fileProvider is an external detection provider's section.
+type fileProvider struct {
+	APIKey  *string        `yaml:"api_key"`
+	Weight  *float64       `yaml:"weight"`
+	Timeout *time.Duration `yaml:"timeout"`
+	BaseURL *string        `yaml:"base_url"`
+}
+
+// providerKeyEnv maps each external provider to its API key's environment
+// variable.
+var providerKeyEnv = map[string]string{
+	"hive":        "HIVE_API_KEY",
+	"openai":      "OPENAI_API_KEY",
+	"gptzero":     "GPTZERO_API_KEY",
+	"sightengine": "SIGHTENGINE_API_KEY",
+	"aiornot":     "AIORNOT_API_KEY",
+	"anthropic":   "ANTHROPIC_API_KEY",
+	"originality": "ORIGINALITY_API_KEY",
+	"winston":     "WINSTON_API_KEY",
+}
+
+// readFile parses the config file at path.
+func readFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f fileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			// yaml names the Go types; the file's keys mean more
+			msgs := make([]string, len(typeErr.Errors))
+			for i, msg := range typeErr.Errors {
+				msgs[i] = unknownFieldPattern.ReplaceAllString(msg, `unknown key "$1"`)
+			}
+			return nil, fmt.Errorf("%s: %s", path, strings.Join(msgs, "; "))
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// unknownFieldPattern matches yaml's message for a key with no field.
+var unknownFieldPattern = regexp.MustCompile(`field (\S+) not found in type .*`)
+
+// fileLayer applies a config file's settings to a Config, under the
+// environment.
+type fileLayer struct {
+	cfg *Config
+}
+
+// set copies a setting from the file into field, unless the environment
+// sets env. key is the setting's place in the file, for error messages.
+func set[T any](l fileLayer, env, key string, value *T, field *T) {
+	if value == nil || envSet(env) {
+		return
+	}
+	*field = *value
+	l.cfg.setSource(env, key+" in "+l.cfg.ConfigFile)
+}
+
+// setSlice is set for a list; an empty list in the file counts as set.
+func setSlice(l fileLayer, env, key string, value []string, field *[]string) {
+	if value == nil {
+		return
+	}
+	set(l, env, key, &value, field)
+}
+
+// setMap is set for a map; an empty map in the file counts as set.
+func setMap[V any](l fileLayer, env, key string, value map[string]V, field *map[string]V) {
+	if value == nil {
+		return
+	}
+	lowered := make(map[string]V, len(value))
+	for name, v := range value {
+		lowered[strings.ToLower(strings.TrimSpace(name))] = v
+	}
+	set(l, env, key, &lowered, field)
+}
+
+// applyFile applies the config file at path to cfg, skipping settings the
+// environment sets.
+func applyFile(cfg *Config, path string) error {
+	f, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	cfg.ConfigFile = path
+	l := fileLayer{cfg: cfg}
+
+	set(l, "ENV", "environment", f.Environment, &cfg.Environment)
+	set(l, "PORT", "port", f.Port, &cfg.Port)
+	set(l, "DATABASE_URL", "database_url", f.DatabaseURL, &cfg.DatabaseURL)
+	set(l, "REDIS_URL", "redis_url", f.RedisURL, &cfg.RedisURL)
+	set(l, "ADMIN_TOKEN", "admin_token", f.AdminToken, &cfg.AdminToken)
+	set(l, "WEBHOOK_SECRET", "webhook_secret", f.WebhookSecret, &cfg.WebhookSecret)
+	set(l, "API_KEY_REQUIRED", "api_key_required", f.APIKeyRequired, &cfg.APIKeyRequired)
+	set(l, "USAGE_MONTHLY_QUOTA", "usage_monthly_quota", f.UsageMonthlyQuota, &cfg.UsageMonthlyQuota)
+	set(l, "VALIDATE_RESPONSE_SCHEMA", "validate_response_schema", f.ValidateResponseSchema, &cfg.ValidateResponseSchema)
+	set(l, "CONTENT_SECURITY_POLICY", "content_security_policy", f.ContentSecurityPolicy, &cfg.ContentSecurityPolicy)
+	set(l, "REFERRER_POLICY", "referrer_policy", f.ReferrerPolicy, &cfg.ReferrerPolicy)
+	set(l, "AUDIT_LOG_FILE", "audit_log_file", f.AuditLogFile, &cfg.AuditLogFile)
+
+	d := f.Detectors
+	set(l, "AGGREGATION_STRATEGY", "detectors.aggregation", d.Aggregation, &cfg.AggregationStrategy)
+	set(l, "DETECTOR_TIMEOUT", "detectors.timeout", d.Timeout, &cfg.DetectorTimeout)
+	set(l, "ALLOW_PRIVATE_URLS", "detectors.allow_private_urls", d.AllowPrivateURLs, &cfg.AllowPrivateURLs)
+	set(l, "MAX_REDIRECTS", "detectors.max_redirects", d.MaxRedirects, &cfg.MaxRedirects)
+	set(l, "HOST_REPUTATION_FILE", "detectors.host_reputation_file", d.HostReputationFile, &cfg.HostReputationFile)
+	set(l, "AI_PHRASES_FILE", "detectors.ai_phrases_file", d.AIPhrasesFile, &cfg.AIPhrasesFile)
+	if err := l.applyProviders(d.Providers); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	o := f.Outbound
+	set(l, "OUTBOUND_PROXY", "outbound.proxy", o.Proxy, &cfg.OutboundProxy)
+	set(l, "OUTBOUND_CA_FILE", "outbound.ca_file", o.CAFile, &cfg.OutboundCAFile)
+	set(l, "OUTBOUND_INSECURE_SKIP_VERIFY", "outbound.insecure_skip_verify", o.InsecureSkipVerify, &cfg.OutboundInsecureSkipVerify)
+	set(l, "OUTBOUND_MAX_IDLE_CONNS", "outbound.max_idle_conns", o.MaxIdleConns, &cfg.OutboundMaxIdleConns)
+	set(l, "OUTBOUND_MAX_IDLE_CONNS_PER_HOST", "outbound.max_idle_conns_per_host", o.MaxIdleConnsPerHost, &cfg.OutboundMaxIdleConnsPerHost)
+	set(l, "OUTBOUND_MAX_CONNS_PER_HOST", "outbound.max_conns_per_host", o.MaxConnsPerHost, &cfg.OutboundMaxConnsPerHost)
+
+	dec := f.Decision
+	set(l, "AI_THRESHOLD", "decision.ai_threshold", dec.AIThreshold, &cfg.AIThreshold)
+	set(l, "HUMAN_THRESHOLD", "decision.human_threshold", dec.HumanThreshold, &cfg.HumanThreshold)
+	set(l, "UNCERTAINTY_THRESHOLD", "decision.uncertainty_threshold", dec.UncertaintyThreshold, &cfg.UncertaintyThreshold)
+	set(l, "DISAGREEMENT_THRESHOLD", "decision.disagreement_threshold", dec.DisagreementThreshold, &cfg.DisagreementThreshold)
+	set(l, "LOCAL_ONLY_CONFIDENCE_CAP", "decision.local_only_confidence_cap", dec.LocalOnlyConfidenceCap, &cfg.LocalOnlyConfidenceCap)
+
+	// Profiles only come from the file
+	cfg.Profiles = f.Profiles
+
+	rl := f.RateLimits
+	set(l, "RATE_LIMIT_PER_MINUTE", "rate_limits.per_minute", rl.PerMinute, &cfg.RateLimitPerMinute)
+	set(l, "RATE_LIMIT_BURST", "rate_limits.burst", rl.Burst, &cfg.RateLimitBurst)
+	set(l, "RATE_LIMIT_MAX_CLIENTS", "rate_limits.max_clients", rl.MaxClients, &cfg.RateLimitMaxClients)
+	if rl.Routes != nil {
+		// Route patterns are case-sensitive, so they're checked as
+		// RATE_LIMITS is rather than lowered
+		var rules []string
+		for pattern, limit := range rl.Routes {
+			rules = append(rules, fmt.Sprintf("%s=%d", pattern, limit))
+		}
+		routes, err := parseRateLimits(strings.Join(rules, ","))
+		if err != nil {
+			return fmt.Errorf("%s: rate_limits.routes: %w", path, err)
+		}
+		set(l, "RATE_LIMITS", "rate_limits.routes", &routes, &cfg.RateLimits)
+	}
+
+	r := f.Requests
+	set(l, "REQUEST_TIMEOUT", "requests.timeout", r.Timeout, &cfg.RequestTimeout)
+	setMap(l, "REQUEST_TIMEOUT_BUDGETS", "requests.timeout_budgets", r.TimeoutBudgets, &cfg.RequestTimeoutBudgets)
+	set(l, "MAX_CONCURRENT_REQUESTS", "requests.max_concurrent", r.MaxConcurrent, &cfg.MaxConcurrent)
+	set(l, "MAX_CONCURRENT_WAIT", "requests.max_concurrent_wait", r.MaxConcurrentWait, &cfg.MaxConcurrentWait)
+	setMap(l, "MAX_CONCURRENT_POOLS", "requests.max_concurrent_pools", r.MaxConcurrentPools, &cfg.MaxConcurrentPools)
+	set(l, "MAX_UPLOAD_SIZE", "requests.max_upload_size", r.MaxUploadSize, &cfg.MaxUploadSize)
+	set(l, "MAX_UPLOAD_FILES", "requests.max_upload_files", r.MaxUploadFiles, &cfg.MaxUploadFiles)
+	set(l, "UPLOAD_MEMORY_LIMIT", "requests.upload_memory_limit", r.UploadMemoryLimit, &cfg.UploadMemoryLimit)
+	set(l, "MAX_RESPONSE_SIZE", "requests.max_response_size", r.MaxResponseSize, &cfg.MaxResponseSize)
+	set(l, "SHUTDOWN_TIMEOUT", "requests.shutdown_timeout", r.ShutdownTimeout, &cfg.ShutdownTimeout)
+
+	c := f.CORS
+	setSlice(l, "ALLOWED_ORIGINS", "cors.allowed_origins", c.AllowedOrigins, &cfg.AllowedOrigins)
+	setSlice(l, "CORS_ALLOWED_METHODS", "cors.allowed_methods", c.AllowedMethods, &cfg.CORSAllowedMethods)
+	setSlice(l, "CORS_ALLOWED_HEADERS", "cors.allowed_headers", c.AllowedHeaders, &cfg.CORSAllowedHeaders)
+	setSlice(l, "CORS_EXPOSED_HEADERS", "cors.exposed_headers", c.ExposedHeaders, &cfg.CORSExposedHeaders)
+	set(l, "CORS_ALLOW_CREDENTIALS", "cors.allow_credentials", c.AllowCredentials, &cfg.CORSAllowCredentials)
+
+	a := f.Access
+	setSlice(l, "ACCESS_DENY_IPS", "access.deny_ips", a.DenyIPs, &cfg.AccessDenyIPs)
+	setSlice(l, "ACCESS_ALLOW_IPS", "access.allow_ips", a.AllowIPs, &cfg.AccessAllowIPs)
+	setSlice(l, "ACCESS_DENY_KEYS", "access.deny_keys", a.DenyKeys, &cfg.AccessDenyKeys)
+	set(l, "ACCESS_LIST_FILE", "access.list_file", a.ListFile, &cfg.AccessListFile)
+	setSlice(l, "TRUSTED_PROXIES", "access.trusted_proxies", a.TrustedProxies, &cfg.TrustedProxies)
+
+	set(l, "ASYNC_WORKERS", "async.workers", f.Async.Workers, &cfg.AsyncWorkers)
+	set(l, "ASYNC_QUEUE_SIZE", "async.queue_size", f.Async.QueueSize, &cfg.AsyncQueueSize)
+	set(l, "ASYNC_THRESHOLD_BYTES", "async.threshold_bytes", f.Async.ThresholdBytes, &cfg.AsyncThresholdBytes)
+
+	set(l, "RETENTION_DAYS", "storage.retention_days", f.Storage.RetentionDays, &cfg.RetentionDays)
+	set(l, "MEMORY_MAX_JOBS", "storage.memory_max_jobs", f.Storage.MemoryMaxJobs, &cfg.MemoryMaxJobs)
+	set(l, "MEMORY_MAX_AGE", "storage.memory_max_age", f.Storage.MemoryMaxAge, &cfg.MemoryMaxAge)
+
+	set(l, "PAGE_MAX_ASSETS", "page.max_assets", f.Page.MaxAssets, &cfg.PageMaxAssets)
+	set(l, "PAGE_BYTE_BUDGET", "page.byte_budget", f.Page.ByteBudget, &cfg.PageByteBudget)
+
+	set(l, "CERTIFICATE_KEY", "certificates.key", f.Certificates.Key, &cfg.CertificateKey)
+	setSlice(l, "CERTIFICATE_OLD_KEYS", "certificates.old_keys", f.Certificates.OldKeys, &cfg.CertificateOldKeys)
+
+	lg := f.Log
+	set(l, "LOG_LEVEL", "log.level", lg.Level, &cfg.LogLevel)
+	set(l, "LOG_FORMAT", "log.format", lg.Format, &cfg.LogFormat)
+	set(l, "LOG_TIME_FORMAT", "log.time_format", lg.TimeFormat, &cfg.LogTimeFormat)
+	if lg.Levels != nil {
+		var rules []string
+		for component, level := range lg.Levels {
+			rules = append(rules, component+"="+level)
+		}
+		levels, err := parseLevels(strings.Join(rules, ","))
+		if err != nil {
+			return fmt.Errorf("%s: log.levels: %w", path, err)
+		}
+		set(l, "LOG_LEVELS", "log.levels", &levels, &cfg.LogLevels)
+	}
+	setSlice(l, "LOG_REDACT_KEYS", "log.redact_keys", lg.RedactKeys, &cfg.LogRedactKeys)
+	set(l, "LOG_MAX_VALUE_LENGTH", "log.max_value_length", lg.MaxValueLength, &cfg.LogMaxValueLength)
+	set(l, "LOG_SAMPLE_BURST", "log.sample_burst", lg.SampleBurst, &cfg.LogSampleBurst)
+	set(l, "LOG_SAMPLE_RATE", "log.sample_rate", lg.SampleRate, &cfg.LogSampleRate)
+	set(l, "PANIC_LOG_BODY_BYTES", "log.panic_body_bytes", lg.PanicBodyBytes, &cfg.PanicBodyBytes)
+
+	return nil
+}
+
+// applyProviders applies the detectors.providers section: API keys,
+// weights, timeouts and base URLs by provider.
+func (l fileLayer) applyProviders(providers map[string]fileProvider) error {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	weights := make(map[string]float64)
+	urls := make(map[string]string)
+	for _, name := range names {
+		p := providers[name]
+		keyEnv, ok := providerKeyEnv[name]
+		if !ok {
+			known := make([]string, 0, len(providerKeyEnv))
+			for provider := range providerKeyEnv {
+				known = append(known, provider)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("detectors.providers.%s: unknown provider (must be one of %s)", name, strings.Join(known, ", "))
+		}
+
+		set(l, keyEnv, "detectors.providers."+name+".api_key", p.APIKey, l.cfg.secret(keyEnv))
+		if p.Weight != nil {
+			weights[name] = *p.Weight
+		}
+		if p.BaseURL != nil {
+			urls[name] = *p.BaseURL
+		}
+		if p.Timeout != nil {
+			if l.cfg.ProviderTimeouts == nil {
+				l.cfg.ProviderTimeouts = make(map[string]time.Duration)
+			}
+			l.cfg.ProviderTimeouts[name] = *p.Timeout
+		}
+	}
+	if len(weights) > 0 {
+		set(l, "PROVIDER_WEIGHTS", "detectors.providers.*.weight", &weights, &l.cfg.ProviderWeights)
+	}
+	if len(urls) > 0 {
+		set(l, "PROVIDER_URLS", "detectors.providers.*.base_url", &urls, &l.cfg.ProviderURLs)
+	}
+	return nil
+}
+
+// envNamePattern matches the environment variable names Validate's
+// messages refer to settings by.
+var envNamePattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]*(?:_[A-Z0-9]+)+\b|\bENV\b|\bPORT\b`)
+
+// setSource records where the setting env came from, if not from env.
+func (c *Config) setSource(env, source string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[env] = source
+}
+
+// pointAtSource adds to msg where each setting it names came from, if not
+// its environment variable, e.g. "invalid PORT (port in
+// /etc/humanmark.yaml): 0".
+func (c *Config) pointAtSource(msg string) string {
+	if len(c.sources) == 0 {
+		return msg
+	}
+	return envNamePattern.ReplaceAllStringFunc(msg, func(name string) string {
+		if source, ok := c.sources[name]; ok {
+			return fmt.Sprintf("%s (%s)", name, source)
+		}
+		return name
+	})
+}
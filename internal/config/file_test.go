@@ -0,0 +1,238 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes content to a config file named name in a temp
+// directory and returns its path.
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+const testConfigFile = `
+environment: staging
+port: 9090
+detectors:
+  aggregation: median
+  timeout: 20s
+  providers:
+    winston: {api_key: file-winston-key, weight: 1.3, timeout: 5s, base_url: "https://gateway.internal/winston"}
+    hive: {api_key: file-hive-key}
+outbound:
+  proxy: http://proxy.internal:3128
+  ca_file: /etc/ssl/corp-ca.pem
+  max_conns_per_host: 8
+decision:
+  ai_threshold: 0.7
+  human_threshold: 0.3
+rate_limits:
+  per_minute: 120
+  routes:
+    POST /verify/batch: 10
+requests:
+  timeout_budgets: {Video: 5m}
+cors:
+  allowed_origins: [https://app.example.com]
+log:
+  level: warn
+  levels: {video: debug}
+profiles:
+  legal:
+    mode: academic
+    common_words: [plaintiff, tort]
+`
+
+// TestLoadFile verifies that the config file is read under the
+// environment.
+func TestLoadFile(t *testing.T) {
+	// Save original environment
+	originalEnv := os.Environ()
+	defer func() {
+		os.Clearenv()
+		for _, e := range originalEnv {
+			pair := splitEnvPair(e)
+			os.Setenv(pair[0], pair[1])
+		}
+	}()
+
+	t.Run("loads settings from the file", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("CONFIG_FILE", writeConfigFile(t, "humanmark.yaml", testConfigFile))
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		assertEqual(t, "Environment", cfg.Environment, "staging")
+		assertEqual(t, "Port", cfg.Port, 9090)
+		assertEqual(t, "AggregationStrategy", cfg.AggregationStrategy, "median")
+		assertEqual(t, "DetectorTimeout", cfg.DetectorTimeout, 20*time.Second)
+		assertEqual(t, "WinstonAPIKey", cfg.WinstonAPIKey, "file-winston-key")
+		assertEqual(t, "HiveAPIKey", cfg.HiveAPIKey, "file-hive-key")
+		assertEqual(t, "winston weight", cfg.ProviderWeights["winston"], 1.3)
+		assertEqual(t, "weight count", len(cfg.ProviderWeights), 1)
+		assertEqual(t, "winston timeout", cfg.ProviderTimeouts["winston"], 5*time.Second)
+		assertEqual(t, "winston URL", cfg.ProviderURLs["winston"], "https://gateway.internal/winston")
+		assertEqual(t, "OutboundProxy", cfg.OutboundProxy, "http://proxy.internal:3128")
+		assertEqual(t, "OutboundCAFile", cfg.OutboundCAFile, "/etc/ssl/corp-ca.pem")
+		assertEqual(t, "OutboundMaxConnsPerHost", cfg.OutboundMaxConnsPerHost, 8)
+		assertEqual(t, "OutboundMaxIdleConns", cfg.OutboundMaxIdleConns, 100)
+		assertEqual(t, "AIThreshold", cfg.AIThreshold, 0.7)
+		assertEqual(t, "HumanThreshold", cfg.HumanThreshold, 0.3)
+		assertEqual(t, "RateLimitPerMinute", cfg.RateLimitPerMinute, 120)
+		assertEqual(t, "batch limit", cfg.RateLimits["POST /verify/batch"], 10)
+		assertEqual(t, "video budget", cfg.RequestTimeoutBudgets["video"], 5*time.Minute)
+		assertEqual(t, "budget count", len(cfg.RequestTimeoutBudgets), 1)
+		assertEqual(t, "AllowedOrigins", strings.Join(cfg.AllowedOrigins, ","), "https://app.example.com")
+		assertEqual(t, "LogLevel", cfg.LogLevel, "warn")
+		assertEqual(t, "video log level", cfg.LogLevels["video"], "debug")
+		assertEqual(t, "legal mode", cfg.Profiles["legal"].Mode, "academic")
+		assertEqual(t, "legal common words", strings.Join(cfg.Profiles["legal"].CommonWords, ","), "plaintiff,tort")
+
+		// Settings the file leaves out keep their defaults
+		assertEqual(t, "MaxUploadSize", cfg.MaxUploadSize, int64(100*1024*1024))
+		assertEqual(t, "UncertaintyThreshold", cfg.UncertaintyThreshold, 0.15)
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() returned error: %v", err)
+		}
+	})
+
+	t.Run("loads settings from the environment alone", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("PORT", "3000")
+		os.Setenv("WINSTON_API_KEY", "env-winston-key")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+		assertEqual(t, "ConfigFile", cfg.ConfigFile, "")
+		assertEqual(t, "Port", cfg.Port, 3000)
+		assertEqual(t, "WinstonAPIKey", cfg.WinstonAPIKey, "env-winston-key")
+		assertEqual(t, "ProviderTimeouts", len(cfg.ProviderTimeouts), 0)
+		assertEqual(t, "Profiles", len(cfg.Profiles), 0)
+	})
+
+	t.Run("environment overrides the file", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("CONFIG_FILE", writeConfigFile(t, "humanmark.yaml", testConfigFile))
+		os.Setenv("PORT", "3000")
+		os.Setenv("WINSTON_API_KEY", "env-winston-key")
+		os.Setenv("PROVIDER_WEIGHTS", "openai=0.5")
+		os.Setenv("RATE_LIMITS", "POST /verify=30")
+		os.Setenv("LOG_LEVEL", "debug")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		assertEqual(t, "Port", cfg.Port, 3000)
+		assertEqual(t, "WinstonAPIKey", cfg.WinstonAPIKey, "env-winston-key")
+		assertEqual(t, "HiveAPIKey", cfg.HiveAPIKey, "file-hive-key")
+		assertEqual(t, "weight count", len(cfg.ProviderWeights), 1)
+		assertEqual(t, "openai weight", cfg.ProviderWeights["openai"], 0.5)
+		assertEqual(t, "rule count", len(cfg.RateLimits), 1)
+		assertEqual(t, "verify limit", cfg.RateLimits["POST /verify"], 30)
+		assertEqual(t, "LogLevel", cfg.LogLevel, "debug")
+
+		// The rest still comes from the file
+		assertEqual(t, "Environment", cfg.Environment, "staging")
+		assertEqual(t, "RateLimitPerMinute", cfg.RateLimitPerMinute, 120)
+		assertEqual(t, "winston timeout", cfg.ProviderTimeouts["winston"], 5*time.Second)
+	})
+
+	t.Run("reads JSON", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("CONFIG_FILE", writeConfigFile(t, "humanmark.json",
+			`{"port": 9191, "detectors": {"providers": {"openai": {"api_key": "json-key"}}}, "cors": {"allowed_origins": []}}`))
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+		assertEqual(t, "Port", cfg.Port, 9191)
+		assertEqual(t, "OpenAIAPIKey", cfg.OpenAIAPIKey, "json-key")
+		assertEqual(t, "AllowedOrigins", len(cfg.AllowedOrigins), 0)
+	})
+
+	t.Run("rejects malformed files", func(t *testing.T) {
+		for name, tt := range map[string]struct {
+			content string
+			want    string
+		}{
+			"unknown key":      {"port: 8080\ndetectors:\n  timeot: 5s\n", `line 3: unknown key "timeot"`},
+			"wrong type":       {"rate_limits:\n  burst: lots\n", "line 2: cannot unmarshal"},
+			"unknown provider": {"detectors:\n  providers:\n    acme: {api_key: x}\n", "detectors.providers.acme: unknown provider"},
+			"bad route":        {"rate_limits:\n  routes: {\"post /verify\": 10}\n", "rate_limits.routes"},
+			"bad log level":    {"log:\n  levels: {video: loud}\n", "log.levels"},
+		} {
+			os.Clearenv()
+			path := writeConfigFile(t, "humanmark.yaml", tt.content)
+			os.Setenv("CONFIG_FILE", path)
+
+			_, err := Load()
+			if err == nil {
+				t.Errorf("%s: Load() should fail", name)
+				continue
+			}
+			if !strings.Contains(err.Error(), path) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: error = %v, want the path and %q", name, err, tt.want)
+			}
+		}
+
+		os.Clearenv()
+		os.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+		if _, err := Load(); err == nil {
+			t.Error("Load() should fail on a missing file")
+		}
+	})
+
+	t.Run("validation errors point at the file", func(t *testing.T) {
+		os.Clearenv()
+		path := writeConfigFile(t, "humanmark.yaml", `
+port: 70000
+detectors:
+  timeout: 10s
+  providers:
+    winston: {weight: 20, timeout: 15s}
+decision:
+  human_threshold: 0.8
+profiles:
+  legal: {mode: legalese}
+`)
+		os.Setenv("CONFIG_FILE", path)
+		os.Setenv("AI_THRESHOLD", "0.6")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+		err = cfg.Validate()
+		if err == nil {
+			t.Fatal("Validate() should fail")
+		}
+		for _, want := range []string{
+			"invalid PORT (port in " + path + "): 70000",
+			"PROVIDER_WEIGHTS (detectors.providers.*.weight in " + path + "): winston=20",
+			"detectors.providers.winston.timeout in " + path + ": 15s",
+			"HUMAN_THRESHOLD (decision.human_threshold in " + path + ") (0.8) must not be above AI_THRESHOLD (0.6)",
+			"profiles.legal.mode in " + path + ": legalese",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error should contain %q:\n%v", want, err)
+			}
+		}
+	})
+}
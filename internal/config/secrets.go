@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Settings holding credentials can be read from a file instead of the
+// environment: HIVE_API_KEY_FILE=/run/secrets/hive reads HIVE_API_KEY from
+// /run/secrets/hive, which is how Docker and Kubernetes mount secrets.
+// Surrounding whitespace, such as a trailing newline, is trimmed. The
+// variable itself wins over its _FILE, and both win over the config file.
+
+// secretEnvs are the environment variables of the settings holding
+// credentials.
+var secretEnvs = []string{
+	"DATABASE_URL",
+	"REDIS_URL",
+	"ADMIN_TOKEN",
+	"WEBHOOK_SECRET",
+	"CERTIFICATE_KEY",
+	"HIVE_API_KEY",
+	"OPENAI_API_KEY",
+	"GPTZERO_API_KEY",
+	"SIGHTENGINE_API_KEY",
+	"AIORNOT_API_KEY",
+	"ANTHROPIC_API_KEY",
+	"ORIGINALITY_API_KEY",
+	"WINSTON_API_KEY",
+}
+
+// secret returns the field holding the secret setting env, or nil if env
+// isn't one.
+func (c *Config) secret(env string) *string {
+	switch env {
+	case "DATABASE_URL":
+		return &c.DatabaseURL
+	case "REDIS_URL":
+		return &c.RedisURL
+	case "ADMIN_TOKEN":
+		return &c.AdminToken
+	case "WEBHOOK_SECRET":
+		return &c.WebhookSecret
+	case "CERTIFICATE_KEY":
+		return &c.CertificateKey
+	case "HIVE_API_KEY":
+		return &c.HiveAPIKey
+	case "OPENAI_API_KEY":
+		return &c.OpenAIAPIKey
+	case "GPTZERO_API_KEY":
+		return &c.GPTZeroAPIKey
+	case "SIGHTENGINE_API_KEY":
+		return &c.SightengineAPIKey
+	case "AIORNOT_API_KEY":
+		return &c.AIorNotAPIKey
+	case "ANTHROPIC_API_KEY":
+		return &c.AnthropicAPIKey
+	case "ORIGINALITY_API_KEY":
+		return &c.OriginalityAPIKey
+	case "WINSTON_API_KEY":
+		return &c.WinstonAPIKey
+	}
+	return nil
+}
+
+// envSet reports whether the environment sets env, directly or, for a
+// secret, through its _FILE.
+func envSet(env string) bool {
+	if os.Getenv(env) != "" {
+		return true
+	}
+	var c Config
+	return c.secret(env) != nil && os.Getenv(env+"_FILE") != ""
+}
+
+// readSecretFiles fills in the secrets cfg's environment names files for
+// instead of setting them.
+func readSecretFiles(cfg *Config) error {
+	for _, env := range secretEnvs {
+		path := os.Getenv(env + "_FILE")
+		if path == "" || os.Getenv(env) != "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("invalid %s_FILE: %w", env, err)
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return fmt.Errorf("invalid %s_FILE: %s is empty", env, path)
+		}
+		*cfg.secret(env) = value
+		cfg.setSource(env, "read from "+path)
+	}
+	return nil
+}
+
+// redactedSecret replaces secrets in Redacted.
+const redactedSecret = "[REDACTED]"
+
+// Redacted returns a copy of c with its credentials masked, safe to log or
+// print. URLs keep everything but their password, which also covers
+// credentials in the proxy and provider URLs.
+func (c *Config) Redacted() Config {
+	redacted := *c
+	for _, env := range secretEnvs {
+		field := redacted.secret(env)
+		if *field == "" {
+			continue
+		}
+		if masked, ok := redactURL(*field); ok {
+			*field = masked
+			continue
+		}
+		*field = redactedSecret
+	}
+	redacted.OutboundProxy, _ = redactURL(c.OutboundProxy)
+	if c.ProviderURLs != nil {
+		redacted.ProviderURLs = make(map[string]string, len(c.ProviderURLs))
+		for name, raw := range c.ProviderURLs {
+			redacted.ProviderURLs[name], _ = redactURL(raw)
+		}
+	}
+	return redacted
+}
+
+// redactURL masks the password in raw, reporting whether raw is a URL at
+// all. Anything else is returned unchanged.
+func redactURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw, false
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	return u.String(), true
+}
+
+// LogValue logs c as its SafeSummary, so a configuration dump can't leak
+// credentials.
+func (c *Config) LogValue() slog.Value {
+	return slog.GroupValue(c.SafeSummary()...)
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSecretFiles verifies that credentials can be read from the files
+// their _FILE variables name.
+func TestSecretFiles(t *testing.T) {
+	// Save original environment
+	originalEnv := os.Environ()
+	defer func() {
+		os.Clearenv()
+		for _, e := range originalEnv {
+			pair := splitEnvPair(e)
+			os.Setenv(pair[0], pair[1])
+		}
+	}()
+
+	t.Run("reads and trims secret files", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("HIVE_API_KEY_FILE", writeConfigFile(t, "hive", "file-hive-key\n"))
+		os.Setenv("DATABASE_URL_FILE", writeConfigFile(t, "database", "  postgres://app:pw@db/humanmark \n"))
+		os.Setenv("WEBHOOK_SECRET_FILE", writeConfigFile(t, "webhook", "0123456789abcdef"))
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+		assertEqual(t, "HiveAPIKey", cfg.HiveAPIKey, "file-hive-key")
+		assertEqual(t, "DatabaseURL", cfg.DatabaseURL, "postgres://app:pw@db/humanmark")
+		assertEqual(t, "WebhookSecret", cfg.WebhookSecret, "0123456789abcdef")
+	})
+
+	t.Run("the variable wins over its file, and both over the config file", func(t *testing.T) {
+		os.Clearenv()
+		os.Setenv("HIVE_API_KEY", "env-hive-key")
+		os.Setenv("HIVE_API_KEY_FILE", writeConfigFile(t, "hive", "file-hive-key"))
+		os.Setenv("WINSTON_API_KEY_FILE", writeConfigFile(t, "winston", "file-winston-key"))
+		os.Setenv("CONFIG_FILE", writeConfigFile(t, "humanmark.yaml", `
+detectors:
+  providers:
+    hive: {api_key: config-hive-key}
+    winston: {api_key: config-winston-key}
+    openai: {api_key: config-openai-key}
+`))
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+		assertEqual(t, "HiveAPIKey", cfg.HiveAPIKey, "env-hive-key")
+		assertEqual(t, "WinstonAPIKey", cfg.WinstonAPIKey, "file-winston-key")
+		assertEqual(t, "OpenAIAPIKey", cfg.OpenAIAPIKey, "config-openai-key")
+	})
+
+	t.Run("rejects unreadable and empty files", func(t *testing.T) {
+		os.Clearenv()
+		missing := filepath.Join(t.TempDir(), "missing")
+		os.Setenv("OPENAI_API_KEY_FILE", missing)
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), "OPENAI_API_KEY_FILE") || !strings.Contains(err.Error(), missing) {
+			t.Errorf("Load() error = %v, want the variable and path named", err)
+		}
+
+		os.Clearenv()
+		os.Setenv("ADMIN_TOKEN_FILE", writeConfigFile(t, "admin", "\n"))
+		_, err = Load()
+		if err == nil || !strings.Contains(err.Error(), "ADMIN_TOKEN_FILE") || !strings.Contains(err.Error(), "empty") {
+			t.Errorf("Load() error = %v, want an empty file rejected", err)
+		}
+	})
+
+	t.Run("validation errors name the file", func(t *testing.T) {
+		os.Clearenv()
+		path := writeConfigFile(t, "admin", "too-short")
+		os.Setenv("ADMIN_TOKEN_FILE", path)
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+		err = cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "ADMIN_TOKEN (read from "+path+") must be") {
+			t.Errorf("Validate() error = %v, want the file named", err)
+		}
+	})
+}
+
+// TestRedacted verifies that credentials are masked in configuration
+// dumps.
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		Port:           8080,
+		HiveAPIKey:     "hive-secret",
+		AdminToken:     "admin-secret",
+		DatabaseURL:    "postgres://app:db-secret@db:5432/humanmark",
+		RedisURL:       "redis://cache:6379",
+		AllowedOrigins: []string{"https://app.example.com"},
+	}
+
+	redacted := cfg.Redacted()
+	assertEqual(t, "HiveAPIKey", redacted.HiveAPIKey, redactedSecret)
+	assertEqual(t, "AdminToken", redacted.AdminToken, redactedSecret)
+	assertEqual(t, "DatabaseURL", redacted.DatabaseURL, "postgres://app:REDACTED@db:5432/humanmark")
+	assertEqual(t, "RedisURL", redacted.RedisURL, "redis://cache:6379")
+	assertEqual(t, "OpenAIAPIKey", redacted.OpenAIAPIKey, "")
+	assertEqual(t, "Port", redacted.Port, 8080)
+	assertEqual(t, "original HiveAPIKey", cfg.HiveAPIKey, "hive-secret")
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("configuration loaded", "config", cfg)
+	for _, secret := range []string{"hive-secret", "admin-secret", "db-secret"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("log output contains %q: %s", secret, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "https://app.example.com") {
+		t.Errorf("log output should keep other settings: %s", buf.String())
+	}
+}
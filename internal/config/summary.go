@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// SafeSummary returns every setting as a key/value pair, sorted by field
+// name, with credentials masked as in Redacted. It is logged once at
+// startup so operators can see exactly what the process is running with.
+// Lists are joined with commas and maps are written as sorted k=v pairs.
+func (c *Config) SafeSummary() []slog.Attr {
+	redacted := c.Redacted()
+	v := reflect.ValueOf(redacted)
+	t := v.Type()
+
+	attrs := make([]slog.Attr, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		attrs = append(attrs, slog.String(field.Name, summaryValue(v.Field(i))))
+	}
+	slices.SortFunc(attrs, func(a, b slog.Attr) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return attrs
+}
+
+// summaryValue formats one setting for SafeSummary.
+func summaryValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	case reflect.Map:
+		parts := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			parts = append(parts, fmt.Sprintf("%v=%+v", iter.Key().Interface(), iter.Value().Interface()))
+		}
+		slices.Sort(parts)
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/middleware"
+	"github.com/humanmark/humanmark/internal/repository"
+)
+
+// CreateAPIKeyRequest is the JSON body for POST /admin/keys.
+type CreateAPIKeyRequest struct {
+	// Name describes who the key is for (required)
+	Name string `json:"name"`
+
+	// Scopes the key is granted (default: all)
+	Scopes []string `json:"scopes,omitempty"`
+
+	// RateLimitPerMinute overrides the server's rate limit for the key
+	// (0 = use the default)
+	RateLimitPerMinute int `json:"rate_limit_per_minute,omitempty"`
+}
+
+// APIKeyResponse describes an API key. Key, the credential itself, is only
+// returned when the key is created.
+type APIKeyResponse struct {
+	ID                 string     `json:"id"`
+	Name               string     `json:"name"`
+	Key                string     `json:"key,omitempty"`
+	Scopes             []string   `json:"scopes"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyListResponse is the JSON body for GET /admin/keys.
+type APIKeyListResponse struct {
+	Items []APIKeyResponse `json:"items"`
+}
+
+// apiKeyResponse converts a stored key to its response.
+func apiKeyResponse(key *repository.APIKey) APIKeyResponse {
+	response := APIKeyResponse{
+		ID:                 key.ID,
+		Name:               key.Name,
+		Scopes:             key.Scopes,
+		RateLimitPerMinute: key.RateLimitPerMinute,
+		CreatedAt:          key.CreatedAt,
+	}
+	if response.Scopes == nil {
+		response.Scopes = []string{}
+	}
+	if key.Revoked() {
+		revokedAt := key.RevokedAt
+		response.RevokedAt = &revokedAt
+	}
+	return response
+}
+
+// CreateAPIKey handles POST /admin/keys requests.
+// Issues a new API key; the response is the only time its key is shown.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_input", "invalid JSON: "+err.Error())
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "name is required")
+		return
+	}
+	if req.RateLimitPerMinute < 0 {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "rate_limit_per_minute must not be negative")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = repository.Scopes
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(repository.Scopes, scope) {
+			h.writeError(w, http.StatusBadRequest, "validation_error",
+				fmt.Sprintf("unknown scope %q (supported: %s)", scope, strings.Join(repository.Scopes, ", ")))
+			return
+		}
+	}
+
+	secret, hash, err := middleware.NewAPIKeySecret()
+	if err != nil {
+		h.logger.Error("failed to generate API key", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create API key")
+		return
+	}
+
+	key, err := h.repository.CreateAPIKey(r.Context(), repository.APIKey{
+		Name:               req.Name,
+		SecretHash:         hash,
+		Scopes:             req.Scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+	})
+	if err != nil {
+		h.logger.Error("failed to create API key", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create API key")
+		return
+	}
+
+	h.logger.Info("API key created", "key_id", key.ID, "name", key.Name, "scopes", key.Scopes)
+
+	response := apiKeyResponse(key)
+	response.Key = middleware.FormatAPIKey(key.ID, secret)
+	h.writeJSON(w, http.StatusCreated, response)
+}
+
+// ListAPIKeys handles GET /admin/keys requests.
+// Returns every API key, revoked or not, newest first.
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	keys, err := h.repository.ListAPIKeys(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list API keys", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list API keys")
+		return
+	}
+
+	items := make([]APIKeyResponse, len(keys))
+	for i := range keys {
+		items[i] = apiKeyResponse(&keys[i])
+	}
+	h.writeJSON(w, http.StatusOK, APIKeyListResponse{Items: items})
+}
+
+// RevokeAPIKey handles DELETE /admin/keys/{id} requests.
+// The key stops authenticating immediately; revoking it again is a no-op.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	key, err := h.repository.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "API key not found")
+			return
+		}
+		h.logger.Error("failed to revoke API key", "error", err, "key_id", id)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to revoke API key")
+		return
+	}
+
+	h.logger.Info("API key revoked", "key_id", key.ID)
+	h.writeJSON(w, http.StatusOK, apiKeyResponse(key))
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/internal/middleware"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestAdminAPIKeys tests creating, listing and revoking API keys.
+func TestAdminAPIKeys(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector:   &mockDetector{},
+		Repository: repo,
+		Logger:     logger.NopLogger(),
+	})
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/keys", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.CreateAPIKey(rec, req)
+		return rec
+	}
+
+	var created APIKeyResponse
+	t.Run("create returns the key once", func(t *testing.T) {
+		rec := create(`{"name": "ingest", "rate_limit_per_minute": 600}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !strings.HasPrefix(created.Key, "hm_"+created.ID+"_") {
+			t.Errorf("expected key for %s, got %q", created.ID, created.Key)
+		}
+		if !reflect.DeepEqual(created.Scopes, repository.Scopes) || created.RateLimitPerMinute != 600 {
+			t.Errorf("got %+v", created)
+		}
+
+		stored, _ := repo.GetAPIKey(context.Background(), created.ID)
+		if stored == nil || strings.Contains(created.Key, stored.SecretHash) {
+			t.Errorf("expected only the secret's hash stored, got %+v", stored)
+		}
+	})
+
+	t.Run("create validates", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"missing name", `{"scopes": ["verify:text"]}`},
+			{"unknown scope", `{"name": "x", "scopes": ["admin"]}`},
+			{"negative rate limit", `{"name": "x", "rate_limit_per_minute": -1}`},
+			{"invalid JSON", `{`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if rec := create(tt.body); rec.Code != http.StatusBadRequest {
+					t.Errorf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("list omits keys", func(t *testing.T) {
+		create(`{"name": "media", "scopes": ["verify:media"]}`)
+
+		rec := httptest.NewRecorder()
+		h.ListAPIKeys(rec, httptest.NewRequest("GET", "/admin/keys", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var list APIKeyListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(list.Items) != 2 || list.Items[0].Name != "media" || list.Items[1].ID != created.ID {
+			t.Errorf("expected media then ingest, got %+v", list.Items)
+		}
+		for _, item := range list.Items {
+			if item.Key != "" {
+				t.Errorf("list exposed key %q", item.Key)
+			}
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/admin/keys/"+created.ID, nil)
+		req.SetPathValue("id", created.ID)
+		rec := httptest.NewRecorder()
+		h.RevokeAPIKey(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var revoked APIKeyResponse
+		json.NewDecoder(rec.Body).Decode(&revoked)
+		if revoked.RevokedAt == nil {
+			t.Errorf("expected revoked_at, got %+v", revoked)
+		}
+
+		req = httptest.NewRequest("DELETE", "/admin/keys/missing", nil)
+		req.SetPathValue("id", "missing")
+		rec = httptest.NewRecorder()
+		h.RevokeAPIKey(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+}
+
+// TestVerify_APIKeyScopes tests that keys can only verify content their
+// scopes allow.
+func TestVerify_APIKeyScopes(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+	verify := middleware.Auth(repo, true, logger.NopLogger())(http.HandlerFunc(h.Verify))
+
+	issue := func(scopes ...string) string {
+		secret, hash, err := middleware.NewAPIKeySecret()
+		if err != nil {
+			t.Fatalf("NewAPIKeySecret failed: %v", err)
+		}
+		key, _ := repo.CreateAPIKey(context.Background(), repository.APIKey{Name: "test", SecretHash: hash, Scopes: scopes})
+		return middleware.FormatAPIKey(key.ID, secret)
+	}
+	textKey := issue(repository.ScopeVerifyText)
+	allKey := issue(repository.Scopes...)
+
+	textBody := `{"text": "This is a test text that should be verified as human-written content."}`
+	imageBody := `{"url": "https://example.com/photo.jpg"}`
+	unknownBody := `{"url": "https://example.com/article"}`
+
+	tests := []struct {
+		name     string
+		key      string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{"no key", "", textBody, http.StatusUnauthorized, ""},
+		{"text key verifies text", textKey, textBody, http.StatusOK, ""},
+		{"text key can't verify images", textKey, imageBody, http.StatusForbidden, ErrCodeInsufficientScope},
+		{"text key can't verify unknown content", textKey, unknownBody, http.StatusForbidden, ErrCodeInsufficientScope},
+		{"full key verifies text", allKey, textBody, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/verify", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			rec := httptest.NewRecorder()
+			verify.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantErr != "" {
+				var errResp ErrorResponse
+				json.NewDecoder(rec.Body).Decode(&errResp)
+				if errResp.Code != tt.wantErr {
+					t.Errorf("expected code %s, got %+v", tt.wantErr, errResp)
+				}
+			}
+		})
+	}
+
+	t.Run("revoked key is rejected", func(t *testing.T) {
+		id := strings.SplitN(strings.TrimPrefix(allKey, "hm_"), "_", 2)[0]
+		repo.RevokeAPIKey(context.Background(), id)
+
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(textBody))
+		req.Header.Set("X-API-Key", allKey)
+		rec := httptest.NewRecorder()
+		verify.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/humanmark/humanmark/internal/audit"
+	"github.com/humanmark/humanmark/internal/middleware"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// auditVerdict records the verdict in job, handed out in answer to r, in
+// the audit log. reused marks a stored result given out again. A failed
+// write is only logged: the verdict stands whether or not it was recorded.
+func (h *Handler) auditVerdict(r *http.Request, log *logger.Logger, job *repository.Job, reused bool) {
+	if h.auditLog == nil {
+		return
+	}
+
+	record := audit.NewRecord(job)
+	record.Reused = reused
+	record.ClientIP = middleware.ClientIP(r)
+	if id, ok := r.Context().Value(logger.ContextKeyRequestID).(string); ok {
+		record.RequestID = id
+	}
+	if key := middleware.APIKeyFromContext(r.Context()); key != nil {
+		record.APIKeyID = key.ID
+	}
+
+	if err := h.auditLog.Log(record); err != nil {
+		log.Error("failed to write audit record", "error", err, "job_id", job.ID)
+	}
+}
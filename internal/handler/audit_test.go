@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/humanmark/humanmark/internal/audit"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of a
+// multi-file upload.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the audit records written, failing on a malformed line.
+func (b *syncBuffer) lines(t *testing.T) []audit.Record {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []audit.Record
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var record audit.Record
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("malformed audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// failingAuditLog is an audit.Logger whose writes always fail.
+type failingAuditLog struct{}
+
+func (failingAuditLog) Log(record audit.Record) error {
+	return errors.New("disk full")
+}
+
+// TestVerify_AuditLog tests that every verification writes one
+// well-formed audit line, without the content.
+func TestVerify_AuditLog(t *testing.T) {
+	const text = "A confidential draft nobody else should read."
+
+	newHandler := func(log audit.Logger) *Handler {
+		return New(Config{
+			Detector:      &flipDetector{fingerprint: "cfg-1"},
+			Repository:    repository.NewMemory(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+			AuditLog:      log,
+		})
+	}
+	verify := func(h *Handler, query string) VerifyResponse {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/verify"+query, strings.NewReader(`{"text": "`+text+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.9:4711"
+		ctx := context.WithValue(req.Context(), logger.ContextKeyRequestID, "req-42")
+		ctx = context.WithValue(ctx, logger.ContextKeyAPIKey, &repository.APIKey{ID: "key-7", Scopes: repository.Scopes})
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req.WithContext(ctx))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response VerifyResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		return response
+	}
+
+	buf := &syncBuffer{}
+	h := newHandler(audit.NewJSONLogger(buf))
+	first := verify(h, "")
+
+	records := buf.lines(t)
+	if len(records) != 1 {
+		t.Fatalf("expected one audit line, got %d", len(records))
+	}
+	r := records[0]
+	if r.RequestID != "req-42" || r.APIKeyID != "key-7" || r.ClientIP != "203.0.113.9" {
+		t.Errorf("request context missing: %+v", r)
+	}
+	if r.JobID != first.ID || r.Verdict != first.Verdict || r.Confidence != first.Confidence ||
+		r.ContentType != "text" || len(r.Detectors) != 1 || r.ConfigFingerprint != "cfg-1" || r.Reused {
+		t.Errorf("record = %+v, want the response %+v", r, first)
+	}
+	if r.ContentHash == "" || r.Time.IsZero() {
+		t.Errorf("expected a content hash and time, got %+v", r)
+	}
+	if strings.Contains(buf.buf.String(), "confidential") {
+		t.Errorf("audit log holds the content: %s", buf.buf.String())
+	}
+
+	t.Run("reused results are recorded", func(t *testing.T) {
+		second := verify(h, "")
+		records := buf.lines(t)
+		if len(records) != 2 {
+			t.Fatalf("expected a line per verification, got %d", len(records))
+		}
+		if r := records[1]; !second.Reused || !r.Reused || r.JobID != first.ID {
+			t.Errorf("expected the stored result recorded as reused, got %+v", r)
+		}
+	})
+
+	t.Run("each file of an upload is recorded", func(t *testing.T) {
+		buf := &syncBuffer{}
+		h := newHandler(audit.NewJSONLogger(buf))
+		rec := httptest.NewRecorder()
+		h.Verify(rec, multipartUpload(t, "/verify", map[string][]byte{
+			"a.txt": []byte("The first note."), "b.txt": []byte("The second note."), "c.txt": []byte("The third note."),
+		}))
+		if rec.Code != http.StatusMultiStatus {
+			t.Fatalf("expected 207, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if records := buf.lines(t); len(records) != 3 {
+			t.Errorf("expected 3 audit lines, got %d", len(records))
+		}
+	})
+
+	t.Run("write failures don't fail the request", func(t *testing.T) {
+		if response := verify(newHandler(failingAuditLog{}), "?reuse=false"); response.Verdict == "" {
+			t.Errorf("expected a verdict, got %+v", response)
+		}
+	})
+}
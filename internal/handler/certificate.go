@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/cert"
+)
+
+// CertificateResponse is the JSON body for GET /verify/{id}/certificate.
+type CertificateResponse struct {
+	// Certificate is the signed token. Anyone can check it with
+	// cert.VerifyCertificate against GET /.well-known/humanmark.json.
+	Certificate string `json:"certificate"`
+
+	// Claims is what the certificate states, decoded
+	Claims cert.Claims `json:"claims"`
+}
+
+// GetCertificate handles GET /verify/{id}/certificate requests.
+// Returns a signed certificate of a completed result that publishers can
+// hand to third parties.
+//
+// Each request signs a new certificate, but one already held stays valid:
+// the response carries a weak ETag, and If-None-Match with it gets 304 Not
+// Modified until the result changes or the signing key is rotated.
+//
+// Query parameters:
+//   - format=svg: a badge showing the verdict instead, with the
+//     certificate in its metadata
+func (h *Handler) GetCertificate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.certificates == nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "Certificates are not enabled")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "svg" {
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidParameter, "format must be json or svg")
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, http.StatusBadRequest, "missing_id", "Job ID is required")
+		return
+	}
+
+	job, err := h.repository.GetJob(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "Verification result not found")
+			return
+		}
+		h.logger.Error("failed to get job", "error", err, "id", id)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve result")
+		return
+	}
+
+	if job.Status != "" && job.Status != repository.JobStatusCompleted {
+		h.writeError(w, http.StatusConflict, ErrCodeJobNotCompleted, "Verification has not completed")
+		return
+	}
+
+	// Weak: the bytes differ with issued_at, the meaning doesn't
+	if notModified(w, r, "W/"+jobETag(job, "certificate "+format+" "+h.certificates.KeyID())) {
+		return
+	}
+
+	claims := cert.Claims{
+		JobID:             job.ID,
+		ContentHash:       job.ContentHash,
+		Verdict:           jobVerdict(job),
+		Confidence:        job.Confidence,
+		ContentType:       job.ContentType,
+		AnalyzerVersion:   job.AnalyzerVersion,
+		ConfigFingerprint: job.ConfigFingerprint,
+		VerifiedAt:        job.CreatedAt.UTC(),
+		IssuedAt:          h.now().UTC(),
+	}
+	token, err := h.certificates.Sign(claims)
+	if err != nil {
+		h.logger.Error("failed to sign certificate", "error", err, "id", id)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to sign certificate")
+		return
+	}
+
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.WriteHeader(http.StatusOK)
+		w.Write(certificateBadge(claims, token))
+		return
+	}
+	h.writeJSON(w, http.StatusOK, CertificateResponse{Certificate: token, Claims: claims})
+}
+
+// certificateBadge renders a certificate as an SVG badge: "HumanMark" and
+// the verdict, with the certificate itself in the metadata so the image
+// can be checked on its own.
+func certificateBadge(claims cert.Claims, token string) []byte {
+	const label = "HumanMark"
+
+	var message, color string
+	switch claims.Verdict {
+	case string(service.VerdictHuman):
+		message = fmt.Sprintf("human %d%%", int(math.Round(claims.Confidence*100)))
+		color = "#2e7d32"
+	case string(service.VerdictAI):
+		message = fmt.Sprintf("AI %d%%", int(math.Round(claims.Confidence*100)))
+		color = "#c62828"
+	default:
+		message = "uncertain"
+		color = "#757575"
+	}
+
+	// Verdana 11px averages about 7px a character
+	labelWidth := 10 + 7*len(label)
+	messageWidth := 10 + 7*len(message)
+	width := labelWidth + messageWidth
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">
+<title>%[2]s: %[3]s</title>
+<metadata>%[4]s</metadata>
+<rect width="%[5]d" height="20" fill="#555"/>
+<rect x="%[5]d" width="%[6]d" height="20" fill="%[7]s"/>
+<g fill="#fff" font-family="Verdana,DejaVu Sans,sans-serif" font-size="11" text-anchor="middle">
+<text x="%[8]d" y="14">%[2]s</text>
+<text x="%[9]d" y="14">%[3]s</text>
+</g>
+</svg>
+`, width, label, html.EscapeString(message), html.EscapeString(token),
+		labelWidth, messageWidth, color, labelWidth/2, labelWidth+messageWidth/2))
+}
+
+// WellKnown handles GET /.well-known/humanmark.json requests.
+// Returns the public keys certificates are signed with, as a cert.KeySet.
+func (h *Handler) WellKnown(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.certificates == nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "Certificates are not enabled")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	h.writeJSON(w, http.StatusOK, h.certificates.KeySet())
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/cert"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// newCertificateHandler returns a handler that signs certificates, with a
+// completed AI result stored as "test-job-id" and a pending job as
+// "pending-id".
+func newCertificateHandler(t *testing.T) *Handler {
+	t.Helper()
+	key, err := cert.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	repo := newMockRepository()
+	h := New(Config{
+		Detector: &mockDetector{result: &service.DetectionResult{
+			Verdict:     service.VerdictAI,
+			Confidence:  0.87,
+			AIScore:     0.9,
+			ContentType: service.ContentTypeText,
+			ContentHash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		}},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+		Certificates:  cert.NewSigner("HumanMark", key),
+	})
+
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(`{"text": "Let us delve into the topic."}`))
+	req.Header.Set("Content-Type", "application/json")
+	h.Verify(httptest.NewRecorder(), req)
+	repo.jobs["pending-id"] = &repository.Job{ID: "pending-id", Status: repository.JobStatusPending}
+	return h
+}
+
+// getCertificate requests target from GetCertificate.
+func getCertificate(h *Handler, id, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	h.GetCertificate(rec, req)
+	return rec
+}
+
+// TestGetCertificate tests that a certificate verifies against the
+// published keys and states the result.
+func TestGetCertificate(t *testing.T) {
+	h := newCertificateHandler(t)
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	rec := getCertificate(h, "test-job-id", "/verify/test-job-id/certificate")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response CertificateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	wellKnown := httptest.NewRecorder()
+	h.WellKnown(wellKnown, httptest.NewRequest("GET", "/.well-known/humanmark.json", nil))
+	if wellKnown.Code != http.StatusOK {
+		t.Fatalf("well-known: expected status 200, got %d", wellKnown.Code)
+	}
+	var keys cert.KeySet
+	if err := json.NewDecoder(wellKnown.Body).Decode(&keys); err != nil {
+		t.Fatalf("failed to decode key set: %v", err)
+	}
+
+	claims, err := cert.VerifyCertificate(response.Certificate, keys)
+	if err != nil {
+		t.Fatalf("certificate doesn't verify: %v", err)
+	}
+	if *claims != response.Claims {
+		t.Errorf("signed claims %+v differ from returned claims %+v", *claims, response.Claims)
+	}
+	if claims.JobID != "test-job-id" || claims.Verdict != "ai" || claims.Confidence != 0.87 || claims.ContentType != "text" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if claims.ContentHash == "" || claims.VerifiedAt.IsZero() {
+		t.Errorf("expected content hash and verification time, got %+v", claims)
+	}
+	if !claims.IssuedAt.Equal(now) {
+		t.Errorf("expected issued_at %v, got %v", now, claims.IssuedAt)
+	}
+}
+
+// TestGetCertificate_NotModified tests revalidating a certificate.
+func TestGetCertificate_NotModified(t *testing.T) {
+	h := newCertificateHandler(t)
+
+	first := getCertificate(h, "test-job-id", "/verify/test-job-id/certificate")
+	etag := first.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+
+	req := httptest.NewRequest("GET", "/verify/test-job-id/certificate", nil)
+	req.SetPathValue("id", "test-job-id")
+	req.Header.Set("If-None-Match", etag)
+	rec := httptest.NewRecorder()
+	h.GetCertificate(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	badge := getCertificate(h, "test-job-id", "/verify/test-job-id/certificate?format=svg")
+	if badge.Header().Get("ETag") == etag {
+		t.Error("expected the badge to have its own ETag")
+	}
+}
+
+// TestGetCertificate_Badge tests the SVG badge.
+func TestGetCertificate_Badge(t *testing.T) {
+	h := newCertificateHandler(t)
+
+	rec := getCertificate(h, "test-job-id", "/verify/test-job-id/certificate?format=svg")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("expected image/svg+xml, got %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "<svg") || !strings.Contains(body, "AI 87%") {
+		t.Errorf("expected a badge reading AI 87%%, got %s", body)
+	}
+	if !strings.Contains(body, "<metadata>ey") {
+		t.Error("expected the certificate in the badge metadata")
+	}
+}
+
+// TestGetCertificate_Errors tests the error responses.
+func TestGetCertificate_Errors(t *testing.T) {
+	h := newCertificateHandler(t)
+
+	tests := []struct {
+		name   string
+		id     string
+		target string
+		status int
+		code   string
+	}{
+		{"unknown job", "missing", "/verify/missing/certificate", http.StatusNotFound, "not_found"},
+		{"pending job", "pending-id", "/verify/pending-id/certificate", http.StatusConflict, ErrCodeJobNotCompleted},
+		{"bad format", "test-job-id", "/verify/test-job-id/certificate?format=png", http.StatusBadRequest, ErrCodeInvalidParameter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getCertificate(h, tt.id, tt.target)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			var response ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&response)
+			if response.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, response.Code)
+			}
+		})
+	}
+
+	t.Run("not enabled", func(t *testing.T) {
+		h := newTestHandler()
+		if rec := getCertificate(h, "test-job-id", "/verify/test-job-id/certificate"); rec.Code != http.StatusNotFound {
+			t.Errorf("certificate: expected status 404, got %d", rec.Code)
+		}
+		rec := httptest.NewRecorder()
+		h.WellKnown(rec, httptest.NewRequest("GET", "/.well-known/humanmark.json", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("well-known: expected status 404, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/humanmark/humanmark/internal/repository"
+)
+
+// resultMaxAge is how long clients may reuse a completed result without
+// asking again. Completed results only change on deletion or when a
+// webhook delivery is recorded, so this mostly bounds how long a deleted
+// result lingers in a client's cache.
+const resultMaxAge = 300
+
+// jobETag returns a strong entity tag for a completed job's representation.
+// It changes whenever the job is updated or would be analyzed differently;
+// variant separates representations of the same job (e.g. detailed or
+// not).
+func jobETag(job *repository.Job, variant string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%s\x00%s\x00%s",
+		job.ID, job.UpdatedAt.UnixNano(), job.AnalyzerVersion, job.ConfigFingerprint, variant)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified sets etag and the Cache-Control for a completed result, and
+// writes 304 Not Modified if the request's If-None-Match already has etag.
+// It reports whether it did, in which case the handler is done.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", resultMaxAge))
+
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	// A 304 has no body to describe
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using
+// the weak comparison RFC 9110 specifies for it: W/ prefixes are ignored,
+// so a tag weakened by compression still matches.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
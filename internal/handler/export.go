@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/middleware"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+)
+
+const (
+	// defaultExportWindow is how far back an export without from goes
+	defaultExportWindow = 30 * 24 * time.Hour
+
+	// maxExportWindow caps from to to, so one export can't walk the whole
+	// history; a year covers an annual audit
+	maxExportWindow = 366 * 24 * time.Hour
+
+	// exportPageTimeout is how long each page of an export may take to
+	// write. It replaces the server's write timeout, which a large export
+	// would outlast.
+	exportPageTimeout = time.Minute
+)
+
+// exportCSVHeader is the first row of a CSV export.
+var exportCSVHeader = []string{
+	"id", "created_at", "content_type", "verdict", "confidence", "ai_score", "detectors", "content_hash",
+}
+
+// ExportRecord is one line of a JSONL export.
+type ExportRecord struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ContentType string    `json:"content_type"`
+	Verdict     string    `json:"verdict"`
+	Confidence  float64   `json:"confidence"`
+	AIScore     float64   `json:"ai_score"`
+	Detectors   []string  `json:"detectors"`
+	ContentHash string    `json:"content_hash,omitempty"`
+
+	// Details is the stored detection breakdown, when there is one
+	Details *VerifyDetails `json:"details,omitempty"`
+}
+
+// exportWriter writes an export's records in one format.
+type exportWriter interface {
+	// Write writes one completed job
+	Write(job *repository.Job) error
+
+	// Flush writes out anything buffered
+	Flush() error
+}
+
+// ExportResults handles GET /verify/export requests.
+// Streams completed verification results, newest first, as CSV or JSONL
+// for spreadsheets and compliance archives. Results are read a page at a
+// time and written as they're read, so exports of any size use little
+// memory.
+//
+// Query parameters (all optional):
+//   - format: csv (the default) or jsonl; JSONL lines carry the stored
+//     detection breakdown
+//   - from, to: RFC 3339 bounds on created_at (default: the 30 days up to
+//     now); at most 366 days apart
+//   - content_type: only results of this content type
+//
+// Only the caller's results are exported: those submitted with its API key,
+// or without one for requests without a key. API keys only export the
+// content types their scopes allow verifying.
+func (h *Handler) ExportResults(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidParameter, "format must be csv or jsonl")
+		return
+	}
+
+	filter, err := h.parseExportWindow(query)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+	filter.ContentType = query.Get("content_type")
+	filter.Limit = repository.MaxListLimit
+	owner := apiKeyID(r.Context())
+	filter.APIKeyID = &owner
+
+	// A key only sees results of the kinds of content it may verify
+	allowed := func(contentType string) bool { return true }
+	if key := middleware.APIKeyFromContext(r.Context()); key != nil {
+		allowed = func(contentType string) bool {
+			for _, scope := range requiredScopes(service.DetectionInput{ContentType: service.ContentType(contentType)}, "") {
+				if !key.HasScope(scope) {
+					return false
+				}
+			}
+			return true
+		}
+		if filter.ContentType != "" && !allowed(filter.ContentType) {
+			h.writeError(w, http.StatusForbidden, ErrCodeInsufficientScope,
+				fmt.Sprintf("API key lacks the scope to export %s results", filter.ContentType))
+			return
+		}
+	}
+
+	// The first page is read before anything is written, so failing to
+	// list still gets an error response
+	jobs, next, err := h.repository.ListJobs(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list jobs for export", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to export results")
+		return
+	}
+
+	var out exportWriter
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		out = newCSVExport(w)
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/jsonl")
+		out = newJSONLExport(w)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="humanmark-export-%s-%s.%s"`,
+		filter.CreatedAfter.UTC().Format("20060102"), filter.CreatedBefore.UTC().Format("20060102"), format))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// Too late for an error response from here on; a failure ends the
+	// export early, which the client sees as a truncated body
+	rc := http.NewResponseController(w)
+	written := 0
+	for {
+		rc.SetWriteDeadline(time.Now().Add(exportPageTimeout))
+		for i := range jobs {
+			job := &jobs[i]
+			// Jobs without a status predate async mode and are complete
+			if (job.Status != "" && job.Status != repository.JobStatusCompleted) || !allowed(job.ContentType) {
+				continue
+			}
+			if err := out.Write(job); err != nil {
+				h.logger.Warn("export ended early", "error", err, "written", written)
+				return
+			}
+			written++
+		}
+
+		// Each page goes out as soon as it's written
+		if err := out.Flush(); err != nil {
+			h.logger.Warn("export ended early", "error", err, "written", written)
+			return
+		}
+		rc.Flush()
+
+		if next == "" {
+			return
+		}
+		filter.Cursor = next
+		if jobs, next, err = h.repository.ListJobs(r.Context(), filter); err != nil {
+			h.logger.Error("failed to list jobs for export", "error", err, "written", written)
+			return
+		}
+	}
+}
+
+// parseExportWindow reads an export's from and to, defaulting to the
+// defaultExportWindow up to now.
+func (h *Handler) parseExportWindow(query url.Values) (repository.ListFilter, error) {
+	var filter repository.ListFilter
+
+	bounds := []struct {
+		name string
+		t    *time.Time
+	}{
+		{"from", &filter.CreatedAfter},
+		{"to", &filter.CreatedBefore},
+	}
+	for _, bound := range bounds {
+		if v := query.Get(bound.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", bound.name)
+			}
+			*bound.t = t
+		}
+	}
+
+	if filter.CreatedBefore.IsZero() {
+		filter.CreatedBefore = h.now()
+	}
+	if filter.CreatedAfter.IsZero() {
+		filter.CreatedAfter = filter.CreatedBefore.Add(-defaultExportWindow)
+	}
+	if !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return filter, errors.New("from must be before to")
+	}
+	if filter.CreatedBefore.Sub(filter.CreatedAfter) > maxExportWindow {
+		return filter, fmt.Errorf("from and to must be at most %d days apart", int(maxExportWindow.Hours()/24))
+	}
+	return filter, nil
+}
+
+// csvExport writes an export as CSV, a header row then a row per job.
+type csvExport struct {
+	w *csv.Writer
+}
+
+// newCSVExport starts a CSV export with its header row, so even an empty
+// export is a valid file.
+func newCSVExport(w io.Writer) *csvExport {
+	cw := csv.NewWriter(w)
+	cw.Write(exportCSVHeader)
+	return &csvExport{w: cw}
+}
+
+func (e *csvExport) Write(job *repository.Job) error {
+	return e.w.Write([]string{
+		job.ID,
+		job.CreatedAt.UTC().Format(time.RFC3339),
+		job.ContentType,
+		jobVerdict(job),
+		strconv.FormatFloat(job.Confidence, 'f', 4, 64),
+		strconv.FormatFloat(job.AIScore, 'f', 4, 64),
+		strings.Join(job.Detectors, "; "),
+		job.ContentHash,
+	})
+}
+
+func (e *csvExport) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonlExport writes an export as JSON Lines, an ExportRecord per line.
+type jsonlExport struct {
+	enc *json.Encoder
+}
+
+func newJSONLExport(w io.Writer) *jsonlExport {
+	return &jsonlExport{enc: json.NewEncoder(w)}
+}
+
+func (e *jsonlExport) Write(job *repository.Job) error {
+	record := ExportRecord{
+		ID:          job.ID,
+		CreatedAt:   job.CreatedAt,
+		ContentType: job.ContentType,
+		Verdict:     jobVerdict(job),
+		Confidence:  job.Confidence,
+		AIScore:     job.AIScore,
+		Detectors:   job.Detectors,
+		ContentHash: job.ContentHash,
+	}
+	if job.Details != nil {
+		record.Details = resultResponse(job, true).Details
+	}
+	return e.enc.Encode(record)
+}
+
+// Flush does nothing: the encoder writes each line as it's encoded.
+func (e *jsonlExport) Flush() error {
+	return nil
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// flushRecorder is a ResponseRecorder that records how much of the body
+// had been written at each Flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.Body.Len())
+	r.ResponseRecorder.Flush()
+}
+
+// newExportHandler returns a Handler over a repository holding jobs.
+func newExportHandler(t *testing.T, jobs ...repository.Job) *Handler {
+	t.Helper()
+
+	repo := repository.NewMemory()
+	for _, job := range jobs {
+		if job.Status == "" {
+			job.Status = repository.JobStatusCompleted
+		}
+		if _, err := repo.CreateJob(context.Background(), job); err != nil {
+			t.Fatalf("CreateJob() error = %v", err)
+		}
+	}
+	return New(Config{
+		Detector:   &mockDetector{},
+		Repository: repo,
+		Logger:     logger.NopLogger(),
+	})
+}
+
+// TestExportResults_CSV tests the CSV export's rows and escaping.
+func TestExportResults_CSV(t *testing.T) {
+	h := newExportHandler(t,
+		repository.Job{ContentType: "text", Verdict: "ai", Confidence: 0.91, AIScore: 0.875, ContentHash: "abc123",
+			Detectors: []string{"stylometry", `gpt,zero`, `say "hi"`}},
+		repository.Job{Status: repository.JobStatusPending, ContentType: "video"},
+	)
+
+	rec := httptest.NewRecorder()
+	h.ExportResults(rec, httptest.NewRequest("GET", "/verify/export", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="humanmark-export-`) || !strings.HasSuffix(cd, `.csv"`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	// Commas and quotes in a field are quoted, not split
+	if !strings.Contains(rec.Body.String(), `"stylometry; gpt,zero; say ""hi"""`) {
+		t.Errorf("detectors not escaped: %s", rec.Body.String())
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a header and the completed job's row, got %v", records)
+	}
+	if strings.Join(records[0], ",") != "id,created_at,content_type,verdict,confidence,ai_score,detectors,content_hash" {
+		t.Errorf("header = %v", records[0])
+	}
+	row := records[1]
+	if row[2] != "text" || row[3] != "ai" || row[4] != "0.9100" || row[5] != "0.8750" || row[7] != "abc123" {
+		t.Errorf("row = %v", row)
+	}
+	if row[6] != `stylometry; gpt,zero; say "hi"` {
+		t.Errorf("detectors = %q", row[6])
+	}
+
+	t.Run("empty exports have the header", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newExportHandler(t).ExportResults(rec, httptest.NewRequest("GET", "/verify/export", nil))
+		if got := rec.Body.String(); got != strings.Join(exportCSVHeader, ",")+"\n" {
+			t.Errorf("body = %q", got)
+		}
+	})
+}
+
+// TestExportResults_JSONL tests that JSONL lines carry the stored details.
+func TestExportResults_JSONL(t *testing.T) {
+	h := newExportHandler(t,
+		repository.Job{ContentType: "text", Verdict: "human", Confidence: 0.8, Detectors: []string{"stylometry"}},
+		repository.Job{ContentType: "image", Verdict: "ai", Confidence: 0.7, Detectors: []string{"hive"},
+			Details: &repository.Details{Aggregation: "weighted", Signals: []repository.Signal{{Name: "hive", AIScore: 0.7, Weight: 1}}}},
+	)
+
+	rec := httptest.NewRecorder()
+	h.ExportResults(rec, httptest.NewRequest("GET", "/verify/export?format=jsonl", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/jsonl" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	records := make(map[string]ExportRecord)
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var record ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		records[record.ContentType] = record
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(records))
+	}
+
+	image, text := records["image"], records["text"]
+	if image.Details == nil || len(image.Details.Signals) != 1 {
+		t.Errorf("expected the image's details, got %+v", image)
+	}
+	if text.Verdict != "human" || text.Details != nil {
+		t.Errorf("expected no details for the text, got %+v", text)
+	}
+}
+
+// TestExportResults_Streamed tests that exports are written and flushed a
+// page at a time.
+func TestExportResults_Streamed(t *testing.T) {
+	jobs := make([]repository.Job, repository.MaxListLimit*2+50)
+	for i := range jobs {
+		jobs[i] = repository.Job{ContentType: "text", Verdict: "human", Detectors: []string{"stylometry"}}
+	}
+	h := newExportHandler(t, jobs...)
+
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ExportResults(rec, httptest.NewRequest("GET", "/verify/export", nil))
+	if len(rec.flushed) != 3 {
+		t.Fatalf("expected a flush per page, got %d", len(rec.flushed))
+	}
+	for i := 1; i < len(rec.flushed); i++ {
+		if rec.flushed[i] <= rec.flushed[i-1] {
+			t.Errorf("flush %d wrote nothing new: %v", i, rec.flushed)
+		}
+	}
+	if rows := strings.Count(rec.Body.String(), "\n"); rows != len(jobs)+1 {
+		t.Errorf("expected %d rows and the header, got %d lines", len(jobs), rows)
+	}
+
+	t.Run("served chunked", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(h.ExportResults))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/verify/export")
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.ContentLength != -1 || len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+			t.Errorf("expected a chunked response, got length %d, encoding %v", resp.ContentLength, resp.TransferEncoding)
+		}
+	})
+}
+
+// TestExportResults_Parameters tests the export window and format checks.
+func TestExportResults_Parameters(t *testing.T) {
+	h := newExportHandler(t)
+	h.now = func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"defaults", "", http.StatusOK},
+		{"unknown format", "?format=xlsx", http.StatusBadRequest},
+		{"bad timestamp", "?from=yesterday", http.StatusBadRequest},
+		{"from after to", "?from=2024-05-01T00:00:00Z&to=2024-04-01T00:00:00Z", http.StatusBadRequest},
+		{"window too long", "?from=2022-01-01T00:00:00Z&to=2024-01-01T00:00:00Z", http.StatusBadRequest},
+		{"a year", "?from=2023-01-01T00:00:00Z&to=2024-01-01T00:00:00Z", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ExportResults(rec, httptest.NewRequest("GET", "/verify/export"+tt.query, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ExportResults(rec, httptest.NewRequest("GET", "/verify/export", nil))
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="humanmark-export-20240502-20240601.csv"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+}
+
+// TestExportResults_APIKeyScopes tests that keys only export the content
+// types they may verify.
+func TestExportResults_APIKeyScopes(t *testing.T) {
+	h := newExportHandler(t,
+		repository.Job{ContentType: "text", Verdict: "human", APIKeyID: "k1"},
+		repository.Job{ContentType: "video", Verdict: "ai", APIKeyID: "k1"},
+		repository.Job{ContentType: "document", Verdict: "human", APIKeyID: "k1"},
+	)
+	export := func(key *repository.APIKey, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/verify/export"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+		rec := httptest.NewRecorder()
+		h.ExportResults(rec, req)
+		return rec
+	}
+	textKey := &repository.APIKey{ID: "k1", Scopes: []string{repository.ScopeVerifyText}}
+
+	rec := export(textKey, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "video") || !strings.Contains(body, "text") || !strings.Contains(body, "document") {
+		t.Errorf("expected only text and document rows, got %s", body)
+	}
+
+	if rec := export(textKey, "?content_type=video"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 exporting video with a text key, got %d", rec.Code)
+	}
+
+	allKey := &repository.APIKey{ID: "k1", Scopes: repository.Scopes}
+	if body := export(allKey, "").Body.String(); strings.Count(body, "\n") != 4 {
+		t.Errorf("expected every row for a full key, got %s", body)
+	}
+}
+
+// TestExportResults_OtherKeys tests that an export only holds the calling
+// key's results, whatever its scopes.
+func TestExportResults_OtherKeys(t *testing.T) {
+	h := newExportHandler(t,
+		repository.Job{ContentType: "text", Verdict: "human", ContentHash: "mine", APIKeyID: "k1"},
+		repository.Job{ContentType: "text", Verdict: "ai", ContentHash: "theirs", APIKeyID: "k2"},
+		repository.Job{ContentType: "text", Verdict: "ai", ContentHash: "anonymous"},
+	)
+	export := func(key *repository.APIKey) string {
+		req := httptest.NewRequest("GET", "/verify/export?format=jsonl", nil)
+		if key != nil {
+			req = req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+		}
+		rec := httptest.NewRecorder()
+		h.ExportResults(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var hashes []string
+		for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+			var record ExportRecord
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("invalid line %q: %v", line, err)
+			}
+			hashes = append(hashes, record.ContentHash)
+		}
+		return strings.Join(hashes, ",")
+	}
+
+	if got := export(&repository.APIKey{ID: "k1", Scopes: repository.Scopes}); got != "mine" {
+		t.Errorf("k1 exported %s, want only its own result", got)
+	}
+	if got := export(&repository.APIKey{ID: "k2", Scopes: repository.Scopes}); got != "theirs" {
+		t.Errorf("k2 exported %s, want only its own result", got)
+	}
+	if got := export(nil); got != "anonymous" {
+		t.Errorf("request without a key exported %s, want only results without one", got)
+	}
+}
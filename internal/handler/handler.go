@@ -6,7 +6,14 @@
 // Input formats:
 //   - JSON body with "url" field: {"url": "https://example.com/image.jpg"}
 //   - JSON body with "text" field: {"text": "content to verify"}
+//   - JSON body with "base64_data" field: {"base64_data": "iVBORw0KGgo..."}
+//   - JSON body with a data URI as "url": {"url": "data:image/png;base64,iVBORw0KGgo..."}
 //   - Multipart form with "file" field: file upload
+//   - text/plain body: the text to verify
+//   - URL-encoded form with "text" or "url" field: text=content+to+verify
+//
+// With ?mode=page, a "url" pointing at an HTML page is verified along with
+// the images and videos it embeds.
 //
 // Output format:
 //
@@ -20,25 +27,72 @@
 package handler
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/humanmark/humanmark/internal/audit"
+	"github.com/humanmark/humanmark/internal/middleware"
 	"github.com/humanmark/humanmark/internal/repository"
 	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/cert"
 	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/minhash"
+	"github.com/humanmark/humanmark/pkg/problem"
 )
 
+// JobQueue accepts jobs for background processing.
+// Implemented by worker.Pool. Once Enqueue succeeds the queue owns input,
+// and closes it when the job is done.
+type JobQueue interface {
+	Enqueue(jobID string, input service.DetectionInput) error
+}
+
 // Handler holds dependencies for HTTP handlers.
 // Using a struct allows for easy dependency injection and testing.
 type Handler struct {
-	detector      service.Detector
-	repository    repository.Repository
-	logger        *logger.Logger
-	maxUploadSize int64
+	detector       service.Detector
+	pages          service.PageDetector
+	repository     repository.Repository
+	logger         *logger.Logger
+	maxUploadSize  int64
+	maxFiles       int
+	uploadMemory   int64
+	queue          JobQueue
+	asyncThreshold int64
+	callbacks      bool
+	requireHTTPS   bool
+	providers      bool
+	metrics        metrics.Exporter
+	monthlyQuota   int64
+	certificates   *cert.Signer
+
+	// now is the clock usage is metered by (time.Now outside tests)
+	now func() time.Time
+
+	// reload swaps in re-read detection configuration (nil disables
+	// POST /admin/reload)
+	reload func() error
+
+	// auditLog records every verdict handed out (nil records nothing)
+	auditLog audit.Logger
+
+	// profiles are the analyzer profiles ?profile= picks from, by
+	// lowercase name
+	profiles map[string]Profile
 }
 
 // Config holds configuration for creating a Handler.
@@ -47,50 +101,286 @@ type Config struct {
 	Repository    repository.Repository
 	Logger        *logger.Logger
 	MaxUploadSize int64
+
+	// MaxFiles is how many files one multipart upload may hold (0 =
+	// DefaultMaxFiles); together they're limited to MaxUploadSize
+	MaxFiles int
+
+	// UploadMemoryLimit is the largest uploaded file kept in memory;
+	// bigger ones are written to a temp file, removed when the request (or
+	// its async job) is done (0 = DefaultUploadMemoryLimit)
+	UploadMemoryLimit int64
+
+	// Queue enables async verification (optional - nil disables async mode)
+	Queue JobQueue
+
+	// AsyncThreshold switches requests with a larger Content-Length to async
+	// mode automatically (0 = only when ?async=true)
+	AsyncThreshold int64
+
+	// CallbacksEnabled accepts callback_url on async requests
+	// (requires Queue and a webhook sender on the worker pool)
+	CallbacksEnabled bool
+
+	// RequireHTTPSCallbacks rejects plain http callback URLs (set in production)
+	RequireHTTPSCallbacks bool
+
+	// ProvidersConfigured reports that at least one external detection API
+	// key is set; readiness fails without one
+	ProvidersConfigured bool
+
+	// Metrics is served on GET /metrics (optional - nil returns 404)
+	Metrics metrics.Exporter
+
+	// PageDetector enables ?mode=page (optional - nil returns 501)
+	PageDetector service.PageDetector
+
+	// MonthlyQuota is how many verifications each API key may make per
+	// UTC calendar month (0 = unlimited)
+	MonthlyQuota int64
+
+	// Certificates signs GET /verify/{id}/certificate and publishes its
+	// keys on GET /.well-known/humanmark.json (optional - nil returns 404)
+	Certificates *cert.Signer
+
+	// Reload re-reads the detection configuration and swaps it into
+	// Detector, for POST /admin/reload (optional - nil returns 404)
+	Reload func() error
+
+	// AuditLog records every verdict handed out, for compliance
+	// (optional - nil records nothing). A failed write doesn't fail the
+	// request.
+	AuditLog audit.Logger
+
+	// Profiles are named sets of analyzer options requests pick with
+	// ?profile=NAME (optional - names are case-insensitive)
+	Profiles map[string]Profile
 }
 
+// DefaultMaxFiles is how many files a multipart upload may hold unless
+// Config.MaxFiles says otherwise.
+const DefaultMaxFiles = 10
+
+// DefaultUploadMemoryLimit is the largest uploaded file kept in memory
+// unless Config.UploadMemoryLimit says otherwise.
+const DefaultUploadMemoryLimit = 8 * 1024 * 1024
+
 // New creates a new Handler with the given configuration.
 func New(cfg Config) *Handler {
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = DefaultMaxFiles
+	}
+	if cfg.UploadMemoryLimit <= 0 {
+		cfg.UploadMemoryLimit = DefaultUploadMemoryLimit
+	}
+	profiles := make(map[string]Profile, len(cfg.Profiles))
+	for name, p := range cfg.Profiles {
+		profiles[strings.ToLower(name)] = p
+	}
 	return &Handler{
-		detector:      cfg.Detector,
-		repository:    cfg.Repository,
-		logger:        cfg.Logger,
-		maxUploadSize: cfg.MaxUploadSize,
+		detector:       cfg.Detector,
+		pages:          cfg.PageDetector,
+		repository:     cfg.Repository,
+		logger:         cfg.Logger,
+		maxUploadSize:  cfg.MaxUploadSize,
+		maxFiles:       cfg.MaxFiles,
+		uploadMemory:   cfg.UploadMemoryLimit,
+		queue:          cfg.Queue,
+		asyncThreshold: cfg.AsyncThreshold,
+		callbacks:      cfg.CallbacksEnabled && cfg.Queue != nil,
+		requireHTTPS:   cfg.RequireHTTPSCallbacks,
+		providers:      cfg.ProvidersConfigured,
+		metrics:        cfg.Metrics,
+		monthlyQuota:   cfg.MonthlyQuota,
+		certificates:   cfg.Certificates,
+		now:            time.Now,
+		reload:         cfg.Reload,
+		auditLog:       cfg.AuditLog,
+		profiles:       profiles,
+	}
+}
+
+// configFingerprint returns the ConfigFingerprint detector's results carry,
+// or "" if it can't tell without running. It's asked each time, since a
+// reload changes it.
+func configFingerprint(detector service.Detector) string {
+	if f, ok := detector.(service.ConfigFingerprinter); ok {
+		return f.ConfigFingerprint()
 	}
+	return ""
 }
 
 // VerifyRequest represents the JSON request body for /verify endpoint.
 type VerifyRequest struct {
-	// URL of content to verify (image, video, audio, or webpage)
+	// URL of content to verify (image, video, audio, or webpage), or a
+	// data: URI holding the content itself
 	URL string `json:"url,omitempty"`
 
 	// Text content to verify directly
 	Text string `json:"text,omitempty"`
+
+	// Base64Data is binary content (image, audio, video) encoded as base64.
+	// Useful for browser and mobile clients that can't send multipart uploads.
+	Base64Data string `json:"base64_data,omitempty"`
+
+	// DataBase64 is another name for Base64Data
+	DataBase64 string `json:"data_base64,omitempty"`
+
+	// ContentType is the MIME type of a base64 payload, e.g. "audio/mpeg".
+	// Without it the type is detected from the decoded bytes.
+	ContentType string `json:"content_type,omitempty"`
+
+	// CallbackURL receives a signed POST when the job finishes.
+	// Setting it implies async processing.
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// CustomStopPhrases are AI phrases that are normal in the caller's
+	// domain and shouldn't count toward the text score
+	CustomStopPhrases []string `json:"custom_stop_phrases,omitempty"`
+
+	// CustomCommonWords are treated as common vocabulary
+	CustomCommonWords []string `json:"custom_common_words,omitempty"`
+
+	// Detectors limits verification to these detectors, e.g. ["humanmark"]
+	// to keep content on this server
+	Detectors []string `json:"detectors,omitempty"`
+
+	// ExcludeDetectors are detectors not to run
+	ExcludeDetectors []string `json:"exclude_detectors,omitempty"`
 }
 
+// maxCustomWords caps each custom vocabulary list in a request.
+const maxCustomWords = 100
+
 // VerifyResponse represents the JSON response from /verify endpoint.
 type VerifyResponse struct {
 	// ID is the unique identifier for this verification job
 	ID string `json:"id"`
 
+	// Status is the job state: pending, processing, completed, failed
+	Status string `json:"status,omitempty"`
+
 	// Human is true if the content was created by a human
+	// (false for uncertain verdicts)
 	Human bool `json:"human"`
 
+	// Verdict is human, ai, or uncertain
+	Verdict string `json:"verdict"`
+
 	// Confidence is how confident we are in the verdict (0.0-1.0)
 	// Higher means more confident
 	Confidence float64 `json:"confidence"`
 
-	// ContentType is the detected type: text, image, audio, video
+	// ContentType is the detected type: text, image, audio, video, document
 	ContentType string `json:"content_type"`
 
+	// NeedsReview is true when the detectors disagreed enough that a
+	// person should check the verdict; Confidence is capped
+	NeedsReview bool `json:"needs_review,omitempty"`
+
+	// Basis is what the verdict rests on: local-only, local+N-external
+	// (N external detectors agreed to score it), or conclusive-marker.
+	// Local-only Confidence is capped.
+	Basis string `json:"basis,omitempty"`
+
 	// CreatedAt is when the verification was performed
 	CreatedAt time.Time `json:"created_at"`
 
+	// AnalyzerVersion is the version of the detection logic used
+	AnalyzerVersion string `json:"analyzer_version,omitempty"`
+
+	// ConfigFingerprint identifies the weights and thresholds used.
+	// Results are only comparable when it matches.
+	ConfigFingerprint string `json:"config_fingerprint,omitempty"`
+
+	// ParentID is the page job an embedded asset was verified with
+	ParentID string `json:"parent_id,omitempty"`
+
+	// Reused is true when the content was verified before and this is the
+	// stored result; CreatedAt is when it was first verified
+	Reused bool `json:"reused,omitempty"`
+
 	// Details contains additional information about the detection
 	// Only included if the request asked for detailed response
 	Details *VerifyDetails `json:"details,omitempty"`
 }
 
+// PageVerifyResponse is the response from /verify?mode=page. The top-level
+// verdict is on the page's main text (uncertain if it had too little) and
+// the content type is "page"; each embedded image and video has its own
+// verdict in Assets.
+type PageVerifyResponse struct {
+	VerifyResponse
+
+	// URL is the page URL after redirects
+	URL string `json:"url"`
+
+	// HasText is false if the page had too little text to verify
+	HasText bool `json:"has_text"`
+
+	// Assets are the page's images and videos, in page order
+	Assets []PageAssetResponse `json:"assets"`
+
+	// DroppedAssets counts assets over the per-page limit that weren't
+	// verified
+	DroppedAssets int `json:"dropped_assets,omitempty"`
+}
+
+// PageAssetResponse is one image or video embedded in a page.
+type PageAssetResponse struct {
+	// URL is the asset's absolute URL
+	URL string `json:"url"`
+
+	// ContentType is image or video
+	ContentType string `json:"content_type"`
+
+	// Result is the asset's verdict (omitted if Error is set)
+	Result *VerifyResponse `json:"result,omitempty"`
+
+	// Error says why the asset couldn't be verified
+	Error string `json:"error,omitempty"`
+}
+
+// FilesVerifyResponse is the 207 response to an upload of several files.
+type FilesVerifyResponse struct {
+	// Files has each file's outcome, in upload order (with fields mixed,
+	// "file" comes first, then "files[]", then "files")
+	Files []FileResponse `json:"files"`
+}
+
+// FileResponse is one file of a multi-file upload.
+type FileResponse struct {
+	// Filename is the name the file was uploaded with
+	Filename string `json:"filename"`
+
+	// Result is the file's verdict and job (omitted if Error is set)
+	Result *VerifyResponse `json:"result,omitempty"`
+
+	// Error says why the file couldn't be verified, and Code is its error
+	// code, as for a single upload
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// VerifyResponseSchema describes a well-formed 200 response from Verify.
+// Used with middleware.ValidateResponse outside production.
+func VerifyResponseSchema() middleware.ResponseSchema {
+	unitMin, unitMax := middleware.Bounds(0, 1)
+
+	return middleware.ResponseSchema{
+		Name: "VerifyResponse",
+		Fields: []middleware.FieldRule{
+			{Path: "id", Type: middleware.TypeString, Required: true, NonEmpty: true},
+			{Path: "human", Type: middleware.TypeBool, Required: true},
+			{Path: "verdict", Type: middleware.TypeString, Required: true, NonEmpty: true},
+			{Path: "confidence", Type: middleware.TypeNumber, Required: true, Min: unitMin, Max: unitMax},
+			{Path: "content_type", Type: middleware.TypeString, Required: true, NonEmpty: true},
+			{Path: "details.ai_score", Type: middleware.TypeNumber, Min: unitMin, Max: unitMax},
+			{Path: "details.detectors", Type: middleware.TypeArray, NonEmpty: true},
+		},
+	}
+}
+
 // VerifyDetails contains detailed detection information.
 type VerifyDetails struct {
 	// Detectors lists which detection methods were used
@@ -100,8 +390,70 @@ type VerifyDetails struct {
 	// 0.0 = definitely human, 1.0 = definitely AI
 	AIScore float64 `json:"ai_score"`
 
+	// Aggregation is the strategy used to combine detector scores
+	Aggregation string `json:"aggregation,omitempty"`
+
+	// Disagreement is the weighted standard deviation of detector scores
+	// (0-0.5)
+	Disagreement float64 `json:"disagreement"`
+
+	// Evidence is conclusive provenance found in the content: ai, human
+	Evidence string `json:"evidence,omitempty"`
+
 	// Signals contains individual detector results
 	Signals []DetectorSignal `json:"signals,omitempty"`
+
+	// DetectorOutputs contains per-sentence scores from detectors that provide them
+	DetectorOutputs []DetectorOutput `json:"detector_outputs,omitempty"`
+
+	// SentenceAgreement compares GPTZero's per-sentence scores with each
+	// other detector's that scored the same sentences
+	SentenceAgreement []SentenceAgreement `json:"sentence_agreement,omitempty"`
+
+	// Skipped lists configured detectors that declined the input
+	Skipped []SkippedDetector `json:"skipped,omitempty"`
+
+	// MismatchWarning is set when the file extension or declared MIME type
+	// disagrees with the content, which was analyzed as what its bytes say it is
+	MismatchWarning string `json:"mismatch_warning,omitempty"`
+
+	// SimilarContent points at an earlier job with nearly the same content
+	SimilarContent *SimilarContent `json:"similar_content,omitempty"`
+
+	// HostReputation is the host rule that adjusted a URL input's score
+	HostReputation *HostReputation `json:"host_reputation,omitempty"`
+
+	// DetectorErrors lists detectors that failed; the verdict is based on
+	// the rest
+	DetectorErrors map[string]string `json:"detector_errors,omitempty"`
+
+	// AIPhrases are the AI phrases found in text content
+	AIPhrases []AIPhrase `json:"ai_phrases,omitempty"`
+
+	// Watermark is the experimental watermark scan of text content. It
+	// doesn't affect the verdict.
+	Watermark *Watermark `json:"watermark,omitempty"`
+
+	// ProcessingTimeMs is how long detection took
+	ProcessingTimeMs int64 `json:"processing_time_ms,omitempty"`
+
+	// Truncated is set when a stored result's lists were shortened to fit
+	// storage limits
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// HostReputation is a URL host rule applied to the score.
+type HostReputation struct {
+	Host       string  `json:"host"`
+	Rule       string  `json:"rule"`
+	Adjustment float64 `json:"adjustment"`
+	Reason     string  `json:"reason"`
+}
+
+// SimilarContent is an earlier job whose content is a near-duplicate.
+type SimilarContent struct {
+	JobID      string  `json:"job_id"`
+	Similarity float64 `json:"similarity"`
 }
 
 // DetectorSignal represents a single detector's output.
@@ -111,23 +463,214 @@ type DetectorSignal struct {
 	Confidence float64 `json:"confidence"`
 }
 
-// ErrorResponse represents an error response.
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    string `json:"code,omitempty"`
-	Details string `json:"details,omitempty"`
+// DetectorOutput is one detector's per-sentence output.
+type DetectorOutput struct {
+	Name       string            `json:"name"`
+	Sentences  []SentenceSignal  `json:"sentences"`
+	Paragraphs []ParagraphSignal `json:"paragraphs,omitempty"`
+}
+
+// SentenceSignal is a detector's score for one sentence.
+type SentenceSignal struct {
+	Text    string  `json:"text"`
+	AIScore float64 `json:"ai_score"`
+}
+
+// ParagraphSignal is a detector's score for a run of its sentences,
+// starting at index StartSentence.
+type ParagraphSignal struct {
+	StartSentence int     `json:"start_sentence"`
+	Sentences     int     `json:"sentences"`
+	AIScore       float64 `json:"ai_score"`
+}
+
+// SentenceAgreement is how closely two detectors' per-sentence scores agree.
+type SentenceAgreement struct {
+	// Detectors are the two detectors compared
+	Detectors []string `json:"detectors"`
+
+	// Sentences is how many sentences both scored
+	Sentences int `json:"sentences"`
+
+	// Agreement is the fraction of those sentences both put on the same
+	// side of 0.5
+	Agreement float64 `json:"agreement"`
+
+	// MeanDifference is the mean absolute difference of their scores
+	MeanDifference float64 `json:"mean_difference"`
+}
+
+// AIPhrase is an AI phrase found in text content.
+type AIPhrase struct {
+	Phrase   string `json:"phrase"`
+	Category string `json:"category"`
+}
+
+// Watermark is the experimental scan for the word frequency skew some LLM
+// watermarks leave, against ordinary English prose.
+type Watermark struct {
+	// Experimental is always true: the scan is under evaluation
+	Experimental bool `json:"experimental"`
+
+	// Score is 1 - PValue (0.0-1.0; higher = more skewed)
+	Score float64 `json:"score"`
+
+	// PValue is the chance of a skew this large without a watermark
+	PValue float64 `json:"p_value"`
+
+	// Flagged is set when the skew is statistically significant
+	Flagged bool `json:"flagged"`
+
+	// Words and WordPairs are how many common words and word pairs were
+	// compared
+	Words     int `json:"words"`
+	WordPairs int `json:"word_pairs"`
+
+	// ChiSquare is the length-adjusted test statistic, with
+	// DegreesOfFreedom
+	ChiSquare        float64 `json:"chi_square"`
+	DegreesOfFreedom int     `json:"degrees_of_freedom"`
+}
+
+// SkippedDetector is a detector that didn't score the input, with the reason.
+type SkippedDetector struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// JobStatusResponse is returned for async jobs that have no result yet.
+type JobStatusResponse struct {
+	// ID is the unique identifier for this verification job
+	ID string `json:"id"`
+
+	// Status is the job state: pending, processing, failed
+	Status string `json:"status"`
+
+	// Error describes why the job failed
+	Error string `json:"error,omitempty"`
+
+	// Attempts is how many times a worker started the job
+	Attempts int `json:"attempts,omitempty"`
+
+	// CreatedAt is when the job was submitted
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt is when the job last changed state
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// CompletedAt is when the job failed
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ErrCodeInvalidBase64 is the error code returned when base64_data can't be decoded.
+const ErrCodeInvalidBase64 = "invalid_base64"
+
+// ErrCodeInvalidDataURI is the error code returned when a data: URI can't
+// be decoded.
+const ErrCodeInvalidDataURI = "invalid_data_uri"
+
+// ErrCodeUnreadableDocument is the error code returned when no text can be
+// extracted from a PDF, DOCX, ODT, RTF or message plist upload.
+const ErrCodeUnreadableDocument = "unreadable_document"
+
+// ErrCodeInvalidDetectors is the error code returned when a request selects
+// an unknown or unconfigured detector.
+const ErrCodeInvalidDetectors = "invalid_detectors"
+
+// ErrCodeAllDetectorsFailed is the error code returned when no detector
+// could score the content.
+const ErrCodeAllDetectorsFailed = "all_detectors_failed"
+
+// ErrCodeUnsupportedContentType is the error code returned for content no
+// detector handles.
+const ErrCodeUnsupportedContentType = "unsupported_content_type"
+
+// ErrCodeContentTooLarge is the error code returned when fetched content
+// exceeds the size limit for its type.
+const ErrCodeContentTooLarge = "content_too_large"
+
+// ErrCodeFetchFailed is the error code returned when a submitted URL
+// can't be fetched.
+const ErrCodeFetchFailed = "fetch_failed"
+
+// ErrCodeInvalidMode is the error code returned for an unknown mode.
+const ErrCodeInvalidMode = "invalid_mode"
+
+// ErrCodeEmptyPage is the error code returned when a page verified with
+// mode=page has no text or media to verify.
+const ErrCodeEmptyPage = "empty_page"
+
+// ErrCodePageModeUnavailable is the error code returned for mode=page when
+// the server has no PageDetector.
+const ErrCodePageModeUnavailable = "page_mode_unavailable"
+
+// ErrCodeProviderUnavailable is the error code returned when detection
+// failed because the external providers were unreachable or down.
+const ErrCodeProviderUnavailable = "provider_unavailable"
+
+// ErrCodeInsufficientScope is the error code returned when the request's API
+// key lacks the scope for its content.
+const ErrCodeInsufficientScope = "insufficient_scope"
+
+// ErrCodeQuotaExceeded is the error code returned when the request's API key
+// has used its monthly quota.
+const ErrCodeQuotaExceeded = "quota_exceeded"
+
+// ErrCodeOverloaded is the error code returned when the server is already
+// analyzing as much content of the request's type as it allows.
+const ErrCodeOverloaded = "overloaded"
+
+// ErrCodeRequestTimeout is the error code returned when detection ran past
+// the request's timeout.
+const ErrCodeRequestTimeout = "request_timeout"
+
+// ErrCodeTooManyFiles is the error code returned for a multipart upload
+// with more files than allowed.
+const ErrCodeTooManyFiles = "too_many_files"
+
+// ErrCodeUnsupportedMediaType is the error code returned for a POST /verify
+// body of a Content-Type it doesn't read.
+const ErrCodeUnsupportedMediaType = "unsupported_media_type"
+
+// verifyMediaTypes are the request Content-Types POST /verify reads.
+var verifyMediaTypes = []string{
+	"application/json", "multipart/form-data", "application/x-www-form-urlencoded", "text/plain",
 }
 
+// ErrorResponse is the body of every error response: RFC 9457 problem
+// details, with the error code in "code" and the message in "detail".
+type ErrorResponse = problem.Problem
+
 // Verify handles POST /verify requests.
 // This is the main endpoint of the HumanMark API.
 //
-// It accepts three types of input:
-//  1. JSON with "url": fetches and analyzes content from URL
+// It accepts these types of input:
+//  1. JSON with "url": fetches and analyzes content from URL (a data: URI
+//     is decoded instead)
 //  2. JSON with "text": analyzes the provided text directly
-//  3. Multipart form with "file": analyzes uploaded file
+//  3. JSON with "base64_data": decodes and analyzes binary content
+//  4. Multipart form with "file": analyzes uploaded file
+//  5. Multipart form with several files (in "file", "files[]" or "files",
+//     repeated as needed): analyzes each, see verifyFiles
+//  6. A text/plain body: analyzes the body as text
+//  7. A URL-encoded form with "text" or "url", like the JSON fields
+//
+// Requests without a Content-Type are read as JSON; other types get 415.
 //
 // Query parameters:
 //   - detailed=true: include detailed detection information
+//   - reuse=false: run detection even if the content was verified before
+//   - async=true: queue the job and return 202 immediately (poll GET /verify/{id})
+//   - email=true: analyze text as an email (headers and quoted replies stripped)
+//   - academic=true: analyze text as academic writing (citations and hedges
+//     count as human, generic formality as AI)
+//   - mode=page: verify the HTML page at "url" and its images and videos
+//     (always synchronous)
+//   - profile=NAME: use a configured analyzer profile's options where the
+//     request doesn't set its own
+//
+// Requests larger than the configured async threshold, or with a callback_url,
+// are queued automatically.
 func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
 	// Set JSON content type for response
 	w.Header().Set("Content-Type", "application/json")
@@ -136,185 +679,1147 @@ func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
 
 	var input service.DetectionInput
+	var inputs []service.DetectionInput
 	var err error
 
+	// Uploads spilled to temp files are removed once verified, however
+	// that ends, unless an async job has taken them over
+	queued := false
+	defer func() {
+		if !queued {
+			closeInputs(inputs)
+		}
+	}()
+
 	switch {
 	case strings.HasPrefix(contentType, "multipart/form-data"):
-		input, err = h.parseMultipartInput(r)
+		inputs, err = h.parseMultipartInputs(r)
+		if len(inputs) > 1 {
+			h.verifyFiles(w, r, inputs)
+			return
+		}
+		if err == nil {
+			input = inputs[0]
+		}
 	case strings.HasPrefix(contentType, "application/json"):
 		input, err = h.parseJSONInput(r)
-	default:
-		// Try to parse as JSON by default
+	case strings.HasPrefix(contentType, "text/plain"):
+		input, err = h.parseTextInput(r)
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		input, err = h.parseFormInput(r)
+	case contentType == "":
+		// Clients that don't say are assumed to send JSON
 		input, err = h.parseJSONInput(r)
+	default:
+		w.Header().Set("Accept-Post", strings.Join(verifyMediaTypes, ", "))
+		h.writeError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType,
+			fmt.Sprintf("unsupported Content-Type %q: use %s", contentType, strings.Join(verifyMediaTypes, ", ")))
+		return
 	}
 
+	if errors.Is(err, errTooManyFiles) {
+		h.writeError(w, http.StatusBadRequest, ErrCodeTooManyFiles, err.Error())
+		return
+	}
 	if err != nil {
 		h.writeError(w, http.StatusBadRequest, "invalid_input", err.Error())
 		return
 	}
 
+	input.EmailMode = r.URL.Query().Get("email") == "true"
+	input.AcademicMode = r.URL.Query().Get("academic") == "true"
+
+	profile, err := h.profile(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, ErrCodeUnknownProfile, err.Error())
+		return
+	}
+	profile.apply(&input)
+
+	// Decode inline content now so the upload limit applies to its decoded
+	// size
+	input, err = service.DecodeInlineData(input)
+	if err != nil {
+		h.writeDetectError(w, h.logger.WithContext(r.Context()), err)
+		return
+	}
+
 	// Validate input
 	if err := h.validateInput(input); err != nil {
 		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
 		return
 	}
 
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "page" {
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidMode, fmt.Sprintf("unknown mode %q (supported: page)", mode))
+		return
+	}
+
+	// Authenticated keys must be allowed to verify this kind of content
+	if key := middleware.APIKeyFromContext(r.Context()); key != nil {
+		for _, scope := range requiredScopes(input, mode) {
+			if !key.HasScope(scope) {
+				h.writeError(w, http.StatusForbidden, ErrCodeInsufficientScope, fmt.Sprintf("API key lacks the %s scope", scope))
+				return
+			}
+		}
+		if !h.checkQuota(w, r, key, 1) {
+			return
+		}
+	}
+
+	// Slow content types get a longer timeout than the default
+	expected := expectedContentType(input)
+	ctx, cancel := middleware.WithTimeoutBudget(r.Context(), string(expected))
+	defer cancel()
+	r = r.WithContext(ctx)
+
 	// Get request context for logging
-	ctx := r.Context()
 	log := h.logger.WithContext(ctx)
 
 	log.Debug("processing verification request",
 		"content_type", input.ContentType,
 		"has_url", input.URL != "",
 		"has_text", len(input.Text) > 0,
-		"has_data", len(input.Data) > 0,
+		"has_data", input.HasData(),
 	)
 
-	// Perform detection
-	result, err := h.detector.Detect(ctx, input)
-	if err != nil {
-		log.Error("detection failed", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "detection_failed", "Failed to analyze content")
+	if mode == "page" {
+		h.verifyPage(w, r, input)
 		return
 	}
 
-	// Store result
-	job, err := h.repository.CreateJob(ctx, repository.Job{
-		ContentType: string(result.ContentType),
-		Human:       result.Human,
-		Confidence:  result.Confidence,
-		AIScore:     result.AIScore,
-		Detectors:   result.Detectors,
-		ContentHash: result.ContentHash,
-	})
-	if err != nil {
-		log.Error("failed to store result", "error", err)
-		// Continue - we can still return the result even if storage fails
+	// Large media goes to the background workers
+	if h.shouldRunAsync(r, input) {
+		queued = h.verifyAsync(w, r, input)
+		return
 	}
 
-	// Build response
-	response := VerifyResponse{
-		ID:          job.ID,
-		Human:       result.Human,
-		Confidence:  result.Confidence,
-		ContentType: string(result.ContentType),
-		CreatedAt:   job.CreatedAt,
-	}
+	// Content verified before with the current analyzer is answered from
+	// its stored job
+	share := shareResult(r, input)
+	if share {
+		if job := h.findReusableJob(ctx, log, input); job != nil {
+			event := usageEvent(input, nil)
+			event.ContentType = job.ContentType
+			h.recordUsage(ctx, log, event)
+			h.auditVerdict(r, log, job, true)
 
-	// Include details if requested
-	if r.URL.Query().Get("detailed") == "true" {
-		response.Details = &VerifyDetails{
-			Detectors: result.Detectors,
-			AIScore:   result.AIScore,
+			response := resultResponse(job, r.URL.Query().Get("detailed") == "true")
+			response.Reused = true
+			h.writeJSON(w, http.StatusOK, response)
+			return
 		}
 	}
 
-	// Write response
-	h.writeJSON(w, http.StatusOK, response)
-}
-
-// parseJSONInput parses JSON request body into DetectionInput.
-func (h *Handler) parseJSONInput(r *http.Request) (service.DetectionInput, error) {
-	var req VerifyRequest
-	
-	// Limit body size for JSON requests
-	body := http.MaxBytesReader(nil, r.Body, 10*1024*1024) // 10MB max for JSON
-	defer body.Close()
-
-	if err := json.NewDecoder(body).Decode(&req); err != nil {
-		if err == io.EOF {
-			return service.DetectionInput{}, errors.New("empty request body")
+	// Expensive content types have their own, smaller concurrency pools
+	release, err := middleware.AcquireSlot(ctx, string(expected))
+	if err != nil {
+		if ctx.Err() == nil {
+			h.writeOverloaded(w, err)
 		}
-		return service.DetectionInput{}, errors.New("invalid JSON: " + err.Error())
+		return
 	}
+	defer release()
 
-	input := service.DetectionInput{}
-
-	if req.URL != "" {
-		input.URL = req.URL
-		input.ContentType = service.ContentTypeFromURL(req.URL)
-	} else if req.Text != "" {
-		input.Text = req.Text
-		input.ContentType = service.ContentTypeText
-	} else {
-		return service.DetectionInput{}, errors.New("request must include 'url' or 'text' field")
+	// Perform detection
+	result, err := h.detector.Detect(ctx, input)
+	if err != nil {
+		h.writeDetectError(w, log, err)
+		return
 	}
 
-	return input, nil
+	h.recordUsage(ctx, log, usageEvent(input, result))
+	h.writeResult(w, r, log, result, share)
 }
 
-// parseMultipartInput parses multipart form upload into DetectionInput.
-func (h *Handler) parseMultipartInput(r *http.Request) (service.DetectionInput, error) {
-	// Limit upload size
-	r.Body = http.MaxBytesReader(nil, r.Body, h.maxUploadSize)
-
-	// Parse multipart form
-	if err := r.ParseMultipartForm(h.maxUploadSize); err != nil {
-		return service.DetectionInput{}, errors.New("failed to parse upload: " + err.Error())
+// expectedContentType guesses input's content type from what's known before
+// detection, for its timeout budget and concurrency pool. Detect settles
+// the type for real.
+func expectedContentType(input service.DetectionInput) service.ContentType {
+	if input.ContentType != "" && input.ContentType != service.ContentTypeUnknown {
+		return input.ContentType
 	}
-
-	// Get uploaded file
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		return service.DetectionInput{}, errors.New("no file uploaded: use 'file' form field")
+	if input.Text != "" {
+		return service.ContentTypeText
 	}
-	defer file.Close()
-
-	// Read file content
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return service.DetectionInput{}, errors.New("failed to read uploaded file")
+	if ct := service.ContentTypeFromMagicBytes(input.DataPrefix(512)); ct != service.ContentTypeUnknown {
+		return ct
+	}
+	if input.Filename != "" {
+		return service.ContentTypeFromFilename(input.Filename)
 	}
+	return service.ContentTypeFromURL(input.URL)
+}
 
-	// Detect content type from file
-	contentType := service.ContentTypeFromMIME(header.Header.Get("Content-Type"))
-	if contentType == service.ContentTypeUnknown {
-		contentType = service.ContentTypeFromFilename(header.Filename)
+// requiredScopes returns the API key scopes needed to verify input. Content
+// whose type is only known after fetching or decoding it needs both.
+func requiredScopes(input service.DetectionInput, mode string) []string {
+	if mode == "page" {
+		return []string{repository.ScopeVerifyText, repository.ScopeVerifyMedia}
+	}
+	switch expectedContentType(input) {
+	case service.ContentTypeText, service.ContentTypeDocument, service.ContentTypePlist:
+		return []string{repository.ScopeVerifyText}
+	case service.ContentTypeImage, service.ContentTypeAudio, service.ContentTypeVideo:
+		return []string{repository.ScopeVerifyMedia}
 	}
+	return []string{repository.ScopeVerifyText, repository.ScopeVerifyMedia}
+}
 
-	return service.DetectionInput{
-		Data:        data,
-		Filename:    header.Filename,
-		ContentType: contentType,
-	}, nil
+// shareResult reports whether the request's result may be shared with
+// other requests for the same content: reuse isn't turned off, and no
+// per-request option that changes the score is set.
+func shareResult(r *http.Request, input service.DetectionInput) bool {
+	return r.URL.Query().Get("reuse") != "false" &&
+		!input.EmailMode &&
+		!input.AcademicMode &&
+		len(input.CustomStopPhrases) == 0 &&
+		len(input.CustomCommonWords) == 0 &&
+		len(input.Detectors) == 0 &&
+		len(input.ExcludeDetectors) == 0
 }
 
-// validateInput validates the detection input.
-func (h *Handler) validateInput(input service.DetectionInput) error {
-	// Must have some content
-	if input.URL == "" && input.Text == "" && len(input.Data) == 0 {
-		return errors.New("no content provided")
+// findReusableJob returns the completed job for input's content if it was
+// produced by the current analyzer version and configuration, or nil if
+// detection has to run.
+func (h *Handler) findReusableJob(ctx context.Context, log *logger.Logger, input service.DetectionInput) *repository.Job {
+	fingerprint := configFingerprint(h.detector)
+	if fingerprint == "" {
+		return nil
 	}
 
-	// Validate URL if provided
-	if input.URL != "" {
-		if !strings.HasPrefix(input.URL, "http://") && !strings.HasPrefix(input.URL, "https://") {
-			return errors.New("URL must start with http:// or https://")
-		}
+	// Invalid inline data is left for Detect to report
+	decoded, err := service.DecodeInlineData(input)
+	if err != nil {
+		return nil
 	}
 
-	// Validate text length
-	if input.Text != "" {
-		if len(input.Text) < 10 {
-			return errors.New("text too short: minimum 10 characters")
-		}
-		if len(input.Text) > 100000 {
-			return errors.New("text too long: maximum 100,000 characters")
+	job, err := h.repository.FindJobByContentHash(ctx, service.ContentHash(decoded))
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			log.Warn("stored result lookup failed", "error", err)
 		}
+		return nil
 	}
-
-	// Validate file size
-	if len(input.Data) > 0 {
-		if int64(len(input.Data)) > h.maxUploadSize {
-			return errors.New("file too large")
-		}
+	if job.AnalyzerVersion != service.AnalyzerVersion || job.ConfigFingerprint != fingerprint {
+		log.Debug("stored result is outdated", "job_id", job.ID, "analyzer_version", job.AnalyzerVersion)
+		return nil
 	}
 
-	return nil
+	log.Info("reusing stored result", "job_id", job.ID)
+	return job
+}
+
+// writeOverloaded writes the 503 for a request that found its concurrency
+// pool full.
+func (h *Handler) writeOverloaded(w http.ResponseWriter, err error) {
+	var overloaded *middleware.OverloadedError
+	if !errors.As(err, &overloaded) {
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to start analysis")
+		return
+	}
+	seconds := int((overloaded.RetryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	p := problem.New(http.StatusServiceUnavailable, ErrCodeOverloaded, "server is busy, retry later")
+	p.RetryAfter = seconds
+	if err := problem.Write(w, p); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+	}
+}
+
+// writeDetectError maps a detection error to an error response.
+func (h *Handler) writeDetectError(w http.ResponseWriter, log *logger.Logger, err error) {
+	if status, code, message := detectError(log, err); status != 0 {
+		h.writeError(w, status, code, message)
+	}
+}
+
+// detectError maps a detection error to the status, code and message of
+// its error response, logging the unexpected ones. The status is 0 if the
+// client is gone and there's nothing to respond.
+func detectError(log *logger.Logger, err error) (status int, code, message string) {
+	if errors.Is(err, context.Canceled) {
+		// The client disconnected; nobody is waiting for a response
+		log.Info("detection cancelled", "error", err)
+		return 0, "", ""
+	}
+	if errors.Is(err, service.ErrInvalidBase64) {
+		return http.StatusBadRequest, ErrCodeInvalidBase64, "base64_data is not valid base64"
+	}
+	if errors.Is(err, service.ErrInvalidDataURI) {
+		return http.StatusBadRequest, ErrCodeInvalidDataURI, err.Error()
+	}
+	if errors.Is(err, service.ErrInvalidDetectorSelection) {
+		return http.StatusBadRequest, ErrCodeInvalidDetectors, strings.TrimPrefix(err.Error(), "detection failed: ")
+	}
+	if errors.Is(err, service.ErrDocumentExtraction) {
+		return http.StatusUnprocessableEntity, ErrCodeUnreadableDocument, strings.TrimPrefix(err.Error(), "detection failed: ")
+	}
+	if errors.Is(err, service.ErrUnsupportedContentType) {
+		return http.StatusBadRequest, ErrCodeUnsupportedContentType, strings.TrimPrefix(err.Error(), "detection failed: ")
+	}
+	if errors.Is(err, service.ErrContentTooLarge) {
+		return http.StatusRequestEntityTooLarge, ErrCodeContentTooLarge, "Content exceeds the maximum size"
+	}
+	var fetchErr *service.FetchError
+	if errors.As(err, &fetchErr) {
+		message := "URL could not be fetched: " + fetchErr.Error()
+		if fetchErr.Status != 0 {
+			message = fmt.Sprintf("URL returned status %d", fetchErr.Status)
+		}
+		return http.StatusUnprocessableEntity, ErrCodeFetchFailed, message
+	}
+	if errors.Is(err, service.ErrEmptyPage) {
+		return http.StatusUnprocessableEntity, ErrCodeEmptyPage, "Page has no text or media to verify"
+	}
+	if errors.Is(err, service.ErrProviderUnavailable) {
+		log.Error("detection failed", "error", err)
+		return http.StatusBadGateway, ErrCodeProviderUnavailable, "Detection providers are unavailable"
+	}
+	if errors.Is(err, service.ErrAllDetectorsFailed) {
+		log.Error("detection failed", "error", err)
+		return http.StatusInternalServerError, ErrCodeAllDetectorsFailed, "No detector could analyze the content"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Warn("detection timed out", "error", err)
+		return http.StatusGatewayTimeout, ErrCodeRequestTimeout, "request timed out"
+	}
+	log.Error("detection failed", "error", err)
+	return http.StatusInternalServerError, "detection_failed", "Failed to analyze content"
+}
+
+// writeResult stores a detection result and writes it as the response. A
+// shared result is deduplicated against the job stored for its content.
+func (h *Handler) writeResult(w http.ResponseWriter, r *http.Request, log *logger.Logger, result *service.DetectionResult, share bool) {
+	detailed := r.URL.Query().Get("detailed") == "true"
+	h.writeJSON(w, http.StatusOK, h.storeResult(r, log, result, share, detailed))
+}
+
+// storeResult stores a detection result, records it in the audit log and
+// returns its response (see writeResult).
+func (h *Handler) storeResult(r *http.Request, log *logger.Logger, result *service.DetectionResult, share, detailed bool) VerifyResponse {
+	ctx := r.Context()
+
+	// Flag resubmissions of (lightly edited) earlier content
+	result.SimilarContent = h.findSimilarContent(ctx, log, result.Fingerprint)
+
+	// Store result, or find the job that already holds this content
+	var (
+		job     *repository.Job
+		created = true
+		err     error
+	)
+	if share {
+		job, created, err = h.repository.CreateOrGetJob(ctx, jobFromResult(ctx, result))
+	} else {
+		job, err = h.repository.CreateJob(ctx, jobFromResult(ctx, result))
+	}
+
+	switch {
+	case err != nil:
+		log.Error("failed to store result", "error", err)
+		// Continue - we can still return the result even if storage fails
+		unstored := jobFromResult(ctx, result)
+		h.auditVerdict(r, log, &unstored, false)
+	case !created:
+		// Answer with the stored result so the response matches what
+		// GET /verify/{id} returns for the job
+		log.Info("duplicate submission", "job_id", job.ID)
+		h.auditVerdict(r, log, job, true)
+		response := resultResponse(job, detailed)
+		response.Reused = true
+		return response
+	default:
+		log.Debug("result stored", "job_id", job.ID)
+		h.auditVerdict(r, log, job, false)
+	}
+
+	return verifyResponse(job, result, detailed)
+}
+
+// verifyPage handles mode=page. The page gets a job of its own, and each
+// verified asset a job linked to it by ParentID.
+func (h *Handler) verifyPage(w http.ResponseWriter, r *http.Request, input service.DetectionInput) {
+	ctx := r.Context()
+	log := h.logger.WithContext(ctx)
+
+	if h.pages == nil {
+		h.writeError(w, http.StatusNotImplemented, ErrCodePageModeUnavailable, "Page mode is not enabled on this server")
+		return
+	}
+	if input.URL == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "mode=page requires a url")
+		return
+	}
+	if input.CallbackURL != "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "callback_url is not supported with mode=page")
+		return
+	}
+
+	result, err := h.pages.DetectPage(ctx, input)
+	if err != nil {
+		h.writeDetectError(w, log, err)
+		return
+	}
+
+	// Not a page; respond as if mode weren't set
+	if !result.HTML {
+		h.recordUsage(ctx, log, usageEvent(input, result.Page))
+		h.writeResult(w, r, log, result.Page, shareResult(r, input))
+		return
+	}
+
+	detailed := r.URL.Query().Get("detailed") == "true"
+
+	// Store the page, with the text's verdict if it had enough text
+	pageJob := repository.Job{
+		APIKeyID: apiKeyID(ctx),
+		Status:   repository.JobStatusCompleted,
+		Verdict:  string(service.VerdictUncertain),
+	}
+	if result.Page != nil {
+		result.Page.SimilarContent = h.findSimilarContent(ctx, log, result.Page.Fingerprint)
+		pageJob = jobFromResult(ctx, result.Page)
+	}
+	pageJob.ContentType = pageContentType
+
+	parent, err := h.repository.CreateJob(ctx, pageJob)
+	if err != nil {
+		log.Error("failed to store result", "error", err)
+		parent = &pageJob
+	}
+	h.auditVerdict(r, log, parent, false)
+
+	response := PageVerifyResponse{
+		VerifyResponse: VerifyResponse{
+			ID:          parent.ID,
+			Status:      string(repository.JobStatusCompleted),
+			Verdict:     parent.Verdict,
+			ContentType: pageContentType,
+			CreatedAt:   parent.CreatedAt,
+		},
+		URL:           result.URL,
+		HasText:       result.Page != nil,
+		Assets:        make([]PageAssetResponse, len(result.Assets)),
+		DroppedAssets: result.DroppedAssets,
+	}
+	if result.Page != nil {
+		response.VerifyResponse = verifyResponse(parent, result.Page, detailed)
+		response.ContentType = pageContentType
+	}
+
+	for i, asset := range result.Assets {
+		response.Assets[i] = PageAssetResponse{
+			URL:         asset.URL,
+			ContentType: string(asset.ContentType),
+			Error:       asset.Error,
+		}
+		if asset.Result == nil {
+			continue
+		}
+
+		childJob := jobFromResult(ctx, asset.Result)
+		childJob.ParentID = parent.ID
+		child, err := h.repository.CreateJob(ctx, childJob)
+		if err != nil {
+			log.Error("failed to store result", "error", err)
+			child = &childJob
+		}
+		h.auditVerdict(r, log, child, false)
+		assetResponse := verifyResponse(child, asset.Result, detailed)
+		assetResponse.ParentID = parent.ID
+		response.Assets[i].Result = &assetResponse
+	}
+
+	h.recordUsage(ctx, log, pageUsageEvent(input, result))
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// pageUsageEvent returns the usage of a mode=page verification: one page
+// request, with the provider calls for its text and every asset.
+func pageUsageEvent(input service.DetectionInput, result *service.PageResult) repository.UsageEvent {
+	event := usageEvent(input, result.Page)
+	event.ContentType = pageContentType
+	for _, asset := range result.Assets {
+		if asset.Result != nil {
+			event.ProviderCalls += int64(service.ExternalDetectorCalls(asset.Result))
+		}
+	}
+	return event
+}
+
+// verifyFiles handles a multipart upload of several files. They're
+// verified concurrently, each as if it had been uploaded on its own, and
+// the response is 207 with a FileResponse per file: a corrupt or
+// unsupported file doesn't fail the others. The quota is checked once for
+// the upload, and every verified file is metered. Several files can't be
+// verified async or with mode=page.
+func (h *Handler) verifyFiles(w http.ResponseWriter, r *http.Request, inputs []service.DetectionInput) {
+	ctx := r.Context()
+	log := h.logger.WithContext(ctx)
+	query := r.URL.Query()
+
+	switch mode := query.Get("mode"); mode {
+	case "":
+	case "page":
+		h.writeError(w, http.StatusBadRequest, "validation_error", "mode=page requires a url")
+		return
+	default:
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidMode, fmt.Sprintf("unknown mode %q (supported: page)", mode))
+		return
+	}
+	if query.Get("async") == "true" || inputs[0].CallbackURL != "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "async verification takes one file per request")
+		return
+	}
+	profile, err := h.profile(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, ErrCodeUnknownProfile, err.Error())
+		return
+	}
+	// Each file is a verification; all of them must fit in the quota
+	if key := middleware.APIKeyFromContext(ctx); key != nil && !h.checkQuota(w, r, key, len(inputs)) {
+		return
+	}
+
+	log.Debug("processing multi-file verification request", "files", len(inputs))
+
+	detailed := query.Get("detailed") == "true"
+	responses := make([]FileResponse, len(inputs))
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		input.EmailMode = query.Get("email") == "true"
+		input.AcademicMode = query.Get("academic") == "true"
+		profile.apply(&input)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = h.verifyFile(r, log, input, detailed)
+		}()
+	}
+	wg.Wait()
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The client disconnected; nobody is waiting for a response
+		log.Info("multi-file verification cancelled", "files", len(inputs))
+		return
+	}
+	h.writeJSON(w, http.StatusMultiStatus, FilesVerifyResponse{Files: responses})
+}
+
+// verifyFile verifies one file of a multi-file upload, like Verify does a
+// single one.
+func (h *Handler) verifyFile(r *http.Request, log *logger.Logger, input service.DetectionInput, detailed bool) (response FileResponse) {
+	fail := func(code, message string) FileResponse {
+		return FileResponse{Filename: input.Filename, Error: message, Code: code}
+	}
+
+	// Recovery only covers the serving goroutine; a file that crashes a
+	// parser fails on its own
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("panic verifying file",
+				"error", p,
+				"filename", input.Filename,
+				"stack", string(debug.Stack()),
+			)
+			response = fail("internal_error", "Failed to analyze file")
+		}
+	}()
+
+	if err := h.validateInput(input); err != nil {
+		return fail("validation_error", err.Error())
+	}
+	if key := middleware.APIKeyFromContext(r.Context()); key != nil {
+		for _, scope := range requiredScopes(input, "") {
+			if !key.HasScope(scope) {
+				return fail(ErrCodeInsufficientScope, fmt.Sprintf("API key lacks the %s scope", scope))
+			}
+		}
+	}
+
+	expected := expectedContentType(input)
+	ctx, cancel := middleware.WithTimeoutBudget(r.Context(), string(expected))
+	defer cancel()
+
+	share := shareResult(r, input)
+	if share {
+		if job := h.findReusableJob(ctx, log, input); job != nil {
+			event := usageEvent(input, nil)
+			event.ContentType = job.ContentType
+			h.recordUsage(ctx, log, event)
+			h.auditVerdict(r, log, job, true)
+
+			result := resultResponse(job, detailed)
+			result.Reused = true
+			return FileResponse{Filename: input.Filename, Result: &result}
+		}
+	}
+
+	release, err := middleware.AcquireSlot(ctx, string(expected))
+	if err != nil {
+		if ctx.Err() != nil {
+			_, code, message := detectError(log, ctx.Err())
+			return fail(code, message)
+		}
+		return fail(ErrCodeOverloaded, "server is busy, retry later")
+	}
+	defer release()
+
+	result, err := h.detector.Detect(ctx, input)
+	if err != nil {
+		_, code, message := detectError(log, err)
+		return fail(code, message)
+	}
+
+	h.recordUsage(ctx, log, usageEvent(input, result))
+	stored := h.storeResult(r.WithContext(ctx), log, result, share, detailed)
+	return FileResponse{Filename: input.Filename, Result: &stored}
+}
+
+// pageContentType is the content type of page jobs.
+const pageContentType = "page"
+
+// jobFromResult returns the completed job recording result, owned by the
+// request's API key.
+func jobFromResult(ctx context.Context, result *service.DetectionResult) repository.Job {
+	return repository.Job{
+		APIKeyID:       apiKeyID(ctx),
+		Status:         repository.JobStatusCompleted,
+		ContentType:    string(result.ContentType),
+		Human:          result.Human,
+		Verdict:        string(result.Verdict),
+		Confidence:     result.Confidence,
+		AIScore:        result.AIScore,
+		NeedsReview:    result.NeedsReview,
+		Basis:          string(result.Basis),
+		Detectors:      result.Detectors,
+		Details:        jobDetails(result),
+		ContentHash:    result.ContentHash,
+		NormalizedHash: result.NormalizedHash,
+		Fingerprint:    result.Fingerprint,
+
+		AnalyzerVersion:   result.AnalyzerVersion,
+		ConfigFingerprint: result.ConfigFingerprint,
+
+		TxID:       result.AnchorTxID,
+		AnchoredAt: result.AnchoredAt,
+	}
+}
+
+// jobDetails converts result's detection breakdown for storage.
+func jobDetails(result *service.DetectionResult) *repository.Details {
+	details := &repository.Details{
+		Aggregation:     string(result.Aggregation),
+		Disagreement:    result.Disagreement,
+		Evidence:        string(result.Evidence),
+		DetectorErrors:  result.DetectorErrors,
+		MismatchWarning: result.MismatchWarning,
+		ProcessingTime:  result.ProcessingTime,
+	}
+	for _, p := range result.ProviderScores {
+		details.Signals = append(details.Signals, repository.Signal{Name: p.Name, AIScore: p.AIScore, Weight: p.Weight})
+	}
+	for _, o := range result.DetectorOutputs {
+		output := repository.DetectorOutput{Name: o.Name, Sentences: make([]repository.Sentence, len(o.Sentences))}
+		for i, s := range o.Sentences {
+			output.Sentences[i] = repository.Sentence{Text: s.Text, AIScore: s.AIScore}
+		}
+		for _, p := range o.Paragraphs {
+			output.Paragraphs = append(output.Paragraphs, repository.Paragraph{StartSentence: p.StartSentence, Sentences: p.Sentences, AIScore: p.AIScore})
+		}
+		details.DetectorOutputs = append(details.DetectorOutputs, output)
+	}
+	for _, a := range result.SentenceAgreement {
+		details.SentenceAgreement = append(details.SentenceAgreement, repository.SentenceAgreement{
+			Detectors: a.Detectors, Sentences: a.Sentences, Agreement: a.Agreement, MeanDifference: a.MeanDifference,
+		})
+	}
+	for _, p := range result.AIPhrases {
+		details.AIPhrases = append(details.AIPhrases, repository.Phrase{Phrase: p.Phrase, Category: p.Category})
+	}
+	for _, s := range result.SkippedProviders {
+		details.Skipped = append(details.Skipped, repository.SkippedDetector{Name: s.Name, Reason: s.Reason})
+	}
+	if m := result.HostReputation; m != nil {
+		details.HostReputation = &repository.HostReputation{Host: m.Host, Rule: m.Rule, Adjustment: m.Adjustment, Reason: m.Reason}
+	}
+	if w := result.SimilarContent; w != nil {
+		details.SimilarContent = &repository.SimilarContent{JobID: w.JobID, Similarity: w.Similarity}
+	}
+	if s := result.Watermark; s != nil {
+		details.Watermark = &repository.Watermark{
+			PValue:           s.PValue,
+			Flagged:          s.Flagged,
+			Words:            s.Words,
+			WordPairs:        s.WordPairs,
+			ChiSquare:        s.ChiSquare,
+			DegreesOfFreedom: s.DegreesOfFreedom,
+		}
+	}
+	return details
+}
+
+// verifyResponse builds the response for a result stored as job.
+func verifyResponse(job *repository.Job, result *service.DetectionResult, detailed bool) VerifyResponse {
+	response := VerifyResponse{
+		ID:          job.ID,
+		Status:      string(repository.JobStatusCompleted),
+		Human:       result.Human,
+		Verdict:     string(result.Verdict),
+		Confidence:  result.Confidence,
+		ContentType: string(result.ContentType),
+		NeedsReview: result.NeedsReview,
+		Basis:       string(result.Basis),
+		CreatedAt:   job.CreatedAt,
+
+		AnalyzerVersion:   result.AnalyzerVersion,
+		ConfigFingerprint: result.ConfigFingerprint,
+	}
+
+	// Include details if requested
+	if detailed {
+		response.Details = &VerifyDetails{
+			Detectors:       result.Detectors,
+			AIScore:         result.AIScore,
+			Aggregation:     string(result.Aggregation),
+			Disagreement:    result.Disagreement,
+			Evidence:        string(result.Evidence),
+			Signals:         detectorSignals(result.ProviderScores),
+			DetectorOutputs: detectorOutputs(result.DetectorOutputs),
+			Skipped:         skippedDetectors(result.SkippedProviders),
+			MismatchWarning: result.MismatchWarning,
+			SimilarContent:  similarContent(result.SimilarContent),
+			HostReputation:  hostReputation(result.HostReputation),
+			DetectorErrors:  result.DetectorErrors,
+
+			SentenceAgreement: sentenceAgreement(result.SentenceAgreement),
+
+			AIPhrases:        aiPhrases(result.AIPhrases),
+			Watermark:        watermark(result.Watermark),
+			ProcessingTimeMs: result.ProcessingTime.Milliseconds(),
+		}
+	}
+	return response
+}
+
+// detectorSignals converts pre-aggregation provider scores for the response.
+func detectorSignals(providers []service.ProviderScore) []DetectorSignal {
+	if len(providers) == 0 {
+		return nil
+	}
+
+	signals := make([]DetectorSignal, len(providers))
+	for i, p := range providers {
+		confidence := p.AIScore - 0.5
+		if confidence < 0 {
+			confidence = -confidence
+		}
+		signals[i] = DetectorSignal{
+			Name:       p.Name,
+			AIScore:    p.AIScore,
+			Confidence: confidence * 2,
+		}
+	}
+	return signals
+}
+
+// detectorOutputs converts per-sentence provider outputs for the response.
+func detectorOutputs(outputs []service.DetectorOutput) []DetectorOutput {
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	converted := make([]DetectorOutput, len(outputs))
+	for i, o := range outputs {
+		sentences := make([]SentenceSignal, len(o.Sentences))
+		for j, s := range o.Sentences {
+			sentences[j] = SentenceSignal{Text: s.Text, AIScore: s.AIScore}
+		}
+		converted[i] = DetectorOutput{Name: o.Name, Sentences: sentences}
+		for _, p := range o.Paragraphs {
+			converted[i].Paragraphs = append(converted[i].Paragraphs, ParagraphSignal{StartSentence: p.StartSentence, Sentences: p.Sentences, AIScore: p.AIScore})
+		}
+	}
+	return converted
+}
+
+// sentenceAgreement converts sentence score comparisons for the response.
+func sentenceAgreement(agreements []service.SentenceAgreement) []SentenceAgreement {
+	if len(agreements) == 0 {
+		return nil
+	}
+
+	converted := make([]SentenceAgreement, len(agreements))
+	for i, a := range agreements {
+		converted[i] = SentenceAgreement{
+			Detectors:      a.Detectors,
+			Sentences:      a.Sentences,
+			Agreement:      a.Agreement,
+			MeanDifference: a.MeanDifference,
+		}
+	}
+	return converted
+}
+
+// aiPhrases converts detected AI phrases for the response.
+func aiPhrases(detected []service.DetectedPhrase) []AIPhrase {
+	if len(detected) == 0 {
+		return nil
+	}
+
+	phrases := make([]AIPhrase, len(detected))
+	for i, p := range detected {
+		phrases[i] = AIPhrase{Phrase: p.Phrase, Category: p.Category}
+	}
+	return phrases
+}
+
+// watermark converts a watermark scan for the response.
+func watermark(scan *service.WatermarkScan) *Watermark {
+	if scan == nil {
+		return nil
+	}
+	return &Watermark{
+		Experimental:     true,
+		Score:            scan.Score,
+		PValue:           scan.PValue,
+		Flagged:          scan.Flagged,
+		Words:            scan.Words,
+		WordPairs:        scan.WordPairs,
+		ChiSquare:        scan.ChiSquare,
+		DegreesOfFreedom: scan.DegreesOfFreedom,
+	}
+}
+
+// skippedDetectors converts skipped providers for the response.
+func skippedDetectors(skipped []service.SkippedProvider) []SkippedDetector {
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	converted := make([]SkippedDetector, len(skipped))
+	for i, s := range skipped {
+		converted[i] = SkippedDetector{Name: s.Name, Reason: s.Reason}
+	}
+	return converted
+}
+
+// similarContent converts a similar content warning for the response.
+func similarContent(warning *service.SimilarContentWarning) *SimilarContent {
+	if warning == nil {
+		return nil
+	}
+	return &SimilarContent{JobID: warning.JobID, Similarity: warning.Similarity}
+}
+
+// hostReputation converts a service host rule match to the response type.
+func hostReputation(match *service.HostReputationMatch) *HostReputation {
+	if match == nil {
+		return nil
+	}
+	return &HostReputation{
+		Host:       match.Host,
+		Rule:       match.Rule,
+		Adjustment: match.Adjustment,
+		Reason:     match.Reason,
+	}
+}
+
+// findSimilarContent looks up the earlier job most similar to fingerprint.
+// Returns nil when there is none above service.SimilarContentThreshold; a
+// failed lookup is logged and treated the same way.
+func (h *Handler) findSimilarContent(ctx context.Context, log *logger.Logger, fingerprint []uint32) *service.SimilarContentWarning {
+	if len(fingerprint) == 0 {
+		return nil
+	}
+
+	jobs, err := h.repository.ListJobsByFingerprint(ctx, fingerprint, service.SimilarContentThreshold)
+	if err != nil {
+		log.Warn("similar content lookup failed", "error", err)
+		return nil
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	warning := &service.SimilarContentWarning{
+		JobID:      jobs[0].ID,
+		Similarity: minhash.Similarity(fingerprint, jobs[0].Fingerprint),
+	}
+	log.Info("similar content found", "job_id", warning.JobID, "similarity", warning.Similarity)
+	return warning
+}
+
+// shouldRunAsync reports whether a request should be processed in the background.
+func (h *Handler) shouldRunAsync(r *http.Request, input service.DetectionInput) bool {
+	if h.queue == nil {
+		return false
+	}
+	if r.URL.Query().Get("async") == "true" || input.CallbackURL != "" {
+		return true
+	}
+	return h.asyncThreshold > 0 && r.ContentLength > h.asyncThreshold
+}
+
+// verifyAsync creates a pending job, queues it and responds with 202 Accepted.
+// It reports whether the job was queued, in which case the queue owns input.
+func (h *Handler) verifyAsync(w http.ResponseWriter, r *http.Request, input service.DetectionInput) bool {
+	ctx := r.Context()
+	log := h.logger.WithContext(ctx)
+
+	job, err := h.repository.CreateJob(ctx, repository.Job{
+		APIKeyID:    apiKeyID(ctx),
+		Status:      repository.JobStatusPending,
+		ContentType: string(expectedContentType(input)),
+		CallbackURL: input.CallbackURL,
+	})
+	if err != nil {
+		log.Error("failed to create async job", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create verification job")
+		return false
+	}
+
+	if err := h.queue.Enqueue(job.ID, input); err != nil {
+		log.Warn("failed to enqueue async job", "error", err, "job_id", job.ID)
+
+		_, err := h.repository.UpdateJob(ctx, job.ID, func(job *repository.Job) error {
+			job.Status = repository.JobStatusFailed
+			job.Error = "queue unavailable"
+			return nil
+		})
+		if err != nil {
+			log.Error("failed to mark job failed", "error", err, "job_id", job.ID)
+		}
+
+		h.writeError(w, http.StatusServiceUnavailable, "queue_full", "Too many pending verifications, try again later")
+		return false
+	}
+
+	log.Debug("queued async verification", "job_id", job.ID)
+
+	// Provider calls happen later in the worker and aren't metered
+	h.recordUsage(ctx, log, usageEvent(input, nil))
+
+	w.Header().Set("Location", "/verify/"+job.ID)
+	h.writeJSON(w, http.StatusAccepted, JobStatusResponse{
+		ID:        job.ID,
+		Status:    string(job.Status),
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	})
+	return true
+}
+
+// parseJSONInput parses JSON request body into DetectionInput.
+func (h *Handler) parseJSONInput(r *http.Request) (service.DetectionInput, error) {
+	var req VerifyRequest
+	
+	// Limit body size for JSON requests: 10MB, plus room for an upload's
+	// worth of base64
+	limit := int64(10*1024*1024 + base64.StdEncoding.EncodedLen(int(h.maxUploadSize)))
+	body := http.MaxBytesReader(nil, r.Body, limit)
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		if err == io.EOF {
+			return service.DetectionInput{}, errors.New("empty request body")
+		}
+		return service.DetectionInput{}, errors.New("invalid JSON: " + err.Error())
+	}
+	return requestInput(req)
+}
+
+// maxTextBodySize limits text/plain and URL-encoded bodies. Texts are
+// capped well below it by validateInput.
+const maxTextBodySize = 1024 * 1024
+
+// parseTextInput reads a text/plain body as the text to verify.
+func (h *Handler) parseTextInput(r *http.Request) (service.DetectionInput, error) {
+	body := http.MaxBytesReader(nil, r.Body, maxTextBodySize)
+	defer body.Close()
+
+	text, err := io.ReadAll(body)
+	if err != nil {
+		return service.DetectionInput{}, errors.New("failed to read body: " + err.Error())
+	}
+	if len(text) == 0 {
+		return service.DetectionInput{}, errors.New("empty request body")
+	}
+	if !utf8.Valid(text) {
+		return service.DetectionInput{}, errors.New("text must be UTF-8")
+	}
+	return service.DetectionInput{Text: string(text), ContentType: service.ContentTypeText}, nil
+}
+
+// parseFormInput reads a URL-encoded form: "text" or "url", plus the
+// optional fields a multipart upload takes.
+func (h *Handler) parseFormInput(r *http.Request) (service.DetectionInput, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxTextBodySize)
+	if err := r.ParseForm(); err != nil {
+		return service.DetectionInput{}, errors.New("failed to parse form: " + err.Error())
+	}
+
+	form := r.PostForm
+	if form.Get("text") == "" && form.Get("url") == "" {
+		return service.DetectionInput{}, errors.New("form must include a 'text' or 'url' field")
+	}
+	return requestInput(VerifyRequest{
+		Text:             form.Get("text"),
+		URL:              form.Get("url"),
+		CallbackURL:      form.Get("callback_url"),
+		Detectors:        splitFormList(form.Get("detectors")),
+		ExcludeDetectors: splitFormList(form.Get("exclude_detectors")),
+	})
+}
+
+// requestInput converts a verify request, however it was sent, into a
+// DetectionInput.
+func requestInput(req VerifyRequest) (service.DetectionInput, error) {
+	input := service.DetectionInput{}
+	if req.Base64Data == "" {
+		req.Base64Data = req.DataBase64
+	}
+
+	if req.URL != "" {
+		input.URL = req.URL
+		if !service.IsDataURI(req.URL) {
+			input.ContentType = service.ContentTypeFromURL(req.URL)
+		}
+	} else if req.Text != "" {
+		input.Text = req.Text
+		input.ContentType = service.ContentTypeText
+	} else if req.Base64Data != "" {
+		input.Base64Data = req.Base64Data
+	} else {
+		return service.DetectionInput{}, errors.New("request must include 'url', 'text' or 'base64_data' field")
+	}
+
+	// A declared type wins; otherwise it's detected from the decoded bytes
+	if req.ContentType != "" {
+		if input.Base64Data == "" {
+			return service.DetectionInput{}, errors.New("'content_type' only applies to 'base64_data'")
+		}
+		input.ContentType = service.ContentTypeFromMIME(req.ContentType)
+		if input.ContentType == service.ContentTypeUnknown {
+			return service.DetectionInput{}, fmt.Errorf("unsupported content_type %q", req.ContentType)
+		}
+	}
+
+	input.CallbackURL = req.CallbackURL
+	input.CustomStopPhrases = req.CustomStopPhrases
+	input.CustomCommonWords = req.CustomCommonWords
+	input.Detectors = req.Detectors
+	input.ExcludeDetectors = req.ExcludeDetectors
+
+	return input, nil
+}
+
+// fileFields are the multipart fields files may be uploaded in, each
+// possibly repeated.
+var fileFields = []string{"file", "files[]", "files"}
+
+// errTooManyFiles is returned for an upload with more than MaxFiles files.
+var errTooManyFiles = errors.New("too many files")
+
+// splitFormList splits a comma-separated form value, dropping empty items.
+func splitFormList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateInput validates the detection input.
+func (h *Handler) validateInput(input service.DetectionInput) error {
+	// Must have some content
+	if input.URL == "" && input.Text == "" && !input.HasData() {
+		return errors.New("no content provided")
+	}
+
+	// Validate URL if provided
+	if input.URL != "" {
+		if !strings.HasPrefix(input.URL, "http://") && !strings.HasPrefix(input.URL, "https://") {
+			return errors.New("URL must start with http:// or https://")
+		}
+	}
+
+	// Validate text length
+	if input.Text != "" {
+		if len(input.Text) < 10 {
+			return errors.New("text too short: minimum 10 characters")
+		}
+		if len(input.Text) > 100000 {
+			return errors.New("text too long: maximum 100,000 characters")
+		}
+	}
+
+	// Validate file size
+	if input.HasData() {
+		if input.DataSize() > h.maxUploadSize {
+			return errors.New("file too large")
+		}
+	}
+
+	if err := service.ValidateDetectorNames(input.Detectors); err != nil {
+		return err
+	}
+	if err := service.ValidateDetectorNames(input.ExcludeDetectors); err != nil {
+		return err
+	}
+
+	if len(input.CustomStopPhrases) > maxCustomWords {
+		return fmt.Errorf("too many custom_stop_phrases: maximum %d", maxCustomWords)
+	}
+	if len(input.CustomCommonWords) > maxCustomWords {
+		return fmt.Errorf("too many custom_common_words: maximum %d", maxCustomWords)
+	}
+
+	if input.CallbackURL != "" {
+		if err := h.validateCallbackURL(input.CallbackURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateCallbackURL checks a webhook URL before the job is accepted.
+// Private addresses are refused at delivery time by the webhook client.
+func (h *Handler) validateCallbackURL(rawURL string) error {
+	if !h.callbacks {
+		return errors.New("callback_url is not supported on this server")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return errors.New("callback_url is not a valid URL")
+	}
+	if err := service.CheckURL(rawURL); err != nil {
+		return errors.New("callback_url must start with http:// or https://")
+	}
+	if h.requireHTTPS && u.Scheme != "https" {
+		return errors.New("callback_url must use https")
+	}
+
+	return nil
 }
 
 // GetResult handles GET /verify/{id} requests.
 // Returns the result of a previous verification by ID.
+// Async jobs that haven't completed return their status instead of a verdict,
+// with Cache-Control: no-store. Completed results carry an ETag and may be
+// cached privately; If-None-Match with the ETag gets 304 Not Modified.
+//
+// Query parameters:
+//   - detailed=true: include the stored detection breakdown, as POST /verify
+//     returned it
 func (h *Handler) GetResult(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -337,47 +1842,692 @@ func (h *Handler) GetResult(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build response
-	response := VerifyResponse{
+	// Jobs without a status predate async mode and are always complete
+	status := job.Status
+	if status == "" {
+		status = repository.JobStatusCompleted
+	}
+
+	if status != repository.JobStatusCompleted {
+		response := JobStatusResponse{
+			ID:        job.ID,
+			Status:    string(status),
+			Error:     job.Error,
+			Attempts:  job.AttemptCount,
+			CreatedAt: job.CreatedAt,
+			UpdatedAt: job.UpdatedAt,
+		}
+		if !job.CompletedAt.IsZero() {
+			response.CompletedAt = &job.CompletedAt
+		}
+
+		// The status changes as the job runs, so it mustn't be cached
+		w.Header().Set("Cache-Control", "no-store")
+
+		// Still running: 202 like the submission, so clients keep polling
+		code := http.StatusOK
+		if status == repository.JobStatusPending || status == repository.JobStatusProcessing {
+			code = http.StatusAccepted
+		}
+		h.writeJSON(w, code, response)
+		return
+	}
+
+	// A completed result doesn't change, so pollers can revalidate it
+	// instead of downloading it again
+	detailed := r.URL.Query().Get("detailed") == "true"
+	variant := ""
+	if detailed {
+		variant = "detailed"
+	}
+	if notModified(w, r, jobETag(job, variant)) {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resultResponse(job, detailed))
+}
+
+// ErrCodeInvalidHash is the error code returned for a hash lookup whose
+// hash isn't 64 hex digits.
+const ErrCodeInvalidHash = "invalid_hash"
+
+// ErrCodeHashNotFound is the error code returned for a hash lookup when no
+// completed verification has the hash.
+const ErrCodeHashNotFound = "hash_not_found"
+
+// GetResultByHash handles GET /verify/hash/{sha256} requests.
+// Returns the newest completed result for content with the SHA-256 hash,
+// so clients that can't send the content can still ask for its verdict.
+// It never runs detection.
+//
+// The route is registered as GET /verify/{kind}/{sha256}, since a literal
+// "hash" segment would conflict with the /verify/{id}/... routes; any kind
+// but "hash" is not found.
+//
+// Query parameters:
+//   - normalized=true: sha256 is service.NormalizedTextHash of text rather
+//     than the hash of the exact content
+func (h *Handler) GetResultByHash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if kind := r.PathValue("kind"); kind != "" && kind != "hash" {
+		h.writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+
+	hash := strings.ToLower(r.PathValue("sha256"))
+	if !isSHA256Hex(hash) {
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidHash, "hash must be a SHA-256 as 64 hex digits")
+		return
+	}
+
+	var normalized bool
+	switch r.URL.Query().Get("normalized") {
+	case "", "false":
+	case "true":
+		normalized = true
+	default:
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidParameter, "normalized must be true or false")
+		return
+	}
+
+	var job *repository.Job
+	var err error
+	if normalized {
+		job, err = h.repository.FindJobByNormalizedHash(r.Context(), hash)
+	} else {
+		job, err = h.repository.FindJobByContentHash(r.Context(), hash)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, ErrCodeHashNotFound, "No verification result for this hash")
+			return
+		}
+		h.logger.Error("failed to find job by hash", "error", err, "hash", hash, "normalized", normalized)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve result")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, resultResponse(job, false))
+}
+
+// isSHA256Hex reports whether s is 64 lowercase hex digits.
+func isSHA256Hex(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteResult handles DELETE /verify/{id} requests. The result is gone
+// for every endpoint as soon as this returns 204; storage that deletes
+// softly purges the row on the next retention sweep. Only the API key
+// that submitted a result can delete it; to any other caller it doesn't
+// exist.
+func (h *Handler) DeleteResult(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, http.StatusBadRequest, "missing_id", "Job ID is required")
+		return
+	}
+
+	job, err := h.repository.GetJob(r.Context(), id)
+	if err == nil && job.APIKeyID != apiKeyID(r.Context()) {
+		err = repository.ErrNotFound
+	}
+	if err == nil {
+		err = h.repository.DeleteJob(r.Context(), id)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "Verification result not found")
+			return
+		}
+		h.logger.Error("failed to delete job", "error", err, "id", id)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to delete result")
+		return
+	}
+
+	h.logger.Info("job deleted", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resultResponse builds the response for a completed job, with its stored
+// detection breakdown if detailed is set.
+func resultResponse(job *repository.Job, detailed bool) VerifyResponse {
+	response := storedResponse(job)
+	if detailed && job.Details != nil {
+		response.Details = verifyResponse(job, storedResult(job), true).Details
+		response.Details.Truncated = job.Details.Truncated
+	}
+	return response
+}
+
+// storedResponse builds the response for a stored job. Jobs that haven't
+// completed only carry their ID, status, content type and creation time.
+func storedResponse(job *repository.Job) VerifyResponse {
+	// Jobs without a status predate async mode and are always complete
+	status := job.Status
+	if status == "" {
+		status = repository.JobStatusCompleted
+	}
+
+	if status != repository.JobStatusCompleted {
+		return VerifyResponse{
+			ID:          job.ID,
+			Status:      string(status),
+			ContentType: job.ContentType,
+			CreatedAt:   job.CreatedAt,
+		}
+	}
+
+	return VerifyResponse{
 		ID:          job.ID,
+		Status:      string(status),
 		Human:       job.Human,
+		Verdict:     jobVerdict(job),
 		Confidence:  job.Confidence,
 		ContentType: job.ContentType,
+		NeedsReview: job.NeedsReview,
+		Basis:       job.Basis,
 		CreatedAt:   job.CreatedAt,
+
+		AnalyzerVersion:   job.AnalyzerVersion,
+		ConfigFingerprint: job.ConfigFingerprint,
+		ParentID:          job.ParentID,
+
+		Details: &VerifyDetails{
+			Detectors: job.Detectors,
+			AIScore:   job.AIScore,
+		},
 	}
+}
 
-	h.writeJSON(w, http.StatusOK, response)
+// jobVerdict returns a completed job's verdict. Jobs stored before verdicts
+// existed only have the bool.
+func jobVerdict(job *repository.Job) string {
+	if job.Verdict != "" {
+		return job.Verdict
+	}
+	if job.Human {
+		return string(service.VerdictHuman)
+	}
+	return string(service.VerdictAI)
+}
+
+// ListResponse is a page of verification jobs from GET /verify.
+type ListResponse struct {
+	// Items are the jobs on this page, newest first
+	Items []VerifyResponse `json:"items"`
+
+	// NextCursor fetches the next page (omitted on the last page)
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ErrCodeInvalidParameter is the error code returned for a malformed query
+// parameter.
+const ErrCodeInvalidParameter = "invalid_parameter"
+
+// ErrCodeInvalidCursor is the error code returned for a cursor that wasn't
+// returned by GET /verify.
+const ErrCodeInvalidCursor = "invalid_cursor"
+
+// ListResults handles GET /verify requests.
+// Returns the caller's stored verification jobs, newest first: those
+// submitted with its API key, or without one for requests without a key.
+//
+// Query parameters (all optional):
+//   - limit: page size (default 20, at most 100)
+//   - cursor: next_cursor from the previous page
+//   - content_type: only jobs of this content type
+//   - human: true or false, only jobs with this result
+//   - verdict: human, ai, or uncertain
+//   - content_hash: only jobs for this SHA-256 content hash
+//   - created_after, created_before: RFC 3339 bounds on created_at
+func (h *Handler) ListResults(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+	owner := apiKeyID(r.Context())
+	filter.APIKeyID = &owner
+
+	jobs, next, err := h.repository.ListJobs(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			h.writeError(w, http.StatusBadRequest, ErrCodeInvalidCursor, "Cursor is invalid")
+			return
+		}
+		h.logger.Error("failed to list jobs", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list results")
+		return
+	}
+
+	items := make([]VerifyResponse, len(jobs))
+	for i := range jobs {
+		items[i] = storedResponse(&jobs[i])
+	}
+	h.writeJSON(w, http.StatusOK, ListResponse{Items: items, NextCursor: next})
+}
+
+// parseListFilter reads GET /verify's query parameters.
+func parseListFilter(query url.Values) (repository.ListFilter, error) {
+	filter := repository.ListFilter{
+		Cursor:      query.Get("cursor"),
+		ContentType: query.Get("content_type"),
+		Verdict:     query.Get("verdict"),
+		ContentHash: query.Get("content_hash"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+
+	if v := query.Get("human"); v != "" {
+		human, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("human must be true or false")
+		}
+		filter.Human = &human
+	}
+
+	switch service.Verdict(filter.Verdict) {
+	case "", service.VerdictHuman, service.VerdictAI, service.VerdictUncertain:
+	default:
+		return filter, errors.New("verdict must be human, ai, or uncertain")
+	}
+
+	bounds := []struct {
+		name string
+		t    *time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+	}
+	for _, bound := range bounds {
+		if v := query.Get(bound.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", bound.name)
+			}
+			*bound.t = t
+		}
+	}
+
+	return filter, nil
+}
+
+// ErrCodeJobNotCompleted is the error code returned for a report on a job
+// that has no result yet.
+const ErrCodeJobNotCompleted = "job_not_completed"
+
+// GetReport handles GET /verify/{id}/report.json, report.csv and report.html
+// requests. Returns the stored result as a report for compliance records;
+// see service.Report for the formats.
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, http.StatusBadRequest, "missing_id", "Job ID is required")
+		return
+	}
+
+	job, err := h.repository.GetJob(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "Verification result not found")
+			return
+		}
+		h.logger.Error("failed to get job", "error", err, "id", id)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve result")
+		return
+	}
+
+	if job.Status != "" && job.Status != repository.JobStatusCompleted {
+		h.writeError(w, http.StatusConflict, ErrCodeJobNotCompleted, "Verification has not completed")
+		return
+	}
+
+	format := service.ReportFormat(strings.TrimPrefix(path.Ext(r.URL.Path), "."))
+	report, err := service.Report(storedResult(job), format)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownReportFormat) {
+			h.writeError(w, http.StatusNotFound, "not_found", "Unknown report format")
+			return
+		}
+		h.logger.Error("failed to render report", "error", err, "id", id, "format", format)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to render report")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.MediaType())
+	if format == service.ReportCSV {
+		w.Header().Set("Content-Disposition", `attachment; filename="humanmark-`+job.ID+`.csv"`)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(report)
+}
+
+// storedResult rebuilds the parts of a detection result a job stores.
+func storedResult(job *repository.Job) *service.DetectionResult {
+	result := &service.DetectionResult{
+		Human:       job.Human,
+		Verdict:     service.Verdict(jobVerdict(job)),
+		Confidence:  job.Confidence,
+		AIScore:     job.AIScore,
+		ContentType: service.ContentType(job.ContentType),
+		Detectors:   job.Detectors,
+		ContentHash: job.ContentHash,
+		NeedsReview: job.NeedsReview,
+		Basis:       service.Basis(job.Basis),
+
+		AnalyzerVersion:   job.AnalyzerVersion,
+		ConfigFingerprint: job.ConfigFingerprint,
+
+		AnchorTxID: job.TxID,
+		AnchoredAt: job.AnchoredAt,
+	}
+	result.Uncertain = result.Verdict == service.VerdictUncertain
+
+	details := job.Details
+	if details == nil {
+		return result
+	}
+	result.Aggregation = service.AggregationStrategy(details.Aggregation)
+	result.Disagreement = details.Disagreement
+	result.Evidence = service.Evidence(details.Evidence)
+	result.DetectorErrors = details.DetectorErrors
+	result.MismatchWarning = details.MismatchWarning
+	result.ProcessingTime = details.ProcessingTime
+
+	for _, s := range details.Signals {
+		result.ProviderScores = append(result.ProviderScores, service.ProviderScore{Name: s.Name, AIScore: s.AIScore, Weight: s.Weight})
+	}
+	for _, o := range details.DetectorOutputs {
+		output := service.DetectorOutput{Name: o.Name, Sentences: make([]service.SentenceScore, len(o.Sentences))}
+		for i, s := range o.Sentences {
+			output.Sentences[i] = service.SentenceScore{Text: s.Text, AIScore: s.AIScore}
+		}
+		for _, p := range o.Paragraphs {
+			output.Paragraphs = append(output.Paragraphs, service.ParagraphScore{StartSentence: p.StartSentence, Sentences: p.Sentences, AIScore: p.AIScore})
+		}
+		result.DetectorOutputs = append(result.DetectorOutputs, output)
+	}
+	for _, a := range details.SentenceAgreement {
+		result.SentenceAgreement = append(result.SentenceAgreement, service.SentenceAgreement{
+			Detectors: a.Detectors, Sentences: a.Sentences, Agreement: a.Agreement, MeanDifference: a.MeanDifference,
+		})
+	}
+	for _, p := range details.AIPhrases {
+		result.AIPhrases = append(result.AIPhrases, service.DetectedPhrase{Phrase: p.Phrase, Category: p.Category})
+	}
+	for _, s := range details.Skipped {
+		result.SkippedProviders = append(result.SkippedProviders, service.SkippedProvider{Name: s.Name, Reason: s.Reason})
+	}
+	if m := details.HostReputation; m != nil {
+		result.HostReputation = &service.HostReputationMatch{Host: m.Host, Rule: m.Rule, Adjustment: m.Adjustment, Reason: m.Reason}
+	}
+	if w := details.SimilarContent; w != nil {
+		result.SimilarContent = &service.SimilarContentWarning{JobID: w.JobID, Similarity: w.Similarity}
+	}
+	if w := details.Watermark; w != nil {
+		result.Watermark = &service.WatermarkScan{
+			Experimental:     true,
+			PValue:           w.PValue,
+			Score:            1 - w.PValue,
+			Flagged:          w.Flagged,
+			Words:            w.Words,
+			WordPairs:        w.WordPairs,
+			ChiSquare:        w.ChiSquare,
+			DegreesOfFreedom: w.DegreesOfFreedom,
+		}
+	}
+	return result
 }
 
 // Health handles GET /health requests.
-// Returns service health status for monitoring and load balancers.
+// Kept for existing monitors and load balancers; same as /health/ready.
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	h.Ready(w, r)
+}
+
+// Live handles GET /health/live requests (Kubernetes liveness probe).
+// Always succeeds while the process can serve HTTP, so a database outage
+// doesn't get the pod restarted.
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	h.writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// Ready handles GET /health/ready requests (Kubernetes readiness probe).
+// Returns 503 while the database is unreachable or no external detection
+// provider is configured, so traffic goes to other instances.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Check database connectivity
-	dbHealthy := true
 	if err := h.repository.Ping(r.Context()); err != nil {
-		dbHealthy = false
 		h.logger.Warn("database health check failed", "error", err)
+		h.writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "not_ready",
+			"reason": "database unreachable",
+		})
+		return
 	}
 
-	status := "healthy"
-	httpStatus := http.StatusOK
-
-	if !dbHealthy {
-		status = "degraded"
-		// Still return 200 - the API can work with degraded storage
+	if !h.providers {
+		h.writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "not_ready",
+			"reason": "no detection API key configured",
+		})
+		return
 	}
 
-	response := map[string]any{
-		"status":    status,
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"status":    "ready",
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"checks": map[string]bool{
-			"database": dbHealthy,
-		},
+	})
+}
+
+// Metrics handles GET /metrics requests in the Prometheus text format.
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	if h.metrics == nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "Metrics are not enabled")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := h.metrics.WritePrometheus(w); err != nil {
+		h.logger.Error("failed to write metrics", "error", err)
+	}
+}
+
+// UsageResponse is the JSON body for GET /usage: the calling API key's
+// usage in the current period, a UTC calendar month.
+type UsageResponse struct {
+	KeyID       string    `json:"key_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	// Requests, Bytes, ProviderCalls and ContentTypes total Days
+	Requests      int64            `json:"requests"`
+	Bytes         int64            `json:"bytes"`
+	ProviderCalls int64            `json:"provider_calls"`
+	ContentTypes  map[string]int64 `json:"content_types"`
+
+	// Quota is the monthly request quota and Remaining what's left of it
+	// (both omitted when unlimited)
+	Quota     int64  `json:"quota,omitempty"`
+	Remaining *int64 `json:"remaining,omitempty"`
+
+	// Days are the period's days with usage, oldest first
+	Days []DailyUsageResponse `json:"days"`
+}
+
+// DailyUsageResponse is one day of UsageResponse.Days.
+type DailyUsageResponse struct {
+	// Date is the UTC day, YYYY-MM-DD
+	Date          string           `json:"date"`
+	Requests      int64            `json:"requests"`
+	Bytes         int64            `json:"bytes"`
+	ProviderCalls int64            `json:"provider_calls"`
+	ContentTypes  map[string]int64 `json:"content_types"`
+}
+
+// usagePeriod returns the UTC calendar month holding now.
+func usagePeriod(now time.Time) (start, end time.Time) {
+	y, m, _ := now.UTC().Date()
+	start = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// periodRequests returns how many verifications the key has made in the
+// current period.
+func (h *Handler) periodRequests(ctx context.Context, keyID string) (int64, error) {
+	start, end := usagePeriod(h.now())
+	days, err := h.repository.ListUsage(ctx, keyID, start, end)
+	if err != nil {
+		return 0, err
+	}
+	var requests int64
+	for _, day := range days {
+		requests += day.Requests
+	}
+	return requests, nil
+}
+
+// checkQuota reports whether key may make n more verifications this
+// period, and writes 402 if not. A failed usage lookup lets the request
+// through rather than locking every key out.
+func (h *Handler) checkQuota(w http.ResponseWriter, r *http.Request, key *repository.APIKey, n int) bool {
+	if h.monthlyQuota <= 0 {
+		return true
+	}
+
+	requests, err := h.periodRequests(r.Context(), key.ID)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Warn("usage lookup failed", "error", err, "key_id", key.ID)
+		return true
+	}
+	if requests >= h.monthlyQuota {
+		_, end := usagePeriod(h.now())
+		h.writeError(w, http.StatusPaymentRequired, ErrCodeQuotaExceeded,
+			fmt.Sprintf("API key has used its %d verifications for this month (resets %s)", h.monthlyQuota, end.Format(time.RFC3339)))
+		return false
+	}
+	if remaining := h.monthlyQuota - requests; int64(n) > remaining {
+		_, end := usagePeriod(h.now())
+		h.writeError(w, http.StatusPaymentRequired, ErrCodeQuotaExceeded,
+			fmt.Sprintf("API key has %d of its %d verifications left this month, not enough for %d files (resets %s)", remaining, h.monthlyQuota, n, end.Format(time.RFC3339)))
+		return false
+	}
+	return true
+}
+
+// usageEvent returns the usage of verifying input. result is nil when
+// detection hasn't run in this request.
+func usageEvent(input service.DetectionInput, result *service.DetectionResult) repository.UsageEvent {
+	event := repository.UsageEvent{
+		ContentType: string(input.ContentType),
+		Bytes:       input.DataSize() + int64(len(input.Text)),
+	}
+	if result != nil {
+		event.ContentType = string(result.ContentType)
+		event.ProviderCalls = int64(service.ExternalDetectorCalls(result))
+	}
+	if event.ContentType == "" {
+		event.ContentType = string(service.ContentTypeUnknown)
+	}
+	return event
+}
+
+// recordUsage adds event to the calling API key's usage. Anonymous
+// requests aren't metered. A failure is only logged, since the
+// verification itself succeeded.
+func (h *Handler) recordUsage(ctx context.Context, log *logger.Logger, event repository.UsageEvent) {
+	key := middleware.APIKeyFromContext(ctx)
+	if key == nil {
+		return
+	}
+
+	event.KeyID = key.ID
+	event.At = h.now()
+	if err := h.repository.RecordUsage(ctx, event); err != nil {
+		log.Warn("failed to record usage", "error", err, "key_id", key.ID)
+	}
+}
+
+// apiKeyID returns the ID of the request's API key, which owns the jobs
+// the request creates ("" without a key).
+func apiKeyID(ctx context.Context) string {
+	if key := middleware.APIKeyFromContext(ctx); key != nil {
+		return key.ID
+	}
+	return ""
+}
+
+// Usage handles GET /usage requests.
+// Returns the calling API key's usage this month and its remaining quota.
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	key := middleware.APIKeyFromContext(r.Context())
+	if key == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Usage is tracked per API key; send one to see its usage")
+		return
+	}
+
+	start, end := usagePeriod(h.now())
+	days, err := h.repository.ListUsage(r.Context(), key.ID, start, end)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("failed to list usage", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve usage")
+		return
+	}
+
+	response := UsageResponse{
+		KeyID:        key.ID,
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		ContentTypes: make(map[string]int64),
+		Days:         make([]DailyUsageResponse, len(days)),
+	}
+	for i, day := range days {
+		response.Requests += day.Requests
+		response.Bytes += day.Bytes
+		response.ProviderCalls += day.ProviderCalls
+		for contentType, n := range day.ContentTypes {
+			response.ContentTypes[contentType] += n
+		}
+		response.Days[i] = DailyUsageResponse{
+			Date:          day.Day.Format(time.DateOnly),
+			Requests:      day.Requests,
+			Bytes:         day.Bytes,
+			ProviderCalls: day.ProviderCalls,
+			ContentTypes:  day.ContentTypes,
+		}
+	}
+	if h.monthlyQuota > 0 {
+		remaining := max(h.monthlyQuota-response.Requests, 0)
+		response.Quota = h.monthlyQuota
+		response.Remaining = &remaining
 	}
 
-	h.writeJSON(w, httpStatus, response)
+	h.writeJSON(w, http.StatusOK, response)
 }
 
 // Index handles GET / requests.
@@ -387,12 +2537,29 @@ func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 
 	response := map[string]any{
 		"name":    "HumanMark API",
-		"version": "1.0.0",
+		"version": apiVersion,
 		"description": "Verify whether content was created by a human or generated by AI",
 		"endpoints": map[string]string{
-			"POST /verify":      "Verify content (accepts URL, text, or file upload)",
-			"GET /verify/{id}":  "Get verification result by ID",
-			"GET /health":       "Health check endpoint",
+			"POST /verify":     "Verify content (accepts URL, text, or file upload; ?async=true to queue, ?mode=page for a page and its media)",
+			"GET /verify":      "List verification results (filter by content_type, human, verdict; paginate with cursor)",
+			"GET /verify/{id}": "Get verification result by ID",
+			"GET /verify/{id}/report.{json,csv,html}": "Download a verification result as a report",
+			"GET /verify/hash/{sha256}":               "Look up a verification result by content hash (?normalized=true for the whitespace-insensitive text hash)",
+			"GET /verify/{id}/certificate":            "Signed certificate of a verification result (?format=svg for a badge)",
+			"GET /.well-known/humanmark.json":         "Public keys certificates are signed with",
+			"DELETE /verify/{id}":                     "Delete a verification result",
+			"GET /jobs/{id}/proof":                    "Blockchain anchor of a result's content hash",
+			"GET /health":                             "Health check endpoint (same as /health/ready)",
+			"GET /health/live":                        "Liveness probe",
+			"GET /health/ready":                       "Readiness probe",
+			"GET /metrics":                            "Prometheus metrics",
+			"POST /admin/keys":                        "Create an API key (admin token required)",
+			"GET /admin/keys":                         "List API keys (admin token required)",
+			"DELETE /admin/keys/{id}":                 "Revoke an API key (admin token required)",
+			"GET /admin/stats":                        "Verification statistics for a dashboard (admin token required)",
+			"POST /admin/reload":                      "Re-read the detection config files (admin token required)",
+			"GET /usage":                              "The calling API key's usage and remaining quota this month",
+			"GET /openapi.json":                       "OpenAPI 3.1 description of this API",
 		},
 		"documentation": "https://github.com/humanmark/humanmark",
 	}
@@ -404,14 +2571,21 @@ func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) writeJSON(w http.ResponseWriter, status int, data any) {
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
+		if errors.Is(err, middleware.ErrResponseTruncated) {
+			h.logger.Warn("response truncated", "status", status)
+			return
+		}
 		h.logger.Error("failed to encode response", "error", err)
 	}
 }
 
-// writeError writes an error response.
+// writeError writes an error response as problem details.
 func (h *Handler) writeError(w http.ResponseWriter, status int, code string, message string) {
-	h.writeJSON(w, status, ErrorResponse{
-		Error: message,
-		Code:  code,
-	})
+	if err := problem.Error(w, status, code, message); err != nil {
+		if errors.Is(err, middleware.ErrResponseTruncated) {
+			h.logger.Warn("response truncated", "status", status)
+			return
+		}
+		h.logger.Error("failed to encode response", "error", err)
+	}
 }
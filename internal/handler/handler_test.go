@@ -3,27 +3,43 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/humanmark/humanmark/internal/middleware"
 	"github.com/humanmark/humanmark/internal/repository"
 	"github.com/humanmark/humanmark/internal/service"
 	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/minhash"
+	"github.com/humanmark/humanmark/pkg/problem"
 )
 
 // mockDetector implements service.Detector for testing.
 type mockDetector struct {
 	result *service.DetectionResult
 	err    error
+
+	// lastInput is the input of the most recent Detect call
+	lastInput service.DetectionInput
 }
 
 func (m *mockDetector) Detect(ctx context.Context, input service.DetectionInput) (*service.DetectionResult, error) {
+	m.lastInput = input
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -32,6 +48,7 @@ func (m *mockDetector) Detect(ctx context.Context, input service.DetectionInput)
 	}
 	return &service.DetectionResult{
 		Human:       true,
+		Verdict:     service.VerdictHuman,
 		Confidence:  0.95,
 		AIScore:     0.05,
 		ContentType: input.ContentType,
@@ -39,13 +56,66 @@ func (m *mockDetector) Detect(ctx context.Context, input service.DetectionInput)
 	}, nil
 }
 
+// mockPageDetector implements service.PageDetector for testing.
+type mockPageDetector struct {
+	result *service.PageResult
+	err    error
+}
+
+func (m *mockPageDetector) DetectPage(ctx context.Context, input service.DetectionInput) (*service.PageResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.result, nil
+}
+
+// blockingDetector blocks until the request context is cancelled.
+type blockingDetector struct {
+	started chan struct{}
+}
+
+func (b *blockingDetector) Detect(ctx context.Context, input service.DetectionInput) (*service.DetectionResult, error) {
+	close(b.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// slowDetector takes delay to detect, or gives up when the context ends.
+type slowDetector struct {
+	delay time.Duration
+
+	// cancelled is closed if a Detect call saw its context end
+	cancelled chan struct{}
+}
+
+func (s *slowDetector) Detect(ctx context.Context, input service.DetectionInput) (*service.DetectionResult, error) {
+	select {
+	case <-time.After(s.delay):
+		return (&mockDetector{}).Detect(ctx, input)
+	case <-ctx.Done():
+		close(s.cancelled)
+		return nil, ctx.Err()
+	}
+}
+
 // mockRepository implements repository.Repository for testing.
 type mockRepository struct {
-	jobs map[string]*repository.Job
+	jobs    map[string]*repository.Job
+	apiKeys []*repository.APIKey
+
+	// usage is kept by an in-memory repository
+	usage repository.Repository
+
+	// stats is returned by GetStats, which records its range
+	stats              *repository.Stats
+	statsFrom, statsTo time.Time
+
+	// pingErr is returned by Ping
+	pingErr error
 }
 
 func newMockRepository() *mockRepository {
-	return &mockRepository{jobs: make(map[string]*repository.Job)}
+	return &mockRepository{jobs: make(map[string]*repository.Job), usage: repository.NewMemory()}
 }
 
 func (m *mockRepository) CreateJob(ctx context.Context, job repository.Job) (*repository.Job, error) {
@@ -55,6 +125,11 @@ func (m *mockRepository) CreateJob(ctx context.Context, job repository.Job) (*re
 	return &job, nil
 }
 
+func (m *mockRepository) CreateOrGetJob(ctx context.Context, job repository.Job) (*repository.Job, bool, error) {
+	created, err := m.CreateJob(ctx, job)
+	return created, err == nil, err
+}
+
 func (m *mockRepository) GetJob(ctx context.Context, id string) (*repository.Job, error) {
 	if job, ok := m.jobs[id]; ok {
 		return job, nil
@@ -62,14 +137,147 @@ func (m *mockRepository) GetJob(ctx context.Context, id string) (*repository.Job
 	return nil, repository.ErrNotFound
 }
 
-func (m *mockRepository) Ping(ctx context.Context) error {
+func (m *mockRepository) DeleteJob(ctx context.Context, id string) error {
+	if _, ok := m.jobs[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.jobs, id)
 	return nil
 }
 
+func (m *mockRepository) DeleteJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	for id, job := range m.jobs {
+		if job.CreatedAt.Before(cutoff) {
+			delete(m.jobs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *mockRepository) FindJobByContentHash(ctx context.Context, hash string) (*repository.Job, error) {
+	for _, job := range m.jobs {
+		if job.ContentHash == hash && (job.Status == repository.JobStatusCompleted || job.Status == "") {
+			return job, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *mockRepository) FindJobByNormalizedHash(ctx context.Context, hash string) (*repository.Job, error) {
+	for _, job := range m.jobs {
+		if hash != "" && job.NormalizedHash == hash && (job.Status == repository.JobStatusCompleted || job.Status == "") {
+			return job, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *mockRepository) UpdateJob(ctx context.Context, id string, update func(*repository.Job) error) (*repository.Job, error) {
+	existing, ok := m.jobs[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	job := *existing
+	if err := update(&job); err != nil {
+		return nil, err
+	}
+	m.jobs[id] = &job
+	return &job, nil
+}
+
+func (m *mockRepository) ListJobsByFingerprint(ctx context.Context, fingerprint []uint32, threshold float64) ([]repository.Job, error) {
+	var jobs []repository.Job
+	for _, job := range m.jobs {
+		if minhash.Similarity(fingerprint, job.Fingerprint) > threshold {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *mockRepository) ListJobs(ctx context.Context, filter repository.ListFilter) ([]repository.Job, string, error) {
+	var jobs []repository.Job
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs, "", nil
+}
+
+func (m *mockRepository) CreateAPIKey(ctx context.Context, key repository.APIKey) (*repository.APIKey, error) {
+	key.ID = fmt.Sprintf("test-key-%d", len(m.apiKeys)+1)
+	key.CreatedAt = time.Now()
+	m.apiKeys = append(m.apiKeys, &key)
+	return &key, nil
+}
+
+func (m *mockRepository) GetAPIKey(ctx context.Context, id string) (*repository.APIKey, error) {
+	for _, key := range m.apiKeys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *mockRepository) ListAPIKeys(ctx context.Context) ([]repository.APIKey, error) {
+	keys := make([]repository.APIKey, 0, len(m.apiKeys))
+	for i := len(m.apiKeys) - 1; i >= 0; i-- {
+		keys = append(keys, *m.apiKeys[i])
+	}
+	return keys, nil
+}
+
+func (m *mockRepository) RevokeAPIKey(ctx context.Context, id string) (*repository.APIKey, error) {
+	key, err := m.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !key.Revoked() {
+		key.RevokedAt = time.Now()
+	}
+	return key, nil
+}
+
+func (m *mockRepository) RecordUsage(ctx context.Context, event repository.UsageEvent) error {
+	return m.usage.RecordUsage(ctx, event)
+}
+
+func (m *mockRepository) ListUsage(ctx context.Context, keyID string, from, to time.Time) ([]repository.DailyUsage, error) {
+	return m.usage.ListUsage(ctx, keyID, from, to)
+}
+
+func (m *mockRepository) GetStats(ctx context.Context, from, to time.Time) (*repository.Stats, error) {
+	m.statsFrom, m.statsTo = from, to
+	if m.stats == nil {
+		return &repository.Stats{}, nil
+	}
+	return m.stats, nil
+}
+
+func (m *mockRepository) Ping(ctx context.Context) error {
+	return m.pingErr
+}
+
 func (m *mockRepository) Close() error {
 	return nil
 }
 
+// mockQueue implements JobQueue for testing.
+type mockQueue struct {
+	enqueued []string
+	err      error
+}
+
+func (m *mockQueue) Enqueue(jobID string, input service.DetectionInput) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.enqueued = append(m.enqueued, jobID)
+	return nil
+}
+
 // newTestHandler creates a Handler with mock dependencies for testing.
 func newTestHandler() *Handler {
 	return New(Config{
@@ -165,6 +373,301 @@ func TestVerify_FileUpload(t *testing.T) {
 	}
 }
 
+// TestVerify_MultipleFiles tests uploading several files in one request
+// with the real detector.
+func TestVerify_MultipleFiles(t *testing.T) {
+	detector, err := service.NewDetector(service.DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector failed: %v", err)
+	}
+	newHandler := func(maxFiles int) *Handler {
+		return New(Config{
+			Detector:      detector,
+			Repository:    repository.NewMemory(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+			MaxFiles:      maxFiles,
+		})
+	}
+
+	type file struct {
+		field, name string
+		data        []byte
+	}
+	upload := func(h *Handler, target string, files ...file) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for _, f := range files {
+			part, err := writer.CreateFormFile(f.field, f.name)
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			part.Write(f.data)
+		}
+		writer.Close()
+
+		req := httptest.NewRequest("POST", target, &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		return rec
+	}
+
+	text := file{"file", "notes.txt", []byte("I wrote these notes on the train home, mostly about the garden and what to plant next spring.")}
+	jpeg := file{"files[]", "photo.jpg", append([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'}, make([]byte, 2000)...)}
+	corrupt := file{"files[]", "broken.bin", []byte{0x00, 0x13, 0x37, 0x00, 0xde, 0xad}}
+
+	t.Run("verifies each file", func(t *testing.T) {
+		rec := upload(newHandler(0), "/verify", text, jpeg, corrupt)
+
+		if rec.Code != http.StatusMultiStatus {
+			t.Fatalf("expected status 207, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response FilesVerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(response.Files) != 3 {
+			t.Fatalf("expected 3 files, got %d", len(response.Files))
+		}
+
+		for i, want := range []struct{ filename, contentType string }{
+			{"notes.txt", "text"},
+			{"photo.jpg", "image"},
+		} {
+			got := response.Files[i]
+			if got.Filename != want.filename {
+				t.Errorf("file %d: expected filename %q, got %q", i, want.filename, got.Filename)
+			}
+			if got.Result == nil {
+				t.Fatalf("%s: expected a result, got error %q", want.filename, got.Error)
+			}
+			if got.Result.ID == "" || got.Result.Verdict == "" {
+				t.Errorf("%s: expected a job ID and verdict, got %+v", want.filename, got.Result)
+			}
+			if got.Result.ContentType != want.contentType {
+				t.Errorf("%s: expected content_type %q, got %q", want.filename, want.contentType, got.Result.ContentType)
+			}
+		}
+		if response.Files[0].Result.ID == response.Files[1].Result.ID {
+			t.Error("expected a job per file")
+		}
+
+		broken := response.Files[2]
+		if broken.Filename != "broken.bin" || broken.Result != nil {
+			t.Errorf("expected broken.bin to fail, got %+v", broken)
+		}
+		if broken.Code != ErrCodeUnsupportedContentType {
+			t.Errorf("expected code %q, got %q (%s)", ErrCodeUnsupportedContentType, broken.Code, broken.Error)
+		}
+	})
+
+	t.Run("accepts repeated file fields", func(t *testing.T) {
+		second := text
+		second.name = "more-notes.txt"
+		second.data = []byte("A second page of notes, written the next morning over coffee before work.")
+		rec := upload(newHandler(0), "/verify", text, second)
+
+		if rec.Code != http.StatusMultiStatus {
+			t.Fatalf("expected status 207, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("single file keeps the single response", func(t *testing.T) {
+		rec := upload(newHandler(0), "/verify", jpeg)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("rejects too many files", func(t *testing.T) {
+		rec := upload(newHandler(2), "/verify", text, jpeg, corrupt)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Code != ErrCodeTooManyFiles {
+			t.Errorf("expected code %q, got %q", ErrCodeTooManyFiles, response.Code)
+		}
+	})
+
+	t.Run("rejects async", func(t *testing.T) {
+		rec := upload(newHandler(0), "/verify?async=true", text, jpeg)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+// TestVerify_Base64 tests base64-encoded binary submissions with the real detector.
+func TestVerify_Base64(t *testing.T) {
+	detector, err := service.NewDetector(service.DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector failed: %v", err)
+	}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	jpegData := append([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'}, make([]byte, 2000)...)
+	wavData := append([]byte("RIFF\x00\x00\x00\x00WAVEfmt "), make([]byte, 2000)...)
+
+	tests := []struct {
+		name        string
+		encoded     string
+		contentType string
+	}{
+		{"standard JPEG", base64.StdEncoding.EncodeToString(jpegData), "image"},
+		{"URL-safe JPEG", base64.URLEncoding.EncodeToString(jpegData), "image"},
+		{"standard WAV", base64.StdEncoding.EncodeToString(wavData), "audio"},
+		{"unpadded WAV", base64.RawStdEncoding.EncodeToString(wavData), "audio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(VerifyRequest{Base64Data: tt.encoded})
+			req := httptest.NewRequest("POST", "/verify?detailed=true", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			h.Verify(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response VerifyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.ContentType != tt.contentType {
+				t.Errorf("expected content_type %q, got %q", tt.contentType, response.ContentType)
+			}
+			if response.Details == nil || len(response.Details.Detectors) == 0 {
+				t.Error("expected analysis details with at least one detector")
+			}
+		})
+	}
+
+	t.Run("invalid base64", func(t *testing.T) {
+		body := `{"base64_data": "this is *not* base64!"}`
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Code != ErrCodeInvalidBase64 {
+			t.Errorf("expected code %q, got %q", ErrCodeInvalidBase64, response.Code)
+		}
+	})
+}
+
+// TestVerify_DataURI tests data URIs and declared base64 payloads with the
+// real detector.
+func TestVerify_DataURI(t *testing.T) {
+	detector, err := service.NewDetector(service.DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector failed: %v", err)
+	}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 4096,
+	})
+
+	pngData := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 2000)...)
+	mp3Frame := append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 414)...)
+	mp3Data := bytes.Repeat(mp3Frame, 5)
+
+	verify := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+	}{
+		{"PNG data URI", `{"url": "data:image/png;base64,` + base64.StdEncoding.EncodeToString(pngData) + `"}`, "image"},
+		{"MP3 data URI", `{"url": "data:audio/mpeg;base64,` + base64.StdEncoding.EncodeToString(mp3Data) + `"}`, "audio"},
+		{"declared base64 type", `{"data_base64": "` + base64.StdEncoding.EncodeToString(mp3Data) + `", "content_type": "audio/mpeg"}`, "audio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := verify(tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response VerifyResponse
+			json.NewDecoder(rec.Body).Decode(&response)
+			if response.ContentType != tt.contentType {
+				t.Errorf("expected content_type %q, got %q", tt.contentType, response.ContentType)
+			}
+		})
+	}
+
+	t.Run("size limit applies after decoding", func(t *testing.T) {
+		// Just under the limit decoded, well over it encoded
+		data := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 4000)...)
+		encoded := base64.StdEncoding.EncodeToString(data)
+		if rec := verify(`{"url": "data:image/png;base64,` + encoded + `"}`); rec.Code != http.StatusOK {
+			t.Errorf("expected status 200 for %d decoded bytes, got %d: %s", len(data), rec.Code, rec.Body.String())
+		}
+
+		data = append(data, make([]byte, 100)...)
+		encoded = base64.StdEncoding.EncodeToString(data)
+		if rec := verify(`{"base64_data": "` + encoded + `"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %d decoded bytes, got %d", len(data), rec.Code)
+		}
+	})
+
+	errorTests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"invalid data URI", `{"url": "data:image/png;base64,*not base64*"}`, ErrCodeInvalidDataURI},
+		{"content_type without base64", `{"url": "https://example.com/a.png", "content_type": "image/png"}`, "invalid_input"},
+		{"unsupported content_type", `{"base64_data": "aGVsbG8=", "content_type": "application/zip"}`, "invalid_input"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := verify(tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+
+			var response ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&response)
+			if response.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, response.Code)
+			}
+		})
+	}
+}
+
 // TestVerify_EmptyBody tests error handling for empty request.
 func TestVerify_EmptyBody(t *testing.T) {
 	h := newTestHandler()
@@ -179,14 +682,21 @@ func TestVerify_EmptyBody(t *testing.T) {
 		t.Errorf("expected status 400, got %d", rec.Code)
 	}
 
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("expected %s, got %q", problem.ContentType, ct)
+	}
+
 	var response ErrorResponse
 	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if response.Error == "" {
+	if response.Detail == "" {
 		t.Error("expected error message")
 	}
+	if response.Status != http.StatusBadRequest || response.Title != "Bad Request" || response.Type != problem.TypePrefix+response.Code {
+		t.Errorf("expected problem details for the 400, got %+v", response)
+	}
 }
 
 // TestVerify_InvalidJSON tests error handling for invalid JSON.
@@ -205,29 +715,166 @@ func TestVerify_InvalidJSON(t *testing.T) {
 	}
 }
 
-// TestVerify_NoContent tests error handling when no content provided.
-func TestVerify_NoContent(t *testing.T) {
-	h := newTestHandler()
+// TestVerify_PlainText tests verifying a text/plain body.
+func TestVerify_PlainText(t *testing.T) {
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+	text := "I wrote this on the train home, \"tired\" but happy, with {braces} that aren't JSON."
 
-	body := `{}`
-	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	req := httptest.NewRequest("POST", "/verify?email=true", strings.NewReader(text))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
 	rec := httptest.NewRecorder()
-
 	h.Verify(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := detector.lastInput; got.Text != text || got.ContentType != service.ContentTypeText || !got.EmailMode {
+		t.Errorf("expected the body as email text, got %q (%s, email %v)", got.Text, got.ContentType, got.EmailMode)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"empty", "", http.StatusBadRequest},
+		{"too short", "hi", http.StatusBadRequest},
+		{"not UTF-8", "caf\xe9 au lait, s'il vous pla\xeet", http.StatusBadRequest},
+		{"too large", strings.Repeat("a", maxTextBodySize+1), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, rec.Code, rec.Body.String())
+		}
 	}
 }
 
-// TestVerify_TextTooShort tests validation for short text.
-func TestVerify_TextTooShort(t *testing.T) {
-	h := newTestHandler()
+// TestVerify_FormEncoded tests verifying a URL-encoded form.
+func TestVerify_FormEncoded(t *testing.T) {
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:         detector,
+		Repository:       newMockRepository(),
+		Logger:           logger.NopLogger(),
+		MaxUploadSize:    10 * 1024 * 1024,
+		Queue:            &mockQueue{},
+		CallbacksEnabled: true,
+	})
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		return rec
+	}
 
-	body := `{"text": "short"}`
-	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	text := "This is a test text that should be verified as human-written content & more."
+	rec := post(url.Values{"text": {text}, "detectors": {"humanmark, gptzero"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := detector.lastInput; got.Text != text || !reflect.DeepEqual(got.Detectors, []string{"humanmark", "gptzero"}) {
+		t.Errorf("expected the form's text and detectors, got %q, %v", got.Text, got.Detectors)
+	}
+
+	rec = post(url.Values{"url": {"https://example.com/photo.jpg"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := detector.lastInput; got.URL != "https://example.com/photo.jpg" || got.ContentType != service.ContentTypeImage {
+		t.Errorf("expected the form's URL, got %q (%s)", got.URL, got.ContentType)
+	}
+
+	if rec := post(url.Values{"name": {"essay"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without text or url, got %d", rec.Code)
+	}
+}
+
+// TestVerify_UnsupportedMediaType tests that bodies of types Verify
+// doesn't read get 415, while requests without a type are still read as
+// JSON.
+func TestVerify_UnsupportedMediaType(t *testing.T) {
+	h := newTestHandler()
+
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(`<text>Hello there</text>`))
+	req.Header.Set("Content-Type", "application/xml")
+	rec := httptest.NewRecorder()
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status 415, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("expected a problem, got Content-Type %q", ct)
+	}
+	var p problem.Problem
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	if p.Code != ErrCodeUnsupportedMediaType {
+		t.Errorf("expected code %s, got %q", ErrCodeUnsupportedMediaType, p.Code)
+	}
+	for _, mediaType := range verifyMediaTypes {
+		if !strings.Contains(p.Detail, mediaType) || !strings.Contains(rec.Header().Get("Accept-Post"), mediaType) {
+			t.Errorf("expected %s listed as supported, got %q (Accept-Post %q)", mediaType, p.Detail, rec.Header().Get("Accept-Post"))
+		}
+	}
+
+	req = httptest.NewRequest("POST", "/verify", strings.NewReader(`{"text": "This is a test text that should be verified as human-written content."}`))
+	rec = httptest.NewRecorder()
+	h.Verify(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected a request without Content-Type read as JSON, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestVerify_NoContent tests error handling when no content provided.
+func TestVerify_NoContent(t *testing.T) {
+	h := newTestHandler()
+
+	body := `{}`
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+// TestVerify_TextTooShort tests validation for short text.
+func TestVerify_TextTooShort(t *testing.T) {
+	h := newTestHandler()
+
+	body := `{"text": "short"}`
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+// TestVerify_InvalidURL tests validation for invalid URL.
+func TestVerify_InvalidURL(t *testing.T) {
+	h := newTestHandler()
+
+	body := `{"url": "not-a-valid-url"}`
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
 	h.Verify(rec, req)
@@ -235,161 +882,2296 @@ func TestVerify_TextTooShort(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("expected status 400, got %d", rec.Code)
 	}
-}
+}
+
+// TestVerify_Detailed tests detailed response parameter.
+func TestVerify_Detailed(t *testing.T) {
+	h := newTestHandler()
+
+	body := `{"text": "This is test content for detailed response testing."}`
+	req := httptest.NewRequest("POST", "/verify?detailed=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Details == nil {
+		t.Error("expected details in response")
+	}
+	if response.Details != nil && len(response.Details.Detectors) == 0 {
+		t.Error("expected detectors in details")
+	}
+}
+
+// TestVerifyResponseSchema checks that Verify's own output satisfies its schema.
+func TestVerifyResponseSchema(t *testing.T) {
+	h := newTestHandler()
+
+	for _, query := range []string{"", "?detailed=true"} {
+		body := `{"text": "This is test content for schema validation testing."}`
+		req := httptest.NewRequest("POST", "/verify"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if errs := VerifyResponseSchema().Validate(rec.Body.Bytes()); len(errs) > 0 {
+			t.Errorf("POST /verify%s response violates schema: %v", query, errs)
+		}
+	}
+}
+
+// flipDetector alternates between AI and human verdicts, hashing the
+// text as ContentHash. Safe for concurrent use.
+type flipDetector struct {
+	calls atomic.Int64
+
+	// fingerprint is reported by ConfigFingerprint and recorded on results
+	// (empty disables reusing stored results)
+	fingerprint string
+}
+
+func (f *flipDetector) ConfigFingerprint() string {
+	return f.fingerprint
+}
+
+func (f *flipDetector) Detect(ctx context.Context, input service.DetectionInput) (*service.DetectionResult, error) {
+	result := &service.DetectionResult{
+		Verdict:     service.VerdictAI,
+		Confidence:  0.8,
+		AIScore:     0.9,
+		ContentType: service.ContentTypeText,
+		Detectors:   []string{"mock"},
+		ContentHash: service.ContentHash(input),
+
+		AnalyzerVersion:   service.AnalyzerVersion,
+		ConfigFingerprint: f.fingerprint,
+	}
+	if f.calls.Add(1)%2 == 0 {
+		result.Verdict, result.Human, result.AIScore = service.VerdictHuman, true, 0.1
+	}
+	return result, nil
+}
+
+// TestVerify_Deduplicates tests that resubmitted content returns the job
+// stored for it.
+func TestVerify_Deduplicates(t *testing.T) {
+	newHandler := func() *Handler {
+		return New(Config{
+			Detector:      &flipDetector{},
+			Repository:    repository.NewMemory(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+	}
+	verify := func(h *Handler, text string) VerifyResponse {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(`{"text": "`+text+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Errorf("failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("resubmission returns the stored result", func(t *testing.T) {
+		h := newHandler()
+		first := verify(h, "The same content, submitted twice.")
+		second := verify(h, "The same content, submitted twice.")
+
+		if second.ID != first.ID || second.Verdict != first.Verdict || second.Human != first.Human {
+			t.Errorf("second response = %+v, want the first %+v", second, first)
+		}
+		if other := verify(h, "Different content."); other.ID == first.ID {
+			t.Error("different content shared a job")
+		}
+	})
+
+	t.Run("concurrent identical submissions share one job", func(t *testing.T) {
+		h := newHandler()
+
+		var wg sync.WaitGroup
+		responses := make([]VerifyResponse, 20)
+		for i := range responses {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				responses[i] = verify(h, "Submitted by many clients at once.")
+			}()
+		}
+		wg.Wait()
+
+		for _, response := range responses[1:] {
+			if response.ID != responses[0].ID || response.Verdict != responses[0].Verdict {
+				t.Errorf("response %+v differs from %+v", response, responses[0])
+			}
+		}
+	})
+}
+
+// TestVerify_ReusesStoredResult tests answering repeat content from the
+// stored job without running detection.
+func TestVerify_ReusesStoredResult(t *testing.T) {
+	const text = "A viral post everyone wants checked."
+
+	newHandler := func() (*Handler, *flipDetector, repository.Repository) {
+		detector := &flipDetector{fingerprint: "cfg-1"}
+		repo := repository.NewMemory()
+		return New(Config{
+			Detector:      detector,
+			Repository:    repo,
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		}), detector, repo
+	}
+	verify := func(h *Handler, query string) VerifyResponse {
+		req := httptest.NewRequest("POST", "/verify"+query, strings.NewReader(`{"text": "`+text+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("hash hit skips detection", func(t *testing.T) {
+		h, detector, _ := newHandler()
+		first := verify(h, "")
+		second := verify(h, "?detailed=true")
+
+		if calls := detector.calls.Load(); calls != 1 {
+			t.Errorf("detector ran %d times, want 1", calls)
+		}
+		if first.Reused || !second.Reused {
+			t.Errorf("reused = %v, %v; want false, true", first.Reused, second.Reused)
+		}
+		if second.ID != first.ID || second.Verdict != first.Verdict || !second.CreatedAt.Equal(first.CreatedAt) {
+			t.Errorf("second response = %+v, want the first %+v", second, first)
+		}
+		if second.Details == nil || second.Details.AIScore != 0.9 {
+			t.Errorf("expected stored details, got %+v", second.Details)
+		}
+	})
+
+	t.Run("fingerprint mismatch runs detection", func(t *testing.T) {
+		h, detector, repo := newHandler()
+		stale, err := repo.CreateJob(context.Background(), repository.Job{
+			Status:            repository.JobStatusCompleted,
+			Verdict:           "human",
+			ContentHash:       service.ContentHash(service.DetectionInput{Text: text}),
+			AnalyzerVersion:   service.AnalyzerVersion,
+			ConfigFingerprint: "cfg-0",
+		})
+		if err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+
+		response := verify(h, "")
+		if calls := detector.calls.Load(); calls != 1 {
+			t.Errorf("detector ran %d times, want 1", calls)
+		}
+		if response.Reused || response.ID == stale.ID || response.Verdict != "ai" {
+			t.Errorf("expected a fresh result, got %+v", response)
+		}
+	})
+
+	t.Run("reuse=false runs detection", func(t *testing.T) {
+		h, detector, _ := newHandler()
+		first := verify(h, "")
+		second := verify(h, "?reuse=false")
+
+		if calls := detector.calls.Load(); calls != 2 {
+			t.Errorf("detector ran %d times, want 2", calls)
+		}
+		if second.Reused || second.ID == first.ID || second.Verdict == first.Verdict {
+			t.Errorf("expected a fresh result, got %+v after %+v", second, first)
+		}
+	})
+
+	t.Run("per-request options run detection", func(t *testing.T) {
+		h, detector, _ := newHandler()
+		verify(h, "")
+		if response := verify(h, "?email=true"); response.Reused {
+			t.Error("email mode reused a plain text result")
+		}
+		if calls := detector.calls.Load(); calls != 2 {
+			t.Errorf("detector ran %d times, want 2", calls)
+		}
+	})
+}
+
+// TestVerify_DetailedSignals tests that provider scores are explained.
+func TestVerify_DetailedSignals(t *testing.T) {
+	h := New(Config{
+		Detector: &mockDetector{result: &service.DetectionResult{
+			AIScore:     0.9,
+			ContentType: service.ContentTypeText,
+			Detectors:   []string{"humanmark", "hive"},
+			Aggregation: service.AggregationVeto,
+			ProviderScores: []service.ProviderScore{
+				{Name: "humanmark", AIScore: 0.5, Weight: 1.0},
+				{Name: "hive", AIScore: 0.9, Weight: 1.2},
+			},
+			DetectorOutputs: []service.DetectorOutput{
+				{Name: "hive", Sentences: []service.SentenceScore{{Text: "This is test content.", AIScore: 0.9}}},
+			},
+			SkippedProviders: []service.SkippedProvider{
+				{Name: "winston", Reason: "provider skipped: text too short"},
+			},
+			MismatchWarning: "extension suggests image but content is video",
+		}},
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	body := `{"text": "This is test content for detailed response testing."}`
+	req := httptest.NewRequest("POST", "/verify?detailed=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	var response VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response.Details == nil {
+		t.Fatal("expected details in response")
+	}
+	if response.Details.Aggregation != "veto" {
+		t.Errorf("expected aggregation veto, got %q", response.Details.Aggregation)
+	}
+	if len(response.Details.Signals) != 2 || response.Details.Signals[1].Name != "hive" {
+		t.Errorf("expected per-provider signals, got %+v", response.Details.Signals)
+	}
+	if len(response.Details.DetectorOutputs) != 1 || len(response.Details.DetectorOutputs[0].Sentences) != 1 {
+		t.Errorf("expected per-sentence detector outputs, got %+v", response.Details.DetectorOutputs)
+	}
+	if len(response.Details.Skipped) != 1 || response.Details.Skipped[0].Name != "winston" {
+		t.Errorf("expected skipped detector, got %+v", response.Details.Skipped)
+	}
+	if response.Details.MismatchWarning != "extension suggests image but content is video" {
+		t.Errorf("expected mismatch warning, got %q", response.Details.MismatchWarning)
+	}
+}
+
+// TestVerify_Verdict tests that the verdict is returned and stored.
+func TestVerify_Verdict(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector: &mockDetector{result: &service.DetectionResult{
+			Human:       false,
+			Verdict:     service.VerdictUncertain,
+			AIScore:     0.55,
+			ContentType: service.ContentTypeText,
+			Detectors:   []string{"humanmark"},
+		}},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	body := `{"text": "This is test content for verdict testing."}`
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+
+	var response VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Verdict != "uncertain" || response.Human {
+		t.Errorf("expected uncertain verdict, got %q (human=%v)", response.Verdict, response.Human)
+	}
+	if job := repo.jobs[response.ID]; job == nil || job.Verdict != "uncertain" {
+		t.Errorf("expected stored verdict uncertain, got %+v", job)
+	}
+}
+
+// TestVerify_NeedsReview tests that the review flag is returned and stored.
+func TestVerify_NeedsReview(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector: &mockDetector{result: &service.DetectionResult{
+			Verdict:      service.VerdictAI,
+			Confidence:   service.DisagreementConfidenceCap,
+			AIScore:      0.95,
+			ContentType:  service.ContentTypeImage,
+			Detectors:    []string{"sightengine", "aiornot"},
+			Disagreement: 0.43,
+			NeedsReview:  true,
+		}},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	body := `{"url": "https://example.com/image.jpg"}`
+	req := httptest.NewRequest("POST", "/verify?detailed=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	var response VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !response.NeedsReview {
+		t.Error("expected needs_review in response")
+	}
+	if response.Details == nil || response.Details.Disagreement != 0.43 {
+		t.Errorf("expected disagreement 0.43 in details, got %+v", response.Details)
+	}
+	if job := repo.jobs[response.ID]; job == nil || !job.NeedsReview {
+		t.Error("expected NeedsReview to be stored")
+	}
+
+	// The stored flag is returned on lookup
+	req = httptest.NewRequest("GET", "/verify/"+response.ID, nil)
+	req.SetPathValue("id", response.ID)
+	rec = httptest.NewRecorder()
+	h.GetResult(rec, req)
+
+	var stored VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stored); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !stored.NeedsReview {
+		t.Error("expected needs_review from GET /verify/{id}")
+	}
+}
+
+// TestVerify_Basis tests that the evidence basis is returned and stored.
+func TestVerify_Basis(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector: &mockDetector{result: &service.DetectionResult{
+			Verdict:     service.VerdictAI,
+			Confidence:  0.6,
+			AIScore:     0.95,
+			ContentType: service.ContentTypeText,
+			Detectors:   []string{"humanmark"},
+			Basis:       service.BasisLocalOnly,
+		}},
+		Repository: repo,
+		Logger:     logger.NopLogger(),
+	})
+
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(`{"text": "Some text to verify."}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.Verify(rec, req)
+
+	var response VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Basis != "local-only" {
+		t.Errorf("expected basis local-only, got %q", response.Basis)
+	}
+	if job := repo.jobs[response.ID]; job == nil || job.Basis != "local-only" {
+		t.Errorf("expected the basis to be stored, got %+v", job)
+	}
+
+	req = httptest.NewRequest("GET", "/verify/"+response.ID, nil)
+	req.SetPathValue("id", response.ID)
+	rec = httptest.NewRecorder()
+	h.GetResult(rec, req)
+
+	var stored VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stored); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if stored.Basis != "local-only" {
+		t.Errorf("expected basis local-only from GET /verify/{id}, got %q", stored.Basis)
+	}
+}
+
+// TestVerify_SimilarContent tests that resubmitted paraphrases are flagged.
+func TestVerify_SimilarContent(t *testing.T) {
+	original := "In today's fast-paced digital landscape, it is essential for organizations to leverage cutting-edge solutions that drive innovation and foster meaningful growth."
+	paraphrase := "In today's fast-paced digital landscape, it is crucial for organizations to leverage cutting-edge solutions that drive innovation and foster meaningful growth."
+
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	verify := func(text string) VerifyResponse {
+		detector.result = &service.DetectionResult{
+			Verdict:     service.VerdictAI,
+			AIScore:     0.9,
+			ContentType: service.ContentTypeText,
+			Detectors:   []string{"humanmark"},
+			Fingerprint: minhash.Signature(text),
+		}
+
+		body, _ := json.Marshal(VerifyRequest{Text: text})
+		req := httptest.NewRequest("POST", "/verify?detailed=true", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	if first := verify(original); first.Details.SimilarContent != nil {
+		t.Errorf("first submission flagged as similar to %+v", first.Details.SimilarContent)
+	}
+
+	second := verify(paraphrase)
+	similar := second.Details.SimilarContent
+	if similar == nil {
+		t.Fatal("expected paraphrase to be flagged")
+	}
+	if similar.JobID != "test-job-id" || similar.Similarity <= service.SimilarContentThreshold {
+		t.Errorf("unexpected similar content: %+v", similar)
+	}
+}
+
+// TestVerify_DetectorError tests handling of detector errors.
+func TestVerify_DetectorError(t *testing.T) {
+	h := New(Config{
+		Detector: &mockDetector{
+			err: io.ErrUnexpectedEOF, // Simulate detector error
+		},
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	body := `{"text": "This is test content that will cause a detector error."}`
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+}
+
+// TestVerify_DetectErrorMapping tests the status and code for each typed
+// detection error.
+func TestVerify_DetectErrorMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "unsupported content type",
+			err:        fmt.Errorf("%w: unknown", service.ErrUnsupportedContentType),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrCodeUnsupportedContentType,
+		},
+		{
+			name:       "content too large",
+			err:        fmt.Errorf("failed to fetch text from URL: %w", &service.FetchError{Err: service.ErrResponseTooLarge}),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   ErrCodeContentTooLarge,
+		},
+		{
+			name:       "fetch failed",
+			err:        fmt.Errorf("failed to fetch content: %w", &service.FetchError{Status: 404, Err: errors.New("unexpected status 404")}),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   ErrCodeFetchFailed,
+		},
+		{
+			name:       "provider unavailable",
+			err:        fmt.Errorf("detection failed: %w", service.ErrProviderUnavailable),
+			wantStatus: http.StatusBadGateway,
+			wantCode:   ErrCodeProviderUnavailable,
+		},
+		{
+			name:       "other",
+			err:        io.ErrUnexpectedEOF,
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "detection_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(Config{
+				Detector:      &mockDetector{err: tt.err},
+				Repository:    newMockRepository(),
+				Logger:        logger.NopLogger(),
+				MaxUploadSize: 10 * 1024 * 1024,
+			})
+
+			body := `{"url": "https://example.com/essay.txt"}`
+			req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			h.Verify(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			var response ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Code != tt.wantCode {
+				t.Errorf("expected code %s, got %s", tt.wantCode, response.Code)
+			}
+		})
+	}
+}
+
+// TestVerify_EmailMode tests that ?email=true turns on email analysis.
+func TestVerify_EmailMode(t *testing.T) {
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	for _, tt := range []struct {
+		query string
+		want  bool
+	}{
+		{"?email=true", true},
+		{"", false},
+	} {
+		body := `{"text": "Subject: Lunch\n\nAre we still on for Thursday?"}`
+		req := httptest.NewRequest("POST", "/verify"+tt.query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tt.query, rec.Code)
+		}
+		if detector.lastInput.EmailMode != tt.want {
+			t.Errorf("%q: EmailMode = %v, want %v", tt.query, detector.lastInput.EmailMode, tt.want)
+		}
+	}
+}
+
+// TestVerify_AcademicMode tests that ?academic=true turns on academic
+// analysis and that its results aren't shared with plain text requests.
+func TestVerify_AcademicMode(t *testing.T) {
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	for _, tt := range []struct {
+		query string
+		want  bool
+	}{
+		{"?academic=true", true},
+		{"", false},
+	} {
+		body := `{"text": "In this study, we measured ATP synthesis in iPSC-derived neurons [4]."}`
+		req := httptest.NewRequest("POST", "/verify"+tt.query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tt.query, rec.Code)
+		}
+		if detector.lastInput.AcademicMode != tt.want {
+			t.Errorf("%q: AcademicMode = %v, want %v", tt.query, detector.lastInput.AcademicMode, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/verify?academic=true", nil)
+	if shareResult(req, service.DetectionInput{AcademicMode: true}) {
+		t.Error("academic mode results should not be shared")
+	}
+}
+
+// TestVerify_CustomVocabulary tests that custom stop phrases and common words
+// reach the detector, and that oversized lists are rejected.
+func TestVerify_CustomVocabulary(t *testing.T) {
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	body := `{"text": "We leverage our balance sheet to fund growth.", "custom_stop_phrases": ["leverage"], "custom_common_words": ["covenant"]}`
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := detector.lastInput.CustomStopPhrases; len(got) != 1 || got[0] != "leverage" {
+		t.Errorf("CustomStopPhrases = %v, want [leverage]", got)
+	}
+	if got := detector.lastInput.CustomCommonWords; len(got) != 1 || got[0] != "covenant" {
+		t.Errorf("CustomCommonWords = %v, want [covenant]", got)
+	}
+
+	phrases, _ := json.Marshal(make([]string, maxCustomWords+1))
+	body = `{"text": "We leverage our balance sheet to fund growth.", "custom_stop_phrases": ` + string(phrases) + `}`
+	req = httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for too many stop phrases, got %d", rec.Code)
+	}
+}
+
+// TestVerify_DetectorSelection tests the detectors and exclude_detectors
+// request fields.
+func TestVerify_DetectorSelection(t *testing.T) {
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	verify := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		return rec
+	}
+
+	t.Run("passed to detector", func(t *testing.T) {
+		rec := verify(`{"text": "This is a test text to verify.", "detectors": ["humanmark"], "exclude_detectors": ["openai"]}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := detector.lastInput.Detectors; len(got) != 1 || got[0] != "humanmark" {
+			t.Errorf("Detectors = %v, want [humanmark]", got)
+		}
+		if got := detector.lastInput.ExcludeDetectors; len(got) != 1 || got[0] != "openai" {
+			t.Errorf("ExcludeDetectors = %v, want [openai]", got)
+		}
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		rec := verify(`{"text": "This is a test text to verify.", "detectors": ["turnitin"]}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("unconfigured provider", func(t *testing.T) {
+		detector.err = fmt.Errorf("detection failed: %w: detector \"openai\" is not configured", service.ErrInvalidDetectorSelection)
+		defer func() { detector.err = nil }()
+
+		rec := verify(`{"text": "This is a test text to verify.", "detectors": ["openai"]}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+
+		var resp ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Code != ErrCodeInvalidDetectors {
+			t.Errorf("code = %q, want %q", resp.Code, ErrCodeInvalidDetectors)
+		}
+	})
+}
+
+// TestVerify_UnreadableDocument tests that documents without extractable text
+// get a client error rather than a 500.
+func TestVerify_UnreadableDocument(t *testing.T) {
+	h := New(Config{
+		Detector: &mockDetector{
+			err: fmt.Errorf("detection failed: %w: pdf contains no readable text", service.ErrDocumentExtraction),
+		},
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("file", "scan.pdf")
+	part.Write([]byte("%PDF-1.4 scanned pages only"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/verify", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	h.Verify(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+
+	var response ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.Code != ErrCodeUnreadableDocument {
+		t.Errorf("expected code %q, got %q", ErrCodeUnreadableDocument, response.Code)
+	}
+	if !strings.Contains(response.Detail, "no readable text") || strings.HasPrefix(response.Detail, "detection failed") {
+		t.Errorf("unexpected message %q", response.Detail)
+	}
+}
+
+// TestVerify_DetectorErrors tests reporting failed detectors.
+func TestVerify_DetectorErrors(t *testing.T) {
+	t.Run("partial result", func(t *testing.T) {
+		h := New(Config{
+			Detector: &mockDetector{result: &service.DetectionResult{
+				Verdict:        service.VerdictHuman,
+				Human:          true,
+				AIScore:        0.1,
+				ContentType:    service.ContentTypeText,
+				Detectors:      []string{"humanmark"},
+				DetectorErrors: map[string]string{"gptzero": "panic: index out of range"},
+			}},
+			Repository:    newMockRepository(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+
+		body := `{"text": "This is a test text to verify."}`
+		req := httptest.NewRequest("POST", "/verify?detailed=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Details == nil || response.Details.DetectorErrors["gptzero"] != "panic: index out of range" {
+			t.Errorf("Details = %+v, want the gptzero error", response.Details)
+		}
+	})
+
+	t.Run("all failed", func(t *testing.T) {
+		h := New(Config{
+			Detector: &mockDetector{
+				err: fmt.Errorf("detection failed: %w: humanmark: panic: boom", service.ErrAllDetectorsFailed),
+			},
+			Repository:    newMockRepository(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+
+		body := `{"text": "This is a test text to verify."}`
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", rec.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Code != ErrCodeAllDetectorsFailed {
+			t.Errorf("expected code %q, got %q", ErrCodeAllDetectorsFailed, response.Code)
+		}
+	})
+}
+
+// TestVerify_ClientDisconnect tests that a cancelled request returns promptly
+// and stores nothing.
+func TestVerify_ClientDisconnect(t *testing.T) {
+	detector := &blockingDetector{started: make(chan struct{})}
+	repo := newMockRepository()
+	h := New(Config{
+		Detector:      detector,
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := `{"text": "This request will be abandoned before detection finishes."}`
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		h.Verify(rec, req)
+		close(done)
+	}()
+
+	<-detector.started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Verify did not return after the request was cancelled")
+	}
+
+	if len(repo.jobs) != 0 {
+		t.Errorf("expected no job to be stored, got %d", len(repo.jobs))
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected no response body, got %s", rec.Body.String())
+	}
+}
+
+// TestVerify_Timeout tests that slow detection is cut off with a 504, and
+// that slow content types get their longer budget.
+func TestVerify_Timeout(t *testing.T) {
+	budgets := map[string]time.Duration{"video": 2 * time.Second}
+	newTimeoutHandler := func(detector service.Detector) http.Handler {
+		h := New(Config{
+			Detector:      detector,
+			Repository:    newMockRepository(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+		return middleware.Timeout(50*time.Millisecond, budgets, nil)(http.HandlerFunc(h.Verify))
+	}
+
+	t.Run("slow detection times out", func(t *testing.T) {
+		detector := &slowDetector{delay: 10 * time.Second, cancelled: make(chan struct{})}
+		body := `{"text": "This text takes far too long for the detector to analyze."}`
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		newTimeoutHandler(detector).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatalf("expected status 504, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Code != ErrCodeRequestTimeout {
+			t.Errorf("expected code %s, got %s", ErrCodeRequestTimeout, response.Code)
+		}
+
+		// Detection was stopped, not left running
+		select {
+		case <-detector.cancelled:
+		case <-time.After(time.Second):
+			t.Error("detector context was not cancelled")
+		}
+	})
+
+	t.Run("video gets its budget", func(t *testing.T) {
+		detector := &slowDetector{delay: 150 * time.Millisecond, cancelled: make(chan struct{})}
+		body := `{"url": "https://example.com/clip.mp4"}`
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		newTimeoutHandler(detector).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200 within the video budget, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("detector deadline maps to 504", func(t *testing.T) {
+		h := New(Config{
+			Detector:      &mockDetector{err: context.DeadlineExceeded},
+			Repository:    newMockRepository(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+		body := `{"text": "This text arrives just as the deadline passes."}`
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected status 504, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+// TestVerify_Overloaded tests that a full concurrency pool for the
+// content type gets a 503 with Retry-After.
+func TestVerify_Overloaded(t *testing.T) {
+	detector := &blockingDetector{started: make(chan struct{})}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+	handler := middleware.MaxConcurrent(10, 0, map[string]int{"video": 1}, nil)(http.HandlerFunc(h.Verify))
+	request := func(ctx context.Context, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The first video holds the pool's only slot until cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		request(ctx, `{"url": "https://example.com/first.mp4"}`)
+		close(done)
+	}()
+	<-detector.started
+
+	rec := request(context.Background(), `{"url": "https://example.com/second.mp4"}`)
+	cancel()
+	<-done
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	var response ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Code != ErrCodeOverloaded {
+		t.Errorf("expected code %s, got %s", ErrCodeOverloaded, response.Code)
+	}
+	if strconv.Itoa(response.RetryAfter) != rec.Header().Get("Retry-After") {
+		t.Errorf("expected retry_after to match Retry-After, got %d", response.RetryAfter)
+	}
+}
+
+// TestVerify_Async tests queueing verification jobs for background processing.
+func TestVerify_Async(t *testing.T) {
+	body := `{"text": "This is a test text that should be verified as human-written content."}`
+
+	newAsyncHandler := func(queue *mockQueue, repo *mockRepository, threshold int64) *Handler {
+		return New(Config{
+			Detector:       &mockDetector{},
+			Repository:     repo,
+			Logger:         logger.NopLogger(),
+			MaxUploadSize:  10 * 1024 * 1024,
+			Queue:          queue,
+			AsyncThreshold: threshold,
+		})
+	}
+
+	t.Run("async=true returns 202 with location", func(t *testing.T) {
+		queue := &mockQueue{}
+		repo := newMockRepository()
+		h := newAsyncHandler(queue, repo, 0)
+
+		req := httptest.NewRequest("POST", "/verify?async=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if loc := rec.Header().Get("Location"); loc != "/verify/test-job-id" {
+			t.Errorf("expected Location /verify/test-job-id, got %q", loc)
+		}
+
+		var response JobStatusResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Status != "pending" {
+			t.Errorf("expected status pending, got %q", response.Status)
+		}
+		if len(queue.enqueued) != 1 || queue.enqueued[0] != "test-job-id" {
+			t.Errorf("expected job to be enqueued, got %v", queue.enqueued)
+		}
+		if repo.jobs["test-job-id"].Status != repository.JobStatusPending {
+			t.Errorf("expected stored job to be pending, got %q", repo.jobs["test-job-id"].Status)
+		}
+	})
+
+	t.Run("large request switches to async", func(t *testing.T) {
+		queue := &mockQueue{}
+		h := newAsyncHandler(queue, newMockRepository(), 16)
+
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("small request stays sync", func(t *testing.T) {
+		queue := &mockQueue{}
+		h := newAsyncHandler(queue, newMockRepository(), 1024*1024)
+
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if len(queue.enqueued) != 0 {
+			t.Errorf("expected nothing enqueued, got %v", queue.enqueued)
+		}
+	})
+
+	t.Run("full queue returns 503 and fails job", func(t *testing.T) {
+		repo := newMockRepository()
+		h := newAsyncHandler(&mockQueue{err: io.ErrShortWrite}, repo, 0)
+
+		req := httptest.NewRequest("POST", "/verify?async=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503, got %d", rec.Code)
+		}
+		if repo.jobs["test-job-id"].Status != repository.JobStatusFailed {
+			t.Errorf("expected job to be marked failed, got %q", repo.jobs["test-job-id"].Status)
+		}
+	})
+
+	t.Run("async ignored without queue", func(t *testing.T) {
+		h := newTestHandler()
+
+		req := httptest.NewRequest("POST", "/verify?async=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Verify(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+	})
+}
+
+// TestVerify_CallbackURL tests webhook callback validation.
+func TestVerify_CallbackURL(t *testing.T) {
+	newCallbackHandler := func(queue *mockQueue, repo *mockRepository, requireHTTPS bool) *Handler {
+		return New(Config{
+			Detector:              &mockDetector{},
+			Repository:            repo,
+			Logger:                logger.NopLogger(),
+			MaxUploadSize:         10 * 1024 * 1024,
+			Queue:                 queue,
+			CallbacksEnabled:      true,
+			RequireHTTPSCallbacks: requireHTTPS,
+		})
+	}
+
+	verify := func(h *Handler, callbackURL string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(VerifyRequest{
+			Text:        "This is a test text that should be verified as human-written content.",
+			CallbackURL: callbackURL,
+		})
+		req := httptest.NewRequest("POST", "/verify", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		return rec
+	}
+
+	t.Run("callback implies async and is stored", func(t *testing.T) {
+		queue := &mockQueue{}
+		repo := newMockRepository()
+		rec := verify(newCallbackHandler(queue, repo, true), "https://example.com/hook")
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if repo.jobs["test-job-id"].CallbackURL != "https://example.com/hook" {
+			t.Errorf("expected callback URL on job, got %q", repo.jobs["test-job-id"].CallbackURL)
+		}
+	})
+
+	t.Run("rejects http when https required", func(t *testing.T) {
+		rec := verify(newCallbackHandler(&mockQueue{}, newMockRepository(), true), "http://example.com/hook")
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("allows http when https not required", func(t *testing.T) {
+		rec := verify(newCallbackHandler(&mockQueue{}, newMockRepository(), false), "http://example.com/hook")
+
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("rejects invalid URLs", func(t *testing.T) {
+		h := newCallbackHandler(&mockQueue{}, newMockRepository(), false)
+		for _, u := range []string{"ftp://example.com/hook", "not a url", "https://"} {
+			if rec := verify(h, u); rec.Code != http.StatusBadRequest {
+				t.Errorf("%q: expected status 400, got %d", u, rec.Code)
+			}
+		}
+	})
+
+	t.Run("rejects callback when disabled", func(t *testing.T) {
+		rec := verify(newTestHandler(), "https://example.com/hook")
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+// TestVerify_PageMode tests mode=page responses and job linking.
+func TestVerify_PageMode(t *testing.T) {
+	imageResult := &service.DetectionResult{
+		ContentType: service.ContentTypeImage,
+		Verdict:     service.VerdictAI,
+		Confidence:  0.8,
+		AIScore:     0.9,
+		Detectors:   []string{"mock"},
+	}
+	pageResult := &service.PageResult{
+		URL:  "https://example.com/story",
+		HTML: true,
+		Page: &service.DetectionResult{
+			ContentType: service.ContentTypeText,
+			Human:       true,
+			Verdict:     service.VerdictHuman,
+			Confidence:  0.9,
+			Detectors:   []string{"mock"},
+		},
+		Assets: []service.PageAsset{
+			{URL: "https://example.com/a.png", ContentType: service.ContentTypeImage, Result: imageResult},
+			{URL: "https://example.com/b.png", ContentType: service.ContentTypeImage, Error: "URL returned status 404"},
+		},
+		DroppedAssets: 3,
+	}
+
+	verifyPage := func(h *Handler, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/verify?mode=page", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		return rec
+	}
+
+	t.Run("page with assets", func(t *testing.T) {
+		repo := repository.NewMemory()
+		h := New(Config{
+			Detector:      &mockDetector{},
+			PageDetector:  &mockPageDetector{result: pageResult},
+			Repository:    repo,
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+
+		rec := verifyPage(h, `{"url": "https://example.com/story"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var response PageVerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.ContentType != "page" || response.Verdict != "human" || !response.HasText {
+			t.Errorf("page = %+v, want the text's human verdict", response.VerifyResponse)
+		}
+		if response.URL != "https://example.com/story" || response.DroppedAssets != 3 {
+			t.Errorf("url = %q, dropped = %d", response.URL, response.DroppedAssets)
+		}
+		if len(response.Assets) != 2 {
+			t.Fatalf("expected 2 assets, got %d", len(response.Assets))
+		}
+
+		asset := response.Assets[0]
+		if asset.Result == nil || asset.Result.Verdict != "ai" || asset.Result.ParentID != response.ID {
+			t.Fatalf("asset result = %+v, want an ai verdict linked to the page", asset.Result)
+		}
+		if failed := response.Assets[1]; failed.Result != nil || failed.Error == "" {
+			t.Errorf("failed asset = %+v, want an error and no result", failed)
+		}
+
+		parent, err := repo.GetJob(context.Background(), response.ID)
+		if err != nil || parent.ContentType != "page" {
+			t.Errorf("page job = %+v (%v), want content_type page", parent, err)
+		}
+		child, err := repo.GetJob(context.Background(), asset.Result.ID)
+		if err != nil || child.ParentID != response.ID || child.ContentType != "image" {
+			t.Errorf("asset job = %+v (%v), want an image job linked to the page", child, err)
+		}
+	})
+
+	t.Run("page without text", func(t *testing.T) {
+		h := New(Config{
+			Detector:      &mockDetector{},
+			PageDetector:  &mockPageDetector{result: &service.PageResult{URL: "https://example.com/gallery", HTML: true}},
+			Repository:    newMockRepository(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+
+		rec := verifyPage(h, `{"url": "https://example.com/gallery"}`)
+		var response PageVerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.HasText || response.Verdict != "uncertain" || response.Confidence != 0 {
+			t.Errorf("page = %+v, want uncertain without text", response.VerifyResponse)
+		}
+	})
+
+	t.Run("not a page", func(t *testing.T) {
+		h := New(Config{
+			Detector:      &mockDetector{},
+			PageDetector:  &mockPageDetector{result: &service.PageResult{Page: imageResult}},
+			Repository:    newMockRepository(),
+			Logger:        logger.NopLogger(),
+			MaxUploadSize: 10 * 1024 * 1024,
+		})
+
+		rec := verifyPage(h, `{"url": "https://example.com/a.png"}`)
+		var response map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response["content_type"] != "image" || response["assets"] != nil {
+			t.Errorf("response = %v, want a plain image verification", response)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			query      string
+			body       string
+			pages      service.PageDetector
+			wantStatus int
+			wantCode   string
+		}{
+			{"unknown mode", "mode=site", `{"url": "https://example.com"}`, &mockPageDetector{}, http.StatusBadRequest, ErrCodeInvalidMode},
+			{"text input", "mode=page", `{"text": "` + strings.Repeat("word ", 20) + `"}`, &mockPageDetector{}, http.StatusBadRequest, "validation_error"},
+			{"not configured", "mode=page", `{"url": "https://example.com"}`, nil, http.StatusNotImplemented, ErrCodePageModeUnavailable},
+			{"empty page", "mode=page", `{"url": "https://example.com"}`, &mockPageDetector{err: service.ErrEmptyPage}, http.StatusUnprocessableEntity, ErrCodeEmptyPage},
+			{"fetch failed", "mode=page", `{"url": "https://example.com"}`, &mockPageDetector{err: &service.FetchError{Status: 404, Err: errors.New("status 404")}}, http.StatusUnprocessableEntity, ErrCodeFetchFailed},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				h := New(Config{
+					Detector:      &mockDetector{},
+					PageDetector:  tt.pages,
+					Repository:    newMockRepository(),
+					Logger:        logger.NopLogger(),
+					MaxUploadSize: 10 * 1024 * 1024,
+				})
+
+				req := httptest.NewRequest("POST", "/verify?"+tt.query, strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				rec := httptest.NewRecorder()
+				h.Verify(rec, req)
+
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+				}
+				var response ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if response.Code != tt.wantCode {
+					t.Errorf("expected code %q, got %q", tt.wantCode, response.Code)
+				}
+			})
+		}
+	})
+}
+
+// TestGetResult tests retrieving verification results.
+func TestGetResult(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	// First, create a job
+	repo.jobs["existing-id"] = &repository.Job{
+		ID:          "existing-id",
+		Human:       true,
+		Confidence:  0.9,
+		ContentType: "text",
+		CreatedAt:   time.Now(),
+	}
+
+	t.Run("returns existing job", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/verify/existing-id", nil)
+		req.SetPathValue("id", "existing-id")
+		rec := httptest.NewRecorder()
+
+		h.GetResult(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if response.ID != "existing-id" {
+			t.Errorf("expected ID 'existing-id', got %s", response.ID)
+		}
+		// Stored without a verdict, so it's derived from Human
+		if response.Verdict != "human" {
+			t.Errorf("expected verdict human, got %q", response.Verdict)
+		}
+	})
+
+	t.Run("returns stored verdict", func(t *testing.T) {
+		repo.jobs["uncertain-id"] = &repository.Job{
+			ID:        "uncertain-id",
+			Status:    repository.JobStatusCompleted,
+			Verdict:   "uncertain",
+			AIScore:   0.55,
+			CreatedAt: time.Now(),
+		}
+
+		req := httptest.NewRequest("GET", "/verify/uncertain-id", nil)
+		req.SetPathValue("id", "uncertain-id")
+		rec := httptest.NewRecorder()
+
+		h.GetResult(rec, req)
+
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Verdict != "uncertain" || response.Human {
+			t.Errorf("expected uncertain verdict, got %q (human=%v)", response.Verdict, response.Human)
+		}
+	})
+
+	t.Run("returns status for unfinished jobs", func(t *testing.T) {
+		repo.jobs["pending-id"] = &repository.Job{
+			ID:        "pending-id",
+			Status:    repository.JobStatusPending,
+			CreatedAt: time.Now(),
+		}
+		repo.jobs["failed-id"] = &repository.Job{
+			ID:        "failed-id",
+			Status:    repository.JobStatusFailed,
+			Error:     "fetch failed",
+			CreatedAt: time.Now(),
+		}
+
+		repo.jobs["processing-id"] = &repository.Job{
+			ID:           "processing-id",
+			Status:       repository.JobStatusProcessing,
+			AttemptCount: 2,
+			CreatedAt:    time.Now(),
+		}
+
+		tests := []struct {
+			id       string
+			status   string
+			code     int
+			attempts int
+		}{
+			{"pending-id", "pending", http.StatusAccepted, 0},
+			{"processing-id", "processing", http.StatusAccepted, 2},
+			{"failed-id", "failed", http.StatusOK, 0},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest("GET", "/verify/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			rec := httptest.NewRecorder()
+
+			h.GetResult(rec, req)
+
+			if rec.Code != tt.code {
+				t.Errorf("%s: expected status %d, got %d", tt.id, tt.code, rec.Code)
+			}
+
+			var response JobStatusResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Status != tt.status || response.Attempts != tt.attempts {
+				t.Errorf("%s: expected status %q after %d attempts, got %q after %d",
+					tt.id, tt.status, tt.attempts, response.Status, response.Attempts)
+			}
+		}
+	})
+
+	t.Run("returns full result for completed async job", func(t *testing.T) {
+		repo.jobs["done-id"] = &repository.Job{
+			ID:          "done-id",
+			Status:      repository.JobStatusCompleted,
+			Human:       false,
+			Confidence:  0.8,
+			AIScore:     0.8,
+			ContentType: "video",
+			Detectors:   []string{"video_analyzer"},
+			CreatedAt:   time.Now(),
+
+			AnalyzerVersion:   "1.0.0",
+			ConfigFingerprint: "0123456789abcdef",
+		}
+
+		req := httptest.NewRequest("GET", "/verify/done-id", nil)
+		req.SetPathValue("id", "done-id")
+		rec := httptest.NewRecorder()
+
+		h.GetResult(rec, req)
+
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Status != "completed" {
+			t.Errorf("expected status completed, got %q", response.Status)
+		}
+		if response.Details == nil || response.Details.AIScore != 0.8 {
+			t.Errorf("expected details with ai_score 0.8, got %+v", response.Details)
+		}
+		if response.AnalyzerVersion != "1.0.0" || response.ConfigFingerprint != "0123456789abcdef" {
+			t.Errorf("expected stored version and fingerprint, got %q %q", response.AnalyzerVersion, response.ConfigFingerprint)
+		}
+	})
+
+	t.Run("returns 404 for non-existent job", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/verify/non-existent", nil)
+		req.SetPathValue("id", "non-existent")
+		rec := httptest.NewRecorder()
+
+		h.GetResult(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("returns 400 for missing ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/verify/", nil)
+		req.SetPathValue("id", "")
+		rec := httptest.NewRecorder()
+
+		h.GetResult(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+// TestGetResult_Conditional tests ETags and caching of results.
+func TestGetResult_Conditional(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+	repo.jobs["done-id"] = &repository.Job{
+		ID:              "done-id",
+		Status:          repository.JobStatusCompleted,
+		Confidence:      0.8,
+		ContentType:     "text",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+		AnalyzerVersion: "1.0.0",
+	}
+	repo.jobs["pending-id"] = &repository.Job{ID: "pending-id", Status: repository.JobStatusPending, CreatedAt: time.Now()}
+
+	get := func(id, target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.SetPathValue("id", id)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.GetResult(rec, req)
+		return rec
+	}
+
+	first := get("done-id", "/verify/done-id", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.Len() == 0 {
+		t.Fatalf("expected status 200 with a body, got %d", first.Code)
+	}
+	if !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("expected a strong ETag, got %q", etag)
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "private, max-age=300" {
+		t.Errorf("expected Cache-Control private, max-age=300, got %q", cc)
+	}
+
+	t.Run("matching If-None-Match gets 304", func(t *testing.T) {
+		for _, header := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			rec := get("done-id", "/verify/done-id", header)
+			if rec.Code != http.StatusNotModified {
+				t.Errorf("If-None-Match %s: expected status 304, got %d", header, rec.Code)
+			}
+			if rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+				t.Errorf("If-None-Match %s: expected no body and the ETag, got %q and %q", header, rec.Body.String(), rec.Header().Get("ETag"))
+			}
+		}
+	})
+
+	t.Run("stale ETag gets the result", func(t *testing.T) {
+		if rec := get("done-id", "/verify/done-id", `"stale"`); rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+
+		repo.jobs["done-id"].UpdatedAt = repo.jobs["done-id"].UpdatedAt.Add(time.Second)
+		rec := get("done-id", "/verify/done-id", etag)
+		if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+			t.Errorf("expected status 200 and a new ETag after an update, got %d and %q", rec.Code, rec.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("detailed result has its own ETag", func(t *testing.T) {
+		rec := get("done-id", "/verify/done-id?detailed=true", "")
+		if detailed := rec.Header().Get("ETag"); detailed == "" || detailed == get("done-id", "/verify/done-id", "").Header().Get("ETag") {
+			t.Errorf("expected a distinct ETag for the detailed result, got %q", detailed)
+		}
+	})
+
+	t.Run("pending job is not cached", func(t *testing.T) {
+		rec := get("pending-id", "/verify/pending-id", "*")
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("expected status 202, got %d", rec.Code)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("expected Cache-Control no-store, got %q", cc)
+		}
+		if etag := rec.Header().Get("ETag"); etag != "" {
+			t.Errorf("expected no ETag, got %q", etag)
+		}
+	})
+}
+
+// TestGetResult_Detailed tests that a stored result's breakdown matches the
+// one the verification returned.
+// TestDeleteResult tests DELETE /verify/{id}.
+func TestDeleteResult(t *testing.T) {
+	repo := repository.NewMemory()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	job, err := repo.CreateJob(context.Background(), repository.Job{Status: repository.JobStatusCompleted, ContentType: "text"})
+	if err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+
+	deleteResult := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/verify/"+id, nil)
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		h.DeleteResult(rec, req)
+		return rec
+	}
+
+	t.Run("deletes the result", func(t *testing.T) {
+		rec := deleteResult(job.ID)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("expected an empty body, got %q", rec.Body.String())
+		}
+
+		req := httptest.NewRequest("GET", "/verify/"+job.ID, nil)
+		req.SetPathValue("id", job.ID)
+		rec = httptest.NewRecorder()
+		h.GetResult(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET after delete: expected status 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("already deleted", func(t *testing.T) {
+		if rec := deleteResult(job.ID); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("unknown ID", func(t *testing.T) {
+		rec := deleteResult("non-existent")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Code != "not_found" {
+			t.Errorf("expected code not_found, got %q", response.Code)
+		}
+	})
+
+	t.Run("missing ID", func(t *testing.T) {
+		if rec := deleteResult(""); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("other keys' results", func(t *testing.T) {
+		owned, err := repo.CreateJob(context.Background(), repository.Job{Status: repository.JobStatusCompleted, ContentType: "text", APIKeyID: "k1"})
+		if err != nil {
+			t.Fatalf("CreateJob() error = %v", err)
+		}
+		deleteAs := func(key *repository.APIKey) int {
+			req := httptest.NewRequest("DELETE", "/verify/"+owned.ID, nil)
+			req.SetPathValue("id", owned.ID)
+			if key != nil {
+				req = req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+			}
+			rec := httptest.NewRecorder()
+			h.DeleteResult(rec, req)
+			return rec.Code
+		}
+
+		if code := deleteAs(&repository.APIKey{ID: "k2", Scopes: repository.Scopes}); code != http.StatusNotFound {
+			t.Errorf("another key: expected status 404, got %d", code)
+		}
+		if code := deleteAs(nil); code != http.StatusNotFound {
+			t.Errorf("no key: expected status 404, got %d", code)
+		}
+		if _, err := repo.GetJob(context.Background(), owned.ID); err != nil {
+			t.Fatalf("result deleted by another caller: %v", err)
+		}
+		if code := deleteAs(&repository.APIKey{ID: "k1", Scopes: repository.Scopes}); code != http.StatusNoContent {
+			t.Errorf("owning key: expected status 204, got %d", code)
+		}
+	})
+}
+
+func TestGetResult_Detailed(t *testing.T) {
+	h := New(Config{
+		Detector: &mockDetector{result: &service.DetectionResult{
+			Verdict:      service.VerdictAI,
+			Confidence:   0.8,
+			AIScore:      0.9,
+			ContentType:  service.ContentTypeText,
+			Detectors:    []string{"humanmark", "hive"},
+			Aggregation:  service.AggregationVeto,
+			Disagreement: 0.2,
+			ProviderScores: []service.ProviderScore{
+				{Name: "humanmark", AIScore: 0.5, Weight: 1.0},
+				{Name: "hive", AIScore: 0.9, Weight: 1.2},
+			},
+			DetectorOutputs: []service.DetectorOutput{
+				{Name: "hive", Sentences: []service.SentenceScore{{Text: "Let us delve into it.", AIScore: 0.9}}},
+				{
+					Name:       "gptzero",
+					Sentences:  []service.SentenceScore{{Text: "Let us delve into it.", AIScore: 0.8}},
+					Paragraphs: []service.ParagraphScore{{StartSentence: 0, Sentences: 1, AIScore: 0.8}},
+				},
+			},
+			SentenceAgreement: []service.SentenceAgreement{
+				{Detectors: []string{"gptzero", "hive"}, Sentences: 1, Agreement: 1, MeanDifference: 0.1},
+			},
+			AIPhrases:        []service.DetectedPhrase{{Phrase: "delve into", Category: "filler"}},
+			SkippedProviders: []service.SkippedProvider{{Name: "winston", Reason: "provider skipped: text too short"}},
+			DetectorErrors:   map[string]string{"sapling": "timeout"},
+			ProcessingTime:   1200 * time.Millisecond,
+			Watermark: &service.WatermarkScan{
+				Experimental: true, PValue: 0.25, Score: 0.75, Words: 120, WordPairs: 14, ChiSquare: 9.1, DegreesOfFreedom: 7,
+			},
+		}},
+		Repository:    repository.NewMemory(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	req := httptest.NewRequest("POST", "/verify?detailed=true", strings.NewReader(`{"text": "Let us delve into it."}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.Verify(rec, req)
+
+	var posted VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&posted); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if posted.Details == nil || len(posted.Details.AIPhrases) != 1 || posted.Details.ProcessingTimeMs != 1200 {
+		t.Fatalf("expected phrases and processing time in POST details, got %+v", posted.Details)
+	}
+	if w := posted.Details.Watermark; w == nil || !w.Experimental || w.PValue != 0.25 || w.Words != 120 {
+		t.Fatalf("expected the watermark scan in POST details, got %+v", w)
+	}
+	if a := posted.Details.SentenceAgreement; len(a) != 1 || a[0].Sentences != 1 || len(posted.Details.DetectorOutputs[1].Paragraphs) != 1 {
+		t.Fatalf("expected sentence agreement and paragraphs in POST details, got %+v", posted.Details)
+	}
+
+	getResult := func(query string) VerifyResponse {
+		req := httptest.NewRequest("GET", "/verify/"+posted.ID+query, nil)
+		req.SetPathValue("id", posted.ID)
+		rec := httptest.NewRecorder()
+		h.GetResult(rec, req)
+
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("detailed returns the stored breakdown", func(t *testing.T) {
+		got := getResult("?detailed=true")
+		if !reflect.DeepEqual(got.Details, posted.Details) {
+			t.Errorf("GET details = %+v\nPOST details = %+v", got.Details, posted.Details)
+		}
+	})
+
+	t.Run("summary by default", func(t *testing.T) {
+		got := getResult("")
+		if got.Details == nil || got.Details.AIScore != 0.9 || got.Details.Signals != nil || got.Details.AIPhrases != nil {
+			t.Errorf("expected only detectors and AI score, got %+v", got.Details)
+		}
+	})
+}
+
+// TestGetResultByHash tests looking up results by exact and normalized
+// content hash.
+func TestGetResultByHash(t *testing.T) {
+	repo := repository.NewMemory()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /verify/{kind}/{sha256}", h.GetResultByHash)
+	mux.HandleFunc("GET /verify/{id}/certificate", h.GetCertificate)
+
+	text := "An essay\n\nwith  its own   line breaks."
+	exactHash := service.ContentHash(service.DetectionInput{Text: text})
+	normalizedHash := service.NormalizedTextHash(text)
+	stored, _ := repo.CreateJob(context.Background(), repository.Job{
+		Status:         repository.JobStatusCompleted,
+		ContentType:    "text",
+		Verdict:        "ai",
+		Confidence:     0.8,
+		ContentHash:    exactHash,
+		NormalizedHash: normalizedHash,
+	})
+	pendingText := "Still being analyzed."
+	repo.CreateJob(context.Background(), repository.Job{
+		Status:      repository.JobStatusPending,
+		ContentHash: service.ContentHash(service.DetectionInput{Text: pendingText}),
+	})
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		code   string
+	}{
+		{"exact hash", "/verify/hash/" + exactHash, http.StatusOK, ""},
+		{"uppercase hash", "/verify/hash/" + strings.ToUpper(exactHash), http.StatusOK, ""},
+		{"normalized hash", "/verify/hash/" + normalizedHash + "?normalized=true", http.StatusOK, ""},
+		{"reflowed copy", "/verify/hash/" + service.NormalizedTextHash("An essay with its own line breaks.") + "?normalized=true", http.StatusOK, ""},
+		{"normalized hash without the flag", "/verify/hash/" + normalizedHash, http.StatusNotFound, ErrCodeHashNotFound},
+		{"exact hash as normalized", "/verify/hash/" + exactHash + "?normalized=true", http.StatusNotFound, ErrCodeHashNotFound},
+		{"unknown hash", "/verify/hash/" + strings.Repeat("0", 64), http.StatusNotFound, ErrCodeHashNotFound},
+		{"pending job", "/verify/hash/" + service.ContentHash(service.DetectionInput{Text: pendingText}), http.StatusNotFound, ErrCodeHashNotFound},
+		{"short hash", "/verify/hash/" + exactHash[:63], http.StatusBadRequest, ErrCodeInvalidHash},
+		{"not hex", "/verify/hash/" + strings.Repeat("g", 64), http.StatusBadRequest, ErrCodeInvalidHash},
+		{"bad normalized", "/verify/hash/" + exactHash + "?normalized=yes", http.StatusBadRequest, ErrCodeInvalidParameter},
+		{"other kind", "/verify/other/" + exactHash, http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.target)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.status == http.StatusOK {
+				var response VerifyResponse
+				if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if response.ID != stored.ID || response.Verdict != "ai" || response.CreatedAt.IsZero() {
+					t.Errorf("expected stored job %s, got %+v", stored.ID, response)
+				}
+				return
+			}
+			var response ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&response)
+			if response.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, response.Code)
+			}
+		})
+	}
 
-// TestVerify_InvalidURL tests validation for invalid URL.
-func TestVerify_InvalidURL(t *testing.T) {
-	h := newTestHandler()
+}
 
-	body := `{"url": "not-a-valid-url"}`
-	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	rec := httptest.NewRecorder()
+// TestListResults tests listing stored jobs.
+func TestListResults(t *testing.T) {
+	repo := repository.NewMemory()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
 
-	h.Verify(rec, req)
+	list := func(query string) (*httptest.ResponseRecorder, ListResponse) {
+		req := httptest.NewRequest("GET", "/verify?"+query, nil)
+		rec := httptest.NewRecorder()
+		h.ListResults(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+		var response ListResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, response
 	}
-}
 
-// TestVerify_Detailed tests detailed response parameter.
-func TestVerify_Detailed(t *testing.T) {
-	h := newTestHandler()
+	t.Run("empty list", func(t *testing.T) {
+		rec, response := list("")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if response.Items == nil || len(response.Items) != 0 || response.NextCursor != "" {
+			t.Errorf("expected empty items and no cursor, got %+v", response)
+		}
+	})
 
-	body := `{"text": "This is test content for detailed response testing."}`
-	req := httptest.NewRequest("POST", "/verify?detailed=true", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	rec := httptest.NewRecorder()
+	ctx := context.Background()
+	repo.CreateJob(ctx, repository.Job{Status: repository.JobStatusCompleted, ContentType: "text", Human: true, Verdict: "human"})
+	time.Sleep(time.Millisecond)
+	repo.CreateJob(ctx, repository.Job{Status: repository.JobStatusCompleted, ContentType: "image", Verdict: "ai"})
+	time.Sleep(time.Millisecond)
+	pending, _ := repo.CreateJob(ctx, repository.Job{Status: repository.JobStatusPending, ContentType: "image"})
+	time.Sleep(time.Millisecond)
+	repo.CreateJob(ctx, repository.Job{Status: repository.JobStatusCompleted, ContentType: "text", Verdict: "ai"})
+
+	t.Run("pages with cursor", func(t *testing.T) {
+		_, first := list("limit=3")
+		if len(first.Items) != 3 || first.NextCursor == "" {
+			t.Fatalf("expected 3 items and a cursor, got %+v", first)
+		}
+		if first.Items[1].ID != pending.ID || first.Items[1].Status != "pending" || first.Items[1].Verdict != "" {
+			t.Errorf("expected pending job without verdict second, got %+v", first.Items[1])
+		}
 
-	h.Verify(rec, req)
+		_, second := list("limit=3&cursor=" + first.NextCursor)
+		if len(second.Items) != 1 || second.NextCursor != "" {
+			t.Fatalf("expected last item and no cursor, got %+v", second)
+		}
+		if second.Items[0].Verdict != "human" || !second.Items[0].Human {
+			t.Errorf("expected oldest, human job last, got %+v", second.Items[0])
+		}
+	})
 
-	if rec.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
-	}
+	t.Run("filters", func(t *testing.T) {
+		_, response := list("content_type=text&human=false")
+		if len(response.Items) != 1 || response.Items[0].ContentType != "text" || response.Items[0].Verdict != "ai" {
+			t.Errorf("expected the AI text job, got %+v", response.Items)
+		}
 
-	var response VerifyResponse
-	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
-	}
+		_, response = list("content_type=image&verdict=ai")
+		if len(response.Items) != 1 || response.Items[0].ContentType != "image" {
+			t.Errorf("expected the AI image job, got %+v", response.Items)
+		}
+	})
 
-	if response.Details == nil {
-		t.Error("expected details in response")
-	}
-	if response.Details != nil && len(response.Details.Detectors) == 0 {
-		t.Error("expected detectors in details")
-	}
+	t.Run("invalid parameters", func(t *testing.T) {
+		tests := []struct {
+			query string
+			code  string
+		}{
+			{"limit=0", ErrCodeInvalidParameter},
+			{"limit=ten", ErrCodeInvalidParameter},
+			{"human=maybe", ErrCodeInvalidParameter},
+			{"verdict=robot", ErrCodeInvalidParameter},
+			{"created_after=yesterday", ErrCodeInvalidParameter},
+			{"cursor=bogus", ErrCodeInvalidCursor},
+		}
+
+		for _, tt := range tests {
+			rec, _ := list(tt.query)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", tt.query, rec.Code)
+				continue
+			}
+			var response ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&response)
+			if response.Code != tt.code {
+				t.Errorf("%s: expected code %s, got %s", tt.query, tt.code, response.Code)
+			}
+		}
+	})
 }
 
-// TestVerify_DetectorError tests handling of detector errors.
-func TestVerify_DetectorError(t *testing.T) {
+// TestListResults_APIKeys tests that each API key lists only the results
+// it submitted, and requests without a key only those submitted without
+// one.
+func TestListResults_APIKeys(t *testing.T) {
 	h := New(Config{
-		Detector: &mockDetector{
-			err: io.ErrUnexpectedEOF, // Simulate detector error
-		},
-		Repository:    newMockRepository(),
+		Detector:      &mockDetector{},
+		Repository:    repository.NewMemory(),
 		Logger:        logger.NopLogger(),
 		MaxUploadSize: 10 * 1024 * 1024,
 	})
+	withKey := func(req *http.Request, key *repository.APIKey) *http.Request {
+		if key == nil {
+			return req
+		}
+		return req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+	}
+	k1 := &repository.APIKey{ID: "k1", Scopes: repository.Scopes}
+	k2 := &repository.APIKey{ID: "k2", Scopes: repository.Scopes}
 
-	body := `{"text": "This is test content that will cause a detector error."}`
-	req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	rec := httptest.NewRecorder()
-
-	h.Verify(rec, req)
+	// Each caller verifies its own text
+	submitted := make(map[*repository.APIKey]string)
+	for i, key := range []*repository.APIKey{k1, k2, nil} {
+		body := fmt.Sprintf(`{"text": "Draft number %d, written by hand over a long weekend."}`, i)
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, withKey(req, key))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Verify: expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response VerifyResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		submitted[key] = response.ID
+	}
 
-	if rec.Code != http.StatusInternalServerError {
-		t.Errorf("expected status 500, got %d", rec.Code)
+	for _, key := range []*repository.APIKey{k1, k2, nil} {
+		rec := httptest.NewRecorder()
+		h.ListResults(rec, withKey(httptest.NewRequest("GET", "/verify", nil), key))
+		var response ListResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if len(response.Items) != 1 || response.Items[0].ID != submitted[key] {
+			t.Errorf("key %v: expected only job %s, got %+v", key, submitted[key], response.Items)
+		}
 	}
 }
 
-// TestGetResult tests retrieving verification results.
-func TestGetResult(t *testing.T) {
+// TestGetReport tests downloading results as reports.
+func TestGetReport(t *testing.T) {
 	repo := newMockRepository()
+	detector := &mockDetector{result: &service.DetectionResult{
+		Verdict:     service.VerdictAI,
+		Confidence:  0.9,
+		AIScore:     0.93,
+		ContentType: service.ContentTypeText,
+		Detectors:   []string{"humanmark", "winston"},
+		ProviderScores: []service.ProviderScore{
+			{Name: "humanmark", AIScore: 0.88, Weight: 1},
+			{Name: "winston", AIScore: 0.97, Weight: 1},
+		},
+		AIPhrases: []service.DetectedPhrase{{Phrase: "delve into", Category: "filler"}},
+	}}
 	h := New(Config{
-		Detector:      &mockDetector{},
+		Detector:      detector,
 		Repository:    repo,
 		Logger:        logger.NopLogger(),
 		MaxUploadSize: 10 * 1024 * 1024,
 	})
 
-	// First, create a job
-	repo.jobs["existing-id"] = &repository.Job{
-		ID:          "existing-id",
-		Human:       true,
-		Confidence:  0.9,
-		ContentType: "text",
-		CreatedAt:   time.Now(),
-	}
+	// Store the result the way a verification does
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(`{"text": "Let us delve into the topic."}`))
+	req.Header.Set("Content-Type", "application/json")
+	h.Verify(httptest.NewRecorder(), req)
+	repo.jobs["pending-id"] = &repository.Job{ID: "pending-id", Status: repository.JobStatusPending}
 
-	t.Run("returns existing job", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/verify/existing-id", nil)
-		req.SetPathValue("id", "existing-id")
+	getReport := func(id, name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/verify/"+id+"/"+name, nil)
+		req.SetPathValue("id", id)
 		rec := httptest.NewRecorder()
+		h.GetReport(rec, req)
+		return rec
+	}
 
-		h.GetResult(rec, req)
-
+	t.Run("json", func(t *testing.T) {
+		rec := getReport("test-job-id", "report.json")
 		if rec.Code != http.StatusOK {
-			t.Errorf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
 		}
-
-		var response VerifyResponse
-		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
-			t.Fatalf("failed to decode response: %v", err)
+		var result service.DetectionResult
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
 		}
-
-		if response.ID != "existing-id" {
-			t.Errorf("expected ID 'existing-id', got %s", response.ID)
+		if result.Verdict != service.VerdictAI || len(result.ProviderScores) != 2 || len(result.AIPhrases) != 1 {
+			t.Errorf("report = %+v, want the stored scores and phrases", result)
 		}
 	})
 
-	t.Run("returns 404 for non-existent job", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/verify/non-existent", nil)
-		req.SetPathValue("id", "non-existent")
-		rec := httptest.NewRecorder()
-
-		h.GetResult(rec, req)
-
-		if rec.Code != http.StatusNotFound {
-			t.Errorf("expected status 404, got %d", rec.Code)
+	t.Run("csv", func(t *testing.T) {
+		rec := getReport("test-job-id", "report.csv")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "humanmark-test-job-id.csv") {
+			t.Errorf("Content-Disposition = %q", cd)
+		}
+		if !strings.Contains(rec.Body.String(), "humanmark=0.8800; winston=0.9700") {
+			t.Errorf("expected provider scores in report, got:\n%s", rec.Body.String())
 		}
 	})
 
-	t.Run("returns 400 for missing ID", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/verify/", nil)
-		req.SetPathValue("id", "")
-		rec := httptest.NewRecorder()
+	t.Run("html", func(t *testing.T) {
+		rec := getReport("test-job-id", "report.html")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, "verdict-ai") || !strings.Contains(body, "<mark>delve into</mark>") {
+			t.Errorf("expected verdict banner and phrase, got:\n%s", body)
+		}
+	})
 
-		h.GetResult(rec, req)
+	t.Run("pending job", func(t *testing.T) {
+		rec := getReport("pending-id", "report.html")
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d", rec.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Code != ErrCodeJobNotCompleted {
+			t.Errorf("expected code %s, got %s", ErrCodeJobNotCompleted, response.Code)
+		}
+	})
 
-		if rec.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", rec.Code)
+	t.Run("missing job and unknown format", func(t *testing.T) {
+		if rec := getReport("non-existent", "report.json"); rec.Code != http.StatusNotFound {
+			t.Errorf("missing job: expected status 404, got %d", rec.Code)
+		}
+		if rec := getReport("test-job-id", "report.pdf"); rec.Code != http.StatusNotFound {
+			t.Errorf("unknown format: expected status 404, got %d", rec.Code)
 		}
 	})
 }
 
-// TestHealth tests the health check endpoint.
+// TestHealth tests the liveness, readiness, and legacy health endpoints.
 func TestHealth(t *testing.T) {
-	h := newTestHandler()
+	tests := []struct {
+		name       string
+		pingErr    error
+		providers  bool
+		wantStatus int
+		wantBody   string
+		wantReason string
+	}{
+		{"ready", nil, true, http.StatusOK, "ready", ""},
+		{"database down", errors.New("connection refused"), true, http.StatusServiceUnavailable, "not_ready", "database unreachable"},
+		{"no providers", nil, false, http.StatusServiceUnavailable, "not_ready", "no detection API key configured"},
+	}
 
-	req := httptest.NewRequest("GET", "/health", nil)
-	rec := httptest.NewRecorder()
+	for _, tt := range tests {
+		repo := newMockRepository()
+		repo.pingErr = tt.pingErr
+		h := New(Config{
+			Detector:            &mockDetector{},
+			Repository:          repo,
+			Logger:              logger.NopLogger(),
+			MaxUploadSize:       10 * 1024 * 1024,
+			ProvidersConfigured: tt.providers,
+		})
+
+		probes := []struct {
+			path    string
+			handler http.HandlerFunc
+		}{
+			{"/health/ready", h.Ready},
+			{"/health", h.Health},
+		}
+		for _, probe := range probes {
+			t.Run(tt.name+" "+probe.path, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				probe.handler(rec, httptest.NewRequest("GET", probe.path, nil))
+
+				if rec.Code != tt.wantStatus {
+					t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+				}
+				var response map[string]any
+				if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if response["status"] != tt.wantBody {
+					t.Errorf("expected status %q, got %v", tt.wantBody, response["status"])
+				}
+				if tt.wantReason != "" && response["reason"] != tt.wantReason {
+					t.Errorf("expected reason %q, got %v", tt.wantReason, response["reason"])
+				}
+			})
+		}
+
+		// Liveness never depends on dependencies
+		t.Run(tt.name+" /health/live", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.Live(rec, httptest.NewRequest("GET", "/health/live", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != `{"status":"alive"}` {
+				t.Errorf("unexpected body %s", body)
+			}
+		})
+	}
+}
+
+// TestMetrics tests scraping pipeline metrics after a few verifications.
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	detector, err := service.NewDetector(service.DetectorConfig{Timeout: 5 * time.Second, Metrics: reg}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector failed: %v", err)
+	}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+		Metrics:       reg,
+	})
 
-	h.Health(rec, req)
+	jpegData := append([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'}, make([]byte, 2000)...)
+	bodies := []string{
+		`{"text": "We went to the lake on Saturday and the dog would not stop barking at the ducks."}`,
+		`{"text": "It is important to note that there are several key considerations to keep in mind."}`,
+		`{"base64_data": "` + base64.StdEncoding.EncodeToString(jpegData) + `"}`,
+	}
+	for _, body := range bodies {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("verify: expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
 
+	rec := httptest.NewRecorder()
+	h.Metrics(rec, httptest.NewRequest("GET", "/metrics", nil))
 	if rec.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", rec.Code)
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
 	}
 
-	var response map[string]any
-	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	out := rec.Body.String()
+	for _, series := range []string{
+		`humanmark_detect_duration_seconds_count{content_type="text"} 2`,
+		`humanmark_detect_duration_seconds_count{content_type="image"} 1`,
+		`humanmark_analyzer_duration_seconds_count{content_type="text"} 2`,
+		`humanmark_ai_score_count{content_type="image"} 1`,
+		`# TYPE humanmark_provider_duration_seconds histogram`,
+		`# TYPE humanmark_verdicts_total counter`,
+	} {
+		if !strings.Contains(out, series) {
+			t.Errorf("missing %s in:\n%s", series, out)
+		}
 	}
 
-	if response["status"] != "healthy" {
-		t.Errorf("expected status 'healthy', got %v", response["status"])
+	var verdicts int
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, `humanmark_verdicts_total{content_type="text"`) {
+			var n int
+			fmt.Sscanf(line[strings.LastIndex(line, " ")+1:], "%d", &n)
+			verdicts += n
+		}
+	}
+	if verdicts != 2 {
+		t.Errorf("text verdicts = %d, want 2", verdicts)
 	}
+
+	t.Run("disabled", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestHandler().Metrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
 }
 
 // TestIndex tests the index endpoint.
@@ -466,6 +3248,141 @@ func TestValidateInput(t *testing.T) {
 	}
 }
 
+// TestUsage tests metering API key usage, the monthly quota and its reset
+// at the start of the next UTC month.
+func TestUsage(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+		MonthlyQuota:  2,
+	})
+	now := time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	auth := middleware.Auth(repo, false, logger.NopLogger())
+	verify := auth(http.HandlerFunc(h.Verify))
+	usage := auth(http.HandlerFunc(h.Usage))
+
+	secret, hash, err := middleware.NewAPIKeySecret()
+	if err != nil {
+		t.Fatalf("NewAPIKeySecret failed: %v", err)
+	}
+	key, _ := repo.CreateAPIKey(context.Background(), repository.APIKey{Name: "test", SecretHash: hash, Scopes: repository.Scopes})
+	apiKey := middleware.FormatAPIKey(key.ID, secret)
+
+	text := "This is a test text that should be verified as human-written content."
+	doVerify := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader(`{"text": "`+text+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		verify.ServeHTTP(rec, req)
+		return rec
+	}
+	getUsage := func(t *testing.T) UsageResponse {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/usage", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		usage.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response UsageResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("quota exceeded returns 402", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if rec := doVerify(); rec.Code != http.StatusOK {
+				t.Fatalf("request %d: expected status 200, got %d: %s", i+1, rec.Code, rec.Body.String())
+			}
+		}
+
+		rec := doVerify()
+		if rec.Code != http.StatusPaymentRequired {
+			t.Fatalf("expected status 402, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var errResp ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&errResp)
+		if errResp.Code != ErrCodeQuotaExceeded {
+			t.Errorf("expected code %s, got %+v", ErrCodeQuotaExceeded, errResp)
+		}
+	})
+
+	t.Run("usage reports the period", func(t *testing.T) {
+		got := getUsage(t)
+		if got.KeyID != key.ID || got.Requests != 2 || got.Bytes != int64(2*len(text)) || got.ProviderCalls != 2 {
+			t.Errorf("got %+v", got)
+		}
+		if !reflect.DeepEqual(got.ContentTypes, map[string]int64{"text": 2}) {
+			t.Errorf("expected 2 text requests, got %v", got.ContentTypes)
+		}
+		if got.Quota != 2 || got.Remaining == nil || *got.Remaining != 0 {
+			t.Errorf("expected quota 2 with none remaining, got %d, %v", got.Quota, got.Remaining)
+		}
+		if !got.PeriodStart.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) ||
+			!got.PeriodEnd.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected October, got %v to %v", got.PeriodStart, got.PeriodEnd)
+		}
+		if len(got.Days) != 1 || got.Days[0].Date != "2026-10-31" || got.Days[0].Requests != 2 {
+			t.Errorf("expected one day, got %+v", got.Days)
+		}
+	})
+
+	t.Run("quota resets with the month", func(t *testing.T) {
+		now = time.Date(2026, 11, 1, 0, 30, 0, 0, time.UTC)
+		if rec := doVerify(); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		got := getUsage(t)
+		if got.Requests != 1 || got.Remaining == nil || *got.Remaining != 1 {
+			t.Errorf("expected 1 request and 1 remaining, got %+v", got)
+		}
+		if len(got.Days) != 1 || got.Days[0].Date != "2026-11-01" {
+			t.Errorf("expected only November 1, got %+v", got.Days)
+		}
+	})
+
+	t.Run("each uploaded file counts", func(t *testing.T) {
+		files := map[string][]byte{"a.txt": []byte(text), "b.txt": []byte(text + " Again.")}
+		req := multipartUpload(t, "/verify", files)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		verify.ServeHTTP(rec, req)
+		if rec.Code != http.StatusPaymentRequired {
+			t.Fatalf("two files with one verification left: expected status 402, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		delete(files, "b.txt")
+		req = multipartUpload(t, "/verify", files)
+		req.Header.Set("X-API-Key", apiKey)
+		rec = httptest.NewRecorder()
+		verify.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got := getUsage(t); got.Requests != 2 {
+			t.Errorf("expected 2 requests, got %d", got.Requests)
+		}
+	})
+
+	t.Run("anonymous requests get 401", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		usage.ServeHTTP(rec, httptest.NewRequest("GET", "/usage", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", rec.Code)
+		}
+	})
+}
+
 // BenchmarkVerify benchmarks the verify endpoint.
 func BenchmarkVerify(b *testing.B) {
 	h := newTestHandler()
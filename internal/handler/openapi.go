@@ -0,0 +1,512 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/cert"
+	"github.com/humanmark/humanmark/pkg/openapi"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// apiVersion is the API version reported by Index and the OpenAPI spec.
+const apiVersion = "1.0.0"
+
+// OpenAPISpec describes the API as an OpenAPI 3.1 document. Request and
+// response schemas are generated from this package's types; the routes,
+// parameters and status codes are listed here and must be kept in step
+// with cmd/api.
+func OpenAPISpec() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "HumanMark API",
+		Description: "Verify whether content was created by a human or generated by AI.",
+		Version:     apiVersion,
+	})
+	doc.Components.SecuritySchemes["apiKey"] = &openapi.SecurityScheme{
+		Type: "apiKey", In: "header", Name: "X-API-Key",
+		Description: "An API key from POST /admin/keys. Only required when the server has REQUIRE_API_KEY set.",
+	}
+	doc.Components.SecuritySchemes["bearer"] = &openapi.SecurityScheme{
+		Type: "http", Scheme: "bearer",
+		Description: "The same API key, sent as a bearer token.",
+	}
+	doc.Components.SecuritySchemes["adminToken"] = &openapi.SecurityScheme{
+		Type: "http", Scheme: "bearer",
+		Description: "The server's ADMIN_TOKEN.",
+	}
+	doc.Security = []openapi.SecurityRequirement{{"apiKey": {}}, {"bearer": {}}, {}}
+	admin := []openapi.SecurityRequirement{{"adminToken": {}}}
+
+	errorResponse := func(status int, description, code, message string) *openapi.Response {
+		return &openapi.Response{Description: description, Content: map[string]openapi.MediaType{
+			problem.ContentType: {Schema: doc.Schema(ErrorResponse{}), Example: problem.New(status, code, message)},
+		}}
+	}
+	notFound := errorResponse(404, "No result with this ID", "not_found", "Verification result not found")
+	internalError := errorResponse(500, "The server failed", "internal_error", "Failed to retrieve result")
+	unauthorized := errorResponse(401, "The API key is missing, unknown or revoked", "unauthorized", "API key required")
+	forbidden := errorResponse(403, "The client or its API key is denied", "access_denied", "access denied")
+	rateLimited := errorResponse(429, "The client's rate limit is used up; see Retry-After", "rate_limited", "rate limit exceeded")
+
+	// Completed results can be revalidated with their ETag
+	ifNoneMatch := openapi.Parameter{Name: "If-None-Match", In: "header", Schema: openapi.String(""), Description: "ETag of a copy already held"}
+	notModified := &openapi.Response{Description: "The result with this ETag hasn't changed"}
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	result := VerifyResponse{
+		ID:          "8f14e45f-ceea-467e-a7c9-4b1d2c3f5a6b",
+		Status:      "completed",
+		Human:       true,
+		Verdict:     "human",
+		Confidence:  0.6,
+		ContentType: "text",
+		Basis:       "local-only",
+		CreatedAt:   createdAt,
+	}
+	detailed := result
+	detailed.Details = &VerifyDetails{
+		Detectors: []string{"humanmark"},
+		AIScore:   0.13,
+		Signals:   []DetectorSignal{{Name: "humanmark", AIScore: 0.13, Confidence: 0.87}},
+	}
+
+	doc.Add("GET", "/", &openapi.Operation{
+		OperationID: "index",
+		Summary:     "API information and endpoints",
+		Tags:        []string{"meta"},
+		Security:    openapi.Anonymous,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "API name, version and endpoints", Content: map[string]openapi.MediaType{
+				"application/json": {Schema: &openapi.Schema{Type: openapi.Types{"object"}}},
+			}},
+		},
+	})
+	doc.Add("GET", "/openapi.json", &openapi.Operation{
+		OperationID: "openapi",
+		Summary:     "This OpenAPI document",
+		Tags:        []string{"meta"},
+		Security:    openapi.Anonymous,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "The OpenAPI 3.1 document", Content: map[string]openapi.MediaType{
+				"application/json": {Schema: &openapi.Schema{Type: openapi.Types{"object"}}},
+			}},
+		},
+	})
+
+	verifyMultipart := openapi.Object(map[string]*openapi.Schema{
+		"file":              openapi.Binary("A file to verify"),
+		"files[]":           openapi.ArrayOf(openapi.Binary("")),
+		"files":             openapi.ArrayOf(openapi.Binary("")),
+		"callback_url":      openapi.String("Receives a signed POST when the job finishes (one file only)"),
+		"detectors":         openapi.String("Comma-separated detectors to run"),
+		"exclude_detectors": openapi.String("Comma-separated detectors not to run"),
+	})
+	verifyMultipart.Description = "One file, or up to MAX_UPLOAD_FILES in file, files[] or files (repeated); several files get a 207 response."
+	doc.Add("POST", "/verify", &openapi.Operation{
+		OperationID: "verify",
+		Summary:     "Verify content",
+		Description: "Verifies text, a URL, base64 content or uploaded files. Large inputs, async=true and callback_url queue a job and return 202.",
+		Tags:        []string{"verify"},
+		Parameters: []openapi.Parameter{
+			openapi.Query("detailed", openapi.Boolean(""), "Include the detection breakdown"),
+			openapi.Query("reuse", openapi.Boolean(""), "false runs detection even if the content was verified before"),
+			openapi.Query("async", openapi.Boolean(""), "Queue the job and return 202; poll GET /verify/{id}"),
+			openapi.Query("email", openapi.Boolean(""), "Analyze text as an email"),
+			openapi.Query("academic", openapi.Boolean(""), "Analyze text as academic writing"),
+			openapi.Query("mode", openapi.Enum("", "page"), "page verifies the HTML page at url and its images and videos"),
+			openapi.Query("profile", openapi.String(""), "A configured analyzer profile whose options apply where the request sets none"),
+		},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"application/json": {
+					Schema:  doc.Schema(VerifyRequest{}),
+					Example: VerifyRequest{Text: "I wrote this on the train home, tired but happy that the garden is finally planted."},
+				},
+				"multipart/form-data": {Schema: verifyMultipart},
+				"application/x-www-form-urlencoded": {Schema: openapi.Object(map[string]*openapi.Schema{
+					"text":              openapi.String("Text to verify"),
+					"url":               openapi.String("URL of content to verify"),
+					"callback_url":      openapi.String("Receives a signed POST when the job finishes"),
+					"detectors":         openapi.String("Comma-separated detectors to run"),
+					"exclude_detectors": openapi.String("Comma-separated detectors not to run"),
+				})},
+				"text/plain": {Schema: openapi.String("Text to verify")},
+			},
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "The verdict (with mode=page, a PageVerifyResponse)", Content: map[string]openapi.MediaType{
+				"application/json": {Schema: openapi.AnyOf(doc.Schema(PageVerifyResponse{}), doc.Schema(VerifyResponse{})), Example: detailed},
+			}},
+			"202": doc.JSONResponse("The job was queued", JobStatusResponse{
+				ID: result.ID, Status: "pending", CreatedAt: createdAt, UpdatedAt: createdAt,
+			}),
+			"207": doc.JSONResponse("Each uploaded file's verdict or error", FilesVerifyResponse{}),
+			"400": errorResponse(400, "The input is missing or invalid", "validation_error", "text too short: minimum 10 characters"),
+			"401": unauthorized,
+			"402": errorResponse(402, "The API key's monthly quota is used up", ErrCodeQuotaExceeded, "API key has used its 1000 verifications for this month (resets 2024-06-01T00:00:00Z)"),
+			"403": errorResponse(403, "The API key lacks the scope for the content, or the client is denied", ErrCodeInsufficientScope, "API key lacks the verify:media scope"),
+			"413": errorResponse(413, "The content is too large", ErrCodeContentTooLarge, "content too large"),
+			"415": errorResponse(415, "The body's Content-Type isn't one POST /verify reads", ErrCodeUnsupportedMediaType, `unsupported Content-Type "application/xml": use application/json, multipart/form-data, application/x-www-form-urlencoded, text/plain`),
+			"422": errorResponse(422, "The content can't be analyzed", ErrCodeUnsupportedContentType, "unsupported content type"),
+			"429": rateLimited,
+			"500": internalError,
+			"502": errorResponse(502, "The URL couldn't be fetched", ErrCodeFetchFailed, "failed to fetch URL"),
+			"503": errorResponse(503, "The server or the detection providers are busy or down", ErrCodeOverloaded, "server is busy, retry later"),
+			"504": errorResponse(504, "Detection took too long", ErrCodeRequestTimeout, "request timed out"),
+		},
+	})
+	doc.Add("GET", "/verify", &openapi.Operation{
+		OperationID: "listResults",
+		Summary:     "List verification results, newest first",
+		Description: "Only the caller's results are listed: those submitted with its API key, or without one for requests without a key.",
+		Tags:        []string{"verify"},
+		Parameters: []openapi.Parameter{
+			openapi.Query("limit", openapi.Integer("", 1, 100), "Page size (default 20)"),
+			openapi.Query("cursor", openapi.String(""), "next_cursor from the previous page"),
+			openapi.Query("content_type", openapi.String(""), "Only results of this content type"),
+			openapi.Query("human", openapi.Boolean(""), "Only results with this verdict"),
+			openapi.Query("verdict", openapi.Enum("", "human", "ai", "uncertain"), "Only results with this verdict"),
+			openapi.Query("content_hash", openapi.String(""), "Only results for this SHA-256 content hash"),
+			openapi.Query("created_after", &openapi.Schema{Type: openapi.Types{"string"}, Format: "date-time"}, ""),
+			openapi.Query("created_before", &openapi.Schema{Type: openapi.Types{"string"}, Format: "date-time"}, ""),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("A page of results", ListResponse{Items: []VerifyResponse{result}}),
+			"400": errorResponse(400, "A parameter or the cursor is invalid", ErrCodeInvalidParameter, "limit must be a positive integer"),
+			"401": unauthorized,
+			"403": forbidden,
+			"429": rateLimited,
+			"500": internalError,
+		},
+	})
+	doc.Add("GET", "/verify/export", &openapi.Operation{
+		OperationID: "exportResults",
+		Summary:     "Export completed verification results, newest first",
+		Description: "Streams the caller's results as an attachment: those submitted with its API key, or without one for requests without a key. API keys only export the content types their scopes allow verifying.",
+		Tags:        []string{"verify"},
+		Parameters: []openapi.Parameter{
+			openapi.Query("format", openapi.Enum("", "csv", "jsonl"), "csv (the default) or jsonl, whose lines include the detection breakdown"),
+			openapi.Query("from", &openapi.Schema{Type: openapi.Types{"string"}, Format: "date-time"}, "Start of the window (default: 30 days before to)"),
+			openapi.Query("to", &openapi.Schema{Type: openapi.Types{"string"}, Format: "date-time"}, "End of the window, at most 366 days after from (default: now)"),
+			openapi.Query("content_type", openapi.String(""), "Only results of this content type"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "The results", Content: map[string]openapi.MediaType{
+				"text/csv":          {Schema: openapi.String("A header row, then one row per result")},
+				"application/jsonl": {Schema: doc.Schema(ExportRecord{})},
+			}},
+			"400": errorResponse(400, "A parameter is invalid or the window is too long", ErrCodeInvalidParameter, "from and to must be at most 366 days apart"),
+			"401": unauthorized,
+			"403": errorResponse(403, "The API key lacks the scope for content_type, or the client is denied", ErrCodeInsufficientScope, "API key lacks the scope to export video results"),
+			"429": rateLimited,
+			"500": internalError,
+		},
+	})
+	doc.Add("GET", "/verify/{id}", &openapi.Operation{
+		OperationID: "getResult",
+		Summary:     "Get a verification result",
+		Description: "Jobs that haven't completed return their status: 202 while pending or processing, 200 once failed. Completed results carry an ETag to revalidate with.",
+		Tags:        []string{"verify"},
+		Parameters: []openapi.Parameter{
+			openapi.Query("detailed", openapi.Boolean(""), "Include the stored detection breakdown"),
+			ifNoneMatch,
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "The result, or the status of a failed job", Content: map[string]openapi.MediaType{
+				"application/json": {Schema: openapi.AnyOf(doc.Schema(VerifyResponse{}), doc.Schema(JobStatusResponse{})), Example: result},
+			}},
+			"202": doc.JSONResponse("The job hasn't finished", JobStatusResponse{}),
+			"304": notModified,
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+			"429": rateLimited,
+			"500": internalError,
+		},
+	})
+	doc.Add("GET", "/verify/hash/{sha256}", &openapi.Operation{
+		OperationID: "getResultByHash",
+		Summary:     "Look up a verification result by content hash",
+		Description: "Returns the newest completed result for content with the SHA-256 hash, without sending the content or running detection.",
+		Tags:        []string{"verify"},
+		Parameters: []openapi.Parameter{
+			{Name: "sha256", In: "path", Required: true, Schema: openapi.String(""), Description: "SHA-256 of the content as 64 hex digits"},
+			openapi.Query("normalized", openapi.Boolean(""), "The hash is of the text's words joined by single spaces, matching copies that differ only in whitespace"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "The result", Content: map[string]openapi.MediaType{
+				"application/json": {Schema: doc.Schema(VerifyResponse{}), Example: result},
+			}},
+			"400": errorResponse(400, "The hash isn't 64 hex digits", ErrCodeInvalidHash, "hash must be a SHA-256 as 64 hex digits"),
+			"401": unauthorized,
+			"403": forbidden,
+			"404": errorResponse(404, "No completed result has the hash", ErrCodeHashNotFound, "No verification result for this hash"),
+			"429": rateLimited,
+			"500": internalError,
+		},
+	})
+	doc.Add("DELETE", "/verify/{id}", &openapi.Operation{
+		OperationID: "deleteResult",
+		Summary:     "Delete a verification result",
+		Description: "Only the API key that submitted a result can delete it; for other callers it doesn't exist.",
+		Tags:        []string{"verify"},
+		Responses: map[string]*openapi.Response{
+			"204": {Description: "Deleted"},
+			"401": unauthorized,
+			"403": forbidden,
+			"404": notFound,
+			"429": rateLimited,
+			"500": internalError,
+		},
+	})
+	for _, report := range []struct {
+		format, mediaType string
+		schema            *openapi.Schema
+	}{
+		{"json", "application/json", &openapi.Schema{Type: openapi.Types{"object"}, Description: "The full detection result"}},
+		{"csv", "text/csv", openapi.String("A header row and one result row")},
+		{"html", "text/html", openapi.String("A self-contained page")},
+	} {
+		doc.Add("GET", "/verify/{id}/report."+report.format, &openapi.Operation{
+			OperationID: "getReport" + report.format,
+			Summary:     "Download a verification result as a " + report.format + " report",
+			Tags:        []string{"verify"},
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "The report", Content: map[string]openapi.MediaType{
+					report.mediaType: {Schema: report.schema},
+				}},
+				"401": unauthorized,
+				"404": notFound,
+				"409": errorResponse(409, "The job hasn't completed", ErrCodeJobNotCompleted, "Verification has not completed"),
+				"500": internalError,
+			},
+		})
+	}
+	doc.Add("GET", "/jobs/{id}/proof", &openapi.Operation{
+		OperationID: "getProof",
+		Summary:     "Blockchain anchor of a result's content hash",
+		Tags:        []string{"verify"},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("The anchor", ProofResponse{}),
+			"401": unauthorized,
+			"404": errorResponse(404, "No result with this ID, or its hash wasn't anchored", ErrCodeNotAnchored, "Content hash was not anchored"),
+			"500": internalError,
+		},
+	})
+	doc.Add("GET", "/verify/{id}/certificate", &openapi.Operation{
+		OperationID: "getCertificate",
+		Summary:     "Signed certificate of a verification result",
+		Description: "An Ed25519-signed JWS that third parties can check offline against /.well-known/humanmark.json.",
+		Tags:        []string{"verify"},
+		Parameters: []openapi.Parameter{
+			openapi.Query("format", openapi.Enum("", "json", "svg"), "svg returns a badge with the certificate in its metadata"),
+			ifNoneMatch,
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "The certificate", Content: map[string]openapi.MediaType{
+				"application/json": {Schema: doc.Schema(CertificateResponse{})},
+				"image/svg+xml":    {Schema: openapi.String("A badge showing the verdict")},
+			}},
+			"304": {Description: "The certificate with this ETag is still current"},
+			"400": errorResponse(400, "The format is unknown", ErrCodeInvalidParameter, "format must be json or svg"),
+			"401": unauthorized,
+			"404": notFound,
+			"409": errorResponse(409, "The job hasn't completed", ErrCodeJobNotCompleted, "Verification has not completed"),
+			"500": internalError,
+		},
+	})
+	doc.Add("GET", "/.well-known/humanmark.json", &openapi.Operation{
+		OperationID: "wellKnown",
+		Summary:     "Public keys certificates are signed with",
+		Tags:        []string{"meta"},
+		Security:    openapi.Anonymous,
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("The current key first, then retired keys whose certificates are still valid", cert.KeySet{}),
+			"404": errorResponse(404, "Certificates are disabled", "not_found", "Certificates are not enabled"),
+		},
+	})
+	doc.Add("GET", "/usage", &openapi.Operation{
+		OperationID: "getUsage",
+		Summary:     "The calling API key's usage and remaining quota this month",
+		Tags:        []string{"usage"},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("This month's usage", UsageResponse{}),
+			"401": unauthorized,
+			"500": internalError,
+		},
+	})
+
+	health := openapi.Object(map[string]*openapi.Schema{
+		"status":    openapi.Enum("", "ready", "not_ready", "alive"),
+		"reason":    openapi.String("Why the server isn't ready"),
+		"timestamp": {Type: openapi.Types{"string"}, Format: "date-time"},
+	}, "status")
+	healthResponses := func(live bool) map[string]*openapi.Response {
+		responses := map[string]*openapi.Response{
+			"200": {Description: "Serving", Content: map[string]openapi.MediaType{"application/json": {Schema: health}}},
+		}
+		if !live {
+			responses["503"] = &openapi.Response{Description: "Not ready: the database is unreachable or no provider is configured",
+				Content: map[string]openapi.MediaType{"application/json": {Schema: health}}}
+		}
+		return responses
+	}
+	doc.Add("GET", "/health", &openapi.Operation{
+		OperationID: "health", Summary: "Health check (same as /health/ready)",
+		Tags: []string{"health"}, Security: openapi.Anonymous, Responses: healthResponses(false),
+	})
+	doc.Add("GET", "/health/live", &openapi.Operation{
+		OperationID: "live", Summary: "Liveness probe",
+		Tags: []string{"health"}, Security: openapi.Anonymous, Responses: healthResponses(true),
+	})
+	doc.Add("GET", "/health/ready", &openapi.Operation{
+		OperationID: "ready", Summary: "Readiness probe",
+		Tags: []string{"health"}, Security: openapi.Anonymous, Responses: healthResponses(false),
+	})
+	doc.Add("GET", "/metrics", &openapi.Operation{
+		OperationID: "metrics",
+		Summary:     "Prometheus metrics",
+		Tags:        []string{"health"},
+		Security:    openapi.Anonymous,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Metrics in the Prometheus text format", Content: map[string]openapi.MediaType{
+				"text/plain": {Schema: openapi.String("")},
+			}},
+			"404": errorResponse(404, "Metrics are disabled", "not_found", "Metrics are not enabled"),
+		},
+	})
+
+	adminUnauthorized := errorResponse(401, "The admin token is missing or wrong", "unauthorized", "admin token required")
+	doc.Add("POST", "/admin/keys", &openapi.Operation{
+		OperationID: "createAPIKey",
+		Summary:     "Create an API key",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		RequestBody: doc.JSONBody(CreateAPIKeyRequest{Name: "Newsroom CMS", Scopes: []string{"verify:text"}}),
+		Responses: map[string]*openapi.Response{
+			"201": doc.JSONResponse("The key; its secret is only shown now", APIKeyResponse{}),
+			"400": errorResponse(400, "The request is invalid", "validation_error", "name is required"),
+			"401": adminUnauthorized,
+			"500": errorResponse(500, "The server failed", "internal_error", "Failed to create API key"),
+		},
+	})
+	doc.Add("GET", "/admin/keys", &openapi.Operation{
+		OperationID: "listAPIKeys",
+		Summary:     "List API keys",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("The keys", APIKeyListResponse{}),
+			"401": adminUnauthorized,
+			"500": errorResponse(500, "The server failed", "internal_error", "Failed to list API keys"),
+		},
+	})
+	doc.Add("DELETE", "/admin/keys/{id}", &openapi.Operation{
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke an API key",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("The revoked key", APIKeyResponse{}),
+			"401": adminUnauthorized,
+			"404": errorResponse(404, "No key with this ID", "not_found", "API key not found"),
+			"500": errorResponse(500, "The server failed", "internal_error", "Failed to revoke API key"),
+		},
+	})
+	doc.Add("GET", "/admin/stats", &openapi.Operation{
+		OperationID: "getStats",
+		Summary:     "Verification statistics for a dashboard",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Parameters: []openapi.Parameter{
+			openapi.Query("from", openapi.String(""), "RFC 3339 time or YYYY-MM-DD (default: 30 days before to)"),
+			openapi.Query("to", openapi.String(""), "RFC 3339 time or YYYY-MM-DD (default: now)"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("Aggregates over the range", StatsResponse{}),
+			"400": errorResponse(400, "The range is invalid", ErrCodeInvalidParameter, "from must be before to"),
+			"401": adminUnauthorized,
+			"500": errorResponse(500, "The server failed", "internal_error", "Failed to get stats"),
+		},
+	})
+	doc.Add("POST", "/admin/reload", &openapi.Operation{
+		OperationID: "reloadConfig",
+		Summary:     "Reload the detection configuration files",
+		Description: "Re-reads the AI phrase dictionary and host reputation files, as SIGHUP does. " +
+			"Detections already running finish with the configuration they started with.",
+		Tags:     []string{"admin"},
+		Security: admin,
+		Responses: map[string]*openapi.Response{
+			"200": doc.JSONResponse("The configuration now in force", ReloadResponse{}),
+			"401": adminUnauthorized,
+			"404": errorResponse(404, "Reloading is disabled", "not_found", "Reloading is not enabled"),
+			"500": errorResponse(500, "A file couldn't be read; the current configuration stays in force",
+				ErrCodeReloadFailed, "Failed to reload: parse AI phrases: unexpected end of JSON input"),
+		},
+	})
+
+	return doc
+}
+
+// openAPIJSON is OpenAPISpec encoded, built on first use.
+var openAPIJSON = sync.OnceValue(func() []byte {
+	data, err := json.Marshal(OpenAPISpec())
+	if err != nil {
+		panic("openapi: " + err.Error())
+	}
+	return data
+})
+
+// OpenAPI handles GET /openapi.json requests.
+// Returns the OpenAPI 3.1 document describing this API.
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(openAPIJSON())
+}
+
+// swaggerUIVersion is the swagger-ui-dist release the docs page loads.
+const swaggerUIVersion = "5.17.14"
+
+// docsScript starts Swagger UI on the docs page.
+const docsScript = `SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui", deepLinking: true});`
+
+// docsPage is the docs page: Swagger UI, loaded from a CDN, reading
+// /openapi.json.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>HumanMark API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+<script>` + docsScript + `</script>
+</body>
+</html>
+`
+
+// docsContentSecurityPolicy lets the docs page load Swagger UI and run
+// its own inline script, and nothing else.
+var docsContentSecurityPolicy = func() string {
+	sum := sha256.Sum256([]byte(docsScript))
+	return "default-src 'none'; connect-src 'self'; img-src 'self' data:; " +
+		"style-src https://unpkg.com 'unsafe-inline'; " +
+		"script-src https://unpkg.com 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'; " +
+		"frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+}()
+
+// Docs handles GET /docs requests with an interactive Swagger UI page for
+// /openapi.json. It's only served in development.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsContentSecurityPolicy)
+	w.Write([]byte(docsPage))
+}
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/cert"
+	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/openapi"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// servedSpec fetches GET /openapi.json and decodes it.
+func servedSpec(t *testing.T) *openapi.Document {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	newTestHandler().OpenAPI(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode spec: %v", err)
+	}
+	return &doc
+}
+
+// TestOpenAPI checks that the served spec describes the API's routes and
+// types.
+func TestOpenAPI(t *testing.T) {
+	doc := servedSpec(t)
+
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("expected openapi %s, got %q", openapi.Version, doc.OpenAPI)
+	}
+	for _, route := range []string{
+		"POST /verify", "GET /verify", "GET /verify/export", "GET /verify/{id}", "GET /verify/hash/{sha256}", "DELETE /verify/{id}",
+		"GET /verify/{id}/report.json", "GET /verify/{id}/certificate", "GET /jobs/{id}/proof", "GET /usage",
+		"GET /.well-known/humanmark.json",
+		"GET /health", "GET /health/live", "GET /health/ready", "GET /openapi.json",
+		"POST /admin/keys", "GET /admin/keys", "DELETE /admin/keys/{id}", "GET /admin/stats",
+		"POST /admin/reload",
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		op := doc.Operation(method, path)
+		if op == nil {
+			t.Errorf("%s: missing", route)
+			continue
+		}
+		if len(op.Responses) == 0 {
+			t.Errorf("%s: no responses", route)
+		}
+		if strings.Contains(path, "{id}") && (len(op.Parameters) == 0 || op.Parameters[len(op.Parameters)-1].In != "path") {
+			t.Errorf("%s: missing id path parameter", route)
+		}
+	}
+
+	// The detailed-mode and error schemas come from the handler types
+	details := doc.Components.Schemas["VerifyResponse"].Properties["details"]
+	if details == nil || details.Ref != "#/components/schemas/VerifyDetails" {
+		t.Errorf("expected details to reference VerifyDetails, got %+v", details)
+	}
+	for _, name := range []string{"VerifyDetails", "DetectorSignal", "Problem", "JobStatusResponse", "FilesVerifyResponse"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("missing schema %s", name)
+		}
+	}
+	if required := doc.Components.Schemas["Problem"].Required; !reflect.DeepEqual(required, []string{"type", "title", "status"}) {
+		t.Errorf("Problem: expected type, title and status required, got %v", required)
+	}
+	if _, ok := doc.Operation("GET", "/verify/{id}").Responses["404"].Content[problem.ContentType]; !ok {
+		t.Errorf("expected errors as %s", problem.ContentType)
+	}
+	if content := doc.Operation("POST", "/verify").RequestBody.Content; content["multipart/form-data"].Schema == nil {
+		t.Error("POST /verify: missing multipart upload schema")
+	}
+}
+
+// TestOpenAPIExamples checks every example in the spec against its own
+// schema and sends the request examples to the handlers, checking that
+// each response's status is documented and its body matches the
+// documented schema.
+func TestOpenAPIExamples(t *testing.T) {
+	doc := servedSpec(t)
+
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if op.RequestBody != nil {
+				for mediaType, content := range op.RequestBody.Content {
+					checkExample(t, doc, method+" "+path+" request "+mediaType, content)
+				}
+			}
+			for status, response := range op.Responses {
+				for mediaType, content := range response.Content {
+					checkExample(t, doc, method+" "+path+" "+status+" "+mediaType, content)
+				}
+			}
+		}
+	}
+
+	signingKey, err := cert.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	repo := repository.NewMemory()
+	h := New(Config{
+		Detector:            &mockDetector{},
+		Repository:          repo,
+		Logger:              logger.NopLogger(),
+		MaxUploadSize:       10 * 1024 * 1024,
+		ProvidersConfigured: true,
+		Certificates:        cert.NewSigner("HumanMark", signingKey),
+		Reload:              func() error { return nil },
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /verify", h.Verify)
+	mux.HandleFunc("GET /verify", h.ListResults)
+	mux.HandleFunc("GET /verify/{id}", h.GetResult)
+	mux.HandleFunc("GET /verify/{kind}/{sha256}", h.GetResultByHash)
+	mux.HandleFunc("DELETE /verify/{id}", h.DeleteResult)
+	mux.HandleFunc("GET /verify/{id}/report.json", h.GetReport)
+	mux.HandleFunc("GET /verify/{id}/certificate", h.GetCertificate)
+	mux.HandleFunc("GET /.well-known/humanmark.json", h.WellKnown)
+	mux.HandleFunc("GET /usage", h.Usage)
+	mux.HandleFunc("GET /health", h.Health)
+	mux.HandleFunc("GET /health/live", h.Live)
+	mux.HandleFunc("GET /health/ready", h.Ready)
+	mux.HandleFunc("GET /openapi.json", h.OpenAPI)
+	mux.HandleFunc("GET /", h.Index)
+	mux.HandleFunc("POST /admin/keys", h.CreateAPIKey)
+	mux.HandleFunc("GET /admin/keys", h.ListAPIKeys)
+	mux.HandleFunc("DELETE /admin/keys/{id}", h.RevokeAPIKey)
+	mux.HandleFunc("GET /admin/stats", h.GetStats)
+	mux.HandleFunc("POST /admin/reload", h.ReloadConfig)
+
+	// The mock detector records no hashes, so store a hashed result
+	hashed, err := repo.CreateJob(context.Background(), repository.Job{
+		Status:         repository.JobStatusCompleted,
+		ContentType:    "text",
+		Verdict:        "human",
+		Human:          true,
+		Confidence:     0.9,
+		ContentHash:    strings.Repeat("a", 64),
+		NormalizedHash: strings.Repeat("b", 64),
+	})
+	if err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+
+	var key *repository.APIKey
+	call := func(method, target, pattern string, body []byte) []byte {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		if key != nil {
+			req = req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		path := strings.TrimPrefix(pattern, method+" ")
+		response := doc.Operation(method, path).Responses[strconv.Itoa(rec.Code)]
+		if response == nil {
+			t.Errorf("%s %s: status %d isn't documented: %s", method, target, rec.Code, rec.Body.String())
+			return rec.Body.Bytes()
+		}
+		mediaType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		content, ok := response.Content[mediaType]
+		if !ok && len(response.Content) > 0 {
+			t.Errorf("%s %s: %d response is %s, which isn't documented", method, target, rec.Code, mediaType)
+		}
+		if ok && strings.HasSuffix(mediaType, "json") {
+			if errs := doc.Validate(content.Schema, rec.Body.Bytes()); len(errs) > 0 {
+				t.Errorf("%s %s: %d response violates the spec: %v\n%s", method, target, rec.Code, errs, rec.Body.String())
+			}
+		}
+		return rec.Body.Bytes()
+	}
+	example := func(method, path string) []byte {
+		t.Helper()
+		data, err := json.Marshal(doc.Operation(method, path).RequestBody.Content["application/json"].Example)
+		if err != nil {
+			t.Fatalf("failed to encode example: %v", err)
+		}
+		return data
+	}
+
+	created := call("POST", "/admin/keys", "POST /admin/keys", example("POST", "/admin/keys"))
+	var apiKey APIKeyResponse
+	if err := json.Unmarshal(created, &apiKey); err != nil || apiKey.ID == "" {
+		t.Fatalf("failed to create API key: %v %s", err, created)
+	}
+	key = &repository.APIKey{ID: apiKey.ID, Scopes: apiKey.Scopes}
+
+	verified := call("POST", "/verify", "POST /verify", example("POST", "/verify"))
+	detailed := call("POST", "/verify?detailed=true&reuse=false", "POST /verify", example("POST", "/verify"))
+	for _, body := range [][]byte{verified, detailed} {
+		var response VerifyResponse
+		if err := json.Unmarshal(body, &response); err != nil || response.ID == "" {
+			t.Fatalf("POST /verify example failed: %v %s", err, body)
+		}
+	}
+	var response VerifyResponse
+	json.Unmarshal(detailed, &response)
+	if response.Details == nil || len(response.Details.Detectors) == 0 {
+		t.Errorf("expected detailed response, got %s", detailed)
+	}
+	id := response.ID
+
+	call("POST", "/verify", "POST /verify", []byte(`{"text": "short"}`))
+	call("GET", "/verify", "GET /verify", nil)
+	call("GET", "/verify?limit=0", "GET /verify", nil)
+	call("GET", "/verify/"+id+"?detailed=true", "GET /verify/{id}", nil)
+	call("GET", "/verify/"+id+"/report.json", "GET /verify/{id}/report.json", nil)
+	call("GET", "/verify/hash/"+hashed.ContentHash, "GET /verify/hash/{sha256}", nil)
+	call("GET", "/verify/hash/"+hashed.NormalizedHash+"?normalized=true", "GET /verify/hash/{sha256}", nil)
+	call("GET", "/verify/hash/"+strings.Repeat("0", 64), "GET /verify/hash/{sha256}", nil)
+	call("GET", "/verify/hash/xyz", "GET /verify/hash/{sha256}", nil)
+	call("GET", "/verify/"+id+"/certificate", "GET /verify/{id}/certificate", nil)
+	call("GET", "/verify/"+id+"/certificate?format=gif", "GET /verify/{id}/certificate", nil)
+	call("GET", "/.well-known/humanmark.json", "GET /.well-known/humanmark.json", nil)
+	call("GET", "/usage", "GET /usage", nil)
+	call("DELETE", "/verify/"+id, "DELETE /verify/{id}", nil)
+	call("GET", "/verify/"+id, "GET /verify/{id}", nil)
+	call("GET", "/health", "GET /health", nil)
+	call("GET", "/health/live", "GET /health/live", nil)
+	call("GET", "/health/ready", "GET /health/ready", nil)
+	call("GET", "/openapi.json", "GET /openapi.json", nil)
+	call("GET", "/", "GET /", nil)
+	call("GET", "/admin/keys", "GET /admin/keys", nil)
+	call("GET", "/admin/stats", "GET /admin/stats", nil)
+	call("POST", "/admin/reload", "POST /admin/reload", nil)
+	call("DELETE", "/admin/keys/"+apiKey.ID, "DELETE /admin/keys/{id}", nil)
+	call("DELETE", "/admin/keys/unknown", "DELETE /admin/keys/{id}", nil)
+}
+
+// checkExample checks that a JSON example matches its schema.
+func checkExample(t *testing.T, doc *openapi.Document, name string, content openapi.MediaType) {
+	t.Helper()
+	if content.Example == nil || !strings.HasSuffix(name, "json") {
+		return
+	}
+	data, err := json.Marshal(content.Example)
+	if err != nil {
+		t.Errorf("%s: failed to encode example: %v", name, err)
+		return
+	}
+	if errs := doc.Validate(content.Schema, data); len(errs) > 0 {
+		t.Errorf("%s: example violates its schema: %v", name, errs)
+	}
+}
+
+// TestDocs checks the development docs page.
+func TestDocs(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestHandler().Docs(rec, httptest.NewRequest("GET", "/docs", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `url: "/openapi.json"`) {
+		t.Error("expected the page to load /openapi.json")
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "'sha256-") {
+		t.Errorf("expected a CSP allowing the inline script by hash, got %q", csp)
+	}
+}
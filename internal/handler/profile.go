@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/humanmark/humanmark/internal/service"
+)
+
+// ErrCodeUnknownProfile is the error code returned when ?profile= names a
+// profile the server doesn't have.
+const ErrCodeUnknownProfile = "unknown_profile"
+
+// Profile is a named set of analyzer options, configured on the server,
+// that a request picks with ?profile=NAME instead of spelling them out.
+// Options the request sets itself take precedence.
+type Profile struct {
+	// EmailMode and AcademicMode are as ?email=true and ?academic=true
+	EmailMode    bool
+	AcademicMode bool
+
+	// Detectors and ExcludeDetectors are used when the request names none
+	Detectors        []string
+	ExcludeDetectors []string
+
+	// StopPhrases and CommonWords are used when the request gives none
+	StopPhrases []string
+	CommonWords []string
+}
+
+// profile returns the profile r names with ?profile=, or nil if it names
+// none.
+func (h *Handler) profile(r *http.Request) (*Profile, error) {
+	name := r.URL.Query().Get("profile")
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := h.profiles[strings.ToLower(name)]
+	if !ok {
+		if len(h.profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %q (none are configured)", name)
+		}
+		names := make([]string, 0, len(h.profiles))
+		for known := range h.profiles {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q (supported: %s)", name, strings.Join(names, ", "))
+	}
+	return &p, nil
+}
+
+// apply fills in the options input doesn't set from p. A nil profile
+// changes nothing.
+func (p *Profile) apply(input *service.DetectionInput) {
+	if p == nil {
+		return
+	}
+	input.EmailMode = input.EmailMode || p.EmailMode
+	input.AcademicMode = input.AcademicMode || p.AcademicMode
+	if len(input.Detectors) == 0 && len(input.ExcludeDetectors) == 0 {
+		input.Detectors = p.Detectors
+		input.ExcludeDetectors = p.ExcludeDetectors
+	}
+	if len(input.CustomStopPhrases) == 0 {
+		input.CustomStopPhrases = p.StopPhrases
+	}
+	if len(input.CustomCommonWords) == 0 {
+		input.CustomCommonWords = p.CommonWords
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestVerify_Profile verifies that ?profile= fills in the analyzer options
+// a request leaves out.
+func TestVerify_Profile(t *testing.T) {
+	detector := &mockDetector{}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    newMockRepository(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+		Profiles: map[string]Profile{
+			"Legal": {
+				AcademicMode: true,
+				Detectors:    []string{"winston", "humanmark"},
+				CommonWords:  []string{"plaintiff", "tort"},
+			},
+		},
+	})
+	verify := func(target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		return rec
+	}
+	const text = `"text": "The plaintiff argues that the tort claim was filed in time."`
+
+	t.Run("profile options apply", func(t *testing.T) {
+		rec := verify("/verify?profile=legal", "{"+text+"}")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		input := detector.lastInput
+		if !input.AcademicMode || input.EmailMode {
+			t.Errorf("modes = academic %v, email %v", input.AcademicMode, input.EmailMode)
+		}
+		if !slices.Equal(input.Detectors, []string{"winston", "humanmark"}) {
+			t.Errorf("detectors = %v", input.Detectors)
+		}
+		if !slices.Equal(input.CustomCommonWords, []string{"plaintiff", "tort"}) {
+			t.Errorf("common words = %v", input.CustomCommonWords)
+		}
+	})
+
+	t.Run("the request's own options win", func(t *testing.T) {
+		rec := verify("/verify?profile=Legal&email=true",
+			`{`+text+`, "exclude_detectors": ["openai"], "custom_common_words": ["appellant"]}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		input := detector.lastInput
+		if !input.AcademicMode || !input.EmailMode {
+			t.Errorf("modes = academic %v, email %v, want both", input.AcademicMode, input.EmailMode)
+		}
+		if len(input.Detectors) != 0 || !slices.Equal(input.ExcludeDetectors, []string{"openai"}) {
+			t.Errorf("detectors = %v, excluded = %v", input.Detectors, input.ExcludeDetectors)
+		}
+		if !slices.Equal(input.CustomCommonWords, []string{"appellant"}) {
+			t.Errorf("common words = %v", input.CustomCommonWords)
+		}
+	})
+
+	t.Run("unknown profile is rejected", func(t *testing.T) {
+		rec := verify("/verify?profile=medical", "{"+text+"}")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		var response ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.Code != ErrCodeUnknownProfile || !strings.Contains(response.Detail, "legal") {
+			t.Errorf("error = %s: %s", response.Code, response.Detail)
+		}
+	})
+
+	t.Run("unknown profile is rejected for uploads", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.Verify(rec, multipartUpload(t, "/verify?profile=medical", map[string][]byte{
+			"a.txt": []byte("The first of two essays about gardens."),
+			"b.txt": []byte("The second of two essays about gardens."),
+		}))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+}
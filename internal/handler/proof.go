@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+)
+
+// ProofResponse is the blockchain anchor of a job's content hash.
+type ProofResponse struct {
+	// ID is the job ID
+	ID string `json:"id"`
+
+	// ContentHash is the SHA-256 of the analyzed content, as anchored
+	ContentHash string `json:"content_hash"`
+
+	// TxID is the transaction that holds ContentHash
+	TxID string `json:"tx_id"`
+
+	// AnchoredAt is the timestamp recorded with the hash
+	AnchoredAt time.Time `json:"anchored_at"`
+}
+
+// ErrCodeNotAnchored is the error code returned for proof requests on jobs
+// whose content hash wasn't anchored.
+const ErrCodeNotAnchored = "not_anchored"
+
+// GetProof handles GET /jobs/{id}/proof requests.
+// Returns the content hash and the transaction that anchored it.
+func (h *Handler) GetProof(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, http.StatusBadRequest, "missing_id", "Job ID is required")
+		return
+	}
+
+	job, err := h.repository.GetJob(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "Verification result not found")
+			return
+		}
+		h.logger.Error("failed to get job", "error", err, "id", id)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve result")
+		return
+	}
+
+	if job.TxID == "" {
+		h.writeError(w, http.StatusNotFound, ErrCodeNotAnchored, "Result was not anchored")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, ProofResponse{
+		ID:          job.ID,
+		ContentHash: job.ContentHash,
+		TxID:        job.TxID,
+		AnchoredAt:  job.AnchoredAt,
+	})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestGetProof tests the blockchain proof endpoint.
+func TestGetProof(t *testing.T) {
+	repo := newMockRepository()
+	h := New(Config{
+		Detector:      &mockDetector{},
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+
+	anchoredAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.jobs["anchored-id"] = &repository.Job{
+		ID:          "anchored-id",
+		ContentHash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		TxID:        "0xabc123",
+		AnchoredAt:  anchoredAt,
+	}
+	repo.jobs["unanchored-id"] = &repository.Job{ID: "unanchored-id", ContentHash: "abc"}
+
+	getProof := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/jobs/"+id+"/proof", nil)
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		h.GetProof(rec, req)
+		return rec
+	}
+
+	t.Run("returns proof", func(t *testing.T) {
+		rec := getProof("anchored-id")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var response ProofResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		want := ProofResponse{
+			ID:          "anchored-id",
+			ContentHash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			TxID:        "0xabc123",
+			AnchoredAt:  anchoredAt,
+		}
+		if response != want {
+			t.Errorf("response = %+v, want %+v", response, want)
+		}
+	})
+
+	t.Run("returns 404 when not anchored", func(t *testing.T) {
+		rec := getProof("unanchored-id")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", rec.Code)
+		}
+		var response ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&response)
+		if response.Code != ErrCodeNotAnchored {
+			t.Errorf("expected code %s, got %s", ErrCodeNotAnchored, response.Code)
+		}
+	})
+
+	t.Run("returns 404 for missing job", func(t *testing.T) {
+		if rec := getProof("non-existent"); rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+}
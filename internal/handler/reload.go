@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/humanmark/humanmark/internal/service"
+)
+
+// ErrCodeReloadFailed is the error code returned when the detection
+// configuration couldn't be reloaded; the current configuration stays in
+// force.
+const ErrCodeReloadFailed = "reload_failed"
+
+// ReloadResponse is the JSON body for POST /admin/reload.
+type ReloadResponse struct {
+	// AnalyzerVersion is the version of the detection logic, which a
+	// reload doesn't change
+	AnalyzerVersion string `json:"analyzer_version"`
+
+	// ConfigFingerprint identifies the configuration now in force; new
+	// results carry it
+	ConfigFingerprint string `json:"config_fingerprint"`
+
+	// PreviousConfigFingerprint identifies the configuration replaced
+	PreviousConfigFingerprint string `json:"previous_config_fingerprint"`
+
+	// Changed reports whether the fingerprints differ. Stored results are
+	// only reused while they match, so a change means new detections run
+	// instead.
+	Changed bool `json:"changed"`
+}
+
+// ReloadConfig handles POST /admin/reload requests.
+// Re-reads the detection configuration files (the AI phrase dictionary
+// and host reputation rules) and swaps them in without a restart, as
+// SIGHUP does. Detections already running finish with the configuration
+// they started with; async jobs in the queue aren't dropped.
+func (h *Handler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.reload == nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "Reloading is not enabled")
+		return
+	}
+
+	previous := configFingerprint(h.detector)
+	if err := h.reload(); err != nil {
+		h.logger.Error("failed to reload detection config", "error", err)
+		h.writeError(w, http.StatusInternalServerError, ErrCodeReloadFailed, "Failed to reload: "+err.Error())
+		return
+	}
+	current := configFingerprint(h.detector)
+
+	h.writeJSON(w, http.StatusOK, ReloadResponse{
+		AnalyzerVersion:           service.AnalyzerVersion,
+		ConfigFingerprint:         current,
+		PreviousConfigFingerprint: previous,
+		Changed:                   current != previous,
+	})
+}
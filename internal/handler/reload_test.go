@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestReloadConfig tests that POST /admin/reload picks up an edited phrase
+// file, and that results stored under the old configuration aren't reused.
+func TestReloadConfig(t *testing.T) {
+	const text = "Let's circle back on the quarterly synergy with some blue sky thinking. " +
+		"The committee reviewed the proposal in detail before the vote, then adjourned for lunch."
+
+	path := filepath.Join(t.TempDir(), "phrases.json")
+	writePhrases := func(pattern string) {
+		t.Helper()
+		data := fmt.Sprintf(`[{"pattern": %q, "weight": 1.0, "category": "jargon"}]`, pattern)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	config := func() (service.DetectorConfig, error) {
+		phrases, err := service.LoadAIPhrases(path)
+		return service.DetectorConfig{Timeout: 5 * time.Second, AIPhrases: phrases}, err
+	}
+
+	writePhrases("quarterly synergy")
+	cfg, err := config()
+	if err != nil {
+		t.Fatalf("LoadAIPhrases() error = %v", err)
+	}
+	detector, err := service.NewDetector(cfg, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    repository.NewMemory(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+		Reload: func() error {
+			cfg, err := config()
+			if err != nil {
+				return err
+			}
+			return detector.(service.Reloader).Reload(cfg)
+		},
+	})
+
+	verify := func() VerifyResponse {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/verify?detailed=true", strings.NewReader(`{"text": "`+text+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response VerifyResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return response
+	}
+	phrases := func(response VerifyResponse) []string {
+		var found []string
+		if response.Details != nil {
+			for _, p := range response.Details.AIPhrases {
+				found = append(found, p.Phrase)
+			}
+		}
+		return found
+	}
+	reload := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ReloadConfig(rec, httptest.NewRequest("POST", "/admin/reload", nil))
+		return rec
+	}
+
+	first := verify()
+	if got := phrases(first); len(got) != 1 || got[0] != "quarterly synergy" {
+		t.Fatalf("expected the file's phrase, got %v", got)
+	}
+
+	writePhrases("blue sky thinking")
+	rec := reload()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response ReloadResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !response.Changed || response.PreviousConfigFingerprint != first.ConfigFingerprint ||
+		response.ConfigFingerprint == first.ConfigFingerprint {
+		t.Errorf("response = %+v, want a change from %s", response, first.ConfigFingerprint)
+	}
+
+	second := verify()
+	if second.Reused || second.ID == first.ID {
+		t.Errorf("expected a new detection after the reload, got the stored result %s", second.ID)
+	}
+	if got := phrases(second); len(got) != 1 || got[0] != "blue sky thinking" {
+		t.Errorf("expected the reloaded phrase, got %v", got)
+	}
+	if second.ConfigFingerprint != response.ConfigFingerprint {
+		t.Errorf("result fingerprint = %s, want %s", second.ConfigFingerprint, response.ConfigFingerprint)
+	}
+
+	t.Run("unchanged files", func(t *testing.T) {
+		var response ReloadResponse
+		json.NewDecoder(reload().Body).Decode(&response)
+		if response.Changed || response.ConfigFingerprint != second.ConfigFingerprint {
+			t.Errorf("response = %+v, want no change", response)
+		}
+		if !verify().Reused {
+			t.Error("expected the stored result to be reused")
+		}
+	})
+
+	t.Run("invalid file keeps the current config", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`[{"pattern": "", "weight": 1.0}]`), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		rec := reload()
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), ErrCodeReloadFailed) {
+			t.Errorf("expected a 500 %s, got %d: %s", ErrCodeReloadFailed, rec.Code, rec.Body.String())
+		}
+		if got := phrases(verify()); len(got) != 1 || got[0] != "blue sky thinking" {
+			t.Errorf("expected the last good phrases, got %v", got)
+		}
+	})
+
+	t.Run("disabled without a reload func", func(t *testing.T) {
+		h := newTestHandler()
+		rec := httptest.NewRecorder()
+		h.ReloadConfig(rec, httptest.NewRequest("POST", "/admin/reload", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// StatsResponse is the JSON body for GET /admin/stats: aggregates over the
+// completed verifications created from From up to To.
+type StatsResponse struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	Total             int64   `json:"total"`
+	AverageConfidence float64 `json:"average_confidence"`
+
+	// Daily counts verifications per UTC day, oldest first, leaving out
+	// days without any
+	Daily []DailyCountResponse `json:"daily"`
+
+	// ContentTypes splits each content type's verifications by verdict
+	ContentTypes []ContentTypeStatsResponse `json:"content_types"`
+
+	// AIScoreHistogram counts verifications by AI score in 0.1-wide
+	// buckets, lowest first
+	AIScoreHistogram []HistogramBucket `json:"ai_score_histogram"`
+
+	// TopAIPhrases are the AI phrases found most often, most first
+	TopAIPhrases []PhraseCountResponse `json:"top_ai_phrases"`
+}
+
+// DailyCountResponse is one day of StatsResponse.Daily.
+type DailyCountResponse struct {
+	// Date is the UTC day, YYYY-MM-DD
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// ContentTypeStatsResponse is one content type's verdict split.
+type ContentTypeStatsResponse struct {
+	ContentType string `json:"content_type"`
+	Total       int64  `json:"total"`
+	Human       int64  `json:"human"`
+	AI          int64  `json:"ai"`
+	Uncertain   int64  `json:"uncertain"`
+}
+
+// HistogramBucket counts the AI scores from Min up to Max (the last bucket
+// includes 1.0).
+type HistogramBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int64   `json:"count"`
+}
+
+// PhraseCountResponse is how many times an AI phrase was found.
+type PhraseCountResponse struct {
+	Phrase string `json:"phrase"`
+	Count  int64  `json:"count"`
+}
+
+// defaultStatsPeriod is the range GET /admin/stats covers without from.
+const defaultStatsPeriod = 30 * 24 * time.Hour
+
+// parseStatsRange reads GET /admin/stats's from and to: RFC 3339
+// timestamps or YYYY-MM-DD dates (midnight UTC). to defaults to now and
+// from to defaultStatsPeriod before to.
+func parseStatsRange(query url.Values, now time.Time) (from, to time.Time, err error) {
+	parse := func(name string) (time.Time, error) {
+		v := query.Get(name)
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.DateOnly, v); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+	}
+
+	to = now
+	if query.Has("to") {
+		if to, err = parse("to"); err != nil {
+			return from, to, err
+		}
+	}
+	from = to.Add(-defaultStatsPeriod)
+	if query.Has("from") {
+		if from, err = parse("from"); err != nil {
+			return from, to, err
+		}
+	}
+	if !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+	return from, to, nil
+}
+
+// GetStats handles GET /admin/stats requests.
+// Returns aggregates over completed verifications for an operator
+// dashboard, by default for the last 30 days.
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, to, err := parseStatsRange(r.URL.Query(), h.now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	stats, err := h.repository.GetStats(r.Context(), from, to)
+	if err != nil {
+		h.logger.Error("failed to get stats", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to get stats")
+		return
+	}
+
+	response := StatsResponse{
+		From:              from,
+		To:                to,
+		Total:             stats.Total,
+		AverageConfidence: stats.AverageConfidence,
+		Daily:             make([]DailyCountResponse, len(stats.Daily)),
+		ContentTypes:      make([]ContentTypeStatsResponse, len(stats.ContentTypes)),
+		AIScoreHistogram:  make([]HistogramBucket, len(stats.AIScoreHistogram)),
+		TopAIPhrases:      make([]PhraseCountResponse, len(stats.TopAIPhrases)),
+	}
+	for i, day := range stats.Daily {
+		response.Daily[i] = DailyCountResponse{Date: day.Day.Format(time.DateOnly), Count: day.Jobs}
+	}
+	for i, c := range stats.ContentTypes {
+		response.ContentTypes[i] = ContentTypeStatsResponse{
+			ContentType: c.ContentType,
+			Total:       c.Jobs,
+			Human:       c.Human,
+			AI:          c.AI,
+			Uncertain:   c.Uncertain,
+		}
+	}
+	width := 1.0 / float64(len(stats.AIScoreHistogram))
+	for i, n := range stats.AIScoreHistogram {
+		response.AIScoreHistogram[i] = HistogramBucket{
+			Min:   math.Round(float64(i)*width*100) / 100,
+			Max:   math.Round(float64(i+1)*width*100) / 100,
+			Count: n,
+		}
+	}
+	for i, p := range stats.TopAIPhrases {
+		response.TopAIPhrases[i] = PhraseCountResponse{Phrase: p.Phrase, Count: p.Count}
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestGetStats tests the operator statistics endpoint.
+func TestGetStats(t *testing.T) {
+	repo := newMockRepository()
+	repo.stats = &repository.Stats{
+		Total:             3,
+		AverageConfidence: 0.8,
+		Daily: []repository.DayCount{
+			{Day: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), Jobs: 1},
+			{Day: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), Jobs: 2},
+		},
+		ContentTypes:     []repository.ContentTypeStats{{ContentType: "text", Jobs: 3, Human: 1, AI: 2}},
+		AIScoreHistogram: [repository.StatsHistogramBuckets]int64{0: 1, 9: 2},
+		TopAIPhrases:     []repository.PhraseCount{{Phrase: "delve", Count: 4}},
+	}
+	h := New(Config{
+		Detector:   &mockDetector{},
+		Repository: repo,
+		Logger:     logger.NopLogger(),
+	})
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.GetStats(rec, httptest.NewRequest("GET", "/admin/stats"+query, nil))
+		return rec
+	}
+
+	t.Run("serializes the aggregates", func(t *testing.T) {
+		rec := get("")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var got StatsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if got.Total != 3 || got.AverageConfidence != 0.8 {
+			t.Errorf("got total %d, average confidence %f", got.Total, got.AverageConfidence)
+		}
+		wantDaily := []DailyCountResponse{{"2026-10-14", 1}, {"2026-10-15", 2}}
+		if !reflect.DeepEqual(got.Daily, wantDaily) {
+			t.Errorf("Daily = %+v, want %+v", got.Daily, wantDaily)
+		}
+		wantTypes := []ContentTypeStatsResponse{{ContentType: "text", Total: 3, Human: 1, AI: 2}}
+		if !reflect.DeepEqual(got.ContentTypes, wantTypes) {
+			t.Errorf("ContentTypes = %+v, want %+v", got.ContentTypes, wantTypes)
+		}
+		if len(got.AIScoreHistogram) != 10 {
+			t.Fatalf("expected 10 buckets, got %+v", got.AIScoreHistogram)
+		}
+		if b := got.AIScoreHistogram[3]; b.Min != 0.3 || b.Max != 0.4 || b.Count != 0 {
+			t.Errorf("bucket 3 = %+v", b)
+		}
+		if b := got.AIScoreHistogram[9]; b.Min != 0.9 || b.Max != 1 || b.Count != 2 {
+			t.Errorf("bucket 9 = %+v", b)
+		}
+		if !reflect.DeepEqual(got.TopAIPhrases, []PhraseCountResponse{{"delve", 4}}) {
+			t.Errorf("TopAIPhrases = %+v", got.TopAIPhrases)
+		}
+	})
+
+	t.Run("defaults to the last 30 days", func(t *testing.T) {
+		get("")
+		if !repo.statsTo.Equal(now) || !repo.statsFrom.Equal(now.AddDate(0, 0, -30)) {
+			t.Errorf("range = %v to %v", repo.statsFrom, repo.statsTo)
+		}
+	})
+
+	t.Run("accepts dates and timestamps", func(t *testing.T) {
+		if rec := get("?from=2026-10-01&to=2026-10-08T12:00:00Z"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if !repo.statsFrom.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) ||
+			!repo.statsTo.Equal(time.Date(2026, 10, 8, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("range = %v to %v", repo.statsFrom, repo.statsTo)
+		}
+	})
+
+	t.Run("rejects invalid ranges", func(t *testing.T) {
+		for _, query := range []string{"?from=yesterday", "?to=10/16/2026", "?from=2026-10-08&to=2026-10-01"} {
+			if rec := get(query); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+
+	"github.com/humanmark/humanmark/internal/service"
+)
+
+// maxFormValuesSize bounds the non-file fields of a multipart upload, which
+// are always kept in memory.
+const maxFormValuesSize = 1024 * 1024
+
+// spillFile is an uploaded file written to disk because it was too large to
+// keep in memory. Closing it removes the file.
+type spillFile struct {
+	*os.File
+}
+
+// Close closes and removes the file.
+func (f *spillFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.Name()))
+}
+
+// readUpload reads one uploaded file. Files up to the upload memory limit
+// are kept in Data; larger ones are streamed to a temp file set as Reader,
+// which the caller removes by closing the input.
+func (h *Handler) readUpload(part io.Reader) (service.DetectionInput, error) {
+	data, err := io.ReadAll(io.LimitReader(part, h.uploadMemory+1))
+	if err != nil {
+		return service.DetectionInput{}, err
+	}
+	if int64(len(data)) <= h.uploadMemory {
+		return service.DetectionInput{Data: data}, nil
+	}
+
+	f, err := os.CreateTemp("", "humanmark-upload-*")
+	if err != nil {
+		return service.DetectionInput{}, err
+	}
+	file := &spillFile{f}
+	size, err := io.Copy(file, io.MultiReader(bytes.NewReader(data), part))
+	if err != nil {
+		file.Close()
+		return service.DetectionInput{}, err
+	}
+	return service.DetectionInput{Reader: file, Size: size}, nil
+}
+
+// parseMultipartInputs parses a multipart form upload into one
+// DetectionInput per file, in upload order by field (see fileFields). The
+// form's other fields apply to every file. The whole upload is limited to
+// MaxUploadSize.
+//
+// The upload is streamed rather than buffered: files over the upload
+// memory limit go to temp files, removed when the inputs are closed. On
+// error none are left behind.
+func (h *Handler) parseMultipartInputs(r *http.Request) (inputs []service.DetectionInput, err error) {
+	// Limit upload size
+	r.Body = http.MaxBytesReader(nil, r.Body, h.maxUploadSize)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("failed to parse upload: " + err.Error())
+	}
+
+	files := make(map[string][]service.DetectionInput)
+	defer func() {
+		if err != nil {
+			for _, field := range files {
+				closeInputs(field)
+			}
+		}
+	}()
+
+	form := url.Values{}
+	var formSize, uploaded int64
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("failed to parse upload: " + err.Error())
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValuesSize-formSize+1))
+			if err != nil {
+				return nil, errors.New("failed to parse upload: " + err.Error())
+			}
+			if formSize += int64(len(value)); formSize > maxFormValuesSize {
+				return nil, errors.New("failed to parse upload: form fields too large")
+			}
+			form.Add(field, string(value))
+			continue
+		}
+		if !slices.Contains(fileFields, field) {
+			continue
+		}
+
+		// Files past the limit are only counted, for the error
+		if uploaded++; uploaded > int64(h.maxFiles) {
+			continue
+		}
+		input, err := h.readUpload(part)
+		if err != nil {
+			return nil, errors.New("failed to parse upload: " + err.Error())
+		}
+
+		// Each file's type is sniffed on its own. The client's MIME type
+		// and filename are only hints, checked against the content.
+		input.Filename = part.FileName()
+		input.MIMEType = part.Header.Get("Content-Type")
+		files[field] = append(files[field], input)
+	}
+
+	if uploaded == 0 {
+		return nil, errors.New("no file uploaded: use 'file' form field")
+	}
+	if uploaded > int64(h.maxFiles) {
+		return nil, fmt.Errorf("%w: %d uploaded, at most %d per request", errTooManyFiles, uploaded, h.maxFiles)
+	}
+
+	// Like r.FormValue, fields in the body win over the query string
+	for key, values := range r.URL.Query() {
+		form[key] = append(form[key], values...)
+	}
+	for _, field := range fileFields {
+		for _, input := range files[field] {
+			input.CallbackURL = form.Get("callback_url")
+			input.Detectors = splitFormList(form.Get("detectors"))
+			input.ExcludeDetectors = splitFormList(form.Get("exclude_detectors"))
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs, nil
+}
+
+// closeInputs releases inputs' temp files, if they have any.
+func closeInputs(inputs []service.DetectionInput) {
+	for _, input := range inputs {
+		input.Close()
+	}
+}
@@ -0,0 +1,386 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// spillDetector implements service.Detector, recording how each upload
+// reached it and streaming its content the way the media analyzers do.
+// Files in one upload are detected concurrently, so mu guards the records.
+type spillDetector struct {
+	mu     sync.Mutex
+	inputs []service.DetectionInput
+	files  []string
+	read   int64
+	panics bool
+}
+
+func (d *spillDetector) Detect(ctx context.Context, input service.DetectionInput) (*service.DetectionResult, error) {
+	d.mu.Lock()
+	d.inputs = append(d.inputs, input)
+	if f, ok := input.Reader.(*spillFile); ok {
+		d.files = append(d.files, f.Name())
+	}
+	d.mu.Unlock()
+	if d.panics {
+		panic("parser crashed")
+	}
+	n, err := io.Copy(io.Discard, input.OpenData())
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.read += n
+	d.mu.Unlock()
+	return &service.DetectionResult{
+		Human:       true,
+		Verdict:     service.VerdictHuman,
+		Confidence:  0.9,
+		AIScore:     0.1,
+		ContentType: input.ContentType,
+		Detectors:   []string{"spill"},
+	}, nil
+}
+
+// multipartUpload returns a request uploading files (name to content) in
+// the "file" field.
+func multipartUpload(t *testing.T, target string, files map[string][]byte) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for name, data := range files {
+		part, err := writer.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write(data)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", target, &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// tempFiles returns the names of the files in dir.
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir(%s) error = %v", dir, err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// TestVerify_UploadSpill tests that uploads over the memory limit are
+// streamed to temp files, and that those are removed however the request
+// ends.
+func TestVerify_UploadSpill(t *testing.T) {
+	newHandler := func(t *testing.T, detector service.Detector, queue JobQueue) (*Handler, string) {
+		dir := t.TempDir()
+		t.Setenv("TMPDIR", dir)
+		return New(Config{
+			Detector:          detector,
+			Repository:        repository.NewMemory(),
+			Logger:            logger.NopLogger(),
+			MaxUploadSize:     10 * 1024 * 1024,
+			UploadMemoryLimit: 1024,
+			Queue:             queue,
+		}), dir
+	}
+	small := []byte("A short note, small enough to keep in memory.")
+	large := append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, bytes.Repeat([]byte{0x42}, 64*1024)...)
+
+	t.Run("large files go to disk and are removed after", func(t *testing.T) {
+		detector := &spillDetector{}
+		h, dir := newHandler(t, detector, nil)
+
+		rec := httptest.NewRecorder()
+		h.Verify(rec, multipartUpload(t, "/verify", map[string][]byte{"photo.jpg": large}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		input := detector.inputs[0]
+		if input.Data != nil || input.Size != int64(len(large)) || len(detector.files) != 1 {
+			t.Fatalf("expected a %d byte temp file, got Data of %d bytes, Size %d", len(large), len(input.Data), input.Size)
+		}
+		if detector.read != int64(len(large)) {
+			t.Errorf("detector read %d bytes, want %d", detector.read, len(large))
+		}
+		if input.Filename != "photo.jpg" || input.ContentType != "" {
+			t.Errorf("expected the filename as a hint and the type left to Detect, got %q and %s", input.Filename, input.ContentType)
+		}
+		if left := tempFiles(t, dir); len(left) != 0 {
+			t.Errorf("temp files left behind: %v", left)
+		}
+	})
+
+	t.Run("small files stay in memory", func(t *testing.T) {
+		detector := &spillDetector{}
+		h, _ := newHandler(t, detector, nil)
+
+		rec := httptest.NewRecorder()
+		h.Verify(rec, multipartUpload(t, "/verify", map[string][]byte{"note.txt": small}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if input := detector.inputs[0]; !bytes.Equal(input.Data, small) || input.Reader != nil {
+			t.Errorf("expected the upload in Data, got %q (Reader %v)", input.Data, input.Reader)
+		}
+	})
+
+	t.Run("several files are removed after", func(t *testing.T) {
+		detector := &spillDetector{}
+		h, dir := newHandler(t, detector, nil)
+
+		rec := httptest.NewRecorder()
+		h.Verify(rec, multipartUpload(t, "/verify", map[string][]byte{"a.jpg": large, "b.jpg": large, "c.txt": small}))
+		if rec.Code != http.StatusMultiStatus {
+			t.Fatalf("expected 207, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if len(detector.files) != 2 {
+			t.Errorf("expected 2 temp files, got %v", detector.files)
+		}
+		if left := tempFiles(t, dir); len(left) != 0 {
+			t.Errorf("temp files left behind: %v", left)
+		}
+	})
+
+	t.Run("temp file is removed when detection panics", func(t *testing.T) {
+		h, dir := newHandler(t, &spillDetector{panics: true}, nil)
+
+		func() {
+			defer func() {
+				if p := recover(); p == nil {
+					t.Error("expected the panic to reach the caller")
+				}
+			}()
+			h.Verify(httptest.NewRecorder(), multipartUpload(t, "/verify", map[string][]byte{"photo.jpg": large}))
+		}()
+		if left := tempFiles(t, dir); len(left) != 0 {
+			t.Errorf("temp files left behind: %v", left)
+		}
+	})
+
+	t.Run("temp file is removed when the client goes away", func(t *testing.T) {
+		detector := &spillDetector{}
+		h, dir := newHandler(t, detector, nil)
+
+		// The body ends before the closing boundary, as when a client
+		// disconnects mid-upload
+		req := multipartUpload(t, "/verify", map[string][]byte{"photo.jpg": large})
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body[:len(body)-100]))
+
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		if rec.Code != http.StatusBadRequest || len(detector.inputs) != 0 {
+			t.Errorf("expected a 400 without detection, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if left := tempFiles(t, dir); len(left) != 0 {
+			t.Errorf("temp files left behind: %v", left)
+		}
+	})
+
+	t.Run("queued jobs keep their temp file", func(t *testing.T) {
+		queue := &recordingQueue{}
+		h, dir := newHandler(t, &spillDetector{}, queue)
+
+		rec := httptest.NewRecorder()
+		h.Verify(rec, multipartUpload(t, "/verify?async=true", map[string][]byte{"photo.jpg": large}))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if left := tempFiles(t, dir); len(left) != 1 {
+			t.Fatalf("expected the temp file kept for the job, got %v", left)
+		}
+		data, err := queue.inputs[0].ReadData(1 << 20)
+		if err != nil || !bytes.Equal(data, large) {
+			t.Errorf("queued input unreadable: %v", err)
+		}
+
+		// The queue closes the input once the job is done
+		queue.inputs[0].Close()
+		if left := tempFiles(t, dir); len(left) != 0 {
+			t.Errorf("temp files left behind: %v", left)
+		}
+	})
+
+	t.Run("temp file is removed when the queue is full", func(t *testing.T) {
+		h, dir := newHandler(t, &spillDetector{}, &mockQueue{err: errors.New("queue full")})
+
+		rec := httptest.NewRecorder()
+		h.Verify(rec, multipartUpload(t, "/verify?async=true", map[string][]byte{"photo.jpg": large}))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if left := tempFiles(t, dir); len(left) != 0 {
+			t.Errorf("temp files left behind: %v", left)
+		}
+	})
+}
+
+// TestVerify_UploadSniffed tests that an upload's MIME type and filename
+// are only hints: the real detector analyzes what the bytes are and warns
+// about the label.
+func TestVerify_UploadSniffed(t *testing.T) {
+	detector, err := service.NewDetector(service.DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector failed: %v", err)
+	}
+	h := New(Config{
+		Detector:      detector,
+		Repository:    repository.NewMemory(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 10 * 1024 * 1024,
+	})
+	mp4 := append([]byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm', 0, 0, 0, 0, 'i', 's', 'o', 'm', 'a', 'v', 'c', '1'}, make([]byte, 2048)...)
+
+	tests := []struct {
+		name, filename, mimeType string
+		wantWarning              string
+	}{
+		{"mp4 as photo.jpg", "photo.jpg", "image/jpeg", "extension suggests image but content is video"},
+		{"mp4 as image/jpeg", "upload", "image/jpeg", "MIME type suggests image but content is video"},
+		{"mp4 labeled as one", "clip.mp4", "video/mp4", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := multipart.NewWriter(&buf)
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", `form-data; name="file"; filename="`+tt.filename+`"`)
+			header.Set("Content-Type", tt.mimeType)
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			part.Write(mp4)
+			writer.Close()
+
+			req := httptest.NewRequest("POST", "/verify?detailed=true&reuse=false", &buf)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rec := httptest.NewRecorder()
+			h.Verify(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response VerifyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.ContentType != string(service.ContentTypeVideo) {
+				t.Errorf("content_type = %s, want video", response.ContentType)
+			}
+			if response.Details == nil || response.Details.MismatchWarning != tt.wantWarning {
+				t.Errorf("mismatch warning = %+v, want %q", response.Details, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// recordingQueue implements JobQueue, keeping what's queued.
+type recordingQueue struct {
+	inputs []service.DetectionInput
+}
+
+func (q *recordingQueue) Enqueue(jobID string, input service.DetectionInput) error {
+	q.inputs = append(q.inputs, input)
+	return nil
+}
+
+// BenchmarkVerify_LargeUpload uploads a 200MB video, reporting the peak
+// heap in use while it's verified. With the upload streamed to disk the
+// peak stays around the memory limit, not the upload's size.
+func BenchmarkVerify_LargeUpload(b *testing.B) {
+	const size = 200 * 1024 * 1024
+	b.Setenv("TMPDIR", b.TempDir())
+
+	h := New(Config{
+		Detector:      &spillDetector{},
+		Repository:    repository.NewMemory(),
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 256 * 1024 * 1024,
+	})
+	header := []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm'}
+	chunk := make([]byte, 1024*1024)
+
+	var peak uint64
+	b.ReportAllocs()
+	b.SetBytes(size)
+	for i := 0; i < b.N; i++ {
+		// The body is generated as it's read, so only the handler holds
+		// any of it
+		body, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		go func() {
+			part, _ := writer.CreateFormFile("file", "clip.mp4")
+			part.Write(header)
+			for written := len(header); written < size; written += len(chunk) {
+				part.Write(chunk[:min(len(chunk), size-written)])
+			}
+			writer.Close()
+			pw.Close()
+		}()
+		req := httptest.NewRequest("POST", "/verify", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		done := make(chan struct{})
+		sampled := make(chan uint64)
+		go func() {
+			var max uint64
+			var stats runtime.MemStats
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				runtime.ReadMemStats(&stats)
+				if stats.HeapInuse > max {
+					max = stats.HeapInuse
+				}
+				select {
+				case <-done:
+					sampled <- max
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+
+		rec := httptest.NewRecorder()
+		h.Verify(rec, req)
+		close(done)
+		if p := <-sampled; p > peak {
+			peak = p
+		}
+		if rec.Code != http.StatusOK {
+			b.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	b.ReportMetric(float64(peak)/(1024*1024), "peak-heap-MB")
+}
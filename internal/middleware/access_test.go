@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+)
+
+// TestAccessControl verifies the deny and allow lists and their precedence.
+func TestAccessControl(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	newHandler := func(t *testing.T, list AccessList, reg metrics.Registry) http.Handler {
+		t.Helper()
+		ac, err := NewAccessControl(list)
+		if err != nil {
+			t.Fatalf("NewAccessControl: %v", err)
+		}
+		return ac.Middleware(reg)(ok)
+	}
+	request := func(handler http.Handler, ip string, key *repository.APIKey) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/verify", nil)
+		req.RemoteAddr = ip + ":12345"
+		if key != nil {
+			req = req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("denies listed IPs and ranges", func(t *testing.T) {
+		handler := newHandler(t, AccessList{DenyIPs: []string{"203.0.113.0/24", "198.51.100.7", "2001:db8::/32"}}, nil)
+
+		for _, ip := range []string{"203.0.113.9", "198.51.100.7", "[2001:db8::1]"} {
+			rec := request(handler, ip, nil)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s: expected 403, got %d", ip, rec.Code)
+			}
+			checkProblem(t, rec, http.StatusForbidden, "access_denied")
+		}
+		for _, ip := range []string{"203.0.114.9", "198.51.100.8", "[2001:db9::1]"} {
+			if rec := request(handler, ip, nil); rec.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d", ip, rec.Code)
+			}
+		}
+	})
+
+	t.Run("matches IPv4-mapped IPv6 addresses", func(t *testing.T) {
+		handler := newHandler(t, AccessList{DenyIPs: []string{"203.0.113.0/24"}}, nil)
+
+		if rec := request(handler, "[::ffff:203.0.113.9]", nil); rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", rec.Code)
+		}
+	})
+
+	t.Run("denies listed API keys", func(t *testing.T) {
+		handler := newHandler(t, AccessList{DenyKeys: []string{"bad"}}, nil)
+
+		if rec := request(handler, "10.0.0.1", &repository.APIKey{ID: "bad"}); rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for denied key, got %d", rec.Code)
+		}
+		if rec := request(handler, "10.0.0.1", &repository.APIKey{ID: "good"}); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 for other key, got %d", rec.Code)
+		}
+		if rec := request(handler, "10.0.0.1", nil); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 for anonymous request, got %d", rec.Code)
+		}
+	})
+
+	t.Run("deny wins over allow", func(t *testing.T) {
+		handler := newHandler(t, AccessList{
+			DenyIPs:  []string{"10.0.0.5"},
+			AllowIPs: []string{"10.0.0.0/8"},
+			DenyKeys: []string{"bad"},
+		}, nil)
+
+		if rec := request(handler, "10.0.0.5", nil); rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for denied IP inside allowed range, got %d", rec.Code)
+		}
+		if rec := request(handler, "10.0.0.6", &repository.APIKey{ID: "bad"}); rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for denied key from allowed IP, got %d", rec.Code)
+		}
+		if rec := request(handler, "10.0.0.6", nil); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 for allowed IP, got %d", rec.Code)
+		}
+	})
+
+	t.Run("allowed IPs bypass the rate limit", func(t *testing.T) {
+		ac, err := NewAccessControl(AccessList{AllowIPs: []string{"10.1.0.0/16"}})
+		if err != nil {
+			t.Fatalf("NewAccessControl: %v", err)
+		}
+		handler := ac.Middleware(nil)(newTestRateLimit(t, 1, 0, nil, nil)(ok))
+
+		for i := 0; i < 5; i++ {
+			if rec := request(handler, "10.1.2.3", nil); rec.Code != http.StatusOK {
+				t.Fatalf("request %d from allowed IP: expected 200, got %d", i, rec.Code)
+			}
+		}
+		request(handler, "10.2.0.1", nil)
+		if rec := request(handler, "10.2.0.1", nil); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected 429 for other IP, got %d", rec.Code)
+		}
+	})
+
+	t.Run("ignores spoofed forwarding headers", func(t *testing.T) {
+		ac, err := NewAccessControl(AccessList{DenyIPs: []string{"198.51.100.9"}, AllowIPs: []string{"10.1.0.0/16"}})
+		if err != nil {
+			t.Fatalf("NewAccessControl: %v", err)
+		}
+		handler := RealIP([]string{"10.0.0.1"})(ac.Middleware(nil)(newTestRateLimit(t, 1, 0, nil, nil)(ok)))
+		spoofed := func(remote, xff string) int {
+			req := httptest.NewRequest("GET", "/verify", nil)
+			req.RemoteAddr = remote + ":12345"
+			req.Header.Set("X-Forwarded-For", xff)
+			req.Header.Set("X-Real-IP", xff)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		if code := spoofed("198.51.100.9", "203.0.113.1"); code != http.StatusForbidden {
+			t.Errorf("denied IP claiming another: expected 403, got %d", code)
+		}
+		spoofed("198.51.100.10", "10.1.2.3")
+		if code := spoofed("198.51.100.10", "10.1.2.3"); code != http.StatusTooManyRequests {
+			t.Errorf("IP claiming an allowed one: expected 429, got %d", code)
+		}
+		if code := spoofed("10.0.0.1", "198.51.100.9"); code != http.StatusForbidden {
+			t.Errorf("denied IP behind the trusted proxy: expected 403, got %d", code)
+		}
+	})
+
+	t.Run("counts denials by reason", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		handler := newHandler(t, AccessList{DenyIPs: []string{"203.0.113.1"}, DenyKeys: []string{"bad"}}, reg)
+
+		request(handler, "203.0.113.1", nil)
+		request(handler, "203.0.113.1", nil)
+		request(handler, "10.0.0.1", &repository.APIKey{ID: "bad"})
+
+		var out strings.Builder
+		reg.WritePrometheus(&out)
+		for _, want := range []string{
+			`humanmark_access_denied_total{reason="ip"} 2`,
+			`humanmark_access_denied_total{reason="key"} 1`,
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("expected %s, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("update replaces the lists", func(t *testing.T) {
+		ac, err := NewAccessControl(AccessList{DenyIPs: []string{"10.0.0.1"}})
+		if err != nil {
+			t.Fatalf("NewAccessControl: %v", err)
+		}
+		handler := ac.Middleware(nil)(ok)
+
+		if err := ac.Update(AccessList{DenyIPs: []string{"10.0.0.2"}}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if rec := request(handler, "10.0.0.1", nil); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 for IP removed from the list, got %d", rec.Code)
+		}
+		if rec := request(handler, "10.0.0.2", nil); rec.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for IP added to the list, got %d", rec.Code)
+		}
+
+		if err := ac.Update(AccessList{DenyIPs: []string{"not-an-ip"}}); err == nil {
+			t.Fatal("expected error for invalid IP")
+		}
+		if rec := request(handler, "10.0.0.2", nil); rec.Code != http.StatusForbidden {
+			t.Errorf("expected old lists to stay in force, got %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects invalid entries", func(t *testing.T) {
+		for _, list := range []AccessList{
+			{DenyIPs: []string{"10.0.0.256"}},
+			{AllowIPs: []string{"10.0.0.0/33"}},
+			{DenyIPs: []string{""}},
+		} {
+			if _, err := NewAccessControl(list); err == nil {
+				t.Errorf("expected error for %+v", list)
+			}
+		}
+	})
+}
+
+// TestLoadAccessList verifies reading and merging an access list file.
+func TestLoadAccessList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	data := `{"deny_ips": ["203.0.113.0/24"], "allow_ips": ["10.0.0.5"], "deny_keys": ["bad"]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadAccessList(path)
+	if err != nil {
+		t.Fatalf("LoadAccessList: %v", err)
+	}
+	merged := AccessList{DenyIPs: []string{"198.51.100.1"}}.Merge(list)
+	if len(merged.DenyIPs) != 2 || merged.DenyIPs[1] != "203.0.113.0/24" {
+		t.Errorf("unexpected deny IPs %v", merged.DenyIPs)
+	}
+	if len(merged.AllowIPs) != 1 || len(merged.DenyKeys) != 1 {
+		t.Errorf("unexpected merge %+v", merged)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAccessList(path); err == nil {
+		t.Error("expected error for malformed file")
+	}
+	if _, err := LoadAccessList(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// apiKeyPrefix starts every API key: hm_<id>_<secret>.
+const apiKeyPrefix = "hm_"
+
+// APIKeyStore looks up API keys by ID. repository.Repository implements it.
+type APIKeyStore interface {
+	GetAPIKey(ctx context.Context, id string) (*repository.APIKey, error)
+}
+
+// NewAPIKeySecret returns a random key secret and the hash to store for it.
+func NewAPIKeySecret() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = hex.EncodeToString(b)
+	return secret, HashAPIKeySecret(secret), nil
+}
+
+// HashAPIKeySecret returns the hex SHA-256 of secret, as stored in
+// repository.APIKey.SecretHash.
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// FormatAPIKey returns the key clients send for the stored key id and its
+// secret.
+func FormatAPIKey(id, secret string) string {
+	return apiKeyPrefix + id + "_" + secret
+}
+
+// parseAPIKey splits a key made by FormatAPIKey.
+func parseAPIKey(key string) (id, secret string, ok bool) {
+	rest, found := strings.CutPrefix(key, apiKeyPrefix)
+	if !found {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(rest, "_")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// requestAPIKey returns the key sent in X-API-Key or as an Authorization
+// bearer token, or "" if there is none.
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	return ""
+}
+
+// Auth authenticates requests with API keys from keys. A valid key is
+// attached to the request context (see APIKeyFromContext); unknown,
+// malformed and revoked keys get 401. Requests without a key get 401 when
+// required is set and pass through anonymously otherwise.
+//
+// Health checks, metrics, the index page, admin endpoints (see
+// RequireAdminToken) and CORS preflights skip authentication.
+func Auth(keys APIKeyStore, required bool, log *logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipAuth(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			raw := requestAPIKey(r)
+			if raw == "" {
+				if required {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="humanmark"`)
+					problem.Error(w, http.StatusUnauthorized, "unauthorized", "API key required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := authenticate(r.Context(), keys, raw)
+			if errors.Is(err, errInvalidAPIKey) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="humanmark", error="invalid_token"`)
+				problem.Error(w, http.StatusUnauthorized, "unauthorized", "invalid API key")
+				return
+			}
+			if err != nil {
+				log.WithContext(r.Context()).Error("API key lookup failed", "error", err)
+				problem.Error(w, http.StatusInternalServerError, "internal_error", "internal server error")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), logger.ContextKeyAPIKey, key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// errInvalidAPIKey means a key is malformed, unknown, wrong or revoked.
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// authenticate returns the stored key raw refers to. The secret is checked
+// by comparing hashes in constant time.
+func authenticate(ctx context.Context, keys APIKeyStore, raw string) (*repository.APIKey, error) {
+	id, secret, ok := parseAPIKey(raw)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+
+	key, err := keys.GetAPIKey(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hash := HashAPIKeySecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.SecretHash)) != 1 || key.Revoked() {
+		return nil, errInvalidAPIKey
+	}
+	return key, nil
+}
+
+// skipAuth reports whether r is for an endpoint that doesn't take API keys.
+func skipAuth(r *http.Request) bool {
+	path := r.URL.Path
+	return r.Method == http.MethodOptions ||
+		path == "/" || path == "/metrics" || path == "/openapi.json" || path == "/docs" ||
+		strings.HasPrefix(path, "/.well-known/") ||
+		path == "/health" || strings.HasPrefix(path, "/health/") ||
+		strings.HasPrefix(path, "/admin/")
+}
+
+// APIKeyFromContext returns the API key Auth attached to ctx, or nil for
+// anonymous requests.
+func APIKeyFromContext(ctx context.Context) *repository.APIKey {
+	key, _ := ctx.Value(logger.ContextKeyAPIKey).(*repository.APIKey)
+	return key
+}
+
+// RequireAdminToken protects admin endpoints with a bearer token. With no
+// token configured the endpoints don't exist (404); a missing or wrong
+// token gets 401.
+func RequireAdminToken(token string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				problem.Error(w, http.StatusNotFound, "not_found", "not found")
+				return
+			}
+
+			got := []byte(requestAPIKey(r))
+			if subtle.ConstantTimeCompare(got, []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="humanmark-admin"`)
+				problem.Error(w, http.StatusUnauthorized, "unauthorized", "admin token required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// failingStore is an APIKeyStore whose lookups fail.
+type failingStore struct{}
+
+func (failingStore) GetAPIKey(ctx context.Context, id string) (*repository.APIKey, error) {
+	return nil, errors.New("database down")
+}
+
+// issueKey stores a new API key in repo and returns it and the key clients
+// send.
+func issueKey(t *testing.T, repo repository.Repository) (*repository.APIKey, string) {
+	t.Helper()
+	secret, hash, err := NewAPIKeySecret()
+	if err != nil {
+		t.Fatalf("NewAPIKeySecret failed: %v", err)
+	}
+	key, err := repo.CreateAPIKey(context.Background(), repository.APIKey{
+		Name:       "test",
+		SecretHash: hash,
+		Scopes:     repository.Scopes,
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	return key, FormatAPIKey(key.ID, secret)
+}
+
+// TestAuth verifies API key authentication.
+func TestAuth(t *testing.T) {
+	repo := repository.NewMemory()
+	key, raw := issueKey(t, repo)
+	revoked, revokedRaw := issueKey(t, repo)
+	repo.RevokeAPIKey(context.Background(), revoked.ID)
+
+	var seen *repository.APIKey
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = APIKeyFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(store APIKeyStore, required bool, method, path string, header http.Header) *httptest.ResponseRecorder {
+		seen = nil
+		req := httptest.NewRequest(method, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		Auth(store, required, logger.NopLogger())(next).ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The wrong secret for a real key ID
+	_, otherSecret, _ := parseAPIKey(revokedRaw)
+	wrongSecret := FormatAPIKey(key.ID, otherSecret)
+
+	tests := []struct {
+		name     string
+		required bool
+		header   http.Header
+		wantCode int
+		wantKey  bool
+	}{
+		{"X-API-Key", true, http.Header{"X-Api-Key": {raw}}, http.StatusOK, true},
+		{"bearer token", true, http.Header{"Authorization": {"Bearer " + raw}}, http.StatusOK, true},
+		{"lowercase bearer", true, http.Header{"Authorization": {"bearer " + raw}}, http.StatusOK, true},
+		{"missing key when required", true, nil, http.StatusUnauthorized, false},
+		{"missing key when optional", false, nil, http.StatusOK, false},
+		{"malformed key", false, http.Header{"X-Api-Key": {"not-a-key"}}, http.StatusUnauthorized, false},
+		{"unknown key ID", false, http.Header{"X-Api-Key": {FormatAPIKey("missing", "secret")}}, http.StatusUnauthorized, false},
+		{"wrong secret", true, http.Header{"X-Api-Key": {wrongSecret}}, http.StatusUnauthorized, false},
+		{"revoked key", true, http.Header{"X-Api-Key": {revokedRaw}}, http.StatusUnauthorized, false},
+		{"basic auth isn't a key", true, http.Header{"Authorization": {"Basic " + raw}}, http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(repo, tt.required, "POST", "/verify", tt.header)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantKey && (seen == nil || seen.ID != key.ID) {
+				t.Errorf("expected key %s in context, got %+v", key.ID, seen)
+			}
+			if !tt.wantKey && seen != nil {
+				t.Errorf("expected anonymous request, got key %s", seen.ID)
+			}
+			if rec.Code == http.StatusUnauthorized {
+				if rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("expected WWW-Authenticate header on 401")
+				}
+				checkProblem(t, rec, http.StatusUnauthorized, "unauthorized")
+			}
+		})
+	}
+
+	t.Run("skips health checks, metrics, docs and admin endpoints", func(t *testing.T) {
+		for _, path := range []string{"/", "/health", "/health/ready", "/metrics", "/openapi.json", "/docs", "/.well-known/humanmark.json", "/admin/keys"} {
+			if rec := serve(repo, true, "GET", path, nil); rec.Code != http.StatusOK {
+				t.Errorf("%s: expected status 200, got %d", path, rec.Code)
+			}
+		}
+		if rec := serve(repo, true, "OPTIONS", "/verify", nil); rec.Code != http.StatusOK {
+			t.Errorf("preflight: expected status 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("store errors are 500", func(t *testing.T) {
+		rec := serve(failingStore{}, true, "POST", "/verify", http.Header{"X-Api-Key": {raw}})
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", rec.Code)
+		}
+	})
+}
+
+// TestAPIKeyFormat verifies that formatted keys parse back.
+func TestAPIKeyFormat(t *testing.T) {
+	secret, hash, err := NewAPIKeySecret()
+	if err != nil {
+		t.Fatalf("NewAPIKeySecret failed: %v", err)
+	}
+	if hash != HashAPIKeySecret(secret) || hash == secret {
+		t.Errorf("expected hash of secret, got %q", hash)
+	}
+
+	id, got, ok := parseAPIKey(FormatAPIKey("abc123", secret))
+	if !ok || id != "abc123" || got != secret {
+		t.Errorf("parseAPIKey = %q, %q, %v", id, got, ok)
+	}
+
+	for _, raw := range []string{"", "hm_", "hm_abc", "hm__secret", "hm_abc_", "sk_abc_secret"} {
+		if _, _, ok := parseAPIKey(raw); ok {
+			t.Errorf("parseAPIKey(%q) should fail", raw)
+		}
+	}
+}
+
+// TestRequireAdminToken verifies admin endpoint protection.
+func TestRequireAdminToken(t *testing.T) {
+	token := strings.Repeat("a", 32)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		header   string
+		wantCode int
+	}{
+		{"correct token", token, "Bearer " + token, http.StatusOK},
+		{"wrong token", token, "Bearer " + strings.Repeat("b", 32), http.StatusUnauthorized},
+		{"missing token", token, "", http.StatusUnauthorized},
+		{"disabled without a token", "", "Bearer anything", http.StatusNotFound},
+		{"disabled even for an empty bearer", "", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/keys", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			RequireAdminToken(tt.token)(next).ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+		})
+	}
+}
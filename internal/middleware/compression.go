@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// minCompressSize is the smallest body Compression compresses; below it
+// the gzip header and footer cost more than they save.
+const minCompressSize = 1024
+
+// gzipWriters reuses gzip writers, whose compression state is ~800KB.
+var gzipWriters = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// Compression gzips responses for clients that send Accept-Encoding: gzip.
+// Bodies under minCompressSize, responses that can't have a body, and
+// content that is compressed already (images, audio, video, archives, or a
+// handler-set Content-Encoding) are sent as is. Compressible responses
+// carry Vary: Accept-Encoding either way, so caches keep both forms.
+//
+// Compressing changes the bytes, so a strong ETag on a compressed response
+// is made weak (as nginx does); handlers compare If-None-Match weakly, as
+// RFC 9110 has them do, so the tag still validates.
+//
+// The body is streamed through a pooled writer once minCompressSize bytes
+// have been written; only that much is held back. zstd isn't offered, as
+// the standard library has no encoder for it.
+func Compression() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &compressWriter{
+				ResponseWriter: w,
+				accepts:        r.Method != http.MethodHead && acceptsGzip(r.Header.Get("Accept-Encoding")),
+				status:         http.StatusOK,
+			}
+			next.ServeHTTP(cw, r)
+			cw.Close()
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "x-gzip" && coding != "*" {
+			continue
+		}
+
+		// q=0 means "not acceptable"
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = v
+			}
+		}
+		if coding != "*" {
+			// An explicit gzip entry overrides the wildcard
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
+
+// compressWriter decides whether to compress once it has seen the headers
+// and minCompressSize bytes of the body (or the whole body, if shorter),
+// and holds the status code back until then so it can still change the
+// headers. Logging sees the status when it's finally written.
+type compressWriter struct {
+	http.ResponseWriter
+	accepts     bool
+	status      int
+	wroteHeader bool
+	started     bool
+	buf         []byte
+	bufErr      error        // From writing buf out
+	gz          *gzip.Writer // nil unless compressing
+}
+
+// WriteHeader records the status code. Responses without a body are
+// started right away.
+func (cw *compressWriter) WriteHeader(code int) {
+	if code < 200 {
+		// Informational responses (e.g. 103 Early Hints) go straight out
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+	if !bodyAllowed(code) {
+		cw.start(false)
+	}
+}
+
+// Write buffers the start of the body until the response can be judged,
+// then writes through the gzip writer or directly.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.started {
+		if cw.gz != nil {
+			return cw.gz.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	switch {
+	case !cw.accepts || !cw.compressible():
+		cw.start(false)
+	case len(cw.buf) >= minCompressSize:
+		cw.start(true)
+	}
+	if cw.bufErr != nil {
+		return 0, cw.bufErr
+	}
+	return len(p), nil
+}
+
+// Flush starts the response, compressed if it qualifies, and flushes what
+// has been written so far to the client.
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		// A streamed body is assumed to be worth compressing
+		cw.start(cw.accepts && cw.compressible())
+	}
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close starts a response that was too short to compress, and finishes the
+// gzip stream of one that wasn't.
+func (cw *compressWriter) Close() {
+	if !cw.started {
+		if !cw.wroteHeader {
+			// Nothing written; the server sends its default 200
+			return
+		}
+		cw.start(false)
+	}
+	if cw.gz != nil {
+		cw.gz.Close()
+		gzipWriters.Put(cw.gz)
+		cw.gz = nil
+	}
+}
+
+// compressible reports whether the response may be compressed, judging by
+// its status and headers. An unset Content-Type is sniffed from the body.
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if !bodyAllowed(cw.status) || cw.status == http.StatusPartialContent {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// Set it from the uncompressed body; net/http would sniff the
+		// compressed one
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	return compressibleType(h.Get("Content-Type"))
+}
+
+// start writes the headers and the buffered body.
+func (cw *compressWriter) start(compress bool) {
+	cw.started = true
+	h := cw.Header()
+	if cw.compressible() {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if compress {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.gz = gzipWriters.Get().(*gzip.Writer)
+		cw.gz.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) > 0 {
+		if cw.gz != nil {
+			_, cw.bufErr = cw.gz.Write(cw.buf)
+		} else {
+			_, cw.bufErr = cw.ResponseWriter.Write(cw.buf)
+		}
+	}
+	cw.buf = nil
+}
+
+// bodyAllowed reports whether a response with status may have a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// compressibleType reports whether a Content-Type is worth compressing:
+// anything but formats that are compressed already.
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "font/woff"):
+		return false
+	}
+	switch mediaType {
+	case "application/gzip", "application/x-gzip", "application/zip", "application/zstd",
+		"application/x-7z-compressed", "application/x-rar-compressed", "application/x-bzip2",
+		"application/x-xz":
+		return false
+	}
+	return true
+}
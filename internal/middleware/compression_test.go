@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestCompression verifies gzip negotiation and the cases left uncompressed.
+func TestCompression(t *testing.T) {
+	largeJSON := `{"sentences":[` + strings.Repeat(`{"text":"A sentence of the submitted text.","ai_score":0.42},`, 100) + `{}]}`
+
+	serve := func(handler http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/verify/abc", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		Compression()(handler).ServeHTTP(rec, req)
+		return rec
+	}
+	decoded := func(t *testing.T, rec *httptest.ResponseRecorder) string {
+		t.Helper()
+		if rec.Header().Get("Content-Encoding") != "gzip" {
+			return rec.Body.String()
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		return string(body)
+	}
+	respond := func(contentType, body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			// Several writes, as encoders make
+			for rest := body; len(rest) > 0; {
+				n := min(len(rest), 300)
+				w.Write([]byte(rest[:n]))
+				rest = rest[n:]
+			}
+		})
+	}
+
+	t.Run("compressed and uncompressed bodies match", func(t *testing.T) {
+		bodies := map[string]string{
+			"application/json":                         largeJSON,
+			"text/plain; version=0.0.4; charset=utf-8": strings.Repeat("humanmark_requests_total{status=\"200\"} 12\n", 100),
+			"": strings.Repeat("<p>Report</p>", 200), // Sniffed as text/html
+		}
+		for contentType, body := range bodies {
+			handler := respond(contentType, body)
+			gz := serve(handler, "gzip, deflate, br")
+			plain := serve(handler, "")
+
+			if gz.Header().Get("Content-Encoding") != "gzip" {
+				t.Errorf("%q: expected gzip encoding", contentType)
+			}
+			if gz.Body.Len() >= len(body) {
+				t.Errorf("%q: compressed body is %d bytes, original %d", contentType, gz.Body.Len(), len(body))
+			}
+			if plain.Header().Get("Content-Encoding") != "" {
+				t.Errorf("%q: expected no encoding without Accept-Encoding", contentType)
+			}
+			if got := decoded(t, gz); got != body {
+				t.Errorf("%q: decoded gzip body differs from the original", contentType)
+			}
+			if got := decoded(t, plain); got != body {
+				t.Errorf("%q: uncompressed body differs from the original", contentType)
+			}
+			for _, rec := range []*httptest.ResponseRecorder{gz, plain} {
+				if rec.Header().Get("Vary") != "Accept-Encoding" {
+					t.Errorf("%q: Vary = %q, want Accept-Encoding", contentType, rec.Header().Get("Vary"))
+				}
+			}
+			if gz.Header().Get("Content-Type") != plain.Header().Get("Content-Type") || gz.Header().Get("Content-Type") == "" {
+				t.Errorf("%q: Content-Type %q compressed, %q not", contentType, gz.Header().Get("Content-Type"), plain.Header().Get("Content-Type"))
+			}
+		}
+	})
+
+	t.Run("drops Content-Length when compressing", func(t *testing.T) {
+		rec := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "5000")
+			w.Write([]byte(largeJSON))
+		}), "gzip")
+
+		if rec.Header().Get("Content-Length") != "" {
+			t.Errorf("Content-Length = %q, want none", rec.Header().Get("Content-Length"))
+		}
+	})
+
+	t.Run("skips tiny bodies", func(t *testing.T) {
+		rec := serve(respond("application/json", `{"status":"ok"}`), "gzip")
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"status":"ok"}` {
+			t.Errorf("tiny body was compressed: %q", rec.Body.String())
+		}
+	})
+
+	t.Run("skips compressed content", func(t *testing.T) {
+		png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 4000)
+		for _, handler := range []http.Handler{
+			respond("image/png", png),
+			respond("", png), // Sniffed
+			respond("application/zip", strings.Repeat("PK", 2000)),
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				w.Write([]byte(largeJSON))
+			}),
+		} {
+			rec := serve(handler, "gzip")
+			if got := rec.Header().Get("Content-Encoding"); got == "gzip" {
+				t.Errorf("compressed content was gzipped (Content-Type %q)", rec.Header().Get("Content-Type"))
+			}
+			if rec.Header().Get("Vary") != "" {
+				t.Errorf("Vary = %q for content that is never compressed", rec.Header().Get("Vary"))
+			}
+		}
+	})
+
+	t.Run("no body statuses", func(t *testing.T) {
+		for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+			rec := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}), "gzip")
+			if rec.Code != status || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+				t.Errorf("status %d: got %d, encoding %q, %d body bytes", status, rec.Code, rec.Header().Get("Content-Encoding"), rec.Body.Len())
+			}
+		}
+	})
+
+	t.Run("CORS preflight", func(t *testing.T) {
+		handler := Chain(CORS([]string{"*"}), Compression())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("preflight reached the handler")
+		}))
+		req := httptest.NewRequest("OPTIONS", "/verify", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("preflight: got %d, encoding %q", rec.Code, rec.Header().Get("Content-Encoding"))
+		}
+	})
+
+	t.Run("HEAD is not compressed", func(t *testing.T) {
+		req := httptest.NewRequest("HEAD", "/verify/abc", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		Compression()(respond("application/json", largeJSON)).ServeHTTP(rec, req)
+
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Error("HEAD response was marked gzip")
+		}
+	})
+
+	t.Run("logging sees the status", func(t *testing.T) {
+		for _, body := range []string{`{"error":"x"}`, largeJSON} {
+			var buf bytes.Buffer
+			handler := Chain(Logging(logger.NewWithWriter("info", &buf)), Compression())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(body))
+			}))
+			req := httptest.NewRequest("POST", "/verify", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want 422", rec.Code)
+			}
+			if !strings.Contains(buf.String(), "422") {
+				t.Errorf("status 422 not logged: %s", buf.String())
+			}
+		}
+	})
+
+	t.Run("weakens strong ETags when compressing", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"abc"`)
+			w.Write([]byte(largeJSON))
+		})
+		if etag := serve(handler, "gzip").Header().Get("ETag"); etag != `W/"abc"` {
+			t.Errorf("compressed: ETag = %q, want W/\"abc\"", etag)
+		}
+		if etag := serve(handler, "").Header().Get("ETag"); etag != `"abc"` {
+			t.Errorf("uncompressed: ETag = %q, want \"abc\"", etag)
+		}
+	})
+
+	t.Run("flush streams compressed output", func(t *testing.T) {
+		rec := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: first\n\n"))
+			w.(http.Flusher).Flush()
+			if !w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder).Flushed {
+				t.Error("Flush did not reach the underlying writer")
+			}
+			w.Write([]byte("data: second\n\n"))
+		}), "gzip")
+
+		if got := decoded(t, rec); got != "data: first\n\ndata: second\n\n" {
+			t.Errorf("decoded stream = %q", got)
+		}
+	})
+}
+
+// TestAcceptsGzip verifies Accept-Encoding negotiation.
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"x-gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0", false},
+		{"br", false},
+		{"identity", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"*, gzip;q=0", false},
+		{"gzip;q=0, *", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// overloadRetryAfter is the Retry-After sent when every slot is taken.
+// Analyses take seconds, so that's about when one frees up.
+const overloadRetryAfter = 5 * time.Second
+
+// OverloadedError is returned by AcquireSlot when a content type's pool
+// has no free slot.
+type OverloadedError struct {
+	// Pool is the content type whose pool is full
+	Pool string
+
+	// RetryAfter is when the client should try again
+	RetryAfter time.Duration
+}
+
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("too many %s requests in progress", e.Pool)
+}
+
+// MaxConcurrent caps the requests in progress at limit, so that many
+// clients at once can't exhaust memory with large uploads (the rate limit
+// is per client). A request that finds every slot taken waits up to wait
+// for one (0 = not at all), then gets a 503 Service Unavailable with
+// Retry-After.
+//
+// pools caps content types ("video", "audio", ...) separately, within
+// limit: text is cheap, video expensive. The content type is only known to
+// the handler, which takes a slot from its pool with AcquireSlot.
+//
+// The requests holding a slot, per pool ("all" for limit), are reported to
+// reg as humanmark_in_flight_requests and rejections as
+// humanmark_overloaded_total (nil records nothing). A limit of 0 or less
+// disables the shared cap; pools still apply.
+func MaxConcurrent(limit int, wait time.Duration, pools map[string]int, reg metrics.Registry) Middleware {
+	if reg == nil {
+		reg = metrics.Discard
+	}
+	l := &concurrencyLimiter{
+		wait:     wait,
+		pools:    make(map[string]chan struct{}, len(pools)),
+		inFlight: reg.Gauge("humanmark_in_flight_requests", "Requests holding a concurrency slot.", "pool"),
+		rejected: reg.Counter("humanmark_overloaded_total", "Requests rejected because every concurrency slot was taken.", "pool"),
+	}
+	if limit > 0 {
+		l.all = make(chan struct{}, limit)
+	}
+	for name, size := range pools {
+		if size > 0 {
+			l.pools[name] = make(chan struct{}, size)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.all != nil {
+				release, err := l.acquire(r.Context(), "all", l.all)
+				if err != nil {
+					if r.Context().Err() != nil {
+						// The client is gone; nobody will read a response
+						return
+					}
+					writeOverloaded(w, err.RetryAfter)
+					return
+				}
+				defer release()
+			}
+
+			ctx := context.WithValue(r.Context(), concurrencyLimiterKey{}, l)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// concurrencyLimiterKey is the context key for a request's
+// *concurrencyLimiter.
+type concurrencyLimiterKey struct{}
+
+// concurrencyLimiter holds a semaphore per pool: a buffered channel with a
+// value in it for each slot taken.
+type concurrencyLimiter struct {
+	all      chan struct{} // nil = no shared cap
+	pools    map[string]chan struct{}
+	wait     time.Duration
+	inFlight metrics.Gauge
+	rejected metrics.Counter
+}
+
+// acquire takes a slot in sem, waiting up to l.wait for one. The release
+// func must be called once the request is done with it.
+func (l *concurrencyLimiter) acquire(ctx context.Context, pool string, sem chan struct{}) (func(), *OverloadedError) {
+	release := func() {
+		<-sem
+		l.inFlight.Add(-1, pool)
+	}
+
+	select {
+	case sem <- struct{}{}:
+		l.inFlight.Add(1, pool)
+		return release, nil
+	default:
+	}
+
+	if l.wait > 0 {
+		timer := time.NewTimer(l.wait)
+		defer timer.Stop()
+		select {
+		case sem <- struct{}{}:
+			l.inFlight.Add(1, pool)
+			return release, nil
+		case <-timer.C:
+		case <-ctx.Done():
+			// Not a rejection: the client gave up first
+			return nil, &OverloadedError{Pool: pool, RetryAfter: overloadRetryAfter}
+		}
+	}
+
+	l.rejected.Inc(pool)
+	return nil, &OverloadedError{Pool: pool, RetryAfter: overloadRetryAfter}
+}
+
+// AcquireSlot takes a slot in the MaxConcurrent pool for contentType,
+// waiting as long as MaxConcurrent allows. Call release when the work is
+// done. If MaxConcurrent isn't in use or has no pool for contentType,
+// there's nothing to take and release does nothing. Returns an
+// *OverloadedError if the pool stays full.
+func AcquireSlot(ctx context.Context, contentType string) (release func(), err error) {
+	l, ok := ctx.Value(concurrencyLimiterKey{}).(*concurrencyLimiter)
+	if !ok {
+		return func() {}, nil
+	}
+	sem, ok := l.pools[contentType]
+	if !ok {
+		return func() {}, nil
+	}
+
+	release, overloaded := l.acquire(ctx, contentType, sem)
+	if overloaded != nil {
+		return nil, overloaded
+	}
+	return release, nil
+}
+
+// writeOverloaded writes the 503 for a request that found no free slot.
+func writeOverloaded(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := ceilSeconds(retryAfter)
+	w.Header().Set("Retry-After", formatInt(seconds))
+	p := problem.New(http.StatusServiceUnavailable, "overloaded", "server is busy, retry later")
+	p.RetryAfter = seconds
+	problem.Write(w, p)
+}
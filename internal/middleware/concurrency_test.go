@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/metrics"
+)
+
+// TestMaxConcurrent verifies the concurrency cap under load.
+func TestMaxConcurrent(t *testing.T) {
+	// gate blocks requests until released, tracking how many run at once
+	type gate struct {
+		release chan struct{}
+		entered chan struct{}
+		running atomic.Int32
+		peak    atomic.Int32
+	}
+	newGate := func() *gate {
+		return &gate{release: make(chan struct{}), entered: make(chan struct{}, 100)}
+	}
+	gated := func(g *gate) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := g.running.Add(1)
+			for {
+				peak := g.peak.Load()
+				if n <= peak || g.peak.CompareAndSwap(peak, n) {
+					break
+				}
+			}
+			g.entered <- struct{}{}
+			<-g.release
+			g.running.Add(-1)
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+	// load sends n requests at once and returns their status codes
+	load := func(handler http.Handler, n int) (codes chan *httptest.ResponseRecorder, wait func()) {
+		codes = make(chan *httptest.ResponseRecorder, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify", nil))
+				codes <- rec
+			}()
+		}
+		return codes, func() {
+			wg.Wait()
+			close(codes)
+		}
+	}
+
+	t.Run("enforces the cap", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		g := newGate()
+		handler := MaxConcurrent(3, 0, nil, reg)(gated(g))
+
+		codes, wait := load(handler, 20)
+
+		// Three get in; the rest are turned away without waiting
+		for i := 0; i < 3; i++ {
+			<-g.entered
+		}
+		rejected := 0
+		for rejected < 17 {
+			rec := <-codes
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("expected 503 while saturated, got %d", rec.Code)
+			}
+			if rec.Header().Get("Retry-After") != "5" {
+				t.Errorf("Retry-After = %q, want 5", rec.Header().Get("Retry-After"))
+			}
+			if p := checkProblem(t, rec, http.StatusServiceUnavailable, "overloaded"); p.RetryAfter != 5 {
+				t.Errorf("retry_after = %d, want 5", p.RetryAfter)
+			}
+			rejected++
+		}
+
+		var out strings.Builder
+		reg.WritePrometheus(&out)
+		if !strings.Contains(out.String(), `humanmark_in_flight_requests{pool="all"} 3`+"\n") {
+			t.Errorf("expected 3 in flight, got:\n%s", out.String())
+		}
+
+		close(g.release)
+		wait()
+		for rec := range codes {
+			if rec.Code != http.StatusOK {
+				t.Errorf("admitted request got %d", rec.Code)
+			}
+		}
+		if peak := g.peak.Load(); peak != 3 {
+			t.Errorf("peak concurrency = %d, want 3", peak)
+		}
+
+		out.Reset()
+		reg.WritePrometheus(&out)
+		for _, want := range []string{
+			`humanmark_in_flight_requests{pool="all"} 0` + "\n",
+			`humanmark_overloaded_total{pool="all"} 17` + "\n",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("expected %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("waiting requests get freed slots", func(t *testing.T) {
+		handler := MaxConcurrent(2, 5*time.Second, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		codes, wait := load(handler, 10)
+		wait()
+		for rec := range codes {
+			if rec.Code != http.StatusOK {
+				t.Errorf("waiting request got %d, want 200", rec.Code)
+			}
+		}
+	})
+
+	t.Run("wait is bounded", func(t *testing.T) {
+		g := newGate()
+		defer close(g.release)
+		handler := MaxConcurrent(1, 30*time.Millisecond, nil, nil)(gated(g))
+
+		go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/verify", nil))
+		<-g.entered
+
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", rec.Code)
+		}
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond || elapsed > time.Second {
+			t.Errorf("waited %s, want about 30ms", elapsed)
+		}
+	})
+
+	t.Run("pools cap content types separately", func(t *testing.T) {
+		g := newGate()
+		pools := map[string]int{"video": 2}
+		handler := MaxConcurrent(10, 0, pools, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			kind := r.URL.Query().Get("type")
+			release, err := AcquireSlot(r.Context(), kind)
+			if err != nil {
+				var overloaded *OverloadedError
+				if !errors.As(err, &overloaded) || overloaded.Pool != kind {
+					t.Errorf("AcquireSlot() error = %v, want an OverloadedError for %s", err, kind)
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			defer release()
+			gated(g).ServeHTTP(w, r)
+		}))
+		request := func(kind string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify?type="+kind, nil))
+			return rec
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				request("video")
+			}()
+		}
+		<-g.entered
+		<-g.entered
+
+		// The video pool is full, but text still gets in
+		if rec := request("video"); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("third video: expected 503, got %d", rec.Code)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec := request("text"); rec.Code != http.StatusOK {
+				t.Errorf("text: expected 200, got %d", rec.Code)
+			}
+		}()
+		<-g.entered
+
+		close(g.release)
+		wg.Wait()
+
+		// Slots are given back
+		g = newGate()
+		close(g.release)
+		if rec := request("video"); rec.Code != http.StatusOK {
+			t.Errorf("video after release: expected 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("without MaxConcurrent", func(t *testing.T) {
+		release, err := AcquireSlot(context.Background(), "video")
+		if err != nil {
+			t.Fatalf("AcquireSlot() error = %v", err)
+		}
+		release()
+	})
+}
@@ -2,10 +2,20 @@
 //
 // Middleware wraps HTTP handlers to add cross-cutting functionality like:
 //   - Request logging
-//   - Panic recovery
+//   - Panic recovery (see recovery.go)
 //   - Request ID tracking
-//   - CORS headers
-//   - Rate limiting
+//   - Client IPs behind trusted proxies (see realip.go)
+//   - CORS headers, with wildcard subdomain origins
+//   - Security headers (see security.go)
+//   - API key authentication (see auth.go)
+//   - IP and API key access lists (see access.go)
+//   - Rate limiting (see ratelimit_rules.go for per-route limits)
+//   - Request timeouts (see timeout.go)
+//   - Response compression (see compression.go)
+//   - Concurrency limits (see concurrency.go)
+//   - Tracing (see tracing.go)
+//   - Response schema validation (development/staging only)
+//   - Response size limits, which streaming routes can Bypass
 //
 // Middleware is applied as a chain, with the first middleware being the outermost layer.
 package middleware
@@ -14,13 +24,16 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"net"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/problem"
 )
 
 // Middleware is a function that wraps an HTTP handler.
@@ -38,6 +51,22 @@ func Chain(middlewares ...Middleware) Middleware {
 	}
 }
 
+// Bypass sends requests for the given route patterns of routes (as
+// registered, e.g. "GET /verify/export") straight to direct, skipping the
+// middleware it wraps. It's for streaming responses, which middleware that
+// holds the response until the handler returns would break.
+func Bypass(routes Routes, direct http.Handler, patterns ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, pattern := routes.Handler(r); slices.Contains(patterns, pattern) {
+				direct.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequestID adds a unique request ID to each request.
 // The ID is added to the request context and response headers.
 // If the request already has an X-Request-ID header, it is preserved.
@@ -69,7 +98,9 @@ func generateRequestID() string {
 
 // Logging logs all HTTP requests with timing information.
 // Logs include: method, path, status code, duration, request ID.
+// They're tagged component=http, so LOG_LEVELS can quiet them.
 func Logging(log *logger.Logger) Middleware {
+	log = log.Component("http")
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -99,7 +130,7 @@ func Logging(log *logger.Logger) Middleware {
 				"status", wrapped.statusCode,
 				"duration_ms", duration.Milliseconds(),
 				"request_id", requestID,
-				"remote_addr", getClientIP(r),
+				"remote_addr", ClientIP(r),
 				"user_agent", r.UserAgent(),
 			)
 		})
@@ -130,51 +161,97 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
 
-// Recovery catches panics and returns a 500 error instead of crashing.
-// Panics are logged with stack trace information.
-func Recovery(log *logger.Logger) Middleware {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			defer func() {
-				if err := recover(); err != nil {
-					// Get request ID for correlation
-					requestID := ""
-					if id := r.Context().Value(logger.ContextKeyRequestID); id != nil {
-						requestID = id.(string)
-					}
+// Flush sends what has been written so far, for handlers that stream.
+func (rw *responseWriter) Flush() {
+	rw.written = true
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
 
-					// Log the panic
-					log.Error("panic recovered",
-						"error", err,
-						"request_id", requestID,
-						"method", r.Method,
-						"path", r.URL.Path,
-					)
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
 
-					// Return 500 error
-					http.Error(w, `{"error":"internal server error"}`, http.StatusInternalServerError)
-				}
-			}()
+// CORSConfig configures CORSWithConfig.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the API, e.g.
+	// "https://app.example.com". "*" allows any origin, and
+	// "https://*.example.com" any subdomain of example.com (but not
+	// example.com itself).
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods browsers may use (nil = GET, POST,
+	// DELETE, OPTIONS)
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers browsers may send (nil =
+	// Content-Type, Authorization, X-API-Key, X-Request-ID)
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers scripts may read (nil =
+	// X-Request-ID, Retry-After and the rate limit headers)
+	ExposedHeaders []string
+
+	// AllowCredentials lets browsers send cookies and HTTP auth. It needs
+	// explicit origins: browsers refuse credentials with "*", so it is
+	// ignored then.
+	AllowCredentials bool
+}
 
-			next.ServeHTTP(w, r)
-		})
-	}
+// CORS adds Cross-Origin Resource Sharing headers for allowedOrigins, with
+// the default methods and headers (see CORSConfig).
+func CORS(allowedOrigins []string) Middleware {
+	return CORSWithConfig(CORSConfig{AllowedOrigins: allowedOrigins})
 }
 
-// CORS adds Cross-Origin Resource Sharing headers.
+// CORSWithConfig adds Cross-Origin Resource Sharing headers.
 // This allows browser-based clients to call the API from different domains.
-func CORS(allowedOrigins []string) Middleware {
+func CORSWithConfig(cfg CORSConfig) Middleware {
+	methods := cfg.AllowedMethods
+	if methods == nil {
+		methods = []string{"GET", "POST", "DELETE", "OPTIONS"}
+	}
+	headers := cfg.AllowedHeaders
+	if headers == nil {
+		headers = []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID"}
+	}
+	exposed := cfg.ExposedHeaders
+	if exposed == nil {
+		exposed = []string{"X-Request-ID", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+	}
+
 	// Convert to map for fast lookup
 	originMap := make(map[string]bool)
+	var wildcards []originWildcard
 	allowAll := false
-	for _, origin := range allowedOrigins {
+	for _, origin := range cfg.AllowedOrigins {
 		if origin == "*" {
 			allowAll = true
 			break
 		}
-		originMap[origin] = true
+		if w, ok := parseOriginWildcard(origin); ok {
+			wildcards = append(wildcards, w)
+			continue
+		}
+		originMap[strings.ToLower(origin)] = true
+	}
+	allowed := func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if originMap[origin] {
+			return true
+		}
+		for _, w := range wildcards {
+			if w.matches(origin) {
+				return true
+			}
+		}
+		return false
 	}
 
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	exposeHeaders := strings.Join(exposed, ", ")
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
@@ -182,14 +259,23 @@ func CORS(allowedOrigins []string) Middleware {
 			// Check if origin is allowed
 			if allowAll {
 				w.Header().Set("Access-Control-Allow-Origin", "*")
-			} else if origin != "" && originMap[origin] {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Vary", "Origin") // Important for caching
+			} else {
+				// The response depends on Origin whether or not it's allowed
+				w.Header().Add("Vary", "Origin")
+				if origin != "" && allowed(origin) {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					if cfg.AllowCredentials {
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
+					}
+				}
 			}
 
 			// Set CORS headers
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID")
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			if exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
 			w.Header().Set("Access-Control-Max-Age", "86400") // Cache preflight for 24 hours
 
 			// Handle preflight requests
@@ -203,138 +289,287 @@ func CORS(allowedOrigins []string) Middleware {
 	}
 }
 
-// RateLimit implements a simple in-memory rate limiter.
+// originWildcard is an allowed origin with a "*." subdomain wildcard, e.g.
+// "https://*.example.com".
+type originWildcard struct {
+	prefix string // "https://"
+	suffix string // ".example.com", with any port
+}
+
+// parseOriginWildcard parses an origin with a subdomain wildcard. ok is
+// false for an origin without one.
+func parseOriginWildcard(origin string) (w originWildcard, ok bool) {
+	scheme, host, found := strings.Cut(strings.ToLower(origin), "://*.")
+	if !found || scheme == "" || host == "" {
+		return originWildcard{}, false
+	}
+	return originWildcard{prefix: scheme + "://", suffix: "." + host}, true
+}
+
+// matches reports whether a lowercased origin is a subdomain matched by w.
+// The subdomain may have several labels, but only letters, digits,
+// hyphens and dots, so "https://evil.com/.example.com" doesn't match.
+func (w originWildcard) matches(origin string) bool {
+	if len(origin) <= len(w.prefix)+len(w.suffix) || !strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+		return false
+	}
+	sub := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+	if strings.HasPrefix(sub, ".") || strings.HasSuffix(sub, ".") {
+		return false
+	}
+	for _, c := range sub {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// RateLimiter is an in-memory token bucket rate limiter. Each client's
+// bucket holds up to burst tokens and refills at its per-minute limit, so a
+// client can spend a burst at once but never more than the limit on
+// average; unlike a fixed window, there's no boundary at which it can spend
+// two minutes' worth back to back.
 // For production, use Redis-based rate limiting for distributed deployments.
-type rateLimiter struct {
-	requests map[string]*clientRequests
-	mu       sync.RWMutex
-	limit    int
-	window   time.Duration
+//
+// Buckets that have refilled are swept every minute by a goroutine that
+// runs until Close. At most maxBuckets are kept: when a new client arrives
+// at the cap, the fullest of a few random buckets is evicted, so a flood
+// of spoofed IPs can't exhaust memory. An evicted client starts over with
+// a full bucket.
+type RateLimiter struct {
+	buckets    map[string]*tokenBucket
+	mu         sync.Mutex
+	limit      int
+	burst      int         // 0 = each client's per-minute limit
+	rules      []routeRule // Most specific first
+	maxBuckets int         // 0 = no cap
+	now        func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{} // Closed when the cleanup goroutine exits
+	closeOnce sync.Once
+}
+
+// DefaultMaxRateLimitClients is the bucket cap used when NewRateLimiter is
+// given 0 or less: roughly 15MB of buckets.
+const DefaultMaxRateLimitClients = 100_000
+
+// evictionSample is how many random buckets are compared to pick one to
+// evict.
+const evictionSample = 8
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time // When the bucket refills completely
 }
 
-type clientRequests struct {
-	count    int
-	resetAt  time.Time
+// rateLimitDecision is the outcome of one request against a bucket.
+type rateLimitDecision struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration // Until the bucket is full again
+	retryAfter time.Duration // Until the next token (0 if allowed)
 }
 
-// RateLimit limits requests per client IP.
-// Returns 429 Too Many Requests if limit is exceeded.
-func RateLimit(requestsPerMinute int) Middleware {
-	limiter := &rateLimiter{
-		requests: make(map[string]*clientRequests),
-		limit:    requestsPerMinute,
-		window:   time.Minute,
+// newRateLimiter returns a RateLimiter without its cleanup goroutine.
+func newRateLimiter(requestsPerMinute, burst int, rules []RateLimitRule, maxBuckets int) *RateLimiter {
+	if maxBuckets <= 0 {
+		maxBuckets = DefaultMaxRateLimitClients
 	}
+	return &RateLimiter{
+		buckets:    make(map[string]*tokenBucket),
+		limit:      requestsPerMinute,
+		burst:      burst,
+		rules:      compileRouteRules(rules),
+		maxBuckets: maxBuckets,
+		now:        time.Now,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
 
-	// Start cleanup goroutine
-	go limiter.cleanup()
+// NewRateLimiter returns a rate limiter for requests per API key, or per
+// client IP for anonymous requests, with a token bucket per client that
+// refills at requestsPerMinute and holds up to burst requests (0 =
+// requestsPerMinute). A key's RateLimitPerMinute replaces
+// requestsPerMinute for it.
+// Requests matching one of rules are limited by the most specific match
+// instead, in a bucket of their own per client and rule that holds the
+// rule's limit; it panics if a rule's pattern is invalid.
+// At most maxBuckets buckets are kept (0 = DefaultMaxRateLimitClients).
+// Call Close when done with it to stop its cleanup goroutine.
+func NewRateLimiter(requestsPerMinute, burst int, rules []RateLimitRule, maxBuckets int) *RateLimiter {
+	rl := newRateLimiter(requestsPerMinute, burst, rules, maxBuckets)
+	go rl.cleanup()
+	return rl
+}
+
+// Close stops the cleanup goroutine and waits for it to exit. The
+// middleware keeps working, with buckets only evicted at the cap. Calls
+// after the first do nothing.
+func (rl *RateLimiter) Close() {
+	rl.closeOnce.Do(func() {
+		close(rl.stop)
+	})
+	<-rl.done
+}
+
+// Middleware returns the rate limiting middleware. Keys are attached by
+// Auth, which must run first.
+// Every response carries X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (seconds until the bucket is full). Returns 429 Too
+// Many Requests with Retry-After if the bucket is empty.
+// Rejections are counted in reg (nil records nothing).
+func (rl *RateLimiter) Middleware(reg metrics.Registry) Middleware {
+	if reg == nil {
+		reg = metrics.Discard
+	}
+	rejected := reg.Counter("humanmark_rate_limited_total", "Requests rejected by the rate limiter.")
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Skip rate limiting for health checks
-			if r.URL.Path == "/health" {
+			// Skip rate limiting for health checks, metrics scrapes and
+			// IPs allowlisted by AccessControl
+			if r.URL.Path == "/health" || strings.HasPrefix(r.URL.Path, "/health/") || r.URL.Path == "/metrics" || bypassesRateLimit(r.Context()) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			clientIP := getClientIP(r)
-			
-			if !limiter.allow(clientIP) {
-				w.Header().Set("Retry-After", "60")
-				w.Header().Set("X-RateLimit-Limit", string(rune(limiter.limit)))
-				w.Header().Set("X-RateLimit-Remaining", "0")
-				http.Error(w, `{"error":"rate limit exceeded","retry_after":60}`, http.StatusTooManyRequests)
-				return
+			client, limit := "ip:"+ClientIP(r), rl.limit
+			if key := APIKeyFromContext(r.Context()); key != nil {
+				client = "key:" + key.ID
+				if key.RateLimitPerMinute > 0 {
+					limit = key.RateLimitPerMinute
+				}
 			}
 
-			// Add rate limit headers
-			remaining := limiter.remaining(clientIP)
-			w.Header().Set("X-RateLimit-Limit", formatInt(limiter.limit))
-			w.Header().Set("X-RateLimit-Remaining", formatInt(remaining))
+			bucket, capacity := client, rl.burst
+			if rule := rl.match(r); rule != nil {
+				bucket, limit, capacity = client+" "+rule.pattern, rule.limit, rule.limit
+			}
+
+			d := rl.take(bucket, limit, capacity)
+			w.Header().Set("X-RateLimit-Limit", formatInt(limit))
+			w.Header().Set("X-RateLimit-Remaining", formatInt(d.remaining))
+			w.Header().Set("X-RateLimit-Reset", formatInt(ceilSeconds(d.reset)))
+
+			if !d.allowed {
+				rejected.Inc()
+				seconds := ceilSeconds(d.retryAfter)
+				w.Header().Set("Retry-After", formatInt(seconds))
+				p := problem.New(http.StatusTooManyRequests, "rate_limited", "rate limit exceeded")
+				p.RetryAfter = seconds
+				problem.Write(w, p)
+				return
+			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// allow checks if a client can make another request.
-func (rl *rateLimiter) allow(clientIP string) bool {
+// take refills bucket for the time since its last request and spends a
+// token from it if there is one. The bucket refills at limit per minute
+// and holds up to burst tokens (0 = limit).
+func (rl *RateLimiter) take(bucket string, limit, burst int) rateLimitDecision {
+	limit = max(limit, 1)
+	capacity := float64(limit)
+	if burst > 0 {
+		capacity = float64(burst)
+	}
+	perSecond := float64(limit) / 60
+
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
-
-	client, exists := rl.requests[clientIP]
-	if !exists || now.After(client.resetAt) {
-		// New window
-		rl.requests[clientIP] = &clientRequests{
-			count:   1,
-			resetAt: now.Add(rl.window),
+	now := rl.now()
+	b, exists := rl.buckets[bucket]
+	if !exists {
+		if len(rl.buckets) >= rl.maxBuckets {
+			rl.evict()
 		}
-		return true
+		b = &tokenBucket{tokens: capacity, updated: now}
+		rl.buckets[bucket] = b
 	}
 
-	if client.count >= rl.limit {
-		return false
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = min(capacity, b.tokens+elapsed*perSecond)
+		b.updated = now
 	}
 
-	client.count++
-	return true
+	var d rateLimitDecision
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = secondsDuration((1 - b.tokens) / perSecond)
+	}
+
+	d.reset = secondsDuration((capacity - b.tokens) / perSecond)
+	d.remaining = int(b.tokens)
+	b.fullAt = now.Add(d.reset)
+	return d
 }
 
-// remaining returns the number of requests remaining for a client.
-func (rl *rateLimiter) remaining(clientIP string) int {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
+// secondsDuration converts fractional seconds to a Duration.
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
 
-	if client, exists := rl.requests[clientIP]; exists {
-		remaining := rl.limit - client.count
-		if remaining < 0 {
-			return 0
-		}
-		return remaining
-	}
-	return rl.limit
+// ceilSeconds rounds d up to whole seconds, so a client that waits that
+// long is never early.
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
 }
 
-// cleanup periodically removes expired entries.
-func (rl *rateLimiter) cleanup() {
-	ticker := time.NewTicker(time.Minute)
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for ip, client := range rl.requests {
-			if now.After(client.resetAt) {
-				delete(rl.requests, ip)
-			}
+// evict removes the bucket closest to full out of a few random ones, as
+// forgetting it costs its client the least. rl.mu must be held.
+func (rl *RateLimiter) evict() {
+	var victim string
+	var fullAt time.Time
+	n := 0
+	// Map iteration starts at a random entry
+	for client, b := range rl.buckets {
+		if n == 0 || b.fullAt.Before(fullAt) {
+			victim, fullAt = client, b.fullAt
+		}
+		if n++; n == evictionSample {
+			break
 		}
-		rl.mu.Unlock()
 	}
+	delete(rl.buckets, victim)
 }
 
-// getClientIP extracts the client IP address from the request.
-// Handles X-Forwarded-For and X-Real-IP headers from reverse proxies.
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (set by load balancers/proxies)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP (original client)
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
+// cleanup removes buckets that have refilled every minute, since a new
+// bucket starts out full anyway, until Close.
+func (rl *RateLimiter) cleanup() {
+	defer close(rl.done)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
+			rl.sweep()
 		}
-		return strings.TrimSpace(xff)
 	}
+}
 
-	// Check X-Real-IP header (set by nginx)
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
+// sweep removes buckets that have refilled.
+func (rl *RateLimiter) sweep() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 
-	// Fall back to remote address
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+	now := rl.now()
+	for client, b := range rl.buckets {
+		if !now.Before(b.fullAt) {
+			delete(rl.buckets, client)
+		}
 	}
-	return ip
 }
 
 // formatInt converts an integer to a string without importing strconv.
@@ -374,3 +609,127 @@ func MaxBodySize(maxBytes int64) Middleware {
 		})
 	}
 }
+
+// ErrResponseTruncated is returned by writes past a MaxResponseSize limit.
+var ErrResponseTruncated = errors.New("response exceeds maximum size")
+
+// MaxResponseSize truncates response bodies larger than maxBytes and marks
+// them with an X-Response-Truncated: true header. JSON bodies are cut after
+// the last array element that fits, with open arrays and objects closed, so
+// clients still get valid JSON. Writes past the limit return
+// ErrResponseTruncated. A maxBytes of 0 or less disables the limit.
+//
+// Responses are held until the handler returns, so up to maxBytes is
+// buffered per request.
+func MaxResponseSize(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &limitedResponse{
+				bufferedResponse: bufferedResponse{header: w.Header(), status: http.StatusOK},
+				max:              maxBytes,
+			}
+			next.ServeHTTP(rec, r)
+
+			body := rec.body.Bytes()
+			if rec.truncated {
+				if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+					if cut, ok := truncateJSON(body); ok {
+						body = cut
+					}
+				}
+				w.Header().Del("Content-Length")
+				w.Header().Set("X-Response-Truncated", "true")
+			}
+
+			w.WriteHeader(rec.status)
+			w.Write(body)
+		})
+	}
+}
+
+// limitedResponse buffers at most max bytes of a response.
+type limitedResponse struct {
+	bufferedResponse
+	max       int64
+	truncated bool
+}
+
+// Write buffers p up to the limit and reports ErrResponseTruncated for the rest.
+func (l *limitedResponse) Write(p []byte) (int, error) {
+	l.wroteHeader = true
+	room := l.max - int64(l.body.Len())
+	if int64(len(p)) <= room {
+		return l.body.Write(p)
+	}
+
+	l.truncated = true
+	n, _ := l.body.Write(p[:room])
+	return n, ErrResponseTruncated
+}
+
+// truncateJSON shortens a JSON prefix to the end of its last complete array
+// element and closes whatever is still open, staying within len(body).
+// It returns false if there's no such point.
+func truncateJSON(body []byte) ([]byte, bool) {
+	var (
+		open     []byte // unclosed '[' and '{', outermost first
+		cut      = -1
+		cutOpen  []byte
+		inString bool
+		escaped  bool
+	)
+
+	for i, c := range body {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			open = append(open, c)
+		case '[':
+			// Just inside an array is safe: it closes as empty
+			open = append(open, c)
+			if i+1+len(open) <= len(body) {
+				cut, cutOpen = i+1, append(cutOpen[:0], open...)
+			}
+		case '}', ']':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case ',':
+			// A comma in an array ends an element
+			if len(open) > 0 && open[len(open)-1] == '[' && i+len(open) <= len(body) {
+				cut, cutOpen = i, append(cutOpen[:0], open...)
+			}
+		}
+	}
+
+	if cut < 0 {
+		return nil, false
+	}
+
+	out := append([]byte(nil), body[:cut]...)
+	for i := len(cutOpen) - 1; i >= 0; i-- {
+		if cutOpen[i] == '[' {
+			out = append(out, ']')
+		} else {
+			out = append(out, '}')
+		}
+	}
+	return out, true
+}
@@ -2,14 +2,39 @@ package middleware
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/humanmark/humanmark/internal/repository"
 	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/problem"
 )
 
+// checkProblem checks that rec is a problem details response with status
+// and code, and returns it.
+func checkProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) problem.Problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %s", ct, problem.ContentType)
+	}
+	var p problem.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("body isn't problem details: %v: %s", err, rec.Body.String())
+	}
+	if rec.Code != status || p.Status != status || p.Code != code || p.Type != problem.TypePrefix+code {
+		t.Errorf("got %d %+v, want status %d and code %q", rec.Code, p, status, code)
+	}
+	return p
+}
+
 // TestRequestID verifies request ID generation and propagation.
 func TestRequestID(t *testing.T) {
 	t.Run("generates request ID when not present", func(t *testing.T) {
@@ -65,7 +90,7 @@ func TestRequestID(t *testing.T) {
 func TestLogging(t *testing.T) {
 	t.Run("logs request details", func(t *testing.T) {
 		var buf bytes.Buffer
-		log := logger.NewWithWriter("info", &buf)
+		log := logger.NewWithOptions("info", &buf, logger.Options{Format: logger.FormatText})
 
 		handler := Logging(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -86,6 +111,21 @@ func TestLogging(t *testing.T) {
 		if !strings.Contains(output, "200") {
 			t.Error("status code not logged")
 		}
+		if !strings.Contains(output, "component=http") {
+			t.Error("component not logged")
+		}
+	})
+
+	t.Run("quieted by the http component level", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewWithOptions("info", &buf, logger.Options{Levels: map[string]string{"http": "warn"}})
+
+		handler := Logging(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test-path", nil))
+
+		if buf.Len() != 0 {
+			t.Errorf("expected no request log at warn, got %s", buf.String())
+		}
 	})
 
 	t.Run("captures correct status code", func(t *testing.T) {
@@ -105,44 +145,19 @@ func TestLogging(t *testing.T) {
 			t.Error("status 404 not logged")
 		}
 	})
-}
 
-// TestRecovery verifies panic recovery.
-func TestRecovery(t *testing.T) {
-	t.Run("recovers from panic", func(t *testing.T) {
-		log := logger.NopLogger()
-
-		handler := Recovery(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			panic("test panic")
-		}))
-
-		req := httptest.NewRequest("GET", "/", nil)
-		rec := httptest.NewRecorder()
-
-		// Should not panic
-		handler.ServeHTTP(rec, req)
-
-		// Should return 500
-		if rec.Code != http.StatusInternalServerError {
-			t.Errorf("expected status 500, got %d", rec.Code)
-		}
-	})
-
-	t.Run("logs panic", func(t *testing.T) {
-		var buf bytes.Buffer
-		log := logger.NewWithWriter("error", &buf)
-
-		handler := Recovery(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			panic("test panic message")
+	t.Run("passes flushes through", func(t *testing.T) {
+		handler := Logging(logger.NopLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("first page"))
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				t.Errorf("Flush() error = %v", err)
+			}
 		}))
 
-		req := httptest.NewRequest("GET", "/", nil)
 		rec := httptest.NewRecorder()
-
-		handler.ServeHTTP(rec, req)
-
-		if !strings.Contains(buf.String(), "panic") {
-			t.Error("panic not logged")
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if !rec.Flushed {
+			t.Error("expected the response flushed")
 		}
 	})
 }
@@ -216,12 +231,137 @@ func TestCORS(t *testing.T) {
 			t.Error("Access-Control-Allow-Methods not set")
 		}
 	})
+
+	t.Run("exposes the request ID by default", func(t *testing.T) {
+		handler := CORS([]string{"*"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", "https://example.com")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "X-Request-ID") {
+			t.Errorf("Access-Control-Expose-Headers = %q, want X-Request-ID", got)
+		}
+		if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Error("credentials allowed without AllowCredentials")
+		}
+	})
+
+	t.Run("configured methods and headers", func(t *testing.T) {
+		handler := CORSWithConfig(CORSConfig{
+			AllowedOrigins: []string{"https://allowed.com"},
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Content-Type", "X-Custom"},
+			ExposedHeaders: []string{"X-Request-ID", "X-Trace"},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest("OPTIONS", "/", nil)
+		req.Header.Set("Origin", "https://allowed.com")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		for name, want := range map[string]string{
+			"Access-Control-Allow-Methods":  "GET, POST",
+			"Access-Control-Allow-Headers":  "Content-Type, X-Custom",
+			"Access-Control-Expose-Headers": "X-Request-ID, X-Trace",
+		} {
+			if got := rec.Header().Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+	})
+
+	t.Run("credentials with explicit origins", func(t *testing.T) {
+		handler := CORSWithConfig(CORSConfig{
+			AllowedOrigins:   []string{"https://allowed.com"},
+			AllowCredentials: true,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		for origin, want := range map[string]string{
+			"https://allowed.com":    "true",
+			"https://disallowed.com": "",
+		} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Origin", origin)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != want {
+				t.Errorf("%s: Access-Control-Allow-Credentials = %q, want %q", origin, got, want)
+			}
+			if rec.Header().Get("Vary") != "Origin" {
+				t.Errorf("%s: Vary = %q, want Origin", origin, rec.Header().Get("Vary"))
+			}
+		}
+	})
+
+	t.Run("no credentials with any origin", func(t *testing.T) {
+		handler := CORSWithConfig(CORSConfig{
+			AllowedOrigins:   []string{"*"},
+			AllowCredentials: true,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", "https://example.com")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Error("credentials allowed with *")
+		}
+	})
+
+	t.Run("wildcard subdomains", func(t *testing.T) {
+		handler := CORS([]string{"https://*.example.com", "http://*.local.test:8080"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		tests := []struct {
+			origin  string
+			allowed bool
+		}{
+			{"https://app.example.com", true},
+			{"https://a.b.example.com", true},
+			{"https://App.Example.com", true},
+			{"http://dev.local.test:8080", true},
+			{"https://example.com", false},
+			{"https://.example.com", false},
+			{"http://app.example.com", false},
+			{"https://app.example.com:8443", false},
+			{"https://app.example.com.evil.com", false},
+			{"https://evilexample.com", false},
+			{"https://evil.com/.example.com", false},
+			{"https://evil.com?.example.com", false},
+			{"http://dev.local.test", false},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed && got != tt.origin {
+				t.Errorf("%s: Access-Control-Allow-Origin = %q, want the origin", tt.origin, got)
+			}
+			if !tt.allowed && got != "" {
+				t.Errorf("%s: should not be allowed, got %q", tt.origin, got)
+			}
+		}
+	})
+}
+
+// newTestRateLimit returns a rate limiting middleware whose limiter is
+// closed when the test ends.
+func newTestRateLimit(t *testing.T, requestsPerMinute, burst int, rules []RateLimitRule, reg metrics.Registry) Middleware {
+	limiter := NewRateLimiter(requestsPerMinute, burst, rules, 0)
+	t.Cleanup(limiter.Close)
+	return limiter.Middleware(reg)
 }
 
 // TestRateLimit verifies rate limiting.
 func TestRateLimit(t *testing.T) {
 	t.Run("allows requests under limit", func(t *testing.T) {
-		handler := RateLimit(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := newTestRateLimit(t, 10, 0, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}))
 
@@ -239,7 +379,7 @@ func TestRateLimit(t *testing.T) {
 	})
 
 	t.Run("blocks requests over limit", func(t *testing.T) {
-		handler := RateLimit(3)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := newTestRateLimit(t, 3, 0, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}))
 
@@ -260,14 +400,34 @@ func TestRateLimit(t *testing.T) {
 		}
 	})
 
+	t.Run("counts rejections", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		handler := newTestRateLimit(t, 1, 0, nil, reg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.168.1.4:12345"
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		var out strings.Builder
+		reg.WritePrometheus(&out)
+		if !strings.Contains(out.String(), "humanmark_rate_limited_total 2\n") {
+			t.Errorf("expected 2 rejections, got:\n%s", out.String())
+		}
+	})
+
 	t.Run("skips rate limiting for health checks", func(t *testing.T) {
-		handler := RateLimit(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := newTestRateLimit(t, 1, 0, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}))
 
 		// Make many health check requests
 		for i := 0; i < 10; i++ {
-			req := httptest.NewRequest("GET", "/health", nil)
+			path := []string{"/health", "/health/live", "/health/ready", "/metrics"}[i%4]
+			req := httptest.NewRequest("GET", path, nil)
 			req.RemoteAddr = "192.168.1.3:12345"
 			rec := httptest.NewRecorder()
 
@@ -280,7 +440,7 @@ func TestRateLimit(t *testing.T) {
 	})
 
 	t.Run("rate limits per IP", func(t *testing.T) {
-		handler := RateLimit(2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := newTestRateLimit(t, 2, 0, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		}))
 
@@ -306,62 +466,360 @@ func TestRateLimit(t *testing.T) {
 			t.Error("different IP should not be rate limited")
 		}
 	})
+
+	t.Run("rate limits per API key with its own limit", func(t *testing.T) {
+		handler := newTestRateLimit(t, 1, 0, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		request := func(key *repository.APIKey) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "10.0.0.3:12345" // Shared by every request
+			if key != nil {
+				req = req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			return rec
+		}
+
+		// The IP's own limit is used up first
+		request(nil)
+		if rec := request(nil); rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("anonymous request 2 should be rate limited, got %d", rec.Code)
+		}
+
+		premium := &repository.APIKey{ID: "premium", RateLimitPerMinute: 3}
+		for i := 0; i < 4; i++ {
+			rec := request(premium)
+			if i < 3 && rec.Code != http.StatusOK {
+				t.Errorf("premium request %d should be allowed, got %d", i, rec.Code)
+			}
+			if i == 3 && rec.Code != http.StatusTooManyRequests {
+				t.Errorf("premium request %d should be rate limited, got %d", i, rec.Code)
+			}
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != "3" {
+				t.Errorf("expected X-RateLimit-Limit 3, got %q", got)
+			}
+		}
+
+		// Keys without an override get the default limit
+		if rec := request(&repository.APIKey{ID: "basic"}); rec.Code != http.StatusOK {
+			t.Errorf("basic key should have its own bucket, got %d", rec.Code)
+		}
+		if rec := request(&repository.APIKey{ID: "basic"}); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("basic key request 2 should be rate limited, got %d", rec.Code)
+		}
+	})
 }
 
-// TestGetClientIP verifies client IP extraction.
-func TestGetClientIP(t *testing.T) {
-	tests := []struct {
-		name        string
-		remoteAddr  string
-		xff         string
-		xRealIP     string
-		expectedIP  string
-	}{
-		{
-			name:       "uses RemoteAddr when no headers",
-			remoteAddr: "192.168.1.1:12345",
-			expectedIP: "192.168.1.1",
-		},
-		{
-			name:       "uses X-Forwarded-For first IP",
-			remoteAddr: "10.0.0.1:12345",
-			xff:        "203.0.113.195, 70.41.3.18, 150.172.238.178",
-			expectedIP: "203.0.113.195",
-		},
-		{
-			name:       "uses X-Real-IP",
-			remoteAddr: "10.0.0.1:12345",
-			xRealIP:    "203.0.113.50",
-			expectedIP: "203.0.113.50",
-		},
-		{
-			name:       "X-Forwarded-For takes precedence over X-Real-IP",
-			remoteAddr: "10.0.0.1:12345",
-			xff:        "203.0.113.195",
-			xRealIP:    "203.0.113.50",
-			expectedIP: "203.0.113.195",
-		},
+// TestRateLimitTokenBucket verifies refill, burst and headers against a
+// fake clock.
+func TestRateLimitTokenBucket(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	newHandler := func(requestsPerMinute, burst int) (http.Handler, *time.Time) {
+		now := start
+		limiter := newRateLimiter(requestsPerMinute, burst, nil, 0)
+		limiter.now = func() time.Time { return now }
+		handler := limiter.Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		return handler, &now
+	}
+	request := func(handler http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.2.1:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	allowed := func(handler http.Handler, n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			if request(handler).Code == http.StatusOK {
+				count++
+			}
+		}
+		return count
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", "/", nil)
-			req.RemoteAddr = tt.remoteAddr
-			if tt.xff != "" {
-				req.Header.Set("X-Forwarded-For", tt.xff)
+	t.Run("no double burst across a minute boundary", func(t *testing.T) {
+		handler, now := newHandler(60, 0)
+
+		// A fixed window opened here would reset a minute later
+		request(handler)
+		*now = start.Add(59 * time.Second)
+		if got := allowed(handler, 60); got != 60 {
+			t.Fatalf("allowed %d requests before the boundary, want 60", got)
+		}
+
+		// One second later a fixed window would allow 60 more
+		*now = start.Add(60*time.Second + time.Millisecond)
+		if got := allowed(handler, 60); got != 1 {
+			t.Errorf("allowed %d requests after the boundary, want 1", got)
+		}
+	})
+
+	t.Run("refills at the per-minute rate", func(t *testing.T) {
+		handler, now := newHandler(60, 0)
+
+		allowed(handler, 60)
+		*now = start.Add(10 * time.Second)
+		if got := allowed(handler, 20); got != 10 {
+			t.Errorf("allowed %d requests after 10s, want 10", got)
+		}
+	})
+
+	t.Run("burst caps requests at once", func(t *testing.T) {
+		handler, now := newHandler(60, 5)
+
+		if got := allowed(handler, 10); got != 5 {
+			t.Errorf("allowed %d requests at once, want 5", got)
+		}
+
+		// A long wait only refills the bucket to the burst
+		*now = start.Add(time.Hour)
+		if got := allowed(handler, 10); got != 5 {
+			t.Errorf("allowed %d requests after an hour, want 5", got)
+		}
+	})
+
+	t.Run("sets headers on every response", func(t *testing.T) {
+		// 6 a minute is one token every 10 seconds
+		handler, now := newHandler(6, 3)
+
+		assertHeaders := func(rec *httptest.ResponseRecorder, remaining, reset string) {
+			t.Helper()
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != "6" {
+				t.Errorf("X-RateLimit-Limit = %q, want 6", got)
 			}
-			if tt.xRealIP != "" {
-				req.Header.Set("X-Real-IP", tt.xRealIP)
+			if got := rec.Header().Get("X-RateLimit-Remaining"); got != remaining {
+				t.Errorf("X-RateLimit-Remaining = %q, want %s", got, remaining)
 			}
+			if got := rec.Header().Get("X-RateLimit-Reset"); got != reset {
+				t.Errorf("X-RateLimit-Reset = %q, want %s", got, reset)
+			}
+		}
+
+		assertHeaders(request(handler), "2", "10")
+		assertHeaders(request(handler), "1", "20")
+		assertHeaders(request(handler), "0", "30")
+
+		*now = start.Add(4 * time.Second)
+		rec := request(handler)
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %d", rec.Code)
+		}
+		assertHeaders(rec, "0", "26")
+		if got := rec.Header().Get("Retry-After"); got != "6" {
+			t.Errorf("Retry-After = %q, want 6", got)
+		}
+		if p := checkProblem(t, rec, http.StatusTooManyRequests, "rate_limited"); p.RetryAfter != 6 {
+			t.Errorf("retry_after = %d, want 6", p.RetryAfter)
+		}
+
+		*now = start.Add(10 * time.Second)
+		if rec := request(handler); rec.Code != http.StatusOK {
+			t.Errorf("request after Retry-After should be allowed, got %d", rec.Code)
+		}
+	})
+}
 
-			ip := getClientIP(req)
-			if ip != tt.expectedIP {
-				t.Errorf("expected %s, got %s", tt.expectedIP, ip)
+// TestRateLimiterBounds verifies the bucket cap and the refilled-bucket
+// sweep.
+func TestRateLimiterBounds(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("caps buckets", func(t *testing.T) {
+		limiter := newRateLimiter(60, 0, nil, 100)
+		for i := 0; i < 1000; i++ {
+			limiter.take("ip:10.0."+formatInt(i/256)+"."+formatInt(i%256), 60, 0)
+			if len(limiter.buckets) > 100 {
+				t.Fatalf("%d buckets after %d clients, want at most 100", len(limiter.buckets), i+1)
 			}
-		})
+		}
+	})
+
+	t.Run("evicts the fullest bucket sampled", func(t *testing.T) {
+		now := start
+		limiter := newRateLimiter(60, 0, nil, evictionSample)
+		limiter.now = func() time.Time { return now }
+
+		// Every client but one spends its whole bucket
+		limiter.take("idle", 60, 0)
+		for i := 1; i < evictionSample; i++ {
+			for j := 0; j < 60; j++ {
+				limiter.take("busy"+formatInt(i), 60, 0)
+			}
+		}
+		limiter.take("new", 60, 0)
+
+		if _, ok := limiter.buckets["idle"]; ok {
+			t.Error("the nearly full bucket should have been evicted")
+		}
+		if len(limiter.buckets) != evictionSample {
+			t.Errorf("%d buckets, want %d", len(limiter.buckets), evictionSample)
+		}
+	})
+
+	t.Run("sweeps refilled buckets", func(t *testing.T) {
+		now := start
+		limiter := newRateLimiter(60, 0, nil, 0)
+		limiter.now = func() time.Time { return now }
+
+		limiter.take("a", 60, 0)
+		for j := 0; j < 30; j++ {
+			limiter.take("b", 60, 0)
+		}
+		now = start.Add(5 * time.Second)
+		limiter.sweep()
+
+		if _, ok := limiter.buckets["a"]; ok {
+			t.Error("refilled bucket a was kept")
+		}
+		if _, ok := limiter.buckets["b"]; !ok {
+			t.Error("bucket b is still refilling and should be kept")
+		}
+	})
+}
+
+// TestRateLimiterClose verifies that Close stops the cleanup goroutine,
+// counting the process's goroutines before and after.
+func TestRateLimiterClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	limiters := make([]*RateLimiter, 100)
+	for i := range limiters {
+		limiters[i] = NewRateLimiter(60, 0, nil, 0)
+	}
+	if got := runtime.NumGoroutine() - before; got < 100 {
+		t.Fatalf("%d goroutines started for 100 limiters, want 100", got)
+	}
+
+	// Closing twice must neither panic nor block
+	for _, limiter := range limiters {
+		limiter.Close()
+		limiter.Close()
+	}
+
+	// Close returns once done is closed, a moment before the goroutine
+	// is gone
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if leaked := runtime.NumGoroutine() - before; leaked > 0 {
+		t.Errorf("%d goroutines still running after Close", leaked)
+	}
+
+	// A closed limiter still limits
+	handler := limiters[0].Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Header().Get("X-RateLimit-Limit") != "60" {
+		t.Error("closed limiter stopped limiting")
 	}
 }
 
+// TestRateLimitRules verifies per-route limits: the most specific rule wins,
+// each rule has its own bucket per client, and unmatched requests fall back
+// to the global limit.
+func TestRateLimitRules(t *testing.T) {
+	newHandler := func(rules ...RateLimitRule) http.Handler {
+		return newTestRateLimit(t, 3, 0, rules, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	request := func(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.168.3.1:12345"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	allowed := func(handler http.Handler, method, path string, n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			if request(handler, method, path).Code == http.StatusOK {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("independent buckets per rule", func(t *testing.T) {
+		handler := newHandler(
+			RateLimitRule{Pattern: "POST /verify/batch", Limit: 1},
+			RateLimitRule{Pattern: "POST /verify", Limit: 2},
+		)
+
+		if got := allowed(handler, "POST", "/verify/batch", 3); got != 1 {
+			t.Errorf("POST /verify/batch: allowed %d, want 1", got)
+		}
+		if got := allowed(handler, "POST", "/verify", 3); got != 2 {
+			t.Errorf("POST /verify: allowed %d, want 2", got)
+		}
+		// Neither rule spent the global bucket
+		if got := allowed(handler, "GET", "/verify", 4); got != 3 {
+			t.Errorf("GET /verify: allowed %d, want the global 3", got)
+		}
+	})
+
+	t.Run("most specific rule wins", func(t *testing.T) {
+		handler := newHandler(
+			RateLimitRule{Pattern: "/verify/", Limit: 5},
+			RateLimitRule{Pattern: "GET /verify/{id}", Limit: 2},
+			RateLimitRule{Pattern: "GET /verify/stats", Limit: 1},
+		)
+
+		tests := []struct {
+			method, path string
+			want         string
+		}{
+			{"GET", "/verify/stats", "1"},
+			{"GET", "/verify/abc", "2"},
+			{"HEAD", "/verify/abc", "2"},
+			{"DELETE", "/verify/abc", "5"},
+			{"GET", "/verify/abc/raw", "5"},
+			{"GET", "/verify", "3"},
+		}
+		for _, tt := range tests {
+			rec := request(handler, tt.method, tt.path)
+			if got := rec.Header().Get("X-RateLimit-Limit"); got != tt.want {
+				t.Errorf("%s %s: X-RateLimit-Limit = %q, want %q", tt.method, tt.path, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("rule limit replaces API key limit", func(t *testing.T) {
+		handler := newHandler(RateLimitRule{Pattern: "POST /verify/batch", Limit: 1})
+		key := &repository.APIKey{ID: "premium", RateLimitPerMinute: 100}
+
+		for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			req := httptest.NewRequest("POST", "/verify/batch", nil)
+			req = req.WithContext(context.WithValue(req.Context(), logger.ContextKeyAPIKey, key))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != want {
+				t.Errorf("request %d: got %d, want %d", i, rec.Code, want)
+			}
+		}
+	})
+
+	t.Run("invalid pattern panics", func(t *testing.T) {
+		for _, pattern := range []string{"", "verify", "post /verify", "POST /verify extra", "/a//b", "/{rest...}/b", "/v{id}"} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("pattern %q: expected a panic", pattern)
+					}
+				}()
+				newHandler(RateLimitRule{Pattern: pattern, Limit: 1})
+			}()
+		}
+	})
+}
+
 // TestChain verifies middleware chaining.
 func TestChain(t *testing.T) {
 	var order []string
@@ -403,6 +861,44 @@ func TestChain(t *testing.T) {
 	}
 }
 
+// TestBypass tests that only the listed routes skip the wrapped middleware.
+func TestBypass(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /verify/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("export"))
+	})
+	mux.HandleFunc("GET /verify/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("result"))
+	})
+	buffered := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Buffered", "true")
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := Bypass(mux, mux, "GET /verify/export")(buffered(mux))
+
+	tests := []struct {
+		target   string
+		buffered string
+		body     string
+	}{
+		{"/verify/export", "", "export"},
+		{"/verify/abc123", "true", "result"},
+		{"/missing", "true", "404 page not found\n"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+		if got := rec.Header().Get("X-Buffered"); got != tt.buffered {
+			t.Errorf("%s: X-Buffered = %q, want %q", tt.target, got, tt.buffered)
+		}
+		if rec.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.target, rec.Body.String(), tt.body)
+		}
+	}
+}
+
 // TestMaxBodySize verifies request body size limiting.
 func TestMaxBodySize(t *testing.T) {
 	t.Run("allows small body", func(t *testing.T) {
@@ -428,6 +924,107 @@ func TestMaxBodySize(t *testing.T) {
 	})
 }
 
+// TestMaxResponseSize verifies oversized responses are truncated.
+func TestMaxResponseSize(t *testing.T) {
+	serve := func(limit int64, contentType, body string) (*httptest.ResponseRecorder, error) {
+		var writeErr error
+		handler := MaxResponseSize(limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusCreated)
+			_, writeErr = w.Write([]byte(body))
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		return rec, writeErr
+	}
+
+	t.Run("passes responses within the limit", func(t *testing.T) {
+		body := strings.Repeat("x", 40)
+		rec, err := serve(40, "text/plain", body)
+		if err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+		if rec.Code != http.StatusCreated || rec.Body.String() != body {
+			t.Errorf("got %d %q, want 201 with the body unchanged", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("X-Response-Truncated") != "" {
+			t.Error("unexpected X-Response-Truncated header")
+		}
+	})
+
+	t.Run("truncates at the limit", func(t *testing.T) {
+		rec, err := serve(40, "text/plain", strings.Repeat("x", 100))
+		if !errors.Is(err, ErrResponseTruncated) {
+			t.Errorf("expected ErrResponseTruncated, got %v", err)
+		}
+		if rec.Body.Len() != 40 {
+			t.Errorf("expected 40 bytes, got %d", rec.Body.Len())
+		}
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status 201, got %d", rec.Code)
+		}
+		if rec.Header().Get("X-Response-Truncated") != "true" {
+			t.Error("expected X-Response-Truncated: true")
+		}
+	})
+
+	t.Run("keeps JSON valid", func(t *testing.T) {
+		rec, _ := serve(20, "application/json", `{"scores":[0.1,0.2,0.3,0.4]}`)
+		if got := rec.Body.String(); got != `{"scores":[0.1,0.2]}` {
+			t.Errorf("got %s", got)
+		}
+		if rec.Header().Get("X-Response-Truncated") != "true" {
+			t.Error("expected X-Response-Truncated: true")
+		}
+	})
+
+	t.Run("zero disables the limit", func(t *testing.T) {
+		body := strings.Repeat("x", 100)
+		rec, err := serve(0, "text/plain", body)
+		if err != nil || rec.Body.String() != body {
+			t.Errorf("expected body unchanged, got %d bytes, %v", rec.Body.Len(), err)
+		}
+	})
+}
+
+// TestTruncateJSON verifies JSON is cut at array element boundaries.
+func TestTruncateJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		limit int
+		want  string
+	}{
+		{"array elements", `[1,2,3,4,5]`, 6, `[1,2]`},
+		{"nested arrays and objects", `{"d":{"s":[{"t":"x"},{"t":"y"}]},"v":1}`, 30, `{"d":{"s":[{"t":"x"}]}}`},
+		{"commas in strings", `["a,b","c,d"]`, 9, `["a,b"]`},
+		{"first element too big", `{"s":["aaaaaaaaaa"]}`, 12, `{"s":[]}`},
+		{"escaped quotes", `["a\",b","c"]`, 10, `["a\",b"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := truncateJSON([]byte(tt.body[:tt.limit]))
+			if !ok {
+				t.Fatal("expected a truncation point")
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if len(got) > tt.limit || !json.Valid(got) {
+				t.Errorf("%s is not valid JSON within %d bytes", got, tt.limit)
+			}
+		})
+	}
+
+	t.Run("no array", func(t *testing.T) {
+		if _, ok := truncateJSON([]byte(`{"text":"aaaa`)); ok {
+			t.Error("expected no truncation point")
+		}
+	})
+}
+
 // TestFormatInt verifies integer formatting.
 func TestFormatInt(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RateLimitRule sets the rate limit for requests matching a route pattern,
+// so that expensive routes can be limited harder than the rest.
+type RateLimitRule struct {
+	// Pattern is "[METHOD ]PATH" with http.ServeMux syntax: a PATH ending
+	// in "/" matches everything under it, {name} matches one path segment
+	// and {name...} the rest of the path. GET also matches HEAD; no METHOD
+	// matches every method.
+	Pattern string
+
+	// Limit is requests per minute per client
+	Limit int
+}
+
+// routeRule is a RateLimitRule compiled for matching.
+type routeRule struct {
+	pattern  string
+	method   string
+	segments []string // "" is a single-segment wildcard
+	prefix   bool     // Matches any path below segments
+	literals int
+	limit    int
+}
+
+// compileRouteRule parses rule's pattern.
+func compileRouteRule(rule RateLimitRule) (routeRule, error) {
+	fields := strings.Fields(rule.Pattern)
+	compiled := routeRule{limit: rule.Limit}
+
+	var path string
+	switch len(fields) {
+	case 1:
+		path = fields[0]
+	case 2:
+		compiled.method, path = fields[0], fields[1]
+		if strings.ToUpper(compiled.method) != compiled.method {
+			return routeRule{}, fmt.Errorf("rate limit pattern %q: method must be upper case", rule.Pattern)
+		}
+	default:
+		return routeRule{}, fmt.Errorf("rate limit pattern %q: must be [METHOD ]PATH", rule.Pattern)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return routeRule{}, fmt.Errorf("rate limit pattern %q: path must start with /", rule.Pattern)
+	}
+	compiled.pattern = strings.TrimSpace(compiled.method + " " + path)
+
+	segments := strings.Split(path[1:], "/")
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch {
+		case seg == "" && last:
+			// Trailing slash: everything under the path
+			compiled.prefix = true
+		case seg == "":
+			return routeRule{}, fmt.Errorf("rate limit pattern %q: empty path segment", rule.Pattern)
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "...}"):
+			if !last {
+				return routeRule{}, fmt.Errorf("rate limit pattern %q: {name...} must be last", rule.Pattern)
+			}
+			compiled.prefix = true
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			compiled.segments = append(compiled.segments, "")
+		case strings.ContainsAny(seg, "{}"):
+			return routeRule{}, fmt.Errorf("rate limit pattern %q: a wildcard must be a whole segment", rule.Pattern)
+		default:
+			compiled.segments = append(compiled.segments, seg)
+			compiled.literals++
+		}
+	}
+
+	return compiled, nil
+}
+
+// compileRouteRules compiles rules, most specific first, panicking on an
+// invalid pattern as http.ServeMux does.
+func compileRouteRules(rules []RateLimitRule) []routeRule {
+	compiled := make([]routeRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileRouteRule(rule)
+		if err != nil {
+			panic(err)
+		}
+		compiled = append(compiled, c)
+	}
+
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return compiled[i].moreSpecific(compiled[j])
+	})
+	return compiled
+}
+
+// moreSpecific orders rules by how narrowly they match: more path segments,
+// then more literal segments, then exact paths over prefixes, then rules
+// with a method over rules without. Ties go to the pattern that sorts
+// first, so the order never depends on how rules were listed.
+func (r routeRule) moreSpecific(other routeRule) bool {
+	switch {
+	case len(r.segments) != len(other.segments):
+		return len(r.segments) > len(other.segments)
+	case r.literals != other.literals:
+		return r.literals > other.literals
+	case r.prefix != other.prefix:
+		return !r.prefix
+	case (r.method != "") != (other.method != ""):
+		return r.method != ""
+	default:
+		return r.pattern < other.pattern
+	}
+}
+
+// matches reports whether the rule applies to req.
+func (r routeRule) matches(req *http.Request) bool {
+	if r.method != "" && r.method != req.Method && !(r.method == http.MethodGet && req.Method == http.MethodHead) {
+		return false
+	}
+
+	path := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if r.prefix {
+		// "/verify/" matches "/verify/" and below, but not "/verify"
+		if len(path) <= len(r.segments) {
+			return false
+		}
+	} else if len(path) != len(r.segments) {
+		return false
+	}
+
+	for i, seg := range r.segments {
+		if seg == "" {
+			if path[i] == "" {
+				return false
+			}
+		} else if path[i] != seg {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the most specific rule matching req, or nil.
+func (rl *RateLimiter) match(req *http.Request) *routeRule {
+	for i := range rl.rules {
+		if rl.rules[i].matches(req) {
+			return &rl.rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// clientIPKey holds the client IP RealIP worked out for a request.
+type clientIPKey struct{}
+
+// RealIP returns middleware that works out each request's client IP for
+// ClientIP. X-Forwarded-For and X-Real-IP are only believed from the
+// proxies in trusted, IPs or CIDR ranges; a request from any other address
+// is identified by that address, whatever headers it sends, so that
+// clients can't pick their own IP to get past the access lists or rate
+// limits. Invalid entries in trusted are skipped (config.Validate rejects
+// them).
+//
+// It runs before anything that identifies clients: logging, access
+// control and rate limiting.
+func RealIP(trusted []string) Middleware {
+	var proxies []netip.Prefix
+	for _, entry := range trusted {
+		if p, err := parsePrefixes([]string{entry}); err == nil {
+			proxies = append(proxies, p...)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := forwardedClientIP(r, proxies)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+// ClientIP returns the client IP address of the request, as RealIP worked
+// it out, or the address the request came from if RealIP didn't run. The
+// rate limiter, access lists and request logs all identify clients by it.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// forwardedClientIP returns the client IP of a request that may have come
+// through proxies. Each proxy appends the address it got the request from
+// to X-Forwarded-For, so the list is read from the right, past the
+// trusted proxies, and the first other address is the client; anything to
+// its left was sent by the client and can't be believed. X-Real-IP is used
+// when there is no X-Forwarded-For.
+func forwardedClientIP(r *http.Request, proxies []netip.Prefix) string {
+	ip := remoteIP(r)
+	if !isTrustedProxy(proxies, ip) {
+		return ip
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !isTrustedProxy(proxies, hop) {
+				break
+			}
+		}
+		return ip
+	}
+
+	// Set by nginx
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+	return ip
+}
+
+// isTrustedProxy reports whether ip is in one of the trusted proxy ranges.
+func isTrustedProxy(proxies []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && containsAddr(proxies, addr.Unmap())
+}
+
+// remoteIP returns the IP the request came from, without the port.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestClientIP verifies client IP extraction behind trusted proxies.
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		expectedIP string
+	}{
+		{
+			name:       "uses RemoteAddr when no headers",
+			remoteAddr: "192.168.1.1:12345",
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name:       "uses X-Forwarded-For from a trusted proxy",
+			remoteAddr: "10.0.0.1:12345",
+			xff:        []string{"203.0.113.195"},
+			expectedIP: "203.0.113.195",
+		},
+		{
+			name:       "skips trusted proxies in X-Forwarded-For",
+			remoteAddr: "10.0.0.1:12345",
+			xff:        []string{"203.0.113.195, 10.0.0.7", "10.0.0.8"},
+			expectedIP: "203.0.113.195",
+		},
+		{
+			name:       "ignores addresses the client sent in X-Forwarded-For",
+			remoteAddr: "10.0.0.1:12345",
+			xff:        []string{"127.0.0.1, 70.41.3.18, 203.0.113.195"},
+			expectedIP: "203.0.113.195",
+		},
+		{
+			name:       "uses X-Real-IP from a trusted proxy",
+			remoteAddr: "10.0.0.1:12345",
+			xRealIP:    "203.0.113.50",
+			expectedIP: "203.0.113.50",
+		},
+		{
+			name:       "X-Forwarded-For takes precedence over X-Real-IP",
+			remoteAddr: "10.0.0.1:12345",
+			xff:        []string{"203.0.113.195"},
+			xRealIP:    "203.0.113.50",
+			expectedIP: "203.0.113.195",
+		},
+		{
+			name:       "ignores X-Forwarded-For from an untrusted peer",
+			remoteAddr: "198.51.100.9:12345",
+			xff:        []string{"10.0.0.5"},
+			expectedIP: "198.51.100.9",
+		},
+		{
+			name:       "ignores X-Real-IP from an untrusted peer",
+			remoteAddr: "[2001:db8::9]:12345",
+			xRealIP:    "10.0.0.5",
+			expectedIP: "2001:db8::9",
+		},
+		{
+			name:       "trusts IPv4-mapped proxy addresses",
+			remoteAddr: "[::ffff:10.0.0.1]:12345",
+			xff:        []string{"203.0.113.195"},
+			expectedIP: "203.0.113.195",
+		},
+	}
+
+	realIP := RealIP([]string{"10.0.0.0/8", "not-an-ip"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, xff := range tt.xff {
+				req.Header.Add("X-Forwarded-For", xff)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+
+			var ip string
+			realIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if ip != tt.expectedIP {
+				t.Errorf("expected %s, got %s", tt.expectedIP, ip)
+			}
+		})
+	}
+
+	t.Run("ignores headers without RealIP", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "198.51.100.9:12345"
+		req.Header.Set("X-Forwarded-For", "10.0.0.5")
+		req.Header.Set("X-Real-IP", "10.0.0.6")
+		if ip := ClientIP(req); ip != "198.51.100.9" {
+			t.Errorf("expected 198.51.100.9, got %s", ip)
+		}
+	})
+}
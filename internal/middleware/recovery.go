@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// modulePrefix starts the names of this module's functions in a stack
+// trace.
+const modulePrefix = "github.com/humanmark/humanmark/"
+
+// recoveryFrame starts the name of Recovery's handler in a stack trace;
+// the frames outside it are the same for every panic.
+const recoveryFrame = modulePrefix + "internal/middleware.RecoveryWithConfig."
+
+// RecoveryConfig configures RecoveryWithConfig.
+type RecoveryConfig struct {
+	// BodyCaptureBytes is how much of the request body, as far as the
+	// handler had read it, to log with a panic (0 = none). Bodies can hold
+	// personal data, so only set this while chasing a crash.
+	BodyCaptureBytes int
+
+	// Metrics counts panics by the function that panicked (nil records
+	// nothing)
+	Metrics metrics.Registry
+}
+
+// Recovery catches panics and returns a 500 error instead of crashing.
+// Panics are logged with a stack trace of our own code.
+func Recovery(log *logger.Logger) Middleware {
+	return RecoveryWithConfig(log, RecoveryConfig{})
+}
+
+// RecoveryWithConfig is Recovery with a captured body prefix and a panic
+// counter. The log entry has the panic value, the function that panicked
+// ("site"), the stack trimmed to this module's frames, and whatever
+// inner code recorded with logger.Annotate before the panic, such as the
+// content type and hash. The client only ever gets a generic 500.
+//
+// http.ErrAbortHandler is passed on, so the server still drops the
+// connection quietly.
+func RecoveryWithConfig(log *logger.Logger, cfg RecoveryConfig) Middleware {
+	reg := cfg.Metrics
+	if reg == nil {
+		reg = metrics.Discard
+	}
+	panics := reg.Counter("humanmark_panics_total", "Panics recovered, by the function that panicked.", "site")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, annotations := logger.WithAnnotations(r.Context())
+			stack := &panicStack{}
+			r = r.WithContext(context.WithValue(ctx, panicStackKey{}, stack))
+
+			var body *bodyCapture
+			if cfg.BodyCaptureBytes > 0 && r.Body != nil && r.Body != http.NoBody {
+				body = &bodyCapture{ReadCloser: r.Body, max: cfg.BodyCaptureBytes}
+				r.Body = body
+			}
+
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				// A panic re-raised from another goroutine (see Timeout)
+				// brings the stack where it happened
+				trace := stack.trace
+				if trace == nil {
+					trace = debug.Stack()
+				}
+				frames := trimStack(trace)
+				site := panicSite(frames)
+				panics.Inc(site)
+
+				// Get request ID for correlation
+				requestID := ""
+				if id := r.Context().Value(logger.ContextKeyRequestID); id != nil {
+					requestID = id.(string)
+				}
+
+				args := []any{
+					"error", err,
+					"site", site,
+					"request_id", requestID,
+					"method", r.Method,
+					"path", r.URL.Path,
+				}
+				args = append(args, annotations.Args()...)
+				if body != nil {
+					args = append(args, "body_prefix", string(body.buf))
+				}
+				args = append(args, "stack", strings.Join(frames, "\n"))
+				log.Error("panic recovered", args...)
+
+				// Never says what went wrong; that's in the log
+				problem.Error(w, http.StatusInternalServerError, "internal_error", "internal server error")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// panicStack holds the stack of a panic recovered on another goroutine
+// and re-raised on the serving one.
+type panicStack struct {
+	trace []byte
+}
+
+type panicStackKey struct{}
+
+// recordPanicStack saves the current goroutine's stack for Recovery. Call
+// it from the deferred recover of a goroutine whose panic is re-raised.
+func recordPanicStack(ctx context.Context) {
+	if stack, _ := ctx.Value(panicStackKey{}).(*panicStack); stack != nil && stack.trace == nil {
+		stack.trace = debug.Stack()
+	}
+}
+
+// trimStack turns a debug.Stack trace into "function file:line" frames
+// for this module's code between Recovery and the panic, innermost first,
+// without the module prefix.
+func trimStack(trace []byte) []string {
+	var frames []string
+	belowPanic := false
+	lines := strings.Split(string(trace), "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		// Each frame is a function line followed by a tab-indented
+		// file:line +offset line
+		function, location := lines[i], lines[i+1]
+		if !strings.HasPrefix(location, "\t") {
+			continue
+		}
+		i++
+
+		// Everything above the last panic is the recovery itself
+		if strings.HasPrefix(function, "panic(") {
+			frames = frames[:0]
+			belowPanic = true
+			continue
+		}
+		if belowPanic && strings.HasPrefix(function, recoveryFrame) {
+			break
+		}
+		if !strings.HasPrefix(function, modulePrefix) {
+			continue
+		}
+		// The arguments are just register and pointer values
+		if strings.HasSuffix(function, ")") {
+			if open := strings.LastIndex(function, "("); open > 0 {
+				function = function[:open]
+			}
+		}
+		location = strings.TrimSpace(location)
+		if offset := strings.LastIndex(location, " +0x"); offset != -1 {
+			location = location[:offset]
+		}
+		frames = append(frames, strings.TrimPrefix(function, modulePrefix)+" "+location)
+	}
+	return frames
+}
+
+// panicSite names the function that panicked, e.g.
+// "internal/service.parseMP4", from trimStack's frames.
+func panicSite(frames []string) string {
+	if len(frames) == 0 {
+		return "unknown"
+	}
+	function, _, _ := strings.Cut(frames[0], " ")
+	return function
+}
+
+// bodyCapture keeps the first max bytes read from a request body.
+type bodyCapture struct {
+	io.ReadCloser
+	buf []byte
+	max int
+}
+
+// Read reads from the body, keeping what fits in the capture.
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.max - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(n, room)]...)
+	}
+	return n, err
+}
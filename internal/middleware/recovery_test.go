@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// TestRecovery verifies panic recovery.
+func TestRecovery(t *testing.T) {
+	t.Run("recovers from panic", func(t *testing.T) {
+		log := logger.NopLogger()
+
+		handler := Recovery(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("test panic")
+		}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		rec := httptest.NewRecorder()
+
+		// Should not panic
+		handler.ServeHTTP(rec, req)
+
+		// Should return 500
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", rec.Code)
+		}
+	})
+
+	t.Run("logs panic", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewWithWriter("error", &buf)
+
+		handler := Recovery(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("test panic message")
+		}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if !strings.Contains(buf.String(), "panic") {
+			t.Error("panic not logged")
+		}
+	})
+
+	t.Run("logs the stack and annotations", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewWithWriter("error", &buf)
+
+		handler := Recovery(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger.Annotate(r.Context(), "content_type", "video")
+			logger.Annotate(r.Context(), "content_hash", "abc123")
+			parseForTest(nil)
+		}))
+
+		req := httptest.NewRequest("GET", "/verify", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		output := buf.String()
+		for _, want := range []string{
+			"site=internal/middleware.parseForTest",
+			"recovery_test.go:",
+			"content_type=video",
+			"content_hash=abc123",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in log, got: %s", want, output)
+			}
+		}
+		// Only our own frames, from the handler in
+		if strings.Contains(output, "net/http.") || strings.Contains(output, "RecoveryWithConfig") || strings.Contains(output, "TestRecovery.func3 ") {
+			t.Errorf("stack not trimmed: %s", output)
+		}
+	})
+
+	t.Run("keeps the response generic", func(t *testing.T) {
+		handler := RequestID()(Recovery(logger.NopLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("secret detail at /srv/humanmark/internal/service/video.go")
+		})))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "req-42")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		p := checkProblem(t, rec, http.StatusInternalServerError, "internal_error")
+		if p.Detail != "internal server error" || strings.Contains(rec.Body.String(), "secret") {
+			t.Errorf("unexpected body %q", rec.Body.String())
+		}
+		if p.Instance != problem.InstancePrefix+"req-42" {
+			t.Errorf("instance = %q, want the request ID", p.Instance)
+		}
+	})
+
+	t.Run("logs the stack of a panic under Timeout", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewWithOptions("error", &buf, logger.Options{Format: logger.FormatText})
+
+		inner := Timeout(time.Second, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parseForTest(nil)
+		}))
+		handler := Recovery(log)(inner)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", rec.Code)
+		}
+		if !strings.Contains(buf.String(), "site=internal/middleware.parseForTest") {
+			t.Errorf("expected the panicking goroutine's stack, got: %s", buf.String())
+		}
+	})
+
+	t.Run("logs a capped body prefix when enabled", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewWithOptions("error", &buf, logger.Options{Format: logger.FormatText})
+
+		handler := RecoveryWithConfig(log, RecoveryConfig{BodyCaptureBytes: 8})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+			panic("bad input")
+		}))
+
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader("0123456789abcdef"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if !strings.Contains(buf.String(), "body_prefix=01234567 ") {
+			t.Errorf("expected an 8 byte body prefix, got: %s", buf.String())
+		}
+	})
+
+	t.Run("doesn't log the body by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := logger.NewWithWriter("error", &buf)
+
+		handler := Recovery(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+			panic("bad input")
+		}))
+
+		req := httptest.NewRequest("POST", "/verify", strings.NewReader("private"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if strings.Contains(buf.String(), "private") {
+			t.Errorf("body logged: %s", buf.String())
+		}
+	})
+
+	t.Run("counts panics by site", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		handler := RecoveryWithConfig(logger.NopLogger(), RecoveryConfig{Metrics: reg})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parseForTest(nil)
+		}))
+
+		for i := 0; i < 2; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}
+
+		var out strings.Builder
+		reg.WritePrometheus(&out)
+		if !strings.Contains(out.String(), `humanmark_panics_total{site="internal/middleware.parseForTest"} 2`) {
+			t.Errorf("expected 2 panics at parseForTest, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("passes on ErrAbortHandler", func(t *testing.T) {
+		handler := Recovery(logger.NopLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+// parseForTest panics like a parser given a nil header.
+func parseForTest(header []byte) int {
+	return int(header[0])
+}
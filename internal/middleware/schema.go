@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// FieldType is the JSON type a schema field must have.
+type FieldType string
+
+const (
+	TypeString FieldType = "string"
+	TypeBool   FieldType = "bool"
+	TypeNumber FieldType = "number"
+	TypeArray  FieldType = "array"
+	TypeObject FieldType = "object"
+)
+
+// FieldRule describes one field of a JSON response.
+type FieldRule struct {
+	// Path is the field name; nested fields use dots, e.g. "details.ai_score"
+	Path string
+
+	// Type is the required JSON type
+	Type FieldType
+
+	// Required fails validation when the field is missing.
+	// Optional fields are only checked when present.
+	Required bool
+
+	// NonEmpty rejects empty strings and arrays
+	NonEmpty bool
+
+	// Min and Max bound numbers (inclusive, nil = unbounded)
+	Min *float64
+	Max *float64
+}
+
+// ResponseSchema declares the expected shape of a JSON response body.
+type ResponseSchema struct {
+	// Name identifies the schema in logs, e.g. "VerifyResponse"
+	Name string
+
+	// Status is the response status the schema applies to (default: 200).
+	// Other responses, like errors, pass through unchecked.
+	Status int
+
+	Fields []FieldRule
+}
+
+// Bounds returns pointers for FieldRule.Min and FieldRule.Max.
+func Bounds(min, max float64) (*float64, *float64) {
+	return &min, &max
+}
+
+// ValidateResponse checks response bodies against schema before they reach
+// the client. A non-conforming response is logged as an error and replaced
+// with a generic 500, so handler bugs surface in development and staging
+// instead of as malformed responses in client code.
+//
+// This buffers every response, so it's a safety net for non-production
+// environments - don't enable it in production.
+func ValidateResponse(schema ResponseSchema, log *logger.Logger) Middleware {
+	if schema.Status == 0 {
+		schema.Status = http.StatusOK
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status == schema.Status {
+				if errs := schema.Validate(rec.body.Bytes()); len(errs) > 0 {
+					log.WithContext(r.Context()).Error("response failed schema validation",
+						"schema", schema.Name,
+						"method", r.Method,
+						"path", r.URL.Path,
+						"errors", strings.Join(errs, "; "),
+					)
+
+					problem.Error(w, http.StatusInternalServerError, "invalid_response", "Internal server error")
+					return
+				}
+			}
+
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+		})
+	}
+}
+
+// Validate checks body against the schema and returns one message per violation.
+func (s ResponseSchema) Validate(body []byte) []string {
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return []string{"body is not a JSON object: " + err.Error()}
+	}
+
+	var errs []string
+	for _, f := range s.Fields {
+		value, ok := lookupPath(doc, f.Path)
+		if !ok {
+			if f.Required {
+				errs = append(errs, f.Path+": missing")
+			}
+			continue
+		}
+		if err := f.check(value); err != "" {
+			errs = append(errs, f.Path+": "+err)
+		}
+	}
+
+	return errs
+}
+
+// check validates a single value against the rule.
+func (f FieldRule) check(value any) string {
+	switch f.Type {
+	case TypeString:
+		v, ok := value.(string)
+		if !ok {
+			return "expected string"
+		}
+		if f.NonEmpty && v == "" {
+			return "must not be empty"
+		}
+	case TypeBool:
+		if _, ok := value.(bool); !ok {
+			return "expected bool"
+		}
+	case TypeNumber:
+		v, ok := value.(float64)
+		if !ok {
+			return "expected number"
+		}
+		if f.Min != nil && v < *f.Min {
+			return fmt.Sprintf("%s below minimum %s", formatFloat(v), formatFloat(*f.Min))
+		}
+		if f.Max != nil && v > *f.Max {
+			return fmt.Sprintf("%s above maximum %s", formatFloat(v), formatFloat(*f.Max))
+		}
+	case TypeArray:
+		v, ok := value.([]any)
+		if !ok {
+			return "expected array"
+		}
+		if f.NonEmpty && len(v) == 0 {
+			return "must not be empty"
+		}
+	case TypeObject:
+		if _, ok := value.(map[string]any); !ok {
+			return "expected object"
+		}
+	}
+	return ""
+}
+
+// lookupPath finds a dotted path in a decoded JSON object.
+// A null value counts as missing.
+func lookupPath(doc map[string]any, path string) (any, bool) {
+	var current any = doc
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[key]
+		if !ok || current == nil {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// formatFloat formats a number for validation messages.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// bufferedResponse holds a response until it has been validated.
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// Header returns the underlying writer's headers.
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+// WriteHeader records the status code.
+func (b *bufferedResponse) WriteHeader(code int) {
+	if !b.wroteHeader {
+		b.status = code
+		b.wroteHeader = true
+	}
+}
+
+// Write buffers the body.
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
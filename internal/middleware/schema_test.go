@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// testSchema mirrors the VerifyResponse schema.
+func testSchema() ResponseSchema {
+	unitMin, unitMax := Bounds(0, 1)
+	return ResponseSchema{
+		Name: "VerifyResponse",
+		Fields: []FieldRule{
+			{Path: "id", Type: TypeString, Required: true, NonEmpty: true},
+			{Path: "human", Type: TypeBool, Required: true},
+			{Path: "confidence", Type: TypeNumber, Required: true, Min: unitMin, Max: unitMax},
+			{Path: "content_type", Type: TypeString, Required: true, NonEmpty: true},
+			{Path: "details.ai_score", Type: TypeNumber, Min: unitMin, Max: unitMax},
+			{Path: "details.detectors", Type: TypeArray, NonEmpty: true},
+		},
+	}
+}
+
+// TestValidateResponse tests the schema validation middleware.
+func TestValidateResponse(t *testing.T) {
+	serve := func(status int, body string) (*httptest.ResponseRecorder, string) {
+		var logs bytes.Buffer
+		log := logger.NewWithWriter("debug", &logs)
+
+		handler := ValidateResponse(testSchema(), log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify", nil))
+		return rec, logs.String()
+	}
+
+	t.Run("passes valid response through", func(t *testing.T) {
+		body := `{"id":"abc","human":true,"confidence":0.8,"content_type":"text","details":{"ai_score":0.1,"detectors":["humanmark"]}}`
+		rec, logs := serve(http.StatusOK, body)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+		if rec.Body.String() != body {
+			t.Errorf("body was modified: %s", rec.Body.String())
+		}
+		if logs != "" {
+			t.Errorf("expected no logs, got %s", logs)
+		}
+	})
+
+	failures := []struct {
+		name    string
+		body    string
+		wantLog string
+	}{
+		{"missing id", `{"human":true,"confidence":0.8,"content_type":"text"}`, "id: missing"},
+		{"empty id", `{"id":"","human":true,"confidence":0.8,"content_type":"text"}`, "id: must not be empty"},
+		{"human not bool", `{"id":"a","human":"yes","confidence":0.8,"content_type":"text"}`, "human: expected bool"},
+		{"missing human", `{"id":"a","confidence":0.8,"content_type":"text"}`, "human: missing"},
+		{"confidence above 1", `{"id":"a","human":true,"confidence":1.5,"content_type":"text"}`, "confidence: 1.5 above maximum 1"},
+		{"confidence below 0", `{"id":"a","human":true,"confidence":-0.1,"content_type":"text"}`, "confidence: -0.1 below minimum 0"},
+		{"empty content type", `{"id":"a","human":true,"confidence":0.8,"content_type":""}`, "content_type: must not be empty"},
+		{"ai_score out of range", `{"id":"a","human":true,"confidence":0.8,"content_type":"text","details":{"ai_score":2,"detectors":["x"]}}`, "details.ai_score: 2 above maximum 1"},
+		{"empty detectors", `{"id":"a","human":true,"confidence":0.8,"content_type":"text","details":{"ai_score":0.2,"detectors":[]}}`, "details.detectors: must not be empty"},
+		{"not JSON", `not json`, "body is not a JSON object"},
+	}
+
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, logs := serve(http.StatusOK, tt.body)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("expected status 500, got %d", rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), "invalid_response") {
+				t.Errorf("expected sanitized error body, got %s", rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), tt.body) {
+				t.Error("invalid body leaked to client")
+			}
+			if !strings.Contains(logs, "response failed schema validation") || !strings.Contains(logs, tt.wantLog) {
+				t.Errorf("expected log containing %q, got %s", tt.wantLog, logs)
+			}
+		})
+	}
+
+	t.Run("ignores other statuses", func(t *testing.T) {
+		body := `{"error":"bad input","code":"validation_error"}`
+		rec, _ := serve(http.StatusBadRequest, body)
+
+		if rec.Code != http.StatusBadRequest || rec.Body.String() != body {
+			t.Errorf("expected error response untouched, got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+}
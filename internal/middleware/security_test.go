@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestSecurityHeaders verifies the baseline security headers.
+func TestSecurityHeaders(t *testing.T) {
+	serve := func(mw Middleware, handler http.HandlerFunc) http.Header {
+		rec := httptest.NewRecorder()
+		mw(handler).ServeHTTP(rec, httptest.NewRequest("GET", "/verify/abc", nil))
+		return rec.Header()
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	t.Run("sets every header", func(t *testing.T) {
+		h := serve(SecurityHeaders(DefaultContentSecurityPolicy, "no-referrer"), ok)
+
+		for name, want := range map[string]string{
+			"X-Content-Type-Options":  "nosniff",
+			"X-Frame-Options":         "DENY",
+			"Referrer-Policy":         "no-referrer",
+			"Content-Security-Policy": DefaultContentSecurityPolicy,
+		} {
+			if got := h.Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+	})
+
+	t.Run("empty policies are skipped", func(t *testing.T) {
+		h := serve(SecurityHeaders("", ""), ok)
+
+		if h.Get("Content-Security-Policy") != "" || h.Get("Referrer-Policy") != "" {
+			t.Errorf("empty policies were sent: %v", h)
+		}
+		if h.Get("X-Content-Type-Options") != "nosniff" {
+			t.Error("nosniff is always sent")
+		}
+	})
+
+	t.Run("handlers can override", func(t *testing.T) {
+		h := serve(SecurityHeaders(DefaultContentSecurityPolicy, "no-referrer"), func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Security-Policy", "default-src 'self'")
+			w.WriteHeader(http.StatusOK)
+		})
+
+		if got := h.Get("Content-Security-Policy"); got != "default-src 'self'" {
+			t.Errorf("Content-Security-Policy = %q, want the handler's", got)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/problem"
+)
+
+// Timeout gives each request timeout to finish: its context is cancelled
+// then, so detectors and provider calls stop, and the client gets a 504
+// Gateway Timeout JSON error. Handlers that learn what they're working on
+// can switch to a longer (or shorter) budget with WithTimeoutBudget;
+// budgets maps a content type ("video", "audio", ...) to its timeout,
+// counted from the start of the request.
+//
+// Responses are held until the handler returns, so a handler that is still
+// running at the deadline can't write into the 504; its writes fail with
+// http.ErrHandlerTimeout. Timeouts are counted in reg (nil records
+// nothing). A timeout of 0 or less disables the middleware.
+func Timeout(timeout time.Duration, budgets map[string]time.Duration, reg metrics.Registry) Middleware {
+	if reg == nil {
+		reg = metrics.Discard
+	}
+	timedOut := reg.Counter("humanmark_request_timeouts_total", "Requests that ran out of time.")
+
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			budget := &timeoutBudget{
+				parent:   r.Context(),
+				start:    start,
+				budgets:  budgets,
+				deadline: start.Add(timeout),
+			}
+			ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), timeoutBudgetKey{}, budget), timeout)
+			defer cancel()
+
+			// Starts with the headers set so far, so the handler sees
+			// X-Request-ID for its error responses
+			tw := &timeoutWriter{header: w.Header().Clone(), status: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					// Re-panic on the serving goroutine, where Recovery
+					// can catch it
+					if p := recover(); p != nil {
+						recordPanicStack(r.Context())
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			for {
+				select {
+				case p := <-panicked:
+					panic(p)
+				case <-done:
+					tw.mu.Lock()
+					defer tw.mu.Unlock()
+					for k, v := range tw.header {
+						w.Header()[k] = v
+					}
+					w.WriteHeader(tw.status)
+					w.Write(tw.body.Bytes())
+					return
+				case <-r.Context().Done():
+					// The client is gone; nobody will read a response
+					tw.abandon()
+					return
+				case <-timer.C:
+					// A budget set since may have moved the deadline
+					if remaining := time.Until(budget.currentDeadline()); remaining > 0 {
+						timer.Reset(remaining)
+						continue
+					}
+					tw.abandon()
+					timedOut.Inc()
+					problem.Error(w, http.StatusGatewayTimeout, "request_timeout", "request timed out")
+					return
+				}
+			}
+		})
+	}
+}
+
+// timeoutBudgetKey is the context key for a request's *timeoutBudget.
+type timeoutBudgetKey struct{}
+
+// timeoutBudget is the deadline Timeout holds a request to.
+type timeoutBudget struct {
+	parent  context.Context // The request context before Timeout
+	start   time.Time
+	budgets map[string]time.Duration
+
+	mu       sync.Mutex
+	deadline time.Time
+	budgeted bool // WithTimeoutBudget has moved the deadline
+}
+
+func (b *timeoutBudget) currentDeadline() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.deadline
+}
+
+// WithTimeoutBudget moves the request's deadline to its Timeout budget for
+// contentType, counted from the start of the request, and returns a context
+// with that deadline for the work that remains. ctx must be the request
+// context, or derived from it. If Timeout isn't in use or has no budget for
+// contentType, the deadline is unchanged. Called again, e.g. for each file
+// of an upload, it only moves the request's deadline later, so every
+// returned context can run to its own deadline.
+func WithTimeoutBudget(ctx context.Context, contentType string) (context.Context, context.CancelFunc) {
+	budget, ok := ctx.Value(timeoutBudgetKey{}).(*timeoutBudget)
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	d, ok := budget.budgets[contentType]
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	deadline := budget.start.Add(d)
+	budget.mu.Lock()
+	if !budget.budgeted || deadline.After(budget.deadline) {
+		budget.deadline = deadline
+		budget.budgeted = true
+	}
+	budget.mu.Unlock()
+
+	// Drop the default deadline but keep cancellation by the client
+	extended, cancel := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	stop := context.AfterFunc(budget.parent, cancel)
+	return extended, func() {
+		stop()
+		cancel()
+	}
+}
+
+// timeoutWriter holds a response until the handler returns. Once the
+// request has timed out, writes fail instead of reaching the client.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	abandoned   bool
+}
+
+// Header returns the handler's headers, copied to the response when it
+// finishes.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// WriteHeader records the status code.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.abandoned || tw.wroteHeader {
+		return
+	}
+	tw.status = code
+	tw.wroteHeader = true
+}
+
+// Write buffers the body, or fails with http.ErrHandlerTimeout if the
+// request has timed out.
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.abandoned {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.body.Write(p)
+}
+
+// abandon discards the response; later writes fail.
+func (tw *timeoutWriter) abandon() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.abandoned = true
+	tw.body.Reset()
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/metrics"
+)
+
+// TestTimeout verifies request deadlines, 504 responses and budgets.
+func TestTimeout(t *testing.T) {
+	// slow sleeps for delay unless the request is cancelled first; then it
+	// writes once the test closes responded, and reports how the write went
+	// on result
+	slow := func(delay time.Duration, responded <-chan struct{}, result chan<- error) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(delay):
+				w.Header().Set("X-Handler", "done")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"ok":true}`))
+			case <-r.Context().Done():
+				<-responded
+				_, err := w.Write([]byte(`{"late":true}`))
+				result <- err
+			}
+		})
+	}
+
+	t.Run("passes through fast responses", func(t *testing.T) {
+		handler := Timeout(time.Second, nil, nil)(slow(0, nil, nil))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		if rec.Code != http.StatusCreated || rec.Body.String() != `{"ok":true}` {
+			t.Errorf("got %d %s, want 201 {\"ok\":true}", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("X-Handler") != "done" {
+			t.Error("handler headers were not copied")
+		}
+	})
+
+	t.Run("times out slow handlers", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		responded, result := make(chan struct{}), make(chan error, 1)
+		handler := Timeout(20*time.Millisecond, nil, reg)(slow(5*time.Second, responded, result))
+
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify", nil))
+		close(responded)
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("took %s to time out", elapsed)
+		}
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+		checkProblem(t, rec, http.StatusGatewayTimeout, "request_timeout")
+
+		// The handler saw the cancellation and its late write failed
+		select {
+		case err := <-result:
+			if !errors.Is(err, http.ErrHandlerTimeout) {
+				t.Errorf("late write: got %v, want ErrHandlerTimeout", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("handler was not cancelled")
+		}
+		if strings.Contains(rec.Body.String(), "late") {
+			t.Errorf("late write reached the response: %s", rec.Body.String())
+		}
+
+		var out strings.Builder
+		reg.WritePrometheus(&out)
+		if !strings.Contains(out.String(), "humanmark_request_timeouts_total 1\n") {
+			t.Errorf("expected 1 timeout, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("budget extends the deadline", func(t *testing.T) {
+		budgets := map[string]time.Duration{"video": time.Second}
+		inner := slow(60*time.Millisecond, nil, nil)
+		handler := Timeout(20*time.Millisecond, budgets, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := WithTimeoutBudget(r.Context(), "video")
+			defer cancel()
+
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) < 500*time.Millisecond {
+				t.Errorf("deadline = %v, want about a second away", deadline)
+			}
+			inner.ServeHTTP(w, r.WithContext(ctx))
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify", nil))
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected 201 within the video budget, got %d", rec.Code)
+		}
+	})
+
+	t.Run("several budgets keep the longest", func(t *testing.T) {
+		budgets := map[string]time.Duration{"video": time.Second, "audio": 10 * time.Millisecond}
+		inner := slow(60*time.Millisecond, nil, nil)
+		handler := Timeout(20*time.Millisecond, budgets, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			video, cancelVideo := WithTimeoutBudget(r.Context(), "video")
+			defer cancelVideo()
+			_, cancelAudio := WithTimeoutBudget(r.Context(), "audio")
+			defer cancelAudio()
+			inner.ServeHTTP(w, r.WithContext(video))
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify", nil))
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected 201 within the video budget, got %d", rec.Code)
+		}
+	})
+
+	t.Run("no budget keeps the default", func(t *testing.T) {
+		budgets := map[string]time.Duration{"video": time.Second}
+		responded := make(chan struct{})
+		defer close(responded)
+		inner := slow(5*time.Second, responded, make(chan error, 1))
+		handler := Timeout(20*time.Millisecond, budgets, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := WithTimeoutBudget(r.Context(), "text")
+			defer cancel()
+			inner.ServeHTTP(w, r.WithContext(ctx))
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/verify", nil))
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected 504, got %d", rec.Code)
+		}
+	})
+
+	t.Run("budget without Timeout", func(t *testing.T) {
+		ctx, cancel := WithTimeoutBudget(context.Background(), "video")
+		defer cancel()
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("expected no deadline")
+		}
+	})
+
+	t.Run("client disconnect cancels budgeted work", func(t *testing.T) {
+		budgets := map[string]time.Duration{"video": time.Minute}
+		cancelled := make(chan struct{})
+		handler := Timeout(time.Minute, budgets, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := WithTimeoutBudget(r.Context(), "video")
+			defer cancel()
+			<-ctx.Done()
+			close(cancelled)
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/verify", nil).WithContext(ctx))
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("budgeted context outlived the client")
+		}
+	})
+
+	t.Run("re-panics on the serving goroutine", func(t *testing.T) {
+		handler := Timeout(time.Second, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want boom", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); ok {
+				t.Error("expected no deadline")
+			}
+		})
+		Timeout(0, nil, nil)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
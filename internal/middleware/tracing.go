@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/humanmark/humanmark/pkg/tracing"
+)
+
+// Routes finds the route pattern a request matches; *http.ServeMux
+// implements it.
+type Routes interface {
+	Handler(r *http.Request) (h http.Handler, pattern string)
+}
+
+// Tracing starts a server span for each request, named after the route it
+// matches in routes (e.g. "POST /verify") so that requests for different
+// IDs group together. A W3C traceparent header makes the span part of the
+// caller's trace. The span records the method, route and status code, and
+// counts 5xx responses as errors.
+//
+// Handlers and the detectors they call add child spans with
+// tracing.Start. A nil tracer records nothing.
+func Tracing(tracer tracing.Tracer, routes Routes) Middleware {
+	if tracer == nil {
+		tracer = tracing.Discard
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tracing.WithTracer(r.Context(), tracer)
+			if sc, ok := tracing.ParseTraceparent(r.Header.Get("traceparent")); ok {
+				ctx = tracing.ContextWithRemote(ctx, sc)
+			}
+
+			// The pattern's method is dropped: GET patterns also match HEAD,
+			// and patterns without one match any method
+			_, route := routes.Handler(r)
+			if _, path, ok := strings.Cut(route, " "); ok {
+				route = path
+			}
+			name := r.Method
+			if route != "" {
+				name += " " + route
+			}
+
+			ctx, span := tracer.Start(ctx, name,
+				tracing.String("http.request.method", r.Method),
+				tracing.String("http.route", route),
+				tracing.String("url.path", r.URL.Path),
+			)
+			defer span.End()
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			span.SetAttributes(tracing.Int("http.response.status_code", wrapped.statusCode))
+			if wrapped.statusCode >= 500 {
+				span.RecordError(fmt.Errorf("HTTP %d", wrapped.statusCode))
+			}
+		})
+	}
+}
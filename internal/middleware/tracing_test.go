@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/humanmark/humanmark/pkg/tracing"
+)
+
+// TestTracing verifies server spans, their attributes and traceparent
+// propagation.
+func TestTracing(t *testing.T) {
+	rec := tracing.NewRecorder()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /verify/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "lookup")
+		span.End()
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /verify", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	handler := Tracing(rec, mux)(mux)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/verify/abc123", nil)
+	req.Header.Set("traceparent", traceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+
+	if server.Name != "GET /verify/{id}" {
+		t.Errorf("span name = %q, want the route", server.Name)
+	}
+	for key, want := range map[string]any{
+		"http.request.method":       "GET",
+		"http.route":                "/verify/{id}",
+		"url.path":                  "/verify/abc123",
+		"http.response.status_code": http.StatusNotFound,
+	} {
+		if got := server.Attributes[key]; got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	if server.Err != nil {
+		t.Errorf("a 404 isn't a server error: %v", server.Err)
+	}
+
+	remote, _ := tracing.ParseTraceparent(traceparent)
+	if server.Parent != remote {
+		t.Errorf("server span parent = %v, want the caller's span", server.Parent)
+	}
+	if child.Parent != server.Context {
+		t.Error("handler span isn't a child of the server span")
+	}
+
+	t.Run("5xx is an error", func(t *testing.T) {
+		rec := tracing.NewRecorder()
+		Tracing(rec, mux)(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/verify", nil))
+
+		spans := rec.Spans()
+		if len(spans) != 1 || spans[0].Err == nil {
+			t.Fatalf("expected one failed span, got %+v", spans)
+		}
+		if spans[0].Parent.IsValid() {
+			t.Error("a request without traceparent should start a new trace")
+		}
+	})
+
+	t.Run("unmatched route", func(t *testing.T) {
+		rec := tracing.NewRecorder()
+		Tracing(rec, mux)(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+
+		if spans := rec.Spans(); len(spans) != 1 || spans[0].Name != "GET" {
+			t.Errorf("expected one span named GET, got %+v", spans)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+-- Verification jobs. Columns mirror repository.Job; the lists that are only
+-- ever read back whole (signals, AI phrases, webhook deliveries) live in the
+-- details document.
+CREATE TABLE jobs (
+    id                 TEXT COLLATE "C" PRIMARY KEY,
+    status             TEXT NOT NULL DEFAULT '',
+    error              TEXT NOT NULL DEFAULT '',
+    content_type       TEXT NOT NULL DEFAULT '',
+    human              BOOLEAN NOT NULL DEFAULT FALSE,
+    verdict            TEXT NOT NULL DEFAULT '',
+    confidence         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ai_score           DOUBLE PRECISION NOT NULL DEFAULT 0,
+    needs_review       BOOLEAN NOT NULL DEFAULT FALSE,
+    detectors          TEXT[] NOT NULL DEFAULT '{}',
+    content_hash       TEXT NOT NULL DEFAULT '',
+    fingerprint        BIGINT[],
+    analyzer_version   TEXT NOT NULL DEFAULT '',
+    config_fingerprint TEXT NOT NULL DEFAULT '',
+    tx_id              TEXT NOT NULL DEFAULT '',
+    anchored_at        TIMESTAMPTZ,
+    parent_id          TEXT NOT NULL DEFAULT '',
+    callback_url       TEXT NOT NULL DEFAULT '',
+    details            JSONB NOT NULL DEFAULT '{}',
+    created_at         TIMESTAMPTZ NOT NULL,
+    updated_at         TIMESTAMPTZ NOT NULL
+);
+
+-- Repeat submissions and ListFilter.ContentHash
+CREATE INDEX jobs_content_hash_idx ON jobs (content_hash);
+
+-- ListJobs pages newest first on (created_at, id)
+CREATE INDEX jobs_created_at_idx ON jobs (created_at DESC, id DESC);
+
+-- ListJobsByFingerprint looks for any shared MinHash value
+CREATE INDEX jobs_fingerprint_idx ON jobs USING GIN (fingerprint);
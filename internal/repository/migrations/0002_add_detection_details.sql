@@ -0,0 +1,4 @@
+-- The detection breakdown returned with detailed=true (repository.Details).
+-- NULL for jobs that haven't completed and for jobs stored before it was
+-- kept; those fall back to the signals and AI phrases in details.
+ALTER TABLE jobs ADD COLUMN detection_details JSONB;
@@ -0,0 +1,15 @@
+-- The job CreateOrGetJob returns for each content hash. Kept apart from jobs
+-- so async jobs, which learn their hash after creation, and page assets can
+-- still share content with earlier jobs.
+CREATE TABLE job_content_hashes (
+    content_hash TEXT PRIMARY KEY,
+    job_id       TEXT COLLATE "C" NOT NULL
+                 REFERENCES jobs (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED
+);
+
+-- Existing results become the ones later submissions are deduplicated to
+INSERT INTO job_content_hashes (content_hash, job_id)
+SELECT DISTINCT ON (content_hash) content_hash, id
+FROM jobs
+WHERE content_hash <> '' AND status IN ('', 'completed')
+ORDER BY content_hash, created_at, id;
@@ -0,0 +1,14 @@
+-- FindJobByContentHash wants the newest job for a hash
+DROP INDEX jobs_content_hash_idx;
+CREATE INDEX jobs_content_hash_created_at_idx ON jobs (content_hash, created_at DESC, id DESC);
+
+-- A claimed hash is handed to a new job when the analyzer version or
+-- configuration changes, so stale results aren't returned forever
+ALTER TABLE job_content_hashes
+    ADD COLUMN analyzer_version   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN config_fingerprint TEXT NOT NULL DEFAULT '';
+
+UPDATE job_content_hashes h
+SET analyzer_version = j.analyzer_version, config_fingerprint = j.config_fingerprint
+FROM jobs j
+WHERE j.id = h.job_id;
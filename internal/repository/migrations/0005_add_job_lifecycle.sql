@@ -0,0 +1,8 @@
+-- How many times a worker started an async job, and when the job reached
+-- completed or failed through UpdateJob. Finished async jobs from before
+-- these columns use their last update as the completion time.
+ALTER TABLE jobs ADD COLUMN attempt_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN completed_at TIMESTAMPTZ;
+
+UPDATE jobs SET completed_at = updated_at
+WHERE status IN ('completed', 'failed') AND updated_at > created_at;
@@ -0,0 +1,11 @@
+-- API keys (repository.APIKey). Only the SHA-256 of each key's secret is
+-- kept; revoked keys stay so their IDs aren't reused.
+CREATE TABLE api_keys (
+    id                    TEXT COLLATE "C" PRIMARY KEY,
+    name                  TEXT NOT NULL,
+    secret_hash           TEXT NOT NULL,
+    scopes                TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit_per_minute INTEGER NOT NULL DEFAULT 0,
+    created_at            TIMESTAMPTZ NOT NULL,
+    revoked_at            TIMESTAMPTZ
+);
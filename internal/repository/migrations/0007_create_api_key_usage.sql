@@ -0,0 +1,11 @@
+-- Daily API key usage rollups (repository.DailyUsage), one row per key,
+-- UTC day and content type. A day's totals are the sum of its rows.
+CREATE TABLE api_key_usage (
+    key_id         TEXT COLLATE "C" NOT NULL,
+    day            DATE NOT NULL,
+    content_type   TEXT NOT NULL,
+    requests       BIGINT NOT NULL DEFAULT 0,
+    bytes          BIGINT NOT NULL DEFAULT 0,
+    provider_calls BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day, content_type)
+);
@@ -0,0 +1,5 @@
+-- DeleteJob only marks a job deleted so the request returns quickly; the
+-- retention sweep (DeleteJobsOlderThan) purges marked rows for good.
+ALTER TABLE jobs ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX jobs_deleted_at_idx ON jobs (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,6 @@
+-- FindJobByNormalizedHash looks up text by its whitespace-insensitive hash.
+-- Jobs stored before this have none and are only found by content_hash.
+ALTER TABLE jobs ADD COLUMN normalized_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX jobs_normalized_hash_created_at_idx ON jobs (normalized_hash, created_at DESC, id DESC)
+    WHERE normalized_hash <> '';
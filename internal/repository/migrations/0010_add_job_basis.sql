@@ -0,0 +1,3 @@
+-- What each verdict rested on (local-only, local+N-external,
+-- conclusive-marker), kept for auditing. Jobs stored before this have none.
+ALTER TABLE jobs ADD COLUMN basis TEXT NOT NULL DEFAULT '';
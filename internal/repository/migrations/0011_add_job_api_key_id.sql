@@ -0,0 +1,6 @@
+-- The API key that submitted each job, so that listing, exporting and
+-- deleting results only reach the caller's own. Jobs stored before this
+-- belong to no key and are only listed for requests without one.
+ALTER TABLE jobs ADD COLUMN api_key_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX jobs_api_key_id_created_at_idx ON jobs (api_key_id, created_at DESC, id DESC);
@@ -0,0 +1,1016 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/humanmark/humanmark/pkg/minhash"
+)
+
+// Pool limits, used unless the connection string sets its own pool_*
+// parameters
+const (
+	postgresMaxConns        = 20
+	postgresMaxConnLifetime = 30 * time.Minute
+	postgresMaxConnIdleTime = 5 * time.Minute
+	postgresConnectTimeout  = 10 * time.Second
+)
+
+// postgresQueryTimeout bounds queries whose context has no deadline.
+const postgresQueryTimeout = 10 * time.Second
+
+// migrationLockID is the advisory lock that serializes migrations when
+// several instances start at once.
+const migrationLockID = 0x68756d616e6d6b // "humanmk"
+
+// migrationFiles are applied in name order; each name starts with its
+// version number.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// postgresRepository implements Repository using PostgreSQL.
+type postgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgres connects to PostgreSQL and applies any pending migrations.
+// It fails if the database is unreachable or a migration fails.
+func NewPostgres(ctx context.Context, connString string) (Repository, error) {
+	if connString == "" {
+		return nil, errors.New("database connection string is required")
+	}
+
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database connection string: %w", err)
+	}
+	if !strings.Contains(connString, "pool_max_conns") {
+		config.MaxConns = postgresMaxConns
+	}
+	if !strings.Contains(connString, "pool_max_conn_lifetime") {
+		config.MaxConnLifetime = postgresMaxConnLifetime
+	}
+	if !strings.Contains(connString, "pool_max_conn_idle_time") {
+		config.MaxConnIdleTime = postgresMaxConnIdleTime
+	}
+	if config.ConnConfig.ConnectTimeout == 0 {
+		config.ConnConfig.ConnectTimeout = postgresConnectTimeout
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return &postgresRepository{pool: pool}, nil
+}
+
+// migrate applies the embedded migrations that haven't run yet, all in one
+// transaction.
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, int64(migrationLockID)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	applied, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("migration %s: name must start with a version number", name)
+		}
+		if containsInt(applied, version) {
+			continue
+		}
+
+		sql, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// jobColumns are the columns scanJob reads, in order.
+const jobColumns = `id, status, error, content_type, human, verdict, confidence, ai_score,
+	needs_review, detectors, content_hash, fingerprint, analyzer_version, config_fingerprint,
+	tx_id, anchored_at, parent_id, callback_url, details, created_at, updated_at,
+	detection_details, attempt_count, completed_at, normalized_hash, basis, api_key_id`
+
+// jobDetails is the JSONB details column.
+type jobDetails struct {
+	// Signals and AIPhrases are only read, from jobs stored before the
+	// detection_details column
+	Signals   []Signal `json:"signals,omitempty"`
+	AIPhrases []Phrase `json:"ai_phrases,omitempty"`
+
+	DeliveryAttempts []DeliveryAttempt `json:"delivery_attempts,omitempty"`
+}
+
+// MaxDetailsSize caps a job's serialized Details. Larger details have their
+// longest lists shortened until they fit.
+const MaxDetailsSize = 64 << 10
+
+// marshalDetails encodes d for the detection_details column, halving the
+// longest list (a detector's sentences, the AI phrases, signals or skipped
+// detectors) until it fits in MaxDetailsSize. d itself isn't modified.
+func marshalDetails(d *Details) ([]byte, error) {
+	if d == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil || len(data) <= MaxDetailsSize {
+		return data, err
+	}
+
+	truncated := *d
+	truncated.Truncated = true
+	truncated.DetectorOutputs = append([]DetectorOutput(nil), d.DetectorOutputs...)
+	for len(data) > MaxDetailsSize && truncated.halveLongestList() {
+		if data, err = json.Marshal(&truncated); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// halveLongestList drops the second half of d's longest list. It reports
+// false when every list is empty.
+func (d *Details) halveLongestList() bool {
+	type list struct {
+		length int
+		cut    func(n int)
+	}
+	lists := []list{
+		{len(d.Signals), func(n int) { d.Signals = d.Signals[:n] }},
+		{len(d.AIPhrases), func(n int) { d.AIPhrases = d.AIPhrases[:n] }},
+		{len(d.Skipped), func(n int) { d.Skipped = d.Skipped[:n] }},
+	}
+	for i := range d.DetectorOutputs {
+		out := &d.DetectorOutputs[i]
+		lists = append(lists,
+			list{len(out.Sentences), func(n int) { out.Sentences = out.Sentences[:n] }},
+			list{len(out.Paragraphs), func(n int) { out.Paragraphs = out.Paragraphs[:n] }},
+		)
+	}
+
+	longest := lists[0]
+	for _, l := range lists[1:] {
+		if l.length > longest.length {
+			longest = l
+		}
+	}
+	if longest.length == 0 {
+		return false
+	}
+	longest.cut(longest.length / 2)
+	return true
+}
+
+// jobArgs returns job's column values in jobColumns order.
+func jobArgs(job Job) ([]any, error) {
+	details, err := json.Marshal(jobDetails{DeliveryAttempts: job.DeliveryAttempts})
+	if err != nil {
+		return nil, err
+	}
+	detectionDetails, err := marshalDetails(job.Details)
+	if err != nil {
+		return nil, err
+	}
+
+	detectors := job.Detectors
+	if detectors == nil {
+		detectors = []string{}
+	}
+
+	// BIGINT[] because PostgreSQL has no unsigned integers
+	var fingerprint []int64
+	if job.Fingerprint != nil {
+		fingerprint = make([]int64, len(job.Fingerprint))
+		for i, v := range job.Fingerprint {
+			fingerprint[i] = int64(v)
+		}
+	}
+
+	var anchoredAt, completedAt *time.Time
+	if !job.AnchoredAt.IsZero() {
+		anchoredAt = &job.AnchoredAt
+	}
+	if !job.CompletedAt.IsZero() {
+		completedAt = &job.CompletedAt
+	}
+
+	return []any{
+		job.ID, string(job.Status), job.Error, job.ContentType, job.Human, job.Verdict,
+		job.Confidence, job.AIScore, job.NeedsReview, detectors, job.ContentHash, fingerprint,
+		job.AnalyzerVersion, job.ConfigFingerprint, job.TxID, anchoredAt, job.ParentID,
+		job.CallbackURL, details, job.CreatedAt, job.UpdatedAt, detectionDetails,
+		job.AttemptCount, completedAt, job.NormalizedHash, job.Basis, job.APIKeyID,
+	}, nil
+}
+
+// scanJob reads a row selected with jobColumns.
+func scanJob(row pgx.Row) (Job, error) {
+	var (
+		job         Job
+		status      string
+		fingerprint []int64
+		anchoredAt  *time.Time
+		completedAt *time.Time
+		details     []byte
+
+		detectionDetails []byte
+	)
+	err := row.Scan(
+		&job.ID, &status, &job.Error, &job.ContentType, &job.Human, &job.Verdict,
+		&job.Confidence, &job.AIScore, &job.NeedsReview, &job.Detectors, &job.ContentHash, &fingerprint,
+		&job.AnalyzerVersion, &job.ConfigFingerprint, &job.TxID, &anchoredAt, &job.ParentID,
+		&job.CallbackURL, &details, &job.CreatedAt, &job.UpdatedAt, &detectionDetails,
+		&job.AttemptCount, &completedAt, &job.NormalizedHash, &job.Basis, &job.APIKeyID,
+	)
+	if err != nil {
+		return Job{}, err
+	}
+
+	job.Status = JobStatus(status)
+	if fingerprint != nil {
+		job.Fingerprint = make([]uint32, len(fingerprint))
+		for i, v := range fingerprint {
+			job.Fingerprint[i] = uint32(v)
+		}
+	}
+	if anchoredAt != nil {
+		job.AnchoredAt = *anchoredAt
+	}
+	if completedAt != nil {
+		job.CompletedAt = *completedAt
+	}
+
+	var d jobDetails
+	if err := json.Unmarshal(details, &d); err != nil {
+		return Job{}, fmt.Errorf("job %s: invalid details: %w", job.ID, err)
+	}
+	job.DeliveryAttempts = d.DeliveryAttempts
+
+	switch {
+	case detectionDetails != nil:
+		job.Details = new(Details)
+		if err := json.Unmarshal(detectionDetails, job.Details); err != nil {
+			return Job{}, fmt.Errorf("job %s: invalid detection details: %w", job.ID, err)
+		}
+	case d.Signals != nil || d.AIPhrases != nil:
+		job.Details = &Details{Signals: d.Signals, AIPhrases: d.AIPhrases}
+	}
+
+	return job, nil
+}
+
+// collectJobs scans every row selected with jobColumns.
+func collectJobs(rows pgx.Rows) ([]Job, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Job, error) {
+		return scanJob(row)
+	})
+}
+
+// queryContext bounds ctx by postgresQueryTimeout unless the caller already
+// set a deadline.
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, postgresQueryTimeout)
+}
+
+// CreateJob creates a new job in PostgreSQL.
+func (r *postgresRepository) CreateJob(ctx context.Context, job Job) (*Job, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	job.ID = generateID()
+	// PostgreSQL keeps microseconds; truncate so the returned job matches
+	// what's stored and its ListJobs cursor
+	job.CreatedAt = time.Now().Truncate(time.Microsecond)
+	job.UpdatedAt = job.CreatedAt
+
+	args, err := jobArgs(job)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.pool.Exec(ctx, insertJobSQL, args...); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// insertJobSQL inserts a job from jobArgs.
+const insertJobSQL = `INSERT INTO jobs (` + jobColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27)`
+
+// CreateOrGetJob claims job's content hash in job_content_hashes and
+// inserts the job in the same transaction. If another job with the same
+// analyzer version and config fingerprint already holds the hash, that job
+// is returned; one from other versions loses the claim. A concurrent claim
+// of the same hash blocks until the other transaction finishes, so only
+// one job is created.
+func (r *postgresRepository) CreateOrGetJob(ctx context.Context, job Job) (*Job, bool, error) {
+	if job.ContentHash == "" {
+		created, err := r.CreateJob(ctx, job)
+		return created, err == nil, err
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	job.ID = generateID()
+	job.CreatedAt = time.Now().Truncate(time.Microsecond)
+	job.UpdatedAt = job.CreatedAt
+
+	args, err := jobArgs(job)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create job: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO job_content_hashes AS h (content_hash, job_id, analyzer_version, config_fingerprint)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (content_hash) DO UPDATE SET job_id = EXCLUDED.job_id,
+		 analyzer_version = EXCLUDED.analyzer_version, config_fingerprint = EXCLUDED.config_fingerprint
+		 WHERE (h.analyzer_version, h.config_fingerprint)
+		 IS DISTINCT FROM (EXCLUDED.analyzer_version, EXCLUDED.config_fingerprint)
+		 RETURNING job_id`,
+		job.ContentHash, job.ID, job.AnalyzerVersion, job.ConfigFingerprint,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		existing, err := scanJob(tx.QueryRow(ctx,
+			`SELECT `+jobColumns+` FROM jobs
+			 WHERE id = (SELECT job_id FROM job_content_hashes WHERE content_hash = $1)`,
+			job.ContentHash,
+		))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get job for content hash: %w", err)
+		}
+		return &existing, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, insertJobSQL, args...); err != nil {
+		return nil, false, fmt.Errorf("failed to create job: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	return &job, true, nil
+}
+
+// GetJob retrieves a job from PostgreSQL.
+func (r *postgresRepository) GetJob(ctx context.Context, id string) (*Job, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	job, err := scanJob(r.pool.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1 AND deleted_at IS NULL`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// FindJobByContentHash finds the newest completed job with the hash in
+// PostgreSQL.
+func (r *postgresRepository) FindJobByContentHash(ctx context.Context, hash string) (*Job, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	job, err := scanJob(r.pool.QueryRow(ctx,
+		`SELECT `+jobColumns+` FROM jobs
+		 WHERE content_hash = $1 AND status IN ('', 'completed') AND deleted_at IS NULL
+		 ORDER BY created_at DESC, id DESC LIMIT 1`,
+		hash,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find job by content hash: %w", err)
+	}
+
+	return &job, nil
+}
+
+// FindJobByNormalizedHash finds the newest completed job with the
+// normalized hash in PostgreSQL.
+func (r *postgresRepository) FindJobByNormalizedHash(ctx context.Context, hash string) (*Job, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	// Jobs stored without a normalized hash have '', which never matches
+	if hash == "" {
+		return nil, ErrNotFound
+	}
+	job, err := scanJob(r.pool.QueryRow(ctx,
+		`SELECT `+jobColumns+` FROM jobs
+		 WHERE normalized_hash = $1 AND status IN ('', 'completed') AND deleted_at IS NULL
+		 ORDER BY created_at DESC, id DESC LIMIT 1`,
+		hash,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find job by normalized hash: %w", err)
+	}
+
+	return &job, nil
+}
+
+// DeleteJob marks a job deleted in PostgreSQL and releases its content
+// hash claim, so CreateOrGetJob never returns it. The row stays until
+// DeleteJobsOlderThan purges it.
+func (r *postgresRepository) DeleteJob(ctx context.Context, id string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE jobs SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM job_content_hashes WHERE job_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+
+	return nil
+}
+
+// purgeBatchSize is how many rows each DELETE in DeleteJobsOlderThan
+// removes, keeping every statement well inside postgresQueryTimeout.
+const purgeBatchSize = 1000
+
+// DeleteJobsOlderThan purges old and soft-deleted jobs from PostgreSQL in
+// batches. Their content hash claims go with them (ON DELETE CASCADE).
+func (r *postgresRepository) DeleteJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	var before *time.Time
+	if !cutoff.IsZero() {
+		before = &cutoff
+	}
+
+	var deleted int64
+	for {
+		n, err := r.purgeJobs(ctx, before)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if n < purgeBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// purgeJobs deletes up to purgeBatchSize jobs that are soft-deleted or
+// created before before (NULL for none).
+func (r *postgresRepository) purgeJobs(ctx context.Context, before *time.Time) (int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM jobs WHERE id IN (
+		 SELECT id FROM jobs
+		 WHERE deleted_at IS NOT NULL OR created_at < $1::timestamptz
+		 LIMIT $2)`,
+		before, purgeBatchSize,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// UpdateJob updates a job in PostgreSQL. The write only matches the row if
+// its updated_at is unchanged since the read, so a concurrent update makes
+// it retry rather than be overwritten.
+func (r *postgresRepository) UpdateJob(ctx context.Context, id string, update func(*Job) error) (*Job, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return updateJob(ctx, id, update, r.GetJob, func(job Job, prev time.Time) (*Job, bool, error) {
+		args, err := jobArgs(job)
+		if err != nil {
+			return nil, false, err
+		}
+		// Everything but created_at, which is kept as stored
+		args = append(args[:19], args[20:]...)
+		args = append(args, prev)
+		err = r.pool.QueryRow(ctx,
+			`UPDATE jobs SET status = $2, error = $3, content_type = $4, human = $5, verdict = $6,
+			 confidence = $7, ai_score = $8, needs_review = $9, detectors = $10, content_hash = $11,
+			 fingerprint = $12, analyzer_version = $13, config_fingerprint = $14, tx_id = $15,
+			 anchored_at = $16, parent_id = $17, callback_url = $18, details = $19, updated_at = $20,
+			 detection_details = $21, attempt_count = $22, completed_at = $23, normalized_hash = $24,
+			 basis = $25, api_key_id = $26
+			 WHERE id = $1 AND updated_at = $27 AND deleted_at IS NULL RETURNING created_at`,
+			args...,
+		).Scan(&job.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Changed or deleted since the read; the next read tells which
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update job: %w", err)
+		}
+
+		return &job, true, nil
+	})
+}
+
+// ListJobsByFingerprint finds near-duplicate jobs in PostgreSQL. Candidates
+// share at least one MinHash value, which the GIN index on fingerprint
+// makes cheap to find; similarity is estimated here.
+func (r *postgresRepository) ListJobsByFingerprint(ctx context.Context, fingerprint []uint32, threshold float64) ([]Job, error) {
+	if len(fingerprint) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	values := make([]int64, len(fingerprint))
+	for i, v := range fingerprint {
+		values[i] = int64(v)
+	}
+	rows, err := r.pool.Query(ctx, `SELECT `+jobColumns+` FROM jobs WHERE fingerprint && $1 AND deleted_at IS NULL`, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs by fingerprint: %w", err)
+	}
+	candidates, err := collectJobs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs by fingerprint: %w", err)
+	}
+
+	type match struct {
+		job        Job
+		similarity float64
+	}
+
+	var found []match
+	for _, job := range candidates {
+		if s := minhash.Similarity(fingerprint, job.Fingerprint); s > threshold {
+			found = append(found, match{job, s})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].similarity > found[j].similarity })
+
+	jobs := make([]Job, len(found))
+	for i, m := range found {
+		jobs[i] = m.job
+	}
+	return jobs, nil
+}
+
+// ListJobs pages through jobs in PostgreSQL.
+func (r *postgresRepository) ListJobs(ctx context.Context, filter ListFilter) ([]Job, string, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, "(created_at, id) < ("+arg(c.CreatedAt)+", "+arg(c.ID)+")")
+	}
+	if filter.ContentType != "" {
+		conditions = append(conditions, "content_type = "+arg(filter.ContentType))
+	}
+	if filter.Verdict != "" {
+		conditions = append(conditions, "verdict = "+arg(filter.Verdict))
+	}
+	if filter.ContentHash != "" {
+		conditions = append(conditions, "content_hash = "+arg(filter.ContentHash))
+	}
+	if filter.Human != nil {
+		conditions = append(conditions, "human = "+arg(*filter.Human))
+	}
+	if filter.APIKeyID != nil {
+		conditions = append(conditions, "api_key_id = "+arg(*filter.APIKeyID))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.CreatedBefore))
+	}
+
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE ` + strings.Join(conditions, " AND ")
+	// One extra row tells whether there's a next page
+	limit := filter.limit()
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ` + arg(limit+1)
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list jobs: %w", err)
+	}
+	jobs, err := collectJobs(rows)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	if len(jobs) <= limit {
+		return jobs, "", nil
+	}
+	jobs = jobs[:limit]
+	return jobs, encodeCursor(jobs[limit-1]), nil
+}
+
+// apiKeyColumns are the columns scanAPIKey reads, in order.
+const apiKeyColumns = `id, name, secret_hash, scopes, rate_limit_per_minute, created_at, revoked_at`
+
+// scanAPIKey reads a row selected with apiKeyColumns.
+func scanAPIKey(row pgx.Row) (APIKey, error) {
+	var (
+		key       APIKey
+		revokedAt *time.Time
+	)
+	err := row.Scan(&key.ID, &key.Name, &key.SecretHash, &key.Scopes, &key.RateLimitPerMinute, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return APIKey{}, err
+	}
+	if revokedAt != nil {
+		key.RevokedAt = *revokedAt
+	}
+	return key, nil
+}
+
+// CreateAPIKey stores an API key in PostgreSQL.
+func (r *postgresRepository) CreateAPIKey(ctx context.Context, key APIKey) (*APIKey, error) {
+	key.ID = generateID()
+	key.CreatedAt = time.Now()
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO api_keys (id, name, secret_hash, scopes, rate_limit_per_minute, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		key.ID, key.Name, key.SecretHash, key.Scopes, key.RateLimitPerMinute, key.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// GetAPIKey retrieves an API key from PostgreSQL.
+func (r *postgresRepository) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys returns every API key in PostgreSQL, newest first.
+func (r *postgresRepository) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (APIKey, error) {
+		return scanAPIKey(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key in PostgreSQL.
+func (r *postgresRepository) RevokeAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2)
+		 WHERE id = $1
+		 RETURNING `+apiKeyColumns,
+		id, time.Now(),
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// RecordUsage adds to a daily usage rollup in PostgreSQL.
+func (r *postgresRepository) RecordUsage(ctx context.Context, event UsageEvent) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO api_key_usage (key_id, day, content_type, requests, bytes, provider_calls)
+		 VALUES ($1, $2, $3, 1, $4, $5)
+		 ON CONFLICT (key_id, day, content_type) DO UPDATE SET
+		     requests = api_key_usage.requests + 1,
+		     bytes = api_key_usage.bytes + EXCLUDED.bytes,
+		     provider_calls = api_key_usage.provider_calls + EXCLUDED.provider_calls`,
+		event.KeyID, utcDay(event.At), event.ContentType, event.Bytes, event.ProviderCalls,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+
+	return nil
+}
+
+// ListUsage returns a key's daily usage rollups from PostgreSQL, oldest
+// first.
+func (r *postgresRepository) ListUsage(ctx context.Context, keyID string, from, to time.Time) ([]DailyUsage, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx,
+		`SELECT day, content_type, requests, bytes, provider_calls FROM api_key_usage
+		 WHERE key_id = $1 AND day >= $2 AND day < $3
+		 ORDER BY day, content_type`,
+		keyID, utcDay(from), utcDay(to),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer rows.Close()
+
+	// Rows come per content type; fold each day's into one rollup
+	var days []DailyUsage
+	for rows.Next() {
+		var (
+			day                            time.Time
+			contentType                    string
+			requests, bytes, providerCalls int64
+		)
+		if err := rows.Scan(&day, &contentType, &requests, &bytes, &providerCalls); err != nil {
+			return nil, fmt.Errorf("failed to list usage: %w", err)
+		}
+		day = utcDay(day)
+		if len(days) == 0 || !days[len(days)-1].Day.Equal(day) {
+			days = append(days, DailyUsage{KeyID: keyID, Day: day, ContentTypes: make(map[string]int64)})
+		}
+		usage := &days[len(days)-1]
+		usage.Requests += requests
+		usage.Bytes += bytes
+		usage.ProviderCalls += providerCalls
+		usage.ContentTypes[contentType] += requests
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	return days, nil
+}
+
+// statsJobs selects the jobs GetStats aggregates; $1 and $2 are the range,
+// NULL for an open end.
+const statsJobs = `FROM jobs WHERE status IN ('', 'completed') AND deleted_at IS NULL
+	AND ($1::timestamptz IS NULL OR created_at >= $1)
+	AND ($2::timestamptz IS NULL OR created_at < $2)`
+
+// GetStats aggregates the completed jobs in PostgreSQL. The queries run in
+// one read-only snapshot so the numbers agree with each other.
+func (r *postgresRepository) GetStats(ctx context.Context, from, to time.Time) (*Stats, error) {
+	var start, end *time.Time
+	if !from.IsZero() {
+		start = &from
+	}
+	if !to.IsZero() {
+		end = &to
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		stats      Stats
+		confidence float64
+	)
+
+	// Verdict split, which also gives the totals
+	rows, err := tx.Query(ctx,
+		`SELECT content_type,
+		        CASE WHEN verdict <> '' THEN verdict WHEN human THEN 'human' ELSE 'ai' END,
+		        count(*), sum(confidence)
+		 `+statsJobs+`
+		 GROUP BY 1, 2 ORDER BY 1 COLLATE "C"`,
+		start, end,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	var (
+		contentType, verdict string
+		jobs                 int64
+		sum                  float64
+	)
+	_, err = pgx.ForEachRow(rows, []any{&contentType, &verdict, &jobs, &sum}, func() error {
+		if n := len(stats.ContentTypes); n == 0 || stats.ContentTypes[n-1].ContentType != contentType {
+			stats.ContentTypes = append(stats.ContentTypes, ContentTypeStats{ContentType: contentType})
+		}
+		stats.ContentTypes[len(stats.ContentTypes)-1].add(verdict, jobs)
+		stats.Total += jobs
+		confidence += sum
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	// Daily counts
+	rows, err = tx.Query(ctx,
+		`SELECT (created_at AT TIME ZONE 'UTC')::date, count(*) `+statsJobs+` GROUP BY 1 ORDER BY 1`,
+		start, end,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	stats.Daily, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (DayCount, error) {
+		var c DayCount
+		err := row.Scan(&c.Day, &c.Jobs)
+		c.Day = utcDay(c.Day)
+		return c, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	// AIScore histogram, bucketed the way histogramBucket does
+	rows, err = tx.Query(ctx,
+		`SELECT LEAST(GREATEST(floor(ai_score * 10)::int, 0), 9), count(*) `+statsJobs+` GROUP BY 1`,
+		start, end,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	var bucket int
+	var count int64
+	_, err = pgx.ForEachRow(rows, []any{&bucket, &count}, func() error {
+		stats.AIScoreHistogram[bucket] = count
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	// AI phrases, from the legacy details document for jobs stored before
+	// detection_details
+	rows, err = tx.Query(ctx,
+		`SELECT p.phrase->>'Phrase', count(*)
+		 FROM (SELECT COALESCE(detection_details->'AIPhrases', details->'ai_phrases') AS phrases `+statsJobs+`) j,
+		      jsonb_array_elements(CASE WHEN jsonb_typeof(j.phrases) = 'array' THEN j.phrases ELSE '[]' END) AS p(phrase)
+		 WHERE p.phrase->>'Phrase' IS NOT NULL
+		 GROUP BY 1 ORDER BY 2 DESC, 1 COLLATE "C" LIMIT $3`,
+		start, end, MaxStatsPhrases,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	stats.TopAIPhrases, err = pgx.CollectRows(rows, pgx.RowToStructByPos[PhraseCount])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	if stats.Total > 0 {
+		stats.AverageConfidence = confidence / float64(stats.Total)
+	}
+	return &stats, nil
+}
+
+// Ping checks PostgreSQL connectivity.
+func (r *postgresRepository) Ping(ctx context.Context) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	return r.pool.Ping(ctx)
+}
+
+// Close releases PostgreSQL connection pool.
+func (r *postgresRepository) Close() error {
+	r.pool.Close()
+	return nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestPostgres connects to POSTGRES_TEST_URL and empties the jobs table,
+// skipping the test if the variable isn't set.
+func newTestPostgres(t *testing.T) Repository {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		t.Skip("POSTGRES_TEST_URL not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	repo, err := NewPostgres(ctx, url)
+	if err != nil {
+		t.Fatalf("NewPostgres failed: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	if _, err := repo.(*postgresRepository).pool.Exec(ctx, `TRUNCATE jobs, api_keys, api_key_usage`); err != nil {
+		t.Fatalf("failed to empty tables: %v", err)
+	}
+	return repo
+}
+
+// TestPostgresRepository runs the Repository interface tests against a
+// real database.
+func TestPostgresRepository(t *testing.T) {
+	newTestPostgres(t)
+	runRepositoryTests(t, newTestPostgres)
+
+	t.Run("migrations are idempotent", func(t *testing.T) {
+		// Connecting again finds every migration applied
+		newTestPostgres(t)
+	})
+
+	t.Run("jobs stored before detection_details keep their signals", func(t *testing.T) {
+		repo := newTestPostgres(t)
+		ctx := context.Background()
+
+		_, err := repo.(*postgresRepository).pool.Exec(ctx, `INSERT INTO jobs (id, status, details)
+			VALUES ('legacy', 'completed', '{"signals": [{"Name": "humanmark", "AIScore": 0.7, "Weight": 1}]}')`)
+		if err != nil {
+			t.Fatalf("failed to insert legacy job: %v", err)
+		}
+
+		job, err := repo.GetJob(ctx, "legacy")
+		if err != nil {
+			t.Fatalf("GetJob failed: %v", err)
+		}
+		want := &Details{Signals: []Signal{{Name: "humanmark", AIScore: 0.7, Weight: 1}}}
+		if !reflect.DeepEqual(job.Details, want) {
+			t.Errorf("Details = %+v, want %+v", job.Details, want)
+		}
+	})
+
+	t.Run("oversized details are stored truncated", func(t *testing.T) {
+		repo := newTestPostgres(t)
+		ctx := context.Background()
+
+		created, err := repo.CreateJob(ctx, Job{Status: JobStatusCompleted, Details: oversizedDetails()})
+		if err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+		job, err := repo.GetJob(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("GetJob failed: %v", err)
+		}
+		if !job.Details.Truncated || len(job.Details.DetectorOutputs[0].Sentences) >= 5000 {
+			t.Errorf("expected truncated details, got %d sentences", len(job.Details.DetectorOutputs[0].Sentences))
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		repo := newTestPostgres(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := repo.CreateJob(ctx, Job{ContentType: "text"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+// TestNewPostgres tests connection errors that don't need a database.
+func TestNewPostgres(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := NewPostgres(ctx, ""); err == nil {
+		t.Error("expected error for empty connection string")
+	}
+	if _, err := NewPostgres(ctx, "postgres://%zz"); err == nil {
+		t.Error("expected error for malformed connection string")
+	}
+
+	t.Run("unreachable database fails", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if _, err := NewPostgres(ctx, "postgres://humanmark@127.0.0.1:1/humanmark?connect_timeout=1"); err == nil {
+			t.Error("expected error connecting to a closed port")
+		}
+	})
+}
+
+// oversizedDetails returns details well over MaxDetailsSize, mostly in one
+// detector's sentences.
+func oversizedDetails() *Details {
+	sentences := make([]Sentence, 5000)
+	for i := range sentences {
+		sentences[i] = Sentence{Text: fmt.Sprintf("Sentence number %d of a very long document.", i), AIScore: 0.5}
+	}
+	return &Details{
+		Signals:         []Signal{{Name: "humanmark", AIScore: 0.6, Weight: 1}},
+		DetectorOutputs: []DetectorOutput{{Name: "winston", Sentences: sentences}},
+		AIPhrases:       []Phrase{{Phrase: "delve into", Category: "filler"}},
+	}
+}
+
+// TestMarshalDetails tests capping serialized details at MaxDetailsSize.
+func TestMarshalDetails(t *testing.T) {
+	t.Run("nil details are NULL", func(t *testing.T) {
+		data, err := marshalDetails(nil)
+		if err != nil || data != nil {
+			t.Errorf("marshalDetails(nil) = %q, %v; want nil, nil", data, err)
+		}
+	})
+
+	t.Run("small details are stored whole", func(t *testing.T) {
+		d := &Details{Signals: []Signal{{Name: "humanmark", AIScore: 0.6, Weight: 1}}}
+		data, err := marshalDetails(d)
+		if err != nil {
+			t.Fatalf("marshalDetails failed: %v", err)
+		}
+		var decoded Details
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if !reflect.DeepEqual(&decoded, d) {
+			t.Errorf("decoded %+v, want %+v", decoded, d)
+		}
+	})
+
+	t.Run("large details are truncated", func(t *testing.T) {
+		d := oversizedDetails()
+		data, err := marshalDetails(d)
+		if err != nil {
+			t.Fatalf("marshalDetails failed: %v", err)
+		}
+		if len(data) > MaxDetailsSize {
+			t.Errorf("serialized size %d exceeds %d", len(data), MaxDetailsSize)
+		}
+
+		var decoded Details
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if !decoded.Truncated {
+			t.Error("expected Truncated to be set")
+		}
+		sentences := decoded.DetectorOutputs[0].Sentences
+		if len(sentences) == 0 || len(sentences) >= 5000 || sentences[0].Text != d.DetectorOutputs[0].Sentences[0].Text {
+			t.Errorf("expected a leading subset of the sentences, got %d", len(sentences))
+		}
+		// Short lists are left alone
+		if len(decoded.Signals) != 1 || len(decoded.AIPhrases) != 1 {
+			t.Errorf("short lists were cut: %+v, %+v", decoded.Signals, decoded.AIPhrases)
+		}
+
+		// The caller's details are unchanged
+		if d.Truncated || len(d.DetectorOutputs[0].Sentences) != 5000 {
+			t.Error("marshalDetails modified its argument")
+		}
+	})
+}
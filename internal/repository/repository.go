@@ -3,7 +3,8 @@
 // The Repository interface abstracts storage operations, allowing different
 // implementations for different environments:
 //   - MemoryRepository: In-memory storage for development/testing
-//   - PostgresRepository: PostgreSQL for production
+//   - PostgresRepository: PostgreSQL for production (see postgres.go); the
+//     schema is migrated on connect
 //
 // This follows the repository pattern, separating data access from business logic.
 package repository
@@ -11,15 +12,58 @@ package repository
 import (
 	"context"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/humanmark/humanmark/pkg/minhash"
 )
 
 // Common errors
 var (
 	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidCursor means a ListFilter cursor wasn't returned by ListJobs
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrConflict means UpdateJob gave up because the job kept changing
+	// between reading and writing it
+	ErrConflict = errors.New("job modified concurrently")
+)
+
+// MaxUpdateAttempts is how many times UpdateJob applies its update before
+// giving up with ErrConflict.
+const MaxUpdateAttempts = 5
+
+// Page size limits for ListJobs
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// JobStatus is the lifecycle state of a verification job.
+type JobStatus string
+
+const (
+	// JobStatusPending means the job is queued for async processing
+	JobStatusPending JobStatus = "pending"
+
+	// JobStatusProcessing means a worker is running detection
+	JobStatusProcessing JobStatus = "processing"
+
+	// JobStatusCompleted means the result fields are populated
+	JobStatusCompleted JobStatus = "completed"
+
+	// JobStatusFailed means detection failed; see Job.Error
+	JobStatusFailed JobStatus = "failed"
 )
 
 // Job represents a verification job in the database.
@@ -27,24 +71,88 @@ type Job struct {
 	// ID is the unique identifier (public-facing)
 	ID string
 
+	// Status is the job lifecycle state
+	// Synchronous verifications are stored as completed
+	Status JobStatus
+
+	// Error describes why the job failed (only set when Status is failed)
+	Error string
+
+	// AttemptCount is how many times a worker started processing the job
+	AttemptCount int
+
+	// CompletedAt is when the job reached completed or failed through
+	// UpdateJob (zero otherwise, including synchronous jobs)
+	CompletedAt time.Time
+
 	// ContentType is what type of content was analyzed
 	ContentType string
 
 	// Human is true if content was created by a human
 	Human bool
 
+	// Verdict is human, ai, or uncertain (empty for jobs stored before
+	// verdicts existed)
+	Verdict string
+
 	// Confidence is how confident we are (0.0-1.0)
 	Confidence float64
 
 	// AIScore is the raw AI probability (0.0-1.0)
 	AIScore float64
 
+	// NeedsReview is set when the detectors disagreed too much for the
+	// verdict to be trusted without a person checking it
+	NeedsReview bool
+
+	// Basis is what the verdict rested on: local-only, local+N-external,
+	// or conclusive-marker (empty for jobs stored before it was kept)
+	Basis string
+
 	// Detectors lists which detection methods were used
 	Detectors []string
 
+	// Details is the detection breakdown returned with detailed=true (nil
+	// for jobs that haven't completed and for jobs stored before details
+	// were kept)
+	Details *Details
+
 	// ContentHash is SHA256 hash of the analyzed content
 	ContentHash string
 
+	// NormalizedHash is the whitespace-insensitive SHA256 hash of text
+	// content (empty for media and for jobs stored before it was kept)
+	NormalizedHash string
+
+	// Fingerprint is the MinHash signature of text content (nil for media),
+	// used to find near-duplicate submissions
+	Fingerprint []uint32
+
+	// AnalyzerVersion and ConfigFingerprint identify the detection logic and
+	// configuration that produced the result
+	AnalyzerVersion   string
+	ConfigFingerprint string
+
+	// TxID is the blockchain transaction anchoring ContentHash (empty if
+	// the result wasn't anchored)
+	TxID string
+
+	// AnchoredAt is the timestamp recorded with the anchored hash
+	AnchoredAt time.Time
+
+	// ParentID links a page asset's job to the page's job (empty otherwise)
+	ParentID string
+
+	// APIKeyID is the API key that submitted the job (empty for requests
+	// without one). Only that key can list, export or delete it.
+	APIKeyID string
+
+	// CallbackURL receives a webhook when an async job finishes (optional)
+	CallbackURL string
+
+	// DeliveryAttempts records each webhook delivery to CallbackURL
+	DeliveryAttempts []DeliveryAttempt
+
 	// CreatedAt is when the job was created
 	CreatedAt time.Time
 
@@ -52,14 +160,491 @@ type Job struct {
 	UpdatedAt time.Time
 }
 
+// Details is a completed job's detection breakdown. PostgreSQL stores it as
+// JSON, capped at MaxDetailsSize.
+type Details struct {
+	// Aggregation is the strategy used to combine detector scores
+	Aggregation string
+
+	// Disagreement is the weighted standard deviation of detector scores
+	Disagreement float64
+
+	// Evidence is conclusive provenance found in the content: ai, human
+	Evidence string
+
+	// Signals are each detector's score before aggregation
+	Signals []Signal
+
+	// DetectorOutputs are per-sentence scores from detectors that provide
+	// them
+	DetectorOutputs []DetectorOutput
+
+	// SentenceAgreement compares GPTZero's per-sentence scores with other
+	// detectors'
+	SentenceAgreement []SentenceAgreement
+
+	// AIPhrases are the AI phrases found in text content
+	AIPhrases []Phrase
+
+	// Skipped lists configured detectors that declined the input
+	Skipped []SkippedDetector
+
+	// DetectorErrors maps each detector that failed to its error
+	DetectorErrors map[string]string
+
+	// MismatchWarning is set when the file extension disagreed with the
+	// content
+	MismatchWarning string
+
+	// HostReputation is the host rule that adjusted a URL input's score
+	HostReputation *HostReputation
+
+	// SimilarContent points at an earlier job with nearly the same content
+	SimilarContent *SimilarContent
+
+	// Watermark is the experimental watermark scan of text content
+	Watermark *Watermark
+
+	// ProcessingTime is how long detection took
+	ProcessingTime time.Duration
+
+	// Truncated is set when lists were cut to fit MaxDetailsSize
+	Truncated bool
+}
+
+// DetectorOutput is one detector's per-sentence scores.
+type DetectorOutput struct {
+	Name       string
+	Sentences  []Sentence
+	Paragraphs []Paragraph
+}
+
+// Sentence is a detector's score for one sentence.
+type Sentence struct {
+	Text    string
+	AIScore float64
+}
+
+// Paragraph is a detector's score for a run of its sentences.
+type Paragraph struct {
+	StartSentence int
+	Sentences     int
+	AIScore       float64
+}
+
+// SentenceAgreement is how closely two detectors' sentence scores agree.
+type SentenceAgreement struct {
+	Detectors      []string
+	Sentences      int
+	Agreement      float64
+	MeanDifference float64
+}
+
+// SkippedDetector is a configured detector that declined the input.
+type SkippedDetector struct {
+	Name   string
+	Reason string
+}
+
+// HostReputation is the host rule applied to a URL input's score.
+type HostReputation struct {
+	Host       string
+	Rule       string
+	Adjustment float64
+	Reason     string
+}
+
+// SimilarContent is an earlier job whose content was a near-duplicate.
+type SimilarContent struct {
+	JobID      string
+	Similarity float64
+}
+
+// Watermark is the result of the experimental watermark scan.
+type Watermark struct {
+	PValue           float64
+	Flagged          bool
+	Words            int
+	WordPairs        int
+	ChiSquare        float64
+	DegreesOfFreedom int
+}
+
+// Signal is one detector's score before aggregation.
+type Signal struct {
+	// Name is the detector name
+	Name string
+
+	// AIScore is the detector's AI probability (0.0-1.0)
+	AIScore float64
+
+	// Weight is the detector's weight in the aggregate
+	Weight float64
+}
+
+// Phrase is an AI phrase found in text content.
+type Phrase struct {
+	// Phrase is the matched dictionary pattern
+	Phrase string
+
+	// Category is the phrase's dictionary category, e.g. "hedging"
+	Category string
+}
+
+// DeliveryAttempt records a single webhook delivery.
+type DeliveryAttempt struct {
+	// At is when the attempt was made
+	At time.Time
+
+	// StatusCode is the receiver's HTTP status (0 if no response)
+	StatusCode int
+
+	// Error describes a failed attempt (empty on success)
+	Error string
+}
+
+// API key scopes
+const (
+	// ScopeVerifyText allows verifying text, documents and message exports
+	ScopeVerifyText = "verify:text"
+
+	// ScopeVerifyMedia allows verifying images, audio and video
+	ScopeVerifyMedia = "verify:media"
+)
+
+// Scopes lists every API key scope.
+var Scopes = []string{ScopeVerifyText, ScopeVerifyMedia}
+
+// APIKey is a credential for calling the API. Only a hash of the key's
+// secret is stored.
+type APIKey struct {
+	// ID is the unique identifier, also embedded in the key itself
+	ID string
+
+	// Name describes who the key was issued to
+	Name string
+
+	// SecretHash is the hex SHA-256 of the key's secret
+	SecretHash string
+
+	// Scopes are the operations the key may perform
+	Scopes []string
+
+	// RateLimitPerMinute replaces the default rate limit for the key
+	// (0 = use the default)
+	RateLimitPerMinute int
+
+	// CreatedAt is when the key was issued
+	CreatedAt time.Time
+
+	// RevokedAt is when the key was revoked (zero while active)
+	RevokedAt time.Time
+}
+
+// Revoked reports whether the key has been revoked.
+func (k *APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// HasScope reports whether the key grants scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// UsageEvent is one verification made with an API key, for RecordUsage.
+type UsageEvent struct {
+	// KeyID is the API key that made the request
+	KeyID string
+
+	// At is when the request was made; it counts toward that UTC day
+	At time.Time
+
+	// ContentType is the verified content's type: text, image, page...
+	ContentType string
+
+	// Bytes is the size of the content analyzed
+	Bytes int64
+
+	// ProviderCalls is the number of external detection APIs called
+	ProviderCalls int64
+}
+
+// DailyUsage is an API key's usage on one UTC day.
+type DailyUsage struct {
+	// KeyID is the API key
+	KeyID string
+
+	// Day is midnight UTC at the start of the day
+	Day time.Time
+
+	// Requests is the number of verifications
+	Requests int64
+
+	// Bytes is the content analyzed, in bytes
+	Bytes int64
+
+	// ProviderCalls is the number of external detection API calls
+	ProviderCalls int64
+
+	// ContentTypes counts requests by content type
+	ContentTypes map[string]int64
+}
+
+// add adds event to the rollup.
+func (u *DailyUsage) add(event UsageEvent) {
+	u.Requests++
+	u.Bytes += event.Bytes
+	u.ProviderCalls += event.ProviderCalls
+	if u.ContentTypes == nil {
+		u.ContentTypes = make(map[string]int64)
+	}
+	u.ContentTypes[event.ContentType]++
+}
+
+// StatsHistogramBuckets is the number of 0.1-wide AIScore buckets in
+// Stats.AIScoreHistogram.
+const StatsHistogramBuckets = 10
+
+// MaxStatsPhrases caps Stats.TopAIPhrases.
+const MaxStatsPhrases = 10
+
+// Stats aggregates the completed jobs created in a time range, for an
+// operator dashboard.
+type Stats struct {
+	// Total is the number of completed jobs
+	Total int64
+
+	// AverageConfidence is the jobs' mean confidence (0 without jobs)
+	AverageConfidence float64
+
+	// Daily counts jobs per UTC day, oldest first. Days without jobs are
+	// left out.
+	Daily []DayCount
+
+	// ContentTypes splits each content type's jobs by verdict, sorted by
+	// content type
+	ContentTypes []ContentTypeStats
+
+	// AIScoreHistogram counts jobs by AIScore in 0.1-wide buckets, [0, 0.1)
+	// up to [0.9, 1.0]
+	AIScoreHistogram [StatsHistogramBuckets]int64
+
+	// TopAIPhrases are the AI phrases found most often, most first, up to
+	// MaxStatsPhrases
+	TopAIPhrases []PhraseCount
+}
+
+// DayCount is the number of jobs created on a UTC day.
+type DayCount struct {
+	// Day is midnight UTC at the start of the day
+	Day  time.Time
+	Jobs int64
+}
+
+// ContentTypeStats is the verdict split for one content type. Jobs stored
+// before verdicts existed count as human or ai by their Human flag.
+type ContentTypeStats struct {
+	ContentType string
+	Jobs        int64
+	Human       int64
+	AI          int64
+	Uncertain   int64
+}
+
+// add counts n jobs with verdict.
+func (c *ContentTypeStats) add(verdict string, n int64) {
+	c.Jobs += n
+	switch verdict {
+	case "human":
+		c.Human += n
+	case "ai":
+		c.AI += n
+	case "uncertain":
+		c.Uncertain += n
+	}
+}
+
+// PhraseCount is how many times an AI phrase was found.
+type PhraseCount struct {
+	Phrase string
+	Count  int64
+}
+
+// statsVerdict returns job's verdict, deriving it from Human for jobs
+// stored before verdicts existed.
+func statsVerdict(job *Job) string {
+	switch {
+	case job.Verdict != "":
+		return job.Verdict
+	case job.Human:
+		return "human"
+	}
+	return "ai"
+}
+
+// histogramBucket returns the Stats.AIScoreHistogram index for score.
+func histogramBucket(score float64) int {
+	return max(0, min(int(score*StatsHistogramBuckets), StatsHistogramBuckets-1))
+}
+
+// sortPhraseCounts sorts phrases most found first, ties by phrase, and
+// keeps the first MaxStatsPhrases.
+func sortPhraseCounts(phrases []PhraseCount) []PhraseCount {
+	sort.Slice(phrases, func(i, j int) bool {
+		if phrases[i].Count != phrases[j].Count {
+			return phrases[i].Count > phrases[j].Count
+		}
+		return phrases[i].Phrase < phrases[j].Phrase
+	})
+	return phrases[:min(len(phrases), MaxStatsPhrases)]
+}
+
+// utcDay returns midnight UTC at the start of t's UTC day.
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ListFilter selects jobs for ListJobs. Zero-valued fields don't filter.
+type ListFilter struct {
+	// Limit is the page size (DefaultListLimit if zero, capped at
+	// MaxListLimit)
+	Limit int
+
+	// Cursor continues from the page that returned it (empty for the
+	// first page)
+	Cursor string
+
+	// ContentType, Verdict and ContentHash must match exactly
+	ContentType string
+	Verdict     string
+	ContentHash string
+
+	// Human matches Job.Human when set
+	Human *bool
+
+	// APIKeyID matches Job.APIKeyID when set; the empty string matches
+	// jobs submitted without a key
+	APIKeyID *string
+
+	// CreatedAfter and CreatedBefore bound CreatedAt, inclusive and
+	// exclusive respectively
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// matches reports whether job passes the filter's field conditions.
+func (f ListFilter) matches(job *Job) bool {
+	switch {
+	case f.ContentType != "" && job.ContentType != f.ContentType:
+		return false
+	case f.Verdict != "" && job.Verdict != f.Verdict:
+		return false
+	case f.ContentHash != "" && job.ContentHash != f.ContentHash:
+		return false
+	case f.Human != nil && job.Human != *f.Human:
+		return false
+	case f.APIKeyID != nil && job.APIKeyID != *f.APIKeyID:
+		return false
+	case !f.CreatedAfter.IsZero() && job.CreatedAt.Before(f.CreatedAfter):
+		return false
+	case !f.CreatedBefore.IsZero() && !job.CreatedAt.Before(f.CreatedBefore):
+		return false
+	}
+	return true
+}
+
+// limit returns the effective page size.
+func (f ListFilter) limit() int {
+	if f.Limit <= 0 {
+		return DefaultListLimit
+	}
+	return min(f.Limit, MaxListLimit)
+}
+
 // Repository defines the interface for job persistence.
 type Repository interface {
 	// CreateJob creates a new job and returns it with generated ID.
 	CreateJob(ctx context.Context, job Job) (*Job, error)
 
+	// CreateOrGetJob creates job unless a job created through
+	// CreateOrGetJob already has its ContentHash, AnalyzerVersion and
+	// ConfigFingerprint, in which case that job is returned instead. created
+	// reports which happened. Jobs without a ContentHash are always created.
+	CreateOrGetJob(ctx context.Context, job Job) (stored *Job, created bool, err error)
+
 	// GetJob retrieves a job by ID.
 	GetJob(ctx context.Context, id string) (*Job, error)
 
+	// DeleteJob deletes a job, which from then on is not found by any
+	// lookup. Returns ErrNotFound if the job doesn't exist.
+	DeleteJob(ctx context.Context, id string) error
+
+	// DeleteJobsOlderThan deletes the jobs created before cutoff and
+	// returns how many rows it removed. Implementations that delete softly
+	// purge jobs deleted with DeleteJob here too, whatever their age. A
+	// zero cutoff removes no jobs by age.
+	DeleteJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// FindJobByContentHash returns the most recently created completed job
+	// with the content hash, or ErrNotFound if there is none.
+	FindJobByContentHash(ctx context.Context, hash string) (*Job, error)
+
+	// FindJobByNormalizedHash is FindJobByContentHash for the
+	// whitespace-insensitive text hash.
+	FindJobByNormalizedHash(ctx context.Context, hash string) (*Job, error)
+
+	// UpdateJob applies update to the stored job and saves the result,
+	// keeping its ID and CreatedAt. If the job changed in between (its
+	// UpdatedAt moved), update runs again on the new version, up to
+	// MaxUpdateAttempts times before ErrConflict, so update must not have
+	// side effects. An error from update is returned without saving.
+	// CompletedAt is set when the job first reaches completed or failed.
+	// Returns ErrNotFound if the job doesn't exist.
+	UpdateJob(ctx context.Context, id string, update func(*Job) error) (*Job, error)
+
+	// ListJobsByFingerprint returns jobs whose fingerprint's estimated
+	// similarity to fingerprint is above threshold, most similar first.
+	ListJobsByFingerprint(ctx context.Context, fingerprint []uint32, threshold float64) ([]Job, error)
+
+	// ListJobs returns a page of jobs matching filter, newest first, and the
+	// cursor for the next page (empty on the last page). Pages are keyed on
+	// (CreatedAt, ID), so jobs created while paging don't shift later pages.
+	ListJobs(ctx context.Context, filter ListFilter) ([]Job, string, error)
+
+	// CreateAPIKey stores a new API key and returns it with generated ID
+	// and CreatedAt.
+	CreateAPIKey(ctx context.Context, key APIKey) (*APIKey, error)
+
+	// GetAPIKey retrieves an API key by ID, revoked or not.
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+
+	// ListAPIKeys returns every API key, newest first.
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+
+	// RevokeAPIKey marks an API key revoked and returns it. Revoking it
+	// again keeps the first RevokedAt. Returns ErrNotFound if the key
+	// doesn't exist.
+	RevokeAPIKey(ctx context.Context, id string) (*APIKey, error)
+
+	// RecordUsage adds event to its key's rollup for the UTC day of
+	// event.At.
+	RecordUsage(ctx context.Context, event UsageEvent) error
+
+	// ListUsage returns the key's daily rollups for days from the UTC day
+	// of from up to but not including the UTC day of to, oldest first.
+	// Days without usage are left out.
+	ListUsage(ctx context.Context, keyID string, from, to time.Time) ([]DailyUsage, error)
+
+	// GetStats aggregates the completed jobs created from from up to but
+	// not including to. A zero from or to leaves that end open.
+	GetStats(ctx context.Context, from, to time.Time) (*Stats, error)
+
 	// Ping checks database connectivity.
 	Ping(ctx context.Context) error
 
@@ -71,18 +656,181 @@ type Repository interface {
 // In-Memory Implementation (for development/testing)
 // =============================================================================
 
+// MemoryOptions bounds an in-memory repository. Zero values mean no limit.
+type MemoryOptions struct {
+	// MaxJobs is the most jobs kept; creating another evicts the oldest
+	MaxJobs int
+
+	// MaxAge is how long a job is kept after it was created
+	MaxAge time.Duration
+
+	// SweepInterval is how often jobs older than MaxAge are evicted
+	// (default: a tenth of MaxAge, at least a second)
+	SweepInterval time.Duration
+}
+
 // memoryRepository implements Repository using in-memory storage.
 // This is useful for development and testing when no database is available.
 type memoryRepository struct {
 	mu   sync.RWMutex
 	jobs map[string]*Job
+
+	// order holds job IDs oldest first; evictions take from the front
+	order []string
+
+	opts      MemoryOptions
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// contentHashIndex maps the content hash of each job created by
+	// CreateOrGetJob to its ID
+	contentHashIndex map[string]string
+
+	// contentHashJobs maps each content hash to the IDs of every job with
+	// that hash
+	contentHashJobs map[string][]string
+
+	// normalizedHashJobs does the same for normalized hashes
+	normalizedHashJobs map[string][]string
+
+	// apiKeys aren't subject to the job limits
+	apiKeys map[string]*APIKey
+
+	// usage holds daily rollups by key ID and day, also kept regardless
+	// of the job limits
+	usage map[usageKey]*DailyUsage
 }
 
-// NewMemory creates a new in-memory repository.
+// usageKey identifies a daily rollup in memory.
+type usageKey struct {
+	keyID string
+	day   time.Time
+}
+
+// NewMemory creates a new in-memory repository with no limits.
 func NewMemory() Repository {
-	return &memoryRepository{
-		jobs: make(map[string]*Job),
+	return NewMemoryWithOptions(MemoryOptions{})
+}
+
+// NewMemoryWithOptions creates an in-memory repository that evicts its
+// oldest jobs to stay within opts. With a MaxAge, a background sweeper
+// runs until Close.
+func NewMemoryWithOptions(opts MemoryOptions) Repository {
+	r := &memoryRepository{
+		jobs:               make(map[string]*Job),
+		contentHashIndex:   make(map[string]string),
+		contentHashJobs:    make(map[string][]string),
+		normalizedHashJobs: make(map[string][]string),
+		apiKeys:            make(map[string]*APIKey),
+		usage:              make(map[usageKey]*DailyUsage),
+		opts:               opts,
+		done:               make(chan struct{}),
+	}
+
+	if opts.MaxAge > 0 {
+		interval := opts.SweepInterval
+		if interval <= 0 {
+			interval = max(opts.MaxAge/10, time.Second)
+		}
+		go r.sweep(interval)
+	}
+
+	return r
+}
+
+// sweep evicts expired jobs every interval until Close.
+func (r *memoryRepository) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-ticker.C:
+			r.mu.Lock()
+			r.evict(now)
+			r.mu.Unlock()
+		}
+	}
+}
+
+// add stores a new job and evicts the oldest jobs if that goes over the
+// limits. Callers hold the write lock.
+func (r *memoryRepository) add(job Job) {
+	r.store(job)
+	r.order = append(r.order, job.ID)
+	r.evict(time.Now())
+}
+
+// evict removes the oldest jobs while there are more than MaxJobs or the
+// oldest is older than MaxAge. Callers hold the write lock.
+func (r *memoryRepository) evict(now time.Time) {
+	for len(r.order) > 0 {
+		oldest := r.jobs[r.order[0]]
+		over := r.opts.MaxJobs > 0 && len(r.jobs) > r.opts.MaxJobs
+		expired := r.opts.MaxAge > 0 && now.Sub(oldest.CreatedAt) > r.opts.MaxAge
+		if !over && !expired {
+			return
+		}
+
+		r.remove(oldest)
+		r.order = r.order[1:]
+	}
+}
+
+// remove forgets job everywhere but order, so lookups by content hash
+// never return a removed ID. Callers hold the write lock.
+func (r *memoryRepository) remove(job *Job) {
+	unindexHash(r.contentHashJobs, job.ContentHash, job.ID)
+	unindexHash(r.normalizedHashJobs, job.NormalizedHash, job.ID)
+	if r.contentHashIndex[job.ContentHash] == job.ID {
+		delete(r.contentHashIndex, job.ContentHash)
+	}
+	delete(r.jobs, job.ID)
+}
+
+// unindexHash removes id from index[hash].
+func unindexHash(index map[string][]string, hash, id string) {
+	ids := index[hash]
+	for i, v := range ids {
+		if v == id {
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(index, hash)
+	} else {
+		index[hash] = ids
+	}
+}
+
+// reindexHash moves id from index[oldHash] to index[hash].
+func reindexHash(index map[string][]string, oldHash, hash, id string) {
+	if oldHash == hash {
+		return
+	}
+	if oldHash != "" {
+		unindexHash(index, oldHash, id)
+	}
+	if hash != "" {
+		index[hash] = append(index[hash], id)
+	}
+}
+
+// store saves a copy of job, keeping contentHashJobs and
+// normalizedHashJobs current. Callers hold the write lock.
+func (r *memoryRepository) store(job Job) {
+	var old Job
+	if existing, ok := r.jobs[job.ID]; ok {
+		old = *existing
 	}
+	reindexHash(r.contentHashJobs, old.ContentHash, job.ContentHash, job.ID)
+	reindexHash(r.normalizedHashJobs, old.NormalizedHash, job.NormalizedHash, job.ID)
+
+	stored := job
+	r.jobs[job.ID] = &stored
 }
 
 // CreateJob creates a new job in memory.
@@ -95,13 +843,37 @@ func (r *memoryRepository) CreateJob(ctx context.Context, job Job) (*Job, error)
 	job.CreatedAt = time.Now()
 	job.UpdatedAt = job.CreatedAt
 
-	// Store copy
-	stored := job
-	r.jobs[job.ID] = &stored
+	r.add(job)
 
 	return &job, nil
 }
 
+// CreateOrGetJob creates a job in memory unless one with its content hash
+// exists.
+func (r *memoryRepository) CreateOrGetJob(ctx context.Context, job Job) (*Job, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job.ContentHash != "" {
+		existing, ok := r.jobs[r.contentHashIndex[job.ContentHash]]
+		if ok && existing.AnalyzerVersion == job.AnalyzerVersion && existing.ConfigFingerprint == job.ConfigFingerprint {
+			result := *existing
+			return &result, false, nil
+		}
+	}
+
+	job.ID = generateID()
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+
+	if job.ContentHash != "" {
+		r.contentHashIndex[job.ContentHash] = job.ID
+	}
+	r.add(job)
+
+	return &job, true, nil
+}
+
 // GetJob retrieves a job from memory.
 func (r *memoryRepository) GetJob(ctx context.Context, id string) (*Job, error) {
 	r.mu.RLock()
@@ -116,91 +888,371 @@ func (r *memoryRepository) GetJob(ctx context.Context, id string) (*Job, error)
 	return nil, ErrNotFound
 }
 
-// Ping always succeeds for in-memory repository.
-func (r *memoryRepository) Ping(ctx context.Context) error {
+// DeleteJob removes a job from memory.
+func (r *memoryRepository) DeleteJob(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	r.remove(job)
+	r.order = slices.DeleteFunc(r.order, func(v string) bool { return v == id })
+
 	return nil
 }
 
-// Close is a no-op for in-memory repository.
-func (r *memoryRepository) Close() error {
-	return nil
+// DeleteJobsOlderThan removes the jobs created before cutoff from memory.
+// order is oldest first, so they are all at its front.
+func (r *memoryRepository) DeleteJobsOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for len(r.order) > 0 {
+		oldest := r.jobs[r.order[0]]
+		if !oldest.CreatedAt.Before(cutoff) {
+			break
+		}
+		r.remove(oldest)
+		r.order = r.order[1:]
+		deleted++
+	}
+
+	return deleted, nil
 }
 
-// =============================================================================
-// PostgreSQL Implementation
-// =============================================================================
+// UpdateJob updates a job in memory.
+func (r *memoryRepository) UpdateJob(ctx context.Context, id string, update func(*Job) error) (*Job, error) {
+	return updateJob(ctx, id, update, r.GetJob, func(job Job, prev time.Time) (*Job, bool, error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		existing, ok := r.jobs[id]
+		if !ok {
+			return nil, false, ErrNotFound
+		}
+		if !existing.UpdatedAt.Equal(prev) {
+			return nil, false, nil
+		}
 
-// Note: This is a placeholder. In production, use a real PostgreSQL driver
-// like pgx or database/sql with lib/pq.
+		job.CreatedAt = existing.CreatedAt
+		r.store(job)
 
-// postgresRepository implements Repository using PostgreSQL.
-type postgresRepository struct {
-	connString string
-	// db *pgxpool.Pool // Uncomment when using pgx
+		return &job, true, nil
+	})
 }
 
-// NewPostgres creates a new PostgreSQL repository.
-func NewPostgres(connString string) (Repository, error) {
-	if connString == "" {
-		return nil, errors.New("database connection string is required")
+// updateJob runs UpdateJob's read-modify-write loop. get reads the job;
+// save stores it only if the stored UpdatedAt still equals prev, and
+// reports whether it did.
+func updateJob(
+	ctx context.Context,
+	id string,
+	update func(*Job) error,
+	get func(ctx context.Context, id string) (*Job, error),
+	save func(job Job, prev time.Time) (*Job, bool, error),
+) (*Job, error) {
+	for attempt := 0; attempt < MaxUpdateAttempts; attempt++ {
+		job, err := get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		prev := job.UpdatedAt
+
+		if err := update(job); err != nil {
+			return nil, err
+		}
+		job.ID = id
+		job.UpdatedAt = nextUpdatedAt(prev)
+		if job.CompletedAt.IsZero() && (job.Status == JobStatusCompleted || job.Status == JobStatusFailed) {
+			job.CompletedAt = job.UpdatedAt
+		}
+
+		saved, ok, err := save(*job, prev)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return saved, nil
+		}
 	}
 
-	// TODO: Initialize actual database connection
-	// pool, err := pgxpool.New(context.Background(), connString)
-	// if err != nil {
-	//     return nil, fmt.Errorf("failed to connect to database: %w", err)
-	// }
+	return nil, ErrConflict
+}
 
-	return &postgresRepository{
-		connString: connString,
-	}, nil
+// nextUpdatedAt returns the UpdatedAt for a job last updated at prev: now,
+// at PostgreSQL's microsecond precision, but always after prev so other
+// updates can tell the job changed.
+func nextUpdatedAt(prev time.Time) time.Time {
+	now := time.Now().Truncate(time.Microsecond)
+	if !now.After(prev) {
+		now = prev.Truncate(time.Microsecond).Add(time.Microsecond)
+	}
+	return now
 }
 
-// CreateJob creates a new job in PostgreSQL.
-func (r *postgresRepository) CreateJob(ctx context.Context, job Job) (*Job, error) {
-	job.ID = generateID()
-	job.CreatedAt = time.Now()
-	job.UpdatedAt = job.CreatedAt
+// FindJobByContentHash finds the newest completed job with the hash in
+// memory.
+func (r *memoryRepository) FindJobByContentHash(ctx context.Context, hash string) (*Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	// TODO: Actual database insert
-	// _, err := r.db.Exec(ctx,
-	//     `INSERT INTO jobs (id, content_type, human, confidence, ai_score, detectors, content_hash, created_at, updated_at)
-	//      VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
-	//     job.ID, job.ContentType, job.Human, job.Confidence, job.AIScore,
-	//     job.Detectors, job.ContentHash, job.CreatedAt, job.UpdatedAt,
-	// )
+	return r.newestCompleted(r.contentHashJobs[hash])
+}
 
-	return &job, nil
+// FindJobByNormalizedHash finds the newest completed job with the
+// normalized hash in memory.
+func (r *memoryRepository) FindJobByNormalizedHash(ctx context.Context, hash string) (*Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.newestCompleted(r.normalizedHashJobs[hash])
 }
 
-// GetJob retrieves a job from PostgreSQL.
-func (r *postgresRepository) GetJob(ctx context.Context, id string) (*Job, error) {
-	// TODO: Actual database query
-	// row := r.db.QueryRow(ctx,
-	//     `SELECT id, content_type, human, confidence, ai_score, detectors, content_hash, created_at, updated_at
-	//      FROM jobs WHERE id = $1`, id,
-	// )
-	// var job Job
-	// err := row.Scan(&job.ID, &job.ContentType, &job.Human, &job.Confidence,
-	//     &job.AIScore, &job.Detectors, &job.ContentHash, &job.CreatedAt, &job.UpdatedAt)
-	// if err == pgx.ErrNoRows {
-	//     return nil, ErrNotFound
-	// }
+// newestCompleted returns a copy of the most recently created completed
+// job among ids, or ErrNotFound. Callers hold the read lock.
+func (r *memoryRepository) newestCompleted(ids []string) (*Job, error) {
+	var newest *Job
+	for _, id := range ids {
+		job := r.jobs[id]
+		if job.Status != JobStatusCompleted && job.Status != "" {
+			continue
+		}
+		if newest == nil || job.CreatedAt.After(newest.CreatedAt) ||
+			(job.CreatedAt.Equal(newest.CreatedAt) && job.ID > newest.ID) {
+			newest = job
+		}
+	}
+	if newest == nil {
+		return nil, ErrNotFound
+	}
 
+	result := *newest
+	return &result, nil
+}
+
+// ListJobsByFingerprint scans all jobs in memory.
+func (r *memoryRepository) ListJobsByFingerprint(ctx context.Context, fingerprint []uint32, threshold float64) ([]Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type match struct {
+		job        Job
+		similarity float64
+	}
+
+	var found []match
+	for _, job := range r.jobs {
+		if s := minhash.Similarity(fingerprint, job.Fingerprint); s > threshold {
+			found = append(found, match{*job, s})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].similarity > found[j].similarity })
+
+	jobs := make([]Job, len(found))
+	for i, m := range found {
+		jobs[i] = m.job
+	}
+	return jobs, nil
+}
+
+// ListJobs sorts a filtered snapshot of the jobs in memory.
+func (r *memoryRepository) ListJobs(ctx context.Context, filter ListFilter) ([]Job, string, error) {
+	var after *cursor
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &c
+	}
+
+	r.mu.RLock()
+	var jobs []Job
+	for _, job := range r.jobs {
+		if filter.matches(job) && (after == nil || after.precedes(job)) {
+			jobs = append(jobs, *job)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID > jobs[j].ID
+	})
+
+	limit := filter.limit()
+	if len(jobs) <= limit {
+		return jobs, "", nil
+	}
+	jobs = jobs[:limit]
+	return jobs, encodeCursor(jobs[limit-1]), nil
+}
+
+// CreateAPIKey stores an API key in memory.
+func (r *memoryRepository) CreateAPIKey(ctx context.Context, key APIKey) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.ID = generateID()
+	key.CreatedAt = time.Now()
+	key.Scopes = append([]string(nil), key.Scopes...)
+	r.apiKeys[key.ID] = &key
+
+	result := key
+	return &result, nil
+}
+
+// GetAPIKey retrieves an API key from memory.
+func (r *memoryRepository) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if key, ok := r.apiKeys[id]; ok {
+		result := *key
+		return &result, nil
+	}
 	return nil, ErrNotFound
 }
 
-// Ping checks PostgreSQL connectivity.
-func (r *postgresRepository) Ping(ctx context.Context) error {
-	// TODO: Actual ping
-	// return r.db.Ping(ctx)
+// ListAPIKeys returns every API key in memory, newest first.
+func (r *memoryRepository) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(r.apiKeys))
+	for _, key := range r.apiKeys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID > keys[j].ID
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key in memory.
+func (r *memoryRepository) RevokeAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if key.RevokedAt.IsZero() {
+		key.RevokedAt = time.Now()
+	}
+
+	result := *key
+	return &result, nil
+}
+
+// RecordUsage adds to a daily usage rollup in memory.
+func (r *memoryRepository) RecordUsage(ctx context.Context, event UsageEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := usageKey{keyID: event.KeyID, day: utcDay(event.At)}
+	usage, ok := r.usage[key]
+	if !ok {
+		usage = &DailyUsage{KeyID: key.keyID, Day: key.day}
+		r.usage[key] = usage
+	}
+	usage.add(event)
 	return nil
 }
 
-// Close releases PostgreSQL connection pool.
-func (r *postgresRepository) Close() error {
-	// TODO: Actual close
-	// r.db.Close()
+// ListUsage returns a key's daily usage rollups from memory, oldest first.
+func (r *memoryRepository) ListUsage(ctx context.Context, keyID string, from, to time.Time) ([]DailyUsage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	from, to = utcDay(from), utcDay(to)
+	var days []DailyUsage
+	for key, usage := range r.usage {
+		if key.keyID != keyID || key.day.Before(from) || !key.day.Before(to) {
+			continue
+		}
+		day := *usage
+		day.ContentTypes = maps.Clone(usage.ContentTypes)
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day.Before(days[j].Day) })
+	return days, nil
+}
+
+// GetStats aggregates the completed jobs in memory.
+func (r *memoryRepository) GetStats(ctx context.Context, from, to time.Time) (*Stats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var (
+		stats      Stats
+		confidence float64
+		days       = make(map[time.Time]int64)
+		types      = make(map[string]*ContentTypeStats)
+		phrases    = make(map[string]int64)
+	)
+	for _, job := range r.jobs {
+		if job.Status != JobStatusCompleted && job.Status != "" {
+			continue
+		}
+		if !from.IsZero() && job.CreatedAt.Before(from) || !to.IsZero() && !job.CreatedAt.Before(to) {
+			continue
+		}
+
+		stats.Total++
+		confidence += job.Confidence
+		days[utcDay(job.CreatedAt)]++
+		if types[job.ContentType] == nil {
+			types[job.ContentType] = &ContentTypeStats{ContentType: job.ContentType}
+		}
+		types[job.ContentType].add(statsVerdict(job), 1)
+		stats.AIScoreHistogram[histogramBucket(job.AIScore)]++
+		if job.Details != nil {
+			for _, p := range job.Details.AIPhrases {
+				phrases[p.Phrase]++
+			}
+		}
+	}
+
+	if stats.Total > 0 {
+		stats.AverageConfidence = confidence / float64(stats.Total)
+	}
+	for day, n := range days {
+		stats.Daily = append(stats.Daily, DayCount{Day: day, Jobs: n})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool { return stats.Daily[i].Day.Before(stats.Daily[j].Day) })
+	for _, t := range types {
+		stats.ContentTypes = append(stats.ContentTypes, *t)
+	}
+	sort.Slice(stats.ContentTypes, func(i, j int) bool {
+		return stats.ContentTypes[i].ContentType < stats.ContentTypes[j].ContentType
+	})
+	for phrase, n := range phrases {
+		stats.TopAIPhrases = append(stats.TopAIPhrases, PhraseCount{Phrase: phrase, Count: n})
+	}
+	stats.TopAIPhrases = sortPhraseCounts(stats.TopAIPhrases)
+
+	return &stats, nil
+}
+
+// Ping always succeeds for in-memory repository.
+func (r *memoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close stops the background sweeper, if any.
+func (r *memoryRepository) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
 	return nil
 }
 
@@ -214,3 +1266,40 @@ func generateID() string {
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
 }
+
+// cursor is the (CreatedAt, ID) key of the last job on a page.
+type cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// precedes reports whether job sorts after the cursor, newest first.
+func (c cursor) precedes(job *Job) bool {
+	if !job.CreatedAt.Equal(c.CreatedAt) {
+		return job.CreatedAt.Before(c.CreatedAt)
+	}
+	return job.ID < c.ID
+}
+
+// encodeCursor returns an opaque cursor for the page ending at job.
+func encodeCursor(job Job) string {
+	key := strconv.FormatInt(job.CreatedAt.UnixNano(), 10) + "." + job.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeCursor parses a cursor returned by encodeCursor.
+func decodeCursor(s string) (cursor, error) {
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	nanos, id, ok := strings.Cut(string(key), ".")
+	if !ok || id == "" {
+		return cursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return cursor{CreatedAt: time.Unix(0, n), ID: id}, nil
+}
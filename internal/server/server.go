@@ -0,0 +1,195 @@
+// Package server runs the HTTP server together with the background
+// components behind it, and shuts them all down in order.
+//
+// Shutdown, which cmd/api runs on SIGINT or SIGTERM, goes:
+//
+//  1. The listener closes, so new connections are refused, and in-flight
+//     requests (detections included) are allowed to finish.
+//  2. Async workers finish the jobs they're running; queued jobs stay
+//     pending in the repository.
+//  3. The retention sweeper and rate limiter cleanup stop, and the audit
+//     log closes.
+//  4. The repository closes, and buffered traces are flushed.
+//
+// Steps 1 and 2 share the context passed to Shutdown. If it expires while
+// requests are still running their connections are closed, and running
+// jobs are cancelled and put back to pending; the later steps still run
+// so the repository is closed cleanly.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/audit"
+	"github.com/humanmark/humanmark/internal/middleware"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/worker"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// traceFlushTimeout bounds sending buffered spans on shutdown.
+const traceFlushTimeout = 5 * time.Second
+
+// Config holds configuration for creating a Server.
+type Config struct {
+	// HTTP serves the API
+	HTTP *http.Server
+
+	// Listener is what HTTP serves on (optional - defaults to listening
+	// on HTTP.Addr in Start)
+	Listener net.Listener
+
+	// Worker runs async verifications (optional)
+	Worker *worker.Pool
+
+	// Sweeper enforces data retention (optional)
+	Sweeper *worker.Sweeper
+
+	// RateLimiter's cleanup goroutine is stopped on shutdown (optional)
+	RateLimiter *middleware.RateLimiter
+
+	// AuditLog is closed once the worker has stopped, so no verdict goes
+	// unrecorded (optional)
+	AuditLog *audit.JSONLogger
+
+	// Repository is closed last, once nothing can write to it (optional)
+	Repository repository.Repository
+
+	// FlushTraces sends buffered spans on shutdown (optional)
+	FlushTraces func(context.Context) error
+
+	Logger *logger.Logger
+}
+
+// Server owns the HTTP server and the components it depends on.
+type Server struct {
+	http        *http.Server
+	listener    net.Listener
+	worker      *worker.Pool
+	sweeper     *worker.Sweeper
+	rateLimiter *middleware.RateLimiter
+	auditLog    *audit.JSONLogger
+	repository  repository.Repository
+	flushTraces func(context.Context) error
+	logger      *logger.Logger
+
+	errs    chan error
+	started bool
+}
+
+// New creates a Server. Call Start to begin serving.
+func New(cfg Config) *Server {
+	return &Server{
+		http:        cfg.HTTP,
+		listener:    cfg.Listener,
+		worker:      cfg.Worker,
+		sweeper:     cfg.Sweeper,
+		rateLimiter: cfg.RateLimiter,
+		auditLog:    cfg.AuditLog,
+		repository:  cfg.Repository,
+		flushTraces: cfg.FlushTraces,
+		logger:      cfg.Logger,
+		errs:        make(chan error, 1),
+	}
+}
+
+// Start starts the background components and serves HTTP in the
+// background. It returns once the listener is open, or with the error if
+// it can't be; a later serving error is sent on Errors.
+func (s *Server) Start() error {
+	if s.listener == nil {
+		l, err := net.Listen("tcp", s.http.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", s.http.Addr, err)
+		}
+		s.listener = l
+	}
+
+	if s.worker != nil {
+		s.worker.Start()
+	}
+	if s.sweeper != nil {
+		s.sweeper.Start()
+	}
+	s.started = true
+
+	go func() {
+		if err := s.http.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.errs <- err
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address being served, once Start has returned.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Errors receives the error if serving stops for any reason other than
+// Shutdown.
+func (s *Server) Errors() <-chan error {
+	return s.errs
+}
+
+// Shutdown stops the server as described in the package documentation.
+// The error joins whatever went wrong along the way, including ctx
+// expiring before requests or jobs finished. Call it once.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	if s.started {
+		if err := s.http.Shutdown(ctx); err != nil {
+			s.logger.Error("in-flight requests didn't finish in time", "error", err)
+			s.http.Close()
+			errs = append(errs, fmt.Errorf("http server: %w", err))
+		}
+	}
+
+	if s.worker != nil {
+		if err := s.worker.Stop(ctx); err != nil {
+			s.logger.Error("async jobs cancelled before they finished", "error", err)
+			errs = append(errs, fmt.Errorf("worker pool: %w", err))
+		}
+	}
+
+	// The sweeper only stops once started
+	if s.sweeper != nil && s.started {
+		s.sweeper.Stop()
+	}
+
+	if s.rateLimiter != nil {
+		s.rateLimiter.Close()
+	}
+
+	if s.auditLog != nil {
+		if err := s.auditLog.Close(); err != nil {
+			s.logger.Error("error closing audit log", "error", err)
+			errs = append(errs, fmt.Errorf("audit log: %w", err))
+		}
+	}
+
+	if s.repository != nil {
+		if err := s.repository.Close(); err != nil {
+			s.logger.Error("error closing repository", "error", err)
+			errs = append(errs, fmt.Errorf("repository: %w", err))
+		}
+	}
+
+	// Traces get their own time, so those of a slow shutdown aren't lost
+	if s.flushTraces != nil {
+		flushCtx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+		defer cancel()
+		if err := s.flushTraces(flushCtx); err != nil {
+			s.logger.Error("error flushing traces", "error", err)
+			errs = append(errs, fmt.Errorf("tracing: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
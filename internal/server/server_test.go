@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/handler"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/internal/worker"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// slowDetector implements service.Detector, holding every detection until
+// release is closed.
+type slowDetector struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newSlowDetector() *slowDetector {
+	return &slowDetector{started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (d *slowDetector) Detect(ctx context.Context, input service.DetectionInput) (*service.DetectionResult, error) {
+	d.started <- struct{}{}
+	select {
+	case <-d.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &service.DetectionResult{
+		Human:       true,
+		Verdict:     service.VerdictHuman,
+		Confidence:  0.9,
+		AIScore:     0.1,
+		ContentType: service.ContentTypeText,
+		Detectors:   []string{"slow"},
+	}, nil
+}
+
+// result is the outcome of a request made in the background.
+type result struct {
+	status int
+	body   string
+	err    error
+}
+
+// startServer serves POST /verify backed by detector on an httptest
+// listener.
+func startServer(t *testing.T, detector service.Detector, pool *worker.Pool, repo repository.Repository) *Server {
+	t.Helper()
+
+	h := handler.New(handler.Config{
+		Detector:      detector,
+		Repository:    repo,
+		Logger:        logger.NopLogger(),
+		MaxUploadSize: 1024 * 1024,
+		Queue:         pool,
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /verify", h.Verify)
+
+	ts := httptest.NewUnstartedServer(mux)
+	srv := New(Config{
+		HTTP:       ts.Config,
+		Listener:   ts.Listener,
+		Worker:     pool,
+		Repository: repo,
+		Logger:     logger.NopLogger(),
+	})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return srv
+}
+
+// verify POSTs some text to srv in the background, on a connection of its
+// own.
+func verify(srv *Server, target string) <-chan result {
+	results := make(chan result, 1)
+	go func() {
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Post("http://"+srv.Addr().String()+target, "application/json",
+			strings.NewReader(`{"text": "Some text written by a person."}`))
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+	return results
+}
+
+// TestShutdown tests that a request in flight when shutdown starts
+// completes, while new requests are refused.
+func TestShutdown(t *testing.T) {
+	detector := newSlowDetector()
+	srv := startServer(t, detector, nil, repository.NewMemory())
+
+	inFlight := verify(srv, "/verify")
+	<-detector.started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- srv.Shutdown(ctx)
+	}()
+
+	// Shutdown closes the listener first; once it has, new requests fail
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r := <-verify(srv, "/verify")
+		if r.err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("new requests still served during shutdown, got status %d", r.status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case r := <-inFlight:
+		t.Fatalf("in-flight request ended before its detection finished: %+v", r)
+	case err := <-shutdown:
+		t.Fatalf("Shutdown() returned %v with a request in flight", err)
+	default:
+	}
+
+	close(detector.release)
+	r := <-inFlight
+	if r.err != nil {
+		t.Fatalf("in-flight request failed: %v", r.err)
+	}
+	if r.status != http.StatusOK || !strings.Contains(r.body, `"verdict":"human"`) {
+		t.Errorf("expected the in-flight result, got %d: %s", r.status, r.body)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+// TestShutdown_Timeout tests that shutdown gives up on requests and async
+// jobs when its context expires, leaving the jobs pending.
+func TestShutdown_Timeout(t *testing.T) {
+	detector := newSlowDetector()
+	repo := repository.NewMemory()
+	pool := worker.New(worker.Config{
+		Detector:   detector,
+		Repository: repo,
+		Logger:     logger.NopLogger(),
+		Workers:    1,
+	})
+	srv := startServer(t, detector, pool, repo)
+
+	accepted := <-verify(srv, "/verify?async=true")
+	if accepted.status != http.StatusAccepted {
+		t.Fatalf("expected 202 for the async request, got %d: %s", accepted.status, accepted.body)
+	}
+	var job struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal([]byte(accepted.body), &job)
+	<-detector.started
+
+	inFlight := verify(srv, "/verify")
+	<-detector.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if r := <-inFlight; r.err == nil {
+		t.Errorf("expected the in-flight request to be cut off, got %d", r.status)
+	}
+	got, err := repo.GetJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("GetJob(%q) error = %v", job.ID, err)
+	}
+	if got.Status != repository.JobStatusPending {
+		t.Errorf("expected the async job left pending, got %s", got.Status)
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// Abstracts in the style of published research: specific, cited, hedged.
+const (
+	neuroAbstract = `Mitochondrial dysfunction has been implicated in several neurodegenerative disorders, but its contribution to early-stage pathology remains unclear. In this study, we measured ATP synthesis and ROS production in iPSC-derived neurons from 24 patients with sporadic PD and 18 age-matched controls. Patient-derived neurons showed a 31% reduction in complex I activity (p < 0.01), consistent with earlier post-mortem findings [4, 7]. Surprisingly, ROS levels did not differ between groups (n = 42). These results suggest that bioenergetic deficits might precede oxidative damage, although the small cohort limits how far this can be generalized. To our knowledge, this is the first report of such a dissociation in patient-derived cells (Schapira et al., 2008).`
+
+	economicsAbstract = `We examine whether minimum wage increases affect teen employment in counties along state borders. Using QCEW data from 1990 to 2016 and a contiguous county-pair design, we find employment elasticities close to zero (-0.04, 95% CI -0.11 to 0.03). Earnings rise by roughly 2% for each 10% increase in the minimum. Our findings are consistent with monopsony models (Card and Krueger, 1994) and appear to contradict earlier panel estimates [12]. We argue that spatial heterogeneity in pre-trends could suggest why the two literatures disagree.`
+)
+
+// AI-generated summaries of the same topics: generically formal.
+const (
+	neuroSummary = `Mitochondrial dysfunction plays a crucial role in neurodegenerative diseases. This demonstrates the importance of cellular energy production for neuronal health. Furthermore, oxidative stress is a multifaceted process that affects various aspects of brain function. It is evident that understanding these mechanisms will shed light on potential therapeutic strategies. Moreover, this highlights the need for a comprehensive understanding of the interplay between metabolism and disease. In conclusion, research in this area offers valuable insights and has significant implications for the future of medicine.`
+
+	economicsSummary = `Minimum wage policy plays a pivotal role in shaping labor markets. It is important to note that the effects of minimum wage increases are multifaceted. Furthermore, this underscores the complexity of balancing worker welfare with business interests. Overall, it is evident that policymakers must adopt a holistic approach. This demonstrates that careful analysis of various aspects of the economy is essential. In conclusion, understanding these dynamics offers valuable insights and has significant implications for effective policy.`
+)
+
+// TestAnalyzeAcademicRegister tests the register signal on abstracts and
+// AI summaries.
+func TestAnalyzeAcademicRegister(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	tests := []struct {
+		name  string
+		text  string
+		human bool
+	}{
+		{"neuroscience abstract", neuroAbstract, true},
+		{"economics abstract", economicsAbstract, true},
+		{"neuroscience AI summary", neuroSummary, false},
+		{"economics AI summary", economicsSummary, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := analyzer.analyzeAcademicRegister(tt.text)
+			if tt.human && score > 0.3 {
+				t.Errorf("register = %f, want at most 0.3 for human academic writing", score)
+			}
+			if !tt.human && score < 0.7 {
+				t.Errorf("register = %f, want at least 0.7 for AI formal writing", score)
+			}
+		})
+	}
+
+	t.Run("empty text is neutral", func(t *testing.T) {
+		if score := analyzer.analyzeAcademicRegister(""); score != 0.5 {
+			t.Errorf("register = %f, want 0.5", score)
+		}
+	})
+}
+
+// TestAcademicPatterns tests the citation, jargon and phrase matching.
+func TestAcademicPatterns(t *testing.T) {
+	citations := []string{"[1]", "[2, 5]", "[3-7]", "Smith et al.", "(Smith, 2019)", "(Card and Krueger, 1994)", "(Lee 2020a)"}
+	for _, c := range citations {
+		if !citationPattern.MatchString(c) {
+			t.Errorf("citationPattern should match %q", c)
+		}
+	}
+	for _, c := range []string{"[a]", "(see below)", "(2019)"} {
+		if citationPattern.MatchString(c) {
+			t.Errorf("citationPattern should not match %q", c)
+		}
+	}
+
+	for _, j := range []string{"RNA", "fMRI", "p < 0.05", "n = 42", "95% CI"} {
+		if !academicJargonPattern.MatchString(j) {
+			t.Errorf("academicJargonPattern should match %q", j)
+		}
+	}
+
+	if got := countPhrases("a mighty river might flood; it might not", []string{"might"}); got != 2 {
+		t.Errorf("countPhrases matched %d times, want 2 whole words", got)
+	}
+}
+
+// TestAnalyzeAcademic tests academic-mode scoring.
+func TestAnalyzeAcademic(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	for _, pair := range []struct{ abstract, summary string }{
+		{neuroAbstract, neuroSummary},
+		{economicsAbstract, economicsSummary},
+	} {
+		abstract := analyzer.AnalyzeAcademic(pair.abstract)
+		summary := analyzer.AnalyzeAcademic(pair.summary)
+		if abstract.AIScore >= summary.AIScore {
+			t.Errorf("abstract AIScore = %f, want below AI summary %f", abstract.AIScore, summary.AIScore)
+		}
+
+		// Academic mode gives human abstracts credit for their conventions
+		if plain := analyzer.Analyze(pair.abstract); abstract.AIScore >= plain.AIScore {
+			t.Errorf("academic AIScore = %f, want below plain analysis %f", abstract.AIScore, plain.AIScore)
+		}
+	}
+
+	t.Run("Analyze leaves the register unset", func(t *testing.T) {
+		if got := analyzer.Analyze(neuroSummary).Signals.AcademicRegister; got != 0 {
+			t.Errorf("AcademicRegister = %f, want 0 outside academic mode", got)
+		}
+	})
+}
+
+// TestDetectTextAcademicMode tests that the text detector uses academic
+// analysis.
+func TestDetectTextAcademicMode(t *testing.T) {
+	d := NewTextDetector(DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+	ctx := context.Background()
+
+	plain, err := d.DetectText(ctx, DetectionInput{Text: neuroAbstract})
+	if err != nil {
+		t.Fatalf("DetectText() error = %v", err)
+	}
+	academic, err := d.DetectText(ctx, DetectionInput{Text: neuroAbstract, AcademicMode: true})
+	if err != nil {
+		t.Fatalf("DetectText() error = %v", err)
+	}
+
+	if academic.AIScore >= plain.AIScore {
+		t.Errorf("academic mode AIScore = %f, want below plain text %f", academic.AIScore, plain.AIScore)
+	}
+}
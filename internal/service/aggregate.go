@@ -0,0 +1,387 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// =============================================================================
+// Score Aggregation
+// =============================================================================
+//
+// Every detector combines its local analyzer with any configured external
+// providers. How those scores are combined is selectable:
+//
+//   weighted-mean  Reliability-weighted average (default)
+//   max            Most AI-like provider wins
+//   median         Middle score, robust to one outlier provider
+//   veto           Weighted mean, unless one provider is very sure it's AI or
+//                  the content carries conclusive provenance evidence
+//
+// The weighted mean lets a single confident provider be diluted by neutral
+// local scores; the other strategies trade that off differently.
+//
+// The combined score is then turned into a verdict by the DecisionPolicy.
+// Scores between its human and AI thresholds are "uncertain", so callers
+// can trade precision for recall on either side. A policy that splits at
+// one score instead has an uncertainty band around it: scores that close
+// to the split are a coin flip.
+//
+// A combined score hides how far apart the providers were: 0.3 and 0.9
+// average to 0.6 just like 0.55 and 0.65 do. Disagreement is the weighted
+// standard deviation of the provider scores. Above the configured
+// DisagreementThreshold, the result is flagged NeedsReview and its
+// confidence capped at DisagreementConfidenceCap, so moderation queues can
+// send it to a person.
+//
+// A score from the local analyzer alone is much weaker evidence than one
+// external providers agree with. Each result records its Basis, and
+// local-only results have their confidence capped at the configured
+// LocalOnlyConfidenceCap.
+//
+// =============================================================================
+
+// AggregationStrategy selects how provider scores are combined.
+type AggregationStrategy string
+
+const (
+	AggregationWeightedMean AggregationStrategy = "weighted-mean"
+	AggregationMax          AggregationStrategy = "max"
+	AggregationMedian       AggregationStrategy = "median"
+	AggregationVeto         AggregationStrategy = "veto"
+)
+
+// VetoThreshold is the provider score at or above which the veto strategy
+// forces an AI verdict.
+const VetoThreshold = 0.9
+
+// DisagreementConfidenceCap is the highest confidence reported for a result
+// whose providers disagree beyond DetectorConfig.DisagreementThreshold.
+const DisagreementConfidenceCap = 0.5
+
+// ParseAggregationStrategy validates a strategy name.
+// An empty name selects the weighted mean.
+func ParseAggregationStrategy(name string) (AggregationStrategy, error) {
+	switch s := AggregationStrategy(name); s {
+	case "":
+		return AggregationWeightedMean, nil
+	case AggregationWeightedMean, AggregationMax, AggregationMedian, AggregationVeto:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown aggregation strategy %q (must be weighted-mean, max, median, or veto)", name)
+	}
+}
+
+// Evidence is conclusive provenance found in the content itself, as opposed
+// to a statistical score.
+type Evidence string
+
+const (
+	// EvidenceNone means nothing conclusive was found
+	EvidenceNone Evidence = ""
+
+	// EvidenceAI means an AI generator watermark or marker was found
+	EvidenceAI Evidence = "ai"
+
+	// EvidenceHuman means a verified capture manifest (e.g. C2PA) was found
+	EvidenceHuman Evidence = "human"
+)
+
+// Basis summarizes the evidence a result rests on: BasisConclusiveMarker,
+// BasisLocalOnly, or "local+N-external" when N external providers scored
+// the content too ("N-external" if the local detectors didn't run).
+type Basis string
+
+const (
+	// BasisLocalOnly means only detectors running on this server scored
+	// the content
+	BasisLocalOnly Basis = "local-only"
+
+	// BasisConclusiveMarker means the content carried conclusive
+	// provenance (see Evidence), whatever the detectors scored
+	BasisConclusiveMarker Basis = "conclusive-marker"
+)
+
+// detectionBasis returns the Basis of a result scored by detectors, with
+// evidence found in the content.
+func detectionBasis(detectors []string, evidence Evidence) Basis {
+	if evidence != EvidenceNone {
+		return BasisConclusiveMarker
+	}
+
+	local, external := false, 0
+	for _, name := range detectors {
+		if localDetectors[name] {
+			local = true
+		} else {
+			external++
+		}
+	}
+	switch {
+	case external == 0:
+		return BasisLocalOnly
+	case !local:
+		return Basis(fmt.Sprintf("%d-external", external))
+	default:
+		return Basis(fmt.Sprintf("local+%d-external", external))
+	}
+}
+
+// Verdict is the outcome of detection.
+type Verdict string
+
+const (
+	VerdictHuman     Verdict = "human"
+	VerdictAI        Verdict = "ai"
+	VerdictUncertain Verdict = "uncertain"
+)
+
+// DecisionPolicy turns an AI score into a verdict. Scores at or above
+// AIThreshold are AI, scores below HumanThreshold are human, and anything
+// in between is uncertain. Zero thresholds select DefaultDecisionPolicy's.
+//
+// UncertaintyBand only applies when both thresholds are the same split:
+// scores closer than it to the split are uncertain. Thresholds with room
+// between them already say where uncertain starts, so the band never
+// overrides them.
+type DecisionPolicy struct {
+	AIThreshold     float64
+	HumanThreshold  float64
+	UncertaintyBand float64
+}
+
+// DefaultDecisionPolicy splits at 0.5 with no uncertain band.
+func DefaultDecisionPolicy() DecisionPolicy {
+	return DecisionPolicy{AIThreshold: 0.5, HumanThreshold: 0.5}
+}
+
+// Validate checks that both thresholds are in 0-1, the human threshold
+// doesn't exceed the AI threshold, and the band is in 0-0.5.
+func (p DecisionPolicy) Validate() error {
+	if p.AIThreshold < 0 || p.AIThreshold > 1 || p.HumanThreshold < 0 || p.HumanThreshold > 1 {
+		return fmt.Errorf("decision thresholds must be between 0 and 1 (ai=%g, human=%g)", p.AIThreshold, p.HumanThreshold)
+	}
+	if p.HumanThreshold > p.AIThreshold {
+		return fmt.Errorf("human threshold %g is above AI threshold %g", p.HumanThreshold, p.AIThreshold)
+	}
+	if p.UncertaintyBand < 0 || p.UncertaintyBand > 0.5 {
+		return fmt.Errorf("uncertainty band %g must be between 0 and 0.5", p.UncertaintyBand)
+	}
+	return nil
+}
+
+// withDefaults returns p with DefaultDecisionPolicy's thresholds if it
+// has none.
+func (p DecisionPolicy) withDefaults() DecisionPolicy {
+	if p.AIThreshold == 0 && p.HumanThreshold == 0 {
+		def := DefaultDecisionPolicy()
+		p.AIThreshold, p.HumanThreshold = def.AIThreshold, def.HumanThreshold
+	}
+	return p
+}
+
+// Verdict returns the verdict for aiScore.
+func (p DecisionPolicy) Verdict(aiScore float64) Verdict {
+	p = p.withDefaults()
+	if p.AIThreshold == p.HumanThreshold && abs(aiScore-p.AIThreshold) < p.UncertaintyBand {
+		return VerdictUncertain
+	}
+
+	switch {
+	case aiScore >= p.AIThreshold:
+		return VerdictAI
+	case aiScore < p.HumanThreshold:
+		return VerdictHuman
+	default:
+		return VerdictUncertain
+	}
+}
+
+// ProviderScore is one provider's score before aggregation.
+type ProviderScore struct {
+	// Name is the detector name, e.g. "humanmark" or "hive"
+	Name string
+
+	// AIScore is the provider's AI probability (0.0-1.0)
+	AIScore float64
+
+	// Weight is the provider's reliability weight in the weighted mean
+	Weight float64
+}
+
+// newProviderScores pairs scores with detector names and reliability weights.
+// Detectors missing from weights get 1.0.
+func newProviderScores(scores []float64, detectors []string, weights map[string]float64) []ProviderScore {
+	providers := make([]ProviderScore, len(scores))
+	for i, score := range scores {
+		p := ProviderScore{AIScore: score, Weight: 1.0}
+		if i < len(detectors) {
+			p.Name = detectors[i]
+			if w, ok := weights[p.Name]; ok {
+				p.Weight = w
+			}
+		}
+		providers[i] = p
+	}
+	return providers
+}
+
+// withWeightOverrides returns defaults with configured weights applied.
+func withWeightOverrides(defaults, overrides map[string]float64) map[string]float64 {
+	if len(overrides) == 0 {
+		return defaults
+	}
+
+	weights := make(map[string]float64, len(defaults)+len(overrides))
+	for name, w := range defaults {
+		weights[name] = w
+	}
+	for name, w := range overrides {
+		weights[name] = w
+	}
+	return weights
+}
+
+// aggregateScores combines provider scores using strategy.
+// Returns 0.5 (neutral) when there are no scores and no evidence.
+func aggregateScores(strategy AggregationStrategy, providers []ProviderScore, evidence Evidence) float64 {
+	if strategy == AggregationVeto {
+		switch evidence {
+		case EvidenceAI:
+			return 1.0
+		case EvidenceHuman:
+			return 0.0
+		}
+	}
+
+	if len(providers) == 0 {
+		return 0.5
+	}
+
+	switch strategy {
+	case AggregationMax:
+		return maxScore(providers)
+	case AggregationMedian:
+		return medianScore(providers)
+	case AggregationVeto:
+		if top := maxScore(providers); top >= VetoThreshold {
+			return top
+		}
+		return weightedMeanScore(providers)
+	default:
+		return weightedMeanScore(providers)
+	}
+}
+
+// weightedMeanScore averages scores by provider weight.
+func weightedMeanScore(providers []ProviderScore) float64 {
+	totalWeight := 0.0
+	weightedSum := 0.0
+
+	for _, p := range providers {
+		weightedSum += p.AIScore * p.Weight
+		totalWeight += p.Weight
+	}
+
+	if totalWeight == 0 {
+		return 0.5
+	}
+
+	return weightedSum / totalWeight
+}
+
+// maxScore returns the most AI-like score.
+func maxScore(providers []ProviderScore) float64 {
+	top := providers[0].AIScore
+	for _, p := range providers[1:] {
+		if p.AIScore > top {
+			top = p.AIScore
+		}
+	}
+	return top
+}
+
+// medianScore returns the middle score (mean of the two middle scores for
+// an even count).
+func medianScore(providers []ProviderScore) float64 {
+	scores := make([]float64, len(providers))
+	for i, p := range providers {
+		scores[i] = p.AIScore
+	}
+	sort.Float64s(scores)
+
+	mid := len(scores) / 2
+	if len(scores)%2 == 0 {
+		return (scores[mid-1] + scores[mid]) / 2
+	}
+	return scores[mid]
+}
+
+// scoreDisagreement returns the weighted standard deviation of provider
+// scores: 0 when they agree (or there's only one), up to 0.5 when they're
+// split between 0 and 1.
+func scoreDisagreement(providers []ProviderScore) float64 {
+	if len(providers) < 2 {
+		return 0
+	}
+
+	totalWeight := 0.0
+	for _, p := range providers {
+		totalWeight += p.Weight
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+
+	mean := weightedMeanScore(providers)
+	variance := 0.0
+	for _, p := range providers {
+		variance += p.Weight * (p.AIScore - mean) * (p.AIScore - mean)
+	}
+	return math.Sqrt(variance / totalWeight)
+}
+
+// buildResult aggregates provider scores into a DetectionResult.
+// Shared by all content-type detectors so verdicts are computed the same way.
+func buildResult(config DetectorConfig, contentType ContentType, scores []float64, detectors []string, weights map[string]float64, evidence Evidence) *DetectionResult {
+	strategy, err := ParseAggregationStrategy(string(config.Aggregation))
+	if err != nil {
+		strategy = AggregationWeightedMean
+	}
+
+	providers := newProviderScores(scores, detectors, withWeightOverrides(weights, config.ProviderWeights))
+
+	result := &DetectionResult{
+		ContentType:    contentType,
+		Detectors:      detectors,
+		Aggregation:    strategy,
+		ProviderScores: providers,
+		Evidence:       evidence,
+		Basis:          detectionBasis(detectors, evidence),
+		Disagreement:   scoreDisagreement(providers),
+	}
+	result.setScore(config, aggregateScores(strategy, providers, evidence))
+	return result
+}
+
+// setScore sets the AI score and the verdict and confidence that follow
+// from it, capping confidence when the providers disagreed or only the
+// local detectors ran.
+func (r *DetectionResult) setScore(config DetectorConfig, aiScore float64) {
+	verdict := config.Decision.Verdict(aiScore)
+
+	r.AIScore = aiScore
+	r.Verdict = verdict
+	r.Human = verdict == VerdictHuman
+	r.Uncertain = verdict == VerdictUncertain
+	r.Confidence = abs(aiScore-0.5) * 2 // Convert to 0-1 confidence scale
+
+	r.NeedsReview = config.DisagreementThreshold > 0 && r.Disagreement > config.DisagreementThreshold
+	if r.NeedsReview && r.Confidence > DisagreementConfidenceCap {
+		r.Confidence = DisagreementConfidenceCap
+	}
+	if r.Basis == BasisLocalOnly && config.LocalOnlyConfidenceCap > 0 && r.Confidence > config.LocalOnlyConfidenceCap {
+		r.Confidence = config.LocalOnlyConfidenceCap
+	}
+}
@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAggregateScores tests each aggregation strategy.
+func TestAggregateScores(t *testing.T) {
+	// A neutral local score and one confident external provider
+	providers := []ProviderScore{
+		{Name: "humanmark", AIScore: 0.5, Weight: 1.0},
+		{Name: "hive", AIScore: 0.95, Weight: 1.3},
+		{Name: "gptzero", AIScore: 0.4, Weight: 1.1},
+	}
+
+	tests := []struct {
+		name     string
+		strategy AggregationStrategy
+		evidence Evidence
+		want     float64
+	}{
+		{"weighted mean", AggregationWeightedMean, EvidenceNone, (0.5*1.0 + 0.95*1.3 + 0.4*1.1) / 3.4},
+		{"empty strategy is weighted mean", "", EvidenceNone, (0.5*1.0 + 0.95*1.3 + 0.4*1.1) / 3.4},
+		{"max", AggregationMax, EvidenceNone, 0.95},
+		{"median", AggregationMedian, EvidenceNone, 0.5},
+		{"veto by confident provider", AggregationVeto, EvidenceNone, 0.95},
+		{"veto by AI evidence", AggregationVeto, EvidenceAI, 1.0},
+		{"veto by human evidence", AggregationVeto, EvidenceHuman, 0.0},
+		{"evidence ignored outside veto", AggregationMax, EvidenceHuman, 0.95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := aggregateScores(tt.strategy, providers, tt.evidence)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("aggregateScores() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("veto below threshold falls back to weighted mean", func(t *testing.T) {
+		mild := []ProviderScore{
+			{Name: "humanmark", AIScore: 0.2, Weight: 1.0},
+			{Name: "hive", AIScore: 0.8, Weight: 1.0},
+		}
+		if got := aggregateScores(AggregationVeto, mild, EvidenceNone); math.Abs(got-0.5) > 1e-9 {
+			t.Errorf("aggregateScores() = %f, want 0.5", got)
+		}
+	})
+
+	t.Run("median of even count", func(t *testing.T) {
+		even := []ProviderScore{{AIScore: 0.2}, {AIScore: 0.9}, {AIScore: 0.4}, {AIScore: 0.6}}
+		if got := aggregateScores(AggregationMedian, even, EvidenceNone); math.Abs(got-0.5) > 1e-9 {
+			t.Errorf("aggregateScores() = %f, want 0.5", got)
+		}
+	})
+
+	t.Run("no scores is neutral", func(t *testing.T) {
+		for _, s := range []AggregationStrategy{AggregationWeightedMean, AggregationMax, AggregationMedian, AggregationVeto} {
+			if got := aggregateScores(s, nil, EvidenceNone); got != 0.5 {
+				t.Errorf("%s: aggregateScores(nil) = %f, want 0.5", s, got)
+			}
+		}
+	})
+}
+
+// TestParseAggregationStrategy tests strategy name validation.
+func TestParseAggregationStrategy(t *testing.T) {
+	for _, name := range []string{"weighted-mean", "max", "median", "veto"} {
+		if got, err := ParseAggregationStrategy(name); err != nil || string(got) != name {
+			t.Errorf("ParseAggregationStrategy(%q) = %q, %v", name, got, err)
+		}
+	}
+
+	if got, _ := ParseAggregationStrategy(""); got != AggregationWeightedMean {
+		t.Errorf("empty name should default to weighted-mean, got %q", got)
+	}
+	if _, err := ParseAggregationStrategy("average"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+// TestBuildResult verifies the result records how the verdict was reached.
+func TestBuildResult(t *testing.T) {
+	config := DetectorConfig{Aggregation: AggregationMax}
+	weights := map[string]float64{"humanmark": 1.0, "hive": 1.3}
+
+	result := buildResult(config, ContentTypeImage, []float64{0.3, 0.8}, []string{"humanmark", "hive"}, weights, EvidenceNone)
+
+	if result.Aggregation != AggregationMax {
+		t.Errorf("expected aggregation max, got %q", result.Aggregation)
+	}
+	if result.AIScore != 0.8 || result.Human {
+		t.Errorf("expected AI verdict with score 0.8, got human=%v score=%f", result.Human, result.AIScore)
+	}
+	if len(result.ProviderScores) != 2 {
+		t.Fatalf("expected 2 provider scores, got %d", len(result.ProviderScores))
+	}
+	if p := result.ProviderScores[1]; p.Name != "hive" || p.AIScore != 0.8 || p.Weight != 1.3 {
+		t.Errorf("unexpected provider score: %+v", p)
+	}
+}
+
+// TestBuildResultWeightOverrides verifies configured weights replace defaults.
+func TestBuildResultWeightOverrides(t *testing.T) {
+	config := DetectorConfig{ProviderWeights: map[string]float64{"hive": 3.0}}
+	weights := map[string]float64{"humanmark": 1.0, "hive": 1.3}
+
+	result := buildResult(config, ContentTypeText, []float64{0.25, 0.75}, []string{"humanmark", "hive"}, weights, EvidenceNone)
+
+	if p := result.ProviderScores[1]; p.Weight != 3.0 {
+		t.Errorf("expected overridden weight 3.0, got %f", p.Weight)
+	}
+	if result.AIScore != 0.625 { // (0.25*1 + 0.75*3) / 4
+		t.Errorf("expected score 0.625, got %f", result.AIScore)
+	}
+	if weights["hive"] != 1.3 {
+		t.Error("defaults should not be modified")
+	}
+}
+
+// TestNewDetectorRejectsUnknownStrategy verifies misconfiguration fails at startup.
+func TestNewDetectorRejectsUnknownStrategy(t *testing.T) {
+	if _, err := NewDetector(DetectorConfig{Aggregation: "average"}, nil); err == nil {
+		t.Error("expected error for unknown aggregation strategy")
+	}
+}
+
+// TestDecisionPolicy verifies scores map to the three verdict bands.
+func TestDecisionPolicy(t *testing.T) {
+	banded := DecisionPolicy{AIThreshold: 0.75, HumanThreshold: 0.25}
+
+	tests := []struct {
+		name   string
+		policy DecisionPolicy
+		score  float64
+		want   Verdict
+	}{
+		{"below human threshold", banded, 0.2, VerdictHuman},
+		{"at human threshold", banded, 0.25, VerdictUncertain},
+		{"between thresholds", banded, 0.5, VerdictUncertain},
+		{"at AI threshold", banded, 0.75, VerdictAI},
+		{"default splits at 0.5", DefaultDecisionPolicy(), 0.5, VerdictAI},
+		{"default below 0.5", DefaultDecisionPolicy(), 0.49, VerdictHuman},
+		{"zero value uses default", DecisionPolicy{}, 0.3, VerdictHuman},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Verdict(tt.score); got != tt.want {
+				t.Errorf("Verdict(%v) = %s, want %s", tt.score, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("buildResult applies policy", func(t *testing.T) {
+		config := DetectorConfig{Decision: banded}
+		result := buildResult(config, ContentTypeText, []float64{0.5}, []string{"humanmark"}, nil, EvidenceNone)
+
+		if result.Verdict != VerdictUncertain || result.Human {
+			t.Errorf("got verdict %s (human=%v), want uncertain", result.Verdict, result.Human)
+		}
+	})
+}
+
+// TestNewDetectorRejectsInvalidPolicy verifies bad thresholds fail at startup.
+func TestNewDetectorRejectsInvalidPolicy(t *testing.T) {
+	for _, policy := range []DecisionPolicy{
+		{AIThreshold: 0.3, HumanThreshold: 0.6},
+		{AIThreshold: 1.2, HumanThreshold: 0.5},
+	} {
+		if _, err := NewDetector(DetectorConfig{Decision: policy}, nil); err == nil {
+			t.Errorf("expected error for policy %+v", policy)
+		}
+	}
+}
+
+// TestUncertaintyThreshold verifies scores near 0.5 are uncertain.
+func TestUncertaintyThreshold(t *testing.T) {
+	config := DetectorConfig{Decision: DecisionPolicy{UncertaintyBand: 0.15}}
+
+	tests := []struct {
+		score float64
+		want  Verdict
+	}{
+		{0.48, VerdictUncertain},
+		{0.6, VerdictUncertain},
+		{0.30, VerdictHuman},
+		{0.7, VerdictAI},
+	}
+
+	for _, tt := range tests {
+		result := buildResult(config, ContentTypeText, []float64{tt.score}, []string{"humanmark"}, nil, EvidenceNone)
+
+		if result.Verdict != tt.want {
+			t.Errorf("score %v: verdict = %s, want %s", tt.score, result.Verdict, tt.want)
+		}
+		if result.Uncertain != (tt.want == VerdictUncertain) {
+			t.Errorf("score %v: Uncertain = %v", tt.score, result.Uncertain)
+		}
+		if result.Uncertain && result.Human {
+			t.Errorf("score %v: uncertain result should not be human", tt.score)
+		}
+	}
+
+	t.Run("zero disables", func(t *testing.T) {
+		result := buildResult(DetectorConfig{}, ContentTypeText, []float64{0.48}, []string{"humanmark"}, nil, EvidenceNone)
+		if result.Verdict != VerdictHuman {
+			t.Errorf("verdict = %s, want human", result.Verdict)
+		}
+	})
+
+	t.Run("thresholds win over the band", func(t *testing.T) {
+		// AI_THRESHOLD=0.6 with the default HUMAN_THRESHOLD and band
+		config := DetectorConfig{Decision: DecisionPolicy{AIThreshold: 0.6, HumanThreshold: 0.5, UncertaintyBand: 0.15}}
+		for score, want := range map[float64]Verdict{0.62: VerdictAI, 0.55: VerdictUncertain, 0.45: VerdictHuman} {
+			result := buildResult(config, ContentTypeText, []float64{score}, []string{"humanmark"}, nil, EvidenceNone)
+			if result.Verdict != want {
+				t.Errorf("score %v: verdict = %s, want %s", score, result.Verdict, want)
+			}
+		}
+	})
+
+	t.Run("NewDetector rejects out of range", func(t *testing.T) {
+		if _, err := NewDetector(DetectorConfig{Decision: DecisionPolicy{UncertaintyBand: 0.6}}, nil); err == nil {
+			t.Error("expected error for uncertainty threshold above 0.5")
+		}
+	})
+}
+
+// TestScoreDisagreement tests the weighted standard deviation of scores.
+func TestScoreDisagreement(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers []ProviderScore
+		want      float64
+	}{
+		{"single provider", []ProviderScore{{AIScore: 0.9, Weight: 1}}, 0},
+		{"agreement", []ProviderScore{{AIScore: 0.7, Weight: 1}, {AIScore: 0.7, Weight: 2}}, 0},
+		{"split", []ProviderScore{{AIScore: 0, Weight: 1}, {AIScore: 1, Weight: 1}}, 0.5},
+		{"weighted", []ProviderScore{{AIScore: 0.2, Weight: 3}, {AIScore: 0.6, Weight: 1}}, math.Sqrt(0.03)},
+		{"zero weights", []ProviderScore{{AIScore: 0.2}, {AIScore: 0.6}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scoreDisagreement(tt.providers); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("scoreDisagreement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDisagreementReview tests that discordant providers flag results for
+// review and cap their confidence.
+func TestDisagreementReview(t *testing.T) {
+	// fakeProviders serves the given Sightengine and AIorNot scores
+	fakeProviders := func(sightengine, aiornot string) *httptest.Server {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/sightengine", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"success","type":{"ai_generated":` + sightengine + `}}`))
+		})
+		mux.HandleFunc("/aiornot", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"report":{"verdict":"ai","ai":{"confidence":` + aiornot + `}}}`))
+		})
+		return httptest.NewServer(mux)
+	}
+
+	detect := func(t *testing.T, config DetectorConfig, server *httptest.Server) *DetectionResult {
+		t.Helper()
+		config.SightengineAPIKey = "u:s"
+		config.AIorNotAPIKey = "k"
+		d := newProviderTestDetector(config, "")
+		d.sightengineURL = server.URL + "/sightengine"
+		d.aiornotURL = server.URL + "/aiornot"
+
+		result, err := d.DetectImage(context.Background(), DetectionInput{
+			Data:      testPNG,
+			Detectors: []string{"sightengine", "aiornot"},
+		})
+		if err != nil {
+			t.Fatalf("DetectImage() error = %v", err)
+		}
+		return result
+	}
+
+	// The max strategy reports the most AI-like score with full confidence,
+	// however much the other provider disagreed
+	config := DetectorConfig{Aggregation: AggregationMax, DisagreementThreshold: 0.25}
+
+	t.Run("concordant providers", func(t *testing.T) {
+		server := fakeProviders("0.9", "0.85")
+		defer server.Close()
+
+		result := detect(t, config, server)
+		if result.NeedsReview {
+			t.Errorf("NeedsReview set with disagreement %v", result.Disagreement)
+		}
+		if result.Disagreement > 0.05 {
+			t.Errorf("Disagreement = %v, want near 0", result.Disagreement)
+		}
+		if math.Abs(result.Confidence-0.8) > 1e-9 {
+			t.Errorf("Confidence = %v, want 0.8 uncapped", result.Confidence)
+		}
+	})
+
+	t.Run("discordant providers", func(t *testing.T) {
+		server := fakeProviders("0.95", "0.1")
+		defer server.Close()
+
+		result := detect(t, config, server)
+		if !result.NeedsReview {
+			t.Errorf("NeedsReview not set with disagreement %v", result.Disagreement)
+		}
+		if result.Confidence != DisagreementConfidenceCap {
+			t.Errorf("Confidence = %v, want capped at %v", result.Confidence, DisagreementConfidenceCap)
+		}
+		if result.Verdict != VerdictAI || result.AIScore != 0.95 {
+			t.Errorf("verdict = %s (score %v), want the max strategy's AI verdict", result.Verdict, result.AIScore)
+		}
+	})
+
+	t.Run("zero threshold disables", func(t *testing.T) {
+		server := fakeProviders("0.95", "0.1")
+		defer server.Close()
+
+		result := detect(t, DetectorConfig{Aggregation: AggregationMax}, server)
+		if result.NeedsReview || result.Disagreement == 0 {
+			t.Errorf("NeedsReview = %v, Disagreement = %v; want unflagged but measured", result.NeedsReview, result.Disagreement)
+		}
+	})
+
+	t.Run("NewDetector rejects out of range", func(t *testing.T) {
+		if _, err := NewDetector(DetectorConfig{DisagreementThreshold: 0.6}, nil); err == nil {
+			t.Error("expected error for disagreement threshold above 0.5")
+		}
+	})
+}
+
+// TestDetectionBasis tests summarizing the detectors a result came from.
+func TestDetectionBasis(t *testing.T) {
+	tests := []struct {
+		name      string
+		detectors []string
+		evidence  Evidence
+		want      Basis
+	}{
+		{"local analyzer", []string{"humanmark"}, EvidenceNone, BasisLocalOnly},
+		{"local analyzer and metadata", []string{"humanmark", "metadata"}, EvidenceNone, BasisLocalOnly},
+		{"one external", []string{"humanmark", "hive"}, EvidenceNone, "local+1-external"},
+		{"two external", []string{"humanmark", "metadata", "hive", "gptzero"}, EvidenceNone, "local+2-external"},
+		{"external only", []string{"sightengine"}, EvidenceNone, "1-external"},
+		{"AI marker", []string{"humanmark"}, EvidenceAI, BasisConclusiveMarker},
+		{"capture manifest", []string{"humanmark", "hive"}, EvidenceHuman, BasisConclusiveMarker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectionBasis(tt.detectors, tt.evidence); got != tt.want {
+				t.Errorf("detectionBasis() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLocalOnlyConfidenceCap tests that a result from the local analyzer
+// alone has its confidence capped, and one an external provider scored
+// too doesn't.
+func TestLocalOnlyConfidenceCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","type":{"ai_generated":0.99}}`))
+	}))
+	defer server.Close()
+
+	detect := func(t *testing.T, config DetectorConfig) *DetectionResult {
+		t.Helper()
+		config.Aggregation = AggregationMax
+		result, err := newProviderTestDetector(config, server.URL).DetectImage(context.Background(), DetectionInput{Data: testPNG})
+		if err != nil {
+			t.Fatalf("DetectImage() error = %v", err)
+		}
+		return result
+	}
+
+	// Below the local analyzer's confidence in testPNG
+	const limit = 0.05
+
+	t.Run("local analyzer only", func(t *testing.T) {
+		result := detect(t, DetectorConfig{LocalOnlyConfidenceCap: limit})
+		if result.Basis != BasisLocalOnly {
+			t.Errorf("Basis = %q, want %q", result.Basis, BasisLocalOnly)
+		}
+		if result.Confidence != limit {
+			t.Errorf("Confidence = %v, want capped at %v", result.Confidence, limit)
+		}
+	})
+
+	t.Run("with an external provider", func(t *testing.T) {
+		result := detect(t, DetectorConfig{LocalOnlyConfidenceCap: limit, SightengineAPIKey: "u:s"})
+		if result.Basis != "local+1-external" {
+			t.Errorf("Basis = %q, want local+1-external", result.Basis)
+		}
+		if math.Abs(result.Confidence-0.98) > 1e-9 {
+			t.Errorf("Confidence = %v, want 0.98 uncapped", result.Confidence)
+		}
+	})
+
+	t.Run("zero cap disables", func(t *testing.T) {
+		result := detect(t, DetectorConfig{})
+		if result.Basis != BasisLocalOnly || result.Confidence <= limit {
+			t.Errorf("Basis = %q, Confidence = %v; want local-only above %v", result.Basis, result.Confidence, limit)
+		}
+	})
+
+	t.Run("NewDetector rejects out of range", func(t *testing.T) {
+		if _, err := NewDetector(DetectorConfig{LocalOnlyConfidenceCap: 1.5}, nil); err == nil {
+			t.Error("expected error for local-only confidence cap above 1")
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Blockchain Anchoring
+// =============================================================================
+//
+// With DetectorConfig.Anchor set, each result's content hash is published
+// to a blockchain. The transaction's block time proves the result existed
+// then, and anyone can check the hash against it without trusting us.
+//
+// Anchoring is only available to programs that build their detector with
+// this package. It needs an EthereumSigner backed by the operator's own
+// KMS or wallet, and HumanMark never holds private keys, so the API server
+// has no anchoring settings and its results are never anchored.
+//
+// EthereumAnchor puts the hash in a zero-value transaction's data field
+// (Ethereum's equivalent of Bitcoin's OP_RETURN). Any other ledger works by
+// implementing BlockchainAnchor.
+//
+// Anchoring is best-effort: if it fails, or takes longer than
+// DetectorConfig.AnchorTimeout, the result is returned without a
+// transaction ID rather than failing the request.
+//
+// =============================================================================
+
+// BlockchainAnchor publishes content hashes to a tamper-evident ledger.
+type BlockchainAnchor interface {
+	// Anchor records hash (hex SHA-256) with the time it was produced and
+	// returns the transaction ID.
+	Anchor(ctx context.Context, hash string, timestamp time.Time) (txID string, err error)
+}
+
+// EthereumTx is an unsigned legacy (EIP-155) Ethereum transaction.
+type EthereumTx struct {
+	ChainID  *big.Int
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+
+	// To is the recipient address (0x-prefixed hex)
+	To string
+
+	// Data is the anchored payload; the value is always zero
+	Data []byte
+}
+
+// EthereumSigner signs transactions for EthereumAnchor, e.g. with a key in
+// a KMS or hardware wallet. HumanMark doesn't hold private keys itself.
+type EthereumSigner interface {
+	// SignTx returns the RLP-encoded signed transaction.
+	SignTx(ctx context.Context, tx EthereumTx) ([]byte, error)
+}
+
+// EthereumAnchorConfig configures an EthereumAnchor.
+type EthereumAnchorConfig struct {
+	// RPCURL is the JSON-RPC endpoint of a node on the target network
+	RPCURL string
+
+	// From is the signer's address, used to look up the next nonce
+	From string
+
+	// To receives the transactions (default: From, i.e. a self-send)
+	To string
+
+	// ChainID is the network's chain ID, e.g. 11155111 for Sepolia
+	ChainID int64
+
+	// Signer signs each transaction
+	Signer EthereumSigner
+
+	// Timeout bounds each RPC call (default: 10s)
+	Timeout time.Duration
+}
+
+// EthereumAnchor anchors hashes in Ethereum transactions sent with
+// eth_sendRawTransaction.
+type EthereumAnchor struct {
+	config     EthereumAnchorConfig
+	httpClient *http.Client
+
+	// mu serializes Anchor from choosing a nonce to sending the
+	// transaction, so concurrent calls never reuse one
+	mu sync.Mutex
+
+	// nonce is the next nonce to use when nonceValid. It is fetched from
+	// the node once, then counted locally; any failure drops it so the
+	// next call resyncs.
+	nonce      uint64
+	nonceValid bool
+}
+
+// NewEthereumAnchor creates an EthereumAnchor.
+func NewEthereumAnchor(config EthereumAnchorConfig) (*EthereumAnchor, error) {
+	if config.RPCURL == "" {
+		return nil, errors.New("ethereum anchor: RPC URL is required")
+	}
+	if !isEthereumAddress(config.From) {
+		return nil, fmt.Errorf("ethereum anchor: invalid from address %q", config.From)
+	}
+	if config.To == "" {
+		config.To = config.From
+	}
+	if !isEthereumAddress(config.To) {
+		return nil, fmt.Errorf("ethereum anchor: invalid to address %q", config.To)
+	}
+	if config.ChainID <= 0 {
+		return nil, errors.New("ethereum anchor: chain ID is required")
+	}
+	if config.Signer == nil {
+		return nil, errors.New("ethereum anchor: signer is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &EthereumAnchor{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Anchor sends a zero-value transaction whose data is the 32-byte hash
+// followed by the Unix timestamp (8 bytes, big-endian), and returns the
+// transaction hash. Calls are serialized, since each needs the next nonce.
+func (a *EthereumAnchor) Anchor(ctx context.Context, hash string, timestamp time.Time) (string, error) {
+	data, err := anchorPayload(hash, timestamp)
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	txID, err := a.send(ctx, data)
+	if err != nil {
+		a.nonceValid = false
+		return "", err
+	}
+	a.nonce++
+	return txID, nil
+}
+
+// send signs and sends a transaction carrying data with the next nonce.
+// The caller holds a.mu.
+func (a *EthereumAnchor) send(ctx context.Context, data []byte) (string, error) {
+	if !a.nonceValid {
+		var nonceHex string
+		if err := a.call(ctx, "eth_getTransactionCount", []any{a.config.From, "pending"}, &nonceHex); err != nil {
+			return "", err
+		}
+		nonce, err := strconv.ParseUint(strings.TrimPrefix(nonceHex, "0x"), 16, 64)
+		if err != nil {
+			return "", fmt.Errorf("ethereum rpc eth_getTransactionCount: invalid nonce %q", nonceHex)
+		}
+		a.nonce, a.nonceValid = nonce, true
+	}
+
+	var gasPriceHex string
+	if err := a.call(ctx, "eth_gasPrice", []any{}, &gasPriceHex); err != nil {
+		return "", err
+	}
+	gasPrice, ok := new(big.Int).SetString(strings.TrimPrefix(gasPriceHex, "0x"), 16)
+	if !ok {
+		return "", fmt.Errorf("ethereum rpc eth_gasPrice: invalid gas price %q", gasPriceHex)
+	}
+
+	raw, err := a.config.Signer.SignTx(ctx, EthereumTx{
+		ChainID:  big.NewInt(a.config.ChainID),
+		Nonce:    a.nonce,
+		GasPrice: gasPrice,
+		Gas:      intrinsicGas(data),
+		To:       a.config.To,
+		Data:     data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("ethereum anchor: sign transaction: %w", err)
+	}
+
+	var txID string
+	if err := a.call(ctx, "eth_sendRawTransaction", []any{"0x" + hex.EncodeToString(raw)}, &txID); err != nil {
+		return "", err
+	}
+	return txID, nil
+}
+
+// call makes a JSON-RPC call and decodes its result into result.
+func (a *EthereumAnchor) call(ctx context.Context, method string, params []any, result any) error {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.config.RPCURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ethereum rpc %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ethereum rpc %s: status %d", method, resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("ethereum rpc %s: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("ethereum rpc %s: %s (code %d)", method, rpcResp.Error.Message, rpcResp.Error.Code)
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("ethereum rpc %s: unexpected result %s", method, rpcResp.Result)
+	}
+	return nil
+}
+
+// anchorPayload encodes a hex SHA-256 hash and timestamp as transaction data.
+func anchorPayload(hash string, timestamp time.Time) ([]byte, error) {
+	sum, err := hex.DecodeString(hash)
+	if err != nil || len(sum) != 32 {
+		return nil, fmt.Errorf("anchor: %q is not a hex SHA-256 hash", hash)
+	}
+	return binary.BigEndian.AppendUint64(sum, uint64(timestamp.Unix())), nil
+}
+
+// intrinsicGas is the gas a plain transaction with data needs: 21000 plus
+// 16 per non-zero and 4 per zero data byte.
+func intrinsicGas(data []byte) uint64 {
+	gas := uint64(21000)
+	for _, b := range data {
+		if b == 0 {
+			gas += 4
+		} else {
+			gas += 16
+		}
+	}
+	return gas
+}
+
+// isEthereumAddress reports whether s is a 0x-prefixed 20-byte hex address.
+func isEthereumAddress(s string) bool {
+	if len(s) != 42 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+const (
+	testAnchorHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	testAnchorFrom = "0x1111111111111111111111111111111111111111"
+)
+
+// fakeSigner "signs" a transaction by returning its data, and keeps the
+// last transaction for inspection.
+type fakeSigner struct {
+	last EthereumTx
+	err  error
+}
+
+func (s *fakeSigner) SignTx(ctx context.Context, tx EthereumTx) ([]byte, error) {
+	s.last = tx
+	return tx.Data, s.err
+}
+
+// mockEthereumRPC serves the JSON-RPC methods EthereumAnchor uses and
+// records the raw transactions it was sent.
+func mockEthereumRPC(t *testing.T, sent *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+			Params []any  `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid JSON-RPC request: %v", err)
+		}
+
+		var result any
+		switch req.Method {
+		case "eth_getTransactionCount":
+			if req.Params[0] != testAnchorFrom || req.Params[1] != "pending" {
+				t.Errorf("eth_getTransactionCount params = %v", req.Params)
+			}
+			result = "0x2a"
+		case "eth_gasPrice":
+			result = "0x3b9aca00"
+		case "eth_sendRawTransaction":
+			raw := req.Params[0].(string)
+			*sent = append(*sent, raw)
+			if strings.Contains(raw, "dead") {
+				json.NewEncoder(w).Encode(map[string]any{
+					"jsonrpc": "2.0", "id": 1,
+					"error": map[string]any{"code": -32000, "message": "nonce too low"},
+				})
+				return
+			}
+			result = "0xabc123"
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+}
+
+// TestEthereumAnchor tests the transaction built and sent for a hash.
+func TestEthereumAnchor(t *testing.T) {
+	var sent []string
+	server := mockEthereumRPC(t, &sent)
+	defer server.Close()
+
+	signer := &fakeSigner{}
+	anchor, err := NewEthereumAnchor(EthereumAnchorConfig{
+		RPCURL:  server.URL,
+		From:    testAnchorFrom,
+		ChainID: 11155111,
+		Signer:  signer,
+	})
+	if err != nil {
+		t.Fatalf("NewEthereumAnchor() error = %v", err)
+	}
+
+	at := time.Unix(1700000000, 0)
+	txID, err := anchor.Anchor(context.Background(), testAnchorHash, at)
+	if err != nil {
+		t.Fatalf("Anchor() error = %v", err)
+	}
+	if txID != "0xabc123" {
+		t.Errorf("txID = %q, want 0xabc123", txID)
+	}
+
+	tx := signer.last
+	wantData := testAnchorHash + "000000006553f100"
+	if got := hex.EncodeToString(tx.Data); got != wantData {
+		t.Errorf("Data = %s, want %s", got, wantData)
+	}
+	if tx.Nonce != 42 || tx.GasPrice.Int64() != 1e9 || tx.ChainID.Int64() != 11155111 {
+		t.Errorf("tx = nonce %d, gas price %s, chain %s", tx.Nonce, tx.GasPrice, tx.ChainID)
+	}
+	if tx.To != testAnchorFrom {
+		t.Errorf("To = %s, want a self-send", tx.To)
+	}
+	// 21000 + 35 non-zero bytes * 16 + 5 zero bytes * 4
+	if tx.Gas != 21580 {
+		t.Errorf("Gas = %d, want 21580", tx.Gas)
+	}
+	if len(sent) != 1 || sent[0] != "0x"+wantData {
+		t.Errorf("sent = %v, want the signed transaction", sent)
+	}
+}
+
+// TestEthereumAnchorErrors tests failures before and after signing.
+func TestEthereumAnchorErrors(t *testing.T) {
+	var sent []string
+	server := mockEthereumRPC(t, &sent)
+	defer server.Close()
+
+	newAnchor := func(signer EthereumSigner) *EthereumAnchor {
+		anchor, err := NewEthereumAnchor(EthereumAnchorConfig{
+			RPCURL: server.URL, From: testAnchorFrom, ChainID: 1, Signer: signer,
+		})
+		if err != nil {
+			t.Fatalf("NewEthereumAnchor() error = %v", err)
+		}
+		return anchor
+	}
+
+	t.Run("invalid hash", func(t *testing.T) {
+		if _, err := newAnchor(&fakeSigner{}).Anchor(context.Background(), "abc", time.Now()); err == nil {
+			t.Error("expected an error for a short hash")
+		}
+	})
+
+	t.Run("signer error", func(t *testing.T) {
+		_, err := newAnchor(&fakeSigner{err: errors.New("key locked")}).Anchor(context.Background(), testAnchorHash, time.Now())
+		if err == nil || !strings.Contains(err.Error(), "key locked") {
+			t.Errorf("error = %v, want the signer error", err)
+		}
+	})
+
+	t.Run("rpc error", func(t *testing.T) {
+		// The mock node rejects transactions containing 0xdead
+		hash := "dead" + testAnchorHash[4:]
+		_, err := newAnchor(&fakeSigner{}).Anchor(context.Background(), hash, time.Now())
+		if err == nil || !strings.Contains(err.Error(), "nonce too low") {
+			t.Errorf("error = %v, want the RPC error", err)
+		}
+	})
+}
+
+// nonceSigner "signs" a transaction as its 8-byte nonce, so a mock node
+// can check it.
+type nonceSigner struct{}
+
+func (nonceSigner) SignTx(ctx context.Context, tx EthereumTx) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, tx.Nonce), nil
+}
+
+// nonceCheckingRPC is a mock node that, like a real one, rejects
+// transactions whose nonce isn't the account's next one.
+type nonceCheckingRPC struct {
+	mu       sync.Mutex
+	next     uint64
+	counts   int // eth_getTransactionCount calls
+	rejected int
+}
+
+func (n *nonceCheckingRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string   `json:"method"`
+		Params []string `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var result string
+	switch req.Method {
+	case "eth_getTransactionCount":
+		n.counts++
+		result = fmt.Sprintf("0x%x", n.next)
+	case "eth_gasPrice":
+		result = "0x1"
+	case "eth_sendRawTransaction":
+		raw, _ := hex.DecodeString(strings.TrimPrefix(req.Params[0], "0x"))
+		if nonce := binary.BigEndian.Uint64(raw); nonce != n.next {
+			n.rejected++
+			json.NewEncoder(w).Encode(map[string]any{
+				"jsonrpc": "2.0", "id": 1,
+				"error": map[string]any{"code": -32000, "message": "nonce too low"},
+			})
+			return
+		}
+		result = fmt.Sprintf("0x%x", n.next)
+		n.next++
+	}
+	json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+}
+
+// TestEthereumAnchorConcurrent tests that concurrent anchors each get their
+// own nonce.
+func TestEthereumAnchorConcurrent(t *testing.T) {
+	node := &nonceCheckingRPC{next: 7}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	anchor, err := NewEthereumAnchor(EthereumAnchorConfig{
+		RPCURL: server.URL, From: testAnchorFrom, ChainID: 1, Signer: nonceSigner{},
+	})
+	if err != nil {
+		t.Fatalf("NewEthereumAnchor() error = %v", err)
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := anchor.Anchor(context.Background(), testAnchorHash, time.Now()); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Anchor() error = %v", err)
+	}
+	if node.next != 7+n || node.rejected != 0 {
+		t.Errorf("node accepted up to nonce %d with %d rejected, want %d and none", node.next, node.rejected, 7+n)
+	}
+	if node.counts != 1 {
+		t.Errorf("eth_getTransactionCount called %d times, want once", node.counts)
+	}
+
+	t.Run("resyncs after a rejection", func(t *testing.T) {
+		// Another sender used the account's next nonce
+		node.mu.Lock()
+		node.next++
+		node.mu.Unlock()
+
+		if _, err := anchor.Anchor(context.Background(), testAnchorHash, time.Now()); err == nil {
+			t.Fatal("expected the stale nonce to be rejected")
+		}
+		if _, err := anchor.Anchor(context.Background(), testAnchorHash, time.Now()); err != nil {
+			t.Errorf("Anchor() after resync error = %v", err)
+		}
+		if node.counts != 2 {
+			t.Errorf("eth_getTransactionCount called %d times, want a resync", node.counts)
+		}
+	})
+}
+
+// TestNewEthereumAnchorValidation tests config validation.
+func TestNewEthereumAnchorValidation(t *testing.T) {
+	valid := EthereumAnchorConfig{RPCURL: "http://node", From: testAnchorFrom, ChainID: 1, Signer: &fakeSigner{}}
+
+	tests := []struct {
+		name   string
+		modify func(*EthereumAnchorConfig)
+	}{
+		{"missing RPC URL", func(c *EthereumAnchorConfig) { c.RPCURL = "" }},
+		{"bad from address", func(c *EthereumAnchorConfig) { c.From = "0x123" }},
+		{"bad to address", func(c *EthereumAnchorConfig) { c.To = "not-an-address" }},
+		{"missing chain ID", func(c *EthereumAnchorConfig) { c.ChainID = 0 }},
+		{"missing signer", func(c *EthereumAnchorConfig) { c.Signer = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			if _, err := NewEthereumAnchor(config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// stubAnchor records the hashes it anchors.
+type stubAnchor struct {
+	hashes []string
+	err    error
+}
+
+func (a *stubAnchor) Anchor(ctx context.Context, hash string, timestamp time.Time) (string, error) {
+	a.hashes = append(a.hashes, hash)
+	return "0xfeed", a.err
+}
+
+// hangingAnchor never anchors; it waits for ctx to end.
+type hangingAnchor struct{}
+
+func (hangingAnchor) Anchor(ctx context.Context, hash string, timestamp time.Time) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+// TestDetectAnchoring tests anchoring after detection.
+func TestDetectAnchoring(t *testing.T) {
+	t.Run("off without an Anchor", func(t *testing.T) {
+		d, err := NewDetector(DetectorConfig{}, logger.NopLogger())
+		if err != nil {
+			t.Fatalf("NewDetector() error = %v", err)
+		}
+		result, err := d.Detect(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if result.AnchorTxID != "" || !result.AnchoredAt.IsZero() {
+			t.Errorf("AnchorTxID = %q, AnchoredAt = %v, want unanchored", result.AnchorTxID, result.AnchoredAt)
+		}
+	})
+
+	t.Run("anchored", func(t *testing.T) {
+		anchor := &stubAnchor{}
+		d, err := NewDetector(DetectorConfig{Anchor: anchor}, logger.NopLogger())
+		if err != nil {
+			t.Fatalf("NewDetector() error = %v", err)
+		}
+		result, err := d.Detect(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if result.AnchorTxID != "0xfeed" || result.AnchoredAt.IsZero() {
+			t.Errorf("AnchorTxID = %q, AnchoredAt = %v, want the anchor transaction", result.AnchorTxID, result.AnchoredAt)
+		}
+		if len(anchor.hashes) != 1 || anchor.hashes[0] != result.ContentHash {
+			t.Errorf("anchored %v, want [%s]", anchor.hashes, result.ContentHash)
+		}
+	})
+
+	t.Run("anchor fails", func(t *testing.T) {
+		anchor := &stubAnchor{err: errors.New("node unreachable")}
+		d, err := NewDetector(DetectorConfig{Anchor: anchor}, logger.NopLogger())
+		if err != nil {
+			t.Fatalf("NewDetector() error = %v", err)
+		}
+		result, err := d.Detect(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("Detect() error = %v, anchoring failures shouldn't fail detection", err)
+		}
+		if result.AnchorTxID != "" {
+			t.Errorf("AnchorTxID = %q, want empty", result.AnchorTxID)
+		}
+	})
+
+	t.Run("anchor times out", func(t *testing.T) {
+		d, err := NewDetector(DetectorConfig{
+			Anchor:        hangingAnchor{},
+			AnchorTimeout: 50 * time.Millisecond,
+		}, logger.NopLogger())
+		if err != nil {
+			t.Fatalf("NewDetector() error = %v", err)
+		}
+
+		start := time.Now()
+		result, err := d.Detect(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("Detect() error = %v, a slow anchor shouldn't fail detection", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Detect() took %v, want anchoring cut off", elapsed)
+		}
+		if result.AnchorTxID != "" {
+			t.Errorf("AnchorTxID = %q, want empty", result.AnchorTxID)
+		}
+	})
+}
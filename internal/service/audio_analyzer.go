@@ -2,7 +2,9 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"io"
 	"math"
 )
 
@@ -25,7 +27,8 @@ import (
 //
 // =============================================================================
 
-// AudioAnalyzer performs forensic analysis on audio files.
+// AudioAnalyzer performs forensic analysis on audio files. It is safe for
+// concurrent use.
 type AudioAnalyzer struct {
 	weights AudioAnalyzerWeights
 }
@@ -54,8 +57,11 @@ func DefaultAudioWeights() AudioAnalyzerWeights {
 
 // NewAudioAnalyzer creates a new analyzer.
 func NewAudioAnalyzer() *AudioAnalyzer {
+	weights := DefaultAudioWeights()
+	mustValidateWeights(weights)
+
 	return &AudioAnalyzer{
-		weights: DefaultAudioWeights(),
+		weights: weights,
 	}
 }
 
@@ -91,6 +97,21 @@ type AudioMetadata struct {
 	EncoderName  string
 	IsAIMarked   bool
 	HasRecording bool // Markers of real recording
+
+	// EncoderVersion is the LAME version from an MP3's Xing/Info frame,
+	// e.g. "3.99r"
+	EncoderVersion string
+
+	// DoubleCompressionSuspected is set when an MP3 looks re-encoded from
+	// an earlier lossy encode, as TTS pipelines often leave it
+	DoubleCompressionSuspected bool
+
+	// DeclaredFormat is the format the filename or MIME type claimed
+	// (empty without a recognizable hint)
+	DeclaredFormat string
+
+	// FormatConflict is set when a hint disagrees with Format
+	FormatConflict bool
 }
 
 // AudioStats contains audio statistics.
@@ -100,40 +121,98 @@ type AudioStats struct {
 	SilenceRatio    float64 // Proportion of silence
 	PeakAmplitude   float64 // Normalized 0-1
 	DynamicRange    float64 // Difference between loud/quiet
+
+	// SpectralCentroid approximates where the spectrum's energy is
+	// concentrated, in Hz (WAV PCM only, 0 otherwise)
+	SpectralCentroid float64
+
+	// BitrateVariation is the coefficient of variation of per-page
+	// bitrates (Opus only; -1 when there were too few pages to measure)
+	BitrateVariation float64
 }
 
+// audioHeaderSize is how much of an audio file is buffered for format,
+// metadata and watermark detection. Covers typical ID3v2 tags.
+const audioHeaderSize = 128 * 1024
+
 // Analyze performs forensic analysis on audio data.
 func (a *AudioAnalyzer) Analyze(data []byte) AudioAnalysisResult {
+	// Reading from memory can't fail
+	result, _ := a.AnalyzeFromReader(bytes.NewReader(data))
+	return result
+}
+
+// AnalyzeFromReader performs forensic analysis on audio read from r.
+// The first audioHeaderSize bytes are buffered; the rest is streamed in
+// chunks for pattern analysis.
+func (a *AudioAnalyzer) AnalyzeFromReader(r io.Reader) (AudioAnalysisResult, error) {
+	return a.AnalyzeFromReaderContext(context.Background(), r)
+}
+
+// AnalyzeFromReaderContext is AnalyzeFromReader, stopping with ctx's error
+// if ctx is cancelled while the input is being read.
+func (a *AudioAnalyzer) AnalyzeFromReaderContext(ctx context.Context, r io.Reader) (AudioAnalysisResult, error) {
+	return a.AnalyzeFromReaderWithHints(ctx, r, FormatHints{})
+}
+
+// AnalyzeFromReaderWithHints is AnalyzeFromReaderContext for audio that
+// claims a format through hints. Metadata records the declared format and
+// whether it conflicts with the detected one; the score is unchanged, the
+// detector weighs a conflict as a signal of its own.
+func (a *AudioAnalyzer) AnalyzeFromReaderWithHints(ctx context.Context, r io.Reader, hints FormatHints) (AudioAnalysisResult, error) {
+	s, err := readMediaSample(ctx, r, audioHeaderSize, nil)
+	if err != nil {
+		return AudioAnalysisResult{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return AudioAnalysisResult{}, err
+	}
+
+	result := a.analyze(s)
+	result.Metadata.DeclaredFormat, result.Metadata.FormatConflict = hints.check(audioFormats, result.Metadata.Format)
+	return result, nil
+}
+
+// analyze runs the analysis on a buffered header plus streamed samples.
+func (a *AudioAnalyzer) analyze(s *mediaSample) AudioAnalysisResult {
 	result := AudioAnalysisResult{}
-	result.Stats.FileSize = int64(len(data))
+	header := s.header
 
 	// Detect format
-	format := a.detectAudioFormat(data)
+	format := a.detectAudioFormat(header)
 	result.Metadata.Format = format
 
 	// Extract metadata based on format
 	switch format {
 	case "mp3":
-		result.Metadata, result.Stats = a.analyzeMP3(data)
+		result.Metadata, result.Stats = a.analyzeMP3(header, s.Tail(128))
 	case "wav":
-		result.Metadata, result.Stats = a.analyzeWAV(data)
+		result.Metadata, result.Stats = a.analyzeWAV(header)
+		result.Stats.SpectralCentroid = a.analyzeSpectralCentroidProxy(header, result.Metadata)
 	case "flac":
-		result.Metadata, result.Stats = a.analyzeFLAC(data)
+		result.Metadata, result.Stats = a.analyzeFLAC(header)
 	case "ogg":
-		result.Metadata, result.Stats = a.analyzeOGG(data)
+		result.Metadata, result.Stats = a.analyzeOGG(header)
+		if result.Metadata.Format == "opus" {
+			result.Stats.BitrateVariation = opusBitrateVariation(header)
+		}
 	case "m4a", "aac":
-		result.Metadata, result.Stats = a.analyzeM4A(data)
+		result.Metadata, result.Stats = a.analyzeM4A(header)
 	default:
 		result.Metadata.Format = format
 	}
+	result.Stats.FileSize = s.Len()
 
 	// Calculate signals
 	result.Signals.MetadataScore = a.analyzeMetadata(result.Metadata)
-	result.Signals.FormatAnalysis = a.analyzeFormat(result.Metadata, data)
-	result.Signals.PatternAnalysis = a.analyzePatterns(data, format)
+	result.Signals.FormatAnalysis = a.analyzeFormat(result.Metadata, header)
+	result.Signals.PatternAnalysis = a.analyzePatterns(s, format)
 	result.Signals.QualityIndicators = a.analyzeQuality(result.Metadata, result.Stats)
-	result.Signals.AISignatures = a.detectAISignatures(data, result.Metadata)
-	result.Signals.NoiseProfile = a.analyzeNoiseProfile(data, format)
+	result.Signals.AISignatures = a.detectAISignatures(header, result.Metadata)
+	result.Signals.NoiseProfile = a.analyzeNoiseProfile(s, format)
+	if result.Metadata.Format == "opus" {
+		result.Signals.NoiseProfile = (result.Signals.NoiseProfile + a.analyzeOpusBitrate(header)) / 2
+	}
 
 	// Calculate weighted score
 	result.AIScore = a.calculateWeightedScore(result.Signals)
@@ -190,11 +269,14 @@ func (a *AudioAnalyzer) detectAudioFormat(data []byte) string {
 }
 
 // analyzeMP3 extracts metadata from MP3 files.
-func (a *AudioAnalyzer) analyzeMP3(data []byte) (AudioMetadata, AudioStats) {
+// tail is the last 128 bytes of the file, where an ID3v1 tag would be.
+func (a *AudioAnalyzer) analyzeMP3(data, tail []byte) (AudioMetadata, AudioStats) {
 	meta := AudioMetadata{Format: "mp3"}
 	stats := AudioStats{FileSize: int64(len(data))}
 
 	// Check for ID3v2 tag at start
+	frameSearchStart := 0
+	id3Frames := 0
 	if len(data) > 10 && data[0] == 'I' && data[1] == 'D' && data[2] == '3' {
 		meta.HasID3 = true
 
@@ -213,52 +295,48 @@ func (a *AudioAnalyzer) analyzeMP3(data []byte) (AudioMetadata, AudioStats) {
 			if containsRecordingMarker(id3Data) {
 				meta.HasRecording = true
 			}
+
+			id3Frames = countID3Frames(data[10:10+id3Size], data[3])
 		}
+
+		// Tag data (e.g. cover art) can contain bytes that look like frame
+		// sync. A tag running past the buffered header leaves no frames to find.
+		frameSearchStart = min(10+id3Size, len(data))
 	}
 
 	// Find first MP3 frame to get audio params
-	for i := 0; i < len(data)-4; i++ {
-		if data[i] == 0xFF && (data[i+1]&0xE0) == 0xE0 {
-			// Found frame sync
-			header := binary.BigEndian.Uint32(data[i : i+4])
-
-			// Extract bitrate index and sample rate index
-			version := (header >> 19) & 0x03
-			layer := (header >> 17) & 0x03
-			bitrateIdx := (header >> 12) & 0x0F
-			srIdx := (header >> 10) & 0x03
-			channelMode := (header >> 6) & 0x03
-
-			// Set sample rate (simplified - MPEG1 Layer 3)
-			sampleRates := []int{44100, 48000, 32000, 0}
-			if srIdx < 3 {
-				meta.SampleRate = sampleRates[srIdx]
-			}
+	for i := frameSearchStart; i+4 <= len(data); i++ {
+		if data[i] != 0xFF || data[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		fh, ok := parseMP3FrameHeader(binary.BigEndian.Uint32(data[i : i+4]))
+		if !ok || !confirmMP3Frame(data[i:], fh) {
+			continue // False sync
+		}
 
-			// Set channels
-			if channelMode == 3 {
-				meta.Channels = 1 // Mono
-			} else {
-				meta.Channels = 2 // Stereo
-			}
+		meta.SampleRate = fh.sampleRate
+		meta.Bitrate = fh.bitrate
+		meta.Channels = fh.channels
 
-			// Bitrate table for MPEG1 Layer 3
-			bitrates := []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
-			if bitrateIdx < 15 {
-				meta.Bitrate = bitrates[bitrateIdx]
+		if v := lameVersion(data[i:], fh.version, fh.channels == 1); v != "" {
+			meta.EncoderVersion = v
+			if meta.EncoderName == "" {
+				meta.EncoderName = "LAME"
 			}
+		}
 
-			// Avoid unused variable warnings
-			_ = version
-			_ = layer
+		break
+	}
 
-			break
-		}
+	// A very low bitrate file carrying a full set of tags was most likely
+	// encoded from an earlier, tagged encode rather than from a recording
+	if meta.Bitrate > 0 && meta.Bitrate < doubleCompressionMaxBitrate && id3Frames >= richID3Frames {
+		meta.DoubleCompressionSuspected = true
 	}
 
 	// Check ID3v1 tag at end
-	if len(data) >= 128 {
-		if bytes.Equal(data[len(data)-128:len(data)-125], []byte("TAG")) {
+	if len(tail) >= 128 {
+		if bytes.Equal(tail[:3], []byte("TAG")) {
 			meta.HasID3 = true
 		}
 	}
@@ -266,6 +344,218 @@ func (a *AudioAnalyzer) analyzeMP3(data []byte) (AudioMetadata, AudioStats) {
 	return meta, stats
 }
 
+// MPEG audio bitrates in kbps by bitrate index. Index 0 (free format) and
+// 15 are left 0, as neither gives a frame length.
+var (
+	mpeg1Bitrates = [3][16]int{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0}, // Layer I
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},    // Layer II
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},     // Layer III
+	}
+	mpeg2Bitrates = [3][16]int{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0}, // Layer I
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // Layer II
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // Layer III
+	}
+)
+
+// MPEG audio sample rates by version bits (MPEG 2.5, reserved, MPEG 2,
+// MPEG 1) and sample rate index.
+var mpegSampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+// mp3FrameHeader is a decoded MPEG audio frame header.
+type mp3FrameHeader struct {
+	version    uint32 // Version bits: 3 = MPEG 1, 2 = MPEG 2, 0 = MPEG 2.5
+	layer      int    // 1, 2 or 3
+	bitrate    int    // kbps
+	sampleRate int
+	channels   int
+	length     int // Frame length in bytes, including the header
+}
+
+// parseMP3FrameHeader decodes the 4-byte frame header h. It reports false
+// for anything but a frame sync followed by valid version, layer, bitrate,
+// sample rate and emphasis fields, so stray 0xFF bytes aren't mistaken for
+// a frame.
+func parseMP3FrameHeader(h uint32) (mp3FrameHeader, bool) {
+	if h>>21 != 0x7FF {
+		return mp3FrameHeader{}, false
+	}
+
+	version := (h >> 19) & 0x03
+	layerBits := (h >> 17) & 0x03
+	bitrateIdx := (h >> 12) & 0x0F
+	srIdx := (h >> 10) & 0x03
+	padding := int((h >> 9) & 0x01)
+	channelMode := (h >> 6) & 0x03
+	emphasis := h & 0x03
+	if version == 1 || layerBits == 0 || srIdx == 3 || emphasis == 2 {
+		return mp3FrameHeader{}, false
+	}
+
+	fh := mp3FrameHeader{
+		version:    version,
+		layer:      int(4 - layerBits),
+		sampleRate: mpegSampleRates[version][srIdx],
+		channels:   2,
+	}
+	if channelMode == 3 {
+		fh.channels = 1
+	}
+	if version == 3 {
+		fh.bitrate = mpeg1Bitrates[fh.layer-1][bitrateIdx]
+	} else {
+		fh.bitrate = mpeg2Bitrates[fh.layer-1][bitrateIdx]
+	}
+	if fh.bitrate == 0 {
+		return mp3FrameHeader{}, false
+	}
+
+	bps := fh.bitrate * 1000
+	switch {
+	case fh.layer == 1:
+		fh.length = (12*bps/fh.sampleRate + padding) * 4
+	case fh.layer == 3 && version != 3:
+		fh.length = 72*bps/fh.sampleRate + padding
+	default:
+		fh.length = 144*bps/fh.sampleRate + padding
+	}
+
+	return fh, true
+}
+
+// confirmMP3Frame reports whether the frame with header fh that starts
+// frame is real: the next frame header must follow it with the same
+// version, layer and sample rate. A frame carrying a Xing/Info tag, or a
+// complete last frame, is accepted; one cut off by the end of the data is
+// not.
+func confirmMP3Frame(frame []byte, fh mp3FrameHeader) bool {
+	switch {
+	case hasXingTag(frame, fh):
+		return true
+	case fh.length > len(frame):
+		return false
+	case fh.length+4 > len(frame):
+		return true
+	}
+
+	next, ok := parseMP3FrameHeader(binary.BigEndian.Uint32(frame[fh.length : fh.length+4]))
+	return ok && next.version == fh.version && next.layer == fh.layer && next.sampleRate == fh.sampleRate
+}
+
+// hasXingTag reports whether frame, with header fh, carries a Xing/Info
+// tag after its side information.
+func hasXingTag(frame []byte, fh mp3FrameHeader) bool {
+	pos := 4 + sideInfoSize(fh.version, fh.channels == 1)
+	return len(frame) >= pos+4 && (bytes.Equal(frame[pos:pos+4], []byte("Xing")) || bytes.Equal(frame[pos:pos+4], []byte("Info")))
+}
+
+// sideInfoSize returns the Layer III side information length for MPEG
+// version bits version.
+func sideInfoSize(version uint32, mono bool) int {
+	switch {
+	case version == 3 && mono:
+		return 17
+	case version != 3 && mono:
+		return 9
+	case version != 3:
+		return 17
+	}
+	return 32
+}
+
+// Double compression heuristic thresholds
+const (
+	// doubleCompressionMaxBitrate is the bitrate (kbps) below which a richly
+	// tagged MP3 is suspected of being re-encoded
+	doubleCompressionMaxBitrate = 48
+
+	// richID3Frames is the number of ID3v2 frames that counts as a full
+	// music-library tag (title, artist, album, track, year, ...)
+	richID3Frames = 5
+)
+
+// countID3Frames counts the frames in an ID3v2 tag body. version is the
+// major version byte of the tag header.
+func countID3Frames(tag []byte, version byte) int {
+	count := 0
+	for pos := 0; pos+10 <= len(tag); {
+		id := tag[pos : pos+4]
+		if id[0] == 0 {
+			break // Padding
+		}
+		for _, c := range id {
+			if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+				return count
+			}
+		}
+
+		var size int
+		if version >= 4 {
+			// ID3v2.4 frame sizes are syncsafe
+			size = int(tag[pos+4])<<21 | int(tag[pos+5])<<14 | int(tag[pos+6])<<7 | int(tag[pos+7])
+		} else {
+			size = int(binary.BigEndian.Uint32(tag[pos+4 : pos+8]))
+		}
+		if size <= 0 {
+			break
+		}
+
+		count++
+		pos += 10 + size
+	}
+	return count
+}
+
+// lameVersion returns the LAME version recorded in the Xing/Info frame that
+// starts frame, or in the first frame's ancillary data for older encodes.
+// version is the MPEG version bits of the frame header.
+func lameVersion(frame []byte, version uint32, mono bool) string {
+	// The Xing/Info header follows the side information
+	pos := 4 + sideInfoSize(version, mono)
+	if len(frame) >= pos+8 && (bytes.Equal(frame[pos:pos+4], []byte("Xing")) || bytes.Equal(frame[pos:pos+4], []byte("Info"))) {
+		flags := binary.BigEndian.Uint32(frame[pos+4 : pos+8])
+		pos += 8
+		if flags&0x1 != 0 {
+			pos += 4 // Frame count
+		}
+		if flags&0x2 != 0 {
+			pos += 4 // Byte count
+		}
+		if flags&0x4 != 0 {
+			pos += 100 // Seek table
+		}
+		if flags&0x8 != 0 {
+			pos += 4 // Quality
+		}
+
+		// The LAME extension starts with a 9-byte encoder string
+		if len(frame) >= pos+9 && bytes.HasPrefix(frame[pos:], []byte("LAME")) {
+			return string(bytes.TrimRight(frame[pos+4:pos+9], "\x00 "))
+		}
+	}
+
+	// Without a LAME tag, the version may still be in the first frame's data
+	window := frame
+	if len(window) > 2048 {
+		window = window[:2048]
+	}
+	if i := bytes.Index(window, []byte("LAME")); i >= 0 && i+5 < len(window) && window[i+4] >= '0' && window[i+4] <= '9' {
+		end := i + 4
+		for end < len(window) && end < i+12 && (window[end] == '.' || (window[end] >= '0' && window[end] <= '9') || (window[end] >= 'a' && window[end] <= 'z')) {
+			end++
+		}
+		return string(window[i+4 : end])
+	}
+
+	return ""
+}
+
 // analyzeWAV extracts metadata from WAV files.
 func (a *AudioAnalyzer) analyzeWAV(data []byte) (AudioMetadata, AudioStats) {
 	meta := AudioMetadata{Format: "wav"}
@@ -314,6 +604,127 @@ func (a *AudioAnalyzer) analyzeWAV(data []byte) (AudioMetadata, AudioStats) {
 	return meta, stats
 }
 
+// Spectral centroid proxy parameters: spectralWindow PCM samples are
+// transformed in blocks of spectralPoints, and the block spectra summed.
+const (
+	spectralPoints = 32
+	spectralWindow = 256
+)
+
+// telephonyCentroidHz is the spectral centroid below which speech sounds
+// telephone-band. Natural voice carries energy up to 8000 Hz and beyond,
+// while TTS tends to concentrate it between 200 and 3000 Hz.
+const telephonyCentroidHz = 2000
+
+// analyzeSpectralCentroidProxy estimates the spectral centroid of a WAV
+// PCM file from a 256-sample window in the middle of its buffered audio,
+// taking the first channel. The window is split into 32-sample blocks,
+// each Hann-windowed and transformed with a radix-2 FFT; the frequency of
+// the bin with the most energy across blocks stands in for the centroid.
+// Resolution is SampleRate/32 (about 1378 Hz at 44100 Hz).
+//
+// Returns 0 for non-PCM or non 8/16-bit audio, too little data, or a
+// silent window.
+func (a *AudioAnalyzer) analyzeSpectralCentroidProxy(data []byte, meta AudioMetadata) float64 {
+	if meta.Format != "wav" || meta.EncoderName != "PCM" || meta.SampleRate <= 0 || meta.Channels <= 0 {
+		return 0
+	}
+	sampleSize := meta.BitDepth / 8
+	if sampleSize != 1 && sampleSize != 2 {
+		return 0
+	}
+
+	pcm := wavDataChunk(data)
+	frameSize := sampleSize * meta.Channels
+	frames := len(pcm) / frameSize
+	if frames < spectralWindow {
+		return 0
+	}
+
+	// The middle skips lead-in silence
+	start := (frames - spectralWindow) / 2 * frameSize
+	samples := make([]float64, spectralWindow)
+	for i := range samples {
+		offset := start + i*frameSize
+		if sampleSize == 1 {
+			samples[i] = float64(int(pcm[offset]) - 128) // 8-bit PCM is unsigned
+		} else {
+			samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[offset:])))
+		}
+	}
+
+	var energy [spectralPoints/2 + 1]float64
+	block := make([]complex128, spectralPoints)
+	for offset := 0; offset < spectralWindow; offset += spectralPoints {
+		for i := range block {
+			hann := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/spectralPoints)
+			block[i] = complex(samples[offset+i]*hann, 0)
+		}
+		fftRadix2(block)
+		for k := range energy {
+			energy[k] += real(block[k])*real(block[k]) + imag(block[k])*imag(block[k])
+		}
+	}
+
+	// Bin 0 is the DC offset, not a frequency
+	peak := 1
+	for k := 2; k < len(energy); k++ {
+		if energy[k] > energy[peak] {
+			peak = k
+		}
+	}
+	if energy[peak] == 0 {
+		return 0
+	}
+	return float64(peak) * float64(meta.SampleRate) / spectralPoints
+}
+
+// wavDataChunk returns the part of a WAV file's data chunk present in
+// data, or nil if there is none.
+func wavDataChunk(data []byte) []byte {
+	for offset := 12; offset+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		if bytes.Equal(data[offset:offset+4], []byte("data")) {
+			return data[offset+8 : min(offset+8+size, len(data))]
+		}
+		// Chunks are padded to an even size
+		offset += 8 + size + size%2
+	}
+	return nil
+}
+
+// fftRadix2 replaces x, whose length must be a power of two, with its
+// discrete Fourier transform (iterative Cooley-Tukey butterflies).
+func fftRadix2(x []complex128) {
+	n := len(x)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		angle := -2 * math.Pi / float64(size)
+		step := complex(math.Cos(angle), math.Sin(angle))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
 // analyzeFLAC extracts metadata from FLAC files.
 func (a *AudioAnalyzer) analyzeFLAC(data []byte) (AudioMetadata, AudioStats) {
 	meta := AudioMetadata{Format: "flac"}
@@ -393,6 +804,114 @@ func (a *AudioAnalyzer) analyzeOGG(data []byte) (AudioMetadata, AudioStats) {
 	return meta, stats
 }
 
+// Opus bitrate variation thresholds. Constant-bitrate encodes, as TTS
+// pipelines emit at 24, 32 or 48 kbps, vary by a few percent from page to
+// page; VBR voice varies by a quarter or more as speech and pauses
+// alternate.
+const (
+	opusCBRVariation = 0.05
+	opusVBRVariation = 0.35
+)
+
+// minOpusBitratePages is how many audio pages a bitrate variation needs.
+const minOpusBitratePages = 4
+
+// opusGranuleRate is the rate of Opus granule positions, which always
+// count 48 kHz samples whatever the input rate.
+const opusGranuleRate = 48000
+
+// analyzeOpusBitrate scores how constant an OGG/Opus file's bitrate is:
+// from 0.85 for CBR (likely TTS) down to 0.25 for clearly VBR (likely a
+// real voice). Returns 0.5 when there are too few pages to tell.
+func (a *AudioAnalyzer) analyzeOpusBitrate(data []byte) float64 {
+	cv := opusBitrateVariation(data)
+	switch {
+	case cv < 0:
+		return 0.5
+	case cv <= opusCBRVariation:
+		return 0.85
+	case cv >= opusVBRVariation:
+		return 0.25
+	}
+	// Linear between the thresholds
+	return 0.85 - 0.6*(cv-opusCBRVariation)/(opusVBRVariation-opusCBRVariation)
+}
+
+// opusBitrateVariation returns the coefficient of variation of the
+// bitrates of the first logical stream's audio pages in data. Each page's
+// bitrate is its payload size over the samples its granule position
+// advanced by. The header pages (granule 0) and the first audio page,
+// which also carries the encoder's pre-skip, are left out, as are pages
+// cut off at the end of data. Returns -1 with fewer than
+// minOpusBitratePages pages.
+func opusBitrateVariation(data []byte) float64 {
+	var (
+		bitrates []float64
+		serial   uint32
+		prev     int64 // granule position of the last page that had one
+		pending  int   // payload bytes since that page
+	)
+	for offset := 0; offset+27 <= len(data); {
+		if !bytes.Equal(data[offset:offset+4], []byte("OggS")) {
+			break
+		}
+		granule := int64(binary.LittleEndian.Uint64(data[offset+6:]))
+		pageSerial := binary.LittleEndian.Uint32(data[offset+14:])
+		segments := int(data[offset+26])
+		if offset+27+segments > len(data) {
+			break
+		}
+		payload := 0
+		for _, lacing := range data[offset+27 : offset+27+segments] {
+			payload += int(lacing)
+		}
+		next := offset + 27 + segments + payload
+		if next > len(data) {
+			break
+		}
+		first := offset == 0
+		offset = next
+
+		if first {
+			serial = pageSerial
+		}
+		if pageSerial != serial {
+			continue
+		}
+
+		// -1: no packet ends on this page, so its bytes count toward the
+		// next one
+		pending += payload
+		if granule == -1 {
+			continue
+		}
+		if prev > 0 && granule > prev {
+			seconds := float64(granule-prev) / opusGranuleRate
+			bitrates = append(bitrates, float64(pending*8)/seconds)
+		}
+		if granule > 0 {
+			prev = granule
+		}
+		pending = 0
+	}
+
+	if len(bitrates) < minOpusBitratePages {
+		return -1
+	}
+	mean, sumSq := 0.0, 0.0
+	for _, b := range bitrates {
+		mean += b
+	}
+	mean /= float64(len(bitrates))
+	if mean == 0 {
+		return -1
+	}
+	for _, b := range bitrates {
+		sumSq += (b - mean) * (b - mean)
+	}
+	return math.Sqrt(sumSq/float64(len(bitrates))) / mean
+}
+
 // analyzeM4A extracts metadata from M4A/AAC files.
 func (a *AudioAnalyzer) analyzeM4A(data []byte) (AudioMetadata, AudioStats) {
 	meta := AudioMetadata{Format: "m4a"}
@@ -472,21 +991,18 @@ func (a *AudioAnalyzer) analyzeFormat(meta AudioMetadata, data []byte) float64 {
 }
 
 // analyzePatterns looks for unusual patterns in audio data.
-func (a *AudioAnalyzer) analyzePatterns(data []byte, format string) float64 {
-	if len(data) < 5000 {
+func (a *AudioAnalyzer) analyzePatterns(s *mediaSample, format string) float64 {
+	if s.Len() < 5000 {
 		return 0.5
 	}
 
 	// Sample different regions
-	regionSize := len(data) / 5
+	regionSize := s.Len() / 5
 	entropies := make([]float64, 0)
 
-	for i := 1; i < 5; i++ {
-		start := i * regionSize
-		end := start + min(2000, len(data)-start)
-		if end > start {
-			entropy := calculateEntropy(data[start:end])
-			entropies = append(entropies, entropy)
+	for i := int64(1); i < 5; i++ {
+		if region := s.Region(i*regionSize, 2000); len(region) > 0 {
+			entropies = append(entropies, calculateEntropy(region))
 		}
 	}
 
@@ -530,11 +1046,22 @@ func (a *AudioAnalyzer) analyzeQuality(meta AudioMetadata, stats AudioStats) flo
 		score += 0.1
 	}
 
+	// Re-encoded from an earlier lossy file, as in encode-decode TTS pipelines
+	if meta.DoubleCompressionSuspected {
+		score += 0.2
+	}
+
 	// File size sanity check
 	if stats.FileSize > 0 && stats.FileSize < 10000 {
 		score += 0.2 // Very small file is suspicious
 	}
 
+	// Energy concentrated in the telephone band, as telephony-grade TTS
+	// leaves it
+	if stats.SpectralCentroid > 0 && stats.SpectralCentroid < telephonyCentroidHz {
+		score += 0.15
+	}
+
 	return score
 }
 
@@ -570,8 +1097,8 @@ func (a *AudioAnalyzer) detectAISignatures(data []byte, meta AudioMetadata) floa
 }
 
 // analyzeNoiseProfile checks for natural noise characteristics.
-func (a *AudioAnalyzer) analyzeNoiseProfile(data []byte, format string) float64 {
-	if len(data) < 10000 {
+func (a *AudioAnalyzer) analyzeNoiseProfile(s *mediaSample, format string) float64 {
+	if s.Len() < 10000 {
 		return 0.5
 	}
 
@@ -579,9 +1106,7 @@ func (a *AudioAnalyzer) analyzeNoiseProfile(data []byte, format string) float64
 	// Real recordings have natural noise; AI audio is often "too clean"
 
 	// Sample from middle of file (skip headers)
-	start := len(data) / 3
-	end := start + min(5000, len(data)-start)
-	sample := data[start:end]
+	sample := s.Region(s.Len()/3, 5000)
 
 	// Check for repeated patterns (AI sometimes has artifacts)
 	repeatCount := 0
@@ -0,0 +1,522 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// mp3Frame builds an MPEG1 Layer 3 frame (44.1kHz) with payload after the
+// side information, padded to the frame length for its bitrate.
+func mp3Frame(bitrateIdx byte, mono bool, payload []byte) []byte {
+	channelMode := byte(0x00)
+	sideInfo := 32
+	if mono {
+		channelMode = 0xC0
+		sideInfo = 17
+	}
+
+	frame := []byte{0xFF, 0xFB, bitrateIdx << 4, channelMode}
+	frame = append(frame, make([]byte, sideInfo)...)
+	frame = append(frame, payload...)
+	if n := 144 * mpeg1Bitrates[2][bitrateIdx] * 1000 / 44100; len(frame) < n {
+		frame = append(frame, make([]byte, n-len(frame))...)
+	}
+	return frame
+}
+
+// xingTag builds a Xing/Info header with the fields flags says are present,
+// followed by a LAME encoder string.
+func xingTag(id string, flags uint32, encoder string) []byte {
+	tag := []byte(id)
+	tag = binary.BigEndian.AppendUint32(tag, flags)
+	if flags&0x1 != 0 {
+		tag = binary.BigEndian.AppendUint32(tag, 1000)
+	}
+	if flags&0x2 != 0 {
+		tag = binary.BigEndian.AppendUint32(tag, 400000)
+	}
+	if flags&0x4 != 0 {
+		tag = append(tag, make([]byte, 100)...)
+	}
+	if flags&0x8 != 0 {
+		tag = binary.BigEndian.AppendUint32(tag, 57)
+	}
+	return append(tag, encoder...)
+}
+
+// id3v23 builds an ID3v2.3 tag with one text frame per value.
+func id3v23(frames ...string) []byte {
+	var body []byte
+	for i, value := range frames {
+		ids := []string{"TIT2", "TPE1", "TALB", "TRCK", "TYER", "TCON", "COMM", "TPE2"}
+		body = append(body, ids[i%len(ids)]...)
+		body = binary.BigEndian.AppendUint32(body, uint32(len(value)+1))
+		body = append(body, 0, 0, 0) // Flags, then ISO-8859-1 encoding byte
+		body = append(body, value...)
+	}
+
+	size := len(body)
+	tag := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(tag, body...)
+}
+
+// TestLAMEVersion tests reading the encoder version from the first frame.
+func TestLAMEVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		want  string
+	}{
+		{"Info tag, all fields", mp3Frame(9, false, xingTag("Info", 0x0F, "LAME3.99r")), "3.99r"},
+		{"Xing tag, frame count only", mp3Frame(9, false, xingTag("Xing", 0x01, "LAME3.100")), "3.100"},
+		{"mono side info", mp3Frame(1, true, xingTag("Info", 0x0F, "LAME3.99r")), "3.99r"},
+		{"ancillary data", mp3Frame(9, false, append(make([]byte, 64), "LAME3.98.4"...)), "3.98.4"},
+		{"no encoder", mp3Frame(9, false, nil), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mono := tt.frame[3]&0xC0 == 0xC0
+			if got := lameVersion(tt.frame, 3, mono); got != tt.want {
+				t.Errorf("lameVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("sets metadata", func(t *testing.T) {
+		data := mp3Frame(9, false, xingTag("Info", 0x0F, "LAME3.99r"))
+		data = append(data, bytes.Repeat(mp3Frame(9, false, nil), 30)...)
+
+		result := NewAudioAnalyzer().Analyze(data)
+		if result.Metadata.EncoderVersion != "3.99r" {
+			t.Errorf("EncoderVersion = %q, want 3.99r", result.Metadata.EncoderVersion)
+		}
+		if result.Metadata.EncoderName != "LAME" {
+			t.Errorf("EncoderName = %q, want LAME", result.Metadata.EncoderName)
+		}
+	})
+}
+
+// TestDoubleCompression tests the low-bitrate, richly tagged heuristic.
+func TestDoubleCompression(t *testing.T) {
+	richTag := id3v23("Morning Briefing", "Daily News", "Episode 12", "12", "2024", "Podcast")
+	sparseTag := id3v23("voice note")
+
+	mp3 := func(tag []byte, bitrateIdx byte) []byte {
+		data := append([]byte{}, tag...)
+		data = append(data, mp3Frame(bitrateIdx, true, xingTag("Info", 0x0F, "LAME3.99r"))...)
+		return append(data, bytes.Repeat(mp3Frame(bitrateIdx, true, nil), 30)...)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"32kbps with rich tags", mp3(richTag, 1), true},
+		{"32kbps with sparse tags", mp3(sparseTag, 1), false},
+		{"128kbps with rich tags", mp3(richTag, 9), false},
+	}
+
+	analyzer := NewAudioAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analyzer.Analyze(tt.data)
+			if result.Metadata.DoubleCompressionSuspected != tt.want {
+				t.Errorf("DoubleCompressionSuspected = %v, want %v (bitrate %d)",
+					result.Metadata.DoubleCompressionSuspected, tt.want, result.Metadata.Bitrate)
+			}
+		})
+	}
+
+	t.Run("raises quality indicators", func(t *testing.T) {
+		suspected := analyzer.Analyze(mp3(richTag, 1))
+		clean := analyzer.Analyze(mp3(sparseTag, 1))
+		if suspected.Signals.QualityIndicators <= clean.Signals.QualityIndicators {
+			t.Errorf("QualityIndicators = %f, want above %f", suspected.Signals.QualityIndicators, clean.Signals.QualityIndicators)
+		}
+	})
+
+	t.Run("frame sync inside tag is skipped", func(t *testing.T) {
+		// Cover art bytes that look like a 320kbps frame header
+		tag := id3v23("Title", string([]byte{0xFF, 0xFB, 0xE0, 0x00}))
+		result := analyzer.Analyze(mp3(tag, 1))
+		if result.Metadata.Bitrate != 32 {
+			t.Errorf("Bitrate = %d, want 32 from the first real frame", result.Metadata.Bitrate)
+		}
+	})
+}
+
+// TestParseMP3FrameHeader tests decoding and validating frame headers.
+func TestParseMP3FrameHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header uint32
+		want   mp3FrameHeader
+		ok     bool
+	}{
+		{"MPEG1 Layer III 128kbps", 0xFFFB9000, mp3FrameHeader{version: 3, layer: 3, bitrate: 128, sampleRate: 44100, channels: 2, length: 417}, true},
+		{"MPEG1 Layer III padded mono", 0xFFFB92C0, mp3FrameHeader{version: 3, layer: 3, bitrate: 128, sampleRate: 44100, channels: 1, length: 418}, true},
+		{"MPEG2 Layer III 64kbps", 0xFFF38000, mp3FrameHeader{version: 2, layer: 3, bitrate: 64, sampleRate: 22050, channels: 2, length: 208}, true},
+		{"MPEG2.5 Layer III 8kbps", 0xFFE31800, mp3FrameHeader{version: 0, layer: 3, bitrate: 8, sampleRate: 8000, channels: 2, length: 72}, true},
+		{"MPEG1 Layer II 192kbps", 0xFFFDA400, mp3FrameHeader{version: 3, layer: 2, bitrate: 192, sampleRate: 48000, channels: 2, length: 576}, true},
+		{"MPEG1 Layer I 384kbps", 0xFFFFC800, mp3FrameHeader{version: 3, layer: 1, bitrate: 384, sampleRate: 32000, channels: 2, length: 576}, true},
+		{"no sync", 0xFFDB9000, mp3FrameHeader{}, false},
+		{"reserved version", 0xFFEB9000, mp3FrameHeader{}, false},
+		{"reserved layer", 0xFFF99000, mp3FrameHeader{}, false},
+		{"free format bitrate", 0xFFFB0000, mp3FrameHeader{}, false},
+		{"bad bitrate", 0xFFFBF000, mp3FrameHeader{}, false},
+		{"reserved sample rate", 0xFFFB9C00, mp3FrameHeader{}, false},
+		{"reserved emphasis", 0xFFFB9002, mp3FrameHeader{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseMP3FrameHeader(tt.header)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseMP3FrameHeader(%08X) = %+v, %v, want %+v, %v", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestAnalyzeMP3FrameSync tests that false frame syncs are skipped.
+func TestAnalyzeMP3FrameSync(t *testing.T) {
+	frames := bytes.Repeat(mp3Frame(9, false, nil), 10)
+
+	tests := []struct {
+		name    string
+		garbage []byte
+	}{
+		{"valid header not followed by a frame", append([]byte{0xFF, 0xFB, 0x10, 0xC0}, make([]byte, 200)...)},
+		{"reserved fields", []byte{0xFF, 0xEB, 0x90, 0x00, 0xFF, 0xFB, 0x9C, 0x00, 0xFF, 0xFB, 0xF0, 0x00}},
+		{"run of 0xFF", bytes.Repeat([]byte{0xFF}, 64)},
+	}
+
+	analyzer := NewAudioAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte{0xFF, 0xFB, 0x00, 0x00}, tt.garbage...)
+			data = append(data, frames...)
+
+			meta := analyzer.Analyze(data).Metadata
+			if meta.Format != "mp3" || meta.Bitrate != 128 || meta.SampleRate != 44100 || meta.Channels != 2 {
+				t.Errorf("Metadata = %+v, want 128kbps 44100 Hz stereo from the real frames", meta)
+			}
+		})
+	}
+}
+
+// TestAnalyzeMP3ShortFiles tests that truncated files don't panic and
+// leave the audio parameters unset.
+func TestAnalyzeMP3ShortFiles(t *testing.T) {
+	frame := mp3Frame(9, true, nil)
+	tag := append([]byte("TAG"), make([]byte, 125)...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"3 byte ID3 marker", []byte("ID3")},
+		{"3 byte frame sync", frame[:3]},
+		{"127 byte frame", frame[:127]},
+		{"127 byte ID3 header", append([]byte{'I', 'D', '3', 3, 0, 0, 0x7F, 0x7F, 0x7F, 0x7F}, make([]byte, 117)...)},
+		{"200 byte frame", frame[:200]},
+		{"200 bytes of 0xFF", bytes.Repeat([]byte{0xFF}, 200)},
+		{"200 byte frame with ID3v1 tag", append(frame[:72:72], tag...)},
+	}
+
+	analyzer := NewAudioAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analyzer.Analyze(tt.data)
+			meta := result.Metadata
+			if meta.SampleRate != 0 || meta.Bitrate != 0 || meta.Channels != 0 || meta.DoubleCompressionSuspected {
+				t.Errorf("Metadata = %+v, want no audio parameters", meta)
+			}
+			if result.Signals.FormatAnalysis != 0.5 {
+				t.Errorf("FormatAnalysis = %f, want neutral 0.5", result.Signals.FormatAnalysis)
+			}
+			if result.AIScore >= 0.5 {
+				t.Errorf("AIScore = %f, want below 0.5", result.AIScore)
+			}
+		})
+	}
+}
+
+// TestCountID3Frames tests ID3v2 frame counting.
+func TestCountID3Frames(t *testing.T) {
+	tag := id3v23("a", "b", "c")
+	if got := countID3Frames(tag[10:], 3); got != 3 {
+		t.Errorf("countID3Frames() = %d, want 3", got)
+	}
+
+	padded := append(append([]byte{}, tag[10:]...), make([]byte, 64)...)
+	if got := countID3Frames(padded, 3); got != 3 {
+		t.Errorf("countID3Frames() with padding = %d, want 3", got)
+	}
+
+	if got := countID3Frames([]byte("not a tag at all"), 3); got != 0 {
+		t.Errorf("countID3Frames() on garbage = %d, want 0", got)
+	}
+}
+
+// pcmWAV builds a WAV file with a fmt chunk, a LIST chunk and samples (one
+// per channel per frame) as format (1 = PCM) with bitDepth bits.
+func pcmWAV(format uint16, sampleRate, channels, bitDepth int, samples []int16) []byte {
+	var pcm []byte
+	for _, s := range samples {
+		if bitDepth == 8 {
+			pcm = append(pcm, byte(int(s>>8)+128))
+		} else {
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(s))
+		}
+	}
+
+	fmtChunk := binary.LittleEndian.AppendUint16(nil, format)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(channels))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(sampleRate))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(sampleRate*channels*bitDepth/8))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(channels*bitDepth/8))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(bitDepth))
+
+	chunk := func(id string, body []byte) []byte {
+		c := binary.LittleEndian.AppendUint32([]byte(id), uint32(len(body)))
+		c = append(c, body...)
+		if len(body)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	body := []byte("WAVE")
+	body = append(body, chunk("fmt ", fmtChunk)...)
+	body = append(body, chunk("LIST", []byte("INFOISFT\x05\x00\x00\x00mic\x00\x00"))...)
+	body = append(body, chunk("data", pcm)...)
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+// sweep returns n samples of a sine sweeping linearly from fromHz to toHz
+// (a tone when they're equal).
+func sweep(sampleRate, n int, fromHz, toHz float64) []int16 {
+	samples := make([]int16, n)
+	phase := 0.0
+	for i := range samples {
+		freq := fromHz + (toHz-fromHz)*float64(i)/float64(n)
+		phase += 2 * math.Pi * freq / float64(sampleRate)
+		samples[i] = int16(12000 * math.Sin(phase))
+	}
+	return samples
+}
+
+// TestSpectralCentroidProxy tests locating a WAV file's dominant frequency.
+func TestSpectralCentroidProxy(t *testing.T) {
+	const rate = 44100
+	binWidth := float64(rate) / spectralPoints
+	analyzer := NewAudioAnalyzer()
+
+	tests := []struct {
+		name     string
+		samples  []int16
+		wantHz   float64
+		wantTele bool
+	}{
+		{"440 Hz tone", sweep(rate, 4096, 440, 440), 440, true},
+		{"1000 Hz tone", sweep(rate, 4096, 1000, 1000), 1000, true},
+		{"5000 Hz tone", sweep(rate, 4096, 5000, 5000), 5000, false},
+		{"8000 Hz tone", sweep(rate, 4096, 8000, 8000), 8000, false},
+		{"12000 Hz tone", sweep(rate, 4096, 12000, 12000), 12000, false},
+		{"telephone band sweep", sweep(rate, 4096, 200, 3000), 1600, true},
+		{"wideband sweep", sweep(rate, 4096, 6000, 10000), 8000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analyzer.Analyze(pcmWAV(1, rate, 1, 16, tt.samples))
+			got := result.Stats.SpectralCentroid
+			if math.Abs(got-tt.wantHz) > binWidth {
+				t.Errorf("SpectralCentroid = %.0f Hz, want %.0f Hz within %.0f", got, tt.wantHz, binWidth)
+			}
+			if tele := got > 0 && got < telephonyCentroidHz; tele != tt.wantTele {
+				t.Errorf("SpectralCentroid = %.0f Hz, telephone band = %v, want %v", got, tele, tt.wantTele)
+			}
+		})
+	}
+
+	t.Run("uses the first channel and scales with sample rate", func(t *testing.T) {
+		left := sweep(16000, 2048, 6000, 6000)
+		right := sweep(16000, 2048, 500, 500)
+		stereo := make([]int16, 0, 4096)
+		for i := range left {
+			stereo = append(stereo, left[i], right[i])
+		}
+		got := analyzer.Analyze(pcmWAV(1, 16000, 2, 16, stereo)).Stats.SpectralCentroid
+		if math.Abs(got-6000) > 16000/spectralPoints {
+			t.Errorf("SpectralCentroid = %.0f Hz, want about 6000 Hz", got)
+		}
+	})
+
+	t.Run("8-bit PCM", func(t *testing.T) {
+		got := analyzer.Analyze(pcmWAV(1, rate, 1, 8, sweep(rate, 4096, 8000, 8000))).Stats.SpectralCentroid
+		if math.Abs(got-8000) > binWidth {
+			t.Errorf("SpectralCentroid = %.0f Hz, want about 8000 Hz", got)
+		}
+	})
+
+	zeroTests := []struct {
+		name string
+		data []byte
+	}{
+		{"silence", pcmWAV(1, rate, 1, 16, make([]int16, 4096))},
+		{"too short", pcmWAV(1, rate, 1, 16, sweep(rate, 100, 1000, 1000))},
+		{"float samples", pcmWAV(3, rate, 1, 16, sweep(rate, 4096, 1000, 1000))},
+		{"24-bit", pcmWAV(1, rate, 1, 24, sweep(rate, 4096, 1000, 1000))},
+	}
+	for _, tt := range zeroTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzer.Analyze(tt.data).Stats.SpectralCentroid; got != 0 {
+				t.Errorf("SpectralCentroid = %.0f Hz, want 0", got)
+			}
+		})
+	}
+
+	t.Run("telephone band raises quality indicators", func(t *testing.T) {
+		narrow := analyzer.Analyze(pcmWAV(1, rate, 1, 16, sweep(rate, 8192, 300, 1200)))
+		wide := analyzer.Analyze(pcmWAV(1, rate, 1, 16, sweep(rate, 8192, 7000, 9000)))
+		if narrow.Signals.QualityIndicators <= wide.Signals.QualityIndicators {
+			t.Errorf("QualityIndicators = %f, want above %f", narrow.Signals.QualityIndicators, wide.Signals.QualityIndicators)
+		}
+	})
+}
+
+// TestFFTRadix2 tests the FFT against a direct DFT.
+func TestFFTRadix2(t *testing.T) {
+	x := make([]complex128, spectralPoints)
+	for i := range x {
+		x[i] = complex(math.Sin(float64(i)*0.7)+0.3*float64(i%5), 0)
+	}
+
+	want := make([]complex128, len(x))
+	for k := range want {
+		for n, v := range x {
+			want[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(k*n)/float64(len(x))))
+		}
+	}
+
+	fftRadix2(x)
+	for k := range x {
+		if cmplx.Abs(x[k]-want[k]) > 1e-9 {
+			t.Errorf("bin %d = %v, want %v", k, x[k], want[k])
+		}
+	}
+}
+
+// oggPage builds an OGG page of the given stream holding payload as one
+// packet.
+func oggPage(serial uint32, granule int64, payload []byte) []byte {
+	var lacing []byte
+	for n := len(payload); ; n -= 255 {
+		if n < 255 {
+			lacing = append(lacing, byte(n))
+			break
+		}
+		lacing = append(lacing, 255)
+	}
+
+	page := []byte("OggS")
+	page = append(page, 0, 0)
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = append(page, make([]byte, 8)...) // sequence number and CRC
+	page = append(page, byte(len(lacing)))
+	page = append(page, lacing...)
+	return append(page, payload...)
+}
+
+// opusFile builds an OGG/Opus file with one audio page per size, each
+// holding one second of audio.
+func opusFile(sizes ...int) []byte {
+	head := append([]byte("OpusHead"), 1, 1, 0x38, 0x01, 0x80, 0xBB, 0, 0, 0, 0, 0)
+	data := oggPage(1, 0, head)
+	data = append(data, oggPage(1, 0, []byte("OpusTags"))...)
+	for i, size := range sizes {
+		data = append(data, oggPage(1, int64(i+1)*opusGranuleRate, make([]byte, size))...)
+	}
+	return data
+}
+
+func TestOpusBitrate(t *testing.T) {
+	analyzer := NewAudioAnalyzer()
+
+	// 32 kbps is 4000 bytes a second
+	cbr := opusFile(4000, 4000, 4000, 4000, 4000, 4000, 4000)
+	nearCBR := opusFile(4000, 4010, 3990, 4005, 3995, 4000, 4000)
+	vbr := opusFile(4000, 1200, 6500, 2500, 7000, 900, 5200)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantCV    func(cv float64) bool
+		wantScore float64
+	}{
+		{"CBR", cbr, func(cv float64) bool { return cv == 0 }, 0.85},
+		{"CBR with jitter", nearCBR, func(cv float64) bool { return cv > 0 && cv < opusCBRVariation }, 0.85},
+		{"VBR voice", vbr, func(cv float64) bool { return cv > opusVBRVariation }, 0.25},
+		{"too few pages", opusFile(4000, 4000, 4000), func(cv float64) bool { return cv == -1 }, 0.5},
+		{"not OGG", []byte("RIFF....WAVEfmt "), func(cv float64) bool { return cv == -1 }, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cv := opusBitrateVariation(tt.data); !tt.wantCV(cv) {
+				t.Errorf("opusBitrateVariation() = %.3f", cv)
+			}
+			if got := analyzer.analyzeOpusBitrate(tt.data); got != tt.wantScore {
+				t.Errorf("analyzeOpusBitrate() = %.2f, want %.2f", got, tt.wantScore)
+			}
+		})
+	}
+
+	t.Run("in between scores in between", func(t *testing.T) {
+		got := analyzer.analyzeOpusBitrate(opusFile(4000, 3400, 4600, 3600, 4400, 4000, 3800))
+		if got <= 0.25 || got >= 0.85 {
+			t.Errorf("analyzeOpusBitrate() = %.2f, want between 0.25 and 0.85", got)
+		}
+	})
+
+	t.Run("pages without a granule position carry over", func(t *testing.T) {
+		// The second second of audio spans two pages
+		data := opusFile(4000)
+		data = append(data, oggPage(1, -1, make([]byte, 2000))...)
+		data = append(data, oggPage(1, 2*opusGranuleRate, make([]byte, 2000))...)
+		for i := int64(3); i <= 6; i++ {
+			data = append(data, oggPage(1, i*opusGranuleRate, make([]byte, 4000))...)
+		}
+		if cv := opusBitrateVariation(data); cv != 0 {
+			t.Errorf("opusBitrateVariation() = %.3f, want 0", cv)
+		}
+	})
+
+	t.Run("ignores other streams and truncated pages", func(t *testing.T) {
+		data := append([]byte{}, cbr...)
+		data = append(data, oggPage(2, 8*opusGranuleRate, make([]byte, 9000))...)
+		data = append(data, oggPage(1, 8*opusGranuleRate, make([]byte, 4000))[:100]...)
+		if cv := opusBitrateVariation(data); cv != 0 {
+			t.Errorf("opusBitrateVariation() = %.3f, want 0", cv)
+		}
+	})
+
+	t.Run("Analyze reports it for Opus", func(t *testing.T) {
+		cbrResult := analyzer.Analyze(cbr)
+		vbrResult := analyzer.Analyze(vbr)
+		if cbrResult.Metadata.Format != "opus" || cbrResult.Stats.BitrateVariation != 0 {
+			t.Errorf("got format %q, variation %.3f", cbrResult.Metadata.Format, cbrResult.Stats.BitrateVariation)
+		}
+		if cbrResult.Signals.NoiseProfile <= vbrResult.Signals.NoiseProfile {
+			t.Errorf("CBR NoiseProfile %.2f should exceed VBR's %.2f",
+				cbrResult.Signals.NoiseProfile, vbrResult.Signals.NoiseProfile)
+		}
+	})
+}
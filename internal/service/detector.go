@@ -4,39 +4,55 @@
 // implementations for production (external APIs) and testing (mocks).
 //
 // Detection flow:
-//  1. Determine content type (text, image, audio, video)
+//  1. Determine content type (text, image, audio, video, document)
 //  2. Route to appropriate detector(s)
 //  3. Aggregate results from multiple detectors
 //  4. Return final verdict: Human or Non-Human
 package service
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/minhash"
+	"github.com/humanmark/humanmark/pkg/tracing"
 )
 
 // ContentType represents the type of content being analyzed.
 type ContentType string
 
 const (
-	ContentTypeText    ContentType = "text"
-	ContentTypeImage   ContentType = "image"
-	ContentTypeAudio   ContentType = "audio"
-	ContentTypeVideo   ContentType = "video"
-	ContentTypeUnknown ContentType = "unknown"
+	ContentTypeText     ContentType = "text"
+	ContentTypeImage    ContentType = "image"
+	ContentTypeAudio    ContentType = "audio"
+	ContentTypeVideo    ContentType = "video"
+	ContentTypeDocument ContentType = "document"
+	ContentTypeUnknown  ContentType = "unknown"
+
+	// ContentTypePlist is an iMessage plist export; its messages are
+	// extracted and scored as text
+	ContentTypePlist ContentType = "plist"
 )
 
 // DetectionInput represents input to the detection system.
 type DetectionInput struct {
-	// URL of content to fetch and analyze
+	// URL of content to fetch and analyze. A data: URI carries the content
+	// itself and is decoded into Data instead.
 	URL string
 
 	// Text content to analyze directly
@@ -45,18 +61,83 @@ type DetectionInput struct {
 	// Binary data for uploaded files
 	Data []byte
 
+	// Reader holds an uploaded file too large to keep in memory (usually
+	// a temp file), Size bytes long, in place of Data. Detectors read
+	// content through OpenData and ReadData, which prefer it. Whoever
+	// creates the input releases it with Close.
+	Reader io.ReaderAt
+	Size   int64
+
+	// Base64Data is binary content encoded as standard or URL-safe base64.
+	// It is decoded into Data before detection.
+	Base64Data string
+
 	// Original filename for uploaded files
 	Filename string
 
+	// MIMEType is the Content-Type the content was declared with: an
+	// upload part's header, a data: URI's type, or a fetched response's
+	// header (optional). Media analyzers compare it to the bytes.
+	MIMEType string
+
 	// Detected or specified content type
 	ContentType ContentType
+
+	// CallbackURL receives a webhook when async processing finishes.
+	// Detect ignores it.
+	CallbackURL string
+
+	// EmailMode analyzes text as an email: headers and quoted replies are
+	// stripped and email-specific signals (urgency, generic greetings) added.
+	EmailMode bool
+
+	// AcademicMode analyzes text as academic writing: citations, hedges and
+	// jargon count as human, generic formal phrasing as AI, and AI phrases
+	// weigh less. Ignored in email mode.
+	AcademicMode bool
+
+	// CustomStopPhrases are AI phrases that are ordinary vocabulary in the
+	// caller's domain (e.g. "leverage" in finance) and shouldn't be flagged
+	CustomStopPhrases []string
+
+	// CustomCommonWords are treated as common vocabulary for this request
+	CustomCommonWords []string
+
+	// Detectors limits the request to these detectors (e.g. "humanmark",
+	// "hive"). Empty means every configured detector.
+	Detectors []string
+
+	// ExcludeDetectors are detectors not to run for this request
+	ExcludeDetectors []string
 }
 
+// ErrInvalidBase64 is returned when DetectionInput.Base64Data cannot be decoded.
+var ErrInvalidBase64 = errors.New("invalid base64 data")
+
+// ErrInvalidDataURI is returned when a data: URI in DetectionInput.URL
+// cannot be decoded.
+var ErrInvalidDataURI = errors.New("invalid data URI")
+
+// ErrUnsupportedContentType is returned for content none of the detectors
+// handle.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// ErrContentTooLarge is returned when content exceeds a size limit.
+var ErrContentTooLarge = errors.New("content too large")
+
 // DetectionResult represents the output of detection.
 type DetectionResult struct {
-	// Human is true if content was created by a human
+	// Human is true if content was created by a human.
+	// Kept for compatibility; it is false for uncertain verdicts.
 	Human bool
 
+	// Verdict is human, ai, or uncertain, per the DecisionPolicy
+	Verdict Verdict
+
+	// Uncertain is true when the verdict is uncertain: the score fell
+	// between the policy's thresholds or within its uncertainty band
+	Uncertain bool
+
 	// Confidence is how confident we are (0.0-1.0)
 	// Higher = more confident in the verdict
 	Confidence float64
@@ -74,8 +155,154 @@ type DetectionResult struct {
 	// ContentHash is SHA256 hash of the analyzed content
 	ContentHash string
 
+	// NormalizedHash is NormalizedTextHash of text content (empty for
+	// media), matching copies that differ only in whitespace
+	NormalizedHash string
+
+	// Fingerprint is the MinHash signature of text content (nil for media).
+	// Unlike ContentHash it also matches lightly edited copies.
+	Fingerprint []uint32
+
 	// ProcessingTime is how long detection took
 	ProcessingTime time.Duration
+
+	// AnalyzerVersion is the version of the detection logic (AnalyzerVersion)
+	AnalyzerVersion string
+
+	// ConfigFingerprint identifies the weights, phrase dictionary and
+	// thresholds in effect. Results are only comparable when it matches.
+	ConfigFingerprint string
+
+	// AnchorTxID is the blockchain transaction holding ContentHash, when
+	// anchoring is enabled and succeeded
+	AnchorTxID string
+
+	// AnchoredAt is the timestamp recorded with the anchored hash
+	AnchoredAt time.Time
+
+	// Aggregation is the strategy used to combine provider scores
+	Aggregation AggregationStrategy
+
+	// ProviderScores holds each provider's score before aggregation
+	ProviderScores []ProviderScore
+
+	// Disagreement is the weighted standard deviation of ProviderScores
+	// (0-0.5; 0 with a single provider)
+	Disagreement float64
+
+	// NeedsReview is set when Disagreement exceeds the configured
+	// threshold. Confidence is then capped at DisagreementConfidenceCap.
+	NeedsReview bool
+
+	// Evidence is conclusive provenance found in the content, if any
+	Evidence Evidence
+
+	// Basis summarizes what the result rests on: local-only,
+	// local+N-external, or conclusive-marker. Local-only confidence is
+	// capped at DetectorConfig.LocalOnlyConfidenceCap.
+	Basis Basis
+
+	// HostReputation is the host rule that matched a URL input, if any
+	HostReputation *HostReputationMatch
+
+	// AIPhrases are the AI phrases the local analyzer found (text only)
+	AIPhrases []DetectedPhrase
+
+	// Watermark is the local analyzer's experimental watermark scan (text
+	// long enough to scan only). It doesn't affect AIScore.
+	Watermark *WatermarkScan
+
+	// DetectorOutputs holds per-sentence scores from our analyzer (text
+	// only) and from providers that return them
+	DetectorOutputs []DetectorOutput
+
+	// SentenceAgreement compares GPTZero's per-sentence scores with each
+	// other detector's that scored the same sentences (text only)
+	SentenceAgreement []SentenceAgreement
+
+	// DetectorErrors maps each detector that failed (including by
+	// panicking) to its error. The other detectors' scores were used.
+	DetectorErrors map[string]string
+
+	// SkippedProviders lists configured providers that declined the input,
+	// e.g. text below their minimum length
+	SkippedProviders []SkippedProvider
+
+	// MismatchWarning is set when the filename or URL extension, or the
+	// declared MIME type, disagrees with the content's magic bytes. The
+	// content was analyzed as what its bytes say it is; a mislabeled upload
+	// may be an evasion attempt.
+	MismatchWarning string
+
+	// SimilarContent is set when earlier submitted content is nearly the
+	// same. Detect doesn't set it; callers with job history do.
+	SimilarContent *SimilarContentWarning
+}
+
+// SimilarContentThreshold is the estimated Jaccard similarity above which
+// content counts as a near-duplicate of an earlier job.
+const SimilarContentThreshold = 0.7
+
+// SimilarContentWarning points at an earlier job with nearly the same content.
+type SimilarContentWarning struct {
+	// JobID is the most similar earlier job
+	JobID string
+
+	// Similarity is the estimated Jaccard similarity (0.0-1.0)
+	Similarity float64
+}
+
+// DetectorOutput is one detector's detailed output.
+type DetectorOutput struct {
+	// Name is the detector name, e.g. "humanmark" or "winston"
+	Name string
+
+	// Sentences are the detector's per-sentence scores
+	Sentences []SentenceScore
+
+	// Paragraphs group Sentences, when the provider scores paragraphs too
+	Paragraphs []ParagraphScore
+}
+
+// SentenceScore is a detector's score for one sentence.
+type SentenceScore struct {
+	Text    string
+	AIScore float64
+}
+
+// ParagraphScore is a provider's score for a run of sentences.
+type ParagraphScore struct {
+	// StartSentence is the index of the paragraph's first sentence in
+	// DetectorOutput.Sentences
+	StartSentence int
+
+	// Sentences is how many sentences the paragraph has
+	Sentences int
+
+	AIScore float64
+}
+
+// SentenceAgreement is how closely two detectors' per-sentence scores agree
+// on the sentences both scored.
+type SentenceAgreement struct {
+	// Detectors are the two detectors compared
+	Detectors []string
+
+	// Sentences is how many sentences both detectors scored
+	Sentences int
+
+	// Agreement is the fraction of those sentences both put on the same
+	// side of 0.5
+	Agreement float64
+
+	// MeanDifference is the mean absolute difference of their scores
+	MeanDifference float64
+}
+
+// SkippedProvider is a configured provider that didn't score the input.
+type SkippedProvider struct {
+	Name   string
+	Reason string
 }
 
 // Detector is the interface for content detection.
@@ -84,31 +311,213 @@ type Detector interface {
 	Detect(ctx context.Context, input DetectionInput) (*DetectionResult, error)
 }
 
+// ConfigFingerprinter is implemented by detectors that can report the
+// ConfigFingerprint their results will carry without running, so callers
+// can tell whether a stored result is still current.
+type ConfigFingerprinter interface {
+	ConfigFingerprint() string
+}
+
+// Reloader is implemented by detectors whose configuration can be replaced
+// while they run, e.g. to pick up an edited phrase dictionary without a
+// restart.
+type Reloader interface {
+	// Reload switches to config for detections that start after it
+	// returns; detections already running finish with the configuration
+	// they started with. An invalid config is rejected and the current one
+	// kept.
+	Reload(config DetectorConfig) error
+}
+
 // DetectorConfig holds configuration for the detector.
 type DetectorConfig struct {
 	HiveAPIKey    string
 	OpenAIAPIKey  string
 	GPTZeroAPIKey string
 	Timeout       time.Duration
+
+	// HTTPClient makes the calls to external providers, shared by every
+	// detector (default: a plain client with Timeout). Build it with
+	// NewOutboundClient; URL inputs are fetched with its TLS and
+	// connection settings too, but never through its proxy.
+	HTTPClient *http.Client
+
+	// ProviderURLs overrides external providers' API base URLs by name,
+	// e.g. {"winston": "https://gateway.internal/winston"} for a
+	// self-hosted gateway or a test server.
+	ProviderURLs map[string]string
+
+	// SightengineAPIKey is "api_user:api_secret" (image only)
+	SightengineAPIKey string
+
+	// AIorNotAPIKey enables AIorNot image detection
+	AIorNotAPIKey string
+
+	// AnthropicAPIKey enables Claude vision image detection
+	AnthropicAPIKey string
+
+	// OriginalityAPIKey enables Originality.ai text detection
+	OriginalityAPIKey string
+
+	// WinstonAPIKey enables Winston AI text detection
+	WinstonAPIKey string
+
+	// AllowPrivateNetworks lets URL inputs reach private/loopback addresses.
+	// Only enable this in development.
+	AllowPrivateNetworks bool
+
+	// MaxRedirects caps redirects followed when fetching URL inputs.
+	MaxRedirects int
+
+	// Aggregation selects how provider scores are combined (default: weighted-mean).
+	Aggregation AggregationStrategy
+
+	// ProviderWeights overrides default reliability weights by detector name,
+	// e.g. {"winston": 1.3}. Unlisted detectors keep their defaults.
+	ProviderWeights map[string]float64
+
+	// ProviderTimeouts gives external providers, by name, less time than
+	// Timeout to answer. Unlisted providers get Timeout.
+	ProviderTimeouts map[string]time.Duration
+
+	// Decision sets the verdict thresholds (default: split at 0.5) and
+	// the uncertainty band around a split. The server default band is 0.15.
+	Decision DecisionPolicy
+
+	// DisagreementThreshold flags results whose provider scores have a
+	// larger weighted standard deviation for review. 0 disables it; the
+	// server default is 0.25.
+	DisagreementThreshold float64
+
+	// LocalOnlyConfidenceCap is the highest confidence reported for a
+	// result only the local detectors scored. 0 disables it; the server
+	// default is 0.6.
+	LocalOnlyConfidenceCap float64
+
+	// HostRules add to or replace DefaultHostRules, by host.
+	HostRules []HostRule
+
+	// AIPhrases replace AIPhraseDictionary, the phrases the text analyzer
+	// looks for (empty = AIPhraseDictionary).
+	AIPhrases []AIPhrase
+
+	// Metrics receives pipeline metrics (nil records nothing).
+	Metrics metrics.Registry
+
+	// Anchor, when set, publishes each result's content hash to a
+	// blockchain. The server never sets it; see anchor.go.
+	Anchor BlockchainAnchor
+
+	// AnchorTimeout bounds anchoring each result, which Detect waits for
+	// (default: DefaultAnchorTimeout). A result not anchored in time is
+	// returned unanchored.
+	AnchorTimeout time.Duration
+
+	// PageMaxAssets caps the images and videos checked per page in page
+	// mode (default: DefaultPageMaxAssets).
+	PageMaxAssets int
+
+	// PageByteBudget bounds the bytes fetched for one page, the HTML and
+	// all of its assets (default: DefaultPageByteBudget).
+	PageByteBudget int64
 }
 
 // detector is the main implementation of Detector.
 type detector struct {
-	config DetectorConfig
-	logger *logger.Logger
+	config  DetectorConfig
+	logger  *logger.Logger
+	fetcher *urlFetcher
 
 	// Backend detectors
 	textDetector  TextDetector
 	imageDetector ImageDetector
 	audioDetector AudioDetector
 	videoDetector VideoDetector
+
+	// documentDetector scores extracted text with textDetector
+	documentDetector DocumentDetector
+
+	// hostReputation adjusts scores for URL inputs by host
+	hostReputation hostReputation
+
+	// configFingerprint is computed once; reloading replaces the whole
+	// detector rather than changing its configuration
+	configFingerprint string
+
+	metrics *detectorMetrics
+}
+
+// liveDetector is the Detector NewDetector returns. Each detection runs on
+// the detector current when it starts; Reload swaps in a new one.
+type liveDetector struct {
+	current atomic.Pointer[detector]
+	logger  *logger.Logger
 }
 
-// NewDetector creates a new Detector with the given configuration.
+// NewDetector creates a new Detector with the given configuration. Its
+// configuration can be replaced later with Reload (see Reloader).
 func NewDetector(config DetectorConfig, log *logger.Logger) (Detector, error) {
+	d, err := newDetector(config, log)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &liveDetector{logger: log}
+	l.current.Store(d)
+	return l, nil
+}
+
+// Detect runs detection with the current configuration.
+func (l *liveDetector) Detect(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
+	return l.current.Load().Detect(ctx, input)
+}
+
+// ConfigFingerprint returns the fingerprint of the current configuration.
+func (l *liveDetector) ConfigFingerprint() string {
+	return l.current.Load().ConfigFingerprint()
+}
+
+// Reload builds a detector from config and swaps it in. Detections already
+// running keep the detector they started with.
+func (l *liveDetector) Reload(config DetectorConfig) error {
+	d, err := newDetector(config, l.logger)
+	if err != nil {
+		return err
+	}
+	l.current.Store(d)
+	return nil
+}
+
+// newDetector creates a detector for one configuration.
+func newDetector(config DetectorConfig, log *logger.Logger) (*detector, error) {
+	strategy, err := ParseAggregationStrategy(string(config.Aggregation))
+	if err != nil {
+		return nil, err
+	}
+	config.Aggregation = strategy
+
+	config.Decision = config.Decision.withDefaults()
+	if err := config.Decision.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ValidateProviderURLs(config.ProviderURLs); err != nil {
+		return nil, fmt.Errorf("invalid provider URLs: %w", err)
+	}
+	if config.DisagreementThreshold < 0 || config.DisagreementThreshold > 0.5 {
+		return nil, fmt.Errorf("disagreement threshold %g must be between 0 and 0.5", config.DisagreementThreshold)
+	}
+	if config.LocalOnlyConfidenceCap < 0 || config.LocalOnlyConfidenceCap > 1 {
+		return nil, fmt.Errorf("local-only confidence cap %g must be between 0 and 1", config.LocalOnlyConfidenceCap)
+	}
+	if config.AnchorTimeout <= 0 {
+		config.AnchorTimeout = DefaultAnchorTimeout
+	}
+
 	d := &detector{
-		config: config,
-		logger: log,
+		config:  config,
+		logger:  log,
+		fetcher: newURLFetcherFromConfig(config),
+		metrics: newDetectorMetrics(config.Metrics),
 	}
 
 	// Initialize backend detectors based on available API keys
@@ -116,29 +525,90 @@ func NewDetector(config DetectorConfig, log *logger.Logger) (Detector, error) {
 	d.imageDetector = NewImageDetector(config, log)
 	d.audioDetector = NewAudioDetector(config, log)
 	d.videoDetector = NewVideoDetector(config, log)
+	d.documentDetector = NewDocumentDetector(config, log, d.textDetector)
+	d.hostReputation = newHostReputation(DefaultHostRules, config.HostRules)
+	d.configFingerprint = newEffectiveConfig(config).fingerprint()
 
 	return d, nil
 }
 
+// ConfigFingerprint returns the fingerprint Detect records on results.
+func (d *detector) ConfigFingerprint() string {
+	return d.configFingerprint
+}
+
 // Detect analyzes content and returns whether it was human-created.
+// If ctx is cancelled, Detect stops at the next stage and returns ctx's error.
 func (d *detector) Detect(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
+	ctx, span := tracing.Start(ctx, "humanmark.detect")
+	defer span.End()
+
+	result, err := d.detect(ctx, input)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(
+		tracing.String("content_type", string(result.ContentType)),
+		tracing.String("verdict", string(result.Verdict)),
+		tracing.Float64("ai_score", result.AIScore),
+	)
+	return result, nil
+}
+
+// detect is Detect without its span.
+func (d *detector) detect(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
 	start := time.Now()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Decode base64 payloads and data URIs into raw bytes
+	input, err := DecodeInlineData(input)
+	if err != nil {
+		return nil, err
+	}
+
 	// Determine content type if not specified
+	var mismatch string
 	if input.ContentType == ContentTypeUnknown || input.ContentType == "" {
-		input.ContentType = d.detectContentType(input)
+		input.ContentType, mismatch = d.detectContentType(input)
+	}
+	if mismatch != "" {
+		d.logger.Warn("content type mismatch",
+			"filename", input.Filename,
+			"url", input.URL,
+			"warning", mismatch,
+		)
+	}
+
+	// URLs without a recognizable extension need the response to tell us the type
+	if input.ContentType == ContentTypeUnknown && input.URL != "" && !input.HasData() {
+		input, err = d.fetchURLInput(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch content: %w", err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	d.logger.Debug("starting detection",
 		"content_type", input.ContentType,
 		"has_url", input.URL != "",
 		"text_length", len(input.Text),
-		"data_length", len(input.Data),
+		"data_length", input.DataSize(),
 	)
 
+	// Known up front so a panic in a parser can be traced to its input
+	contentHash := ContentHash(input)
+	logger.Annotate(ctx, "content_type", input.ContentType)
+	logger.Annotate(ctx, "content_hash", contentHash)
+
 	// Route to appropriate detector
 	var result *DetectionResult
-	var err error
 
 	switch input.ContentType {
 	case ContentTypeText:
@@ -149,20 +619,57 @@ func (d *detector) Detect(ctx context.Context, input DetectionInput) (*Detection
 		result, err = d.audioDetector.DetectAudio(ctx, input)
 	case ContentTypeVideo:
 		result, err = d.videoDetector.DetectVideo(ctx, input)
+	case ContentTypeDocument:
+		result, err = d.documentDetector.DetectDocument(ctx, input)
+	case ContentTypePlist:
+		result, err = d.detectPlist(ctx, input)
 	default:
-		return nil, errors.New("unsupported content type: " + string(input.ContentType))
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, input.ContentType)
 	}
+	d.metrics.detectDuration.Observe(time.Since(start).Seconds(), string(input.ContentType))
 
+	// The client may have gone away while detectors ran
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if errors.Is(err, ErrAllDetectorsFailed) {
+		d.logger.Error("all detectors failed", "content_hash", contentHash, "error", err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("detection failed: %w", err)
 	}
 
-	// Calculate content hash
-	result.ContentHash = d.hashContent(input)
+	// Only the submitted URL counts, never where it redirected to
+	if input.URL != "" {
+		if match, ok := d.hostReputation.match(input.URL); ok {
+			result.HostReputation = match
+			result.setScore(d.config, clampScore(result.AIScore+match.Adjustment))
+		}
+	}
+
+	// Record the content hash and, for text, a near-duplicate fingerprint
+	result.ContentHash = contentHash
+	if len(result.DetectorErrors) > 0 {
+		d.logger.Warn("detectors failed", "content_hash", result.ContentHash, "errors", result.DetectorErrors)
+	}
+	if input.Text != "" {
+		result.NormalizedHash = NormalizedTextHash(input.Text)
+		result.Fingerprint = minhash.Signature(input.Text)
+	}
 	result.ProcessingTime = time.Since(start)
+	result.AnalyzerVersion = AnalyzerVersion
+	result.ConfigFingerprint = d.configFingerprint
+	if mismatch != "" {
+		result.MismatchWarning = mismatch
+	}
+	d.metrics.recordVerdict(result)
+
+	if d.config.Anchor != nil {
+		d.anchorResult(ctx, result)
+	}
 
 	d.logger.Debug("detection complete",
-		"human", result.Human,
+		"verdict", result.Verdict,
 		"confidence", result.Confidence,
 		"ai_score", result.AIScore,
 		"processing_time_ms", result.ProcessingTime.Milliseconds(),
@@ -171,39 +678,225 @@ func (d *detector) Detect(ctx context.Context, input DetectionInput) (*Detection
 	return result, nil
 }
 
+// DefaultAnchorTimeout is how long Detect waits to anchor a result by
+// default. Anchoring takes several RPC round trips, which shouldn't add
+// more than a few seconds to a verification.
+const DefaultAnchorTimeout = 3 * time.Second
+
+// anchorResult publishes result's content hash, within AnchorTimeout. A
+// failure is logged and leaves the result unanchored.
+func (d *detector) anchorResult(ctx context.Context, result *DetectionResult) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.AnchorTimeout)
+	defer cancel()
+
+	anchoredAt := time.Now().UTC()
+	txID, err := d.config.Anchor.Anchor(ctx, result.ContentHash, anchoredAt)
+	if err != nil {
+		d.logger.Warn("anchoring failed", "content_hash", result.ContentHash, "error", err)
+		return
+	}
+
+	result.AnchorTxID = txID
+	result.AnchoredAt = anchoredAt
+}
+
+// decodeBase64 decodes standard or URL-safe base64, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	}
+	for _, enc := range encodings {
+		if data, err := enc.DecodeString(s); err == nil && len(data) > 0 {
+			return data, nil
+		}
+	}
+
+	return nil, ErrInvalidBase64
+}
+
+// DecodeInlineData decodes content sent inside the request into Data:
+// Base64Data, or a data: URI in URL. A data URI's MIME type sets
+// ContentType (sniffed from the bytes if the type isn't one we handle).
+// Detect calls this itself; callers that limit upload size call it first so
+// the limit applies to the decoded bytes.
+func DecodeInlineData(input DetectionInput) (DetectionInput, error) {
+	if input.HasData() {
+		return input, nil
+	}
+
+	if input.Base64Data != "" {
+		data, err := decodeBase64(input.Base64Data)
+		if err != nil {
+			return input, err
+		}
+		input.Data = data
+		input.Base64Data = ""
+		return input, nil
+	}
+
+	if IsDataURI(input.URL) {
+		data, mimeType, err := decodeDataURI(input.URL)
+		if err != nil {
+			return input, err
+		}
+		input.Data = data
+		input.URL = ""
+		input.ContentType = ContentTypeFromResponse(mimeType, data)
+		if input.MIMEType == "" {
+			input.MIMEType = mimeType
+		}
+	}
+	return input, nil
+}
+
+// IsDataURI reports whether rawURL is a data: URI.
+func IsDataURI(rawURL string) bool {
+	return len(rawURL) >= 5 && strings.EqualFold(rawURL[:5], "data:")
+}
+
+// decodeDataURI decodes a data: URI (RFC 2397) into its payload and MIME
+// type. The payload is base64 if marked ";base64" and percent-encoded
+// otherwise; the MIME type defaults to text/plain.
+func decodeDataURI(uri string) ([]byte, string, error) {
+	meta, payload, ok := strings.Cut(uri[len("data:"):], ",")
+	if !ok {
+		return nil, "", fmt.Errorf("%w: missing ',' before the data", ErrInvalidDataURI)
+	}
+
+	params := strings.Split(meta, ";")
+	mimeType := strings.ToLower(strings.TrimSpace(params[0]))
+	if mimeType == "" {
+		mimeType = "text/plain"
+	}
+	isBase64 := len(params) > 1 && strings.EqualFold(strings.TrimSpace(params[len(params)-1]), "base64")
+
+	// Base64 may be percent-encoded too
+	unescaped, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidDataURI, err)
+	}
+
+	data := []byte(unescaped)
+	if isBase64 {
+		if data, err = decodeBase64(unescaped); err != nil {
+			return nil, "", fmt.Errorf("%w: payload is not valid base64", ErrInvalidDataURI)
+		}
+	}
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("%w: no data", ErrInvalidDataURI)
+	}
+	return data, mimeType, nil
+}
+
 // detectContentType determines content type from input.
-func (d *detector) detectContentType(input DetectionInput) ContentType {
+// When there is data, its magic bytes win over the filename or URL
+// extension; if the two disagree, the returned warning describes how.
+func (d *detector) detectContentType(input DetectionInput) (ContentType, string) {
 	// If we have text, it's text
 	if input.Text != "" {
-		return ContentTypeText
+		return ContentTypeText, ""
 	}
 
-	// Check filename extension
+	// Check filename extension, then URL extension, then declared MIME type
+	hinted, source := ContentTypeUnknown, "extension"
 	if input.Filename != "" {
-		return ContentTypeFromFilename(input.Filename)
+		hinted = ContentTypeFromFilename(input.Filename)
+	} else if input.URL != "" {
+		hinted = ContentTypeFromURL(input.URL)
 	}
-
-	// Check URL extension
-	if input.URL != "" {
-		return ContentTypeFromURL(input.URL)
+	if hinted == ContentTypeUnknown && input.MIMEType != "" {
+		hinted, source = ContentTypeFromMIME(input.MIMEType), "MIME type"
 	}
 
 	// Check magic bytes if we have data
-	if len(input.Data) > 0 {
-		return ContentTypeFromMagicBytes(input.Data)
+	if !input.HasData() {
+		return hinted, ""
+	}
+	sniffed := ContentTypeFromMagicBytes(input.DataPrefix(sniffSize))
+
+	switch {
+	case sniffed == ContentTypeUnknown:
+		// Text and some formats (SVG) have no magic bytes
+		return hinted, ""
+	case hinted == ContentTypeUnknown, hinted == sniffed:
+		return sniffed, ""
+	}
+
+	return sniffed, fmt.Sprintf("%s suggests %s but content is %s", source, hinted, sniffed)
+}
+
+// maxURLFetchSize bounds downloads made before the content type is known.
+// It matches the largest per-type limit (video).
+const maxURLFetchSize = 500 * 1024 * 1024
+
+// fetchURLInput downloads a URL input and determines its type from the response.
+// The fetched bytes are attached to the input so detectors don't download twice.
+func (d *detector) fetchURLInput(ctx context.Context, input DetectionInput) (DetectionInput, error) {
+	result, err := d.fetcher.Fetch(ctx, input.URL, maxURLFetchSize)
+	if err != nil {
+		return input, err
+	}
+
+	input.Data = result.Data
+	input.ContentType = ContentTypeFromResponse(result.ContentType, result.Data)
+	if input.MIMEType == "" {
+		input.MIMEType = result.ContentType
+	}
+
+	d.logger.Debug("resolved url content type",
+		"declared_mime", result.ContentType,
+		"content_type", input.ContentType,
+		"data_length", len(result.Data),
+	)
+
+	return input, nil
+}
+
+// sniffSize is how much of the content its type is sniffed from: the same
+// window net/http uses.
+const sniffSize = 512
+
+// ContentTypeFromResponse determines content type from a fetched response.
+// The declared MIME type wins; otherwise the first 512 bytes are sniffed.
+func ContentTypeFromResponse(mimeType string, data []byte) ContentType {
+	if ct := ContentTypeFromMIME(mimeType); ct != ContentTypeUnknown {
+		return ct
+	}
+
+	sniff := data
+	if len(sniff) > sniffSize {
+		sniff = sniff[:sniffSize]
+	}
+
+	if ct := ContentTypeFromMagicBytes(sniff); ct != ContentTypeUnknown {
+		return ct
+	}
+
+	// Catches HTML and plain text served as application/octet-stream
+	if len(sniff) > 0 {
+		return ContentTypeFromMIME(http.DetectContentType(sniff))
 	}
 
 	return ContentTypeUnknown
 }
 
-// hashContent creates a SHA256 hash of the content.
-func (d *detector) hashContent(input DetectionInput) string {
+// ContentHash returns the SHA-256 hash Detect records as
+// DetectionResult.ContentHash: of the text, else the data, else the URL.
+// Inline data must already be decoded (see DecodeInlineData). A Reader is
+// streamed through the hash; one that fails part way hashes what could be
+// read, and the detectors reading it report the error.
+func ContentHash(input DetectionInput) string {
 	h := sha256.New()
 
 	if input.Text != "" {
 		h.Write([]byte(input.Text))
-	} else if len(input.Data) > 0 {
-		h.Write(input.Data)
+	} else if input.HasData() {
+		io.Copy(h, input.OpenData())
 	} else if input.URL != "" {
 		h.Write([]byte(input.URL))
 	}
@@ -211,6 +904,16 @@ func (d *detector) hashContent(input DetectionInput) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// NormalizedTextHash returns the SHA-256 hash of text's words joined by
+// single spaces, so copies that differ only in line breaks, indentation or
+// trailing whitespace hash the same. Clients can compute it without
+// sending the text: split on Unicode whitespace, join with " ", hash the
+// UTF-8 bytes and hex-encode.
+func NormalizedTextHash(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
 // ContentTypeFromFilename determines content type from filename extension.
 func ContentTypeFromFilename(filename string) ContentType {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -222,8 +925,12 @@ func ContentTypeFromFilename(filename string) ContentType {
 		return ContentTypeImage
 	case ".mp3", ".wav", ".flac", ".ogg", ".m4a", ".aac":
 		return ContentTypeAudio
-	case ".mp4", ".mov", ".avi", ".webm", ".mkv", ".wmv":
+	case ".mp4", ".mov", ".avi", ".webm", ".mkv", ".wmv", ".flv":
 		return ContentTypeVideo
+	case ".pdf", ".docx", ".odt", ".rtf":
+		return ContentTypeDocument
+	case ".plist":
+		return ContentTypePlist
 	default:
 		return ContentTypeUnknown
 	}
@@ -242,14 +949,11 @@ func ContentTypeFromURL(url string) ContentType {
 
 // ContentTypeFromMIME determines content type from MIME type.
 func ContentTypeFromMIME(mimeType string) ContentType {
-	// Normalize MIME type
-	mime := strings.ToLower(mimeType)
-	if idx := strings.Index(mime, ";"); idx != -1 {
-		mime = mime[:idx]
-	}
-	mime = strings.TrimSpace(mime)
+	mime := normalizeMIMEType(mimeType)
 
 	switch {
+	case mime == "text/rtf":
+		return ContentTypeDocument
 	case strings.HasPrefix(mime, "text/"):
 		return ContentTypeText
 	case strings.HasPrefix(mime, "image/"):
@@ -260,6 +964,11 @@ func ContentTypeFromMIME(mimeType string) ContentType {
 		return ContentTypeVideo
 	case mime == "application/json", mime == "application/xml":
 		return ContentTypeText
+	case mime == "application/pdf", mime == "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		mime == "application/vnd.oasis.opendocument.text", mime == "application/rtf":
+		return ContentTypeDocument
+	case mime == "application/x-plist":
+		return ContentTypePlist
 	default:
 		return ContentTypeUnknown
 	}
@@ -293,6 +1002,15 @@ func ContentTypeFromMagicBytes(data []byte) ContentType {
 		}
 	}
 
+	// BMP: 42 4D; "BM" alone is too common in text, so the DIB header
+	// size at offset 14 must be a known one too
+	if len(data) >= 18 && data[0] == 0x42 && data[1] == 0x4D {
+		switch binary.LittleEndian.Uint32(data[14:18]) {
+		case 12, 40, 52, 56, 64, 108, 124:
+			return ContentTypeImage
+		}
+	}
+
 	// MP3: FF FB, FF FA, FF F3, FF F2 or ID3
 	if (data[0] == 0xFF && (data[1]&0xE0) == 0xE0) || (data[0] == 0x49 && data[1] == 0x44 && data[2] == 0x33) {
 		return ContentTypeAudio
@@ -303,6 +1021,11 @@ func ContentTypeFromMagicBytes(data []byte) ContentType {
 		return ContentTypeVideo
 	}
 
+	// FLV: "FLV" + version 1
+	if data[0] == 'F' && data[1] == 'L' && data[2] == 'V' && data[3] == 0x01 {
+		return ContentTypeVideo
+	}
+
 	// WAV: 52 49 46 46 ... 57 41 56 45
 	if len(data) >= 12 && data[0] == 0x52 && data[1] == 0x49 && data[2] == 0x46 && data[3] == 0x46 {
 		if data[8] == 0x57 && data[9] == 0x41 && data[10] == 0x56 && data[11] == 0x45 {
@@ -310,6 +1033,30 @@ func ContentTypeFromMagicBytes(data []byte) ContentType {
 		}
 	}
 
+	// PDF: %PDF-
+	if len(data) >= 5 && bytes.HasPrefix(data, []byte("%PDF-")) {
+		return ContentTypeDocument
+	}
+
+	// Plist: bplist00, or XML whose root is <plist>
+	if isBinaryPlist(data) || isXMLPlist(data) {
+		return ContentTypePlist
+	}
+
+	// RTF: {\rtf
+	if isRTF(data) {
+		return ContentTypeDocument
+	}
+
+	// DOCX: ZIP (50 4B 03 04) whose first entries are Word parts. ODT:
+	// ZIP whose first entry is the uncompressed OpenDocument mimetype
+	if data[0] == 0x50 && data[1] == 0x4B && data[2] == 0x03 && data[3] == 0x04 {
+		if bytes.Contains(data, []byte("word/")) || bytes.Contains(data, []byte("[Content_Types].xml")) ||
+			bytes.Contains(data, []byte(odtMimeType)) {
+			return ContentTypeDocument
+		}
+	}
+
 	return ContentTypeUnknown
 }
 
@@ -332,3 +1079,8 @@ type AudioDetector interface {
 type VideoDetector interface {
 	DetectVideo(ctx context.Context, input DetectionInput) (*DetectionResult, error)
 }
+
+// DocumentDetector handles PDF, DOCX, ODT and RTF detection.
+type DocumentDetector interface {
+	DetectDocument(ctx context.Context, input DetectionInput) (*DetectionResult, error)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// =============================================================================
+// Detector Failures
+// =============================================================================
+//
+// One broken detector shouldn't cost the caller a result. Each analyzer and
+// provider call runs under runDetector, which turns a panic (say, a
+// malformed file slipping past a bounds check) into an ordinary error. The
+// failure is recorded in DetectionResult.DetectorErrors and aggregation
+// goes ahead with the detectors that did return a score. Only when none
+// did does detection fail, with ErrAllDetectorsFailed. If every failure
+// was a provider being unreachable or down, the error also wraps
+// ErrProviderUnavailable.
+//
+// =============================================================================
+
+// ErrAllDetectorsFailed is returned when no detector produced a score.
+var ErrAllDetectorsFailed = errors.New("all detectors failed")
+
+// detectorPanic is a recovered panic from a detector.
+type detectorPanic struct {
+	value any
+	stack []byte
+}
+
+func (p *detectorPanic) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+// runDetector calls fn, converting a panic into a *detectorPanic error.
+func runDetector(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &detectorPanic{value: r, stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// panicStack returns the stack of a recovered detector panic, or nil if
+// err isn't one.
+func panicStack(err error) []byte {
+	var p *detectorPanic
+	if errors.As(err, &p) {
+		return p.stack
+	}
+	return nil
+}
+
+// detectorFailures is the error returned when every detector failed. It
+// wraps ErrAllDetectorsFailed.
+type detectorFailures struct {
+	errors map[string]error
+}
+
+// allDetectorsFailed returns an error wrapping ErrAllDetectorsFailed that
+// carries the per-detector errors.
+func allDetectorsFailed(detectorErrors map[string]error) error {
+	return &detectorFailures{errors: detectorErrors}
+}
+
+func (f *detectorFailures) Error() string {
+	if len(f.errors) == 0 {
+		return ErrAllDetectorsFailed.Error()
+	}
+
+	names := make([]string, 0, len(f.errors))
+	for name := range f.errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + f.errors[name].Error()
+	}
+	return ErrAllDetectorsFailed.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (f *detectorFailures) Unwrap() []error {
+	if len(f.errors) == 0 {
+		return []error{ErrAllDetectorsFailed}
+	}
+	for _, err := range f.errors {
+		if !errors.Is(err, ErrProviderUnavailable) {
+			return []error{ErrAllDetectorsFailed}
+		}
+	}
+	return []error{ErrAllDetectorsFailed, ErrProviderUnavailable}
+}
+
+// recordDetectorError notes a failed detector in detectorErrors and logs
+// it. Panics are logged with their stack.
+func recordDetectorError(log *logger.Logger, detectorErrors map[string]error, name string, contentType ContentType, err error) {
+	detectorErrors[name] = err
+
+	if stack := panicStack(err); stack != nil {
+		log.Error("detector panicked", "detector", name, "content_type", contentType, "error", err, "stack", string(stack))
+		return
+	}
+	log.Warn("detector failed", "detector", name, "content_type", contentType, "error", err)
+}
+
+// errorMessages converts detector errors for DetectionResult.DetectorErrors.
+func errorMessages(detectorErrors map[string]error) map[string]string {
+	messages := make(map[string]string, len(detectorErrors))
+	for name, err := range detectorErrors {
+		messages[name] = err.Error()
+	}
+	return messages
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestRunDetector tests panic recovery.
+func TestRunDetector(t *testing.T) {
+	if err := runDetector(func() error { return nil }); err != nil {
+		t.Errorf("runDetector() error = %v, want nil", err)
+	}
+
+	plain := errors.New("rate limited")
+	if err := runDetector(func() error { return plain }); err != plain || panicStack(err) != nil {
+		t.Errorf("runDetector() error = %v, want the returned error unchanged", err)
+	}
+
+	err := runDetector(func() error {
+		var b []byte
+		_ = b[3]
+		return nil
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "panic: ") {
+		t.Fatalf("runDetector() error = %v, want a recovered panic", err)
+	}
+	if !strings.Contains(string(panicStack(err)), "TestRunDetector") {
+		t.Error("panicStack() is missing the panicking frame")
+	}
+}
+
+// TestAllDetectorsFailed tests the error message and its sentinel.
+func TestAllDetectorsFailed(t *testing.T) {
+	err := allDetectorsFailed(map[string]error{"winston": errors.New("timeout"), "humanmark": errors.New("panic: boom")})
+	if !errors.Is(err, ErrAllDetectorsFailed) {
+		t.Errorf("error = %v, want ErrAllDetectorsFailed", err)
+	}
+	if want := "all detectors failed: humanmark: panic: boom; winston: timeout"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+// TestAllProvidersUnavailable tests that detection failing only because
+// providers are down wraps ErrProviderUnavailable.
+func TestAllProvidersUnavailable(t *testing.T) {
+	down := cannedResponse(http.StatusServiceUnavailable, `{"error":"maintenance"}`)
+	defer down.Close()
+	d := newTextProviderTestDetector(DetectorConfig{WinstonAPIKey: "k", OriginalityAPIKey: "k"}, down.URL)
+
+	_, err := d.DetectText(context.Background(), DetectionInput{Text: longText, Detectors: []string{"winston", "originality"}})
+	if !errors.Is(err, ErrAllDetectorsFailed) || !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("error = %v, want ErrAllDetectorsFailed and ErrProviderUnavailable", err)
+	}
+
+	// A bad response isn't the provider being down
+	broken := cannedResponse(http.StatusOK, `not json`)
+	defer broken.Close()
+	d.winstonURL = broken.URL
+
+	_, err = d.DetectText(context.Background(), DetectionInput{Text: longText, Detectors: []string{"winston", "originality"}})
+	if !errors.Is(err, ErrAllDetectorsFailed) || errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("error = %v, want only ErrAllDetectorsFailed", err)
+	}
+}
+
+// panickingTextProvider swaps the named text provider for one that panics
+// and is always enabled, until the test ends.
+func panickingTextProvider(t *testing.T, name string) {
+	t.Helper()
+	saved := append([]textProvider(nil), textProviders...)
+	t.Cleanup(func() { textProviders = saved })
+
+	for i, p := range textProviders {
+		if p.name == name {
+			textProviders[i].enabled = func(DetectorConfig) bool { return true }
+			textProviders[i].detect = func(*textDetector, context.Context, string) (textProviderResult, error) {
+				panic("provider exploded")
+			}
+			return
+		}
+	}
+	t.Fatalf("no text provider named %s", name)
+}
+
+// TestDetectTextProviderPanic tests that a panicking provider is reported
+// and the remaining detectors still score.
+func TestDetectTextProviderPanic(t *testing.T) {
+	panickingTextProvider(t, "gptzero")
+	d := NewTextDetector(DetectorConfig{}, logger.NopLogger())
+
+	t.Run("partial result", func(t *testing.T) {
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+		if strings.Join(result.Detectors, ",") != "humanmark" {
+			t.Errorf("Detectors = %v, want [humanmark]", result.Detectors)
+		}
+		if got := result.DetectorErrors["gptzero"]; got != "panic: provider exploded" {
+			t.Errorf("DetectorErrors[gptzero] = %q, want the recovered panic", got)
+		}
+	})
+
+	t.Run("nothing left", func(t *testing.T) {
+		_, err := d.DetectText(context.Background(), DetectionInput{Text: longText, Detectors: []string{"gptzero"}})
+		if !errors.Is(err, ErrAllDetectorsFailed) {
+			t.Fatalf("error = %v, want ErrAllDetectorsFailed", err)
+		}
+		if !strings.Contains(err.Error(), "gptzero: panic: provider exploded") {
+			t.Errorf("error = %v, want the gptzero failure", err)
+		}
+	})
+
+	t.Run("document", func(t *testing.T) {
+		// Every text detector fails, but the metadata still scores
+		detector, err := NewDetector(DetectorConfig{}, logger.NopLogger())
+		if err != nil {
+			t.Fatalf("NewDetector() error = %v", err)
+		}
+		result, err := detector.Detect(context.Background(), DetectionInput{
+			Data:      readFixture(t, "essay.docx"),
+			Filename:  "essay.docx",
+			Detectors: []string{"gptzero", "metadata"},
+		})
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if strings.Join(result.Detectors, ",") != "metadata" {
+			t.Errorf("Detectors = %v, want [metadata]", result.Detectors)
+		}
+		if got := result.DetectorErrors["gptzero"]; got != "panic: provider exploded" {
+			t.Errorf("DetectorErrors[gptzero] = %q, want the recovered panic", got)
+		}
+	})
+}
+
+// TestDetectImageProviderPanic tests panic recovery in the image detector.
+func TestDetectImageProviderPanic(t *testing.T) {
+	saved := append([]imageProvider(nil), imageProviders...)
+	defer func() { imageProviders = saved }()
+	imageProviders[0].enabled = func(DetectorConfig) bool { return true }
+	imageProviders[0].detect = func(*imageDetector, context.Context, []byte) (float64, error) {
+		panic("provider exploded")
+	}
+	name := imageProviders[0].name
+
+	result, err := NewImageDetector(DetectorConfig{}, logger.NopLogger()).DetectImage(context.Background(), DetectionInput{Data: testPNG})
+	if err != nil {
+		t.Fatalf("DetectImage() error = %v", err)
+	}
+	if got := result.DetectorErrors[name]; got != "panic: provider exploded" {
+		t.Errorf("DetectorErrors[%s] = %q, want the recovered panic", name, got)
+	}
+}
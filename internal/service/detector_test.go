@@ -1,7 +1,17 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -47,6 +57,17 @@ func TestContentTypeFromFilename(t *testing.T) {
 		{"stream.webm", ContentTypeVideo},
 		{"film.mkv", ContentTypeVideo},
 		{"windows.wmv", ContentTypeVideo},
+		{"legacy.flv", ContentTypeVideo},
+
+		// Documents
+		{"essay.pdf", ContentTypeDocument},
+		{"essay.docx", ContentTypeDocument},
+		{"essay.odt", ContentTypeDocument},
+		{"essay.rtf", ContentTypeDocument},
+		{"Essay.PDF", ContentTypeDocument},
+
+		// Message exports
+		{"chat.plist", ContentTypePlist},
 
 		// Unknown
 		{"file.unknown", ContentTypeUnknown},
@@ -77,7 +98,7 @@ func TestContentTypeFromURL(t *testing.T) {
 	}{
 		// Simple URLs
 		{"https://example.com/image.jpg", ContentTypeImage},
-		{"https://example.com/document.pdf", ContentTypeUnknown},
+		{"https://example.com/document.pdf", ContentTypeDocument},
 		{"https://example.com/video.mp4", ContentTypeVideo},
 
 		// URLs with query strings
@@ -134,13 +155,21 @@ func TestContentTypeFromMIME(t *testing.T) {
 		{"video/webm", ContentTypeVideo},
 		{"video/quicktime", ContentTypeVideo},
 
+		// Document types
+		{"application/pdf", ContentTypeDocument},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", ContentTypeDocument},
+		{"application/vnd.oasis.opendocument.text", ContentTypeDocument},
+		{"application/rtf", ContentTypeDocument},
+		{"text/rtf", ContentTypeDocument},
+		{"application/x-plist", ContentTypePlist},
+
 		// With charset
 		{"text/plain; charset=utf-8", ContentTypeText},
 		{"application/json; charset=utf-8", ContentTypeText},
 
 		// Unknown
 		{"application/octet-stream", ContentTypeUnknown},
-		{"application/pdf", ContentTypeUnknown},
+		{"application/zip", ContentTypeUnknown},
 		{"", ContentTypeUnknown},
 
 		// Case variations
@@ -185,6 +214,16 @@ func TestContentTypeFromMagicBytes(t *testing.T) {
 			data:     []byte{0x52, 0x49, 0x46, 0x46, 0x00, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50},
 			expected: ContentTypeImage,
 		},
+		{
+			name:     "BMP",
+			data:     bmpFile(640, 480, 24),
+			expected: ContentTypeImage,
+		},
+		{
+			name:     "text starting with BM",
+			data:     []byte("BMW announced a new model today."),
+			expected: ContentTypeUnknown,
+		},
 		{
 			name:     "MP3 with sync",
 			data:     []byte{0xFF, 0xFB, 0x90, 0x00},
@@ -205,6 +244,51 @@ func TestContentTypeFromMagicBytes(t *testing.T) {
 			data:     []byte{0x00, 0x00, 0x00, 0x20, 0x66, 0x74, 0x79, 0x70, 0x69, 0x73, 0x6F, 0x6D},
 			expected: ContentTypeVideo,
 		},
+		{
+			name:     "PDF",
+			data:     []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"),
+			expected: ContentTypeDocument,
+		},
+		{
+			name:     "DOCX",
+			data:     append([]byte{0x50, 0x4B, 0x03, 0x04, 0x14, 0x00}, "[Content_Types].xml"...),
+			expected: ContentTypeDocument,
+		},
+		{
+			name:     "ODT",
+			data:     append([]byte{0x50, 0x4B, 0x03, 0x04, 0x14, 0x00}, "mimetypeapplication/vnd.oasis.opendocument.text"...),
+			expected: ContentTypeDocument,
+		},
+		{
+			name:     "RTF",
+			data:     []byte(`{\rtf1\ansi\deff0 {\fonttbl {\f0 Times;}}`),
+			expected: ContentTypeDocument,
+		},
+		{
+			name:     "FLV",
+			data:     []byte{'F', 'L', 'V', 0x01, 0x05, 0x00, 0x00, 0x00, 0x09},
+			expected: ContentTypeVideo,
+		},
+		{
+			name:     "binary plist",
+			data:     []byte("bplist00\xd1\x01\x02"),
+			expected: ContentTypePlist,
+		},
+		{
+			name:     "XML plist",
+			data:     []byte(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<plist version="1.0"><dict/></plist>`),
+			expected: ContentTypePlist,
+		},
+		{
+			name:     "other XML",
+			data:     []byte(`<?xml version="1.0"?><rss version="2.0"></rss>`),
+			expected: ContentTypeUnknown,
+		},
+		{
+			name:     "other ZIP",
+			data:     append([]byte{0x50, 0x4B, 0x03, 0x04, 0x14, 0x00}, "photos/beach.jpg"...),
+			expected: ContentTypeUnknown,
+		},
 		{
 			name:     "too short",
 			data:     []byte{0xFF, 0xD8},
@@ -347,6 +431,24 @@ func TestDetectorDetect(t *testing.T) {
 		}
 	})
 
+	t.Run("fingerprints text only", func(t *testing.T) {
+		text, err := detector.Detect(ctx, DetectionInput{Text: "A short piece of text to fingerprint."})
+		if err != nil {
+			t.Fatalf("Detect failed: %v", err)
+		}
+		if len(text.Fingerprint) == 0 {
+			t.Error("expected a fingerprint for text")
+		}
+
+		image, err := detector.Detect(ctx, DetectionInput{Data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10}})
+		if err != nil {
+			t.Fatalf("Detect failed: %v", err)
+		}
+		if image.Fingerprint != nil {
+			t.Error("expected no fingerprint for media")
+		}
+	})
+
 	t.Run("returns error for empty input", func(t *testing.T) {
 		input := DetectionInput{}
 
@@ -387,6 +489,456 @@ func TestDetectorDetect(t *testing.T) {
 			t.Error("expected different hashes for different content")
 		}
 	})
+
+	t.Run("hash and fingerprint are known before detection", func(t *testing.T) {
+		input := DetectionInput{
+			Text:        "Content hashed before it is analyzed.",
+			ContentType: ContentTypeText,
+		}
+
+		result, err := detector.Detect(ctx, input)
+		if err != nil {
+			t.Fatalf("Detect failed: %v", err)
+		}
+		if hash := ContentHash(input); hash != result.ContentHash {
+			t.Errorf("ContentHash = %s, Detect recorded %s", hash, result.ContentHash)
+		}
+		if fp := detector.(ConfigFingerprinter).ConfigFingerprint(); fp != result.ConfigFingerprint {
+			t.Errorf("ConfigFingerprint = %s, Detect recorded %s", fp, result.ConfigFingerprint)
+		}
+	})
+
+	t.Run("normalized hash ignores whitespace", func(t *testing.T) {
+		result, err := detector.Detect(ctx, DetectionInput{
+			Text:        "  Content that was\n\treflowed  by an editor.\r\n",
+			ContentType: ContentTypeText,
+		})
+		if err != nil {
+			t.Fatalf("Detect failed: %v", err)
+		}
+		want := NormalizedTextHash("Content that was reflowed by an editor.")
+		if result.NormalizedHash != want {
+			t.Errorf("NormalizedHash = %s, want %s", result.NormalizedHash, want)
+		}
+		if result.NormalizedHash == result.ContentHash {
+			t.Error("expected the normalized hash to differ from the exact hash")
+		}
+		// The documented recipe: SHA-256 of the words joined by spaces
+		if want != ContentHash(DetectionInput{Text: "Content that was reflowed by an editor."}) {
+			t.Errorf("NormalizedTextHash of normalized text = %s, want its SHA-256", want)
+		}
+	})
+}
+
+// TestDetectContentTypeMismatch tests files whose extension disagrees with
+// their magic bytes.
+func TestDetectContentTypeMismatch(t *testing.T) {
+	detector, _ := NewDetector(DetectorConfig{Timeout: 30 * time.Second}, logger.NopLogger())
+	ctx := context.Background()
+
+	jpeg := append([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00}, make([]byte, 1024)...)
+	wav := append([]byte("RIFF\x24\x08\x00\x00WAVEfmt "), make([]byte, 2048)...)
+	mp4 := append([]byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm', 0, 0, 0, 0, 'i', 's', 'o', 'm', 'a', 'v', 'c', '1'}, make([]byte, 2048)...)
+	pdf := readFixture(t, "essay.pdf")
+
+	tests := []struct {
+		name        string
+		input       DetectionInput
+		want        ContentType
+		wantWarning string
+	}{
+		{"mp4 named .jpg", DetectionInput{Data: mp4, Filename: "photo.jpg"}, ContentTypeVideo, "extension suggests image but content is video"},
+		{"jpeg named .mp4", DetectionInput{Data: jpeg, Filename: "clip.mp4"}, ContentTypeImage, "extension suggests video but content is image"},
+		{"wav named .png", DetectionInput{Data: wav, Filename: "cover.png"}, ContentTypeAudio, "extension suggests image but content is audio"},
+		{"pdf named .mp3", DetectionInput{Data: pdf, Filename: "song.mp3"}, ContentTypeDocument, "extension suggests audio but content is document"},
+		{"mp4 from .jpg URL", DetectionInput{Data: mp4, URL: "https://example.com/photo.jpg"}, ContentTypeVideo, "extension suggests image but content is video"},
+		{"mp4 sent as image/jpeg", DetectionInput{Data: mp4, MIMEType: "image/jpeg"}, ContentTypeVideo, "MIME type suggests image but content is video"},
+		{"matching extension", DetectionInput{Data: mp4, Filename: "clip.mov"}, ContentTypeVideo, ""},
+		{"jpeg named .png", DetectionInput{Data: jpeg, Filename: "photo.png"}, ContentTypeImage, "declared as png but content is jpeg"},
+		{"wav sent as audio/mpeg", DetectionInput{Data: wav, Filename: "memo.wav", MIMEType: "audio/mpeg"}, ContentTypeAudio, "declared as mp3 but content is wav"},
+		{"mp4 sent as video/webm", DetectionInput{Data: mp4, MIMEType: "video/webm", ContentType: ContentTypeVideo}, ContentTypeVideo, "declared as webm but content is mp4"},
+		{"jpeg in a PNG data URI", DetectionInput{URL: "data:image/png;base64," + base64.StdEncoding.EncodeToString(jpeg)}, ContentTypeImage, "declared as png but content is jpeg"},
+		{"matching MIME type", DetectionInput{Data: jpeg, Filename: "photo.jpeg", MIMEType: "image/jpeg; charset=binary"}, ContentTypeImage, ""},
+		{"unknown extension", DetectionInput{Data: jpeg, Filename: "upload.bin"}, ContentTypeImage, ""},
+		{"no magic bytes", DetectionInput{Data: []byte("plain words"), Filename: "notes.txt"}, ContentTypeText, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := detector.Detect(ctx, tt.input)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if result.ContentType != tt.want {
+				t.Errorf("ContentType = %s, want %s", result.ContentType, tt.want)
+			}
+			if result.MismatchWarning != tt.wantWarning {
+				t.Errorf("MismatchWarning = %q, want %q", result.MismatchWarning, tt.wantWarning)
+			}
+		})
+	}
+
+	t.Run("explicit content type is kept", func(t *testing.T) {
+		result, err := detector.Detect(ctx, DetectionInput{Data: mp4, Filename: "photo.jpg", ContentType: ContentTypeImage})
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if result.ContentType != ContentTypeImage || result.MismatchWarning != "" {
+			t.Errorf("got %s with warning %q, want image without warning", result.ContentType, result.MismatchWarning)
+		}
+	})
+}
+
+// TestDetectCancelled tests that a cancelled context stops detection.
+func TestDetectCancelled(t *testing.T) {
+	log := logger.NopLogger()
+
+	t.Run("cancelled before detection", func(t *testing.T) {
+		detector, _ := NewDetector(DetectorConfig{Timeout: 30 * time.Second}, log)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		inputs := []DetectionInput{
+			{Text: "This text would be analyzed if anyone were still waiting.", ContentType: ContentTypeText},
+			{Data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10}, ContentType: ContentTypeImage},
+		}
+		for _, input := range inputs {
+			if _, err := detector.Detect(ctx, input); !errors.Is(err, context.Canceled) {
+				t.Errorf("%s: error = %v, want context.Canceled", input.ContentType, err)
+			}
+		}
+	})
+
+	t.Run("cancelled during a provider call", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cancel()
+			w.Write([]byte(`{"score":50}`))
+		}))
+		defer server.Close()
+
+		d, _ := NewDetector(DetectorConfig{Timeout: 30 * time.Second, WinstonAPIKey: "k"}, log)
+		d.(*liveDetector).current.Load().textDetector.(*textDetector).winstonURL = server.URL
+
+		result, err := d.Detect(ctx, DetectionInput{Text: longText, ContentType: ContentTypeText})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+		if result != nil {
+			t.Errorf("expected no result, got %+v", result)
+		}
+	})
+
+	t.Run("text analyzer stops between signals", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := NewTextAnalyzer().AnalyzeContext(ctx, longText); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+// TestDetectorReload tests that a reload applies to detections that start
+// after it, while one already running keeps the configuration it started
+// with.
+func TestDetectorReload(t *testing.T) {
+	log := logger.NopLogger()
+	ctx := context.Background()
+
+	// The phrase dictionary is re-read from disk for each reload, as the
+	// server does with AI_PHRASES_FILE
+	path := filepath.Join(t.TempDir(), "phrases.json")
+	loadPhrases := func(pattern string) []AIPhrase {
+		t.Helper()
+		data := fmt.Sprintf(`[{"pattern": %q, "weight": 1.0, "category": "jargon"}]`, pattern)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		phrases, err := LoadAIPhrases(path)
+		if err != nil {
+			t.Fatalf("LoadAIPhrases() error = %v", err)
+		}
+		return phrases
+	}
+	found := func(result *DetectionResult) string {
+		if result == nil || len(result.AIPhrases) != 1 {
+			return fmt.Sprintf("%+v", result)
+		}
+		return result.AIPhrases[0].Phrase
+	}
+	text := "Let's circle back on the quarterly synergy with some blue sky thinking. " + longText
+
+	// The first detection waits in a provider call until released
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Write([]byte(`{"score":50}`))
+	}))
+	defer server.Close()
+
+	config := DetectorConfig{Timeout: 30 * time.Second, WinstonAPIKey: "k", AIPhrases: loadPhrases("quarterly synergy")}
+	d, err := NewDetector(config, log)
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	d.(*liveDetector).current.Load().textDetector.(*textDetector).winstonURL = server.URL
+	before := d.(ConfigFingerprinter).ConfigFingerprint()
+
+	inFlight := make(chan *DetectionResult)
+	go func() {
+		result, err := d.Detect(ctx, DetectionInput{Text: text, ContentType: ContentTypeText})
+		if err != nil {
+			t.Errorf("in-flight Detect() error = %v", err)
+		}
+		inFlight <- result
+	}()
+	<-started
+
+	config.AIPhrases = loadPhrases("blue sky thinking")
+	if err := d.(Reloader).Reload(config); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	after := d.(ConfigFingerprinter).ConfigFingerprint()
+	if after == before {
+		t.Error("expected a new fingerprint after the phrase dictionary changed")
+	}
+
+	result, err := d.Detect(ctx, DetectionInput{Text: text, ContentType: ContentTypeText, Detectors: []string{"humanmark"}})
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if got := found(result); got != "blue sky thinking" {
+		t.Errorf("new detection found %s, want the reloaded phrase", got)
+	}
+	if result.ConfigFingerprint != after {
+		t.Errorf("new detection's fingerprint = %s, want %s", result.ConfigFingerprint, after)
+	}
+
+	close(release)
+	old := <-inFlight
+	if got := found(old); got != "quarterly synergy" {
+		t.Errorf("in-flight detection found %s, want the phrase it started with", got)
+	}
+	if old != nil && old.ConfigFingerprint != before {
+		t.Errorf("in-flight detection's fingerprint = %s, want %s", old.ConfigFingerprint, before)
+	}
+
+	t.Run("invalid config keeps the current one", func(t *testing.T) {
+		bad := config
+		bad.Decision.UncertaintyBand = 0.9
+		if err := d.(Reloader).Reload(bad); err == nil {
+			t.Error("expected an error for an invalid config")
+		}
+		if got := d.(ConfigFingerprinter).ConfigFingerprint(); got != after {
+			t.Errorf("fingerprint = %s, want %s", got, after)
+		}
+	})
+}
+
+// TestDetectURLContentType tests type resolution for extensionless URLs.
+func TestDetectURLContentType(t *testing.T) {
+	pngData := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 1000)...)
+	wavData := append([]byte("RIFF\x00\x00\x00\x00WAVEfmt "), make([]byte, 1000)...)
+	htmlData := []byte("<!DOCTYPE html><html><body><p>This page was written by a person, honestly.</p></body></html>")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/asset", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngData)
+	})
+	mux.HandleFunc("/sound", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(wavData)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(htmlData)
+	})
+	mux.HandleFunc("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(htmlData)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	config := DetectorConfig{
+		Timeout:              5 * time.Second,
+		AllowPrivateNetworks: true, // httptest listens on loopback
+	}
+	detector, _ := NewDetector(config, logger.NopLogger())
+
+	tests := []struct {
+		path     string
+		expected ContentType
+	}{
+		{"/asset?id=123", ContentTypeImage}, // from Content-Type header
+		{"/sound", ContentTypeAudio},        // from magic bytes
+		{"/page", ContentTypeText},          // from Content-Type header
+		{"/untyped", ContentTypeText},       // from content sniffing
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := detector.Detect(context.Background(), DetectionInput{URL: server.URL + tt.path})
+			if err != nil {
+				t.Fatalf("Detect failed: %v", err)
+			}
+			if result.ContentType != tt.expected {
+				t.Errorf("ContentType = %s, want %s", result.ContentType, tt.expected)
+			}
+		})
+	}
+}
+
+// TestDecodeInlineData tests decoding base64 payloads and data URIs.
+func TestDecodeInlineData(t *testing.T) {
+	pngData := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 100)...)
+	mp3Data := mp3Frame(9, false, nil)
+
+	tests := []struct {
+		name     string
+		input    DetectionInput
+		wantData []byte
+		wantType ContentType
+	}{
+		{"base64 payload", DetectionInput{Base64Data: base64.StdEncoding.EncodeToString(pngData)}, pngData, ""},
+		{"PNG data URI", DetectionInput{URL: "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData)}, pngData, ContentTypeImage},
+		{"MP3 data URI", DetectionInput{URL: "data:audio/mpeg;base64," + base64.StdEncoding.EncodeToString(mp3Data)}, mp3Data, ContentTypeAudio},
+		{"percent-encoded base64", DetectionInput{URL: "DATA:image/png;BASE64," + url.PathEscape(base64.StdEncoding.EncodeToString(pngData))}, pngData, ContentTypeImage},
+		{"percent-encoded text", DetectionInput{URL: "data:,Hello%2C%20world"}, []byte("Hello, world"), ContentTypeText},
+		{"unhandled type is sniffed", DetectionInput{URL: "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(pngData)}, pngData, ContentTypeImage},
+		{"data wins", DetectionInput{Data: []byte("raw"), URL: "data:,ignored"}, []byte("raw"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeInlineData(tt.input)
+			if err != nil {
+				t.Fatalf("DecodeInlineData() error = %v", err)
+			}
+			if !bytes.Equal(got.Data, tt.wantData) {
+				t.Errorf("Data = %q, want %q", got.Data, tt.wantData)
+			}
+			if got.ContentType != tt.wantType {
+				t.Errorf("ContentType = %q, want %q", got.ContentType, tt.wantType)
+			}
+			if got.Base64Data != "" || (tt.input.Data == nil && got.URL != "") {
+				t.Errorf("inline content left in input: URL = %q, Base64Data = %q", got.URL, got.Base64Data)
+			}
+		})
+	}
+
+	for _, uri := range []string{"data:image/png;base64", "data:image/png;base64,not*base64", "data:image/png,", "data:,%zz"} {
+		if _, err := DecodeInlineData(DetectionInput{URL: uri}); !errors.Is(err, ErrInvalidDataURI) {
+			t.Errorf("DecodeInlineData(%q) error = %v, want ErrInvalidDataURI", uri, err)
+		}
+	}
+	if _, err := DecodeInlineData(DetectionInput{Base64Data: "not*base64"}); !errors.Is(err, ErrInvalidBase64) {
+		t.Errorf("error = %v, want ErrInvalidBase64", err)
+	}
+}
+
+// TestDetectDataURI tests detecting content sent as a data URI.
+func TestDetectDataURI(t *testing.T) {
+	detector, _ := NewDetector(DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+
+	pngData := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 1000)...)
+	mp3Data := bytes.Repeat(mp3Frame(9, false, nil), 10)
+
+	tests := []struct {
+		uri      string
+		expected ContentType
+	}{
+		{"data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData), ContentTypeImage},
+		{"data:audio/mpeg;base64," + base64.StdEncoding.EncodeToString(mp3Data), ContentTypeAudio},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.expected), func(t *testing.T) {
+			result, err := detector.Detect(context.Background(), DetectionInput{URL: tt.uri})
+			if err != nil {
+				t.Fatalf("Detect failed: %v", err)
+			}
+			if result.ContentType != tt.expected {
+				t.Errorf("ContentType = %s, want %s", result.ContentType, tt.expected)
+			}
+		})
+	}
+
+	_, err := detector.Detect(context.Background(), DetectionInput{URL: "data:image/png;base64,%%%"})
+	if !errors.Is(err, ErrInvalidDataURI) {
+		t.Errorf("error = %v, want ErrInvalidDataURI", err)
+	}
+}
+
+// TestDetectErrors tests the typed errors Detect returns for bad input.
+func TestDetectErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/missing.txt", http.NotFound)
+	mux.HandleFunc("/huge.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2*1024*1024)) // Over the 1MB text limit
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	detector, _ := NewDetector(DetectorConfig{Timeout: 5 * time.Second, AllowPrivateNetworks: true}, logger.NopLogger())
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		_, err := detector.Detect(context.Background(), DetectionInput{Data: []byte{0x00, 0xde, 0xad, 0xbe, 0xef, 0x00}})
+		if !errors.Is(err, ErrUnsupportedContentType) {
+			t.Errorf("error = %v, want ErrUnsupportedContentType", err)
+		}
+	})
+
+	t.Run("fetch failed", func(t *testing.T) {
+		_, err := detector.Detect(context.Background(), DetectionInput{URL: server.URL + "/missing.txt"})
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) || !errors.Is(err, ErrFetchFailed) {
+			t.Fatalf("error = %v, want a FetchError", err)
+		}
+		if fetchErr.Status != http.StatusNotFound {
+			t.Errorf("Status = %d, want 404", fetchErr.Status)
+		}
+	})
+
+	t.Run("content too large", func(t *testing.T) {
+		_, err := detector.Detect(context.Background(), DetectionInput{URL: server.URL + "/huge.txt"})
+		if !errors.Is(err, ErrContentTooLarge) || !errors.Is(err, ErrFetchFailed) {
+			t.Errorf("error = %v, want ErrContentTooLarge from the fetch", err)
+		}
+	})
+}
+
+// TestContentTypeFromResponse tests MIME-then-sniff resolution.
+func TestContentTypeFromResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		mime     string
+		data     []byte
+		expected ContentType
+	}{
+		{"declared image", "image/jpeg", nil, ContentTypeImage},
+		{"declared wins over bytes", "video/mp4", []byte{0xFF, 0xD8, 0xFF, 0xE0}, ContentTypeVideo},
+		{"jpeg magic bytes", "application/octet-stream", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}, ContentTypeImage},
+		{"sniffed html", "", []byte("<html><body>hi</body></html>"), ContentTypeText},
+		{"binary junk", "application/octet-stream", []byte{0x00, 0x01, 0x02, 0x03, 0x04}, ContentTypeUnknown},
+		{"empty", "", nil, ContentTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ContentTypeFromResponse(tt.mime, tt.data)
+			if result != tt.expected {
+				t.Errorf("ContentTypeFromResponse(%q) = %s, want %s", tt.mime, result, tt.expected)
+			}
+		})
+	}
 }
 
 // TestDetectionInput tests DetectionInput struct.
@@ -479,27 +1031,111 @@ func BenchmarkDetect(b *testing.B) {
 
 // BenchmarkContentHash benchmarks content hashing.
 func BenchmarkContentHash(b *testing.B) {
-	log := logger.NopLogger()
-	config := DetectorConfig{Timeout: 30 * time.Second}
-	detector, _ := NewDetector(config, log)
-
-	// Create detector to access hashContent
-	d := detector.(*detector)
-
 	input := DetectionInput{
 		Text: "Content to hash for benchmarking purposes.",
 	}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		d.hashContent(input)
+		ContentHash(input)
+	}
+}
+
+// concurrentInputs is one input of each media type, for exercising the
+// shared analyzers.
+func concurrentInputs() []DetectionInput {
+	return []DetectionInput{
+		{Text: "Let's circle back on the quarterly synergy. " + longText, ContentType: ContentTypeText},
+		{Text: longText, ContentType: ContentTypeText, EmailMode: true},
+		{Data: testPNG, Filename: "photo.png"},
+		{Data: bytes.Repeat(mp3Frame(9, false, nil), 10), Filename: "song.mp3"},
+		{Data: mp4Atom("ftyp", []byte("isom\x00\x00\x02\x00isomavc1")), Filename: "clip.mp4"},
 	}
 }
 
-// min returns the minimum of two integers.
-func min(a, b int) int {
-	if a < b {
-		return a
+// TestDetectConcurrent runs detections of every type at once, through a
+// reload, so that go test -race catches per-call state in the analyzers
+// each detector now shares.
+func TestDetectConcurrent(t *testing.T) {
+	config := DetectorConfig{Timeout: 30 * time.Second}
+	d, err := NewDetector(config, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	inputs := concurrentInputs()
+
+	// Each input's result when run alone
+	want := make([]float64, len(inputs))
+	for i, input := range inputs {
+		result, err := d.Detect(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Detect(%d) error = %v", i, err)
+		}
+		want[i] = result.AIScore
+	}
+
+	first := d.(*liveDetector).current.Load().textDetector.(*textDetector).analyzer
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				for i, input := range inputs {
+					result, err := d.Detect(context.Background(), input)
+					if err != nil {
+						t.Errorf("Detect(%d) error = %v", i, err)
+						return
+					}
+					if result.AIScore != want[i] {
+						t.Errorf("Detect(%d) AIScore = %v, want %v", i, result.AIScore, want[i])
+					}
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 5; n++ {
+			if err := d.(Reloader).Reload(config); err != nil {
+				t.Errorf("Reload() error = %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	if d.(*liveDetector).current.Load().textDetector.(*textDetector).analyzer == first {
+		t.Error("Reload should build new analyzers")
+	}
+}
+
+// BenchmarkDetectAllocs reports allocations per detection of each media
+// type, with the analyzers built once per detector.
+func BenchmarkDetectAllocs(b *testing.B) {
+	d, err := NewDetector(DetectorConfig{Timeout: 30 * time.Second}, logger.NopLogger())
+	if err != nil {
+		b.Fatalf("NewDetector() error = %v", err)
+	}
+	ctx := context.Background()
+
+	for _, input := range concurrentInputs() {
+		name := string(input.ContentType)
+		if input.Filename != "" {
+			name = input.Filename
+		}
+		if input.EmailMode {
+			name = "email"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := d.Detect(ctx, input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
 	}
-	return b
 }
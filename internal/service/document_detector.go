@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// =============================================================================
+// Document Detector
+// =============================================================================
+//
+// Documents are scored on their text: the extracted text goes through the
+// text detector (our analyzer plus any configured text providers). The
+// document's metadata is blended in as one more, lower-weight score when
+// it says something:
+//
+//   - A producing application that is an AI writing tool
+//   - A long DOCX, ODT or RTF edited for under a minute, i.e. pasted in
+//     at once
+//   - A DOCX, ODT or RTF with a long editing history
+//
+// Most documents say nothing either way (a Word export tells us nothing
+// about who wrote the words), and then only the text decides.
+//
+// =============================================================================
+
+// documentMetadataWeight is the reliability weight of the metadata signal.
+// Metadata is easy to lose or rewrite, so it counts for half a detector.
+const documentMetadataWeight = 0.5
+
+// documentDetectorWeights are the text weights plus the metadata signal.
+var documentDetectorWeights = func() map[string]float64 {
+	weights := map[string]float64{"metadata": documentMetadataWeight}
+	for name, w := range textDetectorWeights {
+		weights[name] = w
+	}
+	return weights
+}()
+
+// Metadata signal scores
+const (
+	metadataScoreAITool      = 0.9
+	metadataScorePasted      = 0.7
+	metadataScoreLongEditing = 0.3
+)
+
+// pastedMinWords is the length at which a document written in under a
+// minute is treated as pasted rather than a short note.
+const pastedMinWords = 300
+
+// longEditingMinutes is editing time that suggests the author drafted the
+// document themselves.
+const longEditingMinutes = 60
+
+// documentDetector implements DocumentDetector on top of a TextDetector.
+type documentDetector struct {
+	config       DetectorConfig
+	logger       *logger.Logger
+	fetcher      *urlFetcher
+	metrics      *detectorMetrics
+	textDetector TextDetector
+}
+
+// NewDocumentDetector creates a document detector that scores extracted
+// text with textDetector.
+func NewDocumentDetector(config DetectorConfig, log *logger.Logger, textDetector TextDetector) DocumentDetector {
+	return &documentDetector{
+		config:       config,
+		logger:       log.Component("document"),
+		fetcher:      newURLFetcherFromConfig(config),
+		metrics:      newDetectorMetrics(config.Metrics),
+		textDetector: textDetector,
+	}
+}
+
+// DetectDocument extracts a document's text and metadata and scores them.
+// Documents with no extractable text return an error wrapping
+// ErrDocumentExtraction.
+func (d *documentDetector) DetectDocument(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
+	available := textDetectorsAvailable(d.config)
+	available["metadata"] = true
+
+	selected, err := selectDetectors(input, ContentTypeDocument, available)
+	if err != nil {
+		return nil, err
+	}
+
+	// The text detectors run on the extracted text
+	var textDetectors []string
+	for name := range selected {
+		if name != "metadata" {
+			textDetectors = append(textDetectors, name)
+		}
+	}
+	sort.Strings(textDetectors)
+
+	var docData []byte
+
+	// Get document data
+	if input.HasData() {
+		docData, err = input.ReadData(maxDocumentSize)
+		if err != nil {
+			return nil, err
+		}
+	} else if input.URL != "" {
+		result, err := d.fetcher.Fetch(ctx, input.URL, maxDocumentSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch document: %w", err)
+		}
+		docData = result.Data
+	} else {
+		return nil, errors.New("no document data provided")
+	}
+
+	var doc *Document
+	err = d.metrics.runAnalyzer(ctx, ContentTypeDocument, func(ctx context.Context) (err error) {
+		doc, err = ExtractDocument(docData)
+		return err
+	})
+	if stack := panicStack(err); stack != nil {
+		d.logger.Error("document extraction panicked", "error", err, "stack", string(stack))
+		return nil, fmt.Errorf("%w: %v", ErrDocumentExtraction, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.logger.Debug("document extracted",
+		"format", doc.Format,
+		"text_length", len(doc.Text),
+		"truncated", doc.Truncated,
+		"producer", doc.Metadata.Producer,
+		"creator", doc.Metadata.Creator,
+		"page_count", doc.Metadata.PageCount,
+	)
+
+	var scores []float64
+	var detectors []string
+	detectorErrors := make(map[string]error)
+	textResult := &DetectionResult{}
+	if len(textDetectors) > 0 {
+		result, err := d.textDetector.DetectText(ctx, DetectionInput{
+			Text:              doc.Text,
+			ContentType:       ContentTypeText,
+			CustomStopPhrases: input.CustomStopPhrases,
+			CustomCommonWords: input.CustomCommonWords,
+			Detectors:         textDetectors,
+		})
+
+		// The metadata may still score when every text detector failed
+		var failures *detectorFailures
+		switch {
+		case errors.As(err, &failures):
+			for name, err := range failures.errors {
+				detectorErrors[name] = err
+			}
+		case err != nil:
+			return nil, err
+		default:
+			textResult = result
+			for name, msg := range result.DetectorErrors {
+				detectorErrors[name] = errors.New(msg)
+			}
+		}
+	}
+	for _, p := range textResult.ProviderScores {
+		scores = append(scores, p.AIScore)
+		detectors = append(detectors, p.Name)
+	}
+
+	if selected["metadata"] {
+		var score float64
+		var ok bool
+		err := runDetector(func() error {
+			score, ok = documentMetadataScore(doc)
+			return nil
+		})
+		if err != nil {
+			recordDetectorError(d.logger, detectorErrors, "metadata", ContentTypeDocument, err)
+		} else if ok {
+			scores = append(scores, score)
+			detectors = append(detectors, "metadata")
+		}
+	}
+
+	if len(scores) == 0 {
+		return nil, allDetectorsFailed(detectorErrors)
+	}
+
+	// Combine scores using the configured strategy
+	result := buildResult(d.config, ContentTypeDocument, scores, detectors, documentDetectorWeights, EvidenceNone)
+	result.DetectorOutputs = textResult.DetectorOutputs
+	result.SkippedProviders = textResult.SkippedProviders
+	if len(detectorErrors) > 0 {
+		result.DetectorErrors = errorMessages(detectorErrors)
+	}
+
+	return result, nil
+}
+
+// documentMetadataScore scores a document's metadata. ok is false when the
+// metadata doesn't point either way.
+func documentMetadataScore(doc *Document) (score float64, ok bool) {
+	meta := doc.Metadata
+
+	if containsAIDocumentMarker(meta.Producer) || containsAIDocumentMarker(meta.Creator) {
+		return metadataScoreAITool, true
+	}
+
+	// PDF dates are export times, so only word processor editing
+	// history is useful
+	if doc.Format == DocumentFormatPDF {
+		return 0, false
+	}
+
+	words := len(strings.Fields(doc.Text))
+
+	switch {
+	case meta.HasEditingTime && meta.EditingMinutes >= longEditingMinutes:
+		return metadataScoreLongEditing, true
+	case meta.HasEditingTime && meta.EditingMinutes <= 1 && words >= pastedMinWords:
+		return metadataScorePasted, true
+	case !meta.HasEditingTime && !meta.CreatedAt.IsZero() && !meta.ModifiedAt.IsZero() &&
+		meta.ModifiedAt.Sub(meta.CreatedAt) < 2*time.Minute && words >= pastedMinWords:
+		return metadataScorePasted, true
+	}
+
+	return 0, false
+}
+
+// containsAIDocumentMarker checks for AI writing tools in a producer or
+// creator string.
+func containsAIDocumentMarker(s string) bool {
+	markers := []string{
+		"chatgpt", "openai", "claude", "anthropic", "gemini", "bard",
+		"copilot", "jasper", "copy.ai", "writesonic", "rytr",
+		"quillbot", "notion ai", "sudowrite", "ai generated", "ai-generated",
+	}
+
+	lower := strings.ToLower(s)
+	for _, marker := range markers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1286 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// =============================================================================
+// Document Text Extraction
+// =============================================================================
+//
+// Essays arrive as PDF, DOCX, ODT and RTF files. We pull out the text so it can go
+// through the text detector, and read the document metadata (producing
+// application, creation and modification dates, editing time) as an extra
+// signal.
+//
+// PDF: objects are scanned in file order rather than through the xref table,
+// which also copes with damaged or incrementally updated files. Content
+// streams (uncompressed or FlateDecode) are tokenized and the text-showing
+// operators (Tj, TJ, ', ") are collected. Text drawn with embedded font
+// encodings comes out as garbage; when that's all there is, extraction fails
+// rather than scoring noise. Scanned PDFs have no text at all.
+//
+// DOCX: word/document.xml holds the body text in <w:t> runs;
+// docProps/core.xml and docProps/app.xml hold the metadata. Text deleted
+// or moved away under tracked changes is left out.
+//
+// ODT: content.xml holds the body text in <text:p> and <text:h>
+// paragraphs; meta.xml holds the metadata. Tracked changes, comments and
+// notes are left out.
+//
+// RTF: control words and groups are stripped, skipping destinations that
+// aren't body text (font tables, document info, pictures, headers,
+// footnotes, comments). The \info group's dates and editing time are read
+// as metadata.
+//
+// Most formats don't record a page count we can trust, so PageCount is
+// estimated from the number of paragraphs unless the document says.
+//
+// Everything is size-limited so a crafted file (e.g. a zip bomb) can't
+// exhaust memory.
+//
+// =============================================================================
+
+// Document size limits
+const (
+	// maxDocumentSize is the largest document accepted for extraction
+	maxDocumentSize = 50 * 1024 * 1024
+
+	// maxDocumentDecompressed caps the total bytes inflated from
+	// PDF streams or DOCX parts
+	maxDocumentDecompressed = 64 * 1024 * 1024
+
+	// maxDocumentText caps extracted text; longer text is truncated
+	maxDocumentText = 1024 * 1024
+)
+
+// paragraphsPerPage is the paragraph count of a typical page of prose,
+// used to estimate Document.Metadata.PageCount.
+const paragraphsPerPage = 5
+
+// ErrDocumentExtraction is returned when no text can be extracted from a document.
+var ErrDocumentExtraction = errors.New("document text extraction failed")
+
+// Document formats
+const (
+	DocumentFormatPDF  = "pdf"
+	DocumentFormatDOCX = "docx"
+	DocumentFormatODT  = "odt"
+	DocumentFormatRTF  = "rtf"
+)
+
+// Document is text and metadata extracted from a PDF, DOCX, ODT or RTF file.
+type Document struct {
+	// Format is "pdf", "docx", "odt" or "rtf"
+	Format string
+
+	// Text is the extracted body text
+	Text string
+
+	// Truncated is true if Text was cut at maxDocumentText
+	Truncated bool
+
+	// Metadata describes how the document was produced
+	Metadata DocumentMetadata
+}
+
+// DocumentMetadata is the producing application and timestamps of a document.
+type DocumentMetadata struct {
+	// Producer is the PDF Producer, or the DOCX AppVersion
+	Producer string
+
+	// Creator is the PDF Creator, the DOCX Application, or the ODT or
+	// RTF generator
+	Creator string
+
+	// CreatedAt and ModifiedAt are zero when not recorded
+	CreatedAt  time.Time
+	ModifiedAt time.Time
+
+	// EditingMinutes is the DOCX TotalTime, ODT editing-duration or RTF
+	// \edmins, valid if HasEditingTime
+	EditingMinutes int
+	HasEditingTime bool
+
+	// PageCount is the recorded page count, or an estimate from the
+	// number of paragraphs when the document doesn't record one
+	PageCount int
+}
+
+// ExtractDocument extracts text and metadata from a PDF, DOCX, ODT or RTF
+// file. Errors wrap ErrDocumentExtraction.
+func ExtractDocument(data []byte) (*Document, error) {
+	if len(data) > maxDocumentSize {
+		return nil, fmt.Errorf("%w: document is %d bytes, limit is %d", ErrDocumentExtraction, len(data), maxDocumentSize)
+	}
+
+	var doc *Document
+	var err error
+
+	switch {
+	case isPDF(data):
+		doc, err = extractPDF(data)
+	case isZIP(data):
+		doc, err = extractZIPDocument(data)
+	case isRTF(data):
+		doc = extractRTF(data)
+	default:
+		return nil, fmt.Errorf("%w: not a PDF, DOCX, ODT or RTF file", ErrDocumentExtraction)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDocumentExtraction, err)
+	}
+
+	doc.Text = normalizeDocumentText(doc.Text)
+	if !isReadableText(doc.Text) {
+		return nil, fmt.Errorf("%w: %s contains no readable text (it may be scanned or use embedded font encodings)", ErrDocumentExtraction, doc.Format)
+	}
+
+	if len(doc.Text) > maxDocumentText {
+		cut := maxDocumentText
+		for cut > 0 && !utf8.RuneStart(doc.Text[cut]) {
+			cut--
+		}
+		doc.Text = doc.Text[:cut]
+		doc.Truncated = true
+	}
+
+	if doc.Metadata.PageCount <= 0 {
+		doc.Metadata.PageCount = estimatePageCount(doc.Text)
+	}
+
+	return doc, nil
+}
+
+// isPDF reports whether data starts with a PDF header. The spec allows
+// junk before it, so the first 1KB is searched.
+func isPDF(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("%PDF-"))
+}
+
+// isZIP reports whether data starts with a ZIP local file header.
+func isZIP(data []byte) bool {
+	return len(data) >= 4 && data[0] == 'P' && data[1] == 'K' && data[2] == 0x03 && data[3] == 0x04
+}
+
+// isRTF reports whether data starts with an RTF header.
+func isRTF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(`{\rtf`))
+}
+
+// estimatePageCount estimates the pages of normalized text from its
+// paragraphs. Every document has at least one page.
+func estimatePageCount(text string) int {
+	paragraphs := strings.Count(text, "\n\n") + 1
+	return (paragraphs + paragraphsPerPage - 1) / paragraphsPerPage
+}
+
+// normalizeDocumentText collapses runs of spaces and blank lines.
+func normalizeDocumentText(text string) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// isReadableText reports whether text looks like words rather than
+// font-encoded glyph IDs: it needs some letters and a majority of
+// letters and spaces.
+func isReadableText(text string) bool {
+	var letters, total int
+	for _, r := range text {
+		total++
+		if unicode.IsLetter(r) || unicode.IsSpace(r) {
+			letters++
+		}
+	}
+	return letters >= 10 && float64(letters)/float64(total) > 0.6
+}
+
+// =============================================================================
+// PDF
+// =============================================================================
+
+var (
+	pdfObjectPattern = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfInfoPattern   = regexp.MustCompile(`/Info\s+(\d+)\s+\d+\s+R`)
+	pdfLengthPattern = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	pdfFirstPattern  = regexp.MustCompile(`/First\s+(\d+)`)
+	pdfPagePattern   = regexp.MustCompile(`/Type\s*/Page\b`)
+
+	xmpCreatorToolPattern = regexp.MustCompile(`<xmp:CreatorTool>([^<]*)</xmp:CreatorTool>`)
+	xmpProducerPattern    = regexp.MustCompile(`<pdf:Producer>([^<]*)</pdf:Producer>`)
+)
+
+// pdfStream is a stream object's dictionary and raw data.
+type pdfStream struct {
+	dict []byte
+	data []byte
+}
+
+// extractPDF extracts text from content streams and metadata from the
+// Info dictionary, falling back to XMP metadata.
+func extractPDF(data []byte) (*Document, error) {
+	objects, streams := scanPDFObjects(data)
+
+	budget := maxDocumentDecompressed
+	var text strings.Builder
+
+	for _, s := range streams {
+		decoded, ok := decodePDFStream(s, &budget)
+		if !ok {
+			continue
+		}
+
+		// Compressed object streams can hold the Info dictionary
+		if bytes.Contains(s.dict, []byte("/ObjStm")) {
+			parsePDFObjectStream(s.dict, decoded, objects)
+			continue
+		}
+
+		if isPDFContentStream(decoded) {
+			extractPDFContentText(decoded, &text)
+			text.WriteString("\n")
+		}
+	}
+
+	doc := &Document{Format: DocumentFormatPDF, Text: text.String()}
+
+	// Objects are keyed by number, so pages rewritten by incremental
+	// updates count once
+	for _, obj := range objects {
+		if pdfPagePattern.Match(obj) {
+			doc.Metadata.PageCount++
+		}
+	}
+
+	// Later trailers (incremental updates) win
+	if m := pdfInfoPattern.FindAllSubmatch(data, -1); len(m) > 0 {
+		num, _ := strconv.Atoi(string(m[len(m)-1][1]))
+		if info, ok := objects[num]; ok {
+			doc.Metadata.Producer = pdfDictString(info, "/Producer")
+			doc.Metadata.Creator = pdfDictString(info, "/Creator")
+			doc.Metadata.CreatedAt = parsePDFDate(pdfDictString(info, "/CreationDate"))
+			doc.Metadata.ModifiedAt = parsePDFDate(pdfDictString(info, "/ModDate"))
+		}
+	}
+
+	if doc.Metadata.Producer == "" {
+		if m := xmpProducerPattern.FindSubmatch(data); m != nil {
+			doc.Metadata.Producer = strings.TrimSpace(string(m[1]))
+		}
+	}
+	if doc.Metadata.Creator == "" {
+		if m := xmpCreatorToolPattern.FindSubmatch(data); m != nil {
+			doc.Metadata.Creator = strings.TrimSpace(string(m[1]))
+		}
+	}
+
+	return doc, nil
+}
+
+// scanPDFObjects walks "N G obj ... endobj" in file order. It returns the
+// dictionaries of non-stream objects by number, and the stream objects.
+func scanPDFObjects(data []byte) (map[int][]byte, []pdfStream) {
+	objects := make(map[int][]byte)
+	var streams []pdfStream
+
+	pos := 0
+	for pos < len(data) {
+		loc := pdfObjectPattern.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[1]
+
+		end := bytes.Index(data[start:], []byte("endobj"))
+		if end < 0 {
+			end = len(data) - start
+		}
+		body := data[start : start+end]
+
+		// A stream's dictionary is followed by the stream keyword
+		streamAt := bytes.Index(body, []byte("stream"))
+		if streamAt < 0 || !bytes.HasSuffix(bytes.TrimSpace(body[:streamAt]), []byte(">>")) {
+			objects[num] = body
+			pos = start + end
+			continue
+		}
+
+		dict := body[:streamAt]
+		dataStart := start + streamAt + len("stream")
+		if dataStart < len(data) && data[dataStart] == '\r' {
+			dataStart++
+		}
+		if dataStart < len(data) && data[dataStart] == '\n' {
+			dataStart++
+		}
+
+		// Trust a direct /Length when endstream follows it; the stream
+		// data can contain "endobj", so searching isn't always safe
+		dataEnd := -1
+		if m := pdfLengthPattern.FindSubmatch(dict); m != nil && len(m[2]) == 0 {
+			if n, err := strconv.Atoi(string(m[1])); err == nil && dataStart+n <= len(data) {
+				rest := bytes.TrimLeft(data[dataStart+n:], "\r\n \t")
+				if bytes.HasPrefix(rest, []byte("endstream")) {
+					dataEnd = dataStart + n
+				}
+			}
+		}
+		if dataEnd < 0 {
+			i := bytes.Index(data[dataStart:], []byte("endstream"))
+			if i < 0 {
+				break
+			}
+			dataEnd = dataStart + i
+		}
+
+		streams = append(streams, pdfStream{dict: dict, data: data[dataStart:dataEnd]})
+		pos = dataEnd
+	}
+
+	return objects, streams
+}
+
+// decodePDFStream returns a stream's decoded bytes. Only unfiltered and
+// FlateDecode streams are supported; images and fonts are skipped. A
+// truncated or corrupt Flate stream yields whatever inflated cleanly.
+func decodePDFStream(s pdfStream, budget *int) ([]byte, bool) {
+	dict := s.dict
+	if bytes.Contains(dict, []byte("/Image")) || bytes.Contains(dict, []byte("/Length1")) || bytes.Contains(dict, []byte("/FontFile")) {
+		return nil, false
+	}
+
+	if !bytes.Contains(dict, []byte("/Filter")) {
+		return s.data, true
+	}
+
+	// Exactly one filter, and it must be Flate
+	if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Count(dict, []byte("Decode")) > 1 {
+		return nil, false
+	}
+	if *budget <= 0 {
+		return nil, false
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(s.data))
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+
+	decoded, _ := io.ReadAll(io.LimitReader(zr, int64(*budget)))
+	*budget -= len(decoded)
+	return decoded, len(decoded) > 0
+}
+
+// parsePDFObjectStream adds the objects packed in an /ObjStm to objects.
+func parsePDFObjectStream(dict, decoded []byte, objects map[int][]byte) {
+	m := pdfFirstPattern.FindSubmatch(dict)
+	if m == nil {
+		return
+	}
+	first, err := strconv.Atoi(string(m[1]))
+	if err != nil || first > len(decoded) {
+		return
+	}
+
+	// Header is pairs of "objnum offset"
+	fields := strings.Fields(string(decoded[:first]))
+	for i := 0; i+1 < len(fields); i += 2 {
+		num, err1 := strconv.Atoi(fields[i])
+		off, err2 := strconv.Atoi(fields[i+1])
+		if err1 != nil || err2 != nil || first+off > len(decoded) {
+			return
+		}
+		end := len(decoded)
+		if i+3 < len(fields) {
+			if next, err := strconv.Atoi(fields[i+3]); err == nil && first+next <= end && next >= off {
+				end = first + next
+			}
+		}
+		objects[num] = decoded[first+off : end]
+	}
+}
+
+// isPDFContentStream reports whether decoded looks like a page content
+// stream with text in it.
+func isPDFContentStream(decoded []byte) bool {
+	return bytes.Contains(decoded, []byte("BT")) && bytes.Contains(decoded, []byte("ET")) &&
+		(bytes.Contains(decoded, []byte("Tj")) || bytes.Contains(decoded, []byte("TJ")))
+}
+
+// pdfOperand is one operand in a content stream.
+type pdfOperand struct {
+	str   string
+	isStr bool
+	num   float64
+	isNum bool
+	arr   []pdfOperand
+}
+
+// extractPDFContentText writes the text shown by a content stream's
+// text operators to out.
+func extractPDFContentText(content []byte, out *strings.Builder) {
+	var operands []pdfOperand
+	var array []pdfOperand
+	inArray := false
+	lastY := 0.0
+
+	push := func(op pdfOperand) {
+		if inArray {
+			array = append(array, op)
+		} else {
+			operands = append(operands, op)
+		}
+	}
+	lastString := func() (string, bool) {
+		for i := len(operands) - 1; i >= 0; i-- {
+			if operands[i].isStr {
+				return operands[i].str, true
+			}
+		}
+		return "", false
+	}
+
+	i := 0
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case isPDFWhitespace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := readPDFLiteral(content[i:])
+			push(pdfOperand{str: s, isStr: true})
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return
+			}
+			push(pdfOperand{str: decodePDFHex(content[i+1 : i+end]), isStr: true})
+			i += end + 1
+		case c == '[':
+			inArray, array = true, nil
+			i++
+		case c == ']':
+			inArray = false
+			operands = append(operands, pdfOperand{arr: array})
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFWhitespace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			push(pdfOperand{})
+		default:
+			start := i
+			for i < len(content) && !isPDFWhitespace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			token := string(content[start:i])
+			if n, err := strconv.ParseFloat(token, 64); err == nil {
+				push(pdfOperand{num: n, isNum: true})
+				continue
+			}
+
+			switch token {
+			case "Tj":
+				if s, ok := lastString(); ok {
+					out.WriteString(s)
+				}
+			case "'", "\"":
+				out.WriteString("\n")
+				if s, ok := lastString(); ok {
+					out.WriteString(s)
+				}
+			case "TJ":
+				if len(operands) > 0 {
+					for _, op := range operands[len(operands)-1].arr {
+						switch {
+						case op.isStr:
+							out.WriteString(op.str)
+						case op.isNum && op.num < -250:
+							// Large negative kerning is a word gap
+							out.WriteString(" ")
+						}
+					}
+				}
+			case "Td", "TD":
+				if len(operands) >= 2 && operands[len(operands)-1].isNum && operands[len(operands)-1].num != 0 {
+					out.WriteString("\n")
+				} else {
+					out.WriteString(" ")
+				}
+			case "Tm":
+				if len(operands) >= 6 && operands[len(operands)-1].isNum {
+					y := operands[len(operands)-1].num
+					if y != lastY {
+						out.WriteString("\n")
+					} else {
+						out.WriteString(" ")
+					}
+					lastY = y
+				}
+			case "T*", "ET":
+				out.WriteString("\n")
+			case "ID":
+				// Inline image data runs to EI
+				end := bytes.Index(content[i:], []byte("EI"))
+				if end < 0 {
+					return
+				}
+				i += end + 2
+			}
+			operands = operands[:0]
+		}
+	}
+}
+
+// readPDFLiteral reads a (literal string) starting at data[0] == '('.
+// It returns the decoded string and the number of bytes consumed.
+func readPDFLiteral(data []byte) (string, int) {
+	var buf []byte
+	depth := 0
+	i := 0
+	for i < len(data) {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for j := 0; j < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; j++ {
+						v = v*8 + int(data[i]-'0')
+						i++
+					}
+					buf = append(buf, byte(v))
+					continue
+				}
+				buf = append(buf, e)
+			}
+		case c == '(':
+			if depth > 0 {
+				buf = append(buf, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodePDFText(buf), i + 1
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+		i++
+	}
+	return decodePDFText(buf), i
+}
+
+// decodePDFHex decodes a <hex string>. An odd final digit is padded with 0.
+func decodePDFHex(h []byte) string {
+	digits := bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, h)
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, err := hex.DecodeString(string(digits))
+	if err != nil {
+		return ""
+	}
+	return decodePDFText(b)
+}
+
+// winAnsiPunctuation maps the WinAnsiEncoding punctuation in 0x80-0x9F,
+// which Word and most exporters use for quotes and dashes.
+var winAnsiPunctuation = map[byte]rune{
+	0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—',
+}
+
+// decodePDFText converts PDF string bytes to UTF-8. Strings with a UTF-16BE
+// byte order mark are decoded as such; everything else is treated as
+// WinAnsi/PDFDocEncoding (Latin-1 with the common punctuation mapped).
+func decodePDFText(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	var sb strings.Builder
+	for _, c := range b {
+		if r, ok := winAnsiPunctuation[c]; ok {
+			sb.WriteRune(r)
+		} else if c >= 0x20 || c == '\n' || c == '\t' {
+			sb.WriteRune(rune(c))
+		}
+	}
+	return sb.String()
+}
+
+// pdfDictString returns a string value from a PDF dictionary, or "".
+func pdfDictString(dict []byte, key string) string {
+	i := bytes.Index(dict, []byte(key))
+	if i < 0 {
+		return ""
+	}
+	rest := bytes.TrimLeft(dict[i+len(key):], " \t\r\n")
+	if len(rest) == 0 {
+		return ""
+	}
+
+	switch rest[0] {
+	case '(':
+		s, _ := readPDFLiteral(rest)
+		return strings.TrimSpace(s)
+	case '<':
+		end := bytes.IndexByte(rest, '>')
+		if end < 0 {
+			return ""
+		}
+		return strings.TrimSpace(decodePDFHex(rest[1:end]))
+	}
+	return ""
+}
+
+// parsePDFDate parses a PDF date string such as "D:20240115103000+01'00'".
+// Missing trailing fields default as the spec says; invalid dates are zero.
+func parsePDFDate(s string) time.Time {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	if len(s) < 4 {
+		return time.Time{}
+	}
+
+	// Pad to YYYYMMDDHHmmSS
+	digits := s
+	tz := ""
+	if i := strings.IndexAny(s, "Z+-"); i >= 0 {
+		digits, tz = s[:i], s[i:]
+	}
+	const defaults = "00000101000000"
+	if len(digits) > len(defaults) {
+		return time.Time{}
+	}
+	digits += defaults[len(digits):]
+
+	loc := time.UTC
+	if tz != "" && tz[0] != 'Z' {
+		offset := strings.NewReplacer("'", "").Replace(tz[1:])
+		if len(offset) >= 2 {
+			h, _ := strconv.Atoi(offset[:2])
+			m := 0
+			if len(offset) >= 4 {
+				m, _ = strconv.Atoi(offset[2:4])
+			}
+			secs := h*3600 + m*60
+			if tz[0] == '-' {
+				secs = -secs
+			}
+			loc = time.FixedZone("", secs)
+		}
+	}
+
+	t, err := time.ParseInLocation("20060102150405", digits, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// isPDFWhitespace reports whether c is PDF whitespace.
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether c is a PDF delimiter character.
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// =============================================================================
+// DOCX
+// =============================================================================
+
+// odtMimeType is the mimetype entry of an OpenDocument text file.
+const odtMimeType = "application/vnd.oasis.opendocument.text"
+
+// extractZIPDocument extracts a DOCX or ODT file, told apart by the part
+// that holds the body text.
+func extractZIPDocument(data []byte) (*Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %v", err)
+	}
+
+	budget := int64(maxDocumentDecompressed)
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml", "docProps/core.xml", "docProps/app.xml":
+		case "mimetype", "content.xml", "meta.xml":
+		default:
+			continue
+		}
+		if f.UncompressedSize64 > uint64(budget) {
+			return nil, fmt.Errorf("%s is too large (%d bytes)", f.Name, f.UncompressedSize64)
+		}
+		part, err := readZipFile(f, budget)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %v", f.Name, err)
+		}
+		budget -= int64(len(part))
+		parts[f.Name] = part
+	}
+
+	if body, ok := parts["word/document.xml"]; ok {
+		return extractDOCX(body, parts)
+	}
+
+	// Spreadsheets and presentations have a content.xml too
+	_, hasContent := parts["content.xml"]
+	mimetype, hasMimetype := parts["mimetype"]
+	if hasContent && (!hasMimetype || strings.TrimSpace(string(mimetype)) == odtMimeType) {
+		return extractODT(parts["content.xml"], parts)
+	}
+
+	return nil, errors.New("zip archive is not a Word or OpenDocument text document (no word/document.xml or content.xml)")
+}
+
+// extractDOCX extracts body text from word/document.xml and metadata
+// from docProps/core.xml and docProps/app.xml.
+func extractDOCX(body []byte, parts map[string][]byte) (*Document, error) {
+	text, err := extractDOCXText(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse word/document.xml: %v", err)
+	}
+
+	doc := &Document{Format: DocumentFormatDOCX, Text: text}
+
+	// Metadata parts are optional; a bad one doesn't lose the text
+	if core, ok := parts["docProps/core.xml"]; ok {
+		var props struct {
+			Created  string `xml:"created"`
+			Modified string `xml:"modified"`
+		}
+		if xml.Unmarshal(core, &props) == nil {
+			doc.Metadata.CreatedAt, _ = time.Parse(time.RFC3339, strings.TrimSpace(props.Created))
+			doc.Metadata.ModifiedAt, _ = time.Parse(time.RFC3339, strings.TrimSpace(props.Modified))
+		}
+	}
+	if app, ok := parts["docProps/app.xml"]; ok {
+		var props struct {
+			Application string `xml:"Application"`
+			AppVersion  string `xml:"AppVersion"`
+			TotalTime   string `xml:"TotalTime"`
+			Pages       string `xml:"Pages"`
+		}
+		if xml.Unmarshal(app, &props) == nil {
+			doc.Metadata.Creator = strings.TrimSpace(props.Application)
+			doc.Metadata.Producer = strings.TrimSpace(props.AppVersion)
+			if minutes, err := strconv.Atoi(strings.TrimSpace(props.TotalTime)); err == nil {
+				doc.Metadata.EditingMinutes = minutes
+				doc.Metadata.HasEditingTime = true
+			}
+			doc.Metadata.PageCount, _ = strconv.Atoi(strings.TrimSpace(props.Pages))
+		}
+	}
+
+	return doc, nil
+}
+
+// readZipFile reads a zip entry, failing if it inflates past limit.
+// The declared size can't be trusted on its own.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errors.New("decompressed size exceeds limit")
+	}
+	return data, nil
+}
+
+// extractDOCXText collects <w:t> runs, turning paragraphs, breaks and
+// tabs into whitespace. Deleted text from tracked changes (<w:delText>,
+// and anything inside <w:del> or <w:moveFrom>) is left out.
+func extractDOCXText(body []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+
+	var sb strings.Builder
+	inText := false
+	deleted := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Keep what parsed before the error
+			if sb.Len() > 0 {
+				break
+			}
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "del", "moveFrom":
+				deleted++
+			case "t":
+				inText = true
+			case "tab":
+				if deleted == 0 {
+					sb.WriteString("\t")
+				}
+			case "br", "cr":
+				if deleted == 0 {
+					sb.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "del", "moveFrom":
+				deleted--
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n\n")
+			}
+		case xml.CharData:
+			if inText && deleted == 0 {
+				sb.Write(t)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// =============================================================================
+// ODT
+// =============================================================================
+
+// odtSkipElements hold text that isn't part of the body: tracked changes
+// (deleted paragraphs live here), comments and footnotes.
+var odtSkipElements = map[string]bool{
+	"tracked-changes": true,
+	"annotation":      true,
+	"note":            true,
+}
+
+// odtDurationPattern matches an ISO 8601 duration such as "P0DT1H23M45S".
+var odtDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:[\d.]+S)?)?$`)
+
+// extractODT extracts body text from content.xml and metadata from
+// meta.xml.
+func extractODT(content []byte, parts map[string][]byte) (*Document, error) {
+	text, err := extractODTText(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse content.xml: %v", err)
+	}
+
+	doc := &Document{Format: DocumentFormatODT, Text: text}
+
+	// meta.xml is optional; a bad one doesn't lose the text
+	if meta, ok := parts["meta.xml"]; ok {
+		var props struct {
+			Meta struct {
+				Generator       string `xml:"generator"`
+				CreationDate    string `xml:"creation-date"`
+				Date            string `xml:"date"`
+				EditingDuration string `xml:"editing-duration"`
+				Statistic       struct {
+					PageCount string `xml:"page-count,attr"`
+				} `xml:"document-statistic"`
+			} `xml:"meta"`
+		}
+		if xml.Unmarshal(meta, &props) == nil {
+			m := props.Meta
+			doc.Metadata.Creator = strings.TrimSpace(m.Generator)
+			doc.Metadata.CreatedAt = parseODTDate(m.CreationDate)
+			doc.Metadata.ModifiedAt = parseODTDate(m.Date)
+			doc.Metadata.EditingMinutes, doc.Metadata.HasEditingTime = parseODTDuration(m.EditingDuration)
+			doc.Metadata.PageCount, _ = strconv.Atoi(strings.TrimSpace(m.Statistic.PageCount))
+		}
+	}
+
+	return doc, nil
+}
+
+// extractODTText collects the text of <text:p> and <text:h> paragraphs,
+// expanding <text:s>, <text:tab> and <text:line-break> into whitespace.
+func extractODTText(content []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+
+	var sb strings.Builder
+	paragraph := 0
+	skip := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Keep what parsed before the error
+			if sb.Len() > 0 {
+				break
+			}
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if odtSkipElements[t.Name.Local] {
+				skip++
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			switch t.Name.Local {
+			case "p", "h":
+				paragraph++
+			case "s":
+				spaces := 1
+				for _, a := range t.Attr {
+					if a.Name.Local == "c" {
+						if n, err := strconv.Atoi(a.Value); err == nil && n > 0 {
+							spaces = min(n, 100)
+						}
+					}
+				}
+				sb.WriteString(strings.Repeat(" ", spaces))
+			case "tab":
+				sb.WriteString("\t")
+			case "line-break":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			if odtSkipElements[t.Name.Local] {
+				skip--
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			switch t.Name.Local {
+			case "p", "h":
+				paragraph--
+				sb.WriteString("\n\n")
+			}
+		case xml.CharData:
+			if paragraph > 0 && skip == 0 {
+				sb.Write(t)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// parseODTDate parses a meta.xml date, which usually has no time zone.
+// Invalid dates are zero.
+func parseODTDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseODTDuration parses a meta.xml editing-duration into whole minutes.
+func parseODTDuration(s string) (int, bool) {
+	m := odtDurationPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || len(m[0]) <= len("PT") {
+		return 0, false
+	}
+	days, _ := strconv.Atoi(m[1])
+	hours, _ := strconv.Atoi(m[2])
+	minutes, _ := strconv.Atoi(m[3])
+	return days*24*60 + hours*60 + minutes, true
+}
+
+// =============================================================================
+// RTF
+// =============================================================================
+
+// rtfSkipDestinations are RTF groups that hold no body text. Groups
+// starting with \* are skipped too.
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "listtable": true,
+	"listoverridetable": true, "revtbl": true, "rsidtbl": true, "info": true,
+	"pict": true, "object": true, "themedata": true, "latentstyles": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"footnote": true, "annotation": true, "atnid": true, "atnauthor": true,
+	"fldinst": true, "generator": true,
+}
+
+// rtfSymbols are control words that stand for a character.
+var rtfSymbols = map[string]string{
+	"par": "\n\n", "sect": "\n\n", "page": "\n\n", "line": "\n", "tab": "\t",
+	"emdash": "—", "endash": "–", "bullet": "•",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+	"~": " ", "_": "-", "\\": "\\", "{": "{", "}": "}",
+}
+
+var (
+	rtfCreatedPattern   = regexp.MustCompile(`\\creatim\\yr(\d+)\\mo(\d+)\\dy(\d+)(?:\\hr(\d+))?(?:\\min(\d+))?`)
+	rtfModifiedPattern  = regexp.MustCompile(`\\revtim\\yr(\d+)\\mo(\d+)\\dy(\d+)(?:\\hr(\d+))?(?:\\min(\d+))?`)
+	rtfEditMinsPattern  = regexp.MustCompile(`\\edmins(\d+)`)
+	rtfGeneratorPattern = regexp.MustCompile(`\\generator\s*([^;}]*)`)
+)
+
+// extractRTF extracts body text and the \info metadata from an RTF file.
+func extractRTF(data []byte) *Document {
+	doc := &Document{Format: DocumentFormatRTF, Text: extractRTFText(data)}
+
+	if m := rtfGeneratorPattern.FindSubmatch(data); m != nil {
+		doc.Metadata.Creator = strings.TrimSpace(string(m[1]))
+	}
+	doc.Metadata.CreatedAt = parseRTFTime(rtfCreatedPattern.FindSubmatch(data))
+	doc.Metadata.ModifiedAt = parseRTFTime(rtfModifiedPattern.FindSubmatch(data))
+	if m := rtfEditMinsPattern.FindSubmatch(data); m != nil {
+		doc.Metadata.EditingMinutes, _ = strconv.Atoi(string(m[1]))
+		doc.Metadata.HasEditingTime = true
+	}
+
+	return doc
+}
+
+// extractRTFText strips control words and groups from an RTF document,
+// keeping the body text. Paragraph marks become blank lines, \'hh and \uN
+// escapes are decoded, and text marked \deleted (tracked changes) is left
+// out.
+func extractRTFText(data []byte) string {
+	type group struct {
+		skip    bool
+		deleted bool
+		uc      int
+	}
+
+	state := group{uc: 1}
+	var stack []group
+	var sb strings.Builder
+
+	// fallback counts the characters after \uN that stand in for it in
+	// readers without Unicode support
+	fallback := 0
+	write := func(s string) {
+		if !state.skip && !state.deleted {
+			sb.WriteString(s)
+		}
+	}
+	char := func(s string) {
+		if fallback > 0 {
+			fallback--
+			return
+		}
+		write(s)
+	}
+
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '{':
+			stack = append(stack, state)
+			fallback = 0
+		case '}':
+			if len(stack) == 0 {
+				return sb.String()
+			}
+			state, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			fallback = 0
+			if len(stack) == 0 {
+				// The document's outer group is closed
+				return sb.String()
+			}
+		case '\\':
+			word, param, hasParam, n := readRTFControl(data[i+1:])
+			i += n
+			switch {
+			case word == "*":
+				state.skip = true
+			case word == "'":
+				if param >= 0 {
+					char(string(rtfByteRune(byte(param))))
+				}
+			case word == "u" && hasParam:
+				if param < 0 {
+					param += 65536
+				}
+				char(string(rune(param)))
+				fallback = state.uc
+			case word == "uc" && hasParam:
+				state.uc = max(param, 0)
+			case word == "bin" && hasParam:
+				i += min(max(param, 0), len(data)-i-1)
+			case word == "deleted":
+				state.deleted = !hasParam || param != 0
+			case word == "plain":
+				state.deleted = false
+			case rtfSkipDestinations[word]:
+				state.skip = true
+			default:
+				if s, ok := rtfSymbols[word]; ok {
+					char(s)
+				}
+			}
+		case '\r', '\n':
+			// Line breaks in the source aren't text
+		default:
+			if c >= 0x20 || c == '\t' {
+				char(string(rtfByteRune(c)))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// readRTFControl reads the control word or symbol after a backslash. It
+// returns the word, its numeric parameter (the byte value for \'hh, -1 if
+// the hex is invalid) and the number of bytes consumed, including the
+// space that ends a control word.
+func readRTFControl(data []byte) (word string, param int, hasParam bool, n int) {
+	if len(data) == 0 {
+		return "", 0, false, 0
+	}
+
+	c := data[0]
+	if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+		if c == '\'' {
+			if len(data) < 3 {
+				return "'", -1, true, len(data)
+			}
+			v, err := strconv.ParseUint(string(data[1:3]), 16, 8)
+			if err != nil {
+				return "'", -1, true, 3
+			}
+			return "'", int(v), true, 3
+		}
+		return string(c), 0, false, 1
+	}
+
+	for n < len(data) && n < 32 && (data[n] >= 'a' && data[n] <= 'z' || data[n] >= 'A' && data[n] <= 'Z') {
+		n++
+	}
+	word = string(data[:n])
+
+	start := n
+	if n < len(data) && data[n] == '-' {
+		n++
+	}
+	for n < len(data) && n-start < 10 && data[n] >= '0' && data[n] <= '9' {
+		n++
+	}
+	if digits := string(data[start:n]); digits != "" && digits != "-" {
+		param, _ = strconv.Atoi(digits)
+		hasParam = true
+	} else {
+		n = start
+	}
+
+	if n < len(data) && data[n] == ' ' {
+		n++
+	}
+	return word, param, hasParam, n
+}
+
+// rtfByteRune decodes a byte of RTF text as Windows-1252.
+func rtfByteRune(c byte) rune {
+	if r, ok := winAnsiPunctuation[c]; ok {
+		return r
+	}
+	return rune(c)
+}
+
+// parseRTFTime builds a time from a \creatim or \revtim match. RTF times
+// are local to the author with no zone, so they are read as UTC.
+func parseRTFTime(m [][]byte) time.Time {
+	if m == nil {
+		return time.Time{}
+	}
+	var f [5]int
+	for i := range f {
+		f[i], _ = strconv.Atoi(string(m[i+1]))
+	}
+	if f[1] < 1 || f[1] > 12 || f[2] < 1 || f[2] > 31 {
+		return time.Time{}
+	}
+	return time.Date(f[0], time.Month(f[1]), f[2], f[3], f[4], 0, 0, time.UTC)
+}
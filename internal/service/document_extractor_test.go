@@ -0,0 +1,433 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// readFixture reads a file from testdata.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return data
+}
+
+// TestExtractDocument tests text and metadata extraction from the fixtures.
+func TestExtractDocument(t *testing.T) {
+	t.Run("uncompressed PDF", func(t *testing.T) {
+		doc, err := ExtractDocument(readFixture(t, "essay.pdf"))
+		if err != nil {
+			t.Fatalf("ExtractDocument() error = %v", err)
+		}
+
+		if doc.Format != DocumentFormatPDF {
+			t.Errorf("Format = %q, want pdf", doc.Format)
+		}
+		for _, want := range []string{
+			"My grandmother kept bees behind the garage for thirty years.",
+			"Every August she would pull the frames out with her bare hands, which",
+			"(not even the wasps)",
+		} {
+			if !strings.Contains(doc.Text, want) {
+				t.Errorf("text missing %q:\n%s", want, doc.Text)
+			}
+		}
+
+		if doc.Metadata.Producer != "Microsoft® Word for Microsoft 365" {
+			t.Errorf("Producer = %q", doc.Metadata.Producer)
+		}
+		want := time.Date(2024, 3, 12, 14, 15, 0, 0, time.UTC)
+		if !doc.Metadata.CreatedAt.Equal(want) {
+			t.Errorf("CreatedAt = %v, want %v", doc.Metadata.CreatedAt, want)
+		}
+	})
+
+	t.Run("Flate PDF with object stream metadata", func(t *testing.T) {
+		doc, err := ExtractDocument(readFixture(t, "essay_flate.pdf"))
+		if err != nil {
+			t.Fatalf("ExtractDocument() error = %v", err)
+		}
+
+		if !strings.Contains(doc.Text, "mostly complaining about my uncle.") {
+			t.Errorf("expected text from the Flate stream, got:\n%s", doc.Text)
+		}
+		if doc.Metadata.Creator != "ChatGPT" {
+			t.Errorf("Creator = %q, want ChatGPT (UTF-16 string in an object stream)", doc.Metadata.Creator)
+		}
+	})
+
+	t.Run("DOCX", func(t *testing.T) {
+		doc, err := ExtractDocument(readFixture(t, "essay.docx"))
+		if err != nil {
+			t.Fatalf("ExtractDocument() error = %v", err)
+		}
+
+		if doc.Format != DocumentFormatDOCX {
+			t.Errorf("Format = %q, want docx", doc.Format)
+		}
+		if !strings.HasPrefix(doc.Text, "My grandmother kept bees") {
+			t.Errorf("unexpected text start:\n%s", doc.Text)
+		}
+		if !strings.Contains(doc.Text, "\n\nLooking back") {
+			t.Error("expected paragraphs to be separated")
+		}
+		if !strings.Contains(doc.Text, "Grandma was right.") {
+			t.Error("expected split runs to be joined")
+		}
+		if strings.Contains(doc.Text, "DELETED") {
+			t.Error("tracked deletions should be left out")
+		}
+
+		meta := doc.Metadata
+		if meta.Creator != "Microsoft Office Word" || !meta.HasEditingTime || meta.EditingMinutes != 95 {
+			t.Errorf("unexpected metadata: %+v", meta)
+		}
+		if !meta.ModifiedAt.Equal(time.Date(2024, 3, 12, 14, 15, 0, 0, time.UTC)) {
+			t.Errorf("ModifiedAt = %v", meta.ModifiedAt)
+		}
+		if meta.PageCount < 1 {
+			t.Errorf("PageCount = %d, want an estimate", meta.PageCount)
+		}
+	})
+
+	t.Run("DOCX tracked changes", func(t *testing.T) {
+		data := zipArchive(t, map[string]string{
+			"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+				`<w:p><w:r><w:t>The bees came back in April.</w:t></w:r>` +
+				`<w:del w:id="1"><w:r><w:delText>DELETED</w:delText></w:r></w:del>` +
+				`<w:moveFrom w:id="2"><w:r><w:t>MOVED AWAY</w:t></w:r></w:moveFrom>` +
+				`<w:ins w:id="3"><w:r><w:t xml:space="preserve"> Nobody expected them.</w:t></w:r></w:ins></w:p>` +
+				`</w:body></w:document>`,
+			"docProps/app.xml": `<Properties><Application>Microsoft Office Word</Application><Pages>3</Pages></Properties>`,
+		})
+
+		doc, err := ExtractDocument(data)
+		if err != nil {
+			t.Fatalf("ExtractDocument() error = %v", err)
+		}
+		if doc.Text != "The bees came back in April. Nobody expected them." {
+			t.Errorf("Text = %q", doc.Text)
+		}
+		if doc.Metadata.PageCount != 3 {
+			t.Errorf("PageCount = %d, want the recorded 3", doc.Metadata.PageCount)
+		}
+	})
+
+	t.Run("ODT", func(t *testing.T) {
+		data := zipArchive(t, map[string]string{
+			"mimetype": odtMimeType,
+			"content.xml": `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"><office:body><office:text>` +
+				`<text:tracked-changes><text:changed-region><text:deletion><text:p>DELETED</text:p></text:deletion></text:changed-region></text:tracked-changes>` +
+				`<text:h>Bees</text:h>` +
+				`<text:p>My grandmother<text:s text:c="3"/>kept <text:span>bees</text:span>.<office:annotation><text:p>COMMENT</text:p></office:annotation></text:p>` +
+				`<text:p>They stung<text:note><text:note-body><text:p>FOOTNOTE</text:p></text:note-body></text:note> everyone<text:line-break/>but her.</text:p>` +
+				`</office:text></office:body></office:document-content>`,
+			"meta.xml": `<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><office:meta>` +
+				`<meta:generator>LibreOffice/7.6.4.1$Linux_X86_64</meta:generator>` +
+				`<meta:creation-date>2024-03-12T09:00:00.123456789</meta:creation-date>` +
+				`<dc:date>2024-03-12T14:15:00</dc:date>` +
+				`<meta:editing-duration>PT1H35M12S</meta:editing-duration>` +
+				`<meta:document-statistic meta:page-count="2" meta:word-count="14"/>` +
+				`</office:meta></office:document-meta>`,
+		})
+
+		doc, err := ExtractDocument(data)
+		if err != nil {
+			t.Fatalf("ExtractDocument() error = %v", err)
+		}
+
+		if doc.Format != DocumentFormatODT {
+			t.Errorf("Format = %q, want odt", doc.Format)
+		}
+		want := "Bees\n\nMy grandmother kept bees.\n\nThey stung everyone\nbut her."
+		if doc.Text != want {
+			t.Errorf("Text = %q, want %q", doc.Text, want)
+		}
+
+		meta := doc.Metadata
+		if !strings.HasPrefix(meta.Creator, "LibreOffice") || !meta.HasEditingTime || meta.EditingMinutes != 95 || meta.PageCount != 2 {
+			t.Errorf("unexpected metadata: %+v", meta)
+		}
+		if !meta.ModifiedAt.Equal(time.Date(2024, 3, 12, 14, 15, 0, 0, time.UTC)) {
+			t.Errorf("ModifiedAt = %v", meta.ModifiedAt)
+		}
+	})
+
+	t.Run("RTF", func(t *testing.T) {
+		data := []byte(`{\rtf1\ansi\ansicpg1252\deff0{\fonttbl{\f0\froman Times New Roman;}}` +
+			`{\*\generator Riched20 10.0.19041}{\info{\title Bees}{\creatim\yr2024\mo3\dy12\hr9\min0}{\revtim\yr2024\mo3\dy12\hr14\min15}{\edmins95}}` +
+			`\viewkind4\uc1\pard\f0\fs24 My grandmother kept bees behind the garage.\par` + "\n" +
+			`She called them \ldblquote her girls\rdblquote  and never wore gloves.\par` + "\n" +
+			`{\deleted DELETED }Not one caf\'e9 \u8212\'97 not one na\u239\'efve visitor.\par}`)
+
+		doc, err := ExtractDocument(data)
+		if err != nil {
+			t.Fatalf("ExtractDocument() error = %v", err)
+		}
+
+		if doc.Format != DocumentFormatRTF {
+			t.Errorf("Format = %q, want rtf", doc.Format)
+		}
+		want := "My grandmother kept bees behind the garage.\n\n" +
+			"She called them “her girls” and never wore gloves.\n\n" +
+			"Not one café — not one naïve visitor."
+		if doc.Text != want {
+			t.Errorf("Text = %q, want %q", doc.Text, want)
+		}
+
+		meta := doc.Metadata
+		if meta.Creator != "Riched20 10.0.19041" || !meta.HasEditingTime || meta.EditingMinutes != 95 || meta.PageCount != 1 {
+			t.Errorf("unexpected metadata: %+v", meta)
+		}
+		if !meta.CreatedAt.Equal(time.Date(2024, 3, 12, 9, 0, 0, 0, time.UTC)) {
+			t.Errorf("CreatedAt = %v", meta.CreatedAt)
+		}
+	})
+
+	failures := []struct {
+		name string
+		data []byte
+	}{
+		{"scanned PDF", readFixture(t, "scanned.pdf")},
+		{"zip without a Word document", zipArchive(t, map[string]string{"photos/readme.txt": "hello"})},
+		{"OpenDocument spreadsheet", zipArchive(t, map[string]string{
+			"mimetype":    "application/vnd.oasis.opendocument.spreadsheet",
+			"content.xml": `<office:document-content><office:body><text:p>Quarterly totals for the bee farm</text:p></office:body></office:document-content>`,
+		})},
+		{"RTF with only control words", []byte(`{\rtf1\ansi{\fonttbl{\f0 Times;}}\pard\par}`)},
+		{"unrecognized format", []byte("just some bytes")},
+		{"truncated zip", readFixture(t, "essay.docx")[:100]},
+		{"too large", append([]byte("%PDF-1.4\n"), make([]byte, maxDocumentSize)...)},
+	}
+
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExtractDocument(tt.data)
+			if !errors.Is(err, ErrDocumentExtraction) {
+				t.Errorf("error = %v, want ErrDocumentExtraction", err)
+			}
+		})
+	}
+
+	t.Run("zip bomb is rejected", func(t *testing.T) {
+		data := zipArchive(t, map[string]string{
+			"word/document.xml": strings.Repeat(" ", maxDocumentDecompressed+1),
+		})
+		_, err := ExtractDocument(data)
+		if !errors.Is(err, ErrDocumentExtraction) || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("error = %v, want size rejection", err)
+		}
+	})
+}
+
+// zipArchive builds a zip file from name/content pairs.
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestEstimatePageCount tests the paragraph-based page estimate.
+func TestEstimatePageCount(t *testing.T) {
+	paragraphs := func(n int) string {
+		return strings.TrimSuffix(strings.Repeat("A paragraph.\n\n", n), "\n\n")
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"one line", "Just a note.", 1},
+		{"one page", paragraphs(paragraphsPerPage), 1},
+		{"spills onto a second page", paragraphs(paragraphsPerPage + 1), 2},
+		{"three pages", paragraphs(3 * paragraphsPerPage), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimatePageCount(tt.text); got != tt.want {
+				t.Errorf("estimatePageCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractRTFText tests control word handling.
+func TestExtractRTFText(t *testing.T) {
+	tests := []struct {
+		name string
+		rtf  string
+		want string
+	}{
+		{"escaped characters", `{\rtf1 braces \{ and \} and \\ slash}`, `braces { and } and \ slash`},
+		{"ignorable destination", `{\rtf1 {\*\unknown hidden}shown}`, "shown"},
+		{"footnote", `{\rtf1 bees{\super\chftn}{\footnote\pard\plain note text} buzz}`, "bees buzz"},
+		{"comment", `{\rtf1 text{\*\atnid JD}{\*\annotation\pard aside} more}`, "text more"},
+		{"unicode with two fallback bytes", `{\rtf1\uc2 \u26085\'93\'fa!}`, "日!"},
+		{"line and tab", `{\rtf1 a\line b\tab c}`, "a\nb\tc"},
+		{"field result kept", `{\rtf1 {\field{\*\fldinst HYPERLINK "x"}{\fldrslt link}}}`, "link"},
+		{"binary data skipped", `{\rtf1 a{\pict\bin3 }}}}b}`, "ab"},
+		{"deleted revision", `{\rtf1 kept \deleted gone\deleted0  back}`, "kept  back"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractRTFText([]byte(tt.rtf)); got != tt.want {
+				t.Errorf("extractRTFText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractPDFContentText tests text operators in content streams.
+func TestExtractPDFContentText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"Tj", `BT (Hello world) Tj ET`, "Hello world"},
+		{"escapes", `BT (a \(b\) c\\d \101) Tj ET`, `a (b) c\d A`},
+		{"TJ kerning gap", `BT [(Hel) 20 (lo) -400 (there)] TJ ET`, "Hello there"},
+		{"hex string", `BT <48692E> Tj ET`, "Hi."},
+		{"quote operator", `BT (one) Tj (two) ' ET`, "one\ntwo"},
+		{"line move", `BT (one) Tj 0 -14 Td (two) Tj ET`, "one\ntwo"},
+		{"WinAnsi quotes", "BT (\x93quoted\x94) Tj ET", "“quoted”"},
+		{"inline image skipped", "BT (before) Tj ET BI /W 1 /H 1 ID \x00\xff EI BT (after) Tj ET", "before\nafter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			extractPDFContentText([]byte(tt.content), &sb)
+			if got := strings.TrimSpace(sb.String()); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParsePDFDate tests PDF date parsing.
+func TestParsePDFDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"D:20240312091500-05'00'", time.Date(2024, 3, 12, 14, 15, 0, 0, time.UTC)},
+		{"D:20240401120000Z", time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)},
+		{"D:2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"garbage", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := parsePDFDate(tt.in); !got.Equal(tt.want) {
+				t.Errorf("parsePDFDate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDocumentMetadataScore tests the metadata signal.
+func TestDocumentMetadataScore(t *testing.T) {
+	essay := strings.Repeat("word ", pastedMinWords)
+	created := time.Date(2024, 3, 12, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		doc    Document
+		want   float64
+		wantOK bool
+	}{
+		{"AI tool producer", Document{Format: DocumentFormatPDF, Metadata: DocumentMetadata{Creator: "ChatGPT"}}, metadataScoreAITool, true},
+		{"Word PDF export", Document{Format: DocumentFormatPDF, Metadata: DocumentMetadata{Producer: "Microsoft® Word for Microsoft 365"}}, 0, false},
+		{"long editing time", Document{Format: DocumentFormatDOCX, Metadata: DocumentMetadata{EditingMinutes: 95, HasEditingTime: true}}, metadataScoreLongEditing, true},
+		{"pasted essay", Document{Format: DocumentFormatDOCX, Text: essay, Metadata: DocumentMetadata{EditingMinutes: 1, HasEditingTime: true}}, metadataScorePasted, true},
+		{"short note written quickly", Document{Format: DocumentFormatDOCX, Text: "Remember milk.", Metadata: DocumentMetadata{EditingMinutes: 0, HasEditingTime: true}}, 0, false},
+		{"saved within a minute of creation", Document{Format: DocumentFormatDOCX, Text: essay, Metadata: DocumentMetadata{CreatedAt: created, ModifiedAt: created.Add(30 * time.Second)}}, metadataScorePasted, true},
+		{"no metadata", Document{Format: DocumentFormatDOCX, Text: essay}, 0, false},
+		{"pasted ODT", Document{Format: DocumentFormatODT, Text: essay, Metadata: DocumentMetadata{EditingMinutes: 0, HasEditingTime: true}}, metadataScorePasted, true},
+		{"RTF with long editing time", Document{Format: DocumentFormatRTF, Metadata: DocumentMetadata{EditingMinutes: 240, HasEditingTime: true}}, metadataScoreLongEditing, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := documentMetadataScore(&tt.doc)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("documentMetadataScore() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestDetectDocument tests routing documents through the detector.
+func TestDetectDocument(t *testing.T) {
+	detector, err := NewDetector(DetectorConfig{Timeout: 30 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("docx upload", func(t *testing.T) {
+		result, err := detector.Detect(ctx, DetectionInput{Data: readFixture(t, "essay.docx"), Filename: "essay.docx"})
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+
+		if result.ContentType != ContentTypeDocument {
+			t.Errorf("ContentType = %s, want document", result.ContentType)
+		}
+		want := []string{"humanmark", "metadata"}
+		if strings.Join(result.Detectors, ",") != strings.Join(want, ",") {
+			t.Errorf("Detectors = %v, want %v", result.Detectors, want)
+		}
+		if result.ProviderScores[1].Weight != documentMetadataWeight {
+			t.Errorf("metadata weight = %v, want %v", result.ProviderScores[1].Weight, documentMetadataWeight)
+		}
+	})
+
+	t.Run("pdf detected from magic bytes", func(t *testing.T) {
+		result, err := detector.Detect(ctx, DetectionInput{Data: readFixture(t, "essay.pdf")})
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if result.ContentType != ContentTypeDocument {
+			t.Errorf("ContentType = %s, want document", result.ContentType)
+		}
+		// A Word export says nothing about authorship
+		if len(result.Detectors) != 1 || result.Detectors[0] != "humanmark" {
+			t.Errorf("Detectors = %v, want only humanmark", result.Detectors)
+		}
+	})
+
+	t.Run("scanned pdf fails extraction", func(t *testing.T) {
+		_, err := detector.Detect(ctx, DetectionInput{Data: readFixture(t, "scanned.pdf"), Filename: "scan.pdf"})
+		if !errors.Is(err, ErrDocumentExtraction) {
+			t.Errorf("error = %v, want ErrDocumentExtraction", err)
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// =============================================================================
+// Email Mode
+// =============================================================================
+//
+// AI-written phishing and spam has a recognizable shape: a generic greeting,
+// a demand for urgent action, and nothing that ties it to a real
+// conversation. In email mode the text analyzer:
+//
+//   1. Splits off the header block (From, To, Subject, ...) so header lines
+//      don't skew the statistical signals
+//   2. Drops quoted replies, which were written by someone else
+//   3. Scores email-specific signals and blends them into the AI score
+//
+// A reply chain or a personal signature is evidence of real correspondence
+// and pulls the email score down.
+//
+// =============================================================================
+
+// emailSignalWeight is how much the email-specific score contributes to the
+// final AI score in email mode. The statistical signals still dominate.
+const emailSignalWeight = 0.3
+
+// EmailHeaders are the headers extracted from an email.
+type EmailHeaders struct {
+	From    string
+	To      string
+	Subject string
+}
+
+// EmailAnalysisResult contains the email-specific analysis.
+type EmailAnalysisResult struct {
+	// Headers found at the top of the text (empty if there were none)
+	Headers EmailHeaders
+
+	// Body is the text that was analyzed: headers and quoted replies removed
+	Body string
+
+	// EmailSpecificScore combines the email signals (0.0 = human, 1.0 = AI)
+	EmailSpecificScore float64
+
+	// UrgentPhrases are the pressure phrases found in the body
+	UrgentPhrases []string
+
+	// GenericSalutation is the impersonal greeting used, if any
+	GenericSalutation string
+
+	// HasReplyChain is true if the email quotes earlier messages
+	HasReplyChain bool
+
+	// HasSignature is true if the body ends with a personal signature block
+	HasSignature bool
+}
+
+// urgentEmailPhrases are pressure tactics common in phishing.
+var urgentEmailPhrases = []string{
+	"your account will be suspended",
+	"your account has been suspended",
+	"your account has been locked",
+	"verify immediately",
+	"verify your account",
+	"verify your identity",
+	"confirm your identity",
+	"immediate action required",
+	"within 24 hours",
+	"within 48 hours",
+	"failure to comply",
+	"unusual activity",
+	"suspicious activity",
+	"update your payment",
+	"click the link below",
+	"click here to",
+	"act now",
+	"urgent",
+}
+
+// genericSalutationPattern matches impersonal greetings at the start of a line.
+var genericSalutationPattern = regexp.MustCompile(`(?im)^\s*((?:dear|hello|hi)\s+(?:valued\s+)?(?:customer|user|client|member|account holder|sir(?:\s+or\s+madam|/madam)?|friend|there))\b`)
+
+// emailHeaderPattern matches an RFC 5322 style header line.
+var emailHeaderPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*):\s?(.*)$`)
+
+// replyAttributionPattern matches "On <date>, <name> wrote:" lines.
+var replyAttributionPattern = regexp.MustCompile(`(?i)^on\s.+wrote:\s*$`)
+
+// signatureLines are sign-offs that usually precede a personal signature.
+var signatureLines = []string{
+	"thanks", "thanks!", "thank you", "cheers", "best", "regards",
+	"kind regards", "best regards", "warm regards", "--",
+}
+
+// AnalyzeEmail analyzes an email: headers are split off, quoted replies
+// dropped, and the body analyzed with the email signals blended in.
+// The result's Email field is set.
+func (a *TextAnalyzer) AnalyzeEmail(raw string) TextAnalysisResult {
+	// A background context is never cancelled
+	result, _ := a.AnalyzeEmailContext(context.Background(), raw)
+	return result
+}
+
+// AnalyzeEmailContext is AnalyzeEmail, returning ctx's error if ctx is
+// cancelled during analysis.
+func (a *TextAnalyzer) AnalyzeEmailContext(ctx context.Context, raw string) (TextAnalysisResult, error) {
+	return a.analyzeEmail(ctx, raw, AnalysisOverrides{})
+}
+
+// analyzeEmail runs the email analysis with overrides.
+func (a *TextAnalyzer) analyzeEmail(ctx context.Context, raw string, opts AnalysisOverrides) (TextAnalysisResult, error) {
+	headers, message := parseEmailHeaders(raw)
+	body, replyChain := stripReplyChain(message)
+
+	result, err := a.analyze(ctx, body, opts)
+	if err != nil {
+		return TextAnalysisResult{}, err
+	}
+
+	email := &EmailAnalysisResult{
+		Headers:       headers,
+		Body:          body,
+		HasReplyChain: replyChain,
+		HasSignature:  hasEmailSignature(body),
+	}
+	email.UrgentPhrases = findUrgentPhrases(body)
+	if m := genericSalutationPattern.FindStringSubmatch(body); m != nil {
+		email.GenericSalutation = strings.TrimSpace(m[1])
+	}
+	email.EmailSpecificScore = emailSpecificScore(email)
+
+	result.Email = email
+	result.AIScore = result.AIScore*(1-emailSignalWeight) + email.EmailSpecificScore*emailSignalWeight
+
+	return result, nil
+}
+
+// parseEmailHeaders splits a leading header block from the message. Header
+// lines run until the first blank line; folded lines (starting with
+// whitespace) continue the previous header. If the text doesn't start with
+// headers, it's returned unchanged.
+func parseEmailHeaders(raw string) (EmailHeaders, string) {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	lines := strings.Split(raw, "\n")
+
+	var headers EmailHeaders
+	var last *string
+	found := false
+
+	i := 0
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		if (line[0] == ' ' || line[0] == '\t') && found {
+			if last != nil {
+				*last += " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		m := emailHeaderPattern.FindStringSubmatch(line)
+		if m == nil {
+			// Not a header block after all
+			if !found {
+				return EmailHeaders{}, raw
+			}
+			break
+		}
+		found = true
+
+		value := strings.TrimSpace(m[2])
+		switch strings.ToLower(m[1]) {
+		case "from":
+			headers.From, last = value, &headers.From
+		case "to":
+			headers.To, last = value, &headers.To
+		case "subject":
+			headers.Subject, last = value, &headers.Subject
+		default:
+			last = nil
+		}
+	}
+
+	// A lone "Word: text" line is more likely prose than a header
+	if headers == (EmailHeaders{}) {
+		return EmailHeaders{}, raw
+	}
+
+	return headers, strings.TrimSpace(strings.Join(lines[i:], "\n"))
+}
+
+// stripReplyChain removes quoted ("> ") lines and everything after a reply
+// attribution or forwarded-message marker. It reports whether anything was
+// removed.
+func stripReplyChain(message string) (string, bool) {
+	var kept []string
+	found := false
+
+	for _, line := range strings.Split(message, "\n") {
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+
+		if replyAttributionPattern.MatchString(trimmed) ||
+			strings.HasPrefix(lower, "-----original message-----") ||
+			strings.HasPrefix(lower, "---------- forwarded message") {
+			found = true
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			found = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	return strings.TrimSpace(strings.Join(kept, "\n")), found
+}
+
+// hasEmailSignature reports whether one of the last few lines is a sign-off
+// followed by a name or contact line.
+func hasEmailSignature(body string) bool {
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	start := len(lines) - 5
+	if start < 0 {
+		start = 0
+	}
+
+	for i := start; i < len(lines); i++ {
+		line := strings.ToLower(strings.TrimRight(strings.TrimSpace(lines[i]), ","))
+		if strings.HasPrefix(line, "sent from my ") {
+			return true
+		}
+		for _, s := range signatureLines {
+			// A sign-off with nothing after it isn't a signature
+			if line == s && i < len(lines)-1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findUrgentPhrases returns the urgent phrases present in body.
+func findUrgentPhrases(body string) []string {
+	lower := strings.ToLower(body)
+
+	var found []string
+	for _, phrase := range urgentEmailPhrases {
+		if strings.Contains(lower, phrase) {
+			found = append(found, phrase)
+		}
+	}
+	return found
+}
+
+// emailSpecificScore combines the email signals into one score.
+func emailSpecificScore(email *EmailAnalysisResult) float64 {
+	urgency := float64(len(email.UrgentPhrases)) * 0.35
+	if urgency > 1 {
+		urgency = 1
+	}
+
+	score := urgency * 0.6
+	if email.GenericSalutation != "" {
+		score += 0.3
+	}
+	if !email.HasReplyChain && !email.HasSignature {
+		// No sign of an existing relationship with the recipient
+		score += 0.1
+	}
+	if email.HasReplyChain {
+		score -= 0.2
+	}
+
+	return clampScore(score)
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// phishingEmail is a typical AI-written account suspension phish.
+const phishingEmail = `From: "Account Security" <security@paypa1-support.com>
+To: victim@example.com
+Subject: Immediate action required: unusual activity detected
+
+Dear Customer,
+
+We have detected unusual activity on your account. To ensure the security of your information, your account will be suspended within 24 hours unless you verify your identity.
+
+Please click the link below to verify immediately. Failure to comply will result in permanent restriction of your account.
+
+We appreciate your prompt attention to this matter.
+
+Best regards,
+Account Security Team`
+
+// personalEmail is a reply between colleagues.
+const personalEmail = `From: Dana Whitfield <dana@example.org>
+To: Marcus Lee <marcus@example.org>
+Subject: Re: Thursday
+
+yeah Thursday's fine but can we push to 2? I've got the dentist at noon and they're never on time lol
+
+also did you ever find the receipts from the Denver trip? finance keeps pinging me
+
+Thanks,
+Dana
+Sent from my iPhone
+
+On Tue, Mar 12, 2024 at 9:14 AM Marcus Lee <marcus@example.org> wrote:
+> Are we still on for Thursday? I can book the small room.`
+
+// TestAnalyzeEmail tests email-mode analysis on sample emails.
+func TestAnalyzeEmail(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	t.Run("phishing email", func(t *testing.T) {
+		result := analyzer.AnalyzeEmail(phishingEmail)
+		email := result.Email
+		if email == nil {
+			t.Fatal("expected Email to be set")
+		}
+
+		if email.Headers.Subject != "Immediate action required: unusual activity detected" {
+			t.Errorf("Subject = %q", email.Headers.Subject)
+		}
+		if strings.Contains(email.Body, "From:") || !strings.HasPrefix(email.Body, "Dear Customer,") {
+			t.Errorf("headers not stripped from body:\n%s", email.Body)
+		}
+		if email.GenericSalutation != "Dear Customer" {
+			t.Errorf("GenericSalutation = %q, want Dear Customer", email.GenericSalutation)
+		}
+		if len(email.UrgentPhrases) < 4 {
+			t.Errorf("UrgentPhrases = %v, want several", email.UrgentPhrases)
+		}
+		if email.EmailSpecificScore < 0.8 {
+			t.Errorf("EmailSpecificScore = %f, want >= 0.8", email.EmailSpecificScore)
+		}
+
+		// The email signals raise the plain text score
+		plain := analyzer.Analyze(email.Body)
+		if result.AIScore <= plain.AIScore {
+			t.Errorf("AIScore = %f, want above plain analysis %f", result.AIScore, plain.AIScore)
+		}
+	})
+
+	t.Run("personal reply", func(t *testing.T) {
+		result := analyzer.AnalyzeEmail(personalEmail)
+		email := result.Email
+
+		if email.Headers.From != "Dana Whitfield <dana@example.org>" {
+			t.Errorf("From = %q", email.Headers.From)
+		}
+		if !email.HasReplyChain || strings.Contains(email.Body, "book the small room") {
+			t.Error("expected quoted reply to be detected and stripped")
+		}
+		if !email.HasSignature {
+			t.Error("expected signature to be detected")
+		}
+		if email.GenericSalutation != "" || len(email.UrgentPhrases) != 0 {
+			t.Errorf("unexpected phishing signals: %q %v", email.GenericSalutation, email.UrgentPhrases)
+		}
+		if email.EmailSpecificScore != 0 {
+			t.Errorf("EmailSpecificScore = %f, want 0", email.EmailSpecificScore)
+		}
+	})
+
+	t.Run("text without headers is kept whole", func(t *testing.T) {
+		text := "Note: this isn't an email.\nHello there, just a note about the meeting."
+		result := analyzer.AnalyzeEmail(text)
+		if result.Email.Body != text {
+			t.Errorf("Body = %q, want original text", result.Email.Body)
+		}
+		if result.Email.GenericSalutation != "Hello there" {
+			t.Errorf("GenericSalutation = %q, want Hello there", result.Email.GenericSalutation)
+		}
+	})
+
+	t.Run("Analyze leaves Email unset", func(t *testing.T) {
+		if NewTextAnalyzer().Analyze(phishingEmail).Email != nil {
+			t.Error("expected Email to be nil outside email mode")
+		}
+	})
+}
+
+// TestParseEmailHeaders tests header block parsing.
+func TestParseEmailHeaders(t *testing.T) {
+	raw := "From: a@example.com\r\nSubject: Quarterly\r\n report\r\nX-Mailer: Outlook\r\n\r\nBody text."
+	headers, body := parseEmailHeaders(raw)
+
+	if headers.From != "a@example.com" || headers.Subject != "Quarterly report" {
+		t.Errorf("unexpected headers: %+v", headers)
+	}
+	if body != "Body text." {
+		t.Errorf("body = %q", body)
+	}
+}
+
+// TestDetectTextEmailMode tests that the text detector uses email analysis.
+func TestDetectTextEmailMode(t *testing.T) {
+	d := NewTextDetector(DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+	ctx := context.Background()
+
+	plain, err := d.DetectText(ctx, DetectionInput{Text: phishingEmail})
+	if err != nil {
+		t.Fatalf("DetectText() error = %v", err)
+	}
+	email, err := d.DetectText(ctx, DetectionInput{Text: phishingEmail, EmailMode: true})
+	if err != nil {
+		t.Fatalf("DetectText() error = %v", err)
+	}
+
+	if email.AIScore == plain.AIScore {
+		t.Errorf("email mode AIScore = %f, same as plain text", email.AIScore)
+	}
+}
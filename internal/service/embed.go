@@ -0,0 +1,91 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// =============================================================================
+// Embedded Data
+// =============================================================================
+//
+// Default dictionaries live in data/ as JSON so they can be reviewed and
+// edited without touching analyzer code. They're compiled into the binary,
+// so a deployment never depends on files being present at runtime.
+//
+// =============================================================================
+
+//go:embed data/ai_phrases_en.json
+var defaultAIPhrasesJSON []byte
+
+// AIPhrase is one entry in the AI phrase dictionary.
+type AIPhrase struct {
+	// Pattern is matched case-insensitively as a substring
+	Pattern string `json:"pattern"`
+
+	// Weight is how strongly the phrase suggests AI writing (0.0-1.0]
+	Weight float64 `json:"weight"`
+
+	// Category groups phrases for reporting, e.g. "hedging"
+	Category string `json:"category"`
+}
+
+// AIPhraseDictionary holds the phrases the text analyzer looks for.
+// Loaded from data/ai_phrases_en.json at init.
+var AIPhraseDictionary []AIPhrase
+
+func init() {
+	phrases, err := ParseAIPhrases(defaultAIPhrasesJSON)
+	if err != nil {
+		panic("service: invalid embedded AI phrase dictionary: " + err.Error())
+	}
+	AIPhraseDictionary = phrases
+}
+
+// WithAIPhrases replaces the analyzer's phrase dictionary. An empty
+// dictionary keeps AIPhraseDictionary.
+func WithAIPhrases(phrases []AIPhrase) TextAnalyzerOption {
+	return func(a *TextAnalyzer) {
+		if len(phrases) > 0 {
+			a.phrases = phrases
+		}
+	}
+}
+
+// LoadAIPhrases reads a phrase dictionary in the data/ai_phrases_en.json
+// format from path.
+func LoadAIPhrases(path string) ([]AIPhrase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAIPhrases(data)
+}
+
+// ParseAIPhrases parses and validates a phrase dictionary.
+// Patterns are lowercased to match the analyzer's case-insensitive search.
+func ParseAIPhrases(data []byte) ([]AIPhrase, error) {
+	var phrases []AIPhrase
+	if err := json.Unmarshal(data, &phrases); err != nil {
+		return nil, fmt.Errorf("parse AI phrases: %w", err)
+	}
+
+	for i, p := range phrases {
+		pattern := strings.ToLower(strings.TrimSpace(p.Pattern))
+		if pattern == "" {
+			return nil, fmt.Errorf("AI phrase %d: empty pattern", i)
+		}
+		if p.Weight <= 0 || p.Weight > 1 {
+			return nil, fmt.Errorf("AI phrase %q: weight %g must be in (0, 1]", pattern, p.Weight)
+		}
+		if p.Category == "" {
+			return nil, fmt.Errorf("AI phrase %q: missing category", pattern)
+		}
+		phrases[i].Pattern = pattern
+	}
+
+	return phrases, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAIPhraseDictionary verifies the embedded dictionary is loaded at init.
+func TestAIPhraseDictionary(t *testing.T) {
+	if len(AIPhraseDictionary) == 0 {
+		t.Fatal("expected embedded AI phrases to be loaded")
+	}
+
+	categories := map[string]bool{}
+	for _, p := range AIPhraseDictionary {
+		categories[p.Category] = true
+	}
+	for _, want := range []string{"ai-reference", "hedging", "transition"} {
+		if !categories[want] {
+			t.Errorf("expected category %q in dictionary", want)
+		}
+	}
+
+	result := NewTextAnalyzer().Analyze("As an AI, it's important to note that I can't browse.")
+	found := map[string]string{}
+	for _, p := range result.DetectedAIPhrases {
+		found[p.Phrase] = p.Category
+	}
+	if found["as an ai"] != "ai-reference" || found["it's important to note"] != "hedging" {
+		t.Errorf("expected categorized phrases, got %+v", result.DetectedAIPhrases)
+	}
+}
+
+// TestLoadAIPhrases verifies edits to the phrase file are picked up.
+func TestLoadAIPhrases(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("data", "ai_phrases_en.json"))
+	if err != nil {
+		t.Fatalf("read phrase file: %v", err)
+	}
+
+	var phrases []AIPhrase
+	if err := json.Unmarshal(data, &phrases); err != nil {
+		t.Fatalf("phrase file is not valid JSON: %v", err)
+	}
+	phrases = append(phrases, AIPhrase{Pattern: "Synergize The Paradigm", Weight: 0.9, Category: "buzzword"})
+
+	edited, _ := json.Marshal(phrases)
+	path := filepath.Join(t.TempDir(), "ai_phrases_en.json")
+	if err := os.WriteFile(path, edited, 0o644); err != nil {
+		t.Fatalf("write phrase file: %v", err)
+	}
+
+	loaded, err := LoadAIPhrases(path)
+	if err != nil {
+		t.Fatalf("LoadAIPhrases() error = %v", err)
+	}
+
+	original := AIPhraseDictionary
+	AIPhraseDictionary = loaded
+	t.Cleanup(func() { AIPhraseDictionary = original })
+
+	result := NewTextAnalyzer().Analyze("We need to synergize the paradigm before the next quarter begins.")
+	if len(result.DetectedAIPhrases) != 1 {
+		t.Fatalf("expected the new phrase to be detected, got %+v", result.DetectedAIPhrases)
+	}
+	if got := result.DetectedAIPhrases[0]; got.Phrase != "synergize the paradigm" || got.Category != "buzzword" {
+		t.Errorf("unexpected detected phrase: %+v", got)
+	}
+	if result.Signals.AIPhraseScore == 0 {
+		t.Error("expected the new phrase to contribute to AIPhraseScore")
+	}
+}
+
+// TestParseAIPhrases verifies invalid dictionaries are rejected.
+func TestParseAIPhrases(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"malformed JSON", `[{"pattern": "x"`},
+		{"empty pattern", `[{"pattern": " ", "weight": 0.5, "category": "hedging"}]`},
+		{"zero weight", `[{"pattern": "moreover", "weight": 0, "category": "transition"}]`},
+		{"weight above one", `[{"pattern": "moreover", "weight": 1.5, "category": "transition"}]`},
+		{"missing category", `[{"pattern": "moreover", "weight": 0.4}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAIPhrases([]byte(tt.json)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFetcherBlocksPrivateAddresses verifies internal addresses are refused.
+func TestFetcherBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("loopback refused by default", func(t *testing.T) {
+		f := newURLFetcher(FetchPolicy{Timeout: 5 * time.Second})
+
+		_, err := f.Fetch(ctx, server.URL, 1024)
+		if !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("expected ErrBlockedAddress, got %v", err)
+		}
+	})
+
+	t.Run("hostname resolving to loopback refused", func(t *testing.T) {
+		f := newURLFetcher(FetchPolicy{Timeout: 5 * time.Second})
+
+		_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+		_, err := f.Fetch(ctx, "http://localhost:"+port+"/", 1024)
+		if !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("expected ErrBlockedAddress, got %v", err)
+		}
+	})
+
+	t.Run("loopback allowed when policy permits", func(t *testing.T) {
+		f := newURLFetcher(FetchPolicy{AllowPrivateNetworks: true, Timeout: 5 * time.Second})
+
+		result, err := f.Fetch(ctx, server.URL, 1024)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if string(result.Data) != "secret" {
+			t.Errorf("unexpected body: %q", result.Data)
+		}
+	})
+}
+
+// TestFetcherSchemes verifies only http and https are allowed.
+func TestFetcherSchemes(t *testing.T) {
+	f := newURLFetcher(FetchPolicy{AllowPrivateNetworks: true, Timeout: 5 * time.Second})
+
+	urls := []string{
+		"file:///etc/passwd",
+		"ftp://example.com/file",
+		"gopher://127.0.0.1:6379/_INFO",
+	}
+
+	for _, u := range urls {
+		t.Run(u, func(t *testing.T) {
+			_, err := f.Fetch(context.Background(), u, 1024)
+			if !errors.Is(err, ErrDisallowedScheme) {
+				t.Errorf("expected ErrDisallowedScheme, got %v", err)
+			}
+		})
+	}
+}
+
+// TestFetcherRedirects verifies redirect capping and per-hop checks.
+func TestFetcherRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/once", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/scheme", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := newURLFetcher(FetchPolicy{AllowPrivateNetworks: true, MaxRedirects: 3, Timeout: 5 * time.Second})
+	ctx := context.Background()
+
+	t.Run("follows a single redirect", func(t *testing.T) {
+		result, err := f.Fetch(ctx, server.URL+"/once", 1024)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if !strings.HasSuffix(result.FinalURL, "/final") {
+			t.Errorf("FinalURL = %s, want .../final", result.FinalURL)
+		}
+		if result.ContentType != "image/png" {
+			t.Errorf("ContentType = %s, want image/png", result.ContentType)
+		}
+	})
+
+	t.Run("caps redirect loops", func(t *testing.T) {
+		_, err := f.Fetch(ctx, server.URL+"/loop", 1024)
+		if !errors.Is(err, ErrTooManyRedirects) {
+			t.Errorf("expected ErrTooManyRedirects, got %v", err)
+		}
+	})
+
+	t.Run("rejects redirect to disallowed scheme", func(t *testing.T) {
+		_, err := f.Fetch(ctx, server.URL+"/scheme", 1024)
+		if !errors.Is(err, ErrDisallowedScheme) {
+			t.Errorf("expected ErrDisallowedScheme, got %v", err)
+		}
+	})
+}
+
+// TestFetcherMaxSize verifies both Content-Length and streamed size limits.
+func TestFetcherMaxSize(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/declared", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "4096")
+		w.Write(make([]byte, 4096))
+	})
+	mux.HandleFunc("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		// Flushing forces chunked encoding, so no Content-Length is sent
+		w.Write(make([]byte, 2048))
+		w.(http.Flusher).Flush()
+		w.Write(make([]byte, 2048))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := newURLFetcher(FetchPolicy{AllowPrivateNetworks: true, Timeout: 5 * time.Second})
+
+	for _, path := range []string{"/declared", "/chunked"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := f.Fetch(context.Background(), server.URL+path, 1024)
+			if !errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("expected ErrResponseTooLarge, got %v", err)
+			}
+		})
+	}
+}
+
+// TestFetcherStatus tests that error statuses are reported in a FetchError.
+func TestFetcherStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer server.Close()
+
+	f := newURLFetcher(FetchPolicy{AllowPrivateNetworks: true, Timeout: 5 * time.Second})
+	_, err := f.Fetch(context.Background(), server.URL, 1024)
+
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) || fetchErr.Status != http.StatusGone {
+		t.Fatalf("error = %v, want a FetchError with status 410", err)
+	}
+	if !errors.Is(err, ErrFetchFailed) {
+		t.Errorf("error = %v, want ErrFetchFailed", err)
+	}
+}
+
+// TestIsBlockedIP tests address classification.
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.0.0.5", true},
+		{"172.16.3.4", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"100.100.100.200", true},
+		{"100.127.255.255", true},
+		{"192.0.0.1", true},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"ff02::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"::ffff:100.64.0.1", true},
+		{"::ffff:192.0.0.1", true},
+		{"::ffff:198.18.0.1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"64:ff9b::7f00:1", true},
+		{"64:ff9b::10.0.0.5", true},
+		{"64:ff9b:1::a00:5", true},
+		{"2002:a9fe:a9fe::1", true},
+		{"2002:7f00:1::", true},
+		{"2002:c0a8:101:1::1", true},
+		{"100.63.255.255", false},
+		{"100.128.0.0", false},
+		{"198.17.255.255", false},
+		{"198.20.0.0", false},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::808:808", false},
+		{"2002:808:808::1", false},
+		{"8.8.8.8", false},
+		{"93.184.216.34", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := isBlockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+				t.Errorf("isBlockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+			}
+		})
+	}
+}
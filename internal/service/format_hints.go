@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// =============================================================================
+// Declared Format Hints
+// =============================================================================
+//
+// The media analyzers identify a file's format from its magic bytes. An
+// upload also says what it is, through its filename and the Content-Type
+// it was sent with. Honest tools agree with the bytes; a .png that is really
+// a JPEG, or image/webp over PNG bytes, was renamed or relabeled by hand,
+// sometimes to get a generated file past a filter.
+//
+// A conflict is only a weak signal. The media detectors add it as the
+// "format" detector, scored formatConflictScore at a quarter of a full
+// detector's weight, so it shows among the provider scores and goes
+// through the aggregation strategy and decision policy like the others.
+// Formats that are easily confused without anyone lying (MP4 and MOV, WebM
+// and MKV, M4A and AAC) count as the same.
+//
+// =============================================================================
+
+// formatConflictScore is the "format" signal's score for media whose
+// declared format conflicts with the one detected from its bytes. It leans
+// AI without being sure: people rename files carelessly too.
+const formatConflictScore = 0.7
+
+// formatConflictWeight is the reliability weight of the "format" signal.
+const formatConflictWeight = 0.25
+
+// FormatHints is what a media file claims to be. Either field may be empty.
+type FormatHints struct {
+	// Filename is the upload's name, or the path of the URL it came from
+	Filename string
+
+	// MIMEType is the declared Content-Type, e.g. from a multipart part
+	MIMEType string
+}
+
+// formatTable maps declared extensions and MIME types of one kind of media
+// to the format names its analyzer detects.
+type formatTable struct {
+	extensions map[string]string
+	mimeTypes  map[string]string
+
+	// families maps formats to the one they are compared as
+	families map[string]string
+}
+
+// imageFormats names the image formats ImageAnalyzer detects.
+var imageFormats = formatTable{
+	extensions: map[string]string{
+		".jpg": "jpeg", ".jpeg": "jpeg", ".png": "png", ".gif": "gif", ".webp": "webp", ".bmp": "bmp",
+	},
+	mimeTypes: map[string]string{
+		"image/jpeg": "jpeg", "image/jpg": "jpeg", "image/pjpeg": "jpeg", "image/png": "png",
+		"image/gif": "gif", "image/webp": "webp", "image/bmp": "bmp", "image/x-ms-bmp": "bmp",
+	},
+	families: map[string]string{"webp_lossless": "webp"},
+}
+
+// audioFormats names the audio formats AudioAnalyzer detects.
+var audioFormats = formatTable{
+	extensions: map[string]string{
+		".mp3": "mp3", ".wav": "wav", ".flac": "flac", ".ogg": "ogg", ".oga": "ogg", ".opus": "opus",
+		".m4a": "m4a", ".aac": "aac",
+	},
+	mimeTypes: map[string]string{
+		"audio/mpeg": "mp3", "audio/mp3": "mp3", "audio/wav": "wav", "audio/x-wav": "wav",
+		"audio/wave": "wav", "audio/vnd.wave": "wav", "audio/flac": "flac", "audio/x-flac": "flac",
+		"audio/ogg": "ogg", "audio/opus": "opus", "audio/mp4": "m4a", "audio/m4a": "m4a",
+		"audio/x-m4a": "m4a", "audio/aac": "aac", "audio/x-aac": "aac",
+	},
+	families: map[string]string{"opus": "ogg", "aac": "m4a"},
+}
+
+// videoFormats names the video formats VideoAnalyzer detects.
+var videoFormats = formatTable{
+	extensions: map[string]string{
+		".mp4": "mp4", ".m4v": "mp4", ".mov": "mov", ".webm": "webm", ".mkv": "mkv",
+		".avi": "avi", ".flv": "flv", ".ts": "ts",
+	},
+	mimeTypes: map[string]string{
+		"video/mp4": "mp4", "video/quicktime": "mov", "video/webm": "webm", "video/x-matroska": "mkv",
+		"video/x-msvideo": "avi", "video/avi": "avi", "video/msvideo": "avi", "video/x-flv": "flv",
+		"video/mp2t": "ts",
+	},
+	families: map[string]string{"mov": "mp4", "mkv": "webm"},
+}
+
+// check returns the format h declares, per table, and whether it conflicts
+// with the detected format. The MIME type's format is preferred, unless
+// only the extension's conflicts. Unrecognized hints and undetected
+// formats never conflict.
+func (h FormatHints) check(table formatTable, detected string) (declared string, conflict bool) {
+	byExtension := table.extensions[strings.ToLower(filepath.Ext(h.Filename))]
+	byMIME := table.mimeTypes[normalizeMIMEType(h.MIMEType)]
+	known := detected != "" && detected != "unknown"
+
+	for _, format := range []string{byMIME, byExtension} {
+		if format == "" {
+			continue
+		}
+		if known && table.family(format) != table.family(detected) {
+			return format, true
+		}
+		if declared == "" {
+			declared = format
+		}
+	}
+	return declared, false
+}
+
+// family returns the format format is compared as.
+func (t formatTable) family(format string) string {
+	if family, ok := t.families[format]; ok {
+		return family
+	}
+	return format
+}
+
+// formatHints returns what input says its format is: the upload's filename
+// (or the URL's path) and its declared MIME type.
+func formatHints(input DetectionInput) FormatHints {
+	name := input.Filename
+	if name == "" && !IsDataURI(input.URL) {
+		name, _, _ = strings.Cut(input.URL, "?")
+	}
+	return FormatHints{Filename: name, MIMEType: input.MIMEType}
+}
+
+// formatMismatchWarning describes a conflict between the declared and
+// detected formats, for DetectionResult.MismatchWarning ("" without one).
+func formatMismatchWarning(declared, detected string, conflict bool) string {
+	if !conflict {
+		return ""
+	}
+	return fmt.Sprintf("declared as %s but content is %s", declared, detected)
+}
+
+// normalizeMIMEType lowercases a MIME type and drops its parameters.
+func normalizeMIMEType(mimeType string) string {
+	mime, _, _ := strings.Cut(strings.ToLower(mimeType), ";")
+	return strings.TrimSpace(mime)
+}
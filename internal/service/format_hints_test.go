@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestFormatHintsCheck tests comparing declared formats with detected ones.
+func TestFormatHintsCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		hints        FormatHints
+		table        formatTable
+		detected     string
+		wantDeclared string
+		wantConflict bool
+	}{
+		{"no hints", FormatHints{}, imageFormats, "png", "", false},
+		{"matching extension", FormatHints{Filename: "photo.JPG"}, imageFormats, "jpeg", "jpeg", false},
+		{"matching MIME type", FormatHints{MIMEType: "Image/PNG; charset=binary"}, imageFormats, "png", "png", false},
+		{"conflicting extension", FormatHints{Filename: "photo.png"}, imageFormats, "jpeg", "png", true},
+		{"conflicting MIME type", FormatHints{Filename: "photo.png", MIMEType: "image/webp"}, imageFormats, "png", "webp", true},
+		{"only the extension conflicts", FormatHints{Filename: "photo.gif", MIMEType: "image/png"}, imageFormats, "png", "gif", true},
+		{"lossless WebP", FormatHints{Filename: "art.webp"}, imageFormats, "webp_lossless", "webp", false},
+		{"unrecognized hints", FormatHints{Filename: "upload.bin", MIMEType: "application/octet-stream"}, imageFormats, "png", "", false},
+		{"undetected format", FormatHints{Filename: "photo.png"}, imageFormats, "unknown", "png", false},
+		{"Opus in Ogg", FormatHints{Filename: "voice.ogg"}, audioFormats, "opus", "ogg", false},
+		{"ADTS named .m4a", FormatHints{MIMEType: "audio/x-m4a"}, audioFormats, "aac", "m4a", false},
+		{"WAV sent as MP3", FormatHints{Filename: "song.mp3"}, audioFormats, "wav", "mp3", true},
+		{"MP4 named .mov", FormatHints{Filename: "clip.mov", MIMEType: "video/quicktime"}, videoFormats, "mp4", "mov", false},
+		{"MKV sent as WebM", FormatHints{MIMEType: "video/webm"}, videoFormats, "mkv", "webm", false},
+		{"AVI named .mp4", FormatHints{Filename: "clip.mp4"}, videoFormats, "avi", "mp4", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			declared, conflict := tt.hints.check(tt.table, tt.detected)
+			if declared != tt.wantDeclared || conflict != tt.wantConflict {
+				t.Errorf("check() = %q, %v, want %q, %v", declared, conflict, tt.wantDeclared, tt.wantConflict)
+			}
+		})
+	}
+}
+
+// TestAnalyzeFromReaderWithHints tests that each media analyzer records the
+// declared format and whether it conflicts, without changing the score.
+func TestAnalyzeFromReaderWithHints(t *testing.T) {
+	ctx := context.Background()
+	mp3 := bytes.Repeat(mp3Frame(9, false, nil), 10)
+	mp4 := mp4Atom("ftyp", []byte("isom\x00\x00\x02\x00isomavc1"))
+
+	// analyze returns the AI score, declared format and conflict for data
+	// analyzed with hints
+	type analyzeFunc func(data []byte, hints FormatHints) (float64, string, bool)
+	analyzers := []struct {
+		name        string
+		data        []byte
+		analyze     analyzeFunc
+		consistent  FormatHints
+		conflicting FormatHints
+	}{
+		{
+			name: "image",
+			data: testPNG,
+			analyze: func(data []byte, hints FormatHints) (float64, string, bool) {
+				result, err := NewImageAnalyzer().AnalyzeFromReaderWithHints(ctx, bytes.NewReader(data), hints)
+				if err != nil {
+					t.Fatalf("AnalyzeFromReaderWithHints() error = %v", err)
+				}
+				return result.AIScore, result.Metadata.DeclaredFormat, result.Metadata.FormatConflict
+			},
+			consistent:  FormatHints{Filename: "photo.png", MIMEType: "image/png"},
+			conflicting: FormatHints{Filename: "photo.png", MIMEType: "image/webp"},
+		},
+		{
+			name: "audio",
+			data: mp3,
+			analyze: func(data []byte, hints FormatHints) (float64, string, bool) {
+				result, err := NewAudioAnalyzer().AnalyzeFromReaderWithHints(ctx, bytes.NewReader(data), hints)
+				if err != nil {
+					t.Fatalf("AnalyzeFromReaderWithHints() error = %v", err)
+				}
+				return result.AIScore, result.Metadata.DeclaredFormat, result.Metadata.FormatConflict
+			},
+			consistent:  FormatHints{Filename: "song.mp3", MIMEType: "audio/mpeg"},
+			conflicting: FormatHints{Filename: "song.wav"},
+		},
+		{
+			name: "video",
+			data: mp4,
+			analyze: func(data []byte, hints FormatHints) (float64, string, bool) {
+				result, err := NewVideoAnalyzer().AnalyzeFromReaderWithHints(ctx, bytes.NewReader(data), hints)
+				if err != nil {
+					t.Fatalf("AnalyzeFromReaderWithHints() error = %v", err)
+				}
+				return result.AIScore, result.Metadata.DeclaredFormat, result.Metadata.FormatConflict
+			},
+			consistent:  FormatHints{Filename: "clip.mp4", MIMEType: "video/mp4"},
+			conflicting: FormatHints{MIMEType: "video/x-msvideo"},
+		},
+	}
+
+	for _, a := range analyzers {
+		t.Run(a.name, func(t *testing.T) {
+			base, declared, conflict := a.analyze(a.data, FormatHints{})
+			if declared != "" || conflict {
+				t.Errorf("without hints: declared %q, conflict %v", declared, conflict)
+			}
+
+			score, declared, conflict := a.analyze(a.data, a.consistent)
+			if declared == "" || conflict || score != base {
+				t.Errorf("consistent hints: declared %q, conflict %v, score %v (want %v)", declared, conflict, score, base)
+			}
+
+			score, declared, conflict = a.analyze(a.data, a.conflicting)
+			if declared == "" || !conflict || score != base {
+				t.Errorf("conflicting hints: declared %q, conflict %v, score %v (want %v)", declared, conflict, score, base)
+			}
+		})
+	}
+}
+
+// TestDetectFormatSignal tests that a format conflict reaches the result as
+// the weighted "format" signal, which the aggregation and verdict take in.
+func TestDetectFormatSignal(t *testing.T) {
+	detector, err := NewDetector(DetectorConfig{Timeout: 30 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector failed: %v", err)
+	}
+	ctx := context.Background()
+	mp3 := bytes.Repeat(mp3Frame(9, false, nil), 10)
+	mp4 := mp4Atom("ftyp", []byte("isom\x00\x00\x02\x00isomavc1"))
+
+	inputs := map[string]DetectionInput{
+		"image": {Data: testPNG, Filename: "photo.png", MIMEType: "image/webp"},
+		"audio": {Data: mp3, Filename: "song.wav"},
+		"video": {Data: mp4, MIMEType: "video/x-msvideo"},
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			result, err := detector.Detect(ctx, input)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			scores := make(map[string]ProviderScore)
+			for _, p := range result.ProviderScores {
+				scores[p.Name] = p
+			}
+			format, ok := scores["format"]
+			if !ok || format.AIScore != formatConflictScore || format.Weight != formatConflictWeight {
+				t.Fatalf("provider scores = %+v, want a format signal", result.ProviderScores)
+			}
+			local := scores["humanmark"].AIScore
+			want := (local + formatConflictScore*formatConflictWeight) / (1 + formatConflictWeight)
+			if math.Abs(result.AIScore-want) > 1e-9 {
+				t.Errorf("AIScore = %v, want the weighted mean %v", result.AIScore, want)
+			}
+			if result.Verdict != DefaultDecisionPolicy().Verdict(result.AIScore) {
+				t.Errorf("verdict = %s for score %v", result.Verdict, result.AIScore)
+			}
+
+			input.ExcludeDetectors = []string{"format"}
+			result, err = detector.Detect(ctx, input)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if len(result.ProviderScores) != 1 || result.AIScore != local {
+				t.Errorf("excluded format signal: provider scores = %+v, AIScore %v", result.ProviderScores, result.AIScore)
+			}
+		})
+	}
+}
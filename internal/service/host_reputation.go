@@ -0,0 +1,167 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// URL Host Reputation
+// =============================================================================
+//
+// For URL inputs the host is evidence in itself: a file on cdn.midjourney.com
+// was almost certainly generated, while a Google Drive link says nothing.
+// Matching hosts nudge the aggregated score by a bounded amount, so the
+// content still decides.
+//
+// A rule matches its host and every subdomain of it ("oaiusercontent.com"
+// matches "files.oaiusercontent.com" but not "myoaiusercontent.com"). The
+// most specific rule wins, so a neutral rule can carve a subdomain out of a
+// broader one.
+//
+// Only the URL the caller submitted is checked. Redirect targets aren't, or
+// an open redirect on a listed host could be used to move anyone's score.
+//
+// =============================================================================
+
+//go:embed data/host_reputation.json
+var defaultHostRulesJSON []byte
+
+// MaxHostAdjustment bounds how far a host rule can move the AI score.
+const MaxHostAdjustment = 0.3
+
+// HostRule is one entry in the host reputation table.
+type HostRule struct {
+	// Host is matched against the URL's hostname and its parent domains
+	Host string `json:"host"`
+
+	// Adjustment is added to the aggregated AI score
+	// (-MaxHostAdjustment to MaxHostAdjustment; 0 marks a neutral host)
+	Adjustment float64 `json:"adjustment"`
+
+	// Reason explains the rule in results
+	Reason string `json:"reason"`
+}
+
+// HostReputationMatch records the rule that matched a URL input.
+type HostReputationMatch struct {
+	// Host is the hostname of the submitted URL
+	Host string
+
+	// Rule is the host of the matching rule
+	Rule string
+
+	// Adjustment is the amount added to the AI score
+	Adjustment float64
+
+	// Reason is the rule's explanation
+	Reason string
+}
+
+// DefaultHostRules is the host reputation table used when no overrides are
+// configured. Loaded from data/host_reputation.json at init.
+var DefaultHostRules []HostRule
+
+func init() {
+	rules, err := ParseHostRules(defaultHostRulesJSON)
+	if err != nil {
+		panic("service: invalid embedded host reputation table: " + err.Error())
+	}
+	DefaultHostRules = rules
+}
+
+// LoadHostRules reads host rules in the data/host_reputation.json format
+// from path.
+func LoadHostRules(path string) ([]HostRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseHostRules(data)
+}
+
+// ParseHostRules parses and validates host rules.
+// Hosts are lowercased and stripped of a leading "." or "*.".
+func ParseHostRules(data []byte) ([]HostRule, error) {
+	var rules []HostRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse host rules: %w", err)
+	}
+
+	for i, r := range rules {
+		host := strings.ToLower(strings.TrimSpace(r.Host))
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "*"), ".")
+		if host == "" {
+			return nil, fmt.Errorf("host rule %d: empty host", i)
+		}
+		if r.Adjustment < -MaxHostAdjustment || r.Adjustment > MaxHostAdjustment {
+			return nil, fmt.Errorf("host rule %q: adjustment %g must be between %g and %g",
+				host, r.Adjustment, -MaxHostAdjustment, MaxHostAdjustment)
+		}
+		rules[i].Host = host
+	}
+
+	return rules, nil
+}
+
+// hostReputation is a host reputation table indexed by host.
+type hostReputation map[string]HostRule
+
+// newHostReputation builds the table from the defaults, with overrides
+// replacing default rules for the same host.
+func newHostReputation(defaults, overrides []HostRule) hostReputation {
+	table := make(hostReputation, len(defaults)+len(overrides))
+	for _, r := range defaults {
+		table[r.Host] = r
+	}
+	for _, r := range overrides {
+		table[r.Host] = r
+	}
+	return table
+}
+
+// rules returns the table's rules sorted by host.
+func (t hostReputation) rules() []HostRule {
+	rules := make([]HostRule, 0, len(t))
+	for _, r := range t {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Host < rules[j].Host })
+	return rules
+}
+
+// match returns the most specific rule for rawURL's host.
+func (t hostReputation) match(rawURL string) (*HostReputationMatch, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return nil, false
+	}
+
+	for domain := host; domain != ""; {
+		if r, ok := t[domain]; ok {
+			return &HostReputationMatch{
+				Host:       host,
+				Rule:       r.Host,
+				Adjustment: r.Adjustment,
+				Reason:     r.Reason,
+			}, true
+		}
+
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+
+	return nil, false
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestDefaultHostRules verifies the embedded table is loaded at init.
+func TestDefaultHostRules(t *testing.T) {
+	table := newHostReputation(DefaultHostRules, nil)
+	for _, host := range []string{"cdn.midjourney.com", "replicate.delivery", "oaiusercontent.com", "drive.google.com"} {
+		if _, ok := table[host]; !ok {
+			t.Errorf("expected a default rule for %s", host)
+		}
+	}
+}
+
+// TestHostReputationMatch tests host and subdomain matching.
+func TestHostReputationMatch(t *testing.T) {
+	table := newHostReputation([]HostRule{
+		{Host: "oaiusercontent.com", Adjustment: 0.25, Reason: "generated files"},
+		{Host: "cdn.example.com", Adjustment: 0.2, Reason: "generator CDN"},
+		{Host: "uploads.cdn.example.com", Adjustment: 0, Reason: "user uploads"},
+		{Host: "drive.google.com", Adjustment: 0, Reason: "file sharing"},
+	}, nil)
+
+	tests := []struct {
+		name     string
+		url      string
+		wantRule string
+	}{
+		{"exact host", "https://oaiusercontent.com/file", "oaiusercontent.com"},
+		{"subdomain", "https://files.oaiusercontent.com/file-abc?se=2025", "oaiusercontent.com"},
+		{"deep subdomain", "https://a.b.oaiusercontent.com/x", "oaiusercontent.com"},
+		{"case and trailing dot", "https://FILES.OaiUserContent.com./x", "oaiusercontent.com"},
+		{"with port", "https://cdn.example.com:8443/img.png", "cdn.example.com"},
+		{"most specific rule wins", "https://uploads.cdn.example.com/a.png", "uploads.cdn.example.com"},
+		{"neutral host", "https://drive.google.com/file/d/abc/view", "drive.google.com"},
+		{"suffix without dot boundary", "https://myoaiusercontent.com/file", ""},
+		{"parent of rule", "https://example.com/img.png", ""},
+		{"sibling of rule", "https://docs.google.com/d/abc", ""},
+		{"unrelated host", "https://photos.example.org/a.jpg", ""},
+		{"not a URL", "://", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, ok := table.match(tt.url)
+			if tt.wantRule == "" {
+				if ok {
+					t.Errorf("match(%q) = %+v, want no match", tt.url, match)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("match(%q) found no rule, want %s", tt.url, tt.wantRule)
+			}
+			if match.Rule != tt.wantRule {
+				t.Errorf("Rule = %q, want %q", match.Rule, tt.wantRule)
+			}
+		})
+	}
+
+	t.Run("overrides replace defaults", func(t *testing.T) {
+		overridden := newHostReputation(
+			[]HostRule{{Host: "cdn.example.com", Adjustment: 0.2}},
+			[]HostRule{{Host: "cdn.example.com", Adjustment: -0.1, Reason: "our own CDN"}},
+		)
+		match, ok := overridden.match("https://cdn.example.com/a.png")
+		if !ok || match.Adjustment != -0.1 {
+			t.Errorf("match = %+v, want the override", match)
+		}
+	})
+}
+
+// TestParseHostRules tests host rule validation.
+func TestParseHostRules(t *testing.T) {
+	rules, err := ParseHostRules([]byte(`[{"host": " *.CDN.Example.com ", "adjustment": 0.1, "reason": "test"}]`))
+	if err != nil {
+		t.Fatalf("ParseHostRules() error = %v", err)
+	}
+	if rules[0].Host != "cdn.example.com" {
+		t.Errorf("Host = %q, want cdn.example.com", rules[0].Host)
+	}
+
+	for _, bad := range []string{
+		`[{"host": "", "adjustment": 0.1}]`,
+		`[{"host": "example.com", "adjustment": 0.5}]`,
+		`[{"host": "example.com", "adjustment": -0.31}]`,
+		`{"host": "example.com"}`,
+	} {
+		if _, err := ParseHostRules([]byte(bad)); err == nil {
+			t.Errorf("ParseHostRules(%s) should fail", bad)
+		}
+	}
+}
+
+// TestDetectHostReputation tests that the submitted URL's host adjusts the
+// score and that redirect targets are ignored.
+func TestDetectHostReputation(t *testing.T) {
+	text := "We went to the lake on Saturday and the dog would not stop barking at the ducks."
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/note", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(text))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The same server under another name, reachable only by redirect
+	redirectTarget := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, redirectTarget+"/note", http.StatusFound)
+	})
+
+	config := DetectorConfig{
+		Timeout:              5 * time.Second,
+		AllowPrivateNetworks: true, // httptest listens on loopback
+		HostRules: []HostRule{
+			{Host: "127.0.0.1", Adjustment: 0.2, Reason: "test generator"},
+			{Host: "localhost", Adjustment: 0.3, Reason: "redirect target"},
+		},
+	}
+	detector, err := NewDetector(config, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+
+	base, err := detector.Detect(context.Background(), DetectionInput{Text: text})
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if base.HostReputation != nil {
+		t.Errorf("HostReputation = %+v for text input, want nil", base.HostReputation)
+	}
+
+	for _, path := range []string{"/note", "/redirect"} {
+		t.Run(path, func(t *testing.T) {
+			result, err := detector.Detect(context.Background(), DetectionInput{URL: server.URL + path})
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if result.HostReputation == nil || result.HostReputation.Rule != "127.0.0.1" {
+				t.Fatalf("HostReputation = %+v, want the 127.0.0.1 rule", result.HostReputation)
+			}
+			if want := clampScore(base.AIScore + 0.2); abs(result.AIScore-want) > 1e-9 {
+				t.Errorf("AIScore = %f, want %f", result.AIScore, want)
+			}
+			if want := abs(result.AIScore-0.5) * 2; abs(result.Confidence-want) > 1e-9 {
+				t.Errorf("Confidence = %f doesn't follow the adjusted score %f", result.Confidence, result.AIScore)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
+	"io"
 	"math"
 	"strings"
 )
@@ -30,7 +33,8 @@ import (
 //
 // =============================================================================
 
-// ImageAnalyzer performs forensic analysis on images.
+// ImageAnalyzer performs forensic analysis on images. It is safe for
+// concurrent use.
 type ImageAnalyzer struct {
 	weights ImageAnalyzerWeights
 }
@@ -59,8 +63,11 @@ func DefaultImageWeights() ImageAnalyzerWeights {
 
 // NewImageAnalyzer creates a new analyzer.
 func NewImageAnalyzer() *ImageAnalyzer {
+	weights := DefaultImageWeights()
+	mustValidateWeights(weights)
+
 	return &ImageAnalyzer{
-		weights: DefaultImageWeights(),
+		weights: weights,
 	}
 }
 
@@ -80,6 +87,8 @@ type ImageSignals struct {
 	NoisePattern        float64 // Missing natural noise = AI-like
 	CompressionAnalysis float64 // Wrong compression = AI-like
 	SymmetryScore       float64 // Unnatural symmetry = AI-like
+	WebPLosslessScore   float64 // Flat green prefix code = AI-like (lossless WebP only)
+	NoiseFloor          float64 // No noise floor = AI-like (JPEG only)
 }
 
 // ImageMetadata contains extracted metadata.
@@ -91,7 +100,14 @@ type ImageMetadata struct {
 	DateTaken    string
 	HasGPS       bool
 	IsScreenshot bool
-	FileFormat   string
+	FileFormat   string // Detected from the magic bytes
+
+	// DeclaredFormat is the format the filename or MIME type claimed
+	// (empty without a recognizable hint)
+	DeclaredFormat string
+
+	// FormatConflict is set when a hint disagrees with FileFormat
+	FormatConflict bool
 }
 
 // ImageStats contains image statistics.
@@ -105,31 +121,96 @@ type ImageStats struct {
 	Contrast        float64
 }
 
+// imageHeaderSize is how much of an image is buffered for format and
+// metadata detection. Covers a maximum-size JPEG APP1 (EXIF) segment.
+const imageHeaderSize = 256 * 1024
+
 // Analyze performs forensic analysis on image data.
 func (a *ImageAnalyzer) Analyze(data []byte) ImageAnalysisResult {
-	result := ImageAnalysisResult{}
+	// Reading from memory can't fail
+	result, _ := a.AnalyzeFromReader(bytes.NewReader(data))
+	return result
+}
+
+// AnalyzeFromReader performs forensic analysis on an image read from r.
+// The first imageHeaderSize bytes are buffered; the rest is streamed in
+// chunks for pattern analysis.
+func (a *ImageAnalyzer) AnalyzeFromReader(r io.Reader) (ImageAnalysisResult, error) {
+	return a.AnalyzeFromReaderContext(context.Background(), r)
+}
+
+// AnalyzeFromReaderContext is AnalyzeFromReader, stopping with ctx's error
+// if ctx is cancelled while the input is being read.
+func (a *ImageAnalyzer) AnalyzeFromReaderContext(ctx context.Context, r io.Reader) (ImageAnalysisResult, error) {
+	return a.AnalyzeFromReaderWithHints(ctx, r, FormatHints{})
+}
+
+// AnalyzeFromReaderWithHints is AnalyzeFromReaderContext for image that
+// claims a format through hints. Metadata records the declared format and
+// whether it conflicts with the detected one; the score is unchanged, the
+// detector weighs a conflict as a signal of its own.
+func (a *ImageAnalyzer) AnalyzeFromReaderWithHints(ctx context.Context, r io.Reader, hints FormatHints) (ImageAnalysisResult, error) {
+	s, err := readMediaSample(ctx, r, imageHeaderSize, nil)
+	if err != nil {
+		return ImageAnalysisResult{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return ImageAnalysisResult{}, err
+	}
+
+	result := a.analyze(s)
+	result.Metadata.DeclaredFormat, result.Metadata.FormatConflict = hints.check(imageFormats, result.Metadata.FileFormat)
+	return result, nil
+}
 
-	// Detect format
-	format := detectImageFormat(data)
+// analyze runs the analysis on a buffered header plus streamed samples.
+func (a *ImageAnalyzer) analyze(s *mediaSample) ImageAnalysisResult {
+	return a.analyzeAs(s, detectImageFormat(s.header))
+}
+
+// analyzeAs is analyze with the format given rather than detected, for
+// data that isn't a complete image file.
+func (a *ImageAnalyzer) analyzeAs(s *mediaSample, format string) ImageAnalysisResult {
+	result := ImageAnalysisResult{}
+	header := s.header
 	result.Metadata.FileFormat = format
 
 	// Extract metadata
-	result.Metadata = a.extractMetadata(data, format)
+	result.Metadata = a.extractMetadata(header, format)
 
 	// Get basic image stats
-	result.Stats = a.getImageStats(data, format)
+	result.Stats = a.getImageStats(header, format)
 
 	// Calculate signals
 	result.Signals.MetadataScore = a.analyzeMetadata(result.Metadata)
-	result.Signals.ColorDistribution = a.analyzeColorDistribution(data, format)
-	result.Signals.EdgeConsistency = a.analyzeEdgeConsistency(data, format)
-	result.Signals.NoisePattern = a.analyzeNoisePattern(data, format)
-	result.Signals.CompressionAnalysis = a.analyzeCompression(data, format)
-	result.Signals.SymmetryScore = a.analyzeSymmetry(data, format)
+	result.Signals.ColorDistribution = a.analyzeColorDistribution(header, format)
+	result.Signals.EdgeConsistency = a.analyzeEdgeConsistency(s, format)
+	result.Signals.NoisePattern = a.analyzeNoisePattern(header, format)
+	if format == "jpeg" {
+		// The noise floor is measured in the scan data rather than anywhere
+		// in the file, so it refines the pattern score
+		result.Signals.NoiseFloor = a.analyzeNoiseFloor(header)
+		result.Signals.NoisePattern = (result.Signals.NoisePattern + result.Signals.NoiseFloor) / 2
+	}
+	result.Signals.CompressionAnalysis = a.analyzeCompression(header, format)
+	result.Signals.SymmetryScore = a.analyzeSymmetry(s, format)
+
+	// Lossless WebP has no quantization tables; its prefix codes stand in
+	// for compression analysis
+	if format == "webp_lossless" {
+		result.Signals.WebPLosslessScore = a.analyzeWebPLossless(header)
+		result.Signals.CompressionAnalysis = result.Signals.WebPLosslessScore
+	}
 
 	// Calculate weighted score
 	result.AIScore = a.calculateWeightedScore(result.Signals)
 
+	// Generators output a few fixed sizes, which survive in an uncompressed
+	// BMP. Cameras and screenshots rarely match them exactly.
+	if format == "bmp" && isAIOutputSize(result.Stats.Width, result.Stats.Height) {
+		result.AIScore = math.Min(1, result.AIScore+bmpAIOutputSizeBoost)
+	}
+
 	return result
 }
 
@@ -144,6 +225,8 @@ func (a *ImageAnalyzer) extractMetadata(data []byte, format string) ImageMetadat
 		meta = a.parseJPEGMetadata(data)
 	case "png":
 		meta = a.parsePNGMetadata(data)
+	case "bmp":
+		meta, _ = a.analyzeBMP(data)
 	}
 
 	return meta
@@ -260,6 +343,10 @@ func (a *ImageAnalyzer) getImageStats(data []byte, format string) ImageStats {
 		stats = a.getJPEGStats(data)
 	case "png":
 		stats = a.getPNGStats(data)
+	case "bmp":
+		_, stats = a.analyzeBMP(data)
+	case "webp_lossless":
+		stats = webpLosslessStats(data)
 	}
 
 	return stats
@@ -301,14 +388,77 @@ func (a *ImageAnalyzer) getPNGStats(data []byte) ImageStats {
 	return stats
 }
 
+// bmpAIOutputSizeBoost is added to the AI score of BMPs with a common
+// generator output size.
+const bmpAIOutputSizeBoost = 0.05
+
+// aiOutputSizes are the dimensions image generators commonly output.
+var aiOutputSizes = [][2]int{{512, 512}, {1024, 1024}, {768, 512}}
+
+// isAIOutputSize reports whether width x height is a common generator
+// output size.
+func isAIOutputSize(width, height int) bool {
+	for _, size := range aiOutputSizes {
+		if width == size[0] && height == size[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeBMP reads the DIB header of a BMP. The header follows the 14-byte
+// file header and is either a BITMAPCOREHEADER (12 bytes, 16-bit sizes) or
+// a BITMAPINFOHEADER or later (40+ bytes, 32-bit sizes). BMPs carry no EXIF.
+func (a *ImageAnalyzer) analyzeBMP(data []byte) (ImageMetadata, ImageStats) {
+	meta := ImageMetadata{FileFormat: "bmp"}
+	stats := ImageStats{}
+
+	if len(data) < 18 {
+		return meta, stats
+	}
+
+	var bitCount int
+	switch headerSize := binary.LittleEndian.Uint32(data[14:18]); {
+	case headerSize == 12 && len(data) >= 26:
+		stats.Width = int(binary.LittleEndian.Uint16(data[18:20]))
+		stats.Height = int(binary.LittleEndian.Uint16(data[20:22]))
+		bitCount = int(binary.LittleEndian.Uint16(data[24:26]))
+	case headerSize >= 40 && len(data) >= 34:
+		stats.Width = int(int32(binary.LittleEndian.Uint32(data[18:22])))
+		stats.Height = int(int32(binary.LittleEndian.Uint32(data[22:26])))
+		bitCount = int(binary.LittleEndian.Uint16(data[28:30]))
+	default:
+		return meta, stats
+	}
+
+	// A negative height marks a top-down bitmap
+	if stats.Height < 0 {
+		stats.Height = -stats.Height
+	}
+
+	stats.BitDepth = bitCount
+	switch {
+	case bitCount == 32:
+		stats.ColorChannels = 4
+	case bitCount == 16 || bitCount == 24:
+		stats.ColorChannels = 3
+	case bitCount > 0:
+		// 1-8 bits per pixel index a palette
+		stats.ColorChannels = 1
+	}
+
+	return meta, stats
+}
+
 // analyzeMetadata scores based on metadata presence.
 func (a *ImageAnalyzer) analyzeMetadata(meta ImageMetadata) float64 {
 	score := 0.5 // Start neutral
 
-	// Real photos typically have EXIF data
+	// Real photos typically have EXIF data; screenshots never do, so its
+	// absence says nothing about them
 	if meta.HasEXIF {
 		score -= 0.2
-	} else {
+	} else if !meta.IsScreenshot {
 		score += 0.2
 	}
 
@@ -387,11 +537,11 @@ func (a *ImageAnalyzer) analyzeColorDistribution(data []byte, format string) flo
 
 // analyzeEdgeConsistency looks for edge artifacts.
 // AI images often have inconsistent edges.
-func (a *ImageAnalyzer) analyzeEdgeConsistency(data []byte, format string) float64 {
+func (a *ImageAnalyzer) analyzeEdgeConsistency(s *mediaSample, format string) float64 {
 	// Simplified analysis - check for repeated patterns
 	// AI sometimes has repeated textures or edge patterns
 
-	if len(data) < 5000 {
+	if s.Len() < 5000 {
 		return 0.5
 	}
 
@@ -401,8 +551,8 @@ func (a *ImageAnalyzer) analyzeEdgeConsistency(data []byte, format string) float
 	sampleSize := 256
 	samples := make([][]byte, 0)
 
-	for i := 1000; i < len(data)-sampleSize && len(samples) < 10; i += len(data) / 12 {
-		samples = append(samples, data[i:i+sampleSize])
+	for i := int64(1000); i < s.Len()-int64(sampleSize) && len(samples) < 10; i += s.Len() / 12 {
+		samples = append(samples, s.Region(i, sampleSize))
 	}
 
 	if len(samples) < 3 {
@@ -522,6 +672,79 @@ func (a *ImageAnalyzer) analyzeNoisePattern(data []byte, format string) float64
 	return 0.4
 }
 
+// analyzeNoiseFloor measures the noise floor of a JPEG as the average
+// absolute difference between adjacent bytes of its scan data (0-255).
+// Cameras always leave some sensor noise; rendered images can be almost
+// perfectly smooth.
+//
+//	< 2    too perfect, AI-like
+//	3-15   natural photography
+//	> 15   heavy compression or noise, which says nothing about origin
+func (a *ImageAnalyzer) analyzeNoiseFloor(data []byte) float64 {
+	scan := jpegScanData(data)
+	if len(scan) < 64 {
+		return 0.5
+	}
+
+	total := 0
+	for i := 1; i < len(scan); i++ {
+		diff := int(scan[i]) - int(scan[i-1])
+		if diff < 0 {
+			diff = -diff
+		}
+		total += diff
+	}
+	deviation := float64(total) / float64(len(scan)-1)
+
+	switch {
+	case deviation < 2:
+		return 0.8
+	case deviation < 3:
+		// Ease from too perfect into the natural range
+		return 0.8 - 0.5*(deviation-2)
+	case deviation <= 15:
+		return 0.3
+	default:
+		return 0.5
+	}
+}
+
+// jpegScanData returns the entropy-coded data after the first SOS marker,
+// up to EOI or the end of data. Nil if there is no scan.
+func jpegScanData(data []byte) []byte {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			i++ // Fill byte
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			i += 2 // No length
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if marker != 0xDA {
+			i += 2 + length
+			continue
+		}
+
+		start := i + 2 + length
+		if start >= len(data) {
+			return nil
+		}
+		scan := data[start:]
+		if end := bytes.Index(scan, []byte{0xFF, 0xD9}); end >= 0 {
+			scan = scan[:end]
+		}
+		return scan
+	}
+	return nil
+}
+
 // analyzeCompression checks compression artifacts.
 // Real JPEGs have natural compression; AI-generated may have artifacts.
 func (a *ImageAnalyzer) analyzeCompression(data []byte, format string) float64 {
@@ -563,26 +786,26 @@ func (a *ImageAnalyzer) analyzeCompression(data []byte, format string) float64 {
 
 // analyzeSymmetry detects unnatural symmetry.
 // AI images sometimes have artifacts related to symmetry.
-func (a *ImageAnalyzer) analyzeSymmetry(data []byte, format string) float64 {
+func (a *ImageAnalyzer) analyzeSymmetry(s *mediaSample, format string) float64 {
 	// This is a simplified check
 	// Full symmetry analysis would require decoding the image
 
-	if len(data) < 2000 {
+	if s.Len() < 2000 {
 		return 0.5
 	}
 
 	// Compare first and second halves of data as a rough proxy
 	// (Not actual image symmetry, but can catch some patterns)
 
-	mid := len(data) / 2
+	mid := s.Len() / 2
 	sampleSize := 500
 
-	if mid+sampleSize > len(data) {
+	if mid+int64(sampleSize) > s.Len() {
 		return 0.5
 	}
 
-	sample1 := data[100:600]                // Near start
-	sample2 := data[mid : mid+sampleSize] // Near middle
+	sample1 := s.header[100:600]         // Near start
+	sample2 := s.Region(mid, sampleSize) // Near middle
 
 	// Check if suspiciously similar
 	similarity := byteSimilarity(sample1, sample2)
@@ -636,10 +859,13 @@ func detectImageFormat(data []byte) string {
 		return "gif"
 	}
 
-	// WebP: 52 49 46 46 ... 57 45 42 50
+	// WebP: 52 49 46 46 ... 57 45 42 50, lossless if it has a VP8L chunk
 	if len(data) >= 12 {
 		if data[0] == 0x52 && data[1] == 0x49 && data[2] == 0x46 && data[3] == 0x46 {
 			if data[8] == 0x57 && data[9] == 0x45 && data[10] == 0x42 && data[11] == 0x50 {
+				if findWebPChunk(data, "VP8L") != nil {
+					return "webp_lossless"
+				}
 				return "webp"
 			}
 		}
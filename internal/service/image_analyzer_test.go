@@ -1,6 +1,10 @@
 package service
 
 import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -96,6 +100,144 @@ func TestImageAnalyzer(t *testing.T) {
 	})
 }
 
+// bmpFile builds a BMP with a BITMAPINFOHEADER and a zeroed pixel array.
+func bmpFile(width, height int32, bitCount uint16) []byte {
+	data := make([]byte, 54+1000)
+	data[0], data[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(data[2:6], uint32(len(data)))
+	binary.LittleEndian.PutUint32(data[10:14], 54) // Pixel data offset
+	binary.LittleEndian.PutUint32(data[14:18], 40) // BITMAPINFOHEADER
+	binary.LittleEndian.PutUint32(data[18:22], uint32(width))
+	binary.LittleEndian.PutUint32(data[22:26], uint32(height))
+	binary.LittleEndian.PutUint16(data[26:28], 1) // Planes
+	binary.LittleEndian.PutUint16(data[28:30], bitCount)
+	return data
+}
+
+// TestAnalyzeBMP tests BMP header parsing and the output size signal.
+func TestAnalyzeBMP(t *testing.T) {
+	analyzer := NewImageAnalyzer()
+
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height int
+		bitDepth      int
+		channels      int
+	}{
+		{"24-bit", bmpFile(640, 480, 24), 640, 480, 24, 3},
+		{"32-bit top-down", bmpFile(800, -600, 32), 800, 600, 32, 4},
+		{"8-bit palette", bmpFile(16, 16, 8), 16, 16, 8, 1},
+		{
+			name: "core header",
+			data: func() []byte {
+				data := make([]byte, 26+100)
+				data[0], data[1] = 'B', 'M'
+				binary.LittleEndian.PutUint32(data[14:18], 12)
+				binary.LittleEndian.PutUint16(data[18:20], 320)
+				binary.LittleEndian.PutUint16(data[20:22], 200)
+				binary.LittleEndian.PutUint16(data[24:26], 24)
+				return data
+			}(),
+			width: 320, height: 200, bitDepth: 24, channels: 3,
+		},
+		{"truncated", []byte{'B', 'M', 0, 0, 0, 0, 0, 0, 0, 0}, 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := analyzer.Analyze(tt.data)
+
+			if result.Metadata.FileFormat != "bmp" {
+				t.Errorf("FileFormat = %s, want bmp", result.Metadata.FileFormat)
+			}
+			if result.Metadata.HasEXIF {
+				t.Error("BMP should have no EXIF")
+			}
+			s := result.Stats
+			if s.Width != tt.width || s.Height != tt.height || s.BitDepth != tt.bitDepth || s.ColorChannels != tt.channels {
+				t.Errorf("Stats = %dx%d, %d bits, %d channels; want %dx%d, %d bits, %d channels",
+					s.Width, s.Height, s.BitDepth, s.ColorChannels, tt.width, tt.height, tt.bitDepth, tt.channels)
+			}
+		})
+	}
+
+	t.Run("common AI output size", func(t *testing.T) {
+		for _, size := range [][2]int32{{512, 512}, {1024, 1024}, {768, 512}} {
+			// Same bytes apart from the dimensions
+			other := analyzer.Analyze(bmpFile(size[0]+1, size[1], 24)).AIScore
+			got := analyzer.Analyze(bmpFile(size[0], size[1], 24)).AIScore
+			if diff := got - other; diff < bmpAIOutputSizeBoost-0.02 {
+				t.Errorf("%dx%d: AI score %f, want about %f more than %f", size[0], size[1], got, bmpAIOutputSizeBoost, other)
+			}
+		}
+	})
+}
+
+// jpegWithScan builds a JPEG with a quantization table and one scan
+// holding the given entropy-coded bytes.
+func jpegWithScan(scan []byte) []byte {
+	data := []byte{0xFF, 0xD8}
+	data = append(data, 0xFF, 0xDB, 0x00, 0x43, 0x00)
+	data = append(data, bytes.Repeat([]byte{0x10}, 64)...)
+	data = append(data, 0xFF, 0xDA, 0x00, 0x08, 0x01, 0x01, 0x00, 0x00, 0x3F, 0x00)
+	data = append(data, scan...)
+	return append(data, 0xFF, 0xD9)
+}
+
+// TestAnalyzeNoiseFloor tests scoring adjacent-byte deviation in JPEG scan data.
+func TestAnalyzeNoiseFloor(t *testing.T) {
+	analyzer := NewImageAnalyzer()
+
+	// Adjacent bytes differ by the given steps in turn
+	stepping := func(steps ...byte) []byte {
+		scan := make([]byte, 4096)
+		for i := range scan {
+			scan[i] = 100 + steps[i%len(steps)]
+		}
+		return scan
+	}
+	noisy := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(noisy)
+	for i := range noisy {
+		if noisy[i] == 0xFF {
+			noisy[i] = 0xFE // Keep clear of markers
+		}
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want float64
+	}{
+		{"flat", jpegWithScan(bytes.Repeat([]byte{0x80}, 4096)), 0.8},
+		{"barely varying", jpegWithScan(stepping(0, 1)), 0.8},
+		{"between", jpegWithScan(stepping(0, 2, 5, 2)), 0.55}, // 2.5 on average
+		{"natural noise", jpegWithScan(stepping(0, 8)), 0.3},
+		{"heavy compression", jpegWithScan(noisy), 0.5},
+		{"no scan", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00}, 0.5},
+		{"short scan", jpegWithScan(make([]byte, 10)), 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzer.analyzeNoiseFloor(tt.data); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("analyzeNoiseFloor() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("refines the noise pattern", func(t *testing.T) {
+		result := analyzer.Analyze(jpegWithScan(bytes.Repeat([]byte{0x80}, 4096)))
+		if result.Signals.NoiseFloor != 0.8 {
+			t.Errorf("NoiseFloor = %f, want 0.8", result.Signals.NoiseFloor)
+		}
+		if result.Signals.NoisePattern < 0.7 {
+			t.Errorf("NoisePattern = %f, want a flat scan to read as too clean", result.Signals.NoisePattern)
+		}
+	})
+}
+
 // TestDetectImageFormat tests format detection from magic bytes.
 func TestDetectImageFormat(t *testing.T) {
 	tests := []struct {
@@ -123,6 +265,11 @@ func TestDetectImageFormat(t *testing.T) {
 			data:     []byte{0x52, 0x49, 0x46, 0x46, 0x00, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50},
 			expected: "webp",
 		},
+		{
+			name:     "WebP lossless",
+			data:     []byte{0x52, 0x49, 0x46, 0x46, 0x00, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50, 0x56, 0x50, 0x38, 0x4C, 0x00, 0x00, 0x00, 0x00},
+			expected: "webp_lossless",
+		},
 		{
 			name:     "BMP",
 			data:     []byte{0x42, 0x4D, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// =============================================================================
+// External Image Providers
+// =============================================================================
+//
+// Each provider maps its own response format to our 0-1 AI score and
+// translates its quota and auth failures into ErrProviderQuota and
+// ErrProviderAuth. Network failures and 5xx responses wrap
+// ErrProviderUnavailable. Providers only run when their API key is
+// configured.
+//
+// To add a provider: write a detectWith* method and register it in
+// imageProviders with a default weight.
+//
+// =============================================================================
+
+// Provider errors
+var (
+	ErrProviderQuota       = errors.New("provider quota exceeded")
+	ErrProviderAuth        = errors.New("provider rejected credentials")
+	ErrProviderSkipped     = errors.New("provider skipped")
+	ErrProviderUnavailable = errors.New("provider unavailable")
+)
+
+// imageProvider is an external image detection backend.
+type imageProvider struct {
+	// name is reported in DetectionResult.Detectors
+	name string
+
+	// weight is the default reliability weight for aggregation
+	weight float64
+
+	// enabled reports whether the provider is configured
+	enabled func(config DetectorConfig) bool
+
+	// detect returns the provider's AI probability (0.0-1.0)
+	detect func(d *imageDetector, ctx context.Context, imageData []byte) (float64, error)
+}
+
+// imageProviders is the registry of external image backends, in call order.
+var imageProviders = []imageProvider{
+	{
+		name:    "hive",
+		weight:  1.3, // ML-based, broadest model coverage
+		enabled: func(c DetectorConfig) bool { return c.HiveAPIKey != "" },
+		detect:  (*imageDetector).detectWithHive,
+	},
+	{
+		name:    "sightengine",
+		weight:  1.2, // Dedicated genai model, strong on diffusion output
+		enabled: func(c DetectorConfig) bool { return c.SightengineAPIKey != "" },
+		detect:  (*imageDetector).detectWithSightengine,
+	},
+	{
+		name:    "aiornot",
+		weight:  1.1, // Good recall, coarser confidence
+		enabled: func(c DetectorConfig) bool { return c.AIorNotAPIKey != "" },
+		detect:  (*imageDetector).detectWithAIorNot,
+	},
+	{
+		name:    "anthropic",
+		weight:  1.0, // General vision model, not trained for detection
+		enabled: func(c DetectorConfig) bool { return c.AnthropicAPIKey != "" },
+		detect: func(d *imageDetector, ctx context.Context, imageData []byte) (float64, error) {
+			return d.detectWithAnthropic(ctx, imageData, detectImageFormat(imageData))
+		},
+	},
+}
+
+// imageDetectorWeights are the reliability weights for image providers.
+var imageDetectorWeights = func() map[string]float64 {
+	weights := map[string]float64{
+		"humanmark": 1.0,                  // Our forensic analysis
+		"format":    formatConflictWeight, // Declared format conflicts
+	}
+	for _, p := range imageProviders {
+		weights[p.name] = p.weight
+	}
+	return weights
+}()
+
+// imageDetectorsAvailable maps each image detector to whether it is configured.
+func imageDetectorsAvailable(config DetectorConfig) map[string]bool {
+	available := map[string]bool{"humanmark": true, "format": true}
+	for _, p := range imageProviders {
+		available[p.name] = p.enabled(config)
+	}
+	return available
+}
+
+// detectWithSightengine calls Sightengine's genai model.
+//
+// The API key is "api_user:api_secret" as shown in the Sightengine dashboard.
+//
+// Score mapping: Sightengine returns type.ai_generated, already a 0-1
+// probability that the image is AI-generated, so it's used as-is.
+//
+// Errors come back as {"status":"failure","error":{"type":...}} - sometimes
+// with HTTP 200. usage_limit and rate_limit map to ErrProviderQuota,
+// credentials_error maps to ErrProviderAuth.
+func (d *imageDetector) detectWithSightengine(ctx context.Context, imageData []byte) (float64, error) {
+	apiUser, apiSecret, ok := strings.Cut(d.config.SightengineAPIKey, ":")
+	if !ok {
+		return 0, fmt.Errorf("%w: sightengine key must be api_user:api_secret", ErrProviderAuth)
+	}
+
+	body, contentType, err := multipartBody("media", "image", imageData, map[string]string{
+		"models":     "genai",
+		"api_user":   apiUser,
+		"api_secret": apiSecret,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.sightengineURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, providerUnreachable("sightengine", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return 0, providerStatusError("sightengine", resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Type   struct {
+			AIGenerated *float64 `json:"ai_generated"`
+		} `json:"type"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("sightengine API returned status %d: %w", resp.StatusCode, err)
+	}
+
+	if result.Status != "success" {
+		switch {
+		case strings.Contains(result.Error.Type, "limit"):
+			return 0, fmt.Errorf("%w: sightengine: %s", ErrProviderQuota, result.Error.Message)
+		case strings.Contains(result.Error.Type, "credentials"):
+			return 0, fmt.Errorf("%w: sightengine: %s", ErrProviderAuth, result.Error.Message)
+		default:
+			return 0, fmt.Errorf("sightengine API error (%s): %s", result.Error.Type, result.Error.Message)
+		}
+	}
+
+	if result.Type.AIGenerated == nil {
+		return 0, errors.New("no result from Sightengine API")
+	}
+
+	return clampScore(*result.Type.AIGenerated), nil
+}
+
+// detectWithAIorNot calls AIorNot's image report API.
+//
+// Score mapping: AIorNot returns a verdict plus a confidence for each class.
+// We use report.ai.confidence directly as the AI probability. If only the
+// verdict is present, "ai" maps to 1.0 and "human" to 0.0.
+//
+// HTTP 401/403 map to ErrProviderAuth; 402 (out of credits) and 429 map
+// to ErrProviderQuota.
+func (d *imageDetector) detectWithAIorNot(ctx context.Context, imageData []byte) (float64, error) {
+	body, contentType, err := multipartBody("object", "image", imageData, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.aiornotURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.config.AIorNotAPIKey)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, providerUnreachable("aiornot", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return 0, fmt.Errorf("%w: aiornot returned status %d", ErrProviderAuth, resp.StatusCode)
+	case http.StatusPaymentRequired, http.StatusTooManyRequests:
+		return 0, fmt.Errorf("%w: aiornot returned status %d", ErrProviderQuota, resp.StatusCode)
+	default:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return 0, providerStatusError("aiornot", resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		Report struct {
+			Verdict string `json:"verdict"`
+			AI      *struct {
+				Confidence float64 `json:"confidence"`
+			} `json:"ai"`
+		} `json:"report"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+
+	if result.Report.AI != nil {
+		return clampScore(result.Report.AI.Confidence), nil
+	}
+
+	switch result.Report.Verdict {
+	case "ai":
+		return 1.0, nil
+	case "human":
+		return 0.0, nil
+	}
+
+	return 0, errors.New("no result from AIorNot API")
+}
+
+// Anthropic vision settings
+const (
+	// anthropicModel is Claude Haiku, the fastest and cheapest vision model
+	anthropicModel = "claude-haiku-4-5"
+
+	// anthropicVersion is the Messages API version
+	anthropicVersion = "2023-06-01"
+
+	// anthropicMaxImageSize is the API's per-image limit
+	anthropicMaxImageSize = 5 * 1024 * 1024
+
+	// anthropicReportTool is the tool the model must answer with
+	anthropicReportTool = "report_ai_probability"
+
+	anthropicPrompt = "Estimate the probability that this image was generated by an AI model " +
+		"rather than captured by a camera or made by a person. Look for generation artifacts: " +
+		"malformed hands, text or reflections, impossible geometry, inconsistent lighting and " +
+		"unnaturally smooth textures. Report the probability with the " + anthropicReportTool + " tool."
+)
+
+// anthropicMediaTypes maps detected image formats to the media types the
+// Messages API accepts.
+var anthropicMediaTypes = map[string]string{
+	"jpeg":          "image/jpeg",
+	"png":           "image/png",
+	"gif":           "image/gif",
+	"webp":          "image/webp",
+	"webp_lossless": "image/webp",
+}
+
+// detectWithAnthropic asks Claude Haiku, through Anthropic's Messages API,
+// how likely the image is to be AI-generated.
+//
+// Score mapping: the model is made to answer by calling a report tool, so
+// the reply is structured JSON ({"ai_probability": 0.83}) rather than prose.
+// The probability is used as-is.
+//
+// Only JPEG, PNG, GIF and WebP up to 5MB are accepted; other images return
+// ErrProviderSkipped. HTTP 401/403 map to ErrProviderAuth and 429 to
+// ErrProviderQuota; 529 (overloaded) is a server error like any other 5xx.
+func (d *imageDetector) detectWithAnthropic(ctx context.Context, imageData []byte, format string) (float64, error) {
+	mediaType, ok := anthropicMediaTypes[format]
+	if !ok {
+		return 0, fmt.Errorf("%w: anthropic doesn't accept %s images", ErrProviderSkipped, format)
+	}
+	if len(imageData) > anthropicMaxImageSize {
+		return 0, fmt.Errorf("%w: anthropic accepts images up to %d bytes, got %d", ErrProviderSkipped, anthropicMaxImageSize, len(imageData))
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"model":      anthropicModel,
+		"max_tokens": 256,
+		"tools": []any{map[string]any{
+			"name":        anthropicReportTool,
+			"description": "Report the probability that the image is AI-generated.",
+			"input_schema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"ai_probability": map[string]any{"type": "number", "minimum": 0, "maximum": 1},
+				},
+				"required": []string{"ai_probability"},
+			},
+		}},
+		"tool_choice": map[string]string{"type": "tool", "name": anthropicReportTool},
+		"messages": []any{map[string]any{
+			"role": "user",
+			"content": []any{
+				map[string]any{
+					"type": "image",
+					"source": map[string]string{
+						"type":       "base64",
+						"media_type": mediaType,
+						"data":       base64.StdEncoding.EncodeToString(imageData),
+					},
+				},
+				map[string]any{"type": "text", "text": anthropicPrompt},
+			},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.anthropicURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("x-api-key", d.config.AnthropicAPIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, providerUnreachable("anthropic", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return 0, fmt.Errorf("%w: anthropic returned status %d", ErrProviderAuth, resp.StatusCode)
+	case http.StatusTooManyRequests:
+		return 0, fmt.Errorf("%w: anthropic returned status %d", ErrProviderQuota, resp.StatusCode)
+	default:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return 0, providerStatusError("anthropic", resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		Content []struct {
+			Type  string `json:"type"`
+			Name  string `json:"name"`
+			Input struct {
+				AIProbability *float64 `json:"ai_probability"`
+			} `json:"input"`
+		} `json:"content"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+
+	for _, block := range result.Content {
+		if block.Type == "tool_use" && block.Name == anthropicReportTool && block.Input.AIProbability != nil {
+			return clampScore(*block.Input.AIProbability), nil
+		}
+	}
+
+	return 0, errors.New("no result from Anthropic API")
+}
+
+// multipartBody builds a multipart form with one file field plus text fields.
+func multipartBody(fileField, filename string, data []byte, fields map[string]string) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	part, err := w.CreateFormFile(fileField, filename)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return &buf, w.FormDataContentType(), nil
+}
+
+// providerUnreachable reports a provider request that got no response.
+func providerUnreachable(name string, err error) error {
+	return fmt.Errorf("%w: %s: %w", ErrProviderUnavailable, name, err)
+}
+
+// providerStatusError reports an unexpected status from a provider. Server
+// errors wrap ErrProviderUnavailable.
+func providerStatusError(name string, status int, body []byte) error {
+	msg := fmt.Sprintf("%s API returned status %d", name, status)
+	if len(body) > 0 {
+		msg += ": " + string(body)
+	}
+	if status >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: %s", ErrProviderUnavailable, msg)
+	}
+	return errors.New(msg)
+}
+
+// clampScore keeps provider scores within 0-1.
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
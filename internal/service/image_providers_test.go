@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// testPNG is a PNG header followed by padding.
+var testPNG = append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, make([]byte, 256)...)
+
+// newProviderTestDetector creates an image detector pointed at a recorded-response server.
+func newProviderTestDetector(config DetectorConfig, serverURL string) *imageDetector {
+	d := NewImageDetector(config, logger.NopLogger()).(*imageDetector)
+	d.sightengineURL = serverURL
+	d.aiornotURL = serverURL
+	d.anthropicURL = serverURL
+	return d
+}
+
+// cannedResponse serves a fixed status and body.
+func cannedResponse(status int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+// TestSightengine tests Sightengine response mapping.
+func TestSightengine(t *testing.T) {
+	config := DetectorConfig{SightengineAPIKey: "user123:secret456"}
+
+	t.Run("maps ai_generated score", func(t *testing.T) {
+		var gotUser, gotModels string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseMultipartForm(1 << 20)
+			gotUser = r.FormValue("api_user")
+			gotModels = r.FormValue("models")
+			w.Write([]byte(`{"status":"success","request":{"id":"req_1"},"type":{"ai_generated":0.97},"media":{"id":"med_1"}}`))
+		}))
+		defer server.Close()
+
+		score, err := newProviderTestDetector(config, server.URL).detectWithSightengine(context.Background(), testPNG)
+		if err != nil {
+			t.Fatalf("detectWithSightengine() error = %v", err)
+		}
+		if score != 0.97 {
+			t.Errorf("score = %f, want 0.97", score)
+		}
+		if gotUser != "user123" || gotModels != "genai" {
+			t.Errorf("unexpected form fields: api_user=%q models=%q", gotUser, gotModels)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{"usage limit", http.StatusOK, `{"status":"failure","error":{"type":"usage_limit","code":32,"message":"Daily usage limit reached"}}`, ErrProviderQuota},
+		{"rate limit", http.StatusTooManyRequests, `{"status":"failure","error":{"type":"rate_limit","code":33,"message":"Too many requests"}}`, ErrProviderQuota},
+		{"bad credentials", http.StatusUnauthorized, `{"status":"failure","error":{"type":"credentials_error","code":1,"message":"Incorrect API user or secret"}}`, ErrProviderAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cannedResponse(tt.status, tt.body)
+			defer server.Close()
+
+			_, err := newProviderTestDetector(config, server.URL).detectWithSightengine(context.Background(), testPNG)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("rejects malformed key", func(t *testing.T) {
+		d := newProviderTestDetector(DetectorConfig{SightengineAPIKey: "no-secret"}, "http://unused")
+		if _, err := d.detectWithSightengine(context.Background(), testPNG); !errors.Is(err, ErrProviderAuth) {
+			t.Errorf("error = %v, want ErrProviderAuth", err)
+		}
+	})
+}
+
+// TestAIorNot tests AIorNot response mapping.
+func TestAIorNot(t *testing.T) {
+	config := DetectorConfig{AIorNotAPIKey: "aon-key"}
+
+	t.Run("maps ai confidence", func(t *testing.T) {
+		var gotAuth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotAuth = r.Header.Get("Authorization")
+			w.Write([]byte(`{"id":"rep_1","report":{"verdict":"human","ai":{"is_detected":false,"confidence":0.12},"human":{"is_detected":true,"confidence":0.88}}}`))
+		}))
+		defer server.Close()
+
+		score, err := newProviderTestDetector(config, server.URL).detectWithAIorNot(context.Background(), testPNG)
+		if err != nil {
+			t.Fatalf("detectWithAIorNot() error = %v", err)
+		}
+		if score != 0.12 {
+			t.Errorf("score = %f, want 0.12", score)
+		}
+		if gotAuth != "Bearer aon-key" {
+			t.Errorf("Authorization = %q", gotAuth)
+		}
+	})
+
+	t.Run("falls back to verdict", func(t *testing.T) {
+		server := cannedResponse(http.StatusOK, `{"id":"rep_2","report":{"verdict":"ai"}}`)
+		defer server.Close()
+
+		score, err := newProviderTestDetector(config, server.URL).detectWithAIorNot(context.Background(), testPNG)
+		if err != nil || score != 1.0 {
+			t.Errorf("detectWithAIorNot() = %f, %v; want 1.0", score, err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{"unauthorized", http.StatusUnauthorized, ErrProviderAuth},
+		{"out of credits", http.StatusPaymentRequired, ErrProviderQuota},
+		{"rate limited", http.StatusTooManyRequests, ErrProviderQuota},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cannedResponse(tt.status, `{"detail":"error"}`)
+			defer server.Close()
+
+			_, err := newProviderTestDetector(config, server.URL).detectWithAIorNot(context.Background(), testPNG)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestAnthropic tests the Claude vision request and response mapping.
+func TestAnthropic(t *testing.T) {
+	config := DetectorConfig{AnthropicAPIKey: "sk-ant-test"}
+
+	t.Run("sends the image and reads the tool result", func(t *testing.T) {
+		var gotKey, gotVersion string
+		var got struct {
+			Model      string `json:"model"`
+			ToolChoice struct {
+				Name string `json:"name"`
+			} `json:"tool_choice"`
+			Messages []struct {
+				Content []struct {
+					Type   string `json:"type"`
+					Source struct {
+						MediaType string `json:"media_type"`
+						Data      string `json:"data"`
+					} `json:"source"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotKey = r.Header.Get("x-api-key")
+			gotVersion = r.Header.Get("anthropic-version")
+			json.NewDecoder(r.Body).Decode(&got)
+			w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[` +
+				`{"type":"text","text":"Checking the image."},` +
+				`{"type":"tool_use","id":"toolu_1","name":"report_ai_probability","input":{"ai_probability":0.83}}],` +
+				`"stop_reason":"tool_use"}`))
+		}))
+		defer server.Close()
+
+		score, err := newProviderTestDetector(config, server.URL).detectWithAnthropic(context.Background(), testPNG, "png")
+		if err != nil {
+			t.Fatalf("detectWithAnthropic() error = %v", err)
+		}
+		if score != 0.83 {
+			t.Errorf("score = %f, want 0.83", score)
+		}
+		if gotKey != "sk-ant-test" || gotVersion != anthropicVersion {
+			t.Errorf("x-api-key = %q, anthropic-version = %q", gotKey, gotVersion)
+		}
+		if got.Model != anthropicModel || got.ToolChoice.Name != anthropicReportTool {
+			t.Errorf("model = %q, tool_choice = %q", got.Model, got.ToolChoice.Name)
+		}
+		if len(got.Messages) != 1 || len(got.Messages[0].Content) == 0 {
+			t.Fatalf("messages = %+v, want the image in one user message", got.Messages)
+		}
+		image := got.Messages[0].Content[0]
+		if image.Type != "image" || image.Source.MediaType != "image/png" ||
+			image.Source.Data != base64.StdEncoding.EncodeToString(testPNG) {
+			t.Errorf("image block = %+v, want the base64 PNG", image)
+		}
+	})
+
+	t.Run("media types", func(t *testing.T) {
+		var gotMediaType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Messages []struct {
+					Content []struct {
+						Source struct {
+							MediaType string `json:"media_type"`
+						} `json:"source"`
+					} `json:"content"`
+				} `json:"messages"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			gotMediaType = body.Messages[0].Content[0].Source.MediaType
+			w.Write([]byte(`{"content":[{"type":"tool_use","name":"report_ai_probability","input":{"ai_probability":0.5}}]}`))
+		}))
+		defer server.Close()
+
+		d := newProviderTestDetector(config, server.URL)
+		for format, want := range map[string]string{"jpeg": "image/jpeg", "gif": "image/gif", "webp_lossless": "image/webp"} {
+			if _, err := d.detectWithAnthropic(context.Background(), testPNG, format); err != nil {
+				t.Fatalf("detectWithAnthropic(%s) error = %v", format, err)
+			}
+			if gotMediaType != want {
+				t.Errorf("%s sent as %q, want %q", format, gotMediaType, want)
+			}
+		}
+	})
+
+	t.Run("unsupported images are skipped", func(t *testing.T) {
+		d := newProviderTestDetector(config, "http://unused")
+		if _, err := d.detectWithAnthropic(context.Background(), testPNG, "bmp"); !errors.Is(err, ErrProviderSkipped) {
+			t.Errorf("bmp: error = %v, want ErrProviderSkipped", err)
+		}
+		large := make([]byte, anthropicMaxImageSize+1)
+		if _, err := d.detectWithAnthropic(context.Background(), large, "png"); !errors.Is(err, ErrProviderSkipped) {
+			t.Errorf("oversized: error = %v, want ErrProviderSkipped", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{"bad key", http.StatusUnauthorized, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, ErrProviderAuth},
+		{"rate limited", http.StatusTooManyRequests, `{"type":"error","error":{"type":"rate_limit_error","message":"Rate limited"}}`, ErrProviderQuota},
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrProviderUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cannedResponse(tt.status, tt.body)
+			defer server.Close()
+
+			_, err := newProviderTestDetector(config, server.URL).detectWithAnthropic(context.Background(), testPNG, "png")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("reply without the tool call", func(t *testing.T) {
+		server := cannedResponse(http.StatusOK, `{"content":[{"type":"text","text":"I can't tell."}]}`)
+		defer server.Close()
+
+		if _, err := newProviderTestDetector(config, server.URL).detectWithAnthropic(context.Background(), testPNG, "png"); err == nil {
+			t.Error("expected an error for a reply with no probability")
+		}
+	})
+}
+
+// TestImageProviderRegistry verifies registered providers are blended into results.
+func TestImageProviderRegistry(t *testing.T) {
+	for _, name := range []string{"hive", "sightengine", "aiornot", "anthropic"} {
+		if _, ok := imageDetectorWeights[name]; !ok {
+			t.Errorf("provider %s has no default weight", name)
+		}
+	}
+
+	t.Run("configured providers contribute scores", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/sightengine", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"success","type":{"ai_generated":0.9}}`))
+		})
+		mux.HandleFunc("/aiornot", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"report":{"verdict":"ai","ai":{"confidence":0.8}}}`))
+		})
+		mux.HandleFunc("/anthropic", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"content":[{"type":"tool_use","name":"report_ai_probability","input":{"ai_probability":0.7}}]}`))
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		d := newProviderTestDetector(DetectorConfig{SightengineAPIKey: "u:s", AIorNotAPIKey: "k", AnthropicAPIKey: "a"}, "")
+		d.sightengineURL = server.URL + "/sightengine"
+		d.aiornotURL = server.URL + "/aiornot"
+		d.anthropicURL = server.URL + "/anthropic"
+
+		result, err := d.DetectImage(context.Background(), DetectionInput{Data: testPNG})
+		if err != nil {
+			t.Fatalf("DetectImage() error = %v", err)
+		}
+
+		want := []string{"humanmark", "sightengine", "aiornot", "anthropic"}
+		if len(result.Detectors) != len(want) {
+			t.Fatalf("Detectors = %v, want %v", result.Detectors, want)
+		}
+		for i, name := range want {
+			if result.Detectors[i] != name {
+				t.Errorf("Detectors[%d] = %s, want %s", i, result.Detectors[i], name)
+			}
+		}
+	})
+
+	t.Run("failing provider is skipped", func(t *testing.T) {
+		server := cannedResponse(http.StatusTooManyRequests, `{}`)
+		defer server.Close()
+
+		d := newProviderTestDetector(DetectorConfig{AIorNotAPIKey: "k"}, server.URL)
+		result, err := d.DetectImage(context.Background(), DetectionInput{Data: testPNG})
+		if err != nil {
+			t.Fatalf("DetectImage() error = %v", err)
+		}
+		if len(result.Detectors) != 1 || result.Detectors[0] != "humanmark" {
+			t.Errorf("Detectors = %v, want only humanmark", result.Detectors)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// HasData reports whether input carries binary content, in Data or Reader.
+func (input DetectionInput) HasData() bool {
+	return len(input.Data) > 0 || input.Reader != nil
+}
+
+// DataSize returns the length of input's binary content.
+func (input DetectionInput) DataSize() int64 {
+	if input.Reader != nil {
+		return input.Size
+	}
+	return int64(len(input.Data))
+}
+
+// OpenData returns a reader over input's binary content, for analyzers
+// that stream it.
+func (input DetectionInput) OpenData() io.Reader {
+	if input.Reader != nil {
+		return io.NewSectionReader(input.Reader, 0, input.Size)
+	}
+	return bytes.NewReader(input.Data)
+}
+
+// ReadData returns input's binary content in memory, for analyzers and
+// providers that need all of it. Content over limit bytes is refused with
+// ErrContentTooLarge rather than read.
+func (input DetectionInput) ReadData(limit int64) ([]byte, error) {
+	if size := input.DataSize(); size > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d", ErrContentTooLarge, size, limit)
+	}
+	if input.Reader == nil {
+		return input.Data, nil
+	}
+	data := make([]byte, input.Size)
+	if _, err := input.Reader.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return data, nil
+}
+
+// DataPrefix returns up to the first n bytes of input's binary content,
+// e.g. for sniffing its type. A Reader that can't be read gives what could
+// be; ReadData and OpenData report the error when the content is
+// analyzed.
+func (input DetectionInput) DataPrefix(n int) []byte {
+	if input.Reader == nil {
+		return input.Data[:min(n, len(input.Data))]
+	}
+	if int64(n) > input.Size {
+		n = int(input.Size)
+	}
+	prefix := make([]byte, n)
+	read, _ := input.Reader.ReadAt(prefix, 0)
+	return prefix[:read]
+}
+
+// Close releases input's Reader, if it can be closed (e.g. removing the
+// temp file an upload was spilled to). Inputs without one need no
+// closing.
+func (input DetectionInput) Close() error {
+	if c, ok := input.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 
 	"github.com/humanmark/humanmark/pkg/logger"
 )
@@ -22,27 +23,47 @@ type imageDetector struct {
 	config     DetectorConfig
 	logger     *logger.Logger
 	httpClient *http.Client
+	fetcher    *urlFetcher
+	metrics    *detectorMetrics
+	analyzer   *ImageAnalyzer
+
+	// Provider endpoints
+	sightengineURL string
+	aiornotURL     string
+	anthropicURL   string
 }
 
 // NewImageDetector creates a new image detector.
 func NewImageDetector(config DetectorConfig, log *logger.Logger) ImageDetector {
 	return &imageDetector{
-		config: config,
-		logger: log,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		config:         config,
+		logger:         log.Component("image"),
+		httpClient:     providerClient(config),
+		fetcher:        newURLFetcherFromConfig(config),
+		metrics:        newDetectorMetrics(config.Metrics),
+		analyzer:       NewImageAnalyzer(),
+		sightengineURL: providerEndpoint(config, "sightengine", sightenginePath),
+		aiornotURL:     providerEndpoint(config, "aiornot", aiornotPath),
+		anthropicURL:   providerEndpoint(config, "anthropic", anthropicPath),
 	}
 }
 
 // DetectImage analyzes image content for AI generation.
 func (d *imageDetector) DetectImage(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
+	selected, err := selectDetectors(input, ContentTypeImage, imageDetectorsAvailable(d.config))
+	if err != nil {
+		return nil, err
+	}
+
 	var imageData []byte
-	var err error
 
-	// Get image data
-	if len(input.Data) > 0 {
-		imageData = input.Data
+	// Get image data; images are decoded whole, so uploads are read into
+	// memory, up to the same 50MB as URLs
+	if input.HasData() {
+		imageData, err = input.ReadData(50 * 1024 * 1024)
+		if err != nil {
+			return nil, err
+		}
 	} else if input.URL != "" {
 		imageData, err = d.fetchImageFromURL(ctx, input.URL)
 		if err != nil {
@@ -54,85 +75,83 @@ func (d *imageDetector) DetectImage(ctx context.Context, input DetectionInput) (
 
 	var scores []float64
 	var detectors []string
+	detectorErrors := make(map[string]error)
 
 	// ==========================================================================
 	// PRIMARY: Our own HumanMark forensic analyzer
 	// This runs locally with no external dependencies
 	// ==========================================================================
-	analyzer := NewImageAnalyzer()
-	analysis := analyzer.Analyze(imageData)
-	
-	scores = append(scores, analysis.AIScore)
-	detectors = append(detectors, "humanmark")
-	
-	d.logger.Debug("humanmark image analysis complete",
-		"ai_score", analysis.AIScore,
-		"has_exif", analysis.Metadata.HasEXIF,
-		"camera_make", analysis.Metadata.CameraMake,
-		"format", analysis.Metadata.FileFormat,
-		"width", analysis.Stats.Width,
-		"height", analysis.Stats.Height,
-	)
+	var mismatch string
+	if selected["humanmark"] {
+		var analysis ImageAnalysisResult
+		err := d.metrics.runAnalyzer(ctx, ContentTypeImage, func(ctx context.Context) (err error) {
+			analysis, err = d.analyzer.AnalyzeFromReaderWithHints(ctx, bytes.NewReader(imageData), formatHints(input))
+			return err
+		})
+
+		switch {
+		case err != nil && panicStack(err) == nil:
+			return nil, err
+		case err != nil:
+			recordDetectorError(d.logger, detectorErrors, "humanmark", ContentTypeImage, err)
+		default:
+			scores = append(scores, analysis.AIScore)
+			detectors = append(detectors, "humanmark")
+
+			d.logger.Debug("humanmark image analysis complete",
+				"ai_score", analysis.AIScore,
+				"has_exif", analysis.Metadata.HasEXIF,
+				"camera_make", analysis.Metadata.CameraMake,
+				"format", analysis.Metadata.FileFormat,
+				"declared_format", analysis.Metadata.DeclaredFormat,
+				"format_conflict", analysis.Metadata.FormatConflict,
+				"width", analysis.Stats.Width,
+				"height", analysis.Stats.Height,
+			)
+
+			meta := analysis.Metadata
+			mismatch = formatMismatchWarning(meta.DeclaredFormat, meta.FileFormat, meta.FormatConflict)
+			if meta.FormatConflict && selected["format"] {
+				scores = append(scores, formatConflictScore)
+				detectors = append(detectors, "format")
+			}
+		}
+	}
 
 	// ==========================================================================
 	// SECONDARY: External APIs (optional, for higher accuracy)
 	// ==========================================================================
 
-	// Try Hive API for image detection
-	if d.config.HiveAPIKey != "" {
-		score, err := d.detectWithHive(ctx, imageData)
+	// Try each configured provider; one failing doesn't fail the request
+	for _, p := range imageProviders {
+		if !selected[p.name] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		score, err := d.metrics.runProvider(ctx, ContentTypeImage, p.name, d.config.ProviderTimeouts[p.name], func(ctx context.Context) (float64, error) {
+			return p.detect(d, ctx, imageData)
+		})
 		if err != nil {
-			d.logger.Warn("hive image detection failed", "error", err)
-		} else {
-			scores = append(scores, score)
-			detectors = append(detectors, "hive")
+			recordDetectorError(d.logger, detectorErrors, p.name, ContentTypeImage, err)
+			continue
 		}
+		scores = append(scores, score)
+		detectors = append(detectors, p.name)
 	}
 
-	// Aggregate scores with weighted average
-	aiScore := d.aggregateScoresWeighted(scores, detectors)
-	human := aiScore < 0.5
-	confidence := abs(aiScore-0.5) * 2
-
-	return &DetectionResult{
-		Human:       human,
-		Confidence:  confidence,
-		AIScore:     aiScore,
-		ContentType: ContentTypeImage,
-		Detectors:   detectors,
-	}, nil
-}
-
-// aggregateScoresWeighted combines scores with detector-specific weights.
-func (d *imageDetector) aggregateScoresWeighted(scores []float64, detectors []string) float64 {
 	if len(scores) == 0 {
-		return 0.5
+		return nil, allDetectorsFailed(detectorErrors)
 	}
 
-	weights := map[string]float64{
-		"humanmark": 1.0, // Our forensic analysis
-		"hive":      1.3, // External API - ML-based
+	// Combine scores using the configured strategy
+	result := buildResult(d.config, ContentTypeImage, scores, detectors, imageDetectorWeights, EvidenceNone)
+	result.MismatchWarning = mismatch
+	if len(detectorErrors) > 0 {
+		result.DetectorErrors = errorMessages(detectorErrors)
 	}
-
-	totalWeight := 0.0
-	weightedSum := 0.0
-
-	for i, score := range scores {
-		w := 1.0
-		if i < len(detectors) {
-			if detectorWeight, ok := weights[detectors[i]]; ok {
-				w = detectorWeight
-			}
-		}
-		weightedSum += score * w
-		totalWeight += w
-	}
-
-	if totalWeight == 0 {
-		return 0.5
-	}
-
-	return weightedSum / totalWeight
+	return result, nil
 }
 
 // detectWithHive calls Hive AI for image detection.
@@ -142,7 +161,7 @@ func (d *imageDetector) detectWithHive(ctx context.Context, imageData []byte) (f
 
 	body, _ := json.Marshal(map[string]string{"image": encoded})
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.thehive.ai/api/v2/task/sync", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", providerEndpoint(d.config, "hive", hivePath), bytes.NewReader(body))
 	if err != nil {
 		return 0, err
 	}
@@ -152,13 +171,13 @@ func (d *imageDetector) detectWithHive(ctx context.Context, imageData []byte) (f
 
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return 0, providerUnreachable("hive", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("hive API returned status %d: %s", resp.StatusCode, string(respBody))
+		return 0, providerStatusError("hive", resp.StatusCode, respBody)
 	}
 
 	var result struct {
@@ -182,156 +201,157 @@ func (d *imageDetector) detectWithHive(ctx context.Context, imageData []byte) (f
 
 // fetchImageFromURL downloads an image from a URL.
 func (d *imageDetector) fetchImageFromURL(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := d.httpClient.Do(req)
+	// Limit size to 50MB
+	result, err := d.fetcher.Fetch(ctx, url, 50*1024*1024)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch image: status %d", resp.StatusCode)
-	}
-
-	// Limit size to 50MB
-	return io.ReadAll(io.LimitReader(resp.Body, 50*1024*1024))
-}
-
-func (d *imageDetector) aggregateScores(scores []float64) float64 {
-	if len(scores) == 0 {
-		return 0.5
-	}
-	sum := 0.0
-	for _, s := range scores {
-		sum += s
-	}
-	return sum / float64(len(scores))
+	return result.Data, nil
 }
 
 // =============================================================================
 // Audio Detector
 // =============================================================================
 
+// audioDetectorWeights are the reliability weights for audio providers.
+var audioDetectorWeights = map[string]float64{
+	"humanmark": 1.0,                  // Our forensic analysis
+	"format":    formatConflictWeight, // Declared format conflicts
+	"hive":      1.3,                  // External API - ML-based
+}
+
 // audioDetector implements AudioDetector.
 type audioDetector struct {
 	config     DetectorConfig
 	logger     *logger.Logger
 	httpClient *http.Client
+	fetcher    *urlFetcher
+	metrics    *detectorMetrics
+	analyzer   *AudioAnalyzer
 }
 
 // NewAudioDetector creates a new audio detector.
 func NewAudioDetector(config DetectorConfig, log *logger.Logger) AudioDetector {
 	return &audioDetector{
-		config: config,
-		logger: log,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		config:     config,
+		logger:     log.Component("audio"),
+		httpClient: providerClient(config),
+		fetcher:    newURLFetcherFromConfig(config),
+		metrics:    newDetectorMetrics(config.Metrics),
+		analyzer:   NewAudioAnalyzer(),
 	}
 }
 
 // DetectAudio analyzes audio content for AI generation.
 func (d *audioDetector) DetectAudio(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
-	var audioData []byte
-	var err error
+	selected, err := selectDetectors(input, ContentTypeAudio, map[string]bool{
+		"humanmark": true,
+		"format":    true,
+		"hive":      d.config.HiveAPIKey != "",
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Get audio data
-	if len(input.Data) > 0 {
-		audioData = input.Data
-	} else if input.URL != "" {
-		audioData, err = d.fetchAudioFromURL(ctx, input.URL)
+	// Get audio data; uploads stay where they are (possibly a temp file)
+	// and are streamed
+	if !input.HasData() {
+		if input.URL == "" {
+			return nil, errors.New("no audio data provided")
+		}
+		input.Data, err = d.fetchAudioFromURL(ctx, input.URL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch audio: %w", err)
 		}
-	} else {
-		return nil, errors.New("no audio data provided")
 	}
 
 	var scores []float64
 	var detectors []string
+	detectorErrors := make(map[string]error)
 
 	// ==========================================================================
 	// PRIMARY: Our own HumanMark forensic analyzer
 	// This runs locally with no external dependencies
 	// ==========================================================================
-	analyzer := NewAudioAnalyzer()
-	analysis := analyzer.Analyze(audioData)
-	
-	scores = append(scores, analysis.AIScore)
-	detectors = append(detectors, "humanmark")
-	
-	d.logger.Debug("humanmark audio analysis complete",
-		"ai_score", analysis.AIScore,
-		"format", analysis.Metadata.Format,
-		"sample_rate", analysis.Metadata.SampleRate,
-		"channels", analysis.Metadata.Channels,
-		"encoder", analysis.Metadata.EncoderName,
-		"is_ai_marked", analysis.Metadata.IsAIMarked,
-	)
+	evidence := EvidenceNone
+	var mismatch string
+	if selected["humanmark"] {
+		var analysis AudioAnalysisResult
+		err := d.metrics.runAnalyzer(ctx, ContentTypeAudio, func(ctx context.Context) (err error) {
+			analysis, err = d.analyzer.AnalyzeFromReaderWithHints(ctx, input.OpenData(), formatHints(input))
+			return err
+		})
+
+		switch {
+		case err != nil && panicStack(err) == nil:
+			return nil, err
+		case err != nil:
+			recordDetectorError(d.logger, detectorErrors, "humanmark", ContentTypeAudio, err)
+		default:
+			scores = append(scores, analysis.AIScore)
+			detectors = append(detectors, "humanmark")
+
+			d.logger.Debug("humanmark audio analysis complete",
+				"ai_score", analysis.AIScore,
+				"format", analysis.Metadata.Format,
+				"declared_format", analysis.Metadata.DeclaredFormat,
+				"format_conflict", analysis.Metadata.FormatConflict,
+				"sample_rate", analysis.Metadata.SampleRate,
+				"channels", analysis.Metadata.Channels,
+				"encoder", analysis.Metadata.EncoderName,
+				"is_ai_marked", analysis.Metadata.IsAIMarked,
+			)
+
+			meta := analysis.Metadata
+			mismatch = formatMismatchWarning(meta.DeclaredFormat, meta.Format, meta.FormatConflict)
+			if meta.FormatConflict && selected["format"] {
+				scores = append(scores, formatConflictScore)
+				detectors = append(detectors, "format")
+			}
+
+			// AI generator markers are conclusive on their own
+			if analysis.Metadata.IsAIMarked {
+				evidence = EvidenceAI
+			}
+		}
+	}
 
 	// ==========================================================================
 	// SECONDARY: External APIs (optional, for higher accuracy)
 	// ==========================================================================
 
 	// Try Hive API for audio detection
-	if d.config.HiveAPIKey != "" {
-		score, err := d.detectWithHive(ctx, audioData)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if selected["hive"] {
+		score, err := d.metrics.runProvider(ctx, ContentTypeAudio, "hive", d.config.ProviderTimeouts["hive"], func(ctx context.Context) (float64, error) {
+			// Hive takes the audio inline, so it has to be in memory
+			audioData, err := input.ReadData(100 * 1024 * 1024)
+			if err != nil {
+				return 0, err
+			}
+			return d.detectWithHive(ctx, audioData)
+		})
 		if err != nil {
-			d.logger.Warn("hive audio detection failed", "error", err)
+			recordDetectorError(d.logger, detectorErrors, "hive", ContentTypeAudio, err)
 		} else {
 			scores = append(scores, score)
 			detectors = append(detectors, "hive")
 		}
 	}
 
-	// Aggregate scores with weighted average
-	aiScore := d.aggregateScoresWeighted(scores, detectors)
-	human := aiScore < 0.5
-	confidence := abs(aiScore-0.5) * 2
-
-	return &DetectionResult{
-		Human:       human,
-		Confidence:  confidence,
-		AIScore:     aiScore,
-		ContentType: ContentTypeAudio,
-		Detectors:   detectors,
-	}, nil
-}
-
-// aggregateScoresWeighted combines scores with detector-specific weights.
-func (d *audioDetector) aggregateScoresWeighted(scores []float64, detectors []string) float64 {
 	if len(scores) == 0 {
-		return 0.5
+		return nil, allDetectorsFailed(detectorErrors)
 	}
 
-	weights := map[string]float64{
-		"humanmark": 1.0, // Our forensic analysis
-		"hive":      1.3, // External API - ML-based
+	// Combine scores using the configured strategy
+	result := buildResult(d.config, ContentTypeAudio, scores, detectors, audioDetectorWeights, evidence)
+	result.MismatchWarning = mismatch
+	if len(detectorErrors) > 0 {
+		result.DetectorErrors = errorMessages(detectorErrors)
 	}
-
-	totalWeight := 0.0
-	weightedSum := 0.0
-
-	for i, score := range scores {
-		w := 1.0
-		if i < len(detectors) {
-			if detectorWeight, ok := weights[detectors[i]]; ok {
-				w = detectorWeight
-			}
-		}
-		weightedSum += score * w
-		totalWeight += w
-	}
-
-	if totalWeight == 0 {
-		return 0.5
-	}
-
-	return weightedSum / totalWeight
+	return result, nil
 }
 
 // detectWithHive calls Hive AI for audio detection.
@@ -339,7 +359,7 @@ func (d *audioDetector) detectWithHive(ctx context.Context, audioData []byte) (f
 	encoded := base64.StdEncoding.EncodeToString(audioData)
 	body, _ := json.Marshal(map[string]string{"audio": encoded})
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.thehive.ai/api/v2/task/sync", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", providerEndpoint(d.config, "hive", hivePath), bytes.NewReader(body))
 	if err != nil {
 		return 0, err
 	}
@@ -349,12 +369,12 @@ func (d *audioDetector) detectWithHive(ctx context.Context, audioData []byte) (f
 
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return 0, providerUnreachable("hive", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("hive API returned status %d", resp.StatusCode)
+		return 0, providerStatusError("hive", resp.StatusCode, nil)
 	}
 
 	var result struct {
@@ -378,185 +398,175 @@ func (d *audioDetector) detectWithHive(ctx context.Context, audioData []byte) (f
 
 // fetchAudioFromURL downloads audio from a URL.
 func (d *audioDetector) fetchAudioFromURL(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := d.httpClient.Do(req)
+	// Limit to 100MB
+	result, err := d.fetcher.Fetch(ctx, url, 100*1024*1024)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch audio: status %d", resp.StatusCode)
-	}
-
-	// Limit to 100MB
-	return io.ReadAll(io.LimitReader(resp.Body, 100*1024*1024))
-}
-
-func (d *audioDetector) aggregateScores(scores []float64) float64 {
-	if len(scores) == 0 {
-		return 0.5
-	}
-	sum := 0.0
-	for _, s := range scores {
-		sum += s
-	}
-	return sum / float64(len(scores))
+	return result.Data, nil
 }
 
 // =============================================================================
 // Video Detector
 // =============================================================================
 
+// videoDetectorWeights are the reliability weights for video providers.
+var videoDetectorWeights = map[string]float64{
+	"humanmark": 1.0,                  // Our forensic analysis
+	"format":    formatConflictWeight, // Declared format conflicts
+	"hive":      1.4,                  // External API - ML-based, better for video
+}
+
 // videoDetector implements VideoDetector.
 type videoDetector struct {
 	config     DetectorConfig
 	logger     *logger.Logger
 	httpClient *http.Client
+	fetcher    *urlFetcher
+	metrics    *detectorMetrics
+	analyzer   *VideoAnalyzer
 }
 
 // NewVideoDetector creates a new video detector.
 func NewVideoDetector(config DetectorConfig, log *logger.Logger) VideoDetector {
 	return &videoDetector{
-		config: config,
-		logger: log,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		config:     config,
+		logger:     log.Component("video"),
+		httpClient: providerClient(config),
+		fetcher:    newURLFetcherFromConfig(config),
+		metrics:    newDetectorMetrics(config.Metrics),
+		analyzer:   NewVideoAnalyzer(),
 	}
 }
 
 // DetectVideo analyzes video content for AI generation.
 func (d *videoDetector) DetectVideo(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
-	var videoData []byte
-	var err error
+	selected, err := selectDetectors(input, ContentTypeVideo, map[string]bool{
+		"humanmark": true,
+		"format":    true,
+		"hive":      d.config.HiveAPIKey != "",
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Get video data
-	if len(input.Data) > 0 {
-		videoData = input.Data
-	} else if input.URL != "" {
+	// Hive scores videos by URL, so it can't see uploads
+	if input.URL == "" && slices.Contains(input.Detectors, "hive") {
+		return nil, fmt.Errorf("%w: detector %q needs a video URL", ErrInvalidDetectorSelection, "hive")
+	}
+
+	// Get video data; uploads stay where they are (possibly a temp file)
+	// and are streamed
+	if !input.HasData() {
+		if input.URL == "" {
+			return nil, errors.New("video data or URL required for detection")
+		}
 		// For videos, we need to fetch the data
-		videoData, err = d.fetchVideoFromURL(ctx, input.URL)
+		input.Data, err = d.fetchVideoFromURL(ctx, input.URL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch video: %w", err)
 		}
-	} else {
-		return nil, errors.New("video data or URL required for detection")
 	}
 
 	var scores []float64
 	var detectors []string
+	detectorErrors := make(map[string]error)
 
 	// ==========================================================================
 	// PRIMARY: Our own HumanMark forensic analyzer
 	// This runs locally with no external dependencies
 	// ==========================================================================
-	analyzer := NewVideoAnalyzer()
-	analysis := analyzer.Analyze(videoData)
-	
-	scores = append(scores, analysis.AIScore)
-	detectors = append(detectors, "humanmark")
-	
-	d.logger.Debug("humanmark video analysis complete",
-		"ai_score", analysis.AIScore,
-		"format", analysis.Metadata.Format,
-		"has_audio", analysis.Metadata.HasAudio,
-		"encoder", analysis.Metadata.EncoderName,
-		"is_ai_marked", analysis.Metadata.IsAIMarked,
-		"file_size", analysis.Stats.FileSize,
-	)
+	evidence := EvidenceNone
+	var mismatch string
+	if selected["humanmark"] {
+		var analysis VideoAnalysisResult
+		err := d.metrics.runAnalyzer(ctx, ContentTypeVideo, func(ctx context.Context) (err error) {
+			analysis, err = d.analyzer.AnalyzeFromReaderWithHints(ctx, input.OpenData(), formatHints(input))
+			return err
+		})
+
+		switch {
+		case err != nil && panicStack(err) == nil:
+			return nil, err
+		case err != nil:
+			recordDetectorError(d.logger, detectorErrors, "humanmark", ContentTypeVideo, err)
+		default:
+			scores = append(scores, analysis.AIScore)
+			detectors = append(detectors, "humanmark")
+
+			d.logger.Debug("humanmark video analysis complete",
+				"ai_score", analysis.AIScore,
+				"format", analysis.Metadata.Format,
+				"declared_format", analysis.Metadata.DeclaredFormat,
+				"format_conflict", analysis.Metadata.FormatConflict,
+				"has_audio", analysis.Metadata.HasAudio,
+				"encoder", analysis.Metadata.EncoderName,
+				"is_ai_marked", analysis.Metadata.IsAIMarked,
+				"file_size", analysis.Stats.FileSize,
+			)
+
+			meta := analysis.Metadata
+			mismatch = formatMismatchWarning(meta.DeclaredFormat, meta.Format, meta.FormatConflict)
+			if meta.FormatConflict && selected["format"] {
+				scores = append(scores, formatConflictScore)
+				detectors = append(detectors, "format")
+			}
+
+			// AI generator markers are conclusive on their own
+			if analysis.Metadata.IsAIMarked {
+				evidence = EvidenceAI
+			}
+		}
+	}
 
 	// ==========================================================================
 	// SECONDARY: External APIs (optional, for higher accuracy)
 	// ==========================================================================
 
 	// Try Hive API for video detection
-	if d.config.HiveAPIKey != "" && input.URL != "" {
-		score, err := d.detectWithHive(ctx, input.URL)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if selected["hive"] && input.URL != "" {
+		score, err := d.metrics.runProvider(ctx, ContentTypeVideo, "hive", d.config.ProviderTimeouts["hive"], func(ctx context.Context) (float64, error) {
+			return d.detectWithHive(ctx, input.URL)
+		})
 		if err != nil {
-			d.logger.Warn("hive video detection failed", "error", err)
+			recordDetectorError(d.logger, detectorErrors, "hive", ContentTypeVideo, err)
 		} else {
 			scores = append(scores, score)
 			detectors = append(detectors, "hive")
 		}
 	}
 
-	// Aggregate scores with weighted average
-	aiScore := d.aggregateScoresWeighted(scores, detectors)
-	human := aiScore < 0.5
-	confidence := abs(aiScore-0.5) * 2
+	if len(scores) == 0 {
+		return nil, allDetectorsFailed(detectorErrors)
+	}
 
-	return &DetectionResult{
-		Human:       human,
-		Confidence:  confidence,
-		AIScore:     aiScore,
-		ContentType: ContentTypeVideo,
-		Detectors:   detectors,
-	}, nil
+	// Combine scores using the configured strategy
+	result := buildResult(d.config, ContentTypeVideo, scores, detectors, videoDetectorWeights, evidence)
+	result.MismatchWarning = mismatch
+	if len(detectorErrors) > 0 {
+		result.DetectorErrors = errorMessages(detectorErrors)
+	}
+	return result, nil
 }
 
 // fetchVideoFromURL downloads video from a URL.
 func (d *videoDetector) fetchVideoFromURL(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := d.httpClient.Do(req)
+	// Limit to 500MB for video
+	result, err := d.fetcher.Fetch(ctx, url, 500*1024*1024)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch video: status %d", resp.StatusCode)
-	}
-
-	// Limit to 500MB for video
-	return io.ReadAll(io.LimitReader(resp.Body, 500*1024*1024))
-}
-
-// aggregateScoresWeighted combines scores with detector-specific weights.
-func (d *videoDetector) aggregateScoresWeighted(scores []float64, detectors []string) float64 {
-	if len(scores) == 0 {
-		return 0.5
-	}
-
-	weights := map[string]float64{
-		"humanmark": 1.0, // Our forensic analysis
-		"hive":      1.4, // External API - ML-based, better for video
-	}
-
-	totalWeight := 0.0
-	weightedSum := 0.0
-
-	for i, score := range scores {
-		w := 1.0
-		if i < len(detectors) {
-			if detectorWeight, ok := weights[detectors[i]]; ok {
-				w = detectorWeight
-			}
-		}
-		weightedSum += score * w
-		totalWeight += w
-	}
-
-	if totalWeight == 0 {
-		return 0.5
-	}
-
-	return weightedSum / totalWeight
+	return result.Data, nil
 }
 
 // detectWithHive calls Hive AI for video detection.
 func (d *videoDetector) detectWithHive(ctx context.Context, videoURL string) (float64, error) {
 	body, _ := json.Marshal(map[string]string{"url": videoURL})
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.thehive.ai/api/v2/task/sync", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", providerEndpoint(d.config, "hive", hivePath), bytes.NewReader(body))
 	if err != nil {
 		return 0, err
 	}
@@ -566,12 +576,12 @@ func (d *videoDetector) detectWithHive(ctx context.Context, videoURL string) (fl
 
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return 0, providerUnreachable("hive", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("hive API returned status %d", resp.StatusCode)
+		return 0, providerStatusError("hive", resp.StatusCode, nil)
 	}
 
 	var result struct {
@@ -592,14 +602,3 @@ func (d *videoDetector) detectWithHive(ctx context.Context, videoURL string) (fl
 
 	return 0, errors.New("no result from Hive API")
 }
-
-func (d *videoDetector) aggregateScores(scores []float64) float64 {
-	if len(scores) == 0 {
-		return 0.5
-	}
-	sum := 0.0
-	for _, s := range scores {
-		sum += s
-	}
-	return sum / float64(len(scores))
-}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// =============================================================================
+// Streaming Media Input
+// =============================================================================
+//
+// The media analyzers don't need a whole file in memory. Format detection and
+// metadata live in the header, and the pattern signals only look at a handful
+// of regions spread across the file.
+//
+// mediaSample keeps:
+//   1. The header, verbatim (sized per analyzer)
+//   2. The entropy of every chunk after it
+//   3. A bounded set of region samples, thinned out as the file grows
+//   4. The last few bytes (e.g. ID3v1 tags)
+//   5. Which of a fixed set of signatures appeared anywhere
+//
+// Inputs that fit in the header are kept whole, so small files are analyzed
+// exactly as before. Past that, region lookups return the nearest sample.
+//
+// =============================================================================
+
+const (
+	// streamChunkSize is the read size once past the header
+	streamChunkSize = 64 * 1024
+
+	// streamSampleSize is how much of a chunk is kept as a region sample.
+	// Large enough for the biggest region any analyzer compares.
+	streamSampleSize = 8 * 1024
+
+	// maxStreamSamples bounds retained region samples (512KB at most)
+	maxStreamSamples = 64
+
+	// streamTailSize is how many trailing bytes are kept
+	streamTailSize = 1024
+)
+
+// mediaSample is the part of a media file kept in memory for analysis.
+type mediaSample struct {
+	// header is the start of the input, or all of it when complete
+	header []byte
+
+	// size is the total input length
+	size int64
+
+	// complete means header holds the entire input
+	complete bool
+
+	// chunks are per-chunk entropies of a streamed input
+	chunks []chunkEntropy
+
+	// samples are region samples, one per stride chunks
+	samples []regionSample
+	stride  int
+
+	// tail is the last streamTailSize bytes of a streamed input
+	tail []byte
+
+	// tokens are the signatures searched for while streaming
+	tokens [][]byte
+	found  map[string]bool
+	carry  []byte
+}
+
+// chunkEntropy is the byte entropy of one streamed chunk.
+type chunkEntropy struct {
+	offset  int64
+	entropy float64
+}
+
+// regionSample is the start of one streamed chunk.
+type regionSample struct {
+	index  int
+	offset int64
+	data   []byte
+}
+
+// readMediaSample reads r into a mediaSample.
+//
+// headerSize bytes are buffered first. If extend is non-nil it sees that
+// prefix and may ask for a larger header (e.g. to cover a whole container
+// header). tokens are the signatures Contains can answer for streamed input.
+// Streaming stops with ctx's error if ctx is cancelled.
+func readMediaSample(ctx context.Context, r io.Reader, headerSize int, extend func(prefix []byte) int, tokens ...[]byte) (*mediaSample, error) {
+	s := &mediaSample{stride: 1, tokens: tokens}
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(r, header)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.finish(header[:n]), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if extend != nil {
+		if want := extend(header); want > len(header) {
+			grown := make([]byte, want)
+			copy(grown, header)
+			n, err := io.ReadFull(r, grown[len(header):])
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return s.finish(grown[:len(header)+n]), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			header = grown
+		}
+	}
+
+	// Input is larger than the header - stream the rest
+	s.header = header
+	s.found = make(map[string]bool, len(tokens))
+	for _, tok := range tokens {
+		s.found[string(tok)] = false
+	}
+	for offset := 0; offset < len(header); offset += streamChunkSize {
+		s.add(header[offset:min(offset+streamChunkSize, len(header))])
+	}
+
+	buf := make([]byte, streamChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			s.add(buf[:n])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// finish records an input that fit entirely in the header.
+func (s *mediaSample) finish(data []byte) *mediaSample {
+	s.header = data
+	s.size = int64(len(data))
+	s.complete = true
+	return s
+}
+
+// add records one chunk of a streamed input.
+func (s *mediaSample) add(chunk []byte) {
+	offset := s.size
+	index := len(s.chunks)
+	s.size += int64(len(chunk))
+
+	s.chunks = append(s.chunks, chunkEntropy{offset: offset, entropy: calculateEntropy(chunk)})
+
+	// Chunks inside the header can be read from it directly
+	if offset+int64(len(chunk)) > int64(len(s.header)) && index%s.stride == 0 {
+		sample := make([]byte, min(len(chunk), streamSampleSize))
+		copy(sample, chunk)
+		s.samples = append(s.samples, regionSample{index: index, offset: offset, data: sample})
+
+		// Too many samples: keep every other one
+		if len(s.samples) > maxStreamSamples {
+			s.stride *= 2
+			kept := s.samples[:0]
+			for _, rs := range s.samples {
+				if rs.index%s.stride == 0 {
+					kept = append(kept, rs)
+				}
+			}
+			s.samples = kept
+		}
+	}
+
+	s.scanTokens(chunk)
+
+	if len(chunk) >= streamTailSize {
+		s.tail = append(s.tail[:0], chunk[len(chunk)-streamTailSize:]...)
+	} else {
+		s.tail = append(s.tail, chunk...)
+		if over := len(s.tail) - streamTailSize; over > 0 {
+			s.tail = append(s.tail[:0], s.tail[over:]...)
+		}
+	}
+}
+
+// scanTokens marks signatures found in chunk, including ones split across
+// the previous chunk boundary.
+func (s *mediaSample) scanTokens(chunk []byte) {
+	longest := 0
+	for _, tok := range s.tokens {
+		longest = max(longest, len(tok))
+		if s.found[string(tok)] {
+			continue
+		}
+		if bytes.Contains(chunk, tok) {
+			s.found[string(tok)] = true
+			continue
+		}
+		if len(s.carry) > 0 {
+			boundary := append(s.carry[:len(s.carry):len(s.carry)], chunk[:min(len(chunk), len(tok)-1)]...)
+			if bytes.Contains(boundary, tok) {
+				s.found[string(tok)] = true
+			}
+		}
+	}
+
+	if longest > 1 {
+		keep := min(len(chunk), longest-1)
+		s.carry = append(s.carry[:0], chunk[len(chunk)-keep:]...)
+	}
+}
+
+// Len returns the total input length.
+func (s *mediaSample) Len() int64 {
+	return s.size
+}
+
+// Region returns up to n bytes at offset. For streamed input past the
+// header, this is the retained sample nearest to offset.
+func (s *mediaSample) Region(offset int64, n int) []byte {
+	if offset < 0 || offset >= s.size || n <= 0 {
+		return nil
+	}
+
+	if s.complete || offset+int64(n) <= int64(len(s.header)) {
+		end := offset + int64(n)
+		if end > int64(len(s.header)) {
+			end = int64(len(s.header))
+		}
+		if offset >= end {
+			return nil
+		}
+		return s.header[offset:end]
+	}
+
+	if len(s.samples) == 0 {
+		return nil
+	}
+
+	nearest := s.samples[0]
+	for _, rs := range s.samples[1:] {
+		if distance(rs.offset, offset) < distance(nearest.offset, offset) {
+			nearest = rs
+		}
+	}
+
+	return nearest.data[:min(n, len(nearest.data))]
+}
+
+// SpanEntropy returns the byte entropy of [start, end). For streamed input
+// this is the mean entropy of the chunks starting in the span.
+func (s *mediaSample) SpanEntropy(start, end int64) float64 {
+	if s.complete {
+		return calculateEntropy(s.header[start:end])
+	}
+
+	sum := 0.0
+	count := 0
+	var containing *chunkEntropy
+
+	for i := range s.chunks {
+		c := &s.chunks[i]
+		if c.offset <= start {
+			containing = c
+		}
+		if c.offset >= start && c.offset < end {
+			sum += c.entropy
+			count++
+		}
+	}
+
+	if count > 0 {
+		return sum / float64(count)
+	}
+	if containing != nil {
+		return containing.entropy
+	}
+	return 0
+}
+
+// Contains reports whether tok appears anywhere in the input. For streamed
+// input, tok must be one of the tokens passed to readMediaSample; other
+// tokens are only searched for in the header.
+func (s *mediaSample) Contains(tok []byte) bool {
+	if !s.complete {
+		if found, ok := s.found[string(tok)]; ok {
+			return found
+		}
+	}
+	return bytes.Contains(s.header, tok)
+}
+
+// distance returns |a - b|.
+func distance(a, b int64) int64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// Tail returns the last n bytes of the input (at most streamTailSize for
+// streamed input).
+func (s *mediaSample) Tail(n int) []byte {
+	data := s.header
+	if !s.complete {
+		data = s.tail
+	}
+	if n > len(data) {
+		n = len(data)
+	}
+	return data[len(data)-n:]
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// syntheticMedia returns a reader of size bytes: header followed by
+// pseudo-random content, without holding the file in memory.
+func syntheticMedia(header []byte, size int64) io.Reader {
+	body := io.LimitReader(rand.New(rand.NewSource(1)), size-int64(len(header)))
+	return io.MultiReader(bytes.NewReader(header), body)
+}
+
+// failingReader returns data and then an error.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+// TestReadMediaSample tests header buffering and streamed sampling.
+func TestReadMediaSample(t *testing.T) {
+	t.Run("small input is kept whole", func(t *testing.T) {
+		data := []byte("small media file")
+		s, err := readMediaSample(context.Background(), bytes.NewReader(data), 1024, nil)
+		if err != nil {
+			t.Fatalf("readMediaSample() error = %v", err)
+		}
+		if !s.complete || !bytes.Equal(s.header, data) || s.Len() != int64(len(data)) {
+			t.Errorf("expected complete sample of %d bytes, got complete=%v len=%d", len(data), s.complete, s.Len())
+		}
+	})
+
+	t.Run("large input is streamed", func(t *testing.T) {
+		size := int64(20 * streamChunkSize)
+		data := make([]byte, size)
+		rand.New(rand.NewSource(2)).Read(data)
+		// Token split across a chunk boundary
+		copy(data[3*streamChunkSize-2:], "mdat")
+		copy(data[size-128:], "TAG")
+
+		s, err := readMediaSample(context.Background(), bytes.NewReader(data), streamChunkSize, nil, []byte("mdat"), []byte("none"))
+		if err != nil {
+			t.Fatalf("readMediaSample() error = %v", err)
+		}
+
+		if s.complete {
+			t.Fatal("expected streamed sample")
+		}
+		if s.Len() != size {
+			t.Errorf("Len() = %d, want %d", s.Len(), size)
+		}
+		if !s.Contains([]byte("mdat")) {
+			t.Error("expected token across chunk boundary to be found")
+		}
+		if s.Contains([]byte("none")) {
+			t.Error("unexpected token found")
+		}
+		if tail := s.Tail(128); !bytes.Equal(tail[:3], []byte("TAG")) {
+			t.Errorf("Tail(128) = %q..., want TAG", tail[:3])
+		}
+
+		offset := int64(7 * streamChunkSize)
+		if got := s.Region(offset, 100); !bytes.Equal(got, data[offset:offset+100]) {
+			t.Error("Region() at a sampled chunk should return its bytes")
+		}
+	})
+
+	t.Run("samples stay bounded", func(t *testing.T) {
+		size := int64(4 * maxStreamSamples * streamChunkSize)
+		s, err := readMediaSample(context.Background(), syntheticMedia(nil, size), 1024, nil)
+		if err != nil {
+			t.Fatalf("readMediaSample() error = %v", err)
+		}
+		if len(s.samples) > maxStreamSamples {
+			t.Errorf("kept %d samples, want at most %d", len(s.samples), maxStreamSamples)
+		}
+		if got := s.Region(size/2, 1000); len(got) != 1000 {
+			t.Errorf("Region() returned %d bytes, want 1000", len(got))
+		}
+	})
+
+	t.Run("extend grows the header", func(t *testing.T) {
+		data := make([]byte, 4096)
+		s, err := readMediaSample(context.Background(), bytes.NewReader(data), 100, func(prefix []byte) int { return 2048 })
+		if err != nil {
+			t.Fatalf("readMediaSample() error = %v", err)
+		}
+		if len(s.header) != 2048 {
+			t.Errorf("header is %d bytes, want 2048", len(s.header))
+		}
+	})
+
+	t.Run("cancelled context stops streaming", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := readMediaSample(ctx, syntheticMedia(nil, 64<<20), 1024, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("read errors are returned", func(t *testing.T) {
+		readErr := errors.New("connection reset")
+		_, err := readMediaSample(context.Background(), &failingReader{data: make([]byte, 5000), err: readErr}, 1024, nil)
+		if !errors.Is(err, readErr) {
+			t.Errorf("error = %v, want %v", err, readErr)
+		}
+	})
+}
+
+// TestAnalyzeFromReader tests that the reader methods match Analyze and
+// still see the end of large files.
+func TestAnalyzeFromReader(t *testing.T) {
+	t.Run("video matches Analyze for small input", func(t *testing.T) {
+		data := append([]byte{0x00, 0x00, 0x00, 0x14, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm'}, make([]byte, 5000)...)
+		analyzer := NewVideoAnalyzer()
+
+		got, err := analyzer.AnalyzeFromReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("AnalyzeFromReader() error = %v", err)
+		}
+		if want := analyzer.Analyze(data); got != want {
+			t.Errorf("AnalyzeFromReader() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("audio finds ID3v1 tag at end of large file", func(t *testing.T) {
+		size := int64(4 << 20)
+		tag := append([]byte("TAG"), make([]byte, 125)...)
+		r := io.MultiReader(syntheticMedia([]byte{0xFF, 0xFB, 0x90, 0x00}, size-128), bytes.NewReader(tag))
+
+		result, err := NewAudioAnalyzer().AnalyzeFromReader(r)
+		if err != nil {
+			t.Fatalf("AnalyzeFromReader() error = %v", err)
+		}
+		if !result.Metadata.HasID3 {
+			t.Error("expected ID3v1 tag to be detected")
+		}
+		if result.Stats.FileSize != size {
+			t.Errorf("FileSize = %d, want %d", result.Stats.FileSize, size)
+		}
+	})
+
+	t.Run("video finds codec past the header", func(t *testing.T) {
+		header := []byte{0x00, 0x00, 0x00, 0x14, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm', 0, 0, 0, 0, 0, 0, 0, 0}
+		r := io.MultiReader(syntheticMedia(header, 1<<20), bytes.NewReader([]byte("avc1")))
+
+		result, err := NewVideoAnalyzer().AnalyzeFromReader(r)
+		if err != nil {
+			t.Fatalf("AnalyzeFromReader() error = %v", err)
+		}
+		if result.Signals.EncodingSignature != 0.45 {
+			t.Errorf("EncodingSignature = %f, want 0.45 (codec found)", result.Signals.EncodingSignature)
+		}
+	})
+
+	t.Run("image read error", func(t *testing.T) {
+		_, err := NewImageAnalyzer().AnalyzeFromReader(&failingReader{err: io.ErrClosedPipe})
+		if !errors.Is(err, io.ErrClosedPipe) {
+			t.Errorf("error = %v, want io.ErrClosedPipe", err)
+		}
+	})
+}
+
+// TestVideoContainerHeaderSize tests header sizing for streamed video.
+func TestVideoContainerHeaderSize(t *testing.T) {
+	atom := func(size int, kind string) []byte {
+		b := []byte{byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size), 0, 0, 0, 0}
+		copy(b[4:], kind)
+		return b
+	}
+
+	t.Run("faststart mp4 covers moov", func(t *testing.T) {
+		prefix := append(atom(24, "ftyp"), make([]byte, 16)...)
+		prefix = append(prefix, atom(500_000, "moov")...)
+		if got := videoContainerHeaderSize(prefix); got != 500_024 {
+			t.Errorf("got %d, want 500024", got)
+		}
+	})
+
+	t.Run("moov after mdat is unknown", func(t *testing.T) {
+		prefix := append(atom(24, "ftyp"), make([]byte, 16)...)
+		prefix = append(prefix, atom(900_000, "mdat")...)
+		if got := videoContainerHeaderSize(prefix); got != 0 {
+			t.Errorf("got %d, want 0", got)
+		}
+	})
+
+	t.Run("size is capped", func(t *testing.T) {
+		prefix := append(atom(24, "ftyp"), make([]byte, 16)...)
+		prefix = append(prefix, atom(1<<30, "moov")...)
+		if got := videoContainerHeaderSize(prefix); got != maxVideoHeaderSize {
+			t.Errorf("got %d, want %d", got, maxVideoHeaderSize)
+		}
+	})
+}
+
+// benchmarkMediaMemory compares reading a whole file into memory before
+// analysis with streaming it. Run with -benchmem to see the difference.
+func benchmarkMediaMemory(b *testing.B, header []byte, size int64, analyze func(io.Reader) error) {
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, err := io.ReadAll(syntheticMedia(header, size))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := analyze(bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := analyze(syntheticMedia(header, size)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkVideoAnalyzerMemory benchmarks memory use on a 64MB video.
+func BenchmarkVideoAnalyzerMemory(b *testing.B) {
+	analyzer := NewVideoAnalyzer()
+	header := []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'i', 's', 'o', 'm', 0, 0, 0, 0, 'i', 's', 'o', 'm', 'a', 'v', 'c', '1'}
+
+	benchmarkMediaMemory(b, header, 64<<20, func(r io.Reader) error {
+		_, err := analyzer.AnalyzeFromReader(r)
+		return err
+	})
+}
+
+// BenchmarkAudioAnalyzerMemory benchmarks memory use on a 16MB audio file.
+func BenchmarkAudioAnalyzerMemory(b *testing.B) {
+	analyzer := NewAudioAnalyzer()
+	header := []byte{'I', 'D', '3', 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, 0xFB, 0x90, 0x00}
+
+	benchmarkMediaMemory(b, header, 16<<20, func(r io.Reader) error {
+		_, err := analyzer.AnalyzeFromReader(r)
+		return err
+	})
+}
+
+// BenchmarkImageAnalyzerMemory benchmarks memory use on an 8MB image.
+func BenchmarkImageAnalyzerMemory(b *testing.B) {
+	analyzer := NewImageAnalyzer()
+	header := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46}
+
+	benchmarkMediaMemory(b, header, 8<<20, func(r io.Reader) error {
+		_, err := analyzer.AnalyzeFromReader(r)
+		return err
+	})
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/tracing"
+)
+
+// detectorMetrics are the detection pipeline's metrics. Each detector
+// registers them from DetectorConfig.Metrics; the registry hands every
+// detector the same instances.
+type detectorMetrics struct {
+	detectDuration   metrics.Histogram // content_type
+	analyzerDuration metrics.Histogram // content_type
+	providerDuration metrics.Histogram // content_type, provider
+	providerErrors   metrics.Counter   // content_type, provider
+	verdicts         metrics.Counter   // content_type, verdict
+	aiScores         metrics.Histogram // content_type
+	providerScores   metrics.Histogram // content_type, provider
+}
+
+// newDetectorMetrics registers the pipeline metrics with reg
+// (nil records nothing).
+func newDetectorMetrics(reg metrics.Registry) *detectorMetrics {
+	if reg == nil {
+		reg = metrics.Discard
+	}
+	return &detectorMetrics{
+		detectDuration: reg.Histogram("humanmark_detect_duration_seconds",
+			"Time to analyze one input, including fetching URLs.", metrics.DurationBuckets, "content_type"),
+		analyzerDuration: reg.Histogram("humanmark_analyzer_duration_seconds",
+			"Time spent computing local analyzer signals.", metrics.DurationBuckets, "content_type"),
+		providerDuration: reg.Histogram("humanmark_provider_duration_seconds",
+			"External detection provider call latency.", metrics.DurationBuckets, "content_type", "provider"),
+		providerErrors: reg.Counter("humanmark_provider_errors_total",
+			"External detection provider calls that failed.", "content_type", "provider"),
+		verdicts: reg.Counter("humanmark_verdicts_total",
+			"Completed detections by verdict.", "content_type", "verdict"),
+		aiScores: reg.Histogram("humanmark_ai_score",
+			"Distribution of final AI scores.", metrics.ScoreBuckets, "content_type"),
+		providerScores: reg.Histogram("humanmark_provider_ai_score",
+			"Distribution of AI scores returned by each external provider.", metrics.ScoreBuckets, "content_type", "provider"),
+	}
+}
+
+// runAnalyzer runs a local analyzer under runDetector in a
+// "humanmark.analyze" span and records how long it took. fn gets the
+// span's context.
+func (m *detectorMetrics) runAnalyzer(ctx context.Context, contentType ContentType, fn func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, "humanmark.analyze", tracing.String("content_type", string(contentType)))
+	defer span.End()
+
+	start := time.Now()
+	err := runDetector(func() error { return fn(ctx) })
+	m.analyzerDuration.Observe(time.Since(start).Seconds(), string(contentType))
+	span.RecordError(err)
+	return err
+}
+
+// runProvider runs an external provider call under runDetector in a
+// "humanmark.provider" span and records its latency, score and failures.
+// Providers that skip the input aren't counted. fn gets the span's context,
+// cancelled after timeout if it's positive, and returns the provider's AI
+// score.
+func (m *detectorMetrics) runProvider(ctx context.Context, contentType ContentType, name string, timeout time.Duration, fn func(ctx context.Context) (float64, error)) (float64, error) {
+	ctx, span := tracing.Start(ctx, "humanmark.provider",
+		tracing.String("content_type", string(contentType)),
+		tracing.String("provider", name),
+	)
+	defer span.End()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var score float64
+	err := runDetector(func() (err error) {
+		score, err = fn(ctx)
+		return err
+	})
+	if errors.Is(err, ErrProviderSkipped) {
+		span.SetAttributes(tracing.Bool("skipped", true))
+		return score, err
+	}
+
+	m.providerDuration.Observe(time.Since(start).Seconds(), string(contentType), name)
+	if err != nil {
+		m.providerErrors.Inc(string(contentType), name)
+		span.RecordError(err)
+		return score, err
+	}
+	span.SetAttributes(tracing.Float64("ai_score", score))
+	return score, nil
+}
+
+// recordVerdict records a completed detection's verdict and score, and
+// each external provider's score so drift in one provider shows up on its
+// own.
+func (m *detectorMetrics) recordVerdict(result *DetectionResult) {
+	m.verdicts.Inc(string(result.ContentType), string(result.Verdict))
+	m.aiScores.Observe(result.AIScore, string(result.ContentType))
+	for _, p := range result.ProviderScores {
+		// The local analyzer isn't an external provider
+		if p.Name == "humanmark" {
+			continue
+		}
+		m.providerScores.Observe(p.AIScore, string(result.ContentType), p.Name)
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+	"github.com/humanmark/humanmark/pkg/metrics"
+	"github.com/humanmark/humanmark/pkg/tracing"
+)
+
+// TestProviderMetrics tests provider latency and error counts.
+func TestProviderMetrics(t *testing.T) {
+	winston := cannedResponse(http.StatusOK, `{"score":30}`)
+	defer winston.Close()
+	originality := cannedResponse(http.StatusInternalServerError, `{"error":"down"}`)
+	defer originality.Close()
+
+	reg := metrics.NewRegistry()
+	d := newTextProviderTestDetector(DetectorConfig{WinstonAPIKey: "k", OriginalityAPIKey: "k", Metrics: reg}, winston.URL)
+	d.originalityURL = originality.URL
+
+	if _, err := d.DetectText(context.Background(), DetectionInput{Text: longText}); err != nil {
+		t.Fatalf("DetectText() error = %v", err)
+	}
+
+	var b strings.Builder
+	reg.WritePrometheus(&b)
+	out := b.String()
+
+	for _, series := range []string{
+		`humanmark_provider_duration_seconds_count{content_type="text",provider="winston"} 1`,
+		`humanmark_provider_duration_seconds_count{content_type="text",provider="originality"} 1`,
+		`humanmark_provider_errors_total{content_type="text",provider="originality"} 1`,
+		`humanmark_analyzer_duration_seconds_count{content_type="text"} 1`,
+	} {
+		if !strings.Contains(out, series) {
+			t.Errorf("missing %s in:\n%s", series, out)
+		}
+	}
+	if strings.Contains(out, `humanmark_provider_errors_total{content_type="text",provider="winston"}`) {
+		t.Errorf("winston succeeded but has an error series:\n%s", out)
+	}
+}
+
+// TestVerdictMetrics tests verdict counts and the final and per-provider
+// score distributions.
+func TestVerdictMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := newDetectorMetrics(reg)
+
+	for _, result := range []*DetectionResult{
+		{ContentType: ContentTypeText, Verdict: VerdictAI, AIScore: 0.92, ProviderScores: []ProviderScore{
+			{Name: "humanmark", AIScore: 0.8}, {Name: "winston", AIScore: 0.95},
+		}},
+		{ContentType: ContentTypeText, Verdict: VerdictHuman, AIScore: 0.15, ProviderScores: []ProviderScore{
+			{Name: "humanmark", AIScore: 0.2}, {Name: "winston", AIScore: 0.05},
+		}},
+		{ContentType: ContentTypeImage, Verdict: VerdictHuman, AIScore: 0, ProviderScores: []ProviderScore{
+			{Name: "hive", AIScore: 0},
+		}},
+	} {
+		m.recordVerdict(result)
+	}
+
+	var b strings.Builder
+	reg.WritePrometheus(&b)
+	out := b.String()
+
+	for _, series := range []string{
+		`humanmark_verdicts_total{content_type="text",verdict="ai"} 1`,
+		`humanmark_verdicts_total{content_type="text",verdict="human"} 1`,
+		`humanmark_verdicts_total{content_type="image",verdict="human"} 1`,
+		`humanmark_ai_score_bucket{content_type="text",le="0.1"} 0`,
+		`humanmark_ai_score_bucket{content_type="text",le="0.2"} 1`,
+		`humanmark_ai_score_bucket{content_type="text",le="0.9"} 1`,
+		`humanmark_ai_score_bucket{content_type="text",le="1"} 2`,
+		`humanmark_ai_score_count{content_type="text"} 2`,
+		`humanmark_ai_score_bucket{content_type="image",le="0"} 1`,
+		`humanmark_provider_ai_score_bucket{content_type="text",provider="winston",le="0.1"} 1`,
+		`humanmark_provider_ai_score_count{content_type="text",provider="winston"} 2`,
+		`humanmark_provider_ai_score_count{content_type="image",provider="hive"} 1`,
+	} {
+		if !strings.Contains(out, series) {
+			t.Errorf("missing %s in:\n%s", series, out)
+		}
+	}
+	if strings.Contains(out, `provider="humanmark"`) {
+		t.Errorf("local analyzer recorded as a provider:\n%s", out)
+	}
+}
+
+// TestSpans tests the span hierarchy of a text verification: detect, then
+// the local analyzer and each provider beneath it.
+func TestSpans(t *testing.T) {
+	winston := cannedResponse(http.StatusOK, `{"score":30}`)
+	defer winston.Close()
+	originality := cannedResponse(http.StatusInternalServerError, `{"error":"down"}`)
+	defer originality.Close()
+
+	d, _ := NewDetector(DetectorConfig{Timeout: 30 * time.Second, WinstonAPIKey: "k", OriginalityAPIKey: "k"}, logger.NopLogger())
+	text := d.(*liveDetector).current.Load().textDetector.(*textDetector)
+	text.winstonURL = winston.URL
+	text.originalityURL = originality.URL
+
+	rec := tracing.NewRecorder()
+	ctx, root := tracing.Start(tracing.WithTracer(context.Background(), rec), "POST /verify")
+	if _, err := d.Detect(ctx, DetectionInput{Text: longText}); err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	root.End()
+
+	spans := make(map[string]tracing.RecordedSpan)
+	for _, s := range rec.Spans() {
+		key := s.Name
+		if provider, ok := s.Attributes["provider"].(string); ok {
+			key += " " + provider
+		}
+		spans[key] = s
+		if s.Context.TraceID != root.SpanContext().TraceID {
+			t.Errorf("%s is in another trace", key)
+		}
+	}
+
+	parents := map[string]string{
+		"humanmark.detect":               "POST /verify",
+		"humanmark.analyze":              "humanmark.detect",
+		"humanmark.provider winston":     "humanmark.detect",
+		"humanmark.provider originality": "humanmark.detect",
+	}
+	for name, parent := range parents {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("missing span %s", name)
+			continue
+		}
+		if s.Parent != spans[parent].Context {
+			t.Errorf("%s: parent is not %s", name, parent)
+		}
+	}
+
+	detect := spans["humanmark.detect"]
+	if detect.Attributes["content_type"] != "text" || detect.Attributes["verdict"] == nil || detect.Attributes["ai_score"] == nil {
+		t.Errorf("detect attributes = %v", detect.Attributes)
+	}
+	// Winston scores how human the text is
+	if got := spans["humanmark.provider winston"]; got.Attributes["ai_score"] != 0.7 || got.Err != nil {
+		t.Errorf("winston: attributes %v, error %v", got.Attributes, got.Err)
+	}
+	if got := spans["humanmark.provider originality"]; got.Err == nil {
+		t.Error("originality failed but its span has no error")
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// OutboundConfig configures the HTTP client external providers are called
+// with, for networks where egress goes through a proxy or TLS is
+// intercepted by a private CA.
+type OutboundConfig struct {
+	// ProxyURL sends provider calls through this proxy (default: the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables)
+	ProxyURL string
+
+	// CAFile is a PEM bundle of CA certificates trusted besides the
+	// system's (optional)
+	CAFile string
+
+	// InsecureSkipVerify accepts any TLS certificate. Only enable this in
+	// development.
+	InsecureSkipVerify bool
+
+	// MaxIdleConns caps idle connections kept across all providers
+	// (0 = no limit)
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost caps idle connections kept per provider
+	// (0 = net/http's default of 2)
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost caps connections per provider, including ones in
+	// use (0 = no limit)
+	MaxConnsPerHost int
+}
+
+// NewOutboundClient returns a client for calls to external providers,
+// configured by config, whose requests time out after timeout (0 = no
+// limit). It's meant to be built once and shared through
+// DetectorConfig.HTTPClient, so detectors share its connection pool.
+func NewOutboundClient(config OutboundConfig, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if config.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		u, err := url.Parse(config.ProxyURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", config.ProxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// providerClient returns the client config's provider calls are made with:
+// the shared one if it has one, or a default one with its timeout.
+func providerClient(config DetectorConfig) *http.Client {
+	if config.HTTPClient != nil {
+		return config.HTTPClient
+	}
+	return &http.Client{Timeout: config.Timeout}
+}
+
+// providerBaseURLs are the external providers' API base URLs, which
+// DetectorConfig.ProviderURLs overrides.
+var providerBaseURLs = map[string]string{
+	"hive":        "https://api.thehive.ai",
+	"openai":      "https://api.openai.com",
+	"gptzero":     "https://api.gptzero.me",
+	"originality": "https://api.originality.ai",
+	"winston":     "https://api.gowinston.ai",
+	"sightengine": "https://api.sightengine.com",
+	"aiornot":     "https://api.aiornot.com",
+	"anthropic":   "https://api.anthropic.com",
+}
+
+// Provider API paths, under their base URLs
+const (
+	hivePath        = "/api/v2/task/sync"
+	openaiPath      = "/v1/chat/completions"
+	gptzeroPath     = "/v2/predict/text"
+	originalityPath = "/api/v1/scan/ai"
+	winstonPath     = "/v2/ai-content-detection"
+	sightenginePath = "/1.0/check.json"
+	aiornotPath     = "/v1/reports/image"
+	anthropicPath   = "/v1/messages"
+)
+
+// providerEndpoint returns the URL of path on the named provider's API,
+// under the base URL config overrides it with, if any.
+func providerEndpoint(config DetectorConfig, name, path string) string {
+	base := providerBaseURLs[name]
+	if override := config.ProviderURLs[name]; override != "" {
+		base = strings.TrimSuffix(override, "/")
+	}
+	return base + path
+}
+
+// ValidateProviderURLs checks that urls names known providers and gives
+// each an absolute http or https base URL.
+func ValidateProviderURLs(urls map[string]string) error {
+	for name, raw := range urls {
+		if _, ok := providerBaseURLs[name]; !ok {
+			return fmt.Errorf("unknown provider %q", name)
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New(name + ": base URL must be an absolute http or https URL")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// writeCABundle writes server's certificate to a PEM file and returns its
+// path, as a private CA's bundle would be.
+func writeCABundle(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write CA bundle: %v", err)
+	}
+	return path
+}
+
+// TestOutboundClient verifies the shared client for provider calls.
+func TestOutboundClient(t *testing.T) {
+	var gotPath, gotKey string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("Authorization")
+		w.Write([]byte(`{"status":[{"response":{"ai_generated":0.8}}]}`))
+	}))
+	defer server.Close()
+
+	t.Run("trusts a custom CA", func(t *testing.T) {
+		client, err := NewOutboundClient(OutboundConfig{}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewOutboundClient() error = %v", err)
+		}
+		if _, err := client.Get(server.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+			t.Errorf("expected a certificate error without the CA, got %v", err)
+		}
+
+		client, err = NewOutboundClient(OutboundConfig{CAFile: writeCABundle(t, server)}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewOutboundClient() error = %v", err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("skips verification when asked", func(t *testing.T) {
+		client, err := NewOutboundClient(OutboundConfig{InsecureSkipVerify: true}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewOutboundClient() error = %v", err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("rejects bad CA bundles", func(t *testing.T) {
+		if _, err := NewOutboundClient(OutboundConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}, 0); err == nil {
+			t.Error("expected an error for a missing bundle")
+		}
+		empty := filepath.Join(t.TempDir(), "empty.pem")
+		os.WriteFile(empty, []byte("not a certificate"), 0o600)
+		if _, err := NewOutboundClient(OutboundConfig{CAFile: empty}, 0); err == nil {
+			t.Error("expected an error for a bundle without certificates")
+		}
+	})
+
+	t.Run("sends requests through the proxy", func(t *testing.T) {
+		var proxied string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = r.URL.String()
+		}))
+		defer proxy.Close()
+
+		client, err := NewOutboundClient(OutboundConfig{ProxyURL: proxy.URL}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewOutboundClient() error = %v", err)
+		}
+		resp, err := client.Get("http://provider.invalid/v1/check")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+		if proxied != "http://provider.invalid/v1/check" {
+			t.Errorf("proxy saw %q", proxied)
+		}
+	})
+
+	t.Run("providers are called at their overridden base URLs", func(t *testing.T) {
+		client, err := NewOutboundClient(OutboundConfig{CAFile: writeCABundle(t, server)}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewOutboundClient() error = %v", err)
+		}
+		d := NewTextDetector(DetectorConfig{
+			HiveAPIKey:   "hive-key",
+			HTTPClient:   client,
+			ProviderURLs: map[string]string{"hive": server.URL + "/gateway/hive/"},
+		}, logger.NopLogger()).(*textDetector)
+
+		score, err := d.detectWithHive(context.Background(), longText)
+		if err != nil {
+			t.Fatalf("detectWithHive() error = %v", err)
+		}
+		if score != 0.8 || gotPath != "/gateway/hive"+hivePath || gotKey != "Token hive-key" {
+			t.Errorf("score = %g, path = %q, key = %q", score, gotPath, gotKey)
+		}
+	})
+
+	t.Run("URL inputs are fetched with the client's TLS settings", func(t *testing.T) {
+		client, err := NewOutboundClient(OutboundConfig{CAFile: writeCABundle(t, server), ProxyURL: "http://proxy.invalid:3128"}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("NewOutboundClient() error = %v", err)
+		}
+		fetcher := newURLFetcherFromConfig(DetectorConfig{HTTPClient: client, AllowPrivateNetworks: true})
+		result, err := fetcher.Fetch(context.Background(), server.URL+"/page", 1024)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if !strings.Contains(string(result.Data), "ai_generated") {
+			t.Errorf("unexpected body %q", result.Data)
+		}
+	})
+}
+
+// TestValidateProviderURLs verifies that base URL overrides are checked.
+func TestValidateProviderURLs(t *testing.T) {
+	if err := ValidateProviderURLs(map[string]string{"winston": "https://gateway.internal/winston"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, urls := range []map[string]string{
+		{"acme": "https://gateway.internal"},
+		{"winston": "gateway.internal/winston"},
+		{"hive": "ftp://gateway.internal"},
+	} {
+		if err := ValidateProviderURLs(urls); err == nil {
+			t.Errorf("expected %v rejected", urls)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// =============================================================================
+// Page Verification
+// =============================================================================
+//
+// Page mode checks an article and everything it embeds in one call: the
+// page's main text plus each <img> and <video> (see page_extractor.go).
+// Each part goes through the Detector on its own, several at a time, and
+// an asset that fails gets an error in its entry rather than failing the
+// page.
+//
+// A page mustn't turn into a crawl, so:
+//
+//   - only the first PageMaxAssets assets are checked; the rest are counted
+//     in DroppedAssets
+//   - PageByteBudget bounds everything fetched for the page, the HTML
+//     included; assets fetched once it is spent fail
+//   - every fetch goes through the hardened fetcher, so assets get the same
+//     SSRF checks and redirect cap as the page itself
+//
+// =============================================================================
+
+// ErrEmptyPage is returned for an HTML page with no text or media to check.
+var ErrEmptyPage = errors.New("page has no text or media to verify")
+
+// Page verification defaults
+const (
+	DefaultPageMaxAssets  = 20
+	DefaultPageByteBudget = 100 * 1024 * 1024
+
+	// maxPageSize bounds the HTML itself
+	maxPageSize = 5 * 1024 * 1024
+
+	// minPageTextLength is the least extracted text worth scoring; less
+	// is usually a caption or a cookie notice
+	minPageTextLength = 200
+
+	// pageConcurrency is how many assets are checked at once
+	pageConcurrency = 4
+)
+
+// PageDetector verifies an HTML page and its embedded media.
+type PageDetector interface {
+	// DetectPage fetches input.URL. An HTML page has its text and media
+	// verified; anything else is verified as-is and returned as Page.
+	// Detector selection and custom vocabulary in input apply to every part.
+	DetectPage(ctx context.Context, input DetectionInput) (*PageResult, error)
+}
+
+// PageResult is the outcome of verifying a page.
+type PageResult struct {
+	// URL is the page URL after redirects
+	URL string
+
+	// HTML is false when the URL wasn't an HTML page; Page is then the
+	// content's own verdict and there are no assets
+	HTML bool
+
+	// Page is the verdict on the page's main text (nil if it had too
+	// little), or on the content itself when the URL wasn't HTML
+	Page *DetectionResult
+
+	// Assets are the checked images and videos, in page order
+	Assets []PageAsset
+
+	// DroppedAssets counts assets beyond PageMaxAssets that weren't checked
+	DroppedAssets int
+}
+
+// PageAsset is one embedded image or video.
+type PageAsset struct {
+	// URL is the absolute asset URL
+	URL string
+
+	// ContentType is image or video
+	ContentType ContentType
+
+	// Result is the verdict (nil if Error is set)
+	Result *DetectionResult
+
+	// Error says why the asset couldn't be checked
+	Error string
+}
+
+// pageDetector is the default PageDetector.
+type pageDetector struct {
+	detector   Detector
+	fetcher    *urlFetcher
+	logger     *logger.Logger
+	maxAssets  int
+	byteBudget int64
+}
+
+// NewPageDetector creates a PageDetector that verifies each part of a page
+// with detector. Fetching follows config's fetch policy and page limits.
+func NewPageDetector(detector Detector, config DetectorConfig, log *logger.Logger) PageDetector {
+	d := &pageDetector{
+		detector:   detector,
+		fetcher:    newURLFetcherFromConfig(config),
+		logger:     log.Component("page"),
+		maxAssets:  config.PageMaxAssets,
+		byteBudget: config.PageByteBudget,
+	}
+	if d.maxAssets <= 0 {
+		d.maxAssets = DefaultPageMaxAssets
+	}
+	if d.byteBudget <= 0 {
+		d.byteBudget = DefaultPageByteBudget
+	}
+	return d
+}
+
+// DetectPage verifies the page at input.URL and its embedded media.
+func (d *pageDetector) DetectPage(ctx context.Context, input DetectionInput) (*PageResult, error) {
+	if input.URL == "" {
+		return nil, errors.New("page verification requires a URL")
+	}
+	budget := &byteBudget{remaining: d.byteBudget}
+
+	pageLimit := int64(maxPageSize)
+	if d.byteBudget < pageLimit {
+		pageLimit = d.byteBudget
+	}
+	fetched, err := d.fetcher.Fetch(ctx, input.URL, pageLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	budget.spend(int64(len(fetched.Data)))
+
+	// Not a page; verify it like any other URL, without fetching it again
+	if !isHTML(fetched.ContentType, fetched.Data) {
+		input.Data = fetched.Data
+		input.ContentType = ContentTypeFromResponse(fetched.ContentType, fetched.Data)
+		result, err := d.detector.Detect(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return &PageResult{URL: fetched.FinalURL, Page: result}, nil
+	}
+
+	pageURL, err := url.Parse(fetched.FinalURL)
+	if err != nil {
+		return nil, &FetchError{Err: err}
+	}
+	page := extractPage(fetched.Data, pageURL)
+
+	hasText := len(page.Text) >= minPageTextLength
+	if !hasText && len(page.Assets) == 0 {
+		return nil, ErrEmptyPage
+	}
+
+	result := &PageResult{URL: fetched.FinalURL, HTML: true}
+	assets := page.Assets
+	if len(assets) > d.maxAssets {
+		result.DroppedAssets = len(assets) - d.maxAssets
+		assets = assets[:d.maxAssets]
+	}
+	result.Assets = make([]PageAsset, len(assets))
+
+	d.logger.Debug("verifying page",
+		"url", result.URL,
+		"text_length", len(page.Text),
+		"assets", len(assets),
+		"dropped_assets", result.DroppedAssets,
+	)
+
+	var wg sync.WaitGroup
+	var textErr error
+	if hasText {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.Page, textErr = d.detector.Detect(ctx, DetectionInput{
+				URL:               input.URL,
+				Text:              page.Text,
+				ContentType:       ContentTypeText,
+				CustomStopPhrases: input.CustomStopPhrases,
+				CustomCommonWords: input.CustomCommonWords,
+				Detectors:         input.Detectors,
+				ExcludeDetectors:  input.ExcludeDetectors,
+			})
+		}()
+	}
+
+	sem := make(chan struct{}, pageConcurrency)
+	for i, ref := range assets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result.Assets[i] = d.detectAsset(ctx, input, ref, budget)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if textErr != nil {
+		return nil, textErr
+	}
+	return result, nil
+}
+
+// detectAsset fetches and verifies one embedded asset.
+func (d *pageDetector) detectAsset(ctx context.Context, input DetectionInput, ref pageAssetRef, budget *byteBudget) PageAsset {
+	asset := PageAsset{URL: ref.URL, ContentType: ref.ContentType}
+
+	limit := budget.left()
+	if limit <= 0 {
+		asset.Error = "page byte budget exhausted"
+		return asset
+	}
+	fetched, err := d.fetcher.Fetch(ctx, ref.URL, limit)
+	if err != nil {
+		asset.Error = err.Error()
+		return asset
+	}
+	if !budget.spend(int64(len(fetched.Data))) {
+		asset.Error = "page byte budget exhausted"
+		return asset
+	}
+
+	contentType := ContentTypeFromResponse(fetched.ContentType, fetched.Data)
+	if contentType != ContentTypeImage && contentType != ContentTypeVideo {
+		asset.Error = fmt.Sprintf("not an image or video (%s)", contentType)
+		return asset
+	}
+	asset.ContentType = contentType
+
+	result, err := d.detector.Detect(ctx, DetectionInput{
+		URL:              ref.URL,
+		Data:             fetched.Data,
+		ContentType:      contentType,
+		Detectors:        input.Detectors,
+		ExcludeDetectors: input.ExcludeDetectors,
+	})
+	if err != nil {
+		d.logger.Warn("page asset detection failed", "url", ref.URL, "error", err)
+		asset.Error = err.Error()
+		return asset
+	}
+	asset.Result = result
+	return asset
+}
+
+// byteBudget is the bytes a page's fetches may still use. Concurrent
+// fetches each get the whole remainder as their limit, and a fetch that
+// overshoots what's left once it lands is refused.
+type byteBudget struct {
+	mu        sync.Mutex
+	remaining int64
+}
+
+// left returns the bytes remaining.
+func (b *byteBudget) left() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining
+}
+
+// spend takes n bytes from the budget, reporting false (and taking
+// nothing) if fewer remain.
+func (b *byteBudget) spend(n int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n > b.remaining {
+		return false
+	}
+	b.remaining -= n
+	return true
+}
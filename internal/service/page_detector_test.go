@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// recordingDetector returns a verdict for each input and records them.
+type recordingDetector struct {
+	mu     sync.Mutex
+	inputs []DetectionInput
+}
+
+func (d *recordingDetector) Detect(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inputs = append(d.inputs, input)
+	return &DetectionResult{ContentType: input.ContentType, Verdict: VerdictHuman, Human: true, Confidence: 0.9}, nil
+}
+
+// input returns the recorded input of the given type.
+func (d *recordingDetector) input(contentType ContentType) (DetectionInput, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, input := range d.inputs {
+		if input.ContentType == contentType {
+			return input, true
+		}
+	}
+	return DetectionInput{}, false
+}
+
+// newPageTestServer serves an article at /article embedding the given
+// image paths, a PNG at /img/*, and 404 elsewhere.
+func newPageTestServer(text string, images ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body><article><p>%s</p>", text)
+		for _, src := range images {
+			fmt.Fprintf(w, `<img src="%s">`, src)
+		}
+		fmt.Fprint(w, "</article></body></html>")
+	})
+	mux.HandleFunc("/img/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(testPNG)
+	})
+	return httptest.NewServer(mux)
+}
+
+// newPageTestDetector creates a page detector that may fetch from test servers.
+func newPageTestDetector(detector Detector, config DetectorConfig) PageDetector {
+	config.AllowPrivateNetworks = true
+	config.Timeout = 5 * time.Second
+	return NewPageDetector(detector, config, logger.NopLogger())
+}
+
+// TestDetectPage tests verifying a page's text and images.
+func TestDetectPage(t *testing.T) {
+	server := newPageTestServer(longText, "/img/a.png", "/missing.png", "/img/b.png")
+	defer server.Close()
+
+	detector := &recordingDetector{}
+	pages := newPageTestDetector(detector, DetectorConfig{})
+
+	result, err := pages.DetectPage(context.Background(), DetectionInput{
+		URL:       server.URL + "/article",
+		Detectors: []string{"local"},
+	})
+	if err != nil {
+		t.Fatalf("DetectPage() error = %v", err)
+	}
+
+	if !result.HTML || result.Page == nil || result.Page.ContentType != ContentTypeText {
+		t.Errorf("Page = %+v, want a text verdict", result.Page)
+	}
+	text, _ := detector.input(ContentTypeText)
+	if text.Text != strings.TrimSpace(longText) {
+		t.Errorf("text input = %q, want the article text", text.Text)
+	}
+	if len(text.Detectors) != 1 || text.Detectors[0] != "local" {
+		t.Errorf("text Detectors = %v, want the request's selection", text.Detectors)
+	}
+
+	if len(result.Assets) != 3 {
+		t.Fatalf("got %d assets, want 3", len(result.Assets))
+	}
+	for _, i := range []int{0, 2} {
+		asset := result.Assets[i]
+		if asset.Result == nil || asset.Error != "" {
+			t.Errorf("asset %s: Result = %v, Error = %q", asset.URL, asset.Result, asset.Error)
+		}
+		if asset.ContentType != ContentTypeImage {
+			t.Errorf("asset %s: ContentType = %s, want image", asset.URL, asset.ContentType)
+		}
+	}
+	if missing := result.Assets[1]; missing.Result != nil || !strings.Contains(missing.Error, "404") {
+		t.Errorf("missing asset = %+v, want a 404 error", missing)
+	}
+	image, _ := detector.input(ContentTypeImage)
+	if len(image.Data) != len(testPNG) {
+		t.Errorf("image input has %d bytes, want the fetched PNG", len(image.Data))
+	}
+}
+
+// TestDetectPageLimits tests the asset cap and byte budget.
+func TestDetectPageLimits(t *testing.T) {
+	images := []string{"/img/1.png", "/img/2.png", "/img/3.png", "/img/4.png", "/img/5.png"}
+	server := newPageTestServer(longText, images...)
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("assets over the cap are dropped", func(t *testing.T) {
+		pages := newPageTestDetector(&recordingDetector{}, DetectorConfig{PageMaxAssets: 2})
+
+		result, err := pages.DetectPage(ctx, DetectionInput{URL: server.URL + "/article"})
+		if err != nil {
+			t.Fatalf("DetectPage() error = %v", err)
+		}
+		if len(result.Assets) != 2 || result.DroppedAssets != 3 {
+			t.Errorf("got %d assets and %d dropped, want 2 and 3", len(result.Assets), result.DroppedAssets)
+		}
+		if !strings.HasSuffix(result.Assets[1].URL, "/img/2.png") {
+			t.Errorf("kept %s, want the first assets in page order", result.Assets[1].URL)
+		}
+	})
+
+	t.Run("byte budget", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/article")
+		if err != nil {
+			t.Fatalf("GET /article: %v", err)
+		}
+		page, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Room for the page and two images
+		budget := int64(len(page) + 2*len(testPNG))
+		pages := newPageTestDetector(&recordingDetector{}, DetectorConfig{PageByteBudget: budget})
+
+		result, err := pages.DetectPage(ctx, DetectionInput{URL: server.URL + "/article"})
+		if err != nil {
+			t.Fatalf("DetectPage() error = %v", err)
+		}
+
+		verified := 0
+		for _, asset := range result.Assets {
+			if asset.Result != nil {
+				verified++
+			}
+		}
+		if verified != 2 {
+			t.Errorf("verified %d assets, want 2 within the budget", verified)
+		}
+	})
+}
+
+// TestDetectPageNotHTML tests that other content is verified directly.
+func TestDetectPageNotHTML(t *testing.T) {
+	server := newPageTestServer(longText)
+	defer server.Close()
+
+	detector := &recordingDetector{}
+	pages := newPageTestDetector(detector, DetectorConfig{})
+
+	result, err := pages.DetectPage(context.Background(), DetectionInput{URL: server.URL + "/img/photo.png"})
+	if err != nil {
+		t.Fatalf("DetectPage() error = %v", err)
+	}
+	if result.HTML || result.Page == nil || result.Page.ContentType != ContentTypeImage {
+		t.Errorf("result = %+v, want the image's own verdict", result)
+	}
+	if len(detector.inputs) != 1 || len(detector.inputs[0].Data) != len(testPNG) {
+		t.Errorf("expected one detection of the fetched image, got %d", len(detector.inputs))
+	}
+}
+
+// TestDetectPageErrors tests pages that can't be verified.
+func TestDetectPageErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("empty page", func(t *testing.T) {
+		server := newPageTestServer("Loading...")
+		defer server.Close()
+
+		pages := newPageTestDetector(&recordingDetector{}, DetectorConfig{})
+		_, err := pages.DetectPage(ctx, DetectionInput{URL: server.URL + "/article"})
+		if !errors.Is(err, ErrEmptyPage) {
+			t.Errorf("expected ErrEmptyPage, got %v", err)
+		}
+	})
+
+	t.Run("page not found", func(t *testing.T) {
+		server := newPageTestServer(longText)
+		defer server.Close()
+
+		pages := newPageTestDetector(&recordingDetector{}, DetectorConfig{})
+		_, err := pages.DetectPage(ctx, DetectionInput{URL: server.URL + "/nope"})
+
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) || fetchErr.Status != http.StatusNotFound {
+			t.Errorf("expected a 404 FetchError, got %v", err)
+		}
+	})
+
+	t.Run("private address refused", func(t *testing.T) {
+		server := newPageTestServer(longText)
+		defer server.Close()
+
+		pages := NewPageDetector(&recordingDetector{}, DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+		_, err := pages.DetectPage(ctx, DetectionInput{URL: server.URL + "/article"})
+		if !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("expected ErrBlockedAddress, got %v", err)
+		}
+	})
+}
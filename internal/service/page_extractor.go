@@ -0,0 +1,329 @@
+package service
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// =============================================================================
+// HTML Page Extraction
+// =============================================================================
+//
+// Page mode needs two things from an HTML page: the article text and the
+// media it embeds. A full HTML parser is more than that takes, so this is a
+// small tolerant tokenizer over tags and text:
+//
+//   Text    Text inside <article> or <main> if the page has them, otherwise
+//           the whole body. Navigation, headers, footers, forms, scripts
+//           and styles are left out. Block elements become line breaks.
+//   Assets  <img src> (or data-src for lazy-loaded images), <video src>
+//           and <source src> inside <video>, resolved against the page URL
+//           (or <base href>). Only http(s) URLs are kept, once each, in
+//           page order.
+//
+// =============================================================================
+
+// extractedPage is the content of an HTML page.
+type extractedPage struct {
+	// Text is the main text with markup removed
+	Text string
+
+	// Assets are the embedded images and videos
+	Assets []pageAssetRef
+}
+
+// pageAssetRef is an embedded media URL and the type its tag implies.
+type pageAssetRef struct {
+	URL         string
+	ContentType ContentType
+}
+
+// htmlSkippedElements hold no article text.
+var htmlSkippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true,
+	"button": true, "select": true, "svg": true, "iframe": true,
+}
+
+// htmlRawTextElements have content that isn't markup.
+var htmlRawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// htmlBlockElements break lines.
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "main": true, "blockquote": true, "pre": true,
+	"table": true, "tr": true, "dd": true, "dt": true, "figure": true, "figcaption": true,
+}
+
+// isHTML reports whether a fetched response is an HTML page.
+func isHTML(mimeType string, data []byte) bool {
+	if mimeType != "" {
+		mime := strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+		if mime == "text/html" || mime == "application/xhtml+xml" {
+			return true
+		}
+		if mime != "application/octet-stream" {
+			return false
+		}
+	}
+
+	head := bytes.ToLower(bytes.TrimSpace(data[:min(len(data), 512)]))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
+}
+
+// extractPage pulls the main text and embedded media out of an HTML page
+// fetched from pageURL.
+func extractPage(data []byte, pageURL *url.URL) *extractedPage {
+	page := &extractedPage{}
+	base := pageURL
+	seen := make(map[string]bool)
+
+	addAsset := func(ref string, contentType ContentType) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "data:") {
+			return
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		if seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		page.Assets = append(page.Assets, pageAssetRef{URL: u.String(), ContentType: contentType})
+	}
+
+	var body, main strings.Builder
+	skipDepth, mainDepth, videoDepth := 0, 0, 0
+
+	scanHTML(data, func(tok htmlToken) {
+		if tok.tag == "" {
+			if skipDepth > 0 {
+				return
+			}
+			body.WriteString(tok.text)
+			if mainDepth > 0 {
+				main.WriteString(tok.text)
+			}
+			return
+		}
+
+		depth := 1
+		if tok.end {
+			depth = -1
+		}
+		switch {
+		case htmlSkippedElements[tok.tag]:
+			skipDepth = max(0, skipDepth+depth)
+		case tok.tag == "article" || tok.tag == "main":
+			mainDepth = max(0, mainDepth+depth)
+		case tok.tag == "video":
+			videoDepth = max(0, videoDepth+depth)
+		}
+
+		if htmlBlockElements[tok.tag] {
+			body.WriteString("\n\n")
+			if mainDepth > 0 || tok.tag == "article" || tok.tag == "main" {
+				main.WriteString("\n\n")
+			}
+		}
+
+		if tok.end {
+			return
+		}
+		switch tok.tag {
+		case "base":
+			if u, err := pageURL.Parse(tok.attrs["href"]); err == nil && tok.attrs["href"] != "" {
+				base = u
+			}
+		case "img":
+			src := tok.attrs["src"]
+			if src == "" || strings.HasPrefix(src, "data:") {
+				src = tok.attrs["data-src"]
+			}
+			addAsset(src, ContentTypeImage)
+		case "video":
+			addAsset(tok.attrs["src"], ContentTypeVideo)
+		case "source":
+			if videoDepth > 0 {
+				addAsset(tok.attrs["src"], ContentTypeVideo)
+			}
+		}
+	})
+
+	page.Text = normalizeDocumentText(main.String())
+	if page.Text == "" {
+		page.Text = normalizeDocumentText(body.String())
+	}
+	return page
+}
+
+// htmlToken is a tag or a run of text.
+type htmlToken struct {
+	// text is a run of decoded text (tag is empty)
+	text string
+
+	// tag is the lowercased element name
+	tag string
+
+	// end marks a closing tag
+	end bool
+
+	// attrs are a start tag's attributes, with lowercased names
+	attrs map[string]string
+}
+
+// scanHTML calls fn for each tag and run of text in data. Comments,
+// doctypes and processing instructions are dropped, as is the content of
+// raw text elements such as <script>.
+func scanHTML(data []byte, fn func(htmlToken)) {
+	for i := 0; i < len(data); {
+		if data[i] != '<' {
+			end := bytes.IndexByte(data[i:], '<')
+			if end < 0 {
+				end = len(data) - i
+			}
+			fn(htmlToken{text: html.UnescapeString(string(data[i : i+end]))})
+			i += end
+			continue
+		}
+
+		rest := data[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end := bytes.Index(rest[4:], []byte("-->"))
+			if end < 0 {
+				return
+			}
+			i += 4 + end + 3
+		case len(rest) > 1 && (rest[1] == '!' || rest[1] == '?'):
+			end := bytes.IndexByte(rest, '>')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+		case len(rest) > 2 && rest[1] == '/' && isASCIILetter(rest[2]):
+			name, _ := readHTMLName(rest[2:])
+			end := bytes.IndexByte(rest, '>')
+			if end < 0 {
+				return
+			}
+			fn(htmlToken{tag: name, end: true})
+			i += end + 1
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			tok, n := readHTMLStartTag(rest)
+			if n < 0 {
+				return
+			}
+			fn(tok)
+			i += n
+			if htmlRawTextElements[tok.tag] {
+				// Skip to the closing tag; the content isn't markup
+				closing := []byte("</" + tok.tag)
+				end := bytes.Index(bytes.ToLower(data[i:]), closing)
+				if end < 0 {
+					return
+				}
+				i += end
+			}
+		default:
+			// A stray "<" is text
+			fn(htmlToken{text: "<"})
+			i++
+		}
+	}
+}
+
+// readHTMLStartTag parses a start tag at the beginning of data and returns
+// it with the number of bytes it spans, or -1 if it isn't closed.
+func readHTMLStartTag(data []byte) (htmlToken, int) {
+	name, n := readHTMLName(data[1:])
+	tok := htmlToken{tag: name, attrs: make(map[string]string)}
+
+	for i := 1 + n; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '>':
+			return tok, i + 1
+		case c == '/' || isHTMLSpace(c):
+			i++
+		default:
+			attr, n := readHTMLAttrName(data[i:])
+			if n == 0 {
+				// Not a valid attribute name character; skip it
+				i++
+				continue
+			}
+			i += n
+			for i < len(data) && isHTMLSpace(data[i]) {
+				i++
+			}
+			if i >= len(data) || data[i] != '=' {
+				tok.attrs[attr] = ""
+				continue
+			}
+			i++
+			for i < len(data) && isHTMLSpace(data[i]) {
+				i++
+			}
+			value, n := readHTMLAttrValue(data[i:])
+			if n < 0 {
+				return tok, -1
+			}
+			if _, dup := tok.attrs[attr]; !dup {
+				tok.attrs[attr] = html.UnescapeString(value)
+			}
+			i += n
+		}
+	}
+	return tok, -1
+}
+
+// readHTMLName reads a lowercased tag name.
+func readHTMLName(data []byte) (string, int) {
+	n := 0
+	for n < len(data) && !isHTMLSpace(data[n]) && data[n] != '>' && data[n] != '/' {
+		n++
+	}
+	return strings.ToLower(string(data[:n])), n
+}
+
+// readHTMLAttrName reads a lowercased attribute name.
+func readHTMLAttrName(data []byte) (string, int) {
+	n := 0
+	for n < len(data) && !isHTMLSpace(data[n]) && data[n] != '>' && data[n] != '/' && data[n] != '=' {
+		n++
+	}
+	return strings.ToLower(string(data[:n])), n
+}
+
+// readHTMLAttrValue reads a quoted or unquoted attribute value, returning
+// -1 if a quoted value isn't closed.
+func readHTMLAttrValue(data []byte) (string, int) {
+	if len(data) > 0 && (data[0] == '"' || data[0] == '\'') {
+		end := bytes.IndexByte(data[1:], data[0])
+		if end < 0 {
+			return "", -1
+		}
+		return string(data[1 : 1+end]), end + 2
+	}
+
+	n := 0
+	for n < len(data) && !isHTMLSpace(data[n]) && data[n] != '>' {
+		n++
+	}
+	return string(data[:n]), n
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
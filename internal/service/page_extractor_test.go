@@ -0,0 +1,119 @@
+package service
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExtractPage tests text and asset extraction from HTML.
+func TestExtractPage(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/news/story.html")
+
+	t.Run("article text preferred over the rest of the body", func(t *testing.T) {
+		page := extractPage([]byte(`<!DOCTYPE html>
+<html><head><title>Story</title><style>p { color: red }</style></head>
+<body>
+<nav><a href="/">Home</a> | <a href="/news">News</a></nav>
+<p>Subscribe to our newsletter!</p>
+<article>
+<h1>Council approves budget</h1>
+<p>The council voted 7&ndash;2 on Tuesday &amp; adjourned.</p>
+<script>var p = "<p>not text</p>";</script>
+<p>Debate ran <b>late</b>.</p>
+</article>
+<footer>Copyright</footer>
+</body></html>`), pageURL)
+
+		want := "Council approves budget\n\nThe council voted 7–2 on Tuesday & adjourned.\n\nDebate ran late."
+		if page.Text != want {
+			t.Errorf("Text = %q, want %q", page.Text, want)
+		}
+	})
+
+	t.Run("body text without an article", func(t *testing.T) {
+		page := extractPage([]byte(`<html><body>
+<header>Site name</header>
+<div>First paragraph.</div><!-- <div>commented out</div> -->
+<div>Second paragraph.</div>
+<form><button>Search</button></form>
+</body></html>`), pageURL)
+
+		want := "First paragraph.\n\nSecond paragraph."
+		if page.Text != want {
+			t.Errorf("Text = %q, want %q", page.Text, want)
+		}
+	})
+
+	t.Run("assets resolved and deduplicated", func(t *testing.T) {
+		page := extractPage([]byte(`<body>
+<img src="/img/a.png" alt="a">
+<img src="b.jpg#zoom">
+<img src="data:image/png;base64,iVBORw0KGgo=" data-src="https://cdn.example.com/lazy.webp">
+<img src='/img/a.png'>
+<img src="ftp://example.com/c.png">
+<video src="https://video.example.com/clip.mp4"></video>
+<video controls><source src="/media/talk.webm" type="video/webm"></video>
+<source src="/media/audio.ogg">
+</body>`), pageURL)
+
+		want := []pageAssetRef{
+			{URL: "https://example.com/img/a.png", ContentType: ContentTypeImage},
+			{URL: "https://example.com/news/b.jpg", ContentType: ContentTypeImage},
+			{URL: "https://cdn.example.com/lazy.webp", ContentType: ContentTypeImage},
+			{URL: "https://video.example.com/clip.mp4", ContentType: ContentTypeVideo},
+			{URL: "https://example.com/media/talk.webm", ContentType: ContentTypeVideo},
+		}
+		if !reflect.DeepEqual(page.Assets, want) {
+			t.Errorf("Assets = %+v, want %+v", page.Assets, want)
+		}
+	})
+
+	t.Run("base href", func(t *testing.T) {
+		page := extractPage([]byte(`<head><base href="https://static.example.org/assets/"></head>
+<body><img src="photo.jpg"></body>`), pageURL)
+
+		if len(page.Assets) != 1 || page.Assets[0].URL != "https://static.example.org/assets/photo.jpg" {
+			t.Errorf("Assets = %+v, want photo.jpg resolved against <base>", page.Assets)
+		}
+	})
+
+	t.Run("malformed markup", func(t *testing.T) {
+		// Must not panic or loop on truncated input
+		for _, html := range []string{"<", "<p", "<img src=\"a.png", "<!--", "</", "a < b", "<script>x"} {
+			extractPage([]byte(html), pageURL)
+		}
+
+		page := extractPage([]byte("<p>1 < 2 and 3 > 2</p>"), pageURL)
+		if page.Text != "1 < 2 and 3 > 2" {
+			t.Errorf("Text = %q, want stray brackets kept", page.Text)
+		}
+	})
+}
+
+// TestIsHTML tests HTML detection from the response type and body.
+func TestIsHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		data     string
+		want     bool
+	}{
+		{"html", "text/html; charset=utf-8", "", true},
+		{"xhtml", "application/xhtml+xml", "", true},
+		{"plain text", "text/plain", "<html>", false},
+		{"image", "image/png", string(testPNG), false},
+		{"sniffed doctype", "", "  <!DOCTYPE html><html>", true},
+		{"sniffed from octet-stream", "application/octet-stream", "<HTML><body>", true},
+		{"unknown bytes", "", strings.Repeat("x", 600), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHTML(tt.mimeType, []byte(tt.data)); got != tt.want {
+				t.Errorf("isHTML(%q) = %v, want %v", tt.mimeType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,555 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// =============================================================================
+// Message Plist Extraction
+// =============================================================================
+//
+// iMessage conversations exported by backup tools arrive as property lists,
+// either XML or binary ("bplist00"). We walk the plist and collect the string
+// under every "text" key, one per message, and score the messages together
+// as text.
+//
+// Format support is limited: exports differ between tools, and only message
+// bodies stored under a "text" key are found. NSKeyedArchiver plists (a
+// top-level $objects array referenced by UIDs, with NSString wrappers) are
+// followed; attributedBody blobs and other nested archives are not decoded.
+//
+// Binary plists reference objects by index, so a crafted file can nest
+// deeply or loop; both are rejected.
+//
+// =============================================================================
+
+// Plist limits
+const (
+	// maxPlistDepth bounds the nesting of arrays and dictionaries
+	maxPlistDepth = 64
+
+	// maxPlistObjects caps the objects in a binary plist
+	maxPlistObjects = 1 << 20
+)
+
+// plistDict is a plist dictionary with its keys in file order.
+type plistDict struct {
+	keys   []string
+	values []any
+}
+
+// get returns the value for key, or nil.
+func (d *plistDict) get(key string) any {
+	for i, k := range d.keys {
+		if k == key {
+			return d.values[i]
+		}
+	}
+	return nil
+}
+
+// plistUID is an NSKeyedArchiver reference into $objects.
+type plistUID uint64
+
+// Decoded plist values are string, int64, []any, *plistDict, plistUID or
+// nil; reals, dates, booleans and data aren't needed and decode as nil.
+
+// detectPlist extracts the messages from an iMessage plist export and
+// scores them as one text. Errors extracting wrap ErrDocumentExtraction.
+func (d *detector) detectPlist(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
+	var data []byte
+	switch {
+	case input.DataSize() > maxDocumentSize:
+		return nil, fmt.Errorf("%w: plist is %d bytes, limit is %d", ErrDocumentExtraction, input.DataSize(), maxDocumentSize)
+	case input.HasData():
+		var err error
+		if data, err = input.ReadData(maxDocumentSize); err != nil {
+			return nil, err
+		}
+	case input.URL != "":
+		result, err := d.fetcher.Fetch(ctx, input.URL, maxDocumentSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch plist: %w", err)
+		}
+		data = result.Data
+	default:
+		return nil, errors.New("no plist data provided")
+	}
+
+	var messages []string
+	err := d.metrics.runAnalyzer(ctx, ContentTypePlist, func(ctx context.Context) (err error) {
+		messages, err = extractTextFromPlist(data)
+		return err
+	})
+	if stack := panicStack(err); stack != nil {
+		d.logger.Error("plist extraction panicked", "error", err, "stack", string(stack))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDocumentExtraction, err)
+	}
+
+	d.logger.Debug("plist messages extracted", "message_count", len(messages))
+
+	textInput := input
+	textInput.ContentType = ContentTypeText
+	textInput.Text = strings.Join(messages, "\n\n")
+	textInput.Data = nil
+	textInput.Reader = nil
+	textInput.URL = ""
+	return d.textDetector.DetectText(ctx, textInput)
+}
+
+// extractTextFromPlist returns the non-empty "text" values of an XML or
+// binary plist, in file order.
+func extractTextFromPlist(data []byte) ([]string, error) {
+	var root any
+	var err error
+
+	switch {
+	case isBinaryPlist(data):
+		root, err = parseBinaryPlist(data)
+	case isXMLPlist(data):
+		root, err = parseXMLPlist(data)
+	default:
+		return nil, errors.New("not an XML or binary plist")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w := plistWalker{visited: make(map[plistUID]bool)}
+
+	// A keyed archive is walked from $top, following UIDs into $objects,
+	// so each object is visited once
+	if dict, ok := root.(*plistDict); ok {
+		if objects, ok := dict.get("$objects").([]any); ok {
+			w.objects = objects
+			root = dict.get("$top")
+		}
+	}
+	w.walk(root, 0)
+
+	if len(w.messages) == 0 {
+		return nil, errors.New("plist contains no message text")
+	}
+	return w.messages, nil
+}
+
+// isBinaryPlist reports whether data starts with the binary plist magic.
+func isBinaryPlist(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("bplist00"))
+}
+
+// isXMLPlist reports whether data is an XML document whose root is a plist.
+func isXMLPlist(data []byte) bool {
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF")), " \t\r\n")
+	if !bytes.HasPrefix(head, []byte("<?xml")) && !bytes.HasPrefix(head, []byte("<plist")) &&
+		!bytes.HasPrefix(head, []byte("<!DOCTYPE plist")) {
+		return false
+	}
+	return bytes.Contains(head, []byte("<!DOCTYPE plist")) || bytes.Contains(head, []byte("<plist"))
+}
+
+// plistWalker collects message text from a decoded plist.
+type plistWalker struct {
+	// objects is a keyed archive's $objects (nil otherwise)
+	objects []any
+	visited map[plistUID]bool
+
+	messages []string
+	size     int
+}
+
+// walk collects the "text" values under v.
+func (w *plistWalker) walk(v any, depth int) {
+	if depth > maxPlistDepth || w.size >= maxDocumentText {
+		return
+	}
+
+	switch v := v.(type) {
+	case plistUID:
+		if int(v) < len(w.objects) && !w.visited[v] {
+			w.visited[v] = true
+			w.walk(w.objects[v], depth+1)
+		}
+	case []any:
+		for _, e := range v {
+			w.walk(e, depth+1)
+		}
+	case *plistDict:
+		for i, key := range v.keys {
+			if strings.EqualFold(key, "text") {
+				if text, ok := w.resolveString(v.values[i]); ok {
+					if text = strings.TrimSpace(text); text != "" {
+						w.messages = append(w.messages, text)
+						w.size += len(text)
+					}
+					continue
+				}
+			}
+			w.walk(v.values[i], depth+1)
+		}
+	}
+}
+
+// resolveString returns v as a string, following a UID into $objects and
+// unwrapping an archived NSString.
+func (w *plistWalker) resolveString(v any) (string, bool) {
+	if uid, ok := v.(plistUID); ok {
+		if int(uid) >= len(w.objects) {
+			return "", false
+		}
+		v = w.objects[uid]
+	}
+	if dict, ok := v.(*plistDict); ok {
+		v = dict.get("NS.string")
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// =============================================================================
+// XML
+// =============================================================================
+
+// parseXMLPlist decodes the value inside an XML plist's <plist> element.
+func parseXMLPlist(data []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, errors.New("xml plist has no value")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xml plist: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			value, err := parseXMLValue(dec, start, 0)
+			if err != nil {
+				return nil, fmt.Errorf("invalid xml plist: %w", err)
+			}
+			return value, nil
+		}
+	}
+}
+
+// parseXMLValue decodes the element opened by start.
+func parseXMLValue(dec *xml.Decoder, start xml.StartElement, depth int) (any, error) {
+	if depth > maxPlistDepth {
+		return nil, errors.New("nested too deeply")
+	}
+
+	switch start.Name.Local {
+	case "string":
+		var s string
+		err := dec.DecodeElement(&s, &start)
+		return s, err
+	case "integer":
+		var s string
+		if err := dec.DecodeElement(&s, &start); err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		return n, nil
+	case "array":
+		var values []any
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				value, err := parseXMLValue(dec, tok, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			case xml.EndElement:
+				return values, nil
+			}
+		}
+	case "dict":
+		dict := &plistDict{}
+		haveKey := false
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				if tok.Name.Local == "key" {
+					var key string
+					if err := dec.DecodeElement(&key, &tok); err != nil {
+						return nil, err
+					}
+					dict.keys = append(dict.keys, key)
+					dict.values = append(dict.values, nil)
+					haveKey = true
+					continue
+				}
+				if !haveKey {
+					return nil, fmt.Errorf("dict value <%s> without a key", tok.Name.Local)
+				}
+				value, err := parseXMLValue(dec, tok, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				dict.values[len(dict.values)-1] = value
+				haveKey = false
+			case xml.EndElement:
+				// XML keyed archives write UIDs as <dict><key>CF$UID</key><integer>
+				if len(dict.keys) == 1 && dict.keys[0] == "CF$UID" {
+					if n, ok := dict.values[0].(int64); ok && n >= 0 {
+						return plistUID(n), nil
+					}
+				}
+				return dict, nil
+			}
+		}
+	default:
+		return nil, dec.Skip()
+	}
+}
+
+// =============================================================================
+// Binary
+// =============================================================================
+
+// binaryPlist decodes objects from a bplist00 file.
+type binaryPlist struct {
+	data        []byte
+	offsetSize  int
+	refSize     int
+	numObjects  uint64
+	offsetTable int
+
+	// decoded caches finished objects; active holds those being decoded,
+	// to catch reference cycles
+	decoded map[uint64]any
+	active  map[uint64]bool
+}
+
+// parseBinaryPlist decodes the top object of a binary plist.
+func parseBinaryPlist(data []byte) (any, error) {
+	// Header, at least one object, and the 32-byte trailer
+	if len(data) < 8+1+32 {
+		return nil, errors.New("binary plist too short")
+	}
+	trailer := data[len(data)-32:]
+
+	p := &binaryPlist{
+		data:       data[:len(data)-32],
+		offsetSize: int(trailer[6]),
+		refSize:    int(trailer[7]),
+		numObjects: binary.BigEndian.Uint64(trailer[8:16]),
+		decoded:    make(map[uint64]any),
+		active:     make(map[uint64]bool),
+	}
+	top := binary.BigEndian.Uint64(trailer[16:24])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if p.offsetSize < 1 || p.offsetSize > 8 || p.refSize < 1 || p.refSize > 8 {
+		return nil, errors.New("invalid binary plist trailer")
+	}
+	if p.numObjects == 0 || p.numObjects > maxPlistObjects || top >= p.numObjects {
+		return nil, fmt.Errorf("invalid binary plist object count %d", p.numObjects)
+	}
+	if tableOffset > uint64(len(p.data)) || p.numObjects*uint64(p.offsetSize) > uint64(len(p.data))-tableOffset {
+		return nil, errors.New("binary plist offset table out of range")
+	}
+	p.offsetTable = int(tableOffset)
+
+	return p.object(top, 0)
+}
+
+// bytes returns n bytes at off, or an error if they run past the objects.
+func (p *binaryPlist) bytes(off, n int) ([]byte, error) {
+	if off < 0 || n < 0 || off > len(p.data) || n > len(p.data)-off {
+		return nil, errors.New("binary plist object out of range")
+	}
+	return p.data[off : off+n], nil
+}
+
+// readUint reads a big-endian unsigned integer of len(b) bytes.
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// object decodes the object with index ref.
+func (p *binaryPlist) object(ref uint64, depth int) (any, error) {
+	if ref >= p.numObjects {
+		return nil, fmt.Errorf("binary plist reference %d out of range", ref)
+	}
+	if v, ok := p.decoded[ref]; ok {
+		return v, nil
+	}
+	if p.active[ref] || depth > maxPlistDepth {
+		return nil, errors.New("binary plist is cyclic or nested too deeply")
+	}
+	p.active[ref] = true
+	defer delete(p.active, ref)
+
+	entry, err := p.bytes(p.offsetTable+int(ref)*p.offsetSize, p.offsetSize)
+	if err != nil {
+		return nil, err
+	}
+	off64 := readUint(entry)
+	if off64 >= uint64(len(p.data)) {
+		return nil, errors.New("binary plist object out of range")
+	}
+	off := int(off64)
+
+	marker := p.data[off]
+	kind, info := marker>>4, int(marker&0x0F)
+
+	var value any
+	switch kind {
+	case 0x0, 0x2, 0x3, 0x4:
+		// null, booleans, reals, dates and data carry no message text
+	case 0x1: // integer, 2^info bytes
+		b, err := p.bytes(off+1, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 8 {
+			b = b[len(b)-8:]
+		}
+		value = int64(readUint(b))
+	case 0x5: // ASCII string
+		n, start, err := p.count(off, info)
+		if err != nil {
+			return nil, err
+		}
+		b, err := p.bytes(start, n)
+		if err != nil {
+			return nil, err
+		}
+		value = string(b)
+	case 0x6: // UTF-16BE string, n code units
+		n, start, err := p.count(off, info)
+		if err != nil {
+			return nil, err
+		}
+		b, err := p.bytes(start, 2*n)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+		value = string(utf16.Decode(units))
+	case 0x8: // UID
+		b, err := p.bytes(off+1, info+1)
+		if err != nil {
+			return nil, err
+		}
+		value = plistUID(readUint(b))
+	case 0xA, 0xC: // array, set
+		refs, err := p.refs(off, info, 1)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(refs))
+		for i, r := range refs {
+			if values[i], err = p.object(r, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		value = values
+	case 0xD: // dict: key refs, then value refs
+		refs, err := p.refs(off, info, 2)
+		if err != nil {
+			return nil, err
+		}
+		n := len(refs) / 2
+		dict := &plistDict{keys: make([]string, n), values: make([]any, n)}
+		for i := 0; i < n; i++ {
+			key, err := p.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, errors.New("binary plist dict key is not a string")
+			}
+			dict.keys[i] = k
+			if dict.values[i], err = p.object(refs[n+i], depth+1); err != nil {
+				return nil, err
+			}
+		}
+		value = dict
+	default:
+		return nil, fmt.Errorf("unknown binary plist object type 0x%02x", marker)
+	}
+
+	p.decoded[ref] = value
+	return value, nil
+}
+
+// count returns an object's length and where its contents start. Lengths
+// of 15 or more follow the marker as an integer object.
+func (p *binaryPlist) count(off, info int) (int, int, error) {
+	if info != 0x0F {
+		return info, off + 1, nil
+	}
+
+	b, err := p.bytes(off+1, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b[0]>>4 != 0x1 || b[0]&0x0F > 3 {
+		return 0, 0, errors.New("invalid binary plist length")
+	}
+	size := 1 << (b[0] & 0x0F)
+	if b, err = p.bytes(off+2, size); err != nil {
+		return 0, 0, err
+	}
+	n := readUint(b)
+	if n > uint64(len(p.data)) {
+		return 0, 0, errors.New("binary plist length out of range")
+	}
+	return int(n), off + 2 + size, nil
+}
+
+// refs reads the per-entry object references of a collection; dicts have
+// two per entry.
+func (p *binaryPlist) refs(off, info, perEntry int) ([]uint64, error) {
+	n, start, err := p.count(off, info)
+	if err != nil {
+		return nil, err
+	}
+	b, err := p.bytes(start, n*perEntry*p.refSize)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]uint64, n*perEntry)
+	for i := range refs {
+		refs[i] = readUint(b[i*p.refSize : (i+1)*p.refSize])
+	}
+	return refs, nil
+}
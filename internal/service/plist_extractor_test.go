@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// fixtureMessages are the message bodies in the messages*.plist fixtures.
+var fixtureMessages = []string{
+	"hey are we still on for dinner tomorrow?",
+	"Yes! I booked the Thai place on 5th for 7. Does that still work for you?",
+	"perfect, I'll bring the wine. should I invite Sam too",
+	"Sure, the more the merrier. Tell him to grab parking on Elm, the lot fills up fast.",
+}
+
+// TestExtractTextFromPlist tests message extraction from XML and binary plists.
+func TestExtractTextFromPlist(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{
+			// The attachment-only message has empty text and is skipped
+			name: "XML export",
+			data: readFixture(t, "messages.plist"),
+			want: fixtureMessages,
+		},
+		{
+			name: "binary keyed archive",
+			data: readFixture(t, "messages_keyed.plist"),
+			want: fixtureMessages,
+		},
+		{
+			name: "XML keyed archive",
+			data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>$objects</key>
+	<array>
+		<string>$null</string>
+		<dict><key>NS.string</key><string>Running late, be there in ten</string></dict>
+		<dict><key>text</key><dict><key>CF$UID</key><integer>1</integer></dict></dict>
+	</array>
+	<key>$top</key>
+	<dict><key>root</key><dict><key>CF$UID</key><integer>2</integer></dict></dict>
+</dict>
+</plist>`),
+			want: []string{"Running late, be there in ten"},
+		},
+		{
+			name: "Text key in a bare array",
+			data: []byte(`<plist version="1.0"><array>
+				<dict><key>Text</key><string>  ok see you soon  </string></dict>
+				<dict><key>text</key><integer>3</integer></dict>
+			</array></plist>`),
+			want: []string{"ok see you soon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractTextFromPlist(tt.data)
+			if err != nil {
+				t.Fatalf("extractTextFromPlist() error = %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// bplist00, one array (0xA1) holding a reference to itself, the offset
+	// table, and the trailer: 1-byte offsets and refs, 1 object, top 0,
+	// table at 10
+	cyclic := []byte("bplist00\xa1\x00\x08\x00\x00\x00\x00\x00\x00\x01\x01" +
+		"\x00\x00\x00\x00\x00\x00\x00\x01" +
+		"\x00\x00\x00\x00\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00\x00\x00\x00\x0a")
+
+	keyed := readFixture(t, "messages_keyed.plist")
+	errorTests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a plist", []byte("just some text")},
+		{"no message text", []byte(`<plist version="1.0"><dict><key>service</key><string>iMessage</string></dict></plist>`)},
+		{"malformed XML", []byte(`<plist version="1.0"><dict><key>text</key><string>hi</dict></plist>`)},
+		{"cyclic binary", cyclic},
+		{"truncated binary", keyed[:len(keyed)/2]},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := extractTextFromPlist(tt.data); err == nil {
+				t.Errorf("expected an error, got %q", got)
+			}
+		})
+	}
+}
+
+// TestAnalyzeMessages tests that the message count is recorded.
+func TestAnalyzeMessages(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	result := analyzer.AnalyzeMessages(fixtureMessages)
+	if result.Stats.MessageCount != len(fixtureMessages) {
+		t.Errorf("MessageCount = %d, want %d", result.Stats.MessageCount, len(fixtureMessages))
+	}
+	if joined := analyzer.Analyze(strings.Join(fixtureMessages, "\n\n")); joined.Stats.WordCount != result.Stats.WordCount {
+		t.Errorf("WordCount = %d, want %d", result.Stats.WordCount, joined.Stats.WordCount)
+	}
+}
+
+// TestDetectPlist tests that plist exports are scored as text.
+func TestDetectPlist(t *testing.T) {
+	detector, err := NewDetector(DetectorConfig{Timeout: 30 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("plist upload", func(t *testing.T) {
+		result, err := detector.Detect(ctx, DetectionInput{Data: readFixture(t, "messages.plist"), Filename: "chat.plist"})
+		if err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+		if result.ContentType != ContentTypeText {
+			t.Errorf("ContentType = %s, want text", result.ContentType)
+		}
+		if len(result.Detectors) != 1 || result.Detectors[0] != "humanmark" {
+			t.Errorf("Detectors = %v, want only humanmark", result.Detectors)
+		}
+	})
+
+	t.Run("binary plist detected from magic bytes", func(t *testing.T) {
+		if _, err := detector.Detect(ctx, DetectionInput{Data: readFixture(t, "messages_keyed.plist")}); err != nil {
+			t.Fatalf("Detect() error = %v", err)
+		}
+	})
+
+	t.Run("plist without messages fails extraction", func(t *testing.T) {
+		_, err := detector.Detect(ctx, DetectionInput{
+			Data:     []byte(`<plist version="1.0"><dict/></plist>`),
+			Filename: "empty.plist",
+		})
+		if !errors.Is(err, ErrDocumentExtraction) {
+			t.Errorf("error = %v, want ErrDocumentExtraction", err)
+		}
+	})
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReportFormat is an export format for Report.
+type ReportFormat string
+
+const (
+	// ReportJSON is the full DetectionResult as indented JSON
+	ReportJSON ReportFormat = "json"
+
+	// ReportCSV is a header row and one result row for spreadsheets
+	ReportCSV ReportFormat = "csv"
+
+	// ReportHTML is a self-contained page with no scripts or external assets
+	ReportHTML ReportFormat = "html"
+)
+
+// ErrUnknownReportFormat is returned by Report for formats other than
+// ReportJSON, ReportCSV and ReportHTML.
+var ErrUnknownReportFormat = errors.New("unknown report format")
+
+// MediaType returns the Content-Type to serve a report in this format with.
+func (f ReportFormat) MediaType() string {
+	switch f {
+	case ReportJSON:
+		return "application/json"
+	case ReportCSV:
+		return "text/csv; charset=utf-8"
+	case ReportHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// Report renders result for compliance records and spreadsheets.
+func Report(result *DetectionResult, format ReportFormat) ([]byte, error) {
+	switch format {
+	case ReportJSON:
+		return json.MarshalIndent(result, "", "  ")
+	case ReportCSV:
+		return csvReport(result)
+	case ReportHTML:
+		return htmlReport(result)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownReportFormat, format)
+}
+
+// csvReportHeader names the columns of a CSV report.
+var csvReportHeader = []string{
+	"verdict", "human", "confidence", "ai_score", "content_type", "needs_review",
+	"detectors", "provider_scores", "ai_phrases", "evidence", "content_hash",
+	"analyzer_version", "config_fingerprint", "anchor_tx_id", "mismatch_warning",
+}
+
+// csvReport writes result as one row. List columns are joined with "; "
+// so the row stays flat.
+func csvReport(result *DetectionResult) ([]byte, error) {
+	scores := make([]string, len(result.ProviderScores))
+	for i, p := range result.ProviderScores {
+		scores[i] = p.Name + "=" + formatScore(p.AIScore)
+	}
+	phrases := make([]string, len(result.AIPhrases))
+	for i, p := range result.AIPhrases {
+		phrases[i] = p.Phrase
+	}
+
+	row := []string{
+		string(result.Verdict),
+		strconv.FormatBool(result.Human),
+		formatScore(result.Confidence),
+		formatScore(result.AIScore),
+		string(result.ContentType),
+		strconv.FormatBool(result.NeedsReview),
+		strings.Join(result.Detectors, "; "),
+		strings.Join(scores, "; "),
+		strings.Join(phrases, "; "),
+		string(result.Evidence),
+		result.ContentHash,
+		result.AnalyzerVersion,
+		result.ConfigFingerprint,
+		result.AnchorTxID,
+		result.MismatchWarning,
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvReportHeader)
+	w.Write(row)
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// formatScore formats a 0-1 score to four decimal places.
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 4, 64)
+}
+
+// htmlReportData is what htmlReportTemplate renders.
+type htmlReportData struct {
+	Result     *DetectionResult
+	Verdict    string
+	Confidence int
+	Scores     []htmlReportBar
+	Sentences  []htmlReportSentence
+	Errors     []string
+}
+
+// htmlReportBar is one provider's score as a bar.
+type htmlReportBar struct {
+	Name    string
+	Percent int
+}
+
+// htmlReportSentence is a scored sentence split around AI phrases.
+type htmlReportSentence struct {
+	Percent  int
+	Segments []htmlReportSegment
+}
+
+// htmlReportSegment is a run of sentence text, marked if it's an AI phrase.
+type htmlReportSegment struct {
+	Text   string
+	Marked bool
+}
+
+func htmlReport(result *DetectionResult) ([]byte, error) {
+	data := htmlReportData{
+		Result:     result,
+		Verdict:    string(result.Verdict),
+		Confidence: percent(result.Confidence),
+	}
+	if data.Verdict == "" {
+		data.Verdict = string(VerdictAI)
+		if result.Human {
+			data.Verdict = string(VerdictHuman)
+		}
+	}
+
+	for _, p := range result.ProviderScores {
+		data.Scores = append(data.Scores, htmlReportBar{Name: p.Name, Percent: percent(p.AIScore)})
+	}
+
+	phrases := make([]string, len(result.AIPhrases))
+	for i, p := range result.AIPhrases {
+		phrases[i] = p.Phrase
+	}
+	for _, out := range result.DetectorOutputs {
+		for _, s := range out.Sentences {
+			data.Sentences = append(data.Sentences, htmlReportSentence{
+				Percent:  percent(s.AIScore),
+				Segments: markPhrases(s.Text, phrases),
+			})
+		}
+	}
+
+	for name, err := range result.DetectorErrors {
+		data.Errors = append(data.Errors, name+": "+err)
+	}
+	sort.Strings(data.Errors)
+
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// percent converts a 0-1 score to a whole percentage for bar widths.
+func percent(score float64) int {
+	return int(clampScore(score)*100 + 0.5)
+}
+
+// markPhrases splits text into segments, marking case-insensitive matches
+// of phrases. Overlapping matches are merged.
+func markPhrases(text string, phrases []string) []htmlReportSegment {
+	// Lowercasing can change byte lengths outside ASCII; only mark when
+	// offsets in the lowered text line up with the original
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		return []htmlReportSegment{{Text: text}}
+	}
+
+	marked := make([]bool, len(text))
+	for _, phrase := range phrases {
+		if phrase == "" {
+			continue
+		}
+		for start := 0; ; {
+			i := strings.Index(lower[start:], phrase)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(phrase); j++ {
+				marked[j] = true
+			}
+			start += i + len(phrase)
+		}
+	}
+
+	var segments []htmlReportSegment
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		segments = append(segments, htmlReportSegment{Text: text[i:j], Marked: marked[i]})
+		i = j
+	}
+	return segments
+}
+
+// htmlReportTemplate is the HTML report. Bars are plain divs sized with
+// inline widths so the page works without scripts.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>HumanMark detection report</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.banner { padding: 1.25rem; border-radius: 0.5rem; color: #fff; }
+.banner h1 { margin: 0 0 0.25rem; font-size: 1.75rem; }
+.verdict-human { background: #2e7d32; }
+.verdict-ai { background: #c62828; }
+.verdict-uncertain { background: #f9a825; color: #222; }
+.review { margin-top: 1rem; padding: 0.75rem; border: 1px solid #f9a825; border-radius: 0.5rem; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.3rem 0.5rem; border-bottom: 1px solid #eee; vertical-align: top; }
+th { width: 12rem; font-weight: 600; }
+.bar-row { display: flex; align-items: center; gap: 0.5rem; margin: 0.4rem 0; }
+.bar-label { width: 10rem; }
+.bar { flex: 1; height: 1rem; background: #eee; border-radius: 0.25rem; overflow: hidden; }
+.bar-fill { height: 100%; background: linear-gradient(90deg, #2e7d32, #f9a825, #c62828); }
+.bar-value { width: 3rem; text-align: right; }
+mark { background: #ffe082; padding: 0 0.1em; }
+.sentence { margin: 0.5rem 0; }
+.sentence small { color: #666; }
+</style>
+</head>
+<body>
+<div class="banner verdict-{{.Verdict}}">
+<h1>Verdict: {{.Verdict}}</h1>
+<div>Confidence {{.Confidence}}% &middot; AI score {{printf "%.2f" .Result.AIScore}}</div>
+</div>
+{{if .Result.NeedsReview}}<div class="review">The detectors disagreed; a person should review this verdict.</div>{{end}}
+{{if .Result.MismatchWarning}}<div class="review">{{.Result.MismatchWarning}}</div>{{end}}
+
+<h2>Details</h2>
+<table>
+<tr><th>Content type</th><td>{{.Result.ContentType}}</td></tr>
+<tr><th>Detectors</th><td>{{range $i, $d := .Result.Detectors}}{{if $i}}, {{end}}{{$d}}{{end}}</td></tr>
+{{if .Result.Evidence}}<tr><th>Evidence</th><td>{{.Result.Evidence}}</td></tr>{{end}}
+{{if .Result.ContentHash}}<tr><th>Content hash</th><td><code>{{.Result.ContentHash}}</code></td></tr>{{end}}
+{{if .Result.AnalyzerVersion}}<tr><th>Analyzer version</th><td>{{.Result.AnalyzerVersion}}</td></tr>{{end}}
+{{if .Result.ConfigFingerprint}}<tr><th>Config fingerprint</th><td><code>{{.Result.ConfigFingerprint}}</code></td></tr>{{end}}
+{{if .Result.AnchorTxID}}<tr><th>Anchor transaction</th><td><code>{{.Result.AnchorTxID}}</code></td></tr>{{end}}
+</table>
+
+{{if .Scores}}
+<h2>Signals</h2>
+{{range .Scores}}<div class="bar-row"><span class="bar-label">{{.Name}}</span><div class="bar"><div class="bar-fill" style="width: {{.Percent}}%"></div></div><span class="bar-value">{{.Percent}}%</span></div>
+{{end}}
+{{end}}
+
+{{if .Result.AIPhrases}}
+<h2>AI phrases</h2>
+<ul>
+{{range .Result.AIPhrases}}<li><mark>{{.Phrase}}</mark> ({{.Category}})</li>
+{{end}}
+</ul>
+{{end}}
+
+{{if .Sentences}}
+<h2>Sentences</h2>
+{{range .Sentences}}<p class="sentence"><small>{{.Percent}}% AI</small> {{range .Segments}}{{if .Marked}}<mark>{{.Text}}</mark>{{else}}{{.Text}}{{end}}{{end}}</p>
+{{end}}
+{{end}}
+
+{{if .Errors}}
+<h2>Detector errors</h2>
+<ul>
+{{range .Errors}}<li>{{.}}</li>
+{{end}}
+</ul>
+{{end}}
+</body>
+</html>
+`))
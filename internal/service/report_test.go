@@ -0,0 +1,153 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// reportTestResult is a text result with provider scores, phrases and
+// sentence output.
+func reportTestResult() *DetectionResult {
+	return &DetectionResult{
+		Verdict:     VerdictAI,
+		Confidence:  0.87,
+		AIScore:     0.91,
+		ContentType: ContentTypeText,
+		Detectors:   []string{"humanmark", "winston"},
+		ContentHash: "abc123",
+		ProviderScores: []ProviderScore{
+			{Name: "humanmark", AIScore: 0.84, Weight: 1},
+			{Name: "winston", AIScore: 0.98, Weight: 1},
+		},
+		AIPhrases: []DetectedPhrase{{Phrase: "it's important to note", Category: "hedging"}},
+		DetectorOutputs: []DetectorOutput{{
+			Name: "winston",
+			Sentences: []SentenceScore{
+				{Text: "It's important to note that <b>tags</b> are escaped.", AIScore: 0.97},
+			},
+		}},
+		DetectorErrors:  map[string]string{"gptzero": "quota exceeded"},
+		AnalyzerVersion: AnalyzerVersion,
+	}
+}
+
+// TestReport tests rendering each report format.
+func TestReport(t *testing.T) {
+	result := reportTestResult()
+
+	t.Run("json", func(t *testing.T) {
+		data, err := Report(result, ReportJSON)
+		if err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		if !bytes.Contains(data, []byte("\n  \"Verdict\": \"ai\"")) {
+			t.Errorf("expected indented JSON, got:\n%s", data)
+		}
+
+		var decoded DetectionResult
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("report is not valid JSON: %v", err)
+		}
+		if decoded.AIScore != result.AIScore || len(decoded.ProviderScores) != 2 || decoded.AIPhrases[0] != result.AIPhrases[0] {
+			t.Errorf("decoded = %+v, want %+v", decoded, result)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		data, err := Report(result, ReportCSV)
+		if err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatalf("report is not valid CSV: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("expected header and one row, got %d records", len(records))
+		}
+
+		row := make(map[string]string)
+		for i, column := range records[0] {
+			row[column] = records[1][i]
+		}
+		want := map[string]string{
+			"verdict":         "ai",
+			"confidence":      "0.8700",
+			"ai_score":        "0.9100",
+			"content_type":    "text",
+			"detectors":       "humanmark; winston",
+			"provider_scores": "humanmark=0.8400; winston=0.9800",
+			"ai_phrases":      "it's important to note",
+			"content_hash":    "abc123",
+		}
+		for column, value := range want {
+			if row[column] != value {
+				t.Errorf("%s = %q, want %q", column, row[column], value)
+			}
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		data, err := Report(result, ReportHTML)
+		if err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		page := string(data)
+
+		for _, want := range []string{
+			`<div class="banner verdict-ai">`,
+			`Confidence 87%`,
+			`<div class="bar-fill" style="width: 84%">`,
+			`<div class="bar-fill" style="width: 98%">`,
+			`<li><mark>it&#39;s important to note</mark> (hedging)</li>`,
+			`<mark>It&#39;s important to note</mark> that &lt;b&gt;tags&lt;/b&gt; are escaped.`,
+			`gptzero: quota exceeded`,
+		} {
+			if !strings.Contains(page, want) {
+				t.Errorf("report missing %q", want)
+			}
+		}
+		for _, forbidden := range []string{"<script", "<b>", "http://", "https://"} {
+			if strings.Contains(page, forbidden) {
+				t.Errorf("report contains %q", forbidden)
+			}
+		}
+	})
+
+	t.Run("html verdict falls back to Human", func(t *testing.T) {
+		data, _ := Report(&DetectionResult{Human: true}, ReportHTML)
+		if !bytes.Contains(data, []byte(`verdict-human`)) {
+			t.Error("expected human verdict banner")
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := Report(result, "pdf"); !errors.Is(err, ErrUnknownReportFormat) {
+			t.Errorf("expected ErrUnknownReportFormat, got %v", err)
+		}
+	})
+}
+
+// TestMarkPhrases tests splitting sentences around AI phrases.
+func TestMarkPhrases(t *testing.T) {
+	segments := markPhrases("Delve in. We DELVE deeper.", []string{"delve", "delve deeper"})
+	want := []htmlReportSegment{
+		{Text: "Delve", Marked: true},
+		{Text: " in. We "},
+		{Text: "DELVE deeper", Marked: true},
+		{Text: "."},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", segments, want)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, segments[i], want[i])
+		}
+	}
+}
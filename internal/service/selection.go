@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// =============================================================================
+// Detector Selection
+// =============================================================================
+//
+// Callers can choose which detectors run for a request:
+//
+//   Detectors         Only these run, e.g. ["humanmark"] to keep content
+//                     from being sent to third-party APIs
+//   ExcludeDetectors  Everything configured except these
+//
+// Names are checked against the detectors for the content type. Asking for
+// a provider that has no API key is an error rather than a silent skip, so
+// a humanmark-only result is never mistaken for a multi-provider one.
+//
+// =============================================================================
+
+// ErrInvalidDetectorSelection is returned when a request names an unknown
+// detector, one that doesn't handle the content type, or one that isn't
+// configured, or leaves no detectors to run.
+var ErrInvalidDetectorSelection = errors.New("invalid detector selection")
+
+// DetectorNames returns the names of all registered detectors, sorted.
+func DetectorNames() []string {
+	seen := make(map[string]bool)
+	for _, weights := range []map[string]float64{
+		textDetectorWeights,
+		imageDetectorWeights,
+		audioDetectorWeights,
+		videoDetectorWeights,
+		documentDetectorWeights,
+	} {
+		for name := range weights {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// localDetectors run on this server; every other detector is an external
+// API.
+var localDetectors = map[string]bool{"humanmark": true, "metadata": true, "format": true}
+
+// ExternalDetectorCalls returns how many external detection APIs result
+// came from, counting ones that failed.
+func ExternalDetectorCalls(result *DetectionResult) int {
+	calls := 0
+	for _, name := range result.Detectors {
+		if !localDetectors[name] {
+			calls++
+		}
+	}
+	for name := range result.DetectorErrors {
+		if !localDetectors[name] {
+			calls++
+		}
+	}
+	return calls
+}
+
+// ValidateDetectorNames checks that every name is a registered detector.
+// Errors wrap ErrInvalidDetectorSelection.
+func ValidateDetectorNames(names []string) error {
+	known := DetectorNames()
+	for _, name := range names {
+		i := sort.SearchStrings(known, name)
+		if i == len(known) || known[i] != name {
+			return fmt.Errorf("%w: unknown detector %q", ErrInvalidDetectorSelection, name)
+		}
+	}
+	return nil
+}
+
+// selectDetectors resolves input's selection for one content type.
+// available maps each of the content type's detectors to whether it is
+// configured. It returns the set of detectors to run.
+func selectDetectors(input DetectionInput, contentType ContentType, available map[string]bool) (map[string]bool, error) {
+	if err := ValidateDetectorNames(input.Detectors); err != nil {
+		return nil, err
+	}
+	if err := ValidateDetectorNames(input.ExcludeDetectors); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool)
+	if len(input.Detectors) == 0 {
+		for name, configured := range available {
+			if configured {
+				selected[name] = true
+			}
+		}
+	} else {
+		for _, name := range input.Detectors {
+			configured, ok := available[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: detector %q does not handle %s", ErrInvalidDetectorSelection, name, contentType)
+			}
+			if !configured {
+				return nil, fmt.Errorf("%w: detector %q is not configured", ErrInvalidDetectorSelection, name)
+			}
+			selected[name] = true
+		}
+	}
+
+	for _, name := range input.ExcludeDetectors {
+		delete(selected, name)
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: no detectors left to run for %s", ErrInvalidDetectorSelection, contentType)
+	}
+	return selected, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestValidateDetectorNames tests name checks against the registries.
+func TestValidateDetectorNames(t *testing.T) {
+	if err := ValidateDetectorNames([]string{"humanmark", "openai", "sightengine", "metadata"}); err != nil {
+		t.Errorf("ValidateDetectorNames() error = %v", err)
+	}
+	if err := ValidateDetectorNames([]string{"humanmark", "turnitin"}); !errors.Is(err, ErrInvalidDetectorSelection) {
+		t.Errorf("error = %v, want ErrInvalidDetectorSelection", err)
+	}
+}
+
+// TestSelectDetectors tests resolving include and exclude lists.
+func TestSelectDetectors(t *testing.T) {
+	available := map[string]bool{"humanmark": true, "hive": true, "openai": false}
+
+	tests := []struct {
+		name    string
+		input   DetectionInput
+		want    string
+		wantErr bool
+	}{
+		{"default is every configured detector", DetectionInput{}, "hive,humanmark", false},
+		{"include", DetectionInput{Detectors: []string{"humanmark"}}, "humanmark", false},
+		{"exclude", DetectionInput{ExcludeDetectors: []string{"hive"}}, "humanmark", false},
+		{"exclude unconfigured is a no-op", DetectionInput{ExcludeDetectors: []string{"openai"}}, "hive,humanmark", false},
+		{"include unconfigured", DetectionInput{Detectors: []string{"openai"}}, "", true},
+		{"include other content type", DetectionInput{Detectors: []string{"sightengine"}}, "", true},
+		{"unknown name", DetectionInput{ExcludeDetectors: []string{"turnitin"}}, "", true},
+		{"nothing left", DetectionInput{Detectors: []string{"hive"}, ExcludeDetectors: []string{"hive"}}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectDetectors(tt.input, ContentTypeText, available)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDetectorSelection) {
+					t.Errorf("error = %v, want ErrInvalidDetectorSelection", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectDetectors() error = %v", err)
+			}
+
+			var got []string
+			for _, name := range []string{"hive", "humanmark", "openai"} {
+				if selected[name] {
+					got = append(got, name)
+				}
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("selected = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDetectTextSelection tests that only selected detectors run and are
+// aggregated.
+func TestDetectTextSelection(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"score":30}`))
+	}))
+	defer server.Close()
+
+	d := newTextProviderTestDetector(DetectorConfig{WinstonAPIKey: "k"}, server.URL)
+
+	t.Run("local only", func(t *testing.T) {
+		calls = 0
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: longText, Detectors: []string{"humanmark"}})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+		if strings.Join(result.Detectors, ",") != "humanmark" {
+			t.Errorf("Detectors = %v, want [humanmark]", result.Detectors)
+		}
+		if calls != 0 {
+			t.Errorf("provider called %d times, want 0", calls)
+		}
+	})
+
+	t.Run("external only", func(t *testing.T) {
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: longText, ExcludeDetectors: []string{"humanmark"}})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+		if strings.Join(result.Detectors, ",") != "winston" {
+			t.Errorf("Detectors = %v, want [winston]", result.Detectors)
+		}
+		if result.AIScore != 0.7 {
+			t.Errorf("AIScore = %f, want winston's 0.7", result.AIScore)
+		}
+	})
+
+	t.Run("provider without key", func(t *testing.T) {
+		_, err := d.DetectText(context.Background(), DetectionInput{Text: longText, Detectors: []string{"openai"}})
+		if !errors.Is(err, ErrInvalidDetectorSelection) {
+			t.Errorf("error = %v, want ErrInvalidDetectorSelection", err)
+		}
+	})
+}
+
+func TestExternalDetectorCalls(t *testing.T) {
+	tests := []struct {
+		name   string
+		result DetectionResult
+		want   int
+	}{
+		{"local only", DetectionResult{Detectors: []string{"humanmark"}}, 0},
+		{"document metadata is local", DetectionResult{Detectors: []string{"humanmark", "metadata", "winston"}}, 1},
+		{"failed providers count", DetectionResult{
+			Detectors:      []string{"humanmark", "hive"},
+			DetectorErrors: map[string]string{"sightengine": "timeout"},
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExternalDetectorCalls(&tt.result); got != tt.want {
+				t.Errorf("ExternalDetectorCalls() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
+	"fmt"
 	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -26,46 +29,84 @@ import (
 //   4. Punctuation patterns (humans use more variety)
 //   5. AI phrase detection (common AI patterns)
 //   6. Perplexity proxy (word predictability)
+//   7. Embedded JSON/YAML blocks (AI examples are deep, uniform and verbose)
+//   8. Sentence types (humans mix in questions, exclamations and commands)
+//
+// An experimental watermark scan (text_watermark.go) is reported too, but
+// isn't one of the signals: it doesn't affect the score.
 //
 // =============================================================================
 
 // TextAnalyzer performs statistical analysis on text to detect AI generation.
+// Its configuration is fixed once built, so it is safe for concurrent use.
 type TextAnalyzer struct {
 	// Weights for each signal (tuned based on testing)
 	weights TextAnalyzerWeights
+
+	// normalization is applied to text before any signal is computed
+	normalization NormalizationOptions
+
+	// signalExplanations enables TextAnalysisResult.SignalExplanations
+	signalExplanations bool
+
+	// phrases is the AI phrase dictionary (AIPhraseDictionary unless
+	// replaced with WithAIPhrases)
+	phrases []AIPhrase
 }
 
 // TextAnalyzerWeights controls the importance of each signal.
 type TextAnalyzerWeights struct {
 	SentenceVariance   float64
 	VocabularyRichness float64
+	BigramDiversity    float64
 	Burstiness         float64
 	PunctuationVariety float64
 	AIPhraseDetection  float64
 	WordLengthVariance float64
 	ContractionsUsage  float64
 	RepetitionPenalty  float64
+	EmbeddedData       float64
+	SentenceTypes      float64
+
+	// CutoffYear is the latest year the signals were calibrated against.
+	// Not a weight - texts referencing later years get a TemporalConfidenceNote.
+	CutoffYear int
 }
 
 // DefaultWeights returns tuned weights for the analyzer.
 func DefaultWeights() TextAnalyzerWeights {
 	return TextAnalyzerWeights{
 		SentenceVariance:   0.15,
-		VocabularyRichness: 0.20,
+		VocabularyRichness: 0.10,
+		BigramDiversity:    0.08,
 		Burstiness:         0.10,
-		PunctuationVariety: 0.10,
+		PunctuationVariety: 0.06, // Overlaps with SentenceTypes on ? and !
 		AIPhraseDetection:  0.20,
-		WordLengthVariance: 0.05,
+		WordLengthVariance: 0.03,
 		ContractionsUsage:  0.10,
-		RepetitionPenalty:  0.10,
+		RepetitionPenalty:  0.08,
+		EmbeddedData:       0.04, // Low: structured data is often generated on purpose
+		SentenceTypes:      0.06,
+		CutoffYear:         2023,
 	}
 }
 
-// NewTextAnalyzer creates a new analyzer with default weights.
-func NewTextAnalyzer() *TextAnalyzer {
-	return &TextAnalyzer{
-		weights: DefaultWeights(),
+// NewTextAnalyzer creates a new analyzer with default weights, every
+// normalization step enabled and signal explanations on, then applies opts.
+func NewTextAnalyzer(opts ...TextAnalyzerOption) *TextAnalyzer {
+	weights := DefaultWeights()
+	mustValidateWeights(weights)
+
+	a := &TextAnalyzer{
+		weights:            weights,
+		normalization:      DefaultNormalization(),
+		signalExplanations: true,
+		phrases:            AIPhraseDictionary,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // TextAnalysisResult contains detailed analysis results.
@@ -77,22 +118,88 @@ type TextAnalysisResult struct {
 	Signals TextSignals
 
 	// Detected AI phrases
-	DetectedAIPhrases []string
+	DetectedAIPhrases []DetectedPhrase
+
+	// SignalExplanations describes each signal score in plain language,
+	// keyed by signal name (SignalSentenceVariance etc.); nil when disabled
+	// with WithSignalExplanations(false)
+	SignalExplanations map[string]string
 
 	// Statistics
 	Stats TextStats
+
+	// TemporalConfidenceNote warns that the text appears to be newer than
+	// the analyzer's calibration data (empty if no warning applies).
+	// It doesn't affect AIScore.
+	TemporalConfidenceNote string
+
+	// Email holds the email-specific analysis; set only by AnalyzeEmail
+	Email *EmailAnalysisResult
+
+	// Watermark is the experimental watermark scan (nil for text too short
+	// to scan). It doesn't affect AIScore.
+	Watermark *WatermarkScan
+}
+
+// DetectedPhrase is an AI phrase found in the text.
+type DetectedPhrase struct {
+	// Phrase is the matched dictionary pattern
+	Phrase string
+
+	// Category is the phrase's dictionary category, e.g. "hedging"
+	Category string
 }
 
 // TextSignals contains individual signal scores.
 type TextSignals struct {
 	SentenceVariance   float64 // Low variance = AI-like
 	VocabularyRichness float64 // Low richness = AI-like
+	BigramDiversity    float64 // Recycled word pairs = AI-like
 	Burstiness         float64 // Low burstiness = AI-like
 	PunctuationVariety float64 // Low variety = AI-like
 	AIPhraseScore      float64 // High = AI-like
 	WordLengthVariance float64 // Low variance = AI-like
 	ContractionsUsage  float64 // Low usage = AI-like
 	RepetitionScore    float64 // High repetition = AI-like
+	EmbeddedDataScore  float64 // Deep, uniform, verbose JSON/YAML = AI-like
+
+	SentenceTypeDistribution float64 // All statements, no questions or commands = AI-like
+
+	AcademicRegister float64 // Generic formality over academic conventions = AI-like (academic mode only)
+}
+
+// AnalysisOverrides adjust the vocabulary lists for one analysis, for
+// domains where dictionary phrases are ordinary jargon.
+type AnalysisOverrides struct {
+	// StopPhrases are AI phrase patterns not to flag (case-insensitive)
+	StopPhrases []string
+
+	// CommonWords are treated as common vocabulary in addition to the
+	// built-in list (case-insensitive)
+	CommonWords []string
+}
+
+// isStopPhrase reports whether pattern is one of the stop phrases.
+func (o AnalysisOverrides) isStopPhrase(pattern string) bool {
+	for _, p := range o.StopPhrases {
+		if strings.EqualFold(strings.TrimSpace(p), pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommonWord is isCommonWord plus the extra common words.
+func (o AnalysisOverrides) isCommonWord(word string) bool {
+	if isCommonWord(word) {
+		return true
+	}
+	for _, w := range o.CommonWords {
+		if strings.EqualFold(strings.TrimSpace(w), word) {
+			return true
+		}
+	}
+	return false
 }
 
 // TextStats contains raw statistics about the text.
@@ -105,29 +212,88 @@ type TextStats struct {
 	UniqueWords      int
 	UniqueRatio      float64
 	PunctuationCount int
+	EmbeddedBlocks   int // Fenced JSON/YAML code blocks
+	MessageCount     int // Messages joined by AnalyzeMessages (0 otherwise)
+
+	NormalizationApplied bool // Normalization changed the text before analysis
 }
 
 // Analyze performs comprehensive text analysis.
 func (a *TextAnalyzer) Analyze(text string) TextAnalysisResult {
+	// A background context is never cancelled
+	result, _ := a.AnalyzeContext(context.Background(), text)
+	return result
+}
+
+// AnalyzeMessages analyzes a conversation's messages as one text, a blank
+// line apart, and records how many there were.
+func (a *TextAnalyzer) AnalyzeMessages(messages []string) TextAnalysisResult {
+	result := a.Analyze(strings.Join(messages, "\n\n"))
+	result.Stats.MessageCount = len(messages)
+	return result
+}
+
+// AnalyzeWithOverrides is Analyze with per-request vocabulary overrides.
+func (a *TextAnalyzer) AnalyzeWithOverrides(text string, opts AnalysisOverrides) TextAnalysisResult {
+	// A background context is never cancelled
+	result, _ := a.analyze(context.Background(), text, opts)
+	return result
+}
+
+// AnalyzeContext is Analyze, returning ctx's error if ctx is cancelled
+// between signals.
+func (a *TextAnalyzer) AnalyzeContext(ctx context.Context, text string) (TextAnalysisResult, error) {
+	return a.analyze(ctx, text, AnalysisOverrides{})
+}
+
+// analyze runs the analysis with overrides, checking ctx between signals.
+func (a *TextAnalyzer) analyze(ctx context.Context, text string, opts AnalysisOverrides) (TextAnalysisResult, error) {
 	result := TextAnalysisResult{}
 
-	// Calculate basic stats
-	result.Stats = a.calculateStats(text)
+	normalized := normalizeText(text, a.normalization)
+	applied := normalized != text
+	text = normalized
+
+	stages := []func(){
+		// Calculate basic stats
+		func() { result.Stats = a.calculateStats(text) },
+
+		// Calculate individual signals
+		func() { result.Signals.SentenceVariance = a.analyzeSentenceVariance(text) },
+		func() { result.Signals.VocabularyRichness = a.analyzeVocabularyRichness(text, opts) },
+		func() { result.Signals.BigramDiversity = a.analyzeBigramDiversity(tokenize(text)) },
+		func() { result.Signals.Burstiness = a.analyzeBurstiness(text, opts) },
+		func() { result.Signals.PunctuationVariety = a.analyzePunctuationVariety(text) },
+		func() { result.Signals.AIPhraseScore, result.DetectedAIPhrases = a.detectAIPhrases(text, opts) },
+		func() { result.Signals.WordLengthVariance = a.analyzeWordLengthVariance(text) },
+		func() { result.Signals.ContractionsUsage = a.analyzeContractions(text) },
+		func() { result.Signals.RepetitionScore = a.analyzeRepetition(text) },
+		func() { result.Signals.SentenceTypeDistribution = a.analyzeSentenceTypeDistribution(text) },
+		func() {
+			result.Stats.EmbeddedBlocks, result.Signals.EmbeddedDataScore = detectEmbeddedStructuredData(text)
+		},
+		func() { result.Watermark = scanWatermark(tokenize(text)) },
+	}
+	for _, stage := range stages {
+		if err := ctx.Err(); err != nil {
+			return TextAnalysisResult{}, err
+		}
+		stage()
+	}
+
+	result.Stats.NormalizationApplied = applied
 
-	// Calculate individual signals
-	result.Signals.SentenceVariance = a.analyzeSentenceVariance(text)
-	result.Signals.VocabularyRichness = a.analyzeVocabularyRichness(text)
-	result.Signals.Burstiness = a.analyzeBurstiness(text)
-	result.Signals.PunctuationVariety = a.analyzePunctuationVariety(text)
-	result.Signals.AIPhraseScore, result.DetectedAIPhrases = a.detectAIPhrases(text)
-	result.Signals.WordLengthVariance = a.analyzeWordLengthVariance(text)
-	result.Signals.ContractionsUsage = a.analyzeContractions(text)
-	result.Signals.RepetitionScore = a.analyzeRepetition(text)
+	if a.signalExplanations {
+		result.SignalExplanations = a.explainSignals(result)
+	}
 
 	// Calculate weighted AI score
-	result.AIScore = a.calculateWeightedScore(result.Signals)
+	result.AIScore = a.calculateWeightedScore(result.Signals, result.Stats.EmbeddedBlocks > 0)
 
-	return result
+	// Flag text that may postdate our calibration
+	result.TemporalConfidenceNote = a.temporalConfidenceNote(text)
+
+	return result, nil
 }
 
 // calculateStats computes basic text statistics.
@@ -229,7 +395,7 @@ func (a *TextAnalyzer) analyzeSentenceVariance(text string) float64 {
 
 // analyzeVocabularyRichness measures lexical diversity.
 // Humans use more varied vocabulary; AI uses "safe" common words.
-func (a *TextAnalyzer) analyzeVocabularyRichness(text string) float64 {
+func (a *TextAnalyzer) analyzeVocabularyRichness(text string, opts AnalysisOverrides) float64 {
 	words := tokenize(text)
 	if len(words) < 10 {
 		return 0.5 // Not enough data
@@ -245,7 +411,7 @@ func (a *TextAnalyzer) analyzeVocabularyRichness(text string) float64 {
 	// Check for rare/unusual words (not in common vocabulary)
 	uncommonCount := 0
 	for w := range unique {
-		if !isCommonWord(w) && len(w) > 3 {
+		if !opts.isCommonWord(w) && len(w) > 3 {
 			uncommonCount++
 		}
 	}
@@ -261,9 +427,145 @@ func (a *TextAnalyzer) analyzeVocabularyRichness(text string) float64 {
 	return math.Max(0, math.Min(1, aiScore))
 }
 
+// bigramWindow is the number of tokens per bigram diversity window. Longer
+// texts repeat word pairs by chance, so the ratio is averaged over windows
+// of this size to keep short and long texts comparable.
+const bigramWindow = 100
+
+// analyzeBigramDiversity measures how often word pairs recur.
+// Type-token ratio only sees single words: AI text recycles the same
+// phrasings ("it is important to", "in order to") even when its vocabulary
+// is as rich as a human's.
+func (a *TextAnalyzer) analyzeBigramDiversity(tokens []string) float64 {
+	if len(tokens) < 20 {
+		return 0.5 // Not enough data
+	}
+
+	var ratioSum float64
+	windows := 0
+	for start := 0; start < len(tokens); start += bigramWindow {
+		end := min(start+bigramWindow, len(tokens))
+		if end-start < 20 && windows > 0 {
+			break // Tail too short to say anything
+		}
+
+		bigrams := make(map[string]int)
+		for i := start; i < end-1; i++ {
+			bigrams[strings.ToLower(tokens[i])+" "+strings.ToLower(tokens[i+1])]++
+		}
+		ratioSum += float64(len(bigrams)) / float64(end-start-1)
+		windows++
+	}
+	ratio := ratioSum / float64(windows)
+
+	// Human prose rarely repeats a pair within 100 words (ratio ~0.97+);
+	// formulaic text drops toward 0.8
+	aiScore := (0.97 - ratio) / 0.17
+
+	return math.Max(0, math.Min(1, aiScore))
+}
+
+// sentenceType is a sentence's mood, as far as its punctuation and first
+// word tell.
+type sentenceType int
+
+const (
+	sentenceDeclarative sentenceType = iota
+	sentenceInterrogative
+	sentenceExclamatory
+	sentenceImperative
+
+	sentenceTypeCount
+)
+
+// imperativeVerbs are verbs that start commands ("Try the soup.").
+var imperativeVerbs = map[string]bool{
+	"do": true, "don't": true, "make": true, "try": true, "consider": true, "note": true,
+	"remember": true, "keep": true, "let": true, "take": true, "check": true, "imagine": true,
+}
+
+// sentenceBoundaryPattern is the boundary splitSentences splits on.
+var sentenceBoundaryPattern = regexp.MustCompile(`[.!?]+\s+`)
+
+// analyzeSentenceTypeDistribution measures how varied sentence types are.
+// AI text is almost all statements; people ask questions, exclaim and give
+// commands. The score is the entropy of the four-type distribution, so a
+// text of only statements is AI-like however it's punctuated otherwise.
+func (a *TextAnalyzer) analyzeSentenceTypeDistribution(text string) float64 {
+	types := classifySentences(text)
+	if len(types) < 3 {
+		return 0.5 // Not enough data
+	}
+
+	var counts [sentenceTypeCount]int
+	for _, t := range types {
+		counts[t]++
+	}
+
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(types))
+		entropy -= p * math.Log2(p)
+	}
+
+	// Statements with one sentence in ten a question and one an exclamation
+	// is ~0.9 bits; a bit or more is as varied as prose gets
+	return 1.0 - math.Min(entropy, 1.0)
+}
+
+// classifySentences returns the type of each sentence in text, in
+// splitSentences order. splitSentences drops the punctuation it splits on,
+// so each sentence's ending is read from the original text at the boundary.
+func classifySentences(text string) []sentenceType {
+	boundaries := sentenceBoundaryPattern.FindAllStringIndex(text, -1)
+
+	var types []sentenceType
+	start := 0
+	for i := 0; i <= len(boundaries); i++ {
+		end, ending := len(text), ""
+		if i < len(boundaries) {
+			end = boundaries[i][0]
+			ending = strings.TrimSpace(text[boundaries[i][0]:boundaries[i][1]])
+		}
+		sentence := strings.TrimSpace(text[start:end])
+		if i < len(boundaries) {
+			start = boundaries[i][1]
+		}
+		if sentence == "" {
+			continue
+		}
+
+		// The last sentence keeps its own punctuation
+		if ending == "" {
+			ending = sentence[len(strings.TrimRight(sentence, ".!?")):]
+		}
+		types = append(types, classifySentence(sentence, ending))
+	}
+	return types
+}
+
+// classifySentence types a sentence by its ending punctuation, then by its
+// first word.
+func classifySentence(sentence, ending string) sentenceType {
+	switch {
+	case strings.Contains(ending, "?"):
+		return sentenceInterrogative
+	case strings.Contains(ending, "!"):
+		return sentenceExclamatory
+	}
+
+	if words := tokenize(sentence); len(words) > 0 && imperativeVerbs[strings.ToLower(words[0])] {
+		return sentenceImperative
+	}
+	return sentenceDeclarative
+}
+
 // analyzeBurstiness measures topic word clustering.
 // Humans tend to cluster related words; AI distributes them evenly.
-func (a *TextAnalyzer) analyzeBurstiness(text string) float64 {
+func (a *TextAnalyzer) analyzeBurstiness(text string, opts AnalysisOverrides) float64 {
 	words := tokenize(text)
 	if len(words) < 20 {
 		return 0.5
@@ -273,7 +575,7 @@ func (a *TextAnalyzer) analyzeBurstiness(text string) float64 {
 	wordPositions := make(map[string][]int)
 	for i, w := range words {
 		w = strings.ToLower(w)
-		if len(w) > 4 && !isCommonWord(w) {
+		if len(w) > 4 && !opts.isCommonWord(w) {
 			wordPositions[w] = append(wordPositions[w], i)
 		}
 	}
@@ -365,66 +667,25 @@ func (a *TextAnalyzer) analyzePunctuationVariety(text string) float64 {
 	return math.Max(0, math.Min(1, aiScore))
 }
 
-// detectAIPhrases looks for common AI writing patterns.
-func (a *TextAnalyzer) detectAIPhrases(text string) (float64, []string) {
+// detectAIPhrases looks for common AI writing patterns from the phrase
+// dictionary, skipping the override stop phrases.
+func (a *TextAnalyzer) detectAIPhrases(text string, opts AnalysisOverrides) (float64, []DetectedPhrase) {
 	lowerText := strings.ToLower(text)
-	detected := []string{}
-
-	// Common AI phrases and patterns
-	aiPhrases := []struct {
-		pattern string
-		weight  float64
-	}{
-		// Direct AI references
-		{"as an ai", 1.0},
-		{"as a language model", 1.0},
-		{"i don't have personal", 0.9},
-		{"i cannot provide", 0.8},
-		{"i'm unable to", 0.7},
-
-		// Hedging phrases
-		{"it's important to note", 0.8},
-		{"it is important to", 0.7},
-		{"it's worth noting", 0.7},
-		{"it should be noted", 0.7},
-		{"keep in mind that", 0.6},
-
-		// Transition phrases (overused by AI)
-		{"furthermore", 0.4},
-		{"moreover", 0.4},
-		{"additionally", 0.4},
-		{"in conclusion", 0.5},
-		{"to summarize", 0.5},
-		{"in summary", 0.5},
-		{"overall", 0.3},
-
-		// Generic helpful phrases
-		{"i hope this helps", 0.7},
-		{"feel free to", 0.5},
-		{"don't hesitate to", 0.5},
-		{"let me know if", 0.4},
-
-		// Formal/stilted phrasing
-		{"utilize", 0.3},
-		{"facilitate", 0.3},
-		{"leverage", 0.3},
-		{"delve into", 0.6},
-		{"dive into", 0.4},
-		{"explore the", 0.3},
-
-		// List introductions
-		{"here are some", 0.5},
-		{"here's a list", 0.5},
-		{"the following", 0.4},
-	}
+	detected := []DetectedPhrase{}
 
 	totalWeight := 0.0
 	matchCount := 0
 
-	for _, phrase := range aiPhrases {
-		if strings.Contains(lowerText, phrase.pattern) {
-			detected = append(detected, phrase.pattern)
-			totalWeight += phrase.weight
+	for _, phrase := range a.phrases {
+		if opts.isStopPhrase(phrase.Pattern) {
+			continue
+		}
+		if strings.Contains(lowerText, phrase.Pattern) {
+			detected = append(detected, DetectedPhrase{
+				Phrase:   phrase.Pattern,
+				Category: phrase.Category,
+			})
+			totalWeight += phrase.Weight
 			matchCount++
 		}
 	}
@@ -552,35 +813,185 @@ func (a *TextAnalyzer) analyzeRepetition(text string) float64 {
 	return aiScore
 }
 
+// fencedBlockPattern matches markdown code blocks tagged json, yaml or yml.
+var fencedBlockPattern = regexp.MustCompile("(?s)```(?:json|yaml|yml)[ \t]*\r?\n(.*?)```")
+
+// structuredKeyPattern matches JSON ("key":) and YAML (key:) keys.
+var structuredKeyPattern = regexp.MustCompile(`(?m)^\s*-?\s*"?([A-Za-z_][A-Za-z0-9_\-]*)"?\s*:`)
+
+// detectEmbeddedStructuredData finds fenced JSON/YAML blocks in markdown and
+// scores how generated they look. AI-written examples tend to be deeply
+// nested, indented with machine precision, and use long descriptive keys
+// ("userAccountCreationTimestamp"). Hand-written ones are shallow, a little
+// sloppy, and use short keys.
+//
+// Returns the number of blocks found and their average AI score (0 if none).
+func detectEmbeddedStructuredData(text string) (count int, aiScore float64) {
+	matches := fencedBlockPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return 0, 0
+	}
+
+	total := 0.0
+	for _, m := range matches {
+		total += scoreStructuredBlock(m[1])
+	}
+
+	return len(matches), total / float64(len(matches))
+}
+
+// scoreStructuredBlock scores a single JSON/YAML block body.
+func scoreStructuredBlock(block string) float64 {
+	var indents []int
+	mixedTabs := false
+
+	for _, line := range strings.Split(block, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		leading := line[:len(line)-len(trimmed)]
+		if strings.Contains(leading, "\t") {
+			mixedTabs = true
+		}
+		indents = append(indents, len(leading))
+	}
+
+	if len(indents) < 3 {
+		return 0.5 // Not enough data
+	}
+
+	// Indentation: find the unit (smallest non-zero indent) and check that
+	// every line is an exact multiple of it
+	unit := 0
+	for _, n := range indents {
+		if n > 0 && (unit == 0 || n < unit) {
+			unit = n
+		}
+	}
+
+	uniformity := 0.5 // Flat block - nothing to judge
+	depth := 0
+	if unit > 0 {
+		// Single-space indents are a typo, not a style
+		uniform := !mixedTabs && unit >= 2
+		for _, n := range indents {
+			if n%unit != 0 {
+				uniform = false
+			}
+		}
+		if uniform {
+			uniformity = 1.0
+		} else {
+			uniformity = 0.1
+			unit = 2 // Estimate depth assuming a conventional indent
+		}
+		for _, n := range indents {
+			if n/unit > depth {
+				depth = n / unit
+			}
+		}
+	}
+
+	// Nesting: 4+ levels is rare in hand-written examples
+	depthScore := math.Min(float64(depth)/4.0, 1.0)
+
+	// Key verbosity: average key length, 5 chars is human-like, 15+ AI-like
+	verbosity := 0.5
+	keys := structuredKeyPattern.FindAllStringSubmatch(block, -1)
+	if len(keys) > 0 {
+		totalLen := 0
+		for _, k := range keys {
+			totalLen += len(k[1])
+		}
+		avgLen := float64(totalLen) / float64(len(keys))
+		verbosity = math.Max(0, math.Min((avgLen-5)/10, 1.0))
+	}
+
+	return uniformity*0.4 + depthScore*0.3 + verbosity*0.3
+}
+
 // calculateWeightedScore combines all signals into final AI score.
-func (a *TextAnalyzer) calculateWeightedScore(signals TextSignals) float64 {
+// The embedded data signal only counts when the text contains such blocks;
+// otherwise it would pull every plain-prose score toward zero.
+func (a *TextAnalyzer) calculateWeightedScore(signals TextSignals, hasEmbeddedData bool) float64 {
 	w := a.weights
+	if !hasEmbeddedData {
+		w.EmbeddedData = 0
+	}
 
 	score := signals.SentenceVariance*w.SentenceVariance +
 		signals.VocabularyRichness*w.VocabularyRichness +
+		signals.BigramDiversity*w.BigramDiversity +
 		signals.Burstiness*w.Burstiness +
 		signals.PunctuationVariety*w.PunctuationVariety +
 		signals.AIPhraseScore*w.AIPhraseDetection +
 		signals.WordLengthVariance*w.WordLengthVariance +
 		signals.ContractionsUsage*w.ContractionsUsage +
-		signals.RepetitionScore*w.RepetitionPenalty
+		signals.RepetitionScore*w.RepetitionPenalty +
+		signals.EmbeddedDataScore*w.EmbeddedData +
+		signals.SentenceTypeDistribution*w.SentenceTypes
 
 	// Normalize to 0-1
-	totalWeight := w.SentenceVariance + w.VocabularyRichness + w.Burstiness +
+	totalWeight := w.SentenceVariance + w.VocabularyRichness + w.BigramDiversity + w.Burstiness +
 		w.PunctuationVariety + w.AIPhraseDetection + w.WordLengthVariance +
-		w.ContractionsUsage + w.RepetitionPenalty
+		w.ContractionsUsage + w.RepetitionPenalty + w.EmbeddedData + w.SentenceTypes
 
 	if totalWeight > 0 {
 		score /= totalWeight
 	}
 
+	// Dense stock phrases are close to conclusive on their own, but the
+	// other signals can wash them out of a linear blend
+	if w.AIPhraseDetection > 0 && signals.AIPhraseScore > 0.5 {
+		score += (1 - score) * aiPhraseLift * (signals.AIPhraseScore - 0.5) / 0.5
+	}
+
 	return math.Max(0, math.Min(1, score))
 }
 
+// aiPhraseLift is how far a saturated AI phrase score moves the blended
+// score towards 1. Assistant boilerplate ("As an AI language model", "I
+// hope this helps") rarely reaches that density in human writing.
+const aiPhraseLift = 0.35
+
+// yearPattern matches four-digit years from 1900 to 2099.
+var yearPattern = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+
+// temporalConfidenceNote returns a warning if the text references a year after
+// the calibration cutoff. Newer AI models write in styles our signals haven't
+// seen, so a recent date is a hint that the score may be less reliable.
+func (a *TextAnalyzer) temporalConfidenceNote(text string) string {
+	cutoff := a.weights.CutoffYear
+	if cutoff <= 0 {
+		return ""
+	}
+
+	latest := latestYear(text)
+	if latest <= cutoff {
+		return ""
+	}
+
+	return fmt.Sprintf("text references %d, after the %d calibration cutoff; "+
+		"detection may be less reliable for recent AI writing styles", latest, cutoff)
+}
+
 // =============================================================================
 // Helper Functions
 // =============================================================================
 
+// latestYear returns the most recent year referenced in text, or 0 if none.
+func latestYear(text string) int {
+	latest := 0
+	for _, m := range yearPattern.FindAllString(text, -1) {
+		year, _ := strconv.Atoi(m)
+		if year > latest {
+			latest = year
+		}
+	}
+	return latest
+}
+
 // tokenize splits text into words.
 func tokenize(text string) []string {
 	// Simple tokenization: split on whitespace and punctuation
@@ -588,6 +999,38 @@ func tokenize(text string) []string {
 	return re.FindAllString(text, -1)
 }
 
+// scoreSentences scores each sentence of text, for comparing with the
+// providers' sentence scores. One sentence is too short for most signals,
+// so each score starts from the document's and moves with what the
+// sentence shows on its own: AI phrases, and a length close to the text's
+// average (uniform sentence lengths are AI-like).
+func (a *TextAnalyzer) scoreSentences(text string, documentScore float64, opts AnalysisOverrides) []SentenceScore {
+	sentences := splitSentences(text)
+	if len(sentences) == 0 {
+		return nil
+	}
+
+	lengths := make([]float64, len(sentences))
+	total := 0.0
+	for i, s := range sentences {
+		lengths[i] = float64(len(tokenize(s)))
+		total += lengths[i]
+	}
+	mean := total / float64(len(sentences))
+
+	scores := make([]SentenceScore, len(sentences))
+	for i, s := range sentences {
+		phraseScore, _ := a.detectAIPhrases(s, opts)
+		uniformity := 0.5
+		if mean > 0 {
+			uniformity = 1 - math.Min(math.Abs(lengths[i]-mean)/mean, 1)
+		}
+		local := phraseScore*0.6 + uniformity*0.4
+		scores[i] = SentenceScore{Text: s, AIScore: clampScore(documentScore*0.5 + local*0.5)}
+	}
+	return scores
+}
+
 // splitSentences splits text into sentences.
 func splitSentences(text string) []string {
 	// Split on sentence-ending punctuation
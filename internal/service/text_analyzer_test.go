@@ -1,6 +1,8 @@
 package service
 
 import (
+	"math"
+	"strings"
 	"testing"
 )
 
@@ -110,6 +112,123 @@ func TestTextAnalyzer(t *testing.T) {
 	})
 }
 
+// TestTemporalConfidenceNote tests the warning for text newer than the calibration cutoff.
+func TestTemporalConfidenceNote(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	t.Run("warns for years after cutoff", func(t *testing.T) {
+		text := `The 2021 season was rough. By 2025 things had changed a lot, and honestly I'm glad they did.`
+
+		result := analyzer.Analyze(text)
+
+		if result.TemporalConfidenceNote == "" {
+			t.Fatal("expected temporal note for text referencing 2025")
+		}
+		if !strings.Contains(result.TemporalConfidenceNote, "2025") {
+			t.Errorf("note should mention the latest year, got %q", result.TemporalConfidenceNote)
+		}
+	})
+
+	t.Run("no warning for older years", func(t *testing.T) {
+		text := `Back in 2021 we moved to Denver. It snowed in 1999 too, or so my dad says.`
+
+		result := analyzer.Analyze(text)
+
+		if result.TemporalConfidenceNote != "" {
+			t.Errorf("expected no note for text referencing 2021, got %q", result.TemporalConfidenceNote)
+		}
+	})
+
+	t.Run("does not change score", func(t *testing.T) {
+		older := analyzer.Analyze(`We launched the product in 2021 and it went well overall.`)
+		newer := analyzer.Analyze(`We launched the product in 2025 and it went well overall.`)
+
+		if older.AIScore != newer.AIScore {
+			t.Errorf("expected equal scores, got %f and %f", older.AIScore, newer.AIScore)
+		}
+	})
+
+	t.Run("ignores numbers that are not years", func(t *testing.T) {
+		if got := latestYear("Order 120250 shipped with 3000 units"); got != 0 {
+			t.Errorf("latestYear() = %d, want 0", got)
+		}
+		if got := latestYear("from 1998 to 2024"); got != 2024 {
+			t.Errorf("latestYear() = %d, want 2024", got)
+		}
+	})
+}
+
+// TestEmbeddedStructuredData tests scoring of fenced JSON/YAML blocks.
+func TestEmbeddedStructuredData(t *testing.T) {
+	handWritten := "Here's roughly what my config looks like:\n\n" +
+		"```json\n" +
+		"{\n" +
+		"  \"port\": 8080,\n" +
+		"   \"db\": \"pg\",\n" +
+		" \"debug\": true\n" +
+		"}\n" +
+		"```\n\nNothing fancy."
+
+	generated := "Here is an example response:\n\n" +
+		"```json\n" +
+		"{\n" +
+		"  \"userAccountInformation\": {\n" +
+		"    \"primaryContactDetails\": {\n" +
+		"      \"emailAddressValue\": \"user@example.com\",\n" +
+		"      \"verificationStatusDetails\": {\n" +
+		"        \"isEmailAddressVerified\": true,\n" +
+		"        \"verificationTimestampUtc\": \"2023-01-01T00:00:00Z\"\n" +
+		"      }\n" +
+		"    }\n" +
+		"  }\n" +
+		"}\n" +
+		"```\n\nThis structure provides a comprehensive overview."
+
+	t.Run("scores generated JSON higher than hand-written", func(t *testing.T) {
+		humanCount, humanScore := detectEmbeddedStructuredData(handWritten)
+		aiCount, aiScore := detectEmbeddedStructuredData(generated)
+
+		if humanCount != 1 || aiCount != 1 {
+			t.Fatalf("expected one block each, got %d and %d", humanCount, aiCount)
+		}
+		if aiScore <= humanScore {
+			t.Errorf("expected generated JSON (%f) to score above hand-written (%f)", aiScore, humanScore)
+		}
+		if aiScore < 0.7 {
+			t.Errorf("expected high score for generated JSON, got %f", aiScore)
+		}
+		if humanScore > 0.4 {
+			t.Errorf("expected low score for hand-written JSON, got %f", humanScore)
+		}
+	})
+
+	t.Run("detects yaml blocks", func(t *testing.T) {
+		text := "Config:\n\n```yaml\nserver:\n  port: 8080\n  host: localhost\n```\n"
+		count, _ := detectEmbeddedStructuredData(text)
+		if count != 1 {
+			t.Errorf("expected 1 yaml block, got %d", count)
+		}
+	})
+
+	t.Run("ignores other code blocks", func(t *testing.T) {
+		text := "Run this:\n\n```go\nfunc main() {\n\tfmt.Println(1)\n}\n```\n"
+		count, score := detectEmbeddedStructuredData(text)
+		if count != 0 || score != 0 {
+			t.Errorf("expected no blocks, got count=%d score=%f", count, score)
+		}
+	})
+
+	t.Run("signal is recorded on analysis", func(t *testing.T) {
+		result := NewTextAnalyzer().Analyze(generated)
+		if result.Stats.EmbeddedBlocks != 1 {
+			t.Errorf("expected EmbeddedBlocks 1, got %d", result.Stats.EmbeddedBlocks)
+		}
+		if result.Signals.EmbeddedDataScore == 0 {
+			t.Error("expected non-zero EmbeddedDataScore")
+		}
+	})
+}
+
 // TestTextStats verifies basic statistics calculation.
 func TestTextStats(t *testing.T) {
 	analyzer := NewTextAnalyzer()
@@ -117,16 +236,16 @@ func TestTextStats(t *testing.T) {
 	text := "Hello world. This is a test. One two three."
 	result := analyzer.Analyze(text)
 
-	if result.Stats.WordCount != 10 {
-		t.Errorf("expected 10 words, got %d", result.Stats.WordCount)
+	if result.Stats.WordCount != 9 {
+		t.Errorf("expected 9 words, got %d", result.Stats.WordCount)
 	}
 
 	if result.Stats.SentenceCount != 3 {
 		t.Errorf("expected 3 sentences, got %d", result.Stats.SentenceCount)
 	}
 
-	if result.Stats.UniqueWords != 10 { // All words are unique
-		t.Errorf("expected 10 unique words, got %d", result.Stats.UniqueWords)
+	if result.Stats.UniqueWords != 9 { // All words are unique
+		t.Errorf("expected 9 unique words, got %d", result.Stats.UniqueWords)
 	}
 
 	t.Logf("Stats: %+v", result.Stats)
@@ -174,6 +293,37 @@ func TestSplitSentences(t *testing.T) {
 	}
 }
 
+// TestScoreSentences tests the local per-sentence scores.
+func TestScoreSentences(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+	text := "We should delve into the results before the meeting. We looked again at the results before the meeting. Fine."
+
+	scores := analyzer.scoreSentences(text, 0.5, AnalysisOverrides{})
+	if len(scores) != 3 {
+		t.Fatalf("scoreSentences() = %+v, want 3 sentences", scores)
+	}
+	for _, s := range scores {
+		if s.AIScore < 0 || s.AIScore > 1 {
+			t.Errorf("%q scored %f, want 0-1", s.Text, s.AIScore)
+		}
+	}
+	if scores[0].AIScore <= scores[1].AIScore {
+		t.Errorf("sentence with an AI phrase scored %f, want above %f", scores[0].AIScore, scores[1].AIScore)
+	}
+	if scores[2].AIScore >= scores[1].AIScore {
+		t.Errorf("outlier-length sentence scored %f, want below %f", scores[2].AIScore, scores[1].AIScore)
+	}
+
+	stopped := analyzer.scoreSentences(text, 0.5, AnalysisOverrides{StopPhrases: []string{"delve into"}})
+	if stopped[0].AIScore != stopped[1].AIScore {
+		t.Errorf("stop phrase still scored: %f vs %f", stopped[0].AIScore, stopped[1].AIScore)
+	}
+
+	if got := analyzer.scoreSentences("", 0.5, AnalysisOverrides{}); got != nil {
+		t.Errorf("scoreSentences(\"\") = %+v, want nil", got)
+	}
+}
+
 // TestIsCommonWord tests common word detection.
 func TestIsCommonWord(t *testing.T) {
 	common := []string{"the", "a", "is", "are", "and", "but", "it", "for"}
@@ -192,6 +342,55 @@ func TestIsCommonWord(t *testing.T) {
 	}
 }
 
+// TestAnalyzeWithOverrides tests per-request stop phrases and common words.
+func TestAnalyzeWithOverrides(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+	text := "We leverage our balance sheet to fund acquisitions. Leverage ratios stayed within covenant limits this quarter, and we plan to utilize the revolver next year."
+
+	hasPhrase := func(result TextAnalysisResult, phrase string) bool {
+		for _, p := range result.DetectedAIPhrases {
+			if p.Phrase == phrase {
+				return true
+			}
+		}
+		return false
+	}
+
+	base := analyzer.Analyze(text)
+	if !hasPhrase(base, "leverage") {
+		t.Fatalf("expected %q without overrides, got %v", "leverage", base.DetectedAIPhrases)
+	}
+
+	t.Run("stop phrases", func(t *testing.T) {
+		result := analyzer.AnalyzeWithOverrides(text, AnalysisOverrides{StopPhrases: []string{"Leverage"}})
+		if hasPhrase(result, "leverage") {
+			t.Errorf("expected %q to be skipped, got %v", "leverage", result.DetectedAIPhrases)
+		}
+		if !hasPhrase(result, "utilize") {
+			t.Errorf("expected %q to still be detected, got %v", "utilize", result.DetectedAIPhrases)
+		}
+		if result.Signals.AIPhraseScore > base.Signals.AIPhraseScore {
+			t.Errorf("AI phrase signal rose with stop phrases: %f > %f", result.Signals.AIPhraseScore, base.Signals.AIPhraseScore)
+		}
+	})
+
+	t.Run("common words", func(t *testing.T) {
+		opts := AnalysisOverrides{CommonWords: []string{"Covenant"}}
+		if !opts.isCommonWord("covenant") || !opts.isCommonWord("the") {
+			t.Error("expected custom and built-in words to be common")
+		}
+		if opts.isCommonWord("revolver") {
+			t.Error("expected revolver to be uncommon")
+		}
+	})
+
+	t.Run("zero overrides match Analyze", func(t *testing.T) {
+		if got := analyzer.AnalyzeWithOverrides(text, AnalysisOverrides{}); got.AIScore != base.AIScore {
+			t.Errorf("AIScore = %f, want %f", got.AIScore, base.AIScore)
+		}
+	})
+}
+
 // TestVocabularyRichness tests lexical diversity analysis.
 func TestVocabularyRichness(t *testing.T) {
 	analyzer := NewTextAnalyzer()
@@ -215,6 +414,108 @@ func TestVocabularyRichness(t *testing.T) {
 		richResult.Signals.VocabularyRichness, poorResult.Signals.VocabularyRichness)
 }
 
+// TestBigramDiversity tests word-pair diversity on paraphrases with similar
+// vocabulary richness.
+func TestBigramDiversity(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	t.Run("paraphrase pair", func(t *testing.T) {
+		// Same content; the first recycles its phrasing
+		formulaic := `Regular exercise can improve your mood and your sleep. Regular exercise can also improve your focus at work. In addition, regular exercise can help you manage stress and anxiety. It is important to find a routine that works for you. It is important to start slowly and build up over time. In addition, it is important to drink enough water and to get enough rest. Regular exercise can make a real difference in your overall health.`
+		varied := `Working out a few times a week tends to lift your mood, and most people find they sleep better too. Plenty also notice sharper focus at the office. Stress and anxiety become easier to manage once movement is part of the week. Pick a routine you actually enjoy, start slow, and build up as your stamina grows. Drink plenty of water, rest when your body asks for it, and the difference in your overall health shows up sooner than you would expect.`
+
+		f := analyzer.Analyze(formulaic).Signals
+		v := analyzer.Analyze(varied).Signals
+
+		if math.Abs(f.VocabularyRichness-v.VocabularyRichness) > 0.1 {
+			t.Fatalf("pair should have similar vocabulary richness: %f vs %f", f.VocabularyRichness, v.VocabularyRichness)
+		}
+		if f.BigramDiversity <= v.BigramDiversity+0.5 {
+			t.Errorf("formulaic paraphrase should score well above varied: %f vs %f", f.BigramDiversity, v.BigramDiversity)
+		}
+	})
+
+	t.Run("same words, different order", func(t *testing.T) {
+		// Identical type-token ratio; only the pairs differ (11 words, so
+		// every stride below is a permutation)
+		words := strings.Fields("we should plan the next release around what user feedback says")
+		var repeated, shuffled []string
+		for i := 0; i < 6; i++ {
+			repeated = append(repeated, words...)
+			for j := range words {
+				shuffled = append(shuffled, words[(j*(i+2)+i)%len(words)])
+			}
+		}
+
+		if r, s := analyzer.analyzeBigramDiversity(repeated), analyzer.analyzeBigramDiversity(shuffled); r <= s {
+			t.Errorf("repeated order should score higher: repeated=%f shuffled=%f", r, s)
+		}
+	})
+
+	t.Run("short text is neutral", func(t *testing.T) {
+		if got := analyzer.analyzeBigramDiversity(tokenize("Too short to tell.")); got != 0.5 {
+			t.Errorf("analyzeBigramDiversity() = %f, want 0.5", got)
+		}
+	})
+}
+
+// TestSentenceTypeDistribution tests sentence type classification and
+// entropy on Q&A content and monologue.
+func TestSentenceTypeDistribution(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	t.Run("classifies by punctuation and first word", func(t *testing.T) {
+		text := "Is this thing on? It works! Try the other button. The lights are off. Really?! Note the date"
+		want := []sentenceType{
+			sentenceInterrogative,
+			sentenceExclamatory,
+			sentenceImperative,
+			sentenceDeclarative,
+			sentenceInterrogative,
+			sentenceImperative,
+		}
+
+		got := classifySentences(text)
+		if len(got) != len(want) {
+			t.Fatalf("classifySentences() = %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("sentence %d: type = %d, want %d", i, got[i], want[i])
+			}
+		}
+		if n := len(splitSentences(text)); n != len(got) {
+			t.Errorf("classified %d sentences, splitSentences found %d", len(got), n)
+		}
+	})
+
+	t.Run("final sentence punctuation", func(t *testing.T) {
+		// The last sentence has no boundary after it to read
+		got := classifySentences("We left early. Did you stay?")
+		if len(got) != 2 || got[1] != sentenceInterrogative {
+			t.Errorf("classifySentences() = %v, want the last sentence interrogative", got)
+		}
+	})
+
+	qa := `How do I reset my password? Open the settings page and pick Security. What if I don't get the email? Check your spam folder first! Still nothing? Try again in ten minutes. The link expires after an hour. Don't share it with anyone!`
+	monologue := `The system stores passwords as salted hashes. Resetting a password sends a link to the registered address. The link expires after one hour for security reasons. Messages may occasionally be filtered as spam. Support staff can resend the link on request. The process typically takes a few minutes to complete.`
+
+	q := analyzer.analyzeSentenceTypeDistribution(qa)
+	m := analyzer.analyzeSentenceTypeDistribution(monologue)
+	if m != 1.0 {
+		t.Errorf("monologue score = %f, want 1.0 (all declarative)", m)
+	}
+	if q > 0.1 {
+		t.Errorf("Q&A score = %f, want near 0", q)
+	}
+
+	t.Run("short text is neutral", func(t *testing.T) {
+		if got := analyzer.analyzeSentenceTypeDistribution("Just two. Sentences here."); got != 0.5 {
+			t.Errorf("analyzeSentenceTypeDistribution() = %f, want 0.5", got)
+		}
+	})
+}
+
 // TestContractionsAnalysis tests contraction detection.
 func TestContractionsAnalysis(t *testing.T) {
 	analyzer := NewTextAnalyzer()
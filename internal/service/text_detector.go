@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/humanmark/humanmark/pkg/logger"
@@ -17,29 +16,50 @@ type textDetector struct {
 	config     DetectorConfig
 	logger     *logger.Logger
 	httpClient *http.Client
+	fetcher    *urlFetcher
+	metrics    *detectorMetrics
+
+	// analyzer is shared by all detections; it holds no per-call state
+	analyzer *TextAnalyzer
+
+	// Provider endpoints
+	originalityURL string
+	winstonURL     string
 }
 
 // NewTextDetector creates a new text detector.
 func NewTextDetector(config DetectorConfig, log *logger.Logger) TextDetector {
 	return &textDetector{
-		config: config,
-		logger: log,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		config:         config,
+		logger:         log.Component("text"),
+		httpClient:     providerClient(config),
+		fetcher:        newURLFetcherFromConfig(config),
+		metrics:        newDetectorMetrics(config.Metrics),
+		analyzer:       NewTextAnalyzer(WithAIPhrases(config.AIPhrases)),
+		originalityURL: providerEndpoint(config, "originality", originalityPath),
+		winstonURL:     providerEndpoint(config, "winston", winstonPath),
 	}
 }
 
 // DetectText analyzes text content for AI generation.
 func (d *textDetector) DetectText(ctx context.Context, input DetectionInput) (*DetectionResult, error) {
+	selected, err := selectDetectors(input, ContentTypeText, textDetectorsAvailable(d.config))
+	if err != nil {
+		return nil, err
+	}
+
 	text := input.Text
-	if text == "" && len(input.Data) > 0 {
-		text = string(input.Data)
+	if text == "" && input.HasData() {
+		// Text files are held to the document limit
+		data, err := input.ReadData(maxDocumentSize)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
 	}
 
 	if text == "" && input.URL != "" {
 		// Fetch text from URL
-		var err error
 		text, err = d.fetchTextFromURL(ctx, input.URL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch text from URL: %w", err)
@@ -53,121 +73,153 @@ func (d *textDetector) DetectText(ctx context.Context, input DetectionInput) (*D
 	// Collect results from available detectors
 	var scores []float64
 	var detectors []string
+	detectorErrors := make(map[string]error)
 
 	// ==========================================================================
 	// PRIMARY: Our own HumanMark statistical analyzer
 	// This runs locally with no external dependencies
 	// ==========================================================================
-	analyzer := NewTextAnalyzer()
-	analysis := analyzer.Analyze(text)
-	
-	scores = append(scores, analysis.AIScore)
-	detectors = append(detectors, "humanmark")
-	
-	d.logger.Debug("humanmark analysis complete",
-		"ai_score", analysis.AIScore,
-		"sentence_variance", analysis.Signals.SentenceVariance,
-		"vocabulary_richness", analysis.Signals.VocabularyRichness,
-		"ai_phrases_detected", len(analysis.DetectedAIPhrases),
-		"word_count", analysis.Stats.WordCount,
-	)
-
-	// ==========================================================================
-	// SECONDARY: External APIs (optional, for higher accuracy)
-	// These are weighted together with our algorithm
-	// ==========================================================================
+	var analysis TextAnalysisResult
+	var analyzed bool
+	overrides := AnalysisOverrides{
+		StopPhrases: input.CustomStopPhrases,
+		CommonWords: input.CustomCommonWords,
+	}
+	if selected["humanmark"] {
+		analyzer := d.analyzer
+
+		err := d.metrics.runAnalyzer(ctx, ContentTypeText, func(ctx context.Context) (err error) {
+			switch {
+			case input.EmailMode:
+				analysis, err = analyzer.analyzeEmail(ctx, text, overrides)
+			case input.AcademicMode:
+				analysis, err = analyzer.analyzeAcademic(ctx, text, overrides)
+			default:
+				analysis, err = analyzer.analyze(ctx, text, overrides)
+			}
+			return err
+		})
+
+		switch {
+		case err != nil && panicStack(err) == nil:
+			// The analyzer only returns an error when ctx is cancelled
+			return nil, err
+		case err != nil:
+			recordDetectorError(d.logger, detectorErrors, "humanmark", ContentTypeText, err)
+		default:
+			analyzed = true
+			scores = append(scores, analysis.AIScore)
+			detectors = append(detectors, "humanmark")
+
+			d.logger.Debug("humanmark analysis complete",
+				"ai_score", analysis.AIScore,
+				"sentence_variance", analysis.Signals.SentenceVariance,
+				"vocabulary_richness", analysis.Signals.VocabularyRichness,
+				"bigram_diversity", analysis.Signals.BigramDiversity,
+				"sentence_types", analysis.Signals.SentenceTypeDistribution,
+				"academic_register", analysis.Signals.AcademicRegister,
+				"ai_phrases_detected", len(analysis.DetectedAIPhrases),
+				"word_count", analysis.Stats.WordCount,
+				"temporal_note", analysis.TemporalConfidenceNote,
+			)
+
+			if analysis.Watermark != nil {
+				d.logger.Debug("humanmark watermark scan complete",
+					"p_value", analysis.Watermark.PValue,
+					"flagged", analysis.Watermark.Flagged,
+					"words", analysis.Watermark.Words,
+				)
+			}
 
-	// Try Hive API
-	if d.config.HiveAPIKey != "" {
-		score, err := d.detectWithHive(ctx, text)
-		if err != nil {
-			d.logger.Warn("hive detection failed", "error", err)
-		} else {
-			scores = append(scores, score)
-			detectors = append(detectors, "hive")
+			if analysis.Email != nil {
+				d.logger.Debug("humanmark email analysis complete",
+					"email_score", analysis.Email.EmailSpecificScore,
+					"urgent_phrases", len(analysis.Email.UrgentPhrases),
+					"generic_salutation", analysis.Email.GenericSalutation,
+					"has_reply_chain", analysis.Email.HasReplyChain,
+				)
+			}
 		}
 	}
 
-	// Try GPTZero API
-	if d.config.GPTZeroAPIKey != "" {
-		score, err := d.detectWithGPTZero(ctx, text)
-		if err != nil {
-			d.logger.Warn("gptzero detection failed", "error", err)
-		} else {
-			scores = append(scores, score)
-			detectors = append(detectors, "gptzero")
-		}
+	if analysis.Email != nil {
+		// Providers see the same body our analyzer scored
+		text = analysis.Email.Body
+	} else if input.EmailMode {
+		// Providers still only see the body
+		_, message := parseEmailHeaders(text)
+		text, _ = stripReplyChain(message)
 	}
 
-	// Try OpenAI-based detection
-	if d.config.OpenAIAPIKey != "" {
-		score, err := d.detectWithOpenAI(ctx, text)
-		if err != nil {
-			d.logger.Warn("openai detection failed", "error", err)
-		} else {
-			scores = append(scores, score)
-			detectors = append(detectors, "openai")
+	// Our own sentence scores, over the same text the providers see, so
+	// GPTZero's can be compared with them
+	var outputs []DetectorOutput
+	if analyzed {
+		if sentences := d.analyzer.scoreSentences(text, analysis.AIScore, overrides); len(sentences) > 0 {
+			outputs = append(outputs, DetectorOutput{Name: "humanmark", Sentences: sentences})
 		}
 	}
 
-	// Aggregate scores with weighted average
-	// Our algorithm has slightly higher weight since it's always available
-	aiScore := d.aggregateScoresWeighted(scores, detectors)
-
-	// Determine verdict
-	// AI score > 0.5 means likely AI-generated
-	human := aiScore < 0.5
-	confidence := abs(aiScore-0.5) * 2 // Convert to 0-1 confidence scale
-
-	return &DetectionResult{
-		Human:       human,
-		Confidence:  confidence,
-		AIScore:     aiScore,
-		ContentType: ContentTypeText,
-		Detectors:   detectors,
-	}, nil
-}
+	// ==========================================================================
+	// SECONDARY: External APIs (optional, for higher accuracy)
+	// These are weighted together with our algorithm
+	// ==========================================================================
 
-// aggregateScoresWeighted combines scores with detector-specific weights.
-func (d *textDetector) aggregateScoresWeighted(scores []float64, detectors []string) float64 {
-	if len(scores) == 0 {
-		return 0.5
-	}
+	// Try each configured provider; one failing doesn't fail the request
+	var skipped []SkippedProvider
 
-	// Detector weights (based on reliability)
-	weights := map[string]float64{
-		"humanmark": 1.0, // Our algorithm - always runs
-		"hive":      1.2, // External API - trained on large dataset
-		"gptzero":   1.1, // External API - specialized for GPT
-		"openai":    0.9, // External API - using LLM to detect
+	for _, p := range textProviders {
+		if !selected[p.name] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var out textProviderResult
+		_, err := d.metrics.runProvider(ctx, ContentTypeText, p.name, d.config.ProviderTimeouts[p.name], func(ctx context.Context) (score float64, err error) {
+			out, err = p.detect(d, ctx, text)
+			return out.AIScore, err
+		})
+		if errors.Is(err, ErrProviderSkipped) {
+			d.logger.Info("provider skipped", "detector", p.name, "content_type", ContentTypeText, "reason", err)
+			skipped = append(skipped, SkippedProvider{Name: p.name, Reason: err.Error()})
+			continue
+		}
+		if err != nil {
+			recordDetectorError(d.logger, detectorErrors, p.name, ContentTypeText, err)
+			continue
+		}
+		scores = append(scores, out.AIScore)
+		detectors = append(detectors, p.name)
+		if len(out.Sentences) > 0 {
+			outputs = append(outputs, DetectorOutput{Name: p.name, Sentences: out.Sentences, Paragraphs: out.Paragraphs})
+		}
 	}
 
-	totalWeight := 0.0
-	weightedSum := 0.0
-
-	for i, score := range scores {
-		w := 1.0
-		if i < len(detectors) {
-			if detectorWeight, ok := weights[detectors[i]]; ok {
-				w = detectorWeight
-			}
-		}
-		weightedSum += score * w
-		totalWeight += w
+	if len(scores) == 0 {
+		return nil, allDetectorsFailed(detectorErrors)
 	}
 
-	if totalWeight == 0 {
-		return 0.5
+	// Combine scores using the configured strategy
+	// Text has no embedded provenance, so there is never conclusive evidence
+	result := buildResult(d.config, ContentTypeText, scores, detectors, textDetectorWeights, EvidenceNone)
+	result.AIPhrases = analysis.DetectedAIPhrases
+	result.Watermark = analysis.Watermark
+	result.DetectorOutputs = outputs
+	result.SentenceAgreement = sentenceAgreements(outputs)
+	result.SkippedProviders = skipped
+	if len(detectorErrors) > 0 {
+		result.DetectorErrors = errorMessages(detectorErrors)
 	}
 
-	return weightedSum / totalWeight
+	return result, nil
 }
 
 // detectWithHive calls the Hive AI API for text detection.
 func (d *textDetector) detectWithHive(ctx context.Context, text string) (float64, error) {
 	body, _ := json.Marshal(map[string]string{"text_data": text})
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.thehive.ai/api/v2/task/sync", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", providerEndpoint(d.config, "hive", hivePath), bytes.NewReader(body))
 	if err != nil {
 		return 0, err
 	}
@@ -177,12 +229,12 @@ func (d *textDetector) detectWithHive(ctx context.Context, text string) (float64
 
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return 0, providerUnreachable("hive", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("hive API returned status %d", resp.StatusCode)
+		return 0, providerStatusError("hive", resp.StatusCode, nil)
 	}
 
 	var result struct {
@@ -204,45 +256,6 @@ func (d *textDetector) detectWithHive(ctx context.Context, text string) (float64
 	return 0, errors.New("no result from Hive API")
 }
 
-// detectWithGPTZero calls the GPTZero API for text detection.
-func (d *textDetector) detectWithGPTZero(ctx context.Context, text string) (float64, error) {
-	body, _ := json.Marshal(map[string]string{"document": text})
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.gptzero.me/v2/predict/text", bytes.NewReader(body))
-	if err != nil {
-		return 0, err
-	}
-
-	req.Header.Set("x-api-key", d.config.GPTZeroAPIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := d.httpClient.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("gptzero API returned status %d", resp.StatusCode)
-	}
-
-	var result struct {
-		Documents []struct {
-			CompletelyGeneratedProb float64 `json:"completely_generated_prob"`
-		} `json:"documents"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, err
-	}
-
-	if len(result.Documents) > 0 {
-		return result.Documents[0].CompletelyGeneratedProb, nil
-	}
-
-	return 0, errors.New("no result from GPTZero API")
-}
-
 // detectWithOpenAI uses OpenAI to analyze text for AI characteristics.
 func (d *textDetector) detectWithOpenAI(ctx context.Context, text string) (float64, error) {
 	// Truncate text if too long
@@ -278,7 +291,7 @@ ai_probability should be:
 
 	body, _ := json.Marshal(requestBody)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", providerEndpoint(d.config, "openai", openaiPath), bytes.NewReader(body))
 	if err != nil {
 		return 0, err
 	}
@@ -288,12 +301,12 @@ ai_probability should be:
 
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return 0, providerUnreachable("openai", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("openai API returned status %d", resp.StatusCode)
+		return 0, providerStatusError("openai", resp.StatusCode, nil)
 	}
 
 	var result struct {
@@ -381,43 +394,13 @@ func (d *textDetector) mockDetection(text string) float64 {
 
 // fetchTextFromURL fetches text content from a URL.
 func (d *textDetector) fetchTextFromURL(ctx context.Context, url string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := d.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch URL: status %d", resp.StatusCode)
-	}
-
 	// Limit response size
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024)) // 1MB max
+	result, err := d.fetcher.Fetch(ctx, url, 1024*1024) // 1MB max
 	if err != nil {
 		return "", err
 	}
 
-	return string(body), nil
-}
-
-// aggregateScores combines multiple detector scores.
-// Currently uses simple average; could be weighted based on detector accuracy.
-func (d *textDetector) aggregateScores(scores []float64) float64 {
-	if len(scores) == 0 {
-		return 0.5 // Neutral if no scores
-	}
-
-	sum := 0.0
-	for _, s := range scores {
-		sum += s
-	}
-
-	return sum / float64(len(scores))
+	return string(result.Data), nil
 }
 
 // abs returns the absolute value of a float64.
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// Signal Explanations
+// =============================================================================
+//
+// A signal score on its own doesn't tell a user much: SentenceVariance 0.8
+// only means "AI-like" to someone who knows how it's computed. ExplainSignal
+// turns a score back into a sentence about the text, quoting the measure
+// behind it where the score maps back to one (the coefficient of variation
+// of sentence lengths, contractions per 100 words, and so on).
+//
+// Explanations are templates, not generated text, so the same score always
+// reads the same way.
+//
+// =============================================================================
+
+// Signal names, as used in TextAnalysisResult.SignalExplanations.
+const (
+	SignalSentenceVariance   = "sentence_variance"
+	SignalVocabularyRichness = "vocabulary_richness"
+	SignalBigramDiversity    = "bigram_diversity"
+	SignalBurstiness         = "burstiness"
+	SignalPunctuationVariety = "punctuation_variety"
+	SignalAIPhrases          = "ai_phrases"
+	SignalWordLengthVariance = "word_length_variance"
+	SignalContractions       = "contractions"
+	SignalRepetition         = "repetition"
+	SignalEmbeddedData       = "embedded_data"
+	SignalSentenceTypes      = "sentence_types"
+	SignalAcademicRegister   = "academic_register"
+)
+
+// Score bands for explanations. Scores in between are described as mixed.
+const (
+	explainAILike    = 0.65
+	explainHumanLike = 0.35
+)
+
+// signalExplainers maps each signal name to its explanation template.
+var signalExplainers = map[string]func(score float64) string{
+	SignalSentenceVariance: func(score float64) string {
+		// analyzeSentenceVariance: score = 1 - min(cv/0.8, 1)
+		cv := (1 - score) * 0.8
+		return explainBands(score,
+			fmt.Sprintf("Sentence lengths are very uniform (coefficient of variation: %.2f), which is characteristic of AI-generated text. Human writing typically has a coefficient of variation above 0.5.", cv),
+			fmt.Sprintf("Sentence lengths vary somewhat (coefficient of variation: %.2f). AI-generated text is typically below 0.3 and human writing above 0.5.", cv),
+			fmt.Sprintf("Sentence lengths vary widely (coefficient of variation: %s), as is typical of human writing. AI-generated text is usually below 0.3.", measure("%.2f", cv, score == 0, "0.80 or more")),
+		)
+	},
+	SignalVocabularyRichness: func(score float64) string {
+		return explainBands(score,
+			"The vocabulary is repetitive and sticks to common words, which is characteristic of AI-generated text. Human writing typically uses more distinct and unusual words.",
+			"The vocabulary is moderately varied, with some uncommon words.",
+			"The vocabulary is varied, with many distinct and uncommon words, as is typical of human writing.",
+		)
+	},
+	SignalBigramDiversity: func(score float64) string {
+		// analyzeBigramDiversity: score = (0.97 - ratio) / 0.17
+		ratio := 0.97 - score*0.17
+		return explainBands(score,
+			fmt.Sprintf("The same word pairs recur often (%.0f%% of word pairs are distinct per 100 words), which is characteristic of formulaic AI-generated text. Human writing rarely repeats a word pair that close together.", ratio*100),
+			fmt.Sprintf("Some word pairs recur (%.0f%% of word pairs are distinct per 100 words).", ratio*100),
+			fmt.Sprintf("Word pairs rarely recur (%s of word pairs are distinct per 100 words), as is typical of human writing.", measure("%.0f%%", ratio*100, score == 0, "97% or more")),
+		)
+	},
+	SignalBurstiness: func(score float64) string {
+		// analyzeBurstiness: score = 1 - min(burstiness/1.5, 1)
+		b := (1 - score) * 1.5
+		return explainBands(score,
+			fmt.Sprintf("Repeated words are spread evenly through the text (burstiness: %.2f), which is characteristic of AI-generated text. Human writing clusters related words together, with burstiness above 1.0.", b),
+			fmt.Sprintf("Repeated words cluster somewhat (burstiness: %.2f).", b),
+			fmt.Sprintf("Repeated words cluster together (burstiness: %s), as is typical of human writing.", measure("%.2f", b, score == 0, "1.50 or more")),
+		)
+	},
+	SignalPunctuationVariety: func(score float64) string {
+		return explainBands(score,
+			"The punctuation is mostly periods and commas, which is characteristic of AI-generated text. Human writing typically mixes in question marks, exclamation marks, dashes, colons and parentheses.",
+			"The punctuation is moderately varied.",
+			"The punctuation is varied, with question marks, dashes, parentheses and the like, as is typical of human writing.",
+		)
+	},
+	SignalAIPhrases: func(score float64) string {
+		if score == 0 {
+			return "No AI phrases were found, such as \"it's important to note\" or \"delve into\"."
+		}
+		return explainBands(score,
+			"The text uses many AI phrases, such as \"it's important to note\" or \"delve into\", for its length. These stock phrases are characteristic of AI-generated text.",
+			"The text uses a few AI phrases, such as \"it's important to note\" or \"delve into\". People use them too, but less often.",
+			"The text uses an AI phrase or two, such as \"it's important to note\" or \"delve into\", but few for its length.",
+		)
+	},
+	SignalWordLengthVariance: func(score float64) string {
+		// analyzeWordLengthVariance: score = 1 - min(cv/0.6, 1)
+		cv := (1 - score) * 0.6
+		return explainBands(score,
+			fmt.Sprintf("Word lengths are uniform (coefficient of variation: %.2f), which is characteristic of AI-generated text. Human writing mixes short and long words more.", cv),
+			fmt.Sprintf("Word lengths vary somewhat (coefficient of variation: %.2f).", cv),
+			fmt.Sprintf("Word lengths vary widely (coefficient of variation: %s), as is typical of human writing.", measure("%.2f", cv, score == 0, "0.60 or more")),
+		)
+	},
+	SignalContractions: func(score float64) string {
+		// analyzeContractions: score = 1 - min(rate/3, 1)
+		rate := (1 - score) * 3
+		return explainBands(score,
+			fmt.Sprintf("The text uses few contractions (%.1f per 100 words), which is characteristic of formal AI-generated text. Casual human writing typically uses 2-5 contractions per 100 words, such as \"don't\" or \"I'm\".", rate),
+			fmt.Sprintf("The text uses some contractions (%.1f per 100 words). Casual human writing typically uses 2-5 per 100 words.", rate),
+			fmt.Sprintf("The text uses contractions freely (%s per 100 words), as is typical of casual human writing.", measure("%.1f", rate, score == 0, "3 or more")),
+		)
+	},
+	SignalRepetition: func(score float64) string {
+		// analyzeRepetition: score = min(rate*2, 1)
+		rate := score / 2
+		return explainBands(score,
+			fmt.Sprintf("Many sentences repeat the same opening words (%s of sentences), which is characteristic of AI-generated text.", measure("%.0f%%", rate*100, score == 1, "half or more")),
+			fmt.Sprintf("Some sentences repeat the same opening words (%.0f%% of sentences).", rate*100),
+			fmt.Sprintf("Sentences rarely repeat the same opening words (%.0f%% of sentences), as is typical of human writing.", rate*100),
+		)
+	},
+	SignalEmbeddedData: func(score float64) string {
+		return explainBands(score,
+			"The embedded JSON/YAML examples are deeply nested, precisely indented and use long descriptive keys, which is characteristic of AI-generated examples.",
+			"The embedded JSON/YAML examples are somewhat regular.",
+			"The embedded JSON/YAML examples are shallow, loosely indented and use short keys, as is typical of hand-written examples.",
+		)
+	},
+	SignalSentenceTypes: func(score float64) string {
+		// analyzeSentenceTypeDistribution: score = 1 - min(entropy, 1)
+		entropy := 1 - score
+		return explainBands(score,
+			fmt.Sprintf("Almost all sentence types are plain statements (variety: %.2f bits), which is characteristic of AI-generated text. Human writing mixes in questions, exclamations and commands.", entropy),
+			fmt.Sprintf("The sentence types are mostly statements, with some questions, exclamations or commands (variety: %.2f bits).", entropy),
+			fmt.Sprintf("The sentence types mix statements with questions, exclamations and commands (variety: %.2f bits), as is typical of human writing.", entropy),
+		)
+	},
+	SignalAcademicRegister: func(score float64) string {
+		return explainBands(score,
+			"The academic register is generic formality without citations, hedges or field jargon, which is characteristic of AI-generated academic text.",
+			"The academic register balances generic formal phrases against citations, hedges and jargon.",
+			"The academic register follows academic conventions, with citations, hedges and field jargon, as is typical of human academic writing.",
+		)
+	},
+}
+
+// ExplainSignal describes what signalName's score says about the text,
+// e.g. ExplainSignal(SignalSentenceVariance, 0.8). It returns "" for
+// unknown signal names.
+func (a *TextAnalyzer) ExplainSignal(signalName string, score float64) string {
+	explain, ok := signalExplainers[signalName]
+	if !ok {
+		return ""
+	}
+	return explain(math.Max(0, math.Min(1, score)))
+}
+
+// WithSignalExplanations sets whether Analyze fills in
+// TextAnalysisResult.SignalExplanations (default: true). Turn it off to keep
+// results small when only the scores are needed.
+func WithSignalExplanations(enabled bool) TextAnalyzerOption {
+	return func(a *TextAnalyzer) {
+		a.signalExplanations = enabled
+	}
+}
+
+// explainSignals explains each signal the analysis computed. The embedded
+// data signal is only explained when the text has embedded data; the
+// academic register is added by analyzeAcademic.
+func (a *TextAnalyzer) explainSignals(result TextAnalysisResult) map[string]string {
+	s := result.Signals
+	scores := map[string]float64{
+		SignalSentenceVariance:   s.SentenceVariance,
+		SignalVocabularyRichness: s.VocabularyRichness,
+		SignalBigramDiversity:    s.BigramDiversity,
+		SignalBurstiness:         s.Burstiness,
+		SignalPunctuationVariety: s.PunctuationVariety,
+		SignalAIPhrases:          s.AIPhraseScore,
+		SignalWordLengthVariance: s.WordLengthVariance,
+		SignalContractions:       s.ContractionsUsage,
+		SignalRepetition:         s.RepetitionScore,
+		SignalSentenceTypes:      s.SentenceTypeDistribution,
+	}
+	if result.Stats.EmbeddedBlocks > 0 {
+		scores[SignalEmbeddedData] = s.EmbeddedDataScore
+	}
+
+	explanations := make(map[string]string, len(scores))
+	for name, score := range scores {
+		explanations[name] = a.ExplainSignal(name, score)
+	}
+	return explanations
+}
+
+// explainBands picks the explanation for score's band.
+func explainBands(score float64, aiLike, mixed, humanLike string) string {
+	switch {
+	case score >= explainAILike:
+		return aiLike
+	case score <= explainHumanLike:
+		return humanLike
+	default:
+		return mixed
+	}
+}
+
+// measure formats a measure recovered from a score. Scores saturate, e.g.
+// any sentence length variation of 0.8 or more scores 0, so a saturated
+// score only gives a bound.
+func measure(format string, value float64, saturated bool, bound string) string {
+	if saturated {
+		return bound
+	}
+	return fmt.Sprintf(format, value)
+}
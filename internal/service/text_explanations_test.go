@@ -0,0 +1,101 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// signalMentions is how each signal's explanation refers to it.
+var signalMentions = map[string]string{
+	SignalSentenceVariance:   "sentence length",
+	SignalVocabularyRichness: "vocabulary",
+	SignalBigramDiversity:    "word pair",
+	SignalBurstiness:         "burstiness",
+	SignalPunctuationVariety: "punctuation",
+	SignalAIPhrases:          "AI phrase",
+	SignalWordLengthVariance: "word length",
+	SignalContractions:       "contractions",
+	SignalRepetition:         "repeat",
+	SignalEmbeddedData:       "JSON/YAML",
+	SignalSentenceTypes:      "sentence types",
+	SignalAcademicRegister:   "academic register",
+}
+
+// TestExplainSignal tests that every signal has an explanation at every
+// score that names what it measured.
+func TestExplainSignal(t *testing.T) {
+	analyzer := NewTextAnalyzer()
+
+	if len(signalMentions) != len(signalExplainers) {
+		t.Fatalf("signalMentions covers %d signals, signalExplainers %d", len(signalMentions), len(signalExplainers))
+	}
+
+	for name, mention := range signalMentions {
+		t.Run(name, func(t *testing.T) {
+			for _, score := range []float64{0, 0.2, 0.5, 0.8, 1} {
+				got := analyzer.ExplainSignal(name, score)
+				if got == "" {
+					t.Fatalf("ExplainSignal(%q, %v) is empty", name, score)
+				}
+				if !strings.Contains(strings.ToLower(got), strings.ToLower(mention)) {
+					t.Errorf("ExplainSignal(%q, %v) = %q, want a mention of %q", name, score, got, mention)
+				}
+			}
+		})
+	}
+
+	t.Run("quotes the measure", func(t *testing.T) {
+		got := analyzer.ExplainSignal(SignalSentenceVariance, 0.8)
+		want := "Sentence lengths are very uniform (coefficient of variation: 0.16), which is characteristic of AI-generated text. Human writing typically has a coefficient of variation above 0.5."
+		if got != want {
+			t.Errorf("ExplainSignal() = %q, want %q", got, want)
+		}
+
+		if got := analyzer.ExplainSignal(SignalContractions, 0); !strings.Contains(got, "3 or more per 100 words") {
+			t.Errorf("saturated score: ExplainSignal() = %q, want a lower bound", got)
+		}
+	})
+
+	t.Run("unknown signal", func(t *testing.T) {
+		if got := analyzer.ExplainSignal("no_such_signal", 0.5); got != "" {
+			t.Errorf("ExplainSignal() = %q, want empty", got)
+		}
+	})
+}
+
+// TestSignalExplanations tests that analysis results carry explanations
+// unless they're turned off.
+func TestSignalExplanations(t *testing.T) {
+	const text = `I don't think we're done yet. There's still the final review. ` +
+		`It's been a long week and I can't say I'm sorry it's over. ` +
+		`We'll pick it up Monday; that's the plan, isn't it?`
+
+	result := NewTextAnalyzer().Analyze(text)
+	for _, name := range []string{
+		SignalSentenceVariance, SignalVocabularyRichness, SignalBigramDiversity, SignalBurstiness,
+		SignalPunctuationVariety, SignalAIPhrases, SignalWordLengthVariance, SignalContractions,
+		SignalRepetition, SignalSentenceTypes,
+	} {
+		if result.SignalExplanations[name] == "" {
+			t.Errorf("missing explanation for %s", name)
+		}
+	}
+	for _, name := range []string{SignalEmbeddedData, SignalAcademicRegister} {
+		if _, ok := result.SignalExplanations[name]; ok {
+			t.Errorf("unexpected explanation for %s", name)
+		}
+	}
+
+	academic := NewTextAnalyzer().AnalyzeAcademic(text)
+	if academic.SignalExplanations[SignalAcademicRegister] == "" {
+		t.Error("academic mode: missing explanation for academic_register")
+	}
+
+	disabled := NewTextAnalyzer(WithSignalExplanations(false))
+	if got := disabled.Analyze(text).SignalExplanations; got != nil {
+		t.Errorf("WithSignalExplanations(false): SignalExplanations = %v, want nil", got)
+	}
+	if got := disabled.AnalyzeAcademic(text).SignalExplanations; got != nil {
+		t.Errorf("WithSignalExplanations(false): academic SignalExplanations = %v, want nil", got)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"html"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// =============================================================================
+// Text Normalization
+// =============================================================================
+//
+// The same words can arrive as very different bytes. Text copied out of a
+// word processor has curly quotes, so "don’t" splits into "don" and "t" and
+// stops counting as a contraction. Text scraped from a web page still has
+// &amp; and &rsquo; in it. Full-width letters, ligatures and invisible
+// zero-width characters break words apart or glue them together.
+//
+// Before computing any signal the text analyzer rewrites these to plain
+// equivalents:
+//
+//  1. HTML entities are decoded (first, so &rsquo; is straightened below)
+//  2. Unicode NFKC normalization folds compatibility characters
+//  3. Curly quotes become straight quotes
+//  4. Em dashes become hyphens
+//  5. Zero-width characters are removed
+//
+// Every step is on by default and can be turned off with WithNormalization.
+//
+// =============================================================================
+
+// NormalizationOptions selects the steps of text normalization.
+type NormalizationOptions struct {
+	// DecodeHTMLEntities decodes entities such as &amp; and &#8217;
+	DecodeHTMLEntities bool
+
+	// NFKC applies Unicode compatibility normalization (ligatures,
+	// full-width letters, non-breaking spaces)
+	NFKC bool
+
+	// StraightenQuotes turns curly quotes and primes into ' and "
+	StraightenQuotes bool
+
+	// ReplaceEmDashes turns em dashes into hyphens
+	ReplaceEmDashes bool
+
+	// RemoveZeroWidth removes zero-width spaces, joiners and soft hyphens
+	RemoveZeroWidth bool
+}
+
+// DefaultNormalization returns NormalizationOptions with every step enabled.
+func DefaultNormalization() NormalizationOptions {
+	return NormalizationOptions{
+		DecodeHTMLEntities: true,
+		NFKC:               true,
+		StraightenQuotes:   true,
+		ReplaceEmDashes:    true,
+		RemoveZeroWidth:    true,
+	}
+}
+
+// TextAnalyzerOption configures a TextAnalyzer.
+type TextAnalyzerOption func(*TextAnalyzer)
+
+// WithNormalization sets the normalization steps applied before analysis.
+func WithNormalization(opts NormalizationOptions) TextAnalyzerOption {
+	return func(a *TextAnalyzer) {
+		a.normalization = opts
+	}
+}
+
+var (
+	// quoteReplacer straightens single and double quotes and primes
+	quoteReplacer = strings.NewReplacer(
+		"\u2018", "'", "\u2019", "'", "\u201A", "'", "\u201B", "'", "\u2032", "'",
+		"\u201C", `"`, "\u201D", `"`, "\u201E", `"`, "\u201F", `"`, "\u2033", `"`,
+	)
+
+	// emDashReplacer also catches the horizontal bar, used the same way
+	emDashReplacer = strings.NewReplacer("\u2014", "-", "\u2015", "-")
+
+	// zeroWidthReplacer removes zero-width spaces and joiners, word
+	// joiners, byte order marks and soft hyphens
+	zeroWidthReplacer = strings.NewReplacer(
+		"\u200B", "", "\u200C", "", "\u200D", "", "\u2060", "", "\uFEFF", "", "\u00AD", "",
+	)
+)
+
+// normalizeText applies the steps selected by opts to text, in the order
+// listed in NormalizationOptions.
+func normalizeText(text string, opts NormalizationOptions) string {
+	if opts.DecodeHTMLEntities && strings.Contains(text, "&") {
+		text = html.UnescapeString(text)
+	}
+	if opts.NFKC {
+		text = norm.NFKC.String(text)
+	}
+	if opts.StraightenQuotes {
+		text = quoteReplacer.Replace(text)
+	}
+	if opts.ReplaceEmDashes {
+		text = emDashReplacer.Replace(text)
+	}
+	if opts.RemoveZeroWidth {
+		text = zeroWidthReplacer.Replace(text)
+	}
+	return text
+}
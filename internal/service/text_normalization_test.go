@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+)
+
+// TestNormalizeText tests each normalization step on its own.
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		opts NormalizationOptions
+		in   string
+		want string
+	}{
+		{"curly quotes", NormalizationOptions{StraightenQuotes: true}, "“I don’t know,” she said", `"I don't know," she said`},
+		{"em dash", NormalizationOptions{ReplaceEmDashes: true}, "wait—what", "wait-what"},
+		{"zero width", NormalizationOptions{RemoveZeroWidth: true}, "hu\u200Bman\u00ADmark\uFEFF", "humanmark"},
+		{"html entities", NormalizationOptions{DecodeHTMLEntities: true}, "Tom &amp; Jerry &lt;3 &#8220;hi&#8221;", "Tom & Jerry <3 “hi”"},
+		{"nfkc", NormalizationOptions{NFKC: true}, "ﬁnd ＡＩ café", "find AI café"},
+		{"entities decoded before quotes", DefaultNormalization(), "it&rsquo;s &mdash; fine", "it's - fine"},
+		{"all disabled", NormalizationOptions{}, "don’t &amp;—", "don’t &amp;—"},
+		{"plain text unchanged", DefaultNormalization(), "Nothing to do here.", "Nothing to do here."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeText(tt.in, tt.opts); got != tt.want {
+				t.Errorf("normalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTextAnalyzerNormalization tests that typographic variants of a text
+// score the same as its plain form.
+func TestTextAnalyzerNormalization(t *testing.T) {
+	const plain = `I don't think we're done yet - there's still the "final" review. ` +
+		`It's been a long week and I can't say I'm sorry it's over. ` +
+		`We'll pick it up Monday; that's the plan, isn't it? ` +
+		`Honestly, I wasn't sure we'd make it this far.`
+
+	variants := map[string]string{
+		"curly quotes": `I don’t think we’re done yet - there’s still the “final” review. ` +
+			`It’s been a long week and I can’t say I’m sorry it’s over. ` +
+			`We’ll pick it up Monday; that’s the plan, isn’t it? ` +
+			`Honestly, I wasn’t sure we’d make it this far.`,
+		"em dashes": `I don't think we're done yet — there's still the "final" review. ` +
+			`It's been a long week and I can't say I'm sorry it's over. ` +
+			`We'll pick it up Monday; that's the plan, isn't it? ` +
+			`Honestly, I wasn't sure we'd make it this far.`,
+		"html entities": `I don&#39;t think we&rsquo;re done yet &mdash; there&apos;s still the &quot;final&quot; review. ` +
+			`It&#8217;s been a long week and I can&#39;t say I&#39;m sorry it&#39;s over. ` +
+			`We&#39;ll pick it up Monday; that&#39;s the plan, isn&#39;t it? ` +
+			`Honestly, I wasn&#39;t sure we&#39;d make it this far.`,
+	}
+
+	analyzer := NewTextAnalyzer()
+	want := analyzer.Analyze(plain)
+	if want.Stats.NormalizationApplied {
+		t.Error("plain text: NormalizationApplied = true, want false")
+	}
+
+	for name, text := range variants {
+		t.Run(name, func(t *testing.T) {
+			got := analyzer.Analyze(text)
+			if !got.Stats.NormalizationApplied {
+				t.Error("NormalizationApplied = false, want true")
+			}
+			if got.Signals != want.Signals {
+				t.Errorf("Signals = %+v, want %+v", got.Signals, want.Signals)
+			}
+			if got.AIScore != want.AIScore {
+				t.Errorf("AIScore = %v, want %v", got.AIScore, want.AIScore)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		raw := NewTextAnalyzer(WithNormalization(NormalizationOptions{}))
+		got := raw.Analyze(variants["curly quotes"])
+		if got.Stats.NormalizationApplied {
+			t.Error("NormalizationApplied = true, want false")
+		}
+		// Curly apostrophes split contractions, so fewer are found
+		if got.Signals.ContractionsUsage <= want.Signals.ContractionsUsage {
+			t.Errorf("ContractionsUsage = %v, want more than %v", got.Signals.ContractionsUsage, want.Signals.ContractionsUsage)
+		}
+	})
+}
@@ -0,0 +1,439 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// =============================================================================
+// External Text Providers
+// =============================================================================
+//
+// Like the image providers, each text provider maps its response to our 0-1
+// AI score. Some also score individual sentences; those are passed through
+// as DetectorOutputs so clients can see which parts of a text were flagged,
+// next to our own analyzer's ("humanmark"). GPTZero's are also compared
+// with the others' (SentenceAgreement): a sentence flagged by two
+// independent detectors is stronger evidence than one flagged by either.
+//
+// Providers that refuse the input itself (usually because it's below their
+// minimum length) return ErrProviderSkipped. That's reported on the result
+// rather than logged as a failure.
+//
+// To add a provider: write a detectWith* method and register it in
+// textProviders with a default weight.
+//
+// =============================================================================
+
+// Minimum input sizes documented by the providers. Shorter text is skipped
+// without spending a request.
+const (
+	originalityMinWords = 50
+	winstonMinChars     = 300
+)
+
+// textProviderResult is one text provider's output.
+type textProviderResult struct {
+	// AIScore is the provider's AI probability (0.0-1.0)
+	AIScore float64
+
+	// Sentences are per-sentence scores, when the provider returns them
+	Sentences []SentenceScore
+
+	// Paragraphs are per-paragraph scores over Sentences, when the provider
+	// returns them
+	Paragraphs []ParagraphScore
+}
+
+// textProvider is an external text detection backend.
+type textProvider struct {
+	// name is reported in DetectionResult.Detectors
+	name string
+
+	// weight is the default reliability weight for aggregation
+	weight float64
+
+	// enabled reports whether the provider is configured
+	enabled func(config DetectorConfig) bool
+
+	// detect scores the text
+	detect func(d *textDetector, ctx context.Context, text string) (textProviderResult, error)
+}
+
+// textProviders is the registry of external text backends, in call order.
+var textProviders = []textProvider{
+	{
+		name:    "hive",
+		weight:  1.2, // Trained on large dataset
+		enabled: func(c DetectorConfig) bool { return c.HiveAPIKey != "" },
+		detect:  scoreOnly((*textDetector).detectWithHive),
+	},
+	{
+		name:    "gptzero",
+		weight:  1.1, // Specialized for GPT
+		enabled: func(c DetectorConfig) bool { return c.GPTZeroAPIKey != "" },
+		detect:  (*textDetector).detectWithGPTZero,
+	},
+	{
+		name:    "openai",
+		weight:  0.9, // Using an LLM to detect
+		enabled: func(c DetectorConfig) bool { return c.OpenAIAPIKey != "" },
+		detect:  scoreOnly((*textDetector).detectWithOpenAI),
+	},
+	{
+		name:    "originality",
+		weight:  1.2, // Tracks newer models closely
+		enabled: func(c DetectorConfig) bool { return c.OriginalityAPIKey != "" },
+		detect:  (*textDetector).detectWithOriginality,
+	},
+	{
+		name:    "winston",
+		weight:  1.0, // Good sentence-level output, coarser overall score
+		enabled: func(c DetectorConfig) bool { return c.WinstonAPIKey != "" },
+		detect:  (*textDetector).detectWithWinston,
+	},
+}
+
+// textDetectorWeights are the reliability weights for text providers.
+var textDetectorWeights = func() map[string]float64 {
+	weights := map[string]float64{
+		"humanmark": 1.0, // Our algorithm - always runs
+	}
+	for _, p := range textProviders {
+		weights[p.name] = p.weight
+	}
+	return weights
+}()
+
+// textDetectorsAvailable maps each text detector to whether it is configured.
+func textDetectorsAvailable(config DetectorConfig) map[string]bool {
+	available := map[string]bool{"humanmark": true}
+	for _, p := range textProviders {
+		available[p.name] = p.enabled(config)
+	}
+	return available
+}
+
+// scoreOnly adapts a provider that returns only an overall score.
+func scoreOnly(detect func(d *textDetector, ctx context.Context, text string) (float64, error)) func(*textDetector, context.Context, string) (textProviderResult, error) {
+	return func(d *textDetector, ctx context.Context, text string) (textProviderResult, error) {
+		score, err := detect(d, ctx, text)
+		return textProviderResult{AIScore: score}, err
+	}
+}
+
+// detectWithGPTZero calls GPTZero's text prediction.
+//
+// Score mapping: the document's completely_generated_prob is already a 0-1
+// AI probability, as is each sentence's generated_prob. Each paragraph's
+// completely_generated_prob covers num_sentences sentences from
+// start_sentence_index.
+//
+// A document too short to predict is rejected with HTTP 400 and a "too
+// short" error; that maps to ErrProviderSkipped, while other 400s are
+// failures. 401/403 map to ErrProviderAuth, 402/429 to ErrProviderQuota.
+func (d *textDetector) detectWithGPTZero(ctx context.Context, text string) (textProviderResult, error) {
+	body, _ := json.Marshal(map[string]string{"document": text})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", providerEndpoint(d.config, "gptzero", gptzeroPath), bytes.NewReader(body))
+	if err != nil {
+		return textProviderResult{}, err
+	}
+	req.Header.Set("x-api-key", d.config.GPTZeroAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return textProviderResult{}, providerUnreachable("gptzero", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if msg := providerMessage(respBody); strings.Contains(strings.ToLower(msg), "too short") {
+			return textProviderResult{}, fmt.Errorf("%w: gptzero: document too short: %s", ErrProviderSkipped, msg)
+		}
+		return textProviderResult{}, providerStatusError("gptzero", resp.StatusCode, respBody)
+	}
+	if err := textProviderStatus("gptzero", resp); err != nil {
+		return textProviderResult{}, err
+	}
+
+	var result struct {
+		Documents []struct {
+			CompletelyGeneratedProb float64 `json:"completely_generated_prob"`
+			Sentences               []struct {
+				Sentence      string  `json:"sentence"`
+				GeneratedProb float64 `json:"generated_prob"`
+			} `json:"sentences"`
+			Paragraphs []struct {
+				StartSentenceIndex      int     `json:"start_sentence_index"`
+				NumSentences            int     `json:"num_sentences"`
+				CompletelyGeneratedProb float64 `json:"completely_generated_prob"`
+			} `json:"paragraphs"`
+		} `json:"documents"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return textProviderResult{}, err
+	}
+
+	if len(result.Documents) == 0 {
+		return textProviderResult{}, errors.New("no result from GPTZero API")
+	}
+
+	doc := result.Documents[0]
+	out := textProviderResult{AIScore: clampScore(doc.CompletelyGeneratedProb)}
+	for _, s := range doc.Sentences {
+		out.Sentences = append(out.Sentences, SentenceScore{
+			Text:    s.Sentence,
+			AIScore: clampScore(s.GeneratedProb),
+		})
+	}
+	for _, p := range doc.Paragraphs {
+		out.Paragraphs = append(out.Paragraphs, ParagraphScore{
+			StartSentence: p.StartSentenceIndex,
+			Sentences:     p.NumSentences,
+			AIScore:       clampScore(p.CompletelyGeneratedProb),
+		})
+	}
+
+	return out, nil
+}
+
+// detectWithOriginality calls Originality.ai's AI scan.
+//
+// Score mapping: score.ai is already a 0-1 AI probability. Each block's
+// result.fake is the AI probability for that sentence.
+//
+// Text under 50 words is rejected by the API (HTTP 400/422); that maps to
+// ErrProviderSkipped. 401/403 map to ErrProviderAuth, 402/429 to
+// ErrProviderQuota.
+func (d *textDetector) detectWithOriginality(ctx context.Context, text string) (textProviderResult, error) {
+	if words := len(strings.Fields(text)); words < originalityMinWords {
+		return textProviderResult{}, fmt.Errorf("%w: originality needs at least %d words, got %d", ErrProviderSkipped, originalityMinWords, words)
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"content":        text,
+		"aiModelVersion": "1",
+		"storeScan":      false,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.originalityURL, bytes.NewReader(body))
+	if err != nil {
+		return textProviderResult{}, err
+	}
+	req.Header.Set("X-OAI-API-KEY", d.config.OriginalityAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return textProviderResult{}, providerUnreachable("originality", err)
+	}
+	defer resp.Body.Close()
+
+	if err := textProviderStatus("originality", resp); err != nil {
+		return textProviderResult{}, err
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+		Score   *struct {
+			AI float64 `json:"ai"`
+		} `json:"score"`
+		Blocks []struct {
+			Text   string `json:"text"`
+			Result struct {
+				Fake float64 `json:"fake"`
+			} `json:"result"`
+		} `json:"blocks"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return textProviderResult{}, err
+	}
+
+	if result.Score == nil {
+		return textProviderResult{}, errors.New("no result from Originality.ai API")
+	}
+
+	out := textProviderResult{AIScore: clampScore(result.Score.AI)}
+	for _, b := range result.Blocks {
+		out.Sentences = append(out.Sentences, SentenceScore{
+			Text:    b.Text,
+			AIScore: clampScore(b.Result.Fake),
+		})
+	}
+
+	return out, nil
+}
+
+// detectWithWinston calls Winston AI's content detection.
+//
+// Score mapping: Winston returns a 0-100 *human* score, for the text and
+// for each sentence, so the AI probability is 1 - score/100.
+//
+// Text under 300 characters is rejected by the API (HTTP 400); that maps
+// to ErrProviderSkipped. 401/403 map to ErrProviderAuth, 402/429 to
+// ErrProviderQuota.
+func (d *textDetector) detectWithWinston(ctx context.Context, text string) (textProviderResult, error) {
+	if chars := len([]rune(text)); chars < winstonMinChars {
+		return textProviderResult{}, fmt.Errorf("%w: winston needs at least %d characters, got %d", ErrProviderSkipped, winstonMinChars, chars)
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"text":      text,
+		"sentences": true,
+		"language":  "en",
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.winstonURL, bytes.NewReader(body))
+	if err != nil {
+		return textProviderResult{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.config.WinstonAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return textProviderResult{}, providerUnreachable("winston", err)
+	}
+	defer resp.Body.Close()
+
+	if err := textProviderStatus("winston", resp); err != nil {
+		return textProviderResult{}, err
+	}
+
+	var result struct {
+		Score     *float64 `json:"score"`
+		Sentences []struct {
+			Text  string  `json:"text"`
+			Score float64 `json:"score"`
+		} `json:"sentences"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return textProviderResult{}, err
+	}
+
+	if result.Score == nil {
+		return textProviderResult{}, errors.New("no result from Winston AI API")
+	}
+
+	out := textProviderResult{AIScore: clampScore(1 - *result.Score/100)}
+	for _, s := range result.Sentences {
+		out.Sentences = append(out.Sentences, SentenceScore{
+			Text:    s.Text,
+			AIScore: clampScore(1 - s.Score/100),
+		})
+	}
+
+	return out, nil
+}
+
+// sentenceAgreements compares GPTZero's sentence scores with each other
+// output's, our own analyzer's included. Sentences are matched by their
+// text, ignoring case, spacing and closing punctuation, since detectors
+// split text differently; outputs sharing no sentences with GPTZero's are
+// left out.
+func sentenceAgreements(outputs []DetectorOutput) []SentenceAgreement {
+	var gptzero *DetectorOutput
+	for i := range outputs {
+		if outputs[i].Name == "gptzero" {
+			gptzero = &outputs[i]
+		}
+	}
+	if gptzero == nil {
+		return nil
+	}
+
+	reference := make(map[string]float64, len(gptzero.Sentences))
+	for _, s := range gptzero.Sentences {
+		reference[sentenceKey(s.Text)] = s.AIScore
+	}
+
+	var agreements []SentenceAgreement
+	for _, out := range outputs {
+		if out.Name == gptzero.Name {
+			continue
+		}
+		var matched, agreed int
+		var difference float64
+		for _, s := range out.Sentences {
+			score, ok := reference[sentenceKey(s.Text)]
+			if !ok {
+				continue
+			}
+			matched++
+			if (score >= 0.5) == (s.AIScore >= 0.5) {
+				agreed++
+			}
+			difference += math.Abs(score - s.AIScore)
+		}
+		if matched == 0 {
+			continue
+		}
+		agreements = append(agreements, SentenceAgreement{
+			Detectors:      []string{gptzero.Name, out.Name},
+			Sentences:      matched,
+			Agreement:      float64(agreed) / float64(matched),
+			MeanDifference: difference / float64(matched),
+		})
+	}
+	return agreements
+}
+
+// sentenceKey normalizes a sentence for matching across detectors.
+// splitSentences drops the punctuation ending a sentence, so it's ignored.
+func sentenceKey(sentence string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(sentence), " "))
+	return strings.TrimRight(key, ".!? ")
+}
+
+// textProviderStatus maps a text provider's error status to our provider
+// errors. Input rejections (400/422) mean the text didn't meet the
+// provider's requirements, so the provider is skipped.
+func textProviderStatus(name string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return fmt.Errorf("%w: %s rejected input: %s", ErrProviderSkipped, name, providerMessage(respBody))
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s returned status %d", ErrProviderAuth, name, resp.StatusCode)
+	case http.StatusPaymentRequired, http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s returned status %d", ErrProviderQuota, name, resp.StatusCode)
+	default:
+		return providerStatusError(name, resp.StatusCode, respBody)
+	}
+}
+
+// providerMessage pulls a readable message out of a provider error body.
+func providerMessage(body []byte) string {
+	var msg struct {
+		Error       string `json:"error"`
+		Message     string `json:"message"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &msg); err == nil {
+		for _, s := range []string{msg.Error, msg.Message, msg.Description} {
+			if s != "" {
+				return s
+			}
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
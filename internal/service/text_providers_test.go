@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// longText is long enough for every text provider's minimum.
+var longText = strings.Repeat("The committee reviewed the proposal in detail before the vote. ", 10)
+
+// newTextProviderTestDetector creates a text detector pointed at a recorded-response server.
+func newTextProviderTestDetector(config DetectorConfig, serverURL string) *textDetector {
+	d := NewTextDetector(config, logger.NopLogger()).(*textDetector)
+	d.originalityURL = serverURL
+	d.winstonURL = serverURL
+	return d
+}
+
+// TestOriginality tests Originality.ai response mapping.
+func TestOriginality(t *testing.T) {
+	config := DetectorConfig{OriginalityAPIKey: "oai-key"}
+
+	t.Run("maps score and blocks", func(t *testing.T) {
+		var gotKey string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotKey = r.Header.Get("X-OAI-API-KEY")
+			w.Write([]byte(`{"success":true,"score":{"original":0.08,"ai":0.92},"blocks":[{"text":"First sentence.","result":{"fake":0.95,"real":0.05}},{"text":"Second sentence.","result":{"fake":0.4,"real":0.6}}]}`))
+		}))
+		defer server.Close()
+
+		out, err := newTextProviderTestDetector(config, server.URL).detectWithOriginality(context.Background(), longText)
+		if err != nil {
+			t.Fatalf("detectWithOriginality() error = %v", err)
+		}
+		if out.AIScore != 0.92 {
+			t.Errorf("AIScore = %f, want 0.92", out.AIScore)
+		}
+		if len(out.Sentences) != 2 || out.Sentences[0].Text != "First sentence." || out.Sentences[0].AIScore != 0.95 {
+			t.Errorf("unexpected sentences: %+v", out.Sentences)
+		}
+		if gotKey != "oai-key" {
+			t.Errorf("X-OAI-API-KEY = %q", gotKey)
+		}
+	})
+
+	t.Run("short text is skipped without a request", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		_, err := newTextProviderTestDetector(config, server.URL).detectWithOriginality(context.Background(), "Too short.")
+		if !errors.Is(err, ErrProviderSkipped) {
+			t.Errorf("error = %v, want ErrProviderSkipped", err)
+		}
+		if called {
+			t.Error("provider should not be called for short text")
+		}
+	})
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{"minimum length rejection", http.StatusUnprocessableEntity, `{"error":"Content must contain at least 50 words"}`, ErrProviderSkipped},
+		{"bad key", http.StatusUnauthorized, `{"error":"Unauthorized"}`, ErrProviderAuth},
+		{"out of credits", http.StatusPaymentRequired, `{"error":"Insufficient credits"}`, ErrProviderQuota},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cannedResponse(tt.status, tt.body)
+			defer server.Close()
+
+			_, err := newTextProviderTestDetector(config, server.URL).detectWithOriginality(context.Background(), longText)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWinston tests Winston AI response mapping.
+func TestWinston(t *testing.T) {
+	config := DetectorConfig{WinstonAPIKey: "win-key"}
+
+	t.Run("inverts human score", func(t *testing.T) {
+		var gotAuth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotAuth = r.Header.Get("Authorization")
+			w.Write([]byte(`{"status":200,"score":25,"sentences":[{"text":"First sentence.","score":25},{"text":"Second sentence.","score":75}],"credits_used":120}`))
+		}))
+		defer server.Close()
+
+		out, err := newTextProviderTestDetector(config, server.URL).detectWithWinston(context.Background(), longText)
+		if err != nil {
+			t.Fatalf("detectWithWinston() error = %v", err)
+		}
+		if out.AIScore != 0.75 {
+			t.Errorf("AIScore = %f, want 0.75", out.AIScore)
+		}
+		if len(out.Sentences) != 2 || out.Sentences[0].AIScore != 0.75 || out.Sentences[1].AIScore != 0.25 {
+			t.Errorf("unexpected sentences: %+v", out.Sentences)
+		}
+		if gotAuth != "Bearer win-key" {
+			t.Errorf("Authorization = %q", gotAuth)
+		}
+	})
+
+	t.Run("short text is skipped", func(t *testing.T) {
+		_, err := newTextProviderTestDetector(config, "http://unused").detectWithWinston(context.Background(), "Too short.")
+		if !errors.Is(err, ErrProviderSkipped) {
+			t.Errorf("error = %v, want ErrProviderSkipped", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{"minimum length rejection", http.StatusBadRequest, `{"error":"The text must be at least 300 characters long."}`, ErrProviderSkipped},
+		{"bad key", http.StatusForbidden, `{"error":"Invalid API token"}`, ErrProviderAuth},
+		{"rate limited", http.StatusTooManyRequests, `{"error":"Too many requests"}`, ErrProviderQuota},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cannedResponse(tt.status, tt.body)
+			defer server.Close()
+
+			_, err := newTextProviderTestDetector(config, server.URL).detectWithWinston(context.Background(), longText)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestGPTZero tests GPTZero response mapping against a recorded response.
+func TestGPTZero(t *testing.T) {
+	t.Run("maps document, sentences and paragraphs", func(t *testing.T) {
+		fixture := readFixture(t, "gptzero_predict.json")
+		var gotKey, gotDocument string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotKey = r.Header.Get("x-api-key")
+			var body struct {
+				Document string `json:"document"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			gotDocument = body.Document
+			w.Write(fixture)
+		}))
+		defer server.Close()
+
+		config := DetectorConfig{GPTZeroAPIKey: "gz-key", ProviderURLs: map[string]string{"gptzero": server.URL}}
+		out, err := newTextProviderTestDetector(config, "").detectWithGPTZero(context.Background(), longText)
+		if err != nil {
+			t.Fatalf("detectWithGPTZero() error = %v", err)
+		}
+		if out.AIScore != 0.87 {
+			t.Errorf("AIScore = %f, want 0.87", out.AIScore)
+		}
+		if len(out.Sentences) != 3 || out.Sentences[1].Text != "It is important to note that the vote was unanimous." ||
+			out.Sentences[1].AIScore != 0.88 || out.Sentences[2].AIScore != 0.12 {
+			t.Errorf("unexpected sentences: %+v", out.Sentences)
+		}
+		wantParagraphs := []ParagraphScore{{StartSentence: 0, Sentences: 2, AIScore: 0.91}, {StartSentence: 2, Sentences: 1, AIScore: 0.22}}
+		if len(out.Paragraphs) != 2 || out.Paragraphs[0] != wantParagraphs[0] || out.Paragraphs[1] != wantParagraphs[1] {
+			t.Errorf("Paragraphs = %+v, want %+v", out.Paragraphs, wantParagraphs)
+		}
+		if gotKey != "gz-key" || gotDocument != longText {
+			t.Errorf("x-api-key = %q, document = %q", gotKey, gotDocument)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantErr     error
+		wantSkipped bool
+	}{
+		{"document too short", http.StatusBadRequest, `{"error":"Document is too short. Please enter at least 250 characters."}`, ErrProviderSkipped, true},
+		{"other bad request", http.StatusBadRequest, `{"error":"Invalid request body"}`, nil, false},
+		{"bad key", http.StatusUnauthorized, `{"error":"Invalid API key"}`, ErrProviderAuth, false},
+		{"rate limited", http.StatusTooManyRequests, `{"error":"Too many requests"}`, ErrProviderQuota, false},
+		{"no documents", http.StatusOK, `{"documents":[]}`, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := cannedResponse(tt.status, tt.body)
+			defer server.Close()
+
+			config := DetectorConfig{GPTZeroAPIKey: "gz-key", ProviderURLs: map[string]string{"gptzero": server.URL}}
+			_, err := newTextProviderTestDetector(config, "").detectWithGPTZero(context.Background(), longText)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrProviderSkipped) != tt.wantSkipped {
+				t.Errorf("error = %v, skipped = %v, want %v", err, !tt.wantSkipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+// TestSentenceAgreements tests comparing GPTZero's sentence scores with
+// other detectors'.
+func TestSentenceAgreements(t *testing.T) {
+	gptzero := DetectorOutput{Name: "gptzero", Sentences: []SentenceScore{
+		{Text: "The committee reviewed the proposal.", AIScore: 0.9},
+		{Text: "It passed.", AIScore: 0.8},
+		{Text: "Nobody expected that.", AIScore: 0.1},
+	}}
+
+	t.Run("compares matching sentences", func(t *testing.T) {
+		winston := DetectorOutput{Name: "winston", Sentences: []SentenceScore{
+			{Text: "the committee  reviewed the proposal.", AIScore: 0.7},
+			{Text: "It passed.", AIScore: 0.4},
+			{Text: "Nobody expected that.", AIScore: 0.2},
+			{Text: "A sentence GPTZero split differently.", AIScore: 0.9},
+		}}
+
+		got := sentenceAgreements([]DetectorOutput{winston, gptzero})
+		if len(got) != 1 {
+			t.Fatalf("sentenceAgreements() = %+v, want one comparison", got)
+		}
+		a := got[0]
+		if strings.Join(a.Detectors, ",") != "gptzero,winston" || a.Sentences != 3 {
+			t.Errorf("Detectors = %v, Sentences = %d, want gptzero,winston over 3", a.Detectors, a.Sentences)
+		}
+		if math.Abs(a.Agreement-2.0/3) > 1e-9 {
+			t.Errorf("Agreement = %f, want 2/3", a.Agreement)
+		}
+		if math.Abs(a.MeanDifference-0.7/3) > 1e-9 {
+			t.Errorf("MeanDifference = %f, want %f", a.MeanDifference, 0.7/3)
+		}
+	})
+
+	t.Run("closing punctuation is ignored", func(t *testing.T) {
+		humanmark := DetectorOutput{Name: "humanmark", Sentences: []SentenceScore{
+			{Text: "The committee reviewed the proposal", AIScore: 0.7},
+			{Text: "It passed", AIScore: 0.6},
+		}}
+		got := sentenceAgreements([]DetectorOutput{gptzero, humanmark})
+		if len(got) != 1 || got[0].Sentences != 2 || got[0].Agreement != 1 {
+			t.Errorf("sentenceAgreements() = %+v, want full agreement over 2 sentences", got)
+		}
+	})
+
+	t.Run("outputs without shared sentences are left out", func(t *testing.T) {
+		originality := DetectorOutput{Name: "originality", Sentences: []SentenceScore{{Text: "Something else entirely.", AIScore: 0.5}}}
+		if got := sentenceAgreements([]DetectorOutput{gptzero, originality}); got != nil {
+			t.Errorf("sentenceAgreements() = %+v, want nil", got)
+		}
+	})
+
+	t.Run("nothing to compare without GPTZero", func(t *testing.T) {
+		winston := DetectorOutput{Name: "winston", Sentences: gptzero.Sentences}
+		if got := sentenceAgreements([]DetectorOutput{winston}); got != nil {
+			t.Errorf("sentenceAgreements() = %+v, want nil", got)
+		}
+	})
+}
+
+// TestTextProviderRegistry verifies registered providers are blended into results.
+func TestTextProviderRegistry(t *testing.T) {
+	for _, name := range []string{"hive", "gptzero", "openai", "originality", "winston"} {
+		if _, ok := textDetectorWeights[name]; !ok {
+			t.Errorf("provider %s has no default weight", name)
+		}
+	}
+
+	t.Run("sentence outputs are returned", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/originality", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"success":true,"score":{"ai":0.9},"blocks":[{"text":"A sentence.","result":{"fake":0.9}}]}`))
+		})
+		mux.HandleFunc("/winston", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"score":30}`))
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		d := newTextProviderTestDetector(DetectorConfig{OriginalityAPIKey: "k", WinstonAPIKey: "k"}, "")
+		d.originalityURL = server.URL + "/originality"
+		d.winstonURL = server.URL + "/winston"
+
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+
+		want := []string{"humanmark", "originality", "winston"}
+		if strings.Join(result.Detectors, ",") != strings.Join(want, ",") {
+			t.Errorf("Detectors = %v, want %v", result.Detectors, want)
+		}
+		if len(result.DetectorOutputs) != 2 || result.DetectorOutputs[0].Name != "humanmark" || result.DetectorOutputs[1].Name != "originality" {
+			t.Errorf("DetectorOutputs = %+v, want humanmark, then originality", result.DetectorOutputs)
+		}
+	})
+
+	t.Run("gptzero sentences are compared with the others", func(t *testing.T) {
+		fixture := readFixture(t, "gptzero_predict.json")
+		mux := http.NewServeMux()
+		mux.HandleFunc(gptzeroPath, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(fixture)
+		})
+		mux.HandleFunc("/winston", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"score":20,"sentences":[{"text":"The committee reviewed the proposal in detail.","score":10},{"text":"Honestly, nobody expected that.","score":70}]}`))
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		d := newTextProviderTestDetector(DetectorConfig{
+			GPTZeroAPIKey: "k",
+			WinstonAPIKey: "k",
+			ProviderURLs:  map[string]string{"gptzero": server.URL},
+		}, "")
+		d.winstonURL = server.URL + "/winston"
+
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+		if len(result.DetectorOutputs) != 3 || result.DetectorOutputs[1].Name != "gptzero" || len(result.DetectorOutputs[1].Paragraphs) != 2 {
+			t.Errorf("DetectorOutputs = %+v, want humanmark, gptzero with paragraphs, then winston", result.DetectorOutputs)
+		}
+		if len(result.SentenceAgreement) != 1 || result.SentenceAgreement[0].Sentences != 2 || result.SentenceAgreement[0].Agreement != 1 {
+			t.Errorf("SentenceAgreement = %+v, want full agreement over 2 sentences", result.SentenceAgreement)
+		}
+	})
+
+	t.Run("gptzero sentences are compared with ours", func(t *testing.T) {
+		fixture := readFixture(t, "gptzero_predict.json")
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(fixture)
+		}))
+		defer server.Close()
+
+		d := newTextProviderTestDetector(DetectorConfig{
+			GPTZeroAPIKey: "k",
+			ProviderURLs:  map[string]string{"gptzero": server.URL},
+		}, "")
+
+		text := "The committee reviewed the proposal in detail. It is important to note that the vote was unanimous. Honestly, nobody expected that."
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: text})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+		if len(result.DetectorOutputs) != 2 || result.DetectorOutputs[0].Name != "humanmark" || len(result.DetectorOutputs[0].Sentences) != 3 {
+			t.Fatalf("DetectorOutputs = %+v, want 3 humanmark sentences, then gptzero", result.DetectorOutputs)
+		}
+		if len(result.SentenceAgreement) != 1 {
+			t.Fatalf("SentenceAgreement = %+v, want one comparison", result.SentenceAgreement)
+		}
+		a := result.SentenceAgreement[0]
+		if strings.Join(a.Detectors, ",") != "gptzero,humanmark" || a.Sentences != 3 {
+			t.Errorf("Detectors = %v, Sentences = %d, want gptzero,humanmark over 3", a.Detectors, a.Sentences)
+		}
+	})
+
+	t.Run("a slow provider gets its own timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		mux := http.NewServeMux()
+		mux.HandleFunc("/originality", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"success":true,"score":{"ai":0.9}}`))
+		})
+		mux.HandleFunc("/winston", func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+		defer close(release)
+
+		d := newTextProviderTestDetector(DetectorConfig{
+			OriginalityAPIKey: "k",
+			WinstonAPIKey:     "k",
+			Timeout:           10 * time.Second,
+			ProviderTimeouts:  map[string]time.Duration{"winston": 50 * time.Millisecond},
+		}, "")
+		d.originalityURL = server.URL + "/originality"
+		d.winstonURL = server.URL + "/winston"
+
+		start := time.Now()
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: longText})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("DetectText() took %v, want winston cut off", elapsed)
+		}
+		if result.DetectorErrors["winston"] == "" || strings.Join(result.Detectors, ",") != "humanmark,originality" {
+			t.Errorf("Detectors = %v, errors = %v, want winston timed out", result.Detectors, result.DetectorErrors)
+		}
+	})
+
+	t.Run("skipped providers are reported", func(t *testing.T) {
+		d := newTextProviderTestDetector(DetectorConfig{OriginalityAPIKey: "k", WinstonAPIKey: "k"}, "http://unused")
+
+		result, err := d.DetectText(context.Background(), DetectionInput{Text: "Short text that no provider accepts."})
+		if err != nil {
+			t.Fatalf("DetectText() error = %v", err)
+		}
+		if len(result.Detectors) != 1 || result.Detectors[0] != "humanmark" {
+			t.Errorf("Detectors = %v, want only humanmark", result.Detectors)
+		}
+		if len(result.SkippedProviders) != 2 {
+			t.Fatalf("SkippedProviders = %+v, want originality and winston", result.SkippedProviders)
+		}
+		if result.SkippedProviders[0].Name != "originality" || result.SkippedProviders[0].Reason == "" {
+			t.Errorf("unexpected skip: %+v", result.SkippedProviders[0])
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// Watermark Scan (experimental)
+// =============================================================================
+//
+// Some LLM watermarking schemes bias token choice toward a "green list",
+// which leaves the text's word frequencies skewed. Without the scheme's key
+// the green list is unknown, but the skew can still show up against ordinary
+// prose: scanWatermark compares how often the most common English words and
+// word pairs occur with an embedded reference distribution
+// (data/reference_frequencies_en.json), using a chi-square test.
+//
+// Natural writing drifts from the reference with genre and topic too, and a
+// plain chi-square test finds any long enough text significant. The
+// statistic is therefore deflated as the text grows (watermarkDispersion),
+// so only a skew well beyond what style explains is flagged.
+//
+// The scan is experimental: it is reported with the analysis but doesn't
+// change AIScore.
+//
+// =============================================================================
+
+//go:embed data/reference_frequencies_en.json
+var referenceFrequenciesJSON []byte
+
+// referenceFrequency is a word or word pair's share of the reference
+// distribution.
+type referenceFrequency struct {
+	item  string
+	share float64
+}
+
+// referenceWords and referenceBigrams are the reference distributions, most
+// frequent first. Each one's shares sum to 1.
+var referenceWords, referenceBigrams []referenceFrequency
+
+func init() {
+	var data struct {
+		Words   map[string]float64 `json:"words"`
+		Bigrams map[string]float64 `json:"bigrams"`
+	}
+	if err := json.Unmarshal(referenceFrequenciesJSON, &data); err != nil {
+		panic("service: invalid embedded reference frequencies: " + err.Error())
+	}
+	referenceWords = referenceShares(data.Words)
+	referenceBigrams = referenceShares(data.Bigrams)
+}
+
+// referenceShares converts occurrence counts to shares, most frequent first.
+func referenceShares(counts map[string]float64) []referenceFrequency {
+	var total float64
+	for _, n := range counts {
+		total += n
+	}
+
+	shares := make([]referenceFrequency, 0, len(counts))
+	for item, n := range counts {
+		shares = append(shares, referenceFrequency{item: item, share: n / total})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].share != shares[j].share {
+			return shares[i].share > shares[j].share
+		}
+		return shares[i].item < shares[j].item
+	})
+	return shares
+}
+
+const (
+	// watermarkMinWords is how many reference words a text needs to be
+	// scanned; in shorter texts a skew can't be told from chance
+	watermarkMinWords = 50
+
+	// watermarkAlpha is the p-value below which a skew is flagged
+	watermarkAlpha = 0.001
+
+	// watermarkDispersion deflates the chi-square statistic of n counted
+	// words by 1 + n/watermarkDispersion, since natural text varies more
+	// than independent draws from the reference would
+	watermarkDispersion = 100.0
+
+	// watermarkMinExpected is the fewest expected occurrences a word or
+	// pair needs to be tested on its own; rarer ones are pooled, as the
+	// chi-square approximation requires
+	watermarkMinExpected = 5.0
+
+	// watermarkMaxCategories is how many of the most frequent words (and
+	// pairs) are tested on their own; the rest are pooled. Fixing it keeps
+	// the degrees of freedom, and so the threshold, from growing with the
+	// text, which would let the deflated statistic of a skewed long text
+	// fall below it.
+	watermarkMaxCategories = 30
+)
+
+// WatermarkScan is the result of the experimental watermark scan.
+type WatermarkScan struct {
+	// Experimental is always true: the scan is a heuristic under
+	// evaluation and doesn't affect AIScore
+	Experimental bool
+
+	// PValue is the chance of a skew at least this large in text without
+	// a watermark (0-1; lower = more skewed)
+	PValue float64
+
+	// Score is 1 - PValue (0-1; higher = more skewed)
+	Score float64
+
+	// Flagged is set when PValue is below the significance threshold
+	Flagged bool
+
+	// Words and WordPairs are how many reference words and word pairs the
+	// text contains
+	Words     int
+	WordPairs int
+
+	// ChiSquare is the length-adjusted statistic for words and pairs
+	// together, with DegreesOfFreedom
+	ChiSquare        float64
+	DegreesOfFreedom int
+}
+
+// scanWatermark tests tokens' word and word pair frequencies against the
+// reference distribution. It returns nil for text too short to test.
+func scanWatermark(tokens []string) *WatermarkScan {
+	words := make(map[string]int, len(tokens))
+	pairs := make(map[string]int, len(tokens))
+	for i, token := range tokens {
+		token = strings.ToLower(token)
+		words[token]++
+		if i > 0 {
+			pairs[strings.ToLower(tokens[i-1])+" "+token]++
+		}
+	}
+
+	wordStat, wordDF, wordCount := chiSquareAgainst(words, referenceWords)
+	if wordCount < watermarkMinWords {
+		return nil
+	}
+	pairStat, pairDF, pairCount := chiSquareAgainst(pairs, referenceBigrams)
+
+	stat := wordStat/(1+float64(wordCount)/watermarkDispersion) +
+		pairStat/(1+float64(pairCount)/watermarkDispersion)
+	df := wordDF + pairDF
+	pValue := chiSquareSurvival(stat, df)
+
+	return &WatermarkScan{
+		Experimental:     true,
+		PValue:           pValue,
+		Score:            1 - pValue,
+		Flagged:          pValue < watermarkAlpha,
+		Words:            wordCount,
+		WordPairs:        pairCount,
+		ChiSquare:        stat,
+		DegreesOfFreedom: df,
+	}
+}
+
+// chiSquareAgainst returns Pearson's chi-square statistic for counts of the
+// reference items against reference, with its degrees of freedom and the
+// number of items counted. Items outside reference are ignored, so the test
+// is of how the common words are used, not of how many there are.
+func chiSquareAgainst(counts map[string]int, reference []referenceFrequency) (stat float64, df, n int) {
+	for _, ref := range reference {
+		n += counts[ref.item]
+	}
+	if n == 0 {
+		return 0, 0, 0
+	}
+
+	categories := 0
+	var pooledObserved, pooledExpected float64
+	for i, ref := range reference {
+		observed := float64(counts[ref.item])
+		expected := float64(n) * ref.share
+		if expected < watermarkMinExpected || i >= watermarkMaxCategories {
+			pooledObserved += observed
+			pooledExpected += expected
+			continue
+		}
+		stat += (observed - expected) * (observed - expected) / expected
+		categories++
+	}
+	if pooledExpected > 0 {
+		stat += (pooledObserved - pooledExpected) * (pooledObserved - pooledExpected) / pooledExpected
+		categories++
+	}
+	if categories < 2 {
+		return 0, 0, n
+	}
+	return stat, categories - 1, n
+}
+
+// chiSquareSurvival returns P(X >= x) for X chi-square distributed with df
+// degrees of freedom: the upper regularized incomplete gamma function
+// Q(df/2, x/2).
+func chiSquareSurvival(x float64, df int) float64 {
+	if df <= 0 || x <= 0 {
+		return 1
+	}
+	a, x := float64(df)/2, x/2
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+
+	if x < a+1 {
+		// Series for the lower function P, converging quickly here
+		term := 1 / a
+		sum := term
+		for n := 1; n < 500; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return math.Max(0, math.Min(1, 1-sum*prefix))
+	}
+
+	// Continued fraction for Q (modified Lentz's method)
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 500; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Max(0, math.Min(1, prefix*h))
+}
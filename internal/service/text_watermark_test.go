@@ -0,0 +1,119 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// referenceText draws n words, about half of them from the reference
+// distribution and the rest filler. Reference words at odd ranks are
+// weighted by boost, the way a green-list watermark favors its list.
+func referenceText(n int, boost float64, seed int64) string {
+	r := rand.New(rand.NewSource(seed))
+	fillers := strings.Fields("river model garden system budget castle winter signal method journey market question doctor window engine theory")
+
+	weights := make([]float64, len(referenceWords))
+	var total float64
+	for i, ref := range referenceWords {
+		weights[i] = ref.share
+		if i%2 == 1 {
+			weights[i] *= boost
+		}
+		total += weights[i]
+	}
+
+	words := make([]string, 0, n)
+	for len(words) < n {
+		if r.Float64() < 0.45 {
+			words = append(words, fillers[r.Intn(len(fillers))])
+			continue
+		}
+		x := r.Float64() * total
+		for i, w := range weights {
+			if x -= w; x <= 0 {
+				words = append(words, referenceWords[i].item)
+				break
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// TestScanWatermark verifies skewed word frequencies are flagged and
+// ordinary text isn't.
+func TestScanWatermark(t *testing.T) {
+	t.Run("flags a green-list skew at any length", func(t *testing.T) {
+		for _, n := range []int{500, 2000, 10000} {
+			scan := scanWatermark(tokenize(referenceText(n, 1000, int64(n))))
+			if scan == nil || !scan.Flagged || !scan.Experimental {
+				t.Errorf("%d words: expected a flagged experimental scan, got %+v", n, scan)
+				continue
+			}
+			if scan.PValue >= watermarkAlpha || scan.Score != 1-scan.PValue {
+				t.Errorf("%d words: p-value %g, score %g", n, scan.PValue, scan.Score)
+			}
+		}
+	})
+
+	t.Run("doesn't flag text drawn from the reference", func(t *testing.T) {
+		for _, n := range []int{500, 2000, 10000} {
+			scan := scanWatermark(tokenize(referenceText(n, 1, int64(n))))
+			if scan == nil || scan.Flagged {
+				t.Errorf("%d words: expected an unflagged scan, got %+v", n, scan)
+			}
+		}
+	})
+
+	t.Run("doesn't flag ordinary writing", func(t *testing.T) {
+		for name, text := range map[string]string{
+			"narrative": `When I got back to the house that evening the lights were already off, and for a moment I thought everyone had gone out without me. My brother was sitting on the porch steps with his dog, a scruffy thing we'd found by the road two winters ago, and he didn't look up when I came through the gate. I asked him what happened. He said our mother had gone to the hospital with Aunt Ruth, who had fallen again in the kitchen, and that he had been told to wait for me. We sat there for a long time without talking. The dog kept nosing at my hand, wanting to be scratched behind the ears, and I did it without thinking, the way you do. Around nine the phone rang inside and my brother ran in to get it. I could hear him saying yes, and okay, and she's here now, and then he came back out and told me Ruth had broken her hip but she was going to be fine, and that Mom would stay the night. I don't know why, but that was when I started crying, after it was all over and nothing bad had happened at all. He put his arm around me, awkwardly, because we weren't that kind of family, and said come on, let's make some eggs. So we did. We burned the first batch and the smoke alarm went off and the dog barked at it like it was a burglar, and by the time we had eaten we were both laughing about it.`,
+			"technical": `To configure the service, copy the example environment file and adjust the values for your deployment. The server reads its settings at startup and validates them before it accepts any connections, so a typo in a variable name or an invalid value will stop the process with an error that lists every problem it found. In production the API key requirement is always enabled, and the allowed origins must be listed explicitly. Detection providers are optional: without any keys the local analyzer is used on its own, which is enough for testing but less accurate on short texts. Each provider can be given its own timeout and weight in the configuration file. When a provider fails, its error is recorded in the result and the remaining detectors are used. Results are stored in PostgreSQL when a database URL is set, and in memory otherwise; the memory store keeps a bounded number of jobs and discards the oldest first. Large uploads are processed asynchronously by a pool of workers, and the client can either poll the job or register a callback URL that receives a signed request when the job is complete.`,
+		} {
+			scan := scanWatermark(tokenize(text))
+			if scan == nil || scan.Flagged {
+				t.Errorf("%s: expected an unflagged scan, got %+v", name, scan)
+			}
+		}
+	})
+
+	t.Run("skips short text", func(t *testing.T) {
+		if scan := scanWatermark(tokenize("The cat sat on the mat, and that was that.")); scan != nil {
+			t.Errorf("expected no scan of a short text, got %+v", scan)
+		}
+	})
+
+	t.Run("is reported without changing the score", func(t *testing.T) {
+		text := referenceText(2000, 1000, 7)
+		result := NewTextAnalyzer().Analyze(text)
+		if result.Watermark == nil || !result.Watermark.Flagged {
+			t.Fatalf("expected a flagged scan in the analysis, got %+v", result.Watermark)
+		}
+		a := NewTextAnalyzer()
+		if want := a.calculateWeightedScore(result.Signals, result.Stats.EmbeddedBlocks > 0); result.AIScore != want {
+			t.Errorf("AIScore = %g, want the signals' score %g", result.AIScore, want)
+		}
+	})
+}
+
+// TestChiSquareSurvival checks p-values against chi-square table values.
+func TestChiSquareSurvival(t *testing.T) {
+	tests := []struct {
+		x    float64
+		df   int
+		want float64
+	}{
+		{3.841, 1, 0.05},
+		{6.635, 1, 0.01},
+		{18.307, 10, 0.05},
+		{59.703, 30, 0.001},
+		{2, 2, math.Exp(-1)},
+		{0, 5, 1},
+	}
+	for _, tt := range tests {
+		if got := chiSquareSurvival(tt.x, tt.df); math.Abs(got-tt.want) > tt.want*0.001 {
+			t.Errorf("chiSquareSurvival(%g, %d) = %g, want %g", tt.x, tt.df, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// =============================================================================
+// Analyzer Versioning
+// =============================================================================
+//
+// Stored results are only comparable if they were produced by the same logic.
+// Every result records two things:
+//
+//   AnalyzerVersion    Bumped by hand when detection logic changes
+//   ConfigFingerprint  Hash of the weights, phrase dictionary and thresholds
+//                      actually in effect, so a weight tweak or a deployment
+//                      override shows up even without a version bump
+//
+// =============================================================================
+
+// AnalyzerVersion identifies the detection logic. Bump the minor version
+// when signals or scoring change, the major version when scores change
+// meaning (e.g. the verdict scale).
+const AnalyzerVersion = "1.9.0"
+
+// effectiveConfig is everything that decides a score or verdict.
+type effectiveConfig struct {
+	TextWeights  TextAnalyzerWeights
+	ImageWeights ImageAnalyzerWeights
+	AudioWeights AudioAnalyzerWeights
+	VideoWeights VideoAnalyzerWeights
+
+	// ProviderWeights are the reliability weights per content type, after
+	// configured overrides
+	ProviderWeights map[ContentType]map[string]float64
+
+	AIPhrases []AIPhrase
+
+	Aggregation   AggregationStrategy
+	Decision      DecisionPolicy
+	VetoThreshold float64
+
+	DisagreementThreshold     float64
+	DisagreementConfidenceCap float64
+	LocalOnlyConfidenceCap    float64
+
+	// HostRules are the host reputation rules, after overrides
+	HostRules []HostRule
+}
+
+// newEffectiveConfig collects the configuration a detector built from
+// config runs with.
+func newEffectiveConfig(config DetectorConfig) effectiveConfig {
+	providerWeights := map[ContentType]map[string]float64{
+		ContentTypeText:     textDetectorWeights,
+		ContentTypeImage:    imageDetectorWeights,
+		ContentTypeAudio:    audioDetectorWeights,
+		ContentTypeVideo:    videoDetectorWeights,
+		ContentTypeDocument: documentDetectorWeights,
+	}
+	for contentType, weights := range providerWeights {
+		providerWeights[contentType] = withWeightOverrides(weights, config.ProviderWeights)
+	}
+
+	phrases := AIPhraseDictionary
+	if len(config.AIPhrases) > 0 {
+		phrases = config.AIPhrases
+	}
+
+	return effectiveConfig{
+		TextWeights:     DefaultWeights(),
+		ImageWeights:    DefaultImageWeights(),
+		AudioWeights:    DefaultAudioWeights(),
+		VideoWeights:    DefaultVideoWeights(),
+		ProviderWeights: providerWeights,
+		AIPhrases:       phrases,
+		Aggregation:     config.Aggregation,
+		Decision:        config.Decision,
+		VetoThreshold:   VetoThreshold,
+
+		DisagreementThreshold:     config.DisagreementThreshold,
+		DisagreementConfidenceCap: DisagreementConfidenceCap,
+		LocalOnlyConfidenceCap:    config.LocalOnlyConfidenceCap,
+
+		HostRules: newHostReputation(DefaultHostRules, config.HostRules).rules(),
+	}
+}
+
+// fingerprint returns a short, stable hash of the configuration.
+func (c effectiveConfig) fingerprint() string {
+	// Map keys are marshaled in sorted order, so the encoding is stable
+	data, err := json.Marshal(c)
+	if err != nil {
+		panic("service: effective config not serializable: " + err.Error())
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestConfigFingerprint tests that the fingerprint tracks the configuration.
+func TestConfigFingerprint(t *testing.T) {
+	config := DetectorConfig{Aggregation: AggregationWeightedMean, Decision: DefaultDecisionPolicy()}
+	base := newEffectiveConfig(config).fingerprint()
+
+	if again := newEffectiveConfig(config).fingerprint(); again != base {
+		t.Errorf("fingerprint not stable: %s != %s", again, base)
+	}
+
+	changes := []struct {
+		name   string
+		modify func(c *effectiveConfig)
+	}{
+		{"text weights", func(c *effectiveConfig) {
+			c.TextWeights.AIPhraseDetection += 0.05
+			c.TextWeights.SentenceVariance -= 0.05
+		}},
+		{"video weights", func(c *effectiveConfig) { c.VideoWeights.FrameRatePattern = 0.2 }},
+		{"phrase list", func(c *effectiveConfig) {
+			c.AIPhrases = append(c.AIPhrases[:len(c.AIPhrases):len(c.AIPhrases)], AIPhrase{Pattern: "synergy", Weight: 0.5, Category: "buzzword"})
+		}},
+		{"decision threshold", func(c *effectiveConfig) { c.Decision.AIThreshold = 0.8 }},
+		{"uncertainty band", func(c *effectiveConfig) { c.Decision.UncertaintyBand = 0.15 }},
+		{"host rules", func(c *effectiveConfig) {
+			c.HostRules = append(c.HostRules[:len(c.HostRules):len(c.HostRules)], HostRule{Host: "cdn.example.com", Adjustment: 0.1})
+		}},
+	}
+
+	for _, tt := range changes {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newEffectiveConfig(config)
+			tt.modify(&c)
+			if c.fingerprint() == base {
+				t.Errorf("fingerprint unchanged after changing %s", tt.name)
+			}
+		})
+	}
+
+	t.Run("provider weight override", func(t *testing.T) {
+		overridden := config
+		overridden.ProviderWeights = map[string]float64{"winston": 1.3}
+		if newEffectiveConfig(overridden).fingerprint() == base {
+			t.Error("fingerprint unchanged after overriding a provider weight")
+		}
+	})
+}
+
+// TestDetectRecordsVersion tests that results carry the version and fingerprint.
+func TestDetectRecordsVersion(t *testing.T) {
+	detector, err := NewDetector(DetectorConfig{Timeout: 5 * time.Second}, logger.NopLogger())
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+
+	result, err := detector.Detect(context.Background(), DetectionInput{Text: "A short note about versioning."})
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+
+	if result.AnalyzerVersion != AnalyzerVersion {
+		t.Errorf("AnalyzerVersion = %q, want %q", result.AnalyzerVersion, AnalyzerVersion)
+	}
+	want := newEffectiveConfig(DetectorConfig{Aggregation: AggregationWeightedMean, Decision: DefaultDecisionPolicy()}).fingerprint()
+	if result.ConfigFingerprint != want {
+		t.Errorf("ConfigFingerprint = %q, want %q", result.ConfigFingerprint, want)
+	}
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"io"
 	"math"
 )
 
@@ -28,9 +30,13 @@ import (
 //
 // =============================================================================
 
-// VideoAnalyzer performs forensic analysis on video files.
+// VideoAnalyzer performs forensic analysis on video files. It is safe for
+// concurrent use.
 type VideoAnalyzer struct {
 	weights VideoAnalyzerWeights
+
+	// keyframes scores keyframe slices as images
+	keyframes *ImageAnalyzer
 }
 
 // VideoAnalyzerWeights controls signal importance.
@@ -41,24 +47,32 @@ type VideoAnalyzerWeights struct {
 	TemporalPattern    float64
 	EncodingSignature  float64
 	BitrateConsistency float64
+	FrameRatePattern   float64
+	KeyframeImageScore float64
 }
 
 // DefaultVideoWeights returns tuned weights.
 func DefaultVideoWeights() VideoAnalyzerWeights {
 	return VideoAnalyzerWeights{
 		MetadataScore:      0.25,
-		ContainerAnalysis:  0.20,
-		AudioPresence:      0.15,
-		TemporalPattern:    0.15,
-		EncodingSignature:  0.15,
-		BitrateConsistency: 0.10,
+		ContainerAnalysis:  0.13,
+		AudioPresence:      0.13,
+		TemporalPattern:    0.09,
+		EncodingSignature:  0.13,
+		BitrateConsistency: 0.09,
+		FrameRatePattern:   0.10,
+		KeyframeImageScore: 0.08,
 	}
 }
 
 // NewVideoAnalyzer creates a new analyzer.
 func NewVideoAnalyzer() *VideoAnalyzer {
+	weights := DefaultVideoWeights()
+	mustValidateWeights(weights)
+
 	return &VideoAnalyzer{
-		weights: DefaultVideoWeights(),
+		weights:   weights,
+		keyframes: NewImageAnalyzer(),
 	}
 }
 
@@ -78,6 +92,8 @@ type VideoSignals struct {
 	TemporalPattern    float64 // Unusual patterns = AI-like
 	EncodingSignature  float64 // Unknown encoder = suspicious
 	BitrateConsistency float64 // Unusual bitrate = AI-like
+	FrameRatePattern   float64 // Exact 24/30/60 fps = AI-like
+	KeyframeImageScore float64 // First keyframe scored as an image
 }
 
 // VideoMetadata contains extracted metadata.
@@ -89,6 +105,13 @@ type VideoMetadata struct {
 	CreationTime string
 	Duration     float64 // seconds (estimated)
 	IsAIMarked   bool    // Contains AI generator markers
+
+	// DeclaredFormat is the format the filename or MIME type claimed
+	// (empty without a recognizable hint)
+	DeclaredFormat string
+
+	// FormatConflict is set when a hint disagrees with Format
+	FormatConflict bool
 }
 
 // VideoStats contains video statistics.
@@ -103,34 +126,97 @@ type VideoStats struct {
 	ChunkCount     int
 }
 
+// Video header sizes for streaming analysis
+const (
+	// videoHeaderSize is the minimum header buffered for format and metadata
+	videoHeaderSize = 100_000
+
+	// maxVideoHeaderSize caps how far a container header can extend the buffer
+	maxVideoHeaderSize = 32 << 20
+)
+
+// videoStreamTokens are the signatures checked across the whole file.
+var videoStreamTokens = [][]byte{
+	[]byte("mdat"),
+	[]byte("avc1"), []byte("h264"),
+	[]byte("hvc1"), []byte("hevc"),
+	[]byte("vp09"), []byte("av01"),
+}
+
 // Analyze performs forensic analysis on video data.
 func (a *VideoAnalyzer) Analyze(data []byte) VideoAnalysisResult {
+	// Reading from memory can't fail
+	result, _ := a.AnalyzeFromReader(bytes.NewReader(data))
+	return result
+}
+
+// AnalyzeFromReader performs forensic analysis on video read from r.
+//
+// Only the container header - at least videoHeaderSize bytes - is buffered;
+// the rest is streamed in chunks for pattern analysis. MP4s with the moov
+// atom after the media data (not "faststart") lose their moov metadata.
+func (a *VideoAnalyzer) AnalyzeFromReader(r io.Reader) (VideoAnalysisResult, error) {
+	return a.AnalyzeFromReaderContext(context.Background(), r)
+}
+
+// AnalyzeFromReaderContext is AnalyzeFromReader, stopping with ctx's error
+// if ctx is cancelled while the input is being read.
+func (a *VideoAnalyzer) AnalyzeFromReaderContext(ctx context.Context, r io.Reader) (VideoAnalysisResult, error) {
+	return a.AnalyzeFromReaderWithHints(ctx, r, FormatHints{})
+}
+
+// AnalyzeFromReaderWithHints is AnalyzeFromReaderContext for video that
+// claims a format through hints. Metadata records the declared format and
+// whether it conflicts with the detected one; the score is unchanged, the
+// detector weighs a conflict as a signal of its own.
+func (a *VideoAnalyzer) AnalyzeFromReaderWithHints(ctx context.Context, r io.Reader, hints FormatHints) (VideoAnalysisResult, error) {
+	s, err := readMediaSample(ctx, r, videoHeaderSize, videoContainerHeaderSize, videoStreamTokens...)
+	if err != nil {
+		return VideoAnalysisResult{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return VideoAnalysisResult{}, err
+	}
+
+	result := a.analyze(s)
+	result.Metadata.DeclaredFormat, result.Metadata.FormatConflict = hints.check(videoFormats, result.Metadata.Format)
+	return result, nil
+}
+
+// analyze runs the analysis on a buffered header plus streamed samples.
+func (a *VideoAnalyzer) analyze(s *mediaSample) VideoAnalysisResult {
 	result := VideoAnalysisResult{}
-	result.Stats.FileSize = int64(len(data))
+	header := s.header
 
 	// Detect format
-	format := a.detectVideoFormat(data)
+	format := a.detectVideoFormat(header)
 	result.Metadata.Format = format
 
 	// Extract metadata based on format
 	switch format {
 	case "mp4", "mov":
-		result.Metadata, result.Stats = a.analyzeMP4(data)
+		result.Metadata, result.Stats = a.analyzeMP4(header)
+		result.Metadata.Format = format
 	case "webm":
-		result.Metadata, result.Stats = a.analyzeWebM(data)
+		result.Metadata, result.Stats = a.analyzeWebM(header)
 	case "avi":
-		result.Metadata, result.Stats = a.analyzeAVI(data)
+		result.Metadata, result.Stats = a.analyzeAVI(header)
+	case "flv":
+		result.Metadata, result.Stats = a.analyzeFLV(header)
 	default:
 		result.Metadata.Format = format
 	}
+	result.Stats.FileSize = s.Len()
 
 	// Calculate signals
 	result.Signals.MetadataScore = a.analyzeMetadata(result.Metadata)
-	result.Signals.ContainerAnalysis = a.analyzeContainer(data, format)
-	result.Signals.AudioPresence = a.analyzeAudioPresence(result.Metadata, data, format)
-	result.Signals.TemporalPattern = a.analyzeTemporalPattern(data, format)
-	result.Signals.EncodingSignature = a.analyzeEncodingSignature(data, result.Metadata)
-	result.Signals.BitrateConsistency = a.analyzeBitrateConsistency(data, result.Stats)
+	result.Signals.ContainerAnalysis = a.analyzeContainer(s, format)
+	result.Signals.AudioPresence = a.analyzeAudioPresence(result.Metadata, header, format)
+	result.Signals.TemporalPattern = a.analyzeTemporalPattern(s, format)
+	result.Signals.EncodingSignature = a.analyzeEncodingSignature(s, result.Metadata)
+	result.Signals.BitrateConsistency = a.analyzeBitrateConsistency(s, result.Stats)
+	result.Signals.FrameRatePattern = a.analyzeFrameRatePattern(result.Stats)
+	result.Signals.KeyframeImageScore = a.analyzeKeyframe(header, format)
 
 	// Calculate weighted score
 	result.AIScore = a.calculateWeightedScore(result.Signals)
@@ -138,18 +224,47 @@ func (a *VideoAnalyzer) Analyze(data []byte) VideoAnalysisResult {
 	return result
 }
 
+// videoContainerHeaderSize returns how much of the file holds the container
+// header, judged from its first bytes. Returns 0 when unknown.
+func videoContainerHeaderSize(prefix []byte) int {
+	size := 0
+
+	switch {
+	case len(prefix) >= 8 && bytes.Equal(prefix[4:8], []byte("ftyp")):
+		// MP4/MOV: everything up to the end of moov, if it comes before mdat
+		offset := 0
+		for offset+8 <= len(prefix) {
+			atomSize := int(binary.BigEndian.Uint32(prefix[offset : offset+4]))
+			atomType := string(prefix[offset+4 : offset+8])
+			if atomSize < 8 || atomType == "mdat" {
+				break
+			}
+			if atomType == "moov" {
+				size = offset + atomSize
+				break
+			}
+			offset += atomSize
+		}
+
+	case len(prefix) >= 24 && bytes.Equal(prefix[0:4], []byte("RIFF")) && bytes.Equal(prefix[12:16], []byte("LIST")):
+		// AVI: the hdrl list follows the RIFF header
+		size = 20 + int(binary.LittleEndian.Uint32(prefix[16:20]))
+	}
+
+	return min(size, maxVideoHeaderSize)
+}
+
 // detectVideoFormat identifies video format from magic bytes.
 func (a *VideoAnalyzer) detectVideoFormat(data []byte) string {
+	// An FLV header is only 9 bytes; the rest need at least 12
+	if len(data) >= 4 && data[0] == 'F' && data[1] == 'L' && data[2] == 'V' && data[3] == 0x01 {
+		return "flv"
+	}
 	if len(data) < 12 {
 		return "unknown"
 	}
 
-	// MP4/MOV: ftyp atom at offset 4
-	if len(data) >= 8 && data[4] == 'f' && data[5] == 't' && data[6] == 'y' && data[7] == 'p' {
-		return "mp4"
-	}
-
-	// Also check for ftyp at start (some files)
+	// MP4/MOV: ftyp atom at offset 4, told apart by the major brand
 	if len(data) >= 12 {
 		if bytes.Equal(data[4:8], []byte("ftyp")) {
 			// Check brand
@@ -180,11 +295,6 @@ func (a *VideoAnalyzer) detectVideoFormat(data []byte) string {
 		}
 	}
 
-	// FLV: FLV + version
-	if len(data) >= 4 && data[0] == 'F' && data[1] == 'L' && data[2] == 'V' {
-		return "flv"
-	}
-
 	// MPEG-TS: sync byte 0x47 repeating
 	if data[0] == 0x47 {
 		// Check for more sync bytes at 188-byte intervals
@@ -289,6 +399,11 @@ func (a *VideoAnalyzer) parseMovieAtom(data []byte, meta VideoMetadata, stats Vi
 				meta.HasVideo = true
 			}
 
+			// Frame rate from the video track's sample timing
+			if fps := videoTrackFPS(trackData); fps > 0 {
+				stats.EstimatedFPS = fps
+			}
+
 			// Look for resolution in video track
 			if bytes.Contains(trackData, []byte("avc1")) || bytes.Contains(trackData, []byte("hvc1")) {
 				// H.264 or H.265 video
@@ -313,6 +428,70 @@ func (a *VideoAnalyzer) parseMovieAtom(data []byte, meta VideoMetadata, stats Vi
 	return meta, stats
 }
 
+// videoTrackFPS computes the average frame rate of a trak atom from its
+// media timescale (mdhd) and sample durations (stts). Returns 0 if the
+// track isn't video or the timing atoms are missing.
+func videoTrackFPS(trak []byte) float64 {
+	mdia := mp4ChildAtom(trak[8:], "mdia")
+
+	// Handler type follows version/flags and pre_defined
+	hdlr := mp4ChildAtom(mdia, "hdlr")
+	if len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+		return 0
+	}
+
+	// Timescale: version 0 has 32-bit times, version 1 64-bit
+	mdhd := mp4ChildAtom(mdia, "mdhd")
+	var timescale uint32
+	switch {
+	case len(mdhd) >= 16 && mdhd[0] == 0:
+		timescale = binary.BigEndian.Uint32(mdhd[12:16])
+	case len(mdhd) >= 24 && mdhd[0] == 1:
+		timescale = binary.BigEndian.Uint32(mdhd[20:24])
+	}
+	if timescale == 0 {
+		return 0
+	}
+
+	// stts: run-length (sample_count, sample_delta) pairs
+	stts := mp4ChildAtom(mp4ChildAtom(mp4ChildAtom(mdia, "minf"), "stbl"), "stts")
+	if len(stts) < 8 {
+		return 0
+	}
+	entries := int(binary.BigEndian.Uint32(stts[4:8]))
+
+	var samples, duration uint64
+	for i := 0; i < entries && 8+i*8+8 <= len(stts); i++ {
+		entry := stts[8+i*8:]
+		count := uint64(binary.BigEndian.Uint32(entry[0:4]))
+		delta := uint64(binary.BigEndian.Uint32(entry[4:8]))
+		samples += count
+		duration += count * delta
+	}
+	if samples == 0 || duration == 0 {
+		return 0
+	}
+
+	return float64(timescale) * float64(samples) / float64(duration)
+}
+
+// mp4ChildAtom returns the payload of the first child atom of the given
+// type in data, or nil if there is none.
+func mp4ChildAtom(data []byte, atomType string) []byte {
+	offset := 0
+	for offset+8 <= len(data) {
+		atomSize := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		if atomSize < 8 || offset+atomSize > len(data) {
+			return nil
+		}
+		if string(data[offset+4:offset+8]) == atomType {
+			return data[offset+8 : offset+atomSize]
+		}
+		offset += atomSize
+	}
+	return nil
+}
+
 // analyzeWebM extracts metadata from WebM/MKV containers.
 func (a *VideoAnalyzer) analyzeWebM(data []byte) (VideoMetadata, VideoStats) {
 	meta := VideoMetadata{Format: "webm", HasVideo: true}
@@ -405,18 +584,20 @@ func (a *VideoAnalyzer) analyzeMetadata(meta VideoMetadata) float64 {
 }
 
 // analyzeContainer checks container structure for anomalies.
-func (a *VideoAnalyzer) analyzeContainer(data []byte, format string) float64 {
-	if len(data) < 1000 {
+func (a *VideoAnalyzer) analyzeContainer(s *mediaSample, format string) float64 {
+	if s.Len() < 1000 {
 		return 0.5
 	}
 
+	data := s.header
+
 	score := 0.5
 
 	switch format {
 	case "mp4", "mov":
 		// Check for proper atom structure
 		hasMoviAtom := bytes.Contains(data[:min(len(data), 100000)], []byte("moov"))
-		hasMdatAtom := bytes.Contains(data, []byte("mdat"))
+		hasMdatAtom := s.Contains([]byte("mdat"))
 
 		if !hasMoviAtom {
 			score += 0.2 // Unusual
@@ -451,8 +632,8 @@ func (a *VideoAnalyzer) analyzeAudioPresence(meta VideoMetadata, data []byte, fo
 }
 
 // analyzeTemporalPattern looks for unusual patterns in the video data.
-func (a *VideoAnalyzer) analyzeTemporalPattern(data []byte, format string) float64 {
-	if len(data) < 10000 {
+func (a *VideoAnalyzer) analyzeTemporalPattern(s *mediaSample, format string) float64 {
+	if s.Len() < 10000 {
 		return 0.5
 	}
 
@@ -460,15 +641,11 @@ func (a *VideoAnalyzer) analyzeTemporalPattern(data []byte, format string) float
 	// AI videos sometimes have unusual byte patterns
 
 	regions := 5
-	regionSize := len(data) / (regions + 1)
+	regionSize := s.Len() / int64(regions+1)
 	samples := make([][]byte, regions)
 
 	for i := 0; i < regions; i++ {
-		start := (i + 1) * regionSize
-		end := start + min(1000, len(data)-start)
-		if end > start {
-			samples[i] = data[start:end]
-		}
+		samples[i] = s.Region(int64(i+1)*regionSize, 1000)
 	}
 
 	// Check for unusual repetition between regions
@@ -498,14 +675,14 @@ func (a *VideoAnalyzer) analyzeTemporalPattern(data []byte, format string) float
 }
 
 // analyzeEncodingSignature checks for known encoder signatures.
-func (a *VideoAnalyzer) analyzeEncodingSignature(data []byte, meta VideoMetadata) float64 {
+func (a *VideoAnalyzer) analyzeEncodingSignature(s *mediaSample, meta VideoMetadata) float64 {
 	score := 0.5
 
 	// Check for H.264/H.265 encoding (common in both real and AI)
-	hasH264 := bytes.Contains(data, []byte("avc1")) || bytes.Contains(data, []byte("h264"))
-	hasH265 := bytes.Contains(data, []byte("hvc1")) || bytes.Contains(data, []byte("hevc"))
-	hasVP9 := bytes.Contains(data, []byte("vp09"))
-	hasAV1 := bytes.Contains(data, []byte("av01"))
+	hasH264 := s.Contains([]byte("avc1")) || s.Contains([]byte("h264"))
+	hasH265 := s.Contains([]byte("hvc1")) || s.Contains([]byte("hevc"))
+	hasVP9 := s.Contains([]byte("vp09"))
+	hasAV1 := s.Contains([]byte("av01"))
 
 	// Standard codecs are neutral
 	if hasH264 || hasH265 || hasVP9 || hasAV1 {
@@ -514,7 +691,7 @@ func (a *VideoAnalyzer) analyzeEncodingSignature(data []byte, meta VideoMetadata
 
 	// Unknown/missing codec is suspicious
 	if !hasH264 && !hasH265 && !hasVP9 && !hasAV1 {
-		if meta.Format == "mp4" || meta.Format == "webm" {
+		if meta.Format == "mp4" || meta.Format == "mov" || meta.Format == "webm" {
 			score = 0.6
 		}
 	}
@@ -523,7 +700,7 @@ func (a *VideoAnalyzer) analyzeEncodingSignature(data []byte, meta VideoMetadata
 }
 
 // analyzeBitrateConsistency estimates bitrate consistency.
-func (a *VideoAnalyzer) analyzeBitrateConsistency(data []byte, stats VideoStats) float64 {
+func (a *VideoAnalyzer) analyzeBitrateConsistency(s *mediaSample, stats VideoStats) float64 {
 	// Very rough estimation without full parsing
 	// AI videos sometimes have unusual bitrate characteristics
 
@@ -532,20 +709,19 @@ func (a *VideoAnalyzer) analyzeBitrateConsistency(data []byte, stats VideoStats)
 	}
 
 	// Estimate bits per byte ratio in different regions
-	if len(data) < 10000 {
+	if s.Len() < 10000 {
 		return 0.5
 	}
 
 	// Check entropy in different parts
 	entropies := make([]float64, 0)
-	chunkSize := len(data) / 5
+	chunkSize := s.Len() / 5
 
-	for i := 0; i < 5; i++ {
+	for i := int64(0); i < 5; i++ {
 		start := i * chunkSize
-		end := start + min(chunkSize, len(data)-start)
+		end := start + chunkSize
 		if end > start {
-			entropy := calculateEntropy(data[start:end])
-			entropies = append(entropies, entropy)
+			entropies = append(entropies, s.SpanEntropy(start, end))
 		}
 	}
 
@@ -576,6 +752,71 @@ func (a *VideoAnalyzer) analyzeBitrateConsistency(data []byte, stats VideoStats)
 	return 0.4
 }
 
+// analyzeFrameRatePattern scores the frame rate. Generators render at
+// mathematically exact rates (24, 30, 60 fps), while cameras and broadcast
+// workflows use the NTSC rates (23.976, 29.97, 59.94) or PAL's 25 and 50.
+func (a *VideoAnalyzer) analyzeFrameRatePattern(stats VideoStats) float64 {
+	fps := stats.EstimatedFPS
+	if fps <= 0 {
+		return 0.5 // Unknown
+	}
+
+	for _, rate := range []float64{24, 30, 60} {
+		if math.Abs(fps-rate) < 0.001 {
+			return 0.8
+		}
+	}
+
+	for _, rate := range []float64{24000.0 / 1001, 30000.0 / 1001, 60000.0 / 1001, 25, 50} {
+		if math.Abs(fps-rate) < 0.01 {
+			return 0.2
+		}
+	}
+
+	return 0.5
+}
+
+// keyframeSliceSize is how much of the first IDR slice is scored as an image.
+const keyframeSliceSize = 4096
+
+// idrStartCode is an Annex B start code followed by the NAL header of an
+// H.264 IDR slice (nal_ref_idc 3, type 5).
+var idrStartCode = []byte{0x00, 0x00, 0x00, 0x01, 0x65}
+
+// analyzeKeyframe scores the first keyframe of an MP4 or MOV with the image
+// analyzer, so AI video whose frames look like AI images is caught here too.
+//
+// This is a rough proxy, not a decoded frame: the slice is entropy-coded
+// macroblock data, so only the image signals that look at raw byte
+// statistics respond to it. The start code has to fall within the buffered
+// header, and most MP4s store length-prefixed rather than Annex B NAL
+// units, so many files have no match and score neutral.
+func (a *VideoAnalyzer) analyzeKeyframe(data []byte, format string) float64 {
+	if format != "mp4" && format != "mov" {
+		return 0.5
+	}
+
+	slice := extractFirstIDRSlice(data)
+	if len(slice) == 0 {
+		return 0.5
+	}
+
+	// Reading from memory can't fail
+	s, _ := readMediaSample(context.Background(), bytes.NewReader(slice), imageHeaderSize, nil)
+	return a.keyframes.analyzeAs(s, "jpeg").AIScore
+}
+
+// extractFirstIDRSlice returns up to keyframeSliceSize bytes following the
+// first H.264 IDR start code in data, or nil if there is none.
+func extractFirstIDRSlice(data []byte) []byte {
+	i := bytes.Index(data, idrStartCode)
+	if i < 0 {
+		return nil
+	}
+	start := i + len(idrStartCode)
+	return data[start:min(start+keyframeSliceSize, len(data))]
+}
+
 // calculateWeightedScore combines signals into final score.
 func (a *VideoAnalyzer) calculateWeightedScore(signals VideoSignals) float64 {
 	w := a.weights
@@ -585,10 +826,13 @@ func (a *VideoAnalyzer) calculateWeightedScore(signals VideoSignals) float64 {
 		signals.AudioPresence*w.AudioPresence +
 		signals.TemporalPattern*w.TemporalPattern +
 		signals.EncodingSignature*w.EncodingSignature +
-		signals.BitrateConsistency*w.BitrateConsistency
+		signals.BitrateConsistency*w.BitrateConsistency +
+		signals.FrameRatePattern*w.FrameRatePattern +
+		signals.KeyframeImageScore*w.KeyframeImageScore
 
 	totalWeight := w.MetadataScore + w.ContainerAnalysis + w.AudioPresence +
-		w.TemporalPattern + w.EncodingSignature + w.BitrateConsistency
+		w.TemporalPattern + w.EncodingSignature + w.BitrateConsistency +
+		w.FrameRatePattern + w.KeyframeImageScore
 
 	if totalWeight > 0 {
 		score /= totalWeight
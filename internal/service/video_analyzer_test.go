@@ -1,6 +1,10 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
 	"testing"
 )
 
@@ -357,10 +361,17 @@ func TestVideoAnalyzerWeights(t *testing.T) {
 	if weights.BitrateConsistency <= 0 {
 		t.Error("BitrateConsistency should be positive")
 	}
+	if weights.FrameRatePattern <= 0 {
+		t.Error("FrameRatePattern should be positive")
+	}
+	if weights.KeyframeImageScore <= 0 {
+		t.Error("KeyframeImageScore should be positive")
+	}
 
 	// Weights should sum to approximately 1
 	sum := weights.MetadataScore + weights.ContainerAnalysis + weights.AudioPresence +
-		weights.TemporalPattern + weights.EncodingSignature + weights.BitrateConsistency
+		weights.TemporalPattern + weights.EncodingSignature + weights.BitrateConsistency +
+		weights.FrameRatePattern + weights.KeyframeImageScore
 
 	if sum < 0.99 || sum > 1.01 {
 		t.Errorf("weights should sum to ~1.0, got %f", sum)
@@ -419,6 +430,8 @@ func TestVideoWeightedScore(t *testing.T) {
 			TemporalPattern:    0.5,
 			EncodingSignature:  0.5,
 			BitrateConsistency: 0.5,
+			FrameRatePattern:   0.5,
+			KeyframeImageScore: 0.5,
 		}
 
 		score := analyzer.calculateWeightedScore(signals)
@@ -436,6 +449,8 @@ func TestVideoWeightedScore(t *testing.T) {
 			TemporalPattern:    0.8,
 			EncodingSignature:  0.7,
 			BitrateConsistency: 0.8,
+			FrameRatePattern:   0.8,
+			KeyframeImageScore: 0.8,
 		}
 
 		score := analyzer.calculateWeightedScore(signals)
@@ -445,3 +460,174 @@ func TestVideoWeightedScore(t *testing.T) {
 		}
 	})
 }
+
+// mp4Atom builds an MP4 atom from a type and payload parts.
+func mp4Atom(atomType string, parts ...[]byte) []byte {
+	var payload []byte
+	for _, p := range parts {
+		payload = append(payload, p...)
+	}
+	atom := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(atom[0:4], uint32(8+len(payload)))
+	copy(atom[4:8], atomType)
+	return append(atom, payload...)
+}
+
+// videoTrak builds a trak atom with the given media timescale and stts
+// (sample_count, sample_delta) entries.
+func videoTrak(handler string, timescale uint32, stts ...uint32) []byte {
+	mdhd := make([]byte, 24) // version 0
+	binary.BigEndian.PutUint32(mdhd[12:16], timescale)
+
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:12], handler)
+
+	entries := make([]byte, 8+4*len(stts))
+	binary.BigEndian.PutUint32(entries[4:8], uint32(len(stts)/2))
+	for i, v := range stts {
+		binary.BigEndian.PutUint32(entries[8+4*i:], v)
+	}
+
+	return mp4Atom("trak",
+		mp4Atom("tkhd", make([]byte, 84)),
+		mp4Atom("mdia",
+			mp4Atom("mdhd", mdhd),
+			mp4Atom("hdlr", hdlr),
+			mp4Atom("minf", mp4Atom("stbl", mp4Atom("stts", entries))),
+		),
+	)
+}
+
+// TestVideoTrackFPS tests frame rate extraction from stts atoms.
+func TestVideoTrackFPS(t *testing.T) {
+	tests := []struct {
+		name string
+		trak []byte
+		want float64
+	}{
+		{"24 fps", videoTrak("vide", 12288, 240, 512), 24},
+		{"23.976 fps", videoTrak("vide", 24000, 240, 1001), 24000.0 / 1001},
+		{"25 fps", videoTrak("vide", 25000, 250, 1000), 25},
+		{"29.97 fps", videoTrak("vide", 30000, 300, 1001), 30000.0 / 1001},
+		{"30 fps with short last frame", videoTrak("vide", 600, 299, 20, 1, 20), 30},
+		{"variable frame rate", videoTrak("vide", 600, 10, 20, 10, 40), 20},
+		{"audio track", videoTrak("soun", 44100, 100, 1024), 0},
+		{"zero timescale", videoTrak("vide", 0, 240, 512), 0},
+		{"no samples", videoTrak("vide", 12288), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := videoTrackFPS(tt.trak); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("videoTrackFPS() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("parsed from moov", func(t *testing.T) {
+		data := mp4Atom("ftyp", []byte("isom\x00\x00\x00\x00isom"))
+		data = append(data, mp4Atom("moov",
+			mp4Atom("mvhd", make([]byte, 100)),
+			videoTrak("soun", 48000, 100, 1024),
+			videoTrak("vide", 15360, 120, 256),
+		)...)
+
+		_, stats := NewVideoAnalyzer().analyzeMP4(data)
+		if stats.EstimatedFPS != 60 {
+			t.Errorf("EstimatedFPS = %f, want 60", stats.EstimatedFPS)
+		}
+	})
+}
+
+// TestFrameRatePattern tests frame rate scoring.
+func TestFrameRatePattern(t *testing.T) {
+	analyzer := NewVideoAnalyzer()
+
+	tests := []struct {
+		fps  float64
+		want float64
+	}{
+		{24, 0.8},
+		{30, 0.8},
+		{60, 0.8},
+		{24000.0 / 1001, 0.2},
+		{25, 0.2},
+		{30000.0 / 1001, 0.2},
+		{15, 0.5},
+		{0, 0.5},
+	}
+
+	for _, tt := range tests {
+		if got := analyzer.analyzeFrameRatePattern(VideoStats{EstimatedFPS: tt.fps}); got != tt.want {
+			t.Errorf("analyzeFrameRatePattern(%.3f fps) = %v, want %v", tt.fps, got, tt.want)
+		}
+	}
+}
+
+// TestExtractFirstIDRSlice tests finding the first H.264 IDR slice.
+func TestExtractFirstIDRSlice(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xAB}, 5000)
+
+	t.Run("takes the bytes after the start code", func(t *testing.T) {
+		// A non-IDR slice (type 1) comes first and is skipped
+		data := append([]byte("mdat\x00\x00\x00\x01\x41\x9a"), idrStartCode...)
+		data = append(data, payload...)
+
+		slice := extractFirstIDRSlice(data)
+		if len(slice) != keyframeSliceSize || !bytes.Equal(slice, payload[:keyframeSliceSize]) {
+			t.Errorf("got %d bytes, want the first %d payload bytes", len(slice), keyframeSliceSize)
+		}
+	})
+
+	t.Run("short slice at end of data", func(t *testing.T) {
+		data := append(append([]byte{}, idrStartCode...), payload[:100]...)
+		if slice := extractFirstIDRSlice(data); len(slice) != 100 {
+			t.Errorf("got %d bytes, want 100", len(slice))
+		}
+	})
+
+	t.Run("no IDR slice", func(t *testing.T) {
+		if slice := extractFirstIDRSlice([]byte("\x00\x00\x00\x01\x41\x9a\x00\x00\x01\x65")); slice != nil {
+			t.Errorf("got %d bytes, want nil", len(slice))
+		}
+	})
+}
+
+// TestKeyframeImageScore tests scoring the first keyframe as an image.
+func TestKeyframeImageScore(t *testing.T) {
+	analyzer := NewVideoAnalyzer()
+	ftyp := mp4Atom("ftyp", []byte("isom\x00\x00\x00\x00isom"))
+
+	// Smooth, low-noise slice data, the way a generated frame compresses
+	smooth := make([]byte, keyframeSliceSize)
+	for i := range smooth {
+		smooth[i] = byte(i / 64)
+	}
+	mp4 := append(ftyp, mp4Atom("mdat", idrStartCode, smooth)...)
+
+	t.Run("matches the image analyzer", func(t *testing.T) {
+		s, _ := readMediaSample(context.Background(), bytes.NewReader(smooth), imageHeaderSize, nil)
+		want := NewImageAnalyzer().analyzeAs(s, "jpeg").AIScore
+
+		result := analyzer.Analyze(mp4)
+		if result.Signals.KeyframeImageScore != want {
+			t.Errorf("KeyframeImageScore = %v, want image score %v", result.Signals.KeyframeImageScore, want)
+		}
+		if want == 0.5 {
+			t.Error("expected the slice to score away from neutral")
+		}
+	})
+
+	t.Run("neutral without a keyframe", func(t *testing.T) {
+		data := append(ftyp, mp4Atom("mdat", smooth)...)
+		if score := analyzer.Analyze(data).Signals.KeyframeImageScore; score != 0.5 {
+			t.Errorf("KeyframeImageScore = %v, want 0.5", score)
+		}
+	})
+
+	t.Run("neutral for other containers", func(t *testing.T) {
+		if score := analyzer.analyzeKeyframe(mp4, "webm"); score != 0.5 {
+			t.Errorf("KeyframeImageScore = %v, want 0.5", score)
+		}
+	})
+}
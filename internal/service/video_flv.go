@@ -0,0 +1,259 @@
+package service
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+)
+
+// =============================================================================
+// FLV Analysis
+// =============================================================================
+//
+// FLV (Flash Video) is still served by legacy streaming platforms and by
+// some AI video tools that target web delivery. The file is a 9-byte header
+// followed by tags, each preceded by the size of the one before:
+//
+//	header:  "FLV" | version | flags (4 = audio, 1 = video) | data offset (u32)
+//	tag:     type (8 audio, 9 video, 18 script data) | size (u24) |
+//	         timestamp (u24 + u8 extension) | stream ID (u24) | data
+//
+// The first script data tag is usually onMetaData: an AMF0-encoded name
+// followed by an ECMA array of properties such as duration, width, height,
+// videocodecid, audiocodecid and encoder. Muxers that don't write it still
+// set the header flags, and the tags themselves show what streams exist.
+//
+// =============================================================================
+
+// FLV tag types.
+const (
+	flvTagAudio  = 8
+	flvTagVideo  = 9
+	flvTagScript = 18
+)
+
+// flvTagHeaderSize is the size of a tag header, before its data.
+const flvTagHeaderSize = 11
+
+// flvKeyframe is the frame type (high nibble of a video tag's first byte)
+// of a keyframe.
+const flvKeyframe = 1
+
+// AMF0 value type markers.
+const (
+	amf0Number      = 0x00
+	amf0Boolean     = 0x01
+	amf0String      = 0x02
+	amf0Object      = 0x03
+	amf0Null        = 0x05
+	amf0Undefined   = 0x06
+	amf0ECMAArray   = 0x08
+	amf0ObjectEnd   = 0x09
+	amf0StrictArray = 0x0A
+	amf0Date        = 0x0B
+	amf0LongString  = 0x0C
+)
+
+// maxAMF0Depth bounds nesting so crafted metadata can't recurse forever.
+const maxAMF0Depth = 8
+
+// analyzeFLV extracts metadata from FLV containers.
+func (a *VideoAnalyzer) analyzeFLV(data []byte) (VideoMetadata, VideoStats) {
+	meta := VideoMetadata{Format: "flv"}
+	stats := VideoStats{FileSize: int64(len(data))}
+
+	if len(data) < 9 {
+		return meta, stats
+	}
+	flags := data[4]
+	meta.HasAudio = flags&0x04 != 0
+	meta.HasVideo = flags&0x01 != 0
+
+	var props map[string]any
+	sawAudio, sawVideo := false, false
+
+	// The first PreviousTagSize follows the header
+	offset := int(binary.BigEndian.Uint32(data[5:9])) + 4
+	for offset >= 13 && offset+flvTagHeaderSize <= len(data) {
+		tagType := data[offset] & 0x1F // The upper bits are reserved/filter flags
+		size := int(data[offset+1])<<16 | int(data[offset+2])<<8 | int(data[offset+3])
+		body := data[offset+flvTagHeaderSize : min(offset+flvTagHeaderSize+size, len(data))]
+
+		switch tagType {
+		case flvTagAudio:
+			sawAudio = true
+		case flvTagVideo:
+			sawVideo = true
+			if len(body) > 0 && body[0]>>4 == flvKeyframe {
+				stats.KeyframeCount++
+			}
+		case flvTagScript:
+			if props == nil {
+				props = parseFLVMetadata(body)
+			}
+		}
+		stats.ChunkCount++
+
+		offset += flvTagHeaderSize + size + 4
+	}
+
+	// Metadata, when present, is more reliable than the header flags,
+	// which some muxers set regardless of the streams written
+	if props != nil {
+		if _, ok := props["videocodecid"]; ok {
+			meta.HasVideo = true
+		}
+		_, meta.HasAudio = props["audiocodecid"]
+	}
+	meta.HasAudio = meta.HasAudio || sawAudio
+	meta.HasVideo = meta.HasVideo || sawVideo
+
+	meta.Duration = amf0Float(props["duration"])
+	stats.Width = int(amf0Float(props["width"]))
+	stats.Height = int(amf0Float(props["height"]))
+	stats.EstimatedFPS = amf0Float(props["framerate"])
+	stats.VideoBitrate = int(amf0Float(props["videodatarate"]))
+	stats.AudioBitrate = int(amf0Float(props["audiodatarate"]))
+
+	if s, ok := props["creationdate"].(string); ok {
+		meta.CreationTime = strings.TrimSpace(s)
+	}
+	for _, key := range []string{"encoder", "metadatacreator", "creator"} {
+		s, ok := props[key].(string)
+		if !ok || s == "" {
+			continue
+		}
+		if containsAIMarker(s) {
+			meta.IsAIMarked = true
+		}
+		if meta.EncoderName == "" {
+			meta.EncoderName = extractEncoder(s)
+			if meta.EncoderName == "" {
+				meta.EncoderName = s
+			}
+		}
+	}
+
+	return meta, stats
+}
+
+// parseFLVMetadata decodes an onMetaData script tag into its properties.
+// Returns nil if the tag isn't onMetaData or can't be decoded.
+func parseFLVMetadata(body []byte) map[string]any {
+	d := amf0Decoder{data: body}
+	if name, ok := d.value(0).(string); !ok || name != "onMetaData" {
+		return nil
+	}
+	props, _ := d.value(0).(map[string]any)
+	return props
+}
+
+// amf0Float returns v as a float64 if it's an AMF0 number, else 0.
+func amf0Float(v any) float64 {
+	f, ok := v.(float64)
+	if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
+
+// amf0Decoder reads AMF0 values from data. Numbers decode to float64,
+// booleans to bool, strings to string, objects and ECMA arrays to
+// map[string]any and strict arrays to []any; anything else, and truncated
+// values, decode to nil.
+type amf0Decoder struct {
+	data []byte
+	pos  int
+	err  bool
+}
+
+// next returns the next n bytes, or nil (and marks the decoder failed) if
+// there aren't that many.
+func (d *amf0Decoder) next(n int) []byte {
+	if d.err || n < 0 || d.pos+n > len(d.data) {
+		d.err = true
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+// string reads a string with a 16-bit length.
+func (d *amf0Decoder) string() string {
+	n := d.next(2)
+	if n == nil {
+		return ""
+	}
+	return string(d.next(int(binary.BigEndian.Uint16(n))))
+}
+
+// value reads one typed value.
+func (d *amf0Decoder) value(depth int) any {
+	marker := d.next(1)
+	if marker == nil || depth > maxAMF0Depth {
+		d.err = true
+		return nil
+	}
+
+	switch marker[0] {
+	case amf0Number:
+		if b := d.next(8); b != nil {
+			return math.Float64frombits(binary.BigEndian.Uint64(b))
+		}
+	case amf0Boolean:
+		if b := d.next(1); b != nil {
+			return b[0] != 0
+		}
+	case amf0String:
+		return d.string()
+	case amf0LongString:
+		if n := d.next(4); n != nil {
+			return string(d.next(int(binary.BigEndian.Uint32(n))))
+		}
+	case amf0Object:
+		return d.properties(depth)
+	case amf0ECMAArray:
+		// The count is only a hint; the array ends like an object
+		d.next(4)
+		return d.properties(depth)
+	case amf0StrictArray:
+		n := d.next(4)
+		if n == nil {
+			return nil
+		}
+		var values []any
+		for i := uint32(0); i < binary.BigEndian.Uint32(n) && !d.err; i++ {
+			values = append(values, d.value(depth+1))
+		}
+		return values
+	case amf0Date:
+		// Milliseconds since the epoch, then a timezone nobody sets
+		if b := d.next(10); b != nil {
+			return math.Float64frombits(binary.BigEndian.Uint64(b))
+		}
+	case amf0Null, amf0Undefined:
+		return nil
+	default:
+		// References, XML and typed objects don't appear in FLV metadata
+		d.err = true
+	}
+	return nil
+}
+
+// properties reads name/value pairs up to the object end marker.
+func (d *amf0Decoder) properties(depth int) map[string]any {
+	props := make(map[string]any)
+	for !d.err {
+		name := d.string()
+		if name == "" {
+			// An empty name followed by the end marker closes the object
+			if end := d.next(1); end == nil || end[0] != amf0ObjectEnd {
+				d.err = true
+			}
+			break
+		}
+		props[name] = d.value(depth + 1)
+	}
+	return props
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// flvFile builds an FLV file with the given header flags and tags.
+func flvFile(flags byte, tags ...[]byte) []byte {
+	data := []byte{'F', 'L', 'V', 0x01, flags, 0, 0, 0, 9, 0, 0, 0, 0}
+	for _, tag := range tags {
+		data = append(data, tag...)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(tag)))
+	}
+	return data
+}
+
+// flvTag builds a tag of tagType holding body.
+func flvTag(tagType byte, body []byte) []byte {
+	size := len(body)
+	tag := []byte{tagType, byte(size >> 16), byte(size >> 8), byte(size), 0, 0, 0, 0, 0, 0, 0}
+	return append(tag, body...)
+}
+
+func amf0NumberValue(f float64) []byte {
+	return binary.BigEndian.AppendUint64([]byte{amf0Number}, math.Float64bits(f))
+}
+
+func amf0StringValue(s string) []byte {
+	b := binary.BigEndian.AppendUint16([]byte{amf0String}, uint16(len(s)))
+	return append(b, s...)
+}
+
+// onMetaData builds a script data body with an ECMA array of props, given
+// as name, encoded value pairs.
+func onMetaData(props ...any) []byte {
+	body := amf0StringValue("onMetaData")
+	body = append(body, amf0ECMAArray)
+	body = binary.BigEndian.AppendUint32(body, uint32(len(props)/2))
+	for i := 0; i < len(props); i += 2 {
+		name := props[i].(string)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(name)))
+		body = append(body, name...)
+		body = append(body, props[i+1].([]byte)...)
+	}
+	return append(body, 0, 0, amf0ObjectEnd)
+}
+
+// TestAnalyzeFLV tests FLV header, tag and metadata parsing.
+func TestAnalyzeFLV(t *testing.T) {
+	analyzer := NewVideoAnalyzer()
+	keyframe := flvTag(flvTagVideo, []byte{0x17, 0x01})
+	interframe := flvTag(flvTagVideo, []byte{0x27, 0x01})
+	audio := flvTag(flvTagAudio, []byte{0xAF, 0x01})
+
+	t.Run("metadata", func(t *testing.T) {
+		data := flvFile(0x05,
+			flvTag(flvTagScript, onMetaData(
+				"duration", amf0NumberValue(12.5),
+				"width", amf0NumberValue(1280),
+				"height", amf0NumberValue(720),
+				"framerate", amf0NumberValue(30),
+				"videocodecid", amf0NumberValue(7),
+				"audiocodecid", amf0NumberValue(10),
+				"videodatarate", amf0NumberValue(2500),
+				"audiodatarate", amf0NumberValue(128),
+				"stereo", []byte{amf0Boolean, 1},
+				"encoder", amf0StringValue("Lavf58.76.100"),
+			)),
+			keyframe, audio, interframe, keyframe,
+		)
+
+		meta, stats := analyzer.analyzeFLV(data)
+		if meta.Format != "flv" || !meta.HasVideo || !meta.HasAudio {
+			t.Errorf("meta = %+v, want flv with video and audio", meta)
+		}
+		if meta.Duration != 12.5 {
+			t.Errorf("Duration = %v, want 12.5", meta.Duration)
+		}
+		if meta.EncoderName != "ffmpeg" {
+			t.Errorf("EncoderName = %q, want ffmpeg", meta.EncoderName)
+		}
+		if stats.Width != 1280 || stats.Height != 720 || stats.EstimatedFPS != 30 {
+			t.Errorf("stats = %+v, want 1280x720 at 30 fps", stats)
+		}
+		if stats.VideoBitrate != 2500 || stats.AudioBitrate != 128 {
+			t.Errorf("bitrates = %d/%d, want 2500/128", stats.VideoBitrate, stats.AudioBitrate)
+		}
+		if stats.KeyframeCount != 2 || stats.ChunkCount != 5 {
+			t.Errorf("KeyframeCount = %d, ChunkCount = %d; want 2, 5", stats.KeyframeCount, stats.ChunkCount)
+		}
+	})
+
+	t.Run("no audiocodecid means no audio", func(t *testing.T) {
+		// Some muxers set the audio flag regardless
+		data := flvFile(0x05, flvTag(flvTagScript, onMetaData(
+			"videocodecid", amf0NumberValue(7),
+		)), keyframe)
+
+		meta, _ := analyzer.analyzeFLV(data)
+		if meta.HasAudio || !meta.HasVideo {
+			t.Errorf("HasAudio = %v, HasVideo = %v; want false, true", meta.HasAudio, meta.HasVideo)
+		}
+	})
+
+	t.Run("without metadata uses flags and tags", func(t *testing.T) {
+		meta, _ := analyzer.analyzeFLV(flvFile(0x01, keyframe, audio))
+		if !meta.HasAudio || !meta.HasVideo {
+			t.Errorf("HasAudio = %v, HasVideo = %v; want true, true", meta.HasAudio, meta.HasVideo)
+		}
+
+		meta, _ = analyzer.analyzeFLV(flvFile(0x01, keyframe))
+		if meta.HasAudio {
+			t.Error("HasAudio = true for a video-only file")
+		}
+	})
+
+	t.Run("AI encoder", func(t *testing.T) {
+		data := flvFile(0x01, flvTag(flvTagScript, onMetaData(
+			"metadatacreator", amf0StringValue("Runway Gen-3"),
+		)))
+
+		meta, _ := analyzer.analyzeFLV(data)
+		if !meta.IsAIMarked || meta.EncoderName != "Runway" {
+			t.Errorf("meta = %+v, want AI-marked Runway", meta)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := flvFile(0x05, flvTag(flvTagScript, onMetaData(
+			"duration", amf0NumberValue(3),
+			"width", amf0NumberValue(640),
+		)))
+		for n := 0; n < len(data); n++ {
+			analyzer.analyzeFLV(data[:n])
+		}
+	})
+
+	t.Run("routed by Analyze", func(t *testing.T) {
+		data := flvFile(0x05, flvTag(flvTagScript, onMetaData(
+			"duration", amf0NumberValue(8),
+			"audiocodecid", amf0NumberValue(10),
+		)), keyframe)
+
+		result := analyzer.Analyze(data)
+		if result.Metadata.Format != "flv" || result.Metadata.Duration != 8 || !result.Metadata.HasAudio {
+			t.Errorf("Metadata = %+v, want flv, 8s, with audio", result.Metadata)
+		}
+	})
+}
+
+// TestAMF0Decoder tests decoding of the value types found in FLV metadata.
+func TestAMF0Decoder(t *testing.T) {
+	data := amf0NumberValue(2)
+	data = append(data, amf0Null)
+	data = append(data, amf0StrictArray, 0, 0, 0, 2)
+	data = append(data, amf0NumberValue(1)...)
+	data = append(data, amf0StringValue("x")...)
+	data = append(data, amf0Object, 0, 1, 'k')
+	data = append(data, amf0StringValue("v")...)
+	data = append(data, 0, 0, amf0ObjectEnd)
+
+	d := amf0Decoder{data: data}
+	if got := d.value(0); got != 2.0 {
+		t.Errorf("number = %v, want 2", got)
+	}
+	if got := d.value(0); got != nil {
+		t.Errorf("null = %v, want nil", got)
+	}
+	if got, ok := d.value(0).([]any); !ok || len(got) != 2 || got[1] != "x" {
+		t.Errorf("strict array = %v, want [1 x]", got)
+	}
+	if got, ok := d.value(0).(map[string]any); !ok || got["k"] != "v" {
+		t.Errorf("object = %v, want map[k:v]", got)
+	}
+	if d.err {
+		t.Error("decoder failed on valid input")
+	}
+
+	// Deep nesting stops at maxAMF0Depth instead of recursing
+	var nested []byte
+	for i := 0; i < 100; i++ {
+		nested = append(nested, amf0StrictArray, 0, 0, 0, 1)
+	}
+	d = amf0Decoder{data: nested}
+	d.value(0)
+	if !d.err {
+		t.Error("expected deep nesting to fail")
+	}
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// =============================================================================
+// WebP Lossless Analysis
+// =============================================================================
+//
+// Generators increasingly save full-quality output as lossless WebP (a VP8L
+// chunk). VP8L predicts each pixel from its neighbours and Huffman-codes the
+// residuals, so the prefix code for the green channel is a compact histogram
+// of them: a symbol with an n-bit code occurs about 2^-n of the time. Camera
+// photos have residuals clustered near zero and a peaked code; AI output
+// spreads them out and gets flatter code lengths.
+//
+// Reaching that code means parsing the bitstream up to the main image's
+// first prefix code group: the header, any transforms (whose sub-images are
+// read just far enough to skip them), the color cache and the meta prefix
+// image. Pixel values are never reconstructed.
+//
+// =============================================================================
+
+// errVP8LInvalid is returned for a VP8L bitstream that can't be parsed.
+var errVP8LInvalid = errors.New("vp8l: invalid bitstream")
+
+// vp8lSignature is the first byte of a VP8L bitstream.
+const vp8lSignature = 0x2f
+
+// vp8lCodeLengthOrder is the order code length code lengths are stored in.
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// analyzeWebPLossless scores how flat the green channel's prefix code is in a
+// lossless WebP. Flat code lengths are AI-like; unparseable data is neutral.
+func (a *ImageAnalyzer) analyzeWebPLossless(data []byte) float64 {
+	green, err := readVP8LGreenCodeLengths(data)
+	if err != nil {
+		return 0.5
+	}
+
+	flatness, ok := prefixCodeFlatness(green[:256])
+	if !ok {
+		return 0.5 // No literals to judge by
+	}
+
+	// Photos' green residuals take roughly 3-5 bits per pixel; 7 and up is
+	// close to noise
+	return math.Max(0, math.Min(1, (flatness-0.5)/0.35))
+}
+
+// webpLosslessStats reads dimensions and channels from a VP8L header.
+func webpLosslessStats(data []byte) ImageStats {
+	bitstream := findWebPChunk(data, "VP8L")
+	if len(bitstream) < 5 || bitstream[0] != vp8lSignature {
+		return ImageStats{}
+	}
+
+	header := binary.LittleEndian.Uint32(bitstream[1:5])
+	stats := ImageStats{
+		Width:         int(header&0x3fff) + 1,
+		Height:        int(header>>14&0x3fff) + 1,
+		BitDepth:      8,
+		ColorChannels: 3,
+	}
+	if header>>28&1 == 1 {
+		stats.ColorChannels = 4
+	}
+	return stats
+}
+
+// findWebPChunk returns the payload of the first chunk with the given
+// FourCC in a RIFF WebP file, or nil.
+func findWebPChunk(data []byte, fourCC string) []byte {
+	for i := 12; i+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		start := i + 8
+		if string(data[i:i+4]) == fourCC {
+			return data[start:min(start+size, len(data))]
+		}
+		if size < 0 || size > len(data) {
+			return nil
+		}
+		i = start + size + size&1 // Chunks are padded to even sizes
+	}
+	return nil
+}
+
+// readVP8LGreenCodeLengths parses a lossless WebP up to the main image's
+// first prefix code group and returns the green code's lengths.
+func readVP8LGreenCodeLengths(data []byte) ([]int, error) {
+	bitstream := findWebPChunk(data, "VP8L")
+	if len(bitstream) < 5 || bitstream[0] != vp8lSignature {
+		return nil, errVP8LInvalid
+	}
+
+	r := &vp8lBitReader{data: bitstream[1:]}
+	width := int(r.read(14)) + 1
+	height := int(r.read(14)) + 1
+	r.read(1) // Alpha hint
+	if r.read(3) != 0 {
+		return nil, errVP8LInvalid // Unknown version
+	}
+
+	// Transforms, each used at most once. Color indexing packs several
+	// pixels into one, narrowing the image for later transforms.
+	xsize := width
+	var used [4]bool
+	for r.err == nil && r.read(1) == 1 {
+		transform := r.read(2)
+		if used[transform] {
+			return nil, errVP8LInvalid
+		}
+		used[transform] = true
+
+		switch transform {
+		case 0, 1: // Predictor, color
+			bits := int(r.read(3)) + 2
+			skipVP8LSubImage(r, vp8lSubSampleSize(xsize, bits), vp8lSubSampleSize(height, bits))
+		case 2: // Subtract green has no data
+		case 3: // Color indexing
+			colors := int(r.read(8)) + 1
+			skipVP8LSubImage(r, colors, 1)
+
+			switch {
+			case colors <= 2:
+				xsize = vp8lSubSampleSize(xsize, 3)
+			case colors <= 4:
+				xsize = vp8lSubSampleSize(xsize, 2)
+			case colors <= 16:
+				xsize = vp8lSubSampleSize(xsize, 1)
+			}
+		}
+	}
+
+	cacheSize := readVP8LColorCache(r)
+	if r.read(1) == 1 {
+		// Meta prefix codes: an entropy image picks a code group per block.
+		// The first group follows it either way.
+		bits := int(r.read(3)) + 2
+		skipVP8LSubImage(r, vp8lSubSampleSize(xsize, bits), vp8lSubSampleSize(height, bits))
+	}
+
+	_, lengths := readVP8LPrefixCode(r, 256+24+cacheSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return lengths, nil
+}
+
+// prefixCodeFlatness returns the entropy of the distribution implied by
+// code lengths, relative to a flat distribution over all of them. False if
+// no symbol has a code.
+func prefixCodeFlatness(lengths []int) (float64, bool) {
+	total := 0.0
+	for _, l := range lengths {
+		if l > 0 {
+			total += math.Exp2(-float64(l))
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	entropy := 0.0
+	for _, l := range lengths {
+		if l > 0 {
+			p := math.Exp2(-float64(l)) / total
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy / math.Log2(float64(len(lengths))), true
+}
+
+// vp8lSubSampleSize is size divided by 2^bits, rounded up.
+func vp8lSubSampleSize(size, bits int) int {
+	return (size + 1<<bits - 1) >> bits
+}
+
+// readVP8LColorCache reads the color cache info and returns the cache size.
+func readVP8LColorCache(r *vp8lBitReader) int {
+	if r.read(1) == 0 {
+		return 0
+	}
+	bits := int(r.read(4))
+	if bits < 1 || bits > 11 {
+		r.fail(errVP8LInvalid)
+		return 0
+	}
+	return 1 << bits
+}
+
+// skipVP8LSubImage reads past an entropy-coded sub-image (a transform's
+// data or the meta prefix image).
+func skipVP8LSubImage(r *vp8lBitReader, width, height int) {
+	cacheSize := readVP8LColorCache(r)
+
+	// Green (plus length and cache symbols), red, blue, alpha, distance
+	alphabets := [5]int{256 + 24 + cacheSize, 256, 256, 256, 40}
+	var codes [5]*vp8lPrefixCode
+	for i, size := range alphabets {
+		codes[i], _ = readVP8LPrefixCode(r, size)
+		if r.err != nil {
+			return
+		}
+	}
+
+	for pixels := width * height; pixels > 0 && r.err == nil; {
+		green := codes[0].decode(r)
+		switch {
+		case green < 256: // Literal
+			codes[1].decode(r)
+			codes[2].decode(r)
+			codes[3].decode(r)
+			pixels--
+		case green < 256+24: // Backward reference
+			length := readVP8LPrefixValue(r, green-256)
+			readVP8LPrefixValue(r, codes[4].decode(r))
+			pixels -= length
+		default: // Color cache
+			pixels--
+		}
+	}
+}
+
+// readVP8LPrefixValue reads an LZ77 length or distance from its prefix
+// symbol and extra bits.
+func readVP8LPrefixValue(r *vp8lBitReader, prefix int) int {
+	if prefix < 4 {
+		return prefix + 1
+	}
+	extraBits := (prefix - 2) >> 1
+	offset := (2 + prefix&1) << extraBits
+	return offset + int(r.read(extraBits)) + 1
+}
+
+// readVP8LPrefixCode reads a simple or normal prefix code over
+// alphabetSize symbols and returns it with its code lengths.
+func readVP8LPrefixCode(r *vp8lBitReader, alphabetSize int) (*vp8lPrefixCode, []int) {
+	lengths := make([]int, alphabetSize)
+
+	if r.read(1) == 1 {
+		// Simple code: one or two symbols listed directly
+		symbols := int(r.read(1)) + 1
+		firstBits := 1
+		if r.read(1) == 1 {
+			firstBits = 8
+		}
+		first := int(r.read(firstBits))
+		second := -1
+		if symbols == 2 {
+			second = int(r.read(8))
+		}
+		if first >= alphabetSize || second >= alphabetSize {
+			r.fail(errVP8LInvalid)
+			return nil, nil
+		}
+		lengths[first] = 1
+		if second >= 0 {
+			lengths[second] = 1
+		}
+		return newVP8LPrefixCode(r, lengths), lengths
+	}
+
+	// Normal code: the code lengths are themselves prefix coded
+	var codeLengthLengths [19]int
+	count := 4 + int(r.read(4))
+	for i := 0; i < count; i++ {
+		codeLengthLengths[vp8lCodeLengthOrder[i]] = int(r.read(3))
+	}
+	codeLengthCode := newVP8LPrefixCode(r, codeLengthLengths[:])
+
+	maxSymbols := alphabetSize
+	if r.read(1) == 1 {
+		lengthBits := 2 + 2*int(r.read(3))
+		maxSymbols = 2 + int(r.read(lengthBits))
+		if maxSymbols > alphabetSize {
+			r.fail(errVP8LInvalid)
+		}
+	}
+
+	prev := 8
+	for symbol := 0; symbol < alphabetSize && maxSymbols > 0 && r.err == nil; maxSymbols-- {
+		length := codeLengthCode.decode(r)
+		if length < 16 {
+			lengths[symbol] = length
+			symbol++
+			if length != 0 {
+				prev = length
+			}
+			continue
+		}
+
+		repeat, value := 0, 0
+		switch length {
+		case 16:
+			repeat, value = 3+int(r.read(2)), prev
+		case 17:
+			repeat = 3 + int(r.read(3))
+		default:
+			repeat = 11 + int(r.read(7))
+		}
+		if symbol+repeat > alphabetSize {
+			r.fail(errVP8LInvalid)
+			break
+		}
+		for ; repeat > 0; repeat-- {
+			lengths[symbol] = value
+			symbol++
+		}
+	}
+
+	return newVP8LPrefixCode(r, lengths), lengths
+}
+
+// vp8lPrefixCode is a canonical prefix code, decoded a bit at a time.
+type vp8lPrefixCode struct {
+	// counts[n] is the number of symbols with n-bit codes
+	counts [16]int
+
+	// symbols are ordered by code length, then symbol
+	symbols []int
+}
+
+// newVP8LPrefixCode builds the canonical code for lengths. A code with a
+// single symbol takes no bits to decode.
+func newVP8LPrefixCode(r *vp8lBitReader, lengths []int) *vp8lPrefixCode {
+	c := &vp8lPrefixCode{}
+	for _, l := range lengths {
+		if l > 15 {
+			r.fail(errVP8LInvalid)
+			return c
+		}
+		c.counts[l]++
+	}
+	for l := 1; l < 16; l++ {
+		for symbol, sl := range lengths {
+			if sl == l {
+				c.symbols = append(c.symbols, symbol)
+			}
+		}
+	}
+	if len(c.symbols) == 0 {
+		r.fail(errVP8LInvalid)
+	}
+	return c
+}
+
+// decode reads one symbol.
+func (c *vp8lPrefixCode) decode(r *vp8lBitReader) int {
+	if len(c.symbols) == 1 {
+		return c.symbols[0]
+	}
+
+	code, first, index := 0, 0, 0
+	for l := 1; l < 16 && r.err == nil; l++ {
+		code |= int(r.read(1))
+		count := c.counts[l]
+		if code-first < count {
+			return c.symbols[index+code-first]
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	r.fail(errVP8LInvalid)
+	return 0
+}
+
+// vp8lBitReader reads VP8L's least-significant-bit-first bitstream. After
+// an error every read returns 0; check err once a section is read.
+type vp8lBitReader struct {
+	data []byte
+	pos  int // In bits
+	err  error
+}
+
+// read returns the next n bits (n <= 32).
+func (r *vp8lBitReader) read(n int) uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos+n > len(r.data)*8 {
+		r.fail(errVP8LInvalid)
+		return 0
+	}
+
+	var v uint32
+	for i := 0; i < n; i++ {
+		v |= uint32(r.data[r.pos>>3]>>(r.pos&7)&1) << i
+		r.pos++
+	}
+	return v
+}
+
+// fail records the first error.
+func (r *vp8lBitReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// vp8lBitWriter writes a VP8L bitstream, least significant bit first.
+type vp8lBitWriter struct {
+	data []byte
+	n    int
+}
+
+func (w *vp8lBitWriter) write(v uint32, bits int) {
+	for i := 0; i < bits; i++ {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		if v>>i&1 == 1 {
+			w.data[w.n/8] |= 1 << (w.n % 8)
+		}
+		w.n++
+	}
+}
+
+// writeSymbol writes symbol's canonical code for lengths, most significant
+// bit first.
+func (w *vp8lBitWriter) writeSymbol(lengths []int, symbol int) {
+	var counts [16]int
+	for _, l := range lengths {
+		counts[l]++
+	}
+	counts[0] = 0
+
+	var next [16]int
+	code := 0
+	for l := 1; l < 16; l++ {
+		code = (code + counts[l-1]) << 1
+		next[l] = code
+	}
+	for s := 0; s < symbol; s++ {
+		if lengths[s] == lengths[symbol] {
+			next[lengths[symbol]]++
+		}
+	}
+
+	for i := lengths[symbol] - 1; i >= 0; i-- {
+		w.write(uint32(next[lengths[symbol]]>>i&1), 1)
+	}
+}
+
+// writeNormalCode writes lengths as a normal prefix code, giving each
+// distinct length an equal-length code length code.
+func (w *vp8lBitWriter) writeNormalCode(lengths []int) {
+	var used [19]bool
+	distinct := 0
+	for _, l := range lengths {
+		if !used[l] {
+			used[l] = true
+			distinct++
+		}
+	}
+	bits := 1
+	for 1<<bits < distinct {
+		bits++
+	}
+
+	codeLengthLengths := make([]int, 19)
+	count := 4
+	for i, symbol := range vp8lCodeLengthOrder {
+		if used[symbol] {
+			codeLengthLengths[symbol] = bits
+			count = max(count, i+1)
+		}
+	}
+
+	w.write(0, 1) // Not a simple code
+	w.write(uint32(count-4), 4)
+	for _, symbol := range vp8lCodeLengthOrder[:count] {
+		w.write(uint32(codeLengthLengths[symbol]), 3)
+	}
+	w.write(0, 1) // No max symbol
+	for _, l := range lengths {
+		w.writeSymbol(codeLengthLengths, l)
+	}
+}
+
+// testWebPLossless builds a 64x64 lossless WebP whose main image's green
+// code has the given lengths for the 256 literals. A predictor transform
+// puts a sub-image ahead of it for the parser to skip.
+func testWebPLossless(literals []int) []byte {
+	w := &vp8lBitWriter{}
+	w.write(63, 14) // Width - 1
+	w.write(63, 14) // Height - 1
+	w.write(0, 1)   // Alpha hint
+	w.write(0, 3)   // Version
+
+	w.write(1, 1) // Subtract green
+	w.write(2, 2)
+	w.write(1, 1) // Predictor with 16x16 blocks: a 4x4 sub-image
+	w.write(0, 2)
+	w.write(2, 3)
+	w.write(0, 1) // No color cache
+	for i := 0; i < 5; i++ {
+		// Simple codes of symbol 0 take no bits per pixel
+		w.write(1, 1)
+		w.write(0, 1)
+		w.write(0, 1)
+		w.write(0, 1)
+	}
+	w.write(0, 1) // No more transforms
+
+	w.write(0, 1) // No color cache
+	w.write(0, 1) // No meta prefix codes
+	w.writeNormalCode(append(literals, make([]int, 24)...))
+
+	bitstream := append([]byte{vp8lSignature}, w.data...)
+	if len(bitstream)%2 == 1 {
+		bitstream = append(bitstream, 0)
+	}
+
+	data := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(bitstream)+12))
+	binary.LittleEndian.PutUint32(data[16:], uint32(len(bitstream)))
+	return append(data, bitstream...)
+}
+
+// TestAnalyzeWebPLossless tests scoring the green prefix code's flatness.
+func TestAnalyzeWebPLossless(t *testing.T) {
+	analyzer := NewImageAnalyzer()
+
+	flat := make([]int, 256)
+	for i := range flat {
+		flat[i] = 8
+	}
+
+	// Residuals clustered around zero, like a photo's
+	peaked := make([]int, 256)
+	peaked[0], peaked[1], peaked[255], peaked[2], peaked[254] = 1, 2, 3, 4, 4
+
+	t.Run("flat code", func(t *testing.T) {
+		if score := analyzer.analyzeWebPLossless(testWebPLossless(flat)); score != 1 {
+			t.Errorf("score = %v, want 1", score)
+		}
+	})
+
+	t.Run("peaked code", func(t *testing.T) {
+		if score := analyzer.analyzeWebPLossless(testWebPLossless(peaked)); score != 0 {
+			t.Errorf("score = %v, want 0", score)
+		}
+	})
+
+	t.Run("unparseable data is neutral", func(t *testing.T) {
+		data := testWebPLossless(flat)
+		for _, bad := range [][]byte{data[:len(data)-20], data[:24], []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")} {
+			if score := analyzer.analyzeWebPLossless(bad); score != 0.5 {
+				t.Errorf("score for %d bytes = %v, want 0.5", len(bad), score)
+			}
+		}
+	})
+
+	t.Run("analyze", func(t *testing.T) {
+		result := analyzer.Analyze(testWebPLossless(flat))
+		if result.Metadata.FileFormat != "webp_lossless" {
+			t.Errorf("FileFormat = %s, want webp_lossless", result.Metadata.FileFormat)
+		}
+		if result.Signals.WebPLosslessScore != 1 || result.Signals.CompressionAnalysis != 1 {
+			t.Errorf("Signals = %+v, want the lossless score as compression analysis", result.Signals)
+		}
+		if result.Stats.Width != 64 || result.Stats.Height != 64 || result.Stats.ColorChannels != 3 {
+			t.Errorf("Stats = %+v, want 64x64 RGB", result.Stats)
+		}
+	})
+}
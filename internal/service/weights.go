@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// weightSumTolerance is how far a weights struct may drift from 1.0.
+const weightSumTolerance = 0.001
+
+// ValidateWeights checks that the float64 fields of a weights struct sum to 1.0.
+//
+// calculateWeightedScore normalizes by the total weight, so any values work
+// mathematically. But the weights are tuned as proportions: when a new signal
+// is added without rebalancing the others, every existing signal silently
+// loses influence. This catches that at construction time.
+//
+// Non-float64 fields (e.g. integer settings) are ignored.
+func ValidateWeights(weights interface{}) error {
+	v := reflect.ValueOf(weights)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return errors.New("weights must not be nil")
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("weights must be a struct, got %s", v.Kind())
+	}
+
+	sum := 0.0
+	fields := 0
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.Float64 {
+			continue
+		}
+		sum += field.Float()
+		fields++
+	}
+
+	if fields == 0 {
+		return fmt.Errorf("%s has no float64 weight fields", v.Type().Name())
+	}
+
+	if math.Abs(sum-1.0) > weightSumTolerance {
+		return fmt.Errorf("%s sum to %.4f, want 1.0", v.Type().Name(), sum)
+	}
+
+	return nil
+}
+
+// mustValidateWeights panics if weights don't sum to 1.0.
+// Unbalanced weights are a programming error, not a runtime condition.
+func mustValidateWeights(weights interface{}) {
+	if err := ValidateWeights(weights); err != nil {
+		panic("invalid analyzer weights: " + err.Error())
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateWeights tests the weight-sum check used by analyzer constructors.
+func TestValidateWeights(t *testing.T) {
+	t.Run("accepts default weights", func(t *testing.T) {
+		defaults := []interface{}{
+			DefaultWeights(),
+			DefaultImageWeights(),
+			DefaultAudioWeights(),
+			DefaultVideoWeights(),
+		}
+		for _, w := range defaults {
+			if err := ValidateWeights(w); err != nil {
+				t.Errorf("ValidateWeights(%T) = %v, want nil", w, err)
+			}
+		}
+	})
+
+	t.Run("accepts pointer to struct", func(t *testing.T) {
+		w := DefaultImageWeights()
+		if err := ValidateWeights(&w); err != nil {
+			t.Errorf("ValidateWeights(&w) = %v, want nil", err)
+		}
+	})
+
+	t.Run("tolerates float rounding", func(t *testing.T) {
+		w := DefaultImageWeights()
+		w.MetadataScore += 0.0005
+		if err := ValidateWeights(w); err != nil {
+			t.Errorf("expected sum within tolerance to pass, got %v", err)
+		}
+	})
+
+	t.Run("rejects over-sum", func(t *testing.T) {
+		w := DefaultWeights()
+		w.AIPhraseDetection += 0.1
+
+		err := ValidateWeights(w)
+		if err == nil {
+			t.Fatal("expected error for weights summing to 1.1")
+		}
+		if !strings.Contains(err.Error(), "1.1000") {
+			t.Errorf("error should report the sum, got %q", err)
+		}
+	})
+
+	t.Run("rejects under-sum", func(t *testing.T) {
+		w := DefaultVideoWeights()
+		w.MetadataScore = 0
+
+		if err := ValidateWeights(w); err == nil {
+			t.Error("expected error for weights summing to 0.75")
+		}
+	})
+
+	t.Run("ignores non-float fields", func(t *testing.T) {
+		w := struct {
+			A     float64
+			B     float64
+			Limit int
+		}{A: 0.4, B: 0.6, Limit: 5}
+
+		if err := ValidateWeights(w); err != nil {
+			t.Errorf("ValidateWeights() = %v, want nil", err)
+		}
+	})
+
+	t.Run("rejects non-struct", func(t *testing.T) {
+		if err := ValidateWeights(1.0); err == nil {
+			t.Error("expected error for non-struct input")
+		}
+	})
+}
+
+// TestMustValidateWeightsPanics verifies constructors fail loudly on bad weights.
+func TestMustValidateWeightsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unbalanced weights")
+		}
+	}()
+
+	w := DefaultAudioWeights()
+	w.NoiseProfile = 0.5
+	mustValidateWeights(w)
+}
@@ -0,0 +1,223 @@
+// Package webhook delivers signed job-completion callbacks.
+//
+// When an async job finishes, the worker POSTs a JSON Payload to the job's
+// callback URL. The body is signed with HMAC-SHA256 using a per-deployment
+// secret so receivers can verify it came from us:
+//
+//	X-HumanMark-Signature: sha256=<hex(hmac_sha256(secret, body))>
+//
+// Deliveries that fail with a network error or 5xx are retried with
+// exponential backoff. 4xx responses are not retried - the receiver
+// rejected the payload and sending it again won't help.
+//
+// Callback URLs are user-supplied, so requests go through the same
+// SSRF-hardened client as URL fetching.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// SignatureHeader carries the HMAC signature of the request body.
+const SignatureHeader = "X-HumanMark-Signature"
+
+// Payload is the JSON body POSTed to callback URLs.
+type Payload struct {
+	// JobID is the verification job that finished
+	JobID string `json:"job_id"`
+
+	// Status is completed or failed
+	Status string `json:"status"`
+
+	// Human is the verdict (only meaningful when completed)
+	Human bool `json:"human"`
+
+	// Verdict is human, ai, or uncertain (only set when completed)
+	Verdict string `json:"verdict,omitempty"`
+
+	// Confidence is how confident we are in the verdict (0.0-1.0)
+	Confidence float64 `json:"confidence"`
+
+	// ContentHash is the SHA256 hash of the analyzed content
+	ContentHash string `json:"content_hash,omitempty"`
+
+	// Error describes why the job failed
+	Error string `json:"error,omitempty"`
+
+	// Timestamp is when the payload was built
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Config holds configuration for creating a Sender.
+type Config struct {
+	// Secret signs every payload
+	Secret string
+
+	// Policy controls which callback addresses may be reached
+	Policy service.FetchPolicy
+
+	// MaxAttempts is the number of deliveries before giving up (default: 3)
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, doubled each time (default: 1s)
+	Backoff time.Duration
+
+	Logger *logger.Logger
+}
+
+// Sender delivers signed webhooks.
+type Sender struct {
+	secret      []byte
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	logger      *logger.Logger
+}
+
+// New creates a new Sender.
+func New(cfg Config) *Sender {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Policy.Timeout <= 0 {
+		cfg.Policy.Timeout = 10 * time.Second
+	}
+
+	return &Sender{
+		secret:      []byte(cfg.Secret),
+		client:      service.NewSafeClient(cfg.Policy),
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		logger:      cfg.Logger,
+	}
+}
+
+// Sign returns the signature header value for body.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for body.
+// Receivers written in Go can use this directly.
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// NewPayload builds the callback payload for a finished job.
+func NewPayload(job repository.Job) Payload {
+	return Payload{
+		JobID:       job.ID,
+		Status:      string(job.Status),
+		Human:       job.Human,
+		Verdict:     job.Verdict,
+		Confidence:  job.Confidence,
+		ContentHash: job.ContentHash,
+		Error:       job.Error,
+		Timestamp:   time.Now().UTC(),
+	}
+}
+
+// Deliver POSTs the job's payload to its callback URL, retrying on failure.
+// It returns every attempt made, successful or not.
+func (s *Sender) Deliver(ctx context.Context, job repository.Job) []repository.DeliveryAttempt {
+	log := s.logger.With("job_id", job.ID)
+
+	body, err := json.Marshal(NewPayload(job))
+	if err != nil {
+		// Payload is plain data; this can't happen in practice
+		return []repository.DeliveryAttempt{{At: time.Now(), Error: err.Error()}}
+	}
+	signature := Sign(s.secret, body)
+
+	var attempts []repository.DeliveryAttempt
+	delay := s.backoff
+
+	for i := 0; i < s.maxAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return attempts
+			}
+			delay *= 2
+		}
+
+		attempt, retry := s.post(ctx, job.CallbackURL, body, signature)
+		attempts = append(attempts, attempt)
+
+		if attempt.Error == "" {
+			log.Debug("webhook delivered", "status", attempt.StatusCode, "attempt", i+1)
+			return attempts
+		}
+
+		log.Warn("webhook delivery failed",
+			"error", attempt.Error,
+			"status", attempt.StatusCode,
+			"attempt", i+1,
+		)
+
+		if !retry {
+			return attempts
+		}
+	}
+
+	return attempts
+}
+
+// post makes a single delivery and reports whether a failure is worth retrying.
+func (s *Sender) post(ctx context.Context, url string, body []byte, signature string) (repository.DeliveryAttempt, bool) {
+	attempt := repository.DeliveryAttempt{At: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "HumanMark-Webhook/1.0")
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		// A blocked address or scheme will be blocked every time
+		blocked := errors.Is(err, service.ErrBlockedAddress) || errors.Is(err, service.ErrDisallowedScheme)
+		return attempt, !blocked
+	}
+	defer resp.Body.Close()
+
+	// Drain so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	attempt.StatusCode = resp.StatusCode
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return attempt, false
+	case resp.StatusCode >= 500:
+		attempt.Error = fmt.Sprintf("receiver returned %d", resp.StatusCode)
+		return attempt, true
+	default:
+		attempt.Error = fmt.Sprintf("receiver returned %d", resp.StatusCode)
+		return attempt, false
+	}
+}
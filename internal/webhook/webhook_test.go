@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+const testSecret = "test-secret-0123456789"
+
+// newTestSender creates a Sender that may reach httptest servers on loopback.
+func newTestSender() *Sender {
+	return New(Config{
+		Secret:  testSecret,
+		Policy:  service.FetchPolicy{AllowPrivateNetworks: true},
+		Backoff: time.Millisecond,
+		Logger:  logger.NopLogger(),
+	})
+}
+
+// TestDeliver tests signed delivery to a receiver.
+func TestDeliver(t *testing.T) {
+	t.Run("signs payload", func(t *testing.T) {
+		var got Payload
+		var signatureOK bool
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			signatureOK = Verify([]byte(testSecret), body, r.Header.Get(SignatureHeader))
+			json.Unmarshal(body, &got)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		attempts := newTestSender().Deliver(context.Background(), repository.Job{
+			ID:          "job-1",
+			Status:      repository.JobStatusCompleted,
+			Human:       true,
+			Confidence:  0.9,
+			ContentHash: "abc123",
+			CallbackURL: server.URL,
+		})
+
+		if len(attempts) != 1 || attempts[0].Error != "" {
+			t.Fatalf("expected one successful attempt, got %+v", attempts)
+		}
+		if attempts[0].StatusCode != http.StatusNoContent {
+			t.Errorf("expected status 204 recorded, got %d", attempts[0].StatusCode)
+		}
+		if !signatureOK {
+			t.Error("receiver could not verify signature")
+		}
+		if got.JobID != "job-1" || !got.Human || got.Confidence != 0.9 || got.ContentHash != "abc123" {
+			t.Errorf("unexpected payload: %+v", got)
+		}
+		if got.Timestamp.IsZero() {
+			t.Error("expected timestamp in payload")
+		}
+	})
+
+	t.Run("retries on 5xx", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		attempts := newTestSender().Deliver(context.Background(), repository.Job{ID: "job-2", CallbackURL: server.URL})
+
+		if len(attempts) != 3 {
+			t.Fatalf("expected 3 attempts, got %d", len(attempts))
+		}
+		if attempts[0].StatusCode != http.StatusBadGateway || attempts[0].Error == "" {
+			t.Errorf("first attempt should record the 502, got %+v", attempts[0])
+		}
+		if attempts[2].Error != "" {
+			t.Errorf("last attempt should succeed, got %+v", attempts[2])
+		}
+	})
+
+	t.Run("does not retry 4xx", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		attempts := newTestSender().Deliver(context.Background(), repository.Job{ID: "job-3", CallbackURL: server.URL})
+
+		if n := atomic.LoadInt32(&calls); len(attempts) != 1 || n != 1 {
+			t.Errorf("expected a single attempt, got %d attempts and %d calls", len(attempts), n)
+		}
+	})
+
+	t.Run("blocks private addresses", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+		}))
+		defer server.Close()
+
+		sender := New(Config{Secret: testSecret, Backoff: time.Millisecond, Logger: logger.NopLogger()})
+		attempts := sender.Deliver(context.Background(), repository.Job{ID: "job-4", CallbackURL: server.URL})
+
+		if atomic.LoadInt32(&calls) != 0 {
+			t.Error("receiver on loopback should not be reached")
+		}
+		if len(attempts) != 1 || attempts[0].Error == "" {
+			t.Errorf("expected one failed attempt without retry, got %+v", attempts)
+		}
+	})
+}
+
+// TestVerify tests signature verification.
+func TestVerify(t *testing.T) {
+	body := []byte(`{"job_id":"x"}`)
+	sig := Sign([]byte(testSecret), body)
+
+	if !Verify([]byte(testSecret), body, sig) {
+		t.Error("expected valid signature")
+	}
+	if Verify([]byte("other-secret-0123456"), body, sig) {
+		t.Error("signature should not verify with a different secret")
+	}
+	if Verify([]byte(testSecret), []byte(`{"job_id":"y"}`), sig) {
+		t.Error("signature should not verify for a modified body")
+	}
+}
@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// defaultSweepInterval is how often the retention sweep runs by default.
+const defaultSweepInterval = time.Hour
+
+// sweepTimeout bounds a single sweep.
+const sweepTimeout = 5 * time.Minute
+
+// SweeperConfig holds configuration for creating a Sweeper.
+type SweeperConfig struct {
+	Repository repository.Repository
+	Logger     *logger.Logger
+
+	// Retention is how long jobs are kept (0 = forever; the sweep then
+	// only purges jobs deleted through the API)
+	Retention time.Duration
+
+	// Interval is the time between sweeps (default: 1h)
+	Interval time.Duration
+
+	// Now returns the current time (default: time.Now)
+	Now func() time.Time
+}
+
+// Sweeper periodically deletes jobs older than the retention period.
+type Sweeper struct {
+	repository repository.Repository
+	logger     *logger.Logger
+	retention  time.Duration
+	interval   time.Duration
+	now        func() time.Time
+
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSweeper creates a Sweeper. Call Start to begin sweeping.
+func NewSweeper(cfg SweeperConfig) *Sweeper {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultSweepInterval
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	return &Sweeper{
+		repository: cfg.Repository,
+		logger:     cfg.Logger,
+		retention:  cfg.Retention,
+		interval:   cfg.Interval,
+		now:        cfg.Now,
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// Start sweeps once right away, then every interval until Stop.
+func (s *Sweeper) Start() {
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.sweep()
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops sweeping and waits for a sweep in progress to finish.
+func (s *Sweeper) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+	<-s.stopped
+}
+
+// Sweep deletes the jobs created more than the retention period ago, and
+// any the repository only marked deleted, returning how many it purged.
+func (s *Sweeper) Sweep(ctx context.Context) (int64, error) {
+	var cutoff time.Time
+	if s.retention > 0 {
+		cutoff = s.now().Add(-s.retention)
+	}
+	return s.repository.DeleteJobsOlderThan(ctx, cutoff)
+}
+
+// sweep runs one Sweep and logs the outcome.
+func (s *Sweeper) sweep() {
+	ctx, cancel := context.WithTimeout(context.Background(), sweepTimeout)
+	defer cancel()
+
+	start := time.Now()
+	purged, err := s.Sweep(ctx)
+	if err != nil {
+		s.logger.Error("retention sweep failed", "error", err, "purged", purged)
+		return
+	}
+
+	// Most sweeps find nothing; only those that purged are worth noticing
+	log := s.logger.Debug
+	if purged > 0 {
+		log = s.logger.Info
+	}
+	log("retention sweep finished",
+		"purged", purged,
+		"retention", s.retention.String(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// TestSweeper tests the retention sweep against a frozen clock.
+func TestSweeper(t *testing.T) {
+	ctx := context.Background()
+	const retention = 30 * 24 * time.Hour
+
+	newSweeper := func(repo repository.Repository, retention time.Duration, now time.Time) *Sweeper {
+		return NewSweeper(SweeperConfig{
+			Repository: repo,
+			Logger:     logger.NopLogger(),
+			Retention:  retention,
+			Now:        func() time.Time { return now },
+		})
+	}
+
+	t.Run("keeps jobs inside the retention period", func(t *testing.T) {
+		repo := repository.NewMemory()
+		job := newPendingJob(t, repo)
+
+		purged, err := newSweeper(repo, retention, job.CreatedAt.Add(retention-time.Minute)).Sweep(ctx)
+		if err != nil || purged != 0 {
+			t.Fatalf("Sweep() = %d, %v; want 0", purged, err)
+		}
+		if _, err := repo.GetJob(ctx, job.ID); err != nil {
+			t.Errorf("GetJob() error = %v", err)
+		}
+	})
+
+	t.Run("deletes jobs past the retention period", func(t *testing.T) {
+		repo := repository.NewMemory()
+		old := newPendingJob(t, repo)
+		time.Sleep(time.Millisecond)
+		recent := newPendingJob(t, repo)
+
+		// Just past retention for old, not yet for recent
+		now := old.CreatedAt.Add(retention).Add(recent.CreatedAt.Sub(old.CreatedAt) / 2)
+		purged, err := newSweeper(repo, retention, now).Sweep(ctx)
+		if err != nil || purged != 1 {
+			t.Fatalf("Sweep() = %d, %v; want 1", purged, err)
+		}
+		if _, err := repo.GetJob(ctx, old.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("old job: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.GetJob(ctx, recent.ID); err != nil {
+			t.Errorf("recent job: GetJob() error = %v", err)
+		}
+	})
+
+	t.Run("zero retention keeps everything", func(t *testing.T) {
+		repo := repository.NewMemory()
+		job := newPendingJob(t, repo)
+
+		purged, err := newSweeper(repo, 0, job.CreatedAt.Add(100*retention)).Sweep(ctx)
+		if err != nil || purged != 0 {
+			t.Fatalf("Sweep() = %d, %v; want 0", purged, err)
+		}
+	})
+
+	t.Run("Start sweeps right away and Stop waits", func(t *testing.T) {
+		repo := repository.NewMemory()
+		job := newPendingJob(t, repo)
+
+		s := newSweeper(repo, retention, job.CreatedAt.Add(2*retention))
+		s.Start()
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			if _, err := repo.GetJob(ctx, job.ID); errors.Is(err, repository.ErrNotFound) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("job was not swept")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		s.Stop()
+		s.Stop()
+	})
+}
@@ -0,0 +1,370 @@
+// Package worker runs verification jobs in the background.
+//
+// Large media (especially video fetched from a URL) can take longer to analyze
+// than any reasonable HTTP timeout. For those requests the handler creates a
+// pending Job, hands it to the Pool, and returns immediately. Clients then poll
+// GET /verify/{id} until the job is completed or failed.
+//
+// Job lifecycle:
+//
+//	pending -> processing -> completed
+//	                      -> failed
+//
+// The queue is an in-process channel. Stop lets running jobs finish and
+// leaves queued ones pending in the repository; a job still running when
+// Stop gives up is cancelled and put back to pending. Nothing picks up
+// pending jobs after a restart, so clients see them stuck in pending.
+//
+// The Sweeper enforces data retention, deleting jobs past RETENTION_DAYS and
+// purging jobs deleted through the API.
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/audit"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// Common errors
+var (
+	ErrQueueFull = errors.New("job queue is full")
+	ErrStopped   = errors.New("worker pool is stopped")
+)
+
+// Notifier delivers a callback for a finished job.
+// Implemented by webhook.Sender.
+type Notifier interface {
+	Deliver(ctx context.Context, job repository.Job) []repository.DeliveryAttempt
+}
+
+// Task is a unit of work: run detection for input and store it on JobID.
+type Task struct {
+	JobID string
+	Input service.DetectionInput
+}
+
+// Config holds configuration for creating a Pool.
+type Config struct {
+	Detector   service.Detector
+	Repository repository.Repository
+	Logger     *logger.Logger
+
+	// Notifier sends webhooks for jobs with a CallbackURL (optional)
+	Notifier Notifier
+
+	// AuditLog records the verdict of every completed job (optional)
+	AuditLog audit.Logger
+
+	// Workers is the number of concurrent detections (default: 4)
+	Workers int
+
+	// QueueSize is how many tasks may wait for a worker (default: 100)
+	QueueSize int
+
+	// JobTimeout bounds a single detection including the fetch (default: 10m)
+	JobTimeout time.Duration
+}
+
+// Pool is a fixed-size pool of goroutines processing verification jobs.
+type Pool struct {
+	detector   service.Detector
+	repository repository.Repository
+	logger     *logger.Logger
+	notifier   Notifier
+	auditLog   audit.Logger
+	workers    int
+	jobTimeout time.Duration
+
+	tasks   chan Task
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	stopped bool
+
+	// quit is closed by Stop so workers don't start queued tasks
+	quit chan struct{}
+
+	// ctx is the parent of every detection; cancel aborts the running
+	// ones when Stop gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a new Pool. Call Start to begin processing.
+func New(cfg Config) *Pool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.JobTimeout <= 0 {
+		cfg.JobTimeout = 10 * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pool{
+		detector:   cfg.Detector,
+		repository: cfg.Repository,
+		logger:     cfg.Logger,
+		notifier:   cfg.Notifier,
+		auditLog:   cfg.AuditLog,
+		workers:    cfg.Workers,
+		jobTimeout: cfg.JobTimeout,
+		tasks:      make(chan Task, cfg.QueueSize),
+		quit:       make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start launches the worker goroutines.
+func (p *Pool) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.run()
+	}
+}
+
+// Enqueue submits a job for background processing.
+// It never blocks: if the queue is full, ErrQueueFull is returned.
+// Once accepted, input is closed when the job is done or dropped by Stop.
+func (p *Pool) Enqueue(jobID string, input service.DetectionInput) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return ErrStopped
+	}
+
+	select {
+	case p.tasks <- Task{JobID: jobID, Input: input}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop stops accepting jobs and waits for the running ones to finish.
+// Queued jobs aren't started; they stay pending in the repository. If ctx
+// expires first, the running jobs are cancelled and put back to pending,
+// and Stop returns ctx.Err() once that's recorded.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.quit)
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		p.cancel()
+		<-done
+	}
+
+	if n := len(p.tasks); n > 0 {
+		p.logger.Info("worker pool stopped with jobs left pending", "jobs", n)
+	}
+	// The queue owns its tasks' inputs; release those left in it
+	for task := range p.tasks {
+		task.Input.Close()
+	}
+	return err
+}
+
+// run processes tasks until the channel is closed or the pool stops.
+func (p *Pool) run() {
+	defer p.wg.Done()
+
+	for task := range p.tasks {
+		select {
+		case <-p.quit:
+			task.Input.Close()
+			return
+		default:
+		}
+		p.process(task)
+	}
+}
+
+// process runs a single job and records the outcome on the repository.
+func (p *Pool) process(task Task) {
+	log := p.logger.With("job_id", task.JobID)
+	defer task.Input.Close()
+
+	ctx, cancel := context.WithTimeout(p.ctx, p.jobTimeout)
+	defer cancel()
+
+	_, err := p.repository.UpdateJob(ctx, task.JobID, func(job *repository.Job) error {
+		job.Status = repository.JobStatusProcessing
+		job.AttemptCount++
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to mark job processing", "error", err)
+		return
+	}
+
+	start := time.Now()
+	result, detectErr := p.detector.Detect(ctx, task.Input)
+	if detectErr != nil {
+		log.Warn("async detection failed", "error", detectErr)
+	}
+
+	// Use a fresh context so a timed-out detection can still be recorded
+	storeCtx, storeCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer storeCancel()
+
+	// Detection cut short by Stop isn't the job's fault; leave it for
+	// another attempt
+	if detectErr != nil && p.ctx.Err() != nil {
+		_, err := p.repository.UpdateJob(storeCtx, task.JobID, func(job *repository.Job) error {
+			job.Status = repository.JobStatusPending
+			return nil
+		})
+		if err != nil {
+			log.Error("failed to put job back to pending", "error", err)
+			return
+		}
+		log.Info("async job cancelled by shutdown, left pending")
+		return
+	}
+
+	job, err := p.repository.UpdateJob(storeCtx, task.JobID, func(job *repository.Job) error {
+		if detectErr != nil {
+			job.Status = repository.JobStatusFailed
+			job.Error = detectErr.Error()
+			return nil
+		}
+
+		job.Status = repository.JobStatusCompleted
+		job.Error = ""
+		job.ContentType = string(result.ContentType)
+		job.Human = result.Human
+		job.Verdict = string(result.Verdict)
+		job.Confidence = result.Confidence
+		job.AIScore = result.AIScore
+		job.NeedsReview = result.NeedsReview
+		job.Basis = string(result.Basis)
+		job.Detectors = result.Detectors
+		job.Details = resultDetails(result)
+		job.ContentHash = result.ContentHash
+		job.NormalizedHash = result.NormalizedHash
+		job.Fingerprint = result.Fingerprint
+		job.AnalyzerVersion = result.AnalyzerVersion
+		job.ConfigFingerprint = result.ConfigFingerprint
+		job.TxID = result.AnchorTxID
+		job.AnchoredAt = result.AnchoredAt
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to store job result", "error", err)
+		return
+	}
+
+	log.Info("async job finished",
+		"status", job.Status,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+
+	// A failed write is only logged; the job's result is already stored
+	if job.Status == repository.JobStatusCompleted && p.auditLog != nil {
+		record := audit.NewRecord(job)
+		record.Async = true
+		if err := p.auditLog.Log(record); err != nil {
+			log.Error("failed to write audit record", "error", err)
+		}
+	}
+
+	if job.CallbackURL != "" && p.notifier != nil {
+		p.notify(job)
+	}
+}
+
+// resultDetails converts result's detection breakdown for storage, the same
+// way the handler does for synchronous jobs.
+func resultDetails(result *service.DetectionResult) *repository.Details {
+	details := &repository.Details{
+		Aggregation:     string(result.Aggregation),
+		Disagreement:    result.Disagreement,
+		Evidence:        string(result.Evidence),
+		DetectorErrors:  result.DetectorErrors,
+		MismatchWarning: result.MismatchWarning,
+		ProcessingTime:  result.ProcessingTime,
+	}
+	for _, p := range result.ProviderScores {
+		details.Signals = append(details.Signals, repository.Signal{Name: p.Name, AIScore: p.AIScore, Weight: p.Weight})
+	}
+	for _, o := range result.DetectorOutputs {
+		output := repository.DetectorOutput{Name: o.Name, Sentences: make([]repository.Sentence, len(o.Sentences))}
+		for i, s := range o.Sentences {
+			output.Sentences[i] = repository.Sentence{Text: s.Text, AIScore: s.AIScore}
+		}
+		for _, p := range o.Paragraphs {
+			output.Paragraphs = append(output.Paragraphs, repository.Paragraph{StartSentence: p.StartSentence, Sentences: p.Sentences, AIScore: p.AIScore})
+		}
+		details.DetectorOutputs = append(details.DetectorOutputs, output)
+	}
+	for _, a := range result.SentenceAgreement {
+		details.SentenceAgreement = append(details.SentenceAgreement, repository.SentenceAgreement{
+			Detectors: a.Detectors, Sentences: a.Sentences, Agreement: a.Agreement, MeanDifference: a.MeanDifference,
+		})
+	}
+	for _, p := range result.AIPhrases {
+		details.AIPhrases = append(details.AIPhrases, repository.Phrase{Phrase: p.Phrase, Category: p.Category})
+	}
+	for _, s := range result.SkippedProviders {
+		details.Skipped = append(details.Skipped, repository.SkippedDetector{Name: s.Name, Reason: s.Reason})
+	}
+	if m := result.HostReputation; m != nil {
+		details.HostReputation = &repository.HostReputation{Host: m.Host, Rule: m.Rule, Adjustment: m.Adjustment, Reason: m.Reason}
+	}
+	if s := result.Watermark; s != nil {
+		details.Watermark = &repository.Watermark{
+			PValue:           s.PValue,
+			Flagged:          s.Flagged,
+			Words:            s.Words,
+			WordPairs:        s.WordPairs,
+			ChiSquare:        s.ChiSquare,
+			DegreesOfFreedom: s.DegreesOfFreedom,
+		}
+	}
+	return details
+}
+
+// notify delivers the job's webhook and records the attempts on the job.
+func (p *Pool) notify(job *repository.Job) {
+	log := p.logger.With("job_id", job.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	attempts := p.notifier.Deliver(ctx, *job)
+
+	storeCtx, storeCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer storeCancel()
+
+	_, err := p.repository.UpdateJob(storeCtx, job.ID, func(job *repository.Job) error {
+		job.DeliveryAttempts = append(job.DeliveryAttempts, attempts...)
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to record webhook delivery", "error", err)
+	}
+}
@@ -0,0 +1,343 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/humanmark/humanmark/internal/audit"
+	"github.com/humanmark/humanmark/internal/repository"
+	"github.com/humanmark/humanmark/internal/service"
+	"github.com/humanmark/humanmark/pkg/logger"
+)
+
+// mockDetector implements service.Detector for testing.
+type mockDetector struct {
+	err   error
+	block chan struct{}
+
+	// started, if set, receives the input of each detection as it starts
+	started chan service.DetectionInput
+}
+
+func (m *mockDetector) Detect(ctx context.Context, input service.DetectionInput) (*service.DetectionResult, error) {
+	if m.started != nil {
+		m.started <- input
+	}
+	if m.block != nil {
+		select {
+		case <-m.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &service.DetectionResult{
+		Human:       false,
+		Confidence:  0.85,
+		AIScore:     0.85,
+		ContentType: service.ContentTypeVideo,
+		Detectors:   []string{"mock"},
+		ContentHash: "abc123",
+	}, nil
+}
+
+// closeRecorder is an input Reader that records whether it was closed.
+type closeRecorder struct {
+	closed bool
+}
+
+func (r *closeRecorder) ReadAt(p []byte, off int64) (int, error) { return 0, io.EOF }
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+// mockNotifier implements Notifier for testing.
+type mockNotifier struct {
+	delivered chan repository.Job
+}
+
+func (m *mockNotifier) Deliver(ctx context.Context, job repository.Job) []repository.DeliveryAttempt {
+	m.delivered <- job
+	return []repository.DeliveryAttempt{{At: time.Now(), StatusCode: 200}}
+}
+
+// newPendingJob stores a pending job the way the handler does.
+func newPendingJob(t *testing.T, repo repository.Repository) *repository.Job {
+	t.Helper()
+
+	job, err := repo.CreateJob(context.Background(), repository.Job{
+		Status: repository.JobStatusPending,
+	})
+	if err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+	return job
+}
+
+// waitForStatus polls the repository until the job reaches one of the final states.
+func waitForStatus(t *testing.T, repo repository.Repository, id string) *repository.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := repo.GetJob(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetJob() error = %v", err)
+		}
+		if job.Status == repository.JobStatusCompleted || job.Status == repository.JobStatusFailed {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return nil
+}
+
+// TestPool tests the job lifecycle through the worker pool.
+func TestPool(t *testing.T) {
+	t.Run("pending job becomes completed", func(t *testing.T) {
+		repo := repository.NewMemory()
+		pool := New(Config{
+			Detector:   &mockDetector{},
+			Repository: repo,
+			Logger:     logger.NopLogger(),
+			Workers:    1,
+		})
+		pool.Start()
+		defer pool.Stop(context.Background())
+
+		job := newPendingJob(t, repo)
+		if err := pool.Enqueue(job.ID, service.DetectionInput{URL: "https://example.com/v.mp4"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+
+		got := waitForStatus(t, repo, job.ID)
+		if got.Status != repository.JobStatusCompleted {
+			t.Fatalf("expected completed, got %s (error %q)", got.Status, got.Error)
+		}
+		if got.Human || got.Confidence != 0.85 || got.ContentType != "video" {
+			t.Errorf("result not stored on job: %+v", got)
+		}
+		if got.ContentHash != "abc123" {
+			t.Errorf("expected content hash abc123, got %q", got.ContentHash)
+		}
+		if got.AttemptCount != 1 || got.CompletedAt.IsZero() {
+			t.Errorf("expected 1 attempt and a completion time, got %d, %v", got.AttemptCount, got.CompletedAt)
+		}
+	})
+
+	t.Run("detector error marks job failed", func(t *testing.T) {
+		repo := repository.NewMemory()
+		pool := New(Config{
+			Detector:   &mockDetector{err: errors.New("fetch failed")},
+			Repository: repo,
+			Logger:     logger.NopLogger(),
+			Workers:    1,
+		})
+		pool.Start()
+		defer pool.Stop(context.Background())
+
+		job := newPendingJob(t, repo)
+		if err := pool.Enqueue(job.ID, service.DetectionInput{}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+
+		got := waitForStatus(t, repo, job.ID)
+		if got.Status != repository.JobStatusFailed {
+			t.Fatalf("expected failed, got %s", got.Status)
+		}
+		if got.Error != "fetch failed" {
+			t.Errorf("expected error 'fetch failed', got %q", got.Error)
+		}
+	})
+
+	t.Run("completed job is audited", func(t *testing.T) {
+		var buf bytes.Buffer
+		repo := repository.NewMemory()
+		pool := New(Config{
+			Detector:   &mockDetector{},
+			Repository: repo,
+			Logger:     logger.NopLogger(),
+			Workers:    1,
+			AuditLog:   audit.NewJSONLogger(&buf),
+		})
+		pool.Start()
+
+		job := newPendingJob(t, repo)
+		if err := pool.Enqueue(job.ID, service.DetectionInput{URL: "https://example.com/v.mp4"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		waitForStatus(t, repo, job.ID)
+		pool.Stop(context.Background())
+
+		var record audit.Record
+		decoder := json.NewDecoder(&buf)
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("malformed audit line %q: %v", buf.String(), err)
+		}
+		if record.JobID != job.ID || !record.Async || record.ContentHash != "abc123" || record.Confidence != 0.85 {
+			t.Errorf("record = %+v", record)
+		}
+		if decoder.More() {
+			t.Errorf("expected one audit line, got more: %s", buf.String())
+		}
+	})
+
+	t.Run("finished job with callback is delivered", func(t *testing.T) {
+		repo := repository.NewMemory()
+		notifier := &mockNotifier{delivered: make(chan repository.Job, 1)}
+		pool := New(Config{
+			Detector:   &mockDetector{},
+			Repository: repo,
+			Logger:     logger.NopLogger(),
+			Notifier:   notifier,
+			Workers:    1,
+		})
+		pool.Start()
+
+		job, err := repo.CreateJob(context.Background(), repository.Job{
+			Status:      repository.JobStatusPending,
+			CallbackURL: "https://example.com/hook",
+		})
+		if err != nil {
+			t.Fatalf("CreateJob() error = %v", err)
+		}
+		if err := pool.Enqueue(job.ID, service.DetectionInput{}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+
+		select {
+		case delivered := <-notifier.delivered:
+			if delivered.Status != repository.JobStatusCompleted {
+				t.Errorf("expected completed job to be delivered, got %s", delivered.Status)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("webhook was not delivered")
+		}
+
+		// Stop waits for the attempts to be recorded
+		pool.Stop(context.Background())
+
+		got, _ := repo.GetJob(context.Background(), job.ID)
+		if len(got.DeliveryAttempts) != 1 || got.DeliveryAttempts[0].StatusCode != 200 {
+			t.Errorf("expected delivery attempt on job, got %+v", got.DeliveryAttempts)
+		}
+	})
+
+	t.Run("full queue rejects jobs", func(t *testing.T) {
+		block := make(chan struct{})
+		pool := New(Config{
+			Detector:   &mockDetector{block: block},
+			Repository: repository.NewMemory(),
+			Logger:     logger.NopLogger(),
+			Workers:    1,
+			QueueSize:  1,
+		})
+		// Not started, so nothing drains the queue
+
+		if err := pool.Enqueue("a", service.DetectionInput{}); err != nil {
+			t.Fatalf("first Enqueue() error = %v", err)
+		}
+		if err := pool.Enqueue("b", service.DetectionInput{}); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("expected ErrQueueFull, got %v", err)
+		}
+		close(block)
+	})
+
+	t.Run("stopped pool rejects jobs", func(t *testing.T) {
+		pool := New(Config{
+			Detector:   &mockDetector{},
+			Repository: repository.NewMemory(),
+			Logger:     logger.NopLogger(),
+		})
+		pool.Start()
+
+		if err := pool.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+		if err := pool.Enqueue("a", service.DetectionInput{}); !errors.Is(err, ErrStopped) {
+			t.Errorf("expected ErrStopped, got %v", err)
+		}
+		// Stopping twice is safe
+		if err := pool.Stop(context.Background()); err != nil {
+			t.Errorf("second Stop() error = %v", err)
+		}
+	})
+
+	t.Run("stop finishes running jobs and leaves queued ones pending", func(t *testing.T) {
+		repo := repository.NewMemory()
+		block := make(chan struct{})
+		detector := &mockDetector{block: block, started: make(chan service.DetectionInput, 1)}
+		pool := New(Config{
+			Detector:   detector,
+			Repository: repo,
+			Logger:     logger.NopLogger(),
+			Workers:    1,
+		})
+		pool.Start()
+
+		running := newPendingJob(t, repo)
+		queued := newPendingJob(t, repo)
+		runningData, queuedData := &closeRecorder{}, &closeRecorder{}
+		pool.Enqueue(running.ID, service.DetectionInput{Reader: runningData})
+		<-detector.started
+		pool.Enqueue(queued.ID, service.DetectionInput{Reader: queuedData})
+
+		stopped := make(chan error, 1)
+		go func() { stopped <- pool.Stop(context.Background()) }()
+		select {
+		case err := <-stopped:
+			t.Fatalf("Stop() returned %v before the running job finished", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(block)
+		if err := <-stopped; err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+		if got, _ := repo.GetJob(context.Background(), running.ID); got.Status != repository.JobStatusCompleted {
+			t.Errorf("running job: expected completed, got %s", got.Status)
+		}
+		if got, _ := repo.GetJob(context.Background(), queued.ID); got.Status != repository.JobStatusPending || got.AttemptCount != 0 {
+			t.Errorf("queued job: expected pending and never attempted, got %s after %d attempts", got.Status, got.AttemptCount)
+		}
+		if !runningData.closed || !queuedData.closed {
+			t.Errorf("expected both jobs' inputs closed, got running %v, queued %v", runningData.closed, queuedData.closed)
+		}
+	})
+
+	t.Run("stop cancels running jobs when ctx expires", func(t *testing.T) {
+		repo := repository.NewMemory()
+		detector := &mockDetector{block: make(chan struct{}), started: make(chan service.DetectionInput, 1)}
+		pool := New(Config{
+			Detector:   detector,
+			Repository: repo,
+			Logger:     logger.NopLogger(),
+			Workers:    1,
+		})
+		pool.Start()
+
+		job := newPendingJob(t, repo)
+		pool.Enqueue(job.ID, service.DetectionInput{})
+		<-detector.started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := pool.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		got, _ := repo.GetJob(context.Background(), job.ID)
+		if got.Status != repository.JobStatusPending || got.Error != "" {
+			t.Errorf("expected job back to pending, got %s (error %q)", got.Status, got.Error)
+		}
+	})
+}
@@ -0,0 +1,286 @@
+// Package cert issues and verifies HumanMark verification certificates:
+// signed statements of a verification result that publishers can hand to
+// third parties, who check them offline against the server's public keys.
+//
+// A certificate is a JWS in compact serialization (RFC 7515), signed with
+// Ed25519:
+//
+//	base64url(header) "." base64url(claims) "." base64url(signature)
+//
+// The header names the signing key with "kid", so keys can be rotated:
+// the server publishes its current and previous public keys at
+// /.well-known/humanmark.json as a KeySet, and certificates signed with a
+// previous key stay valid while it is listed.
+//
+// Verifying a certificate:
+//
+//	var keys cert.KeySet
+//	json.Unmarshal(wellKnownBody, &keys)
+//	claims, err := cert.VerifyCertificate(token, keys)
+package cert
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Algorithm is the JWS "alg" of certificates.
+const Algorithm = "EdDSA"
+
+// Type is the JWS "typ" of certificates.
+const Type = "humanmark-certificate+jwt"
+
+var (
+	// ErrMalformed is returned for a token that isn't a certificate
+	ErrMalformed = errors.New("cert: malformed certificate")
+
+	// ErrUnknownKey is returned when no key in the set has the
+	// certificate's kid
+	ErrUnknownKey = errors.New("cert: unknown signing key")
+
+	// ErrInvalidSignature is returned when the signature doesn't match
+	// the certificate
+	ErrInvalidSignature = errors.New("cert: invalid signature")
+)
+
+// Claims is what a certificate states about a verification.
+type Claims struct {
+	// JobID is the verification job
+	JobID string `json:"job_id"`
+
+	// ContentHash is the SHA-256 of the verified content, hex-encoded, so
+	// a holder of the content can check the certificate is about it
+	ContentHash string `json:"content_hash"`
+
+	// Verdict is human, ai, or uncertain
+	Verdict string `json:"verdict"`
+
+	// Confidence is how confident the verdict is (0.0-1.0)
+	Confidence float64 `json:"confidence"`
+
+	// ContentType is the verified content's type: text, image, ...
+	ContentType string `json:"content_type,omitempty"`
+
+	// AnalyzerVersion and ConfigFingerprint identify the detection logic
+	// and configuration that produced the verdict
+	AnalyzerVersion   string `json:"analyzer_version,omitempty"`
+	ConfigFingerprint string `json:"config_fingerprint,omitempty"`
+
+	// VerifiedAt is when the content was verified
+	VerifiedAt time.Time `json:"verified_at"`
+
+	// IssuedAt is when the certificate was signed
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// header is a certificate's JWS header.
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Key is a public key in a KeySet, as a JSON Web Key (RFC 8037).
+type Key struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+
+	// X is the base64url public key
+	X string `json:"x"`
+}
+
+// KeySet is the public keys certificates can be checked against; the
+// /.well-known/humanmark.json document.
+type KeySet struct {
+	// Issuer names the server
+	Issuer string `json:"issuer,omitempty"`
+
+	// Keys are the current key first, then previous ones
+	Keys []Key `json:"keys"`
+}
+
+// publicKey returns the key with kid, or nil.
+func (s KeySet) publicKey(kid string) ed25519.PublicKey {
+	for _, k := range s.Keys {
+		if k.Kid != kid || k.Kty != "OKP" || k.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil
+		}
+		return ed25519.PublicKey(x)
+	}
+	return nil
+}
+
+// KeyID derives a key's kid from the public key, so a kid always names
+// the same key.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// publicJWK returns key as a Key.
+func publicJWK(key ed25519.PublicKey) Key {
+	return Key{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		Kid: KeyID(key),
+		Alg: Algorithm,
+		Use: "sig",
+		X:   base64.RawURLEncoding.EncodeToString(key),
+	}
+}
+
+// Signer signs certificates with one key and publishes it along with the
+// previous keys whose certificates are still valid.
+type Signer struct {
+	key      ed25519.PrivateKey
+	kid      string
+	previous []ed25519.PublicKey
+	issuer   string
+}
+
+// NewSigner returns a Signer for key. previous are retired public keys to
+// keep publishing, so certificates they signed still verify.
+func NewSigner(issuer string, key ed25519.PrivateKey, previous ...ed25519.PublicKey) *Signer {
+	return &Signer{
+		key:      key,
+		kid:      KeyID(key.Public().(ed25519.PublicKey)),
+		previous: previous,
+		issuer:   issuer,
+	}
+}
+
+// GenerateKey returns a new random signing key.
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// KeyID returns the kid of the signing key.
+func (s *Signer) KeyID() string {
+	return s.kid
+}
+
+// KeySet returns the signing key and the previous keys.
+func (s *Signer) KeySet() KeySet {
+	set := KeySet{Issuer: s.issuer, Keys: []Key{publicJWK(s.key.Public().(ed25519.PublicKey))}}
+	for _, key := range s.previous {
+		set.Keys = append(set.Keys, publicJWK(key))
+	}
+	return set
+}
+
+// Sign returns a certificate stating claims.
+func (s *Signer) Sign(claims Claims) (string, error) {
+	h, err := json.Marshal(header{Alg: Algorithm, Typ: Type, Kid: s.kid})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	signature := ed25519.Sign(s.key, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyCertificate checks token's signature against keys and returns its
+// claims. It fails with ErrMalformed, ErrUnknownKey or ErrInvalidSignature.
+func VerifyCertificate(token string, keys KeySet) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	var h header
+	if err := json.Unmarshal(headerJSON, &h); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+	// Only EdDSA, so a token can't pick a weaker algorithm or "none"
+	if h.Alg != Algorithm {
+		return nil, fmt.Errorf("%w: algorithm %q", ErrMalformed, h.Alg)
+	}
+
+	key := keys.publicKey(h.Kid)
+	if key == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, h.Kid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformed, err)
+	}
+	if !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+	return &claims, nil
+}
+
+// ParsePrivateKey decodes a base64 (standard or URL, padded or not)
+// Ed25519 private key: the 32-byte seed or the 64-byte key.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	data, err := decodeBase64(s)
+	if err != nil {
+		return nil, err
+	}
+	switch len(data) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(data), nil
+	}
+	return nil, fmt.Errorf("cert: private key is %d bytes, want %d or %d", len(data), ed25519.SeedSize, ed25519.PrivateKeySize)
+}
+
+// ParsePublicKey decodes a base64 (standard or URL, padded or not)
+// 32-byte Ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	data, err := decodeBase64(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("cert: public key is %d bytes, want %d", len(data), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// decodeBase64 decodes s in any of the four base64 alphabets and paddings.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	data, err := encoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("cert: invalid base64 key: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,183 @@
+package cert
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newKey returns a signing key, failing the test if it can't.
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+// testClaims are the claims the tests sign.
+var testClaims = Claims{
+	JobID:             "job-1",
+	ContentHash:       "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	Verdict:           "human",
+	Confidence:        0.91,
+	ContentType:       "text",
+	AnalyzerVersion:   "2.1.0",
+	ConfigFingerprint: "ab12cd34",
+	VerifiedAt:        time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	IssuedAt:          time.Date(2024, 5, 2, 8, 30, 0, 0, time.UTC),
+}
+
+// TestVerifyCertificate tests that signed certificates verify.
+func TestVerifyCertificate(t *testing.T) {
+	signer := NewSigner("https://api.example.com", newKey(t))
+
+	token, err := signer.Sign(testClaims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if strings.Count(token, ".") != 2 {
+		t.Fatalf("expected compact JWS, got %q", token)
+	}
+
+	// Verified against the published key set, as a third party would
+	published, err := json.Marshal(signer.KeySet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys KeySet
+	if err := json.Unmarshal(published, &keys); err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := VerifyCertificate(token, keys)
+	if err != nil {
+		t.Fatalf("VerifyCertificate: %v", err)
+	}
+	if *claims != testClaims {
+		t.Errorf("claims = %+v, want %+v", *claims, testClaims)
+	}
+
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	var h header
+	json.Unmarshal(headerJSON, &h)
+	if h.Alg != Algorithm || h.Kid != signer.KeyID() || h.Typ != Type {
+		t.Errorf("unexpected header %+v", h)
+	}
+}
+
+// TestVerifyCertificate_Tampered tests that altered certificates are rejected.
+func TestVerifyCertificate_Tampered(t *testing.T) {
+	signer := NewSigner("", newKey(t))
+	token, err := signer.Sign(testClaims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	forged := testClaims
+	forged.Verdict = "ai"
+	forgedJSON, _ := json.Marshal(forged)
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"` + signer.KeyID() + `"}`))
+	badSignature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	badSignature[0] ^= 1
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString(forgedJSON) + "." + parts[2], ErrInvalidSignature},
+		{"signature", parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(badSignature), ErrInvalidSignature},
+		{"alg none", noneHeader + "." + parts[1] + ".", ErrMalformed},
+		{"truncated", parts[0] + "." + parts[1], ErrMalformed},
+		{"garbage", "not.a.certificate", ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyCertificate(tt.token, signer.KeySet()); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("other server's key", func(t *testing.T) {
+		other := NewSigner("", newKey(t))
+		if _, err := VerifyCertificate(token, other.KeySet()); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("expected ErrUnknownKey, got %v", err)
+		}
+	})
+}
+
+// TestKeyRotation tests that certificates signed with a previous key still
+// verify while it is published, and not after.
+func TestKeyRotation(t *testing.T) {
+	oldKey, currentKey := newKey(t), newKey(t)
+	oldToken, err := NewSigner("", oldKey).Sign(testClaims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	rotated := NewSigner("", currentKey, oldKey.Public().(ed25519.PublicKey))
+	keys := rotated.KeySet()
+	if len(keys.Keys) != 2 || keys.Keys[0].Kid != rotated.KeyID() || keys.Keys[1].Kid != KeyID(oldKey.Public().(ed25519.PublicKey)) {
+		t.Fatalf("expected current then previous key, got %+v", keys.Keys)
+	}
+	if rotated.KeyID() == KeyID(oldKey.Public().(ed25519.PublicKey)) {
+		t.Fatal("expected different kids for different keys")
+	}
+
+	if _, err := VerifyCertificate(oldToken, keys); err != nil {
+		t.Errorf("old certificate with old key published: %v", err)
+	}
+	newToken, _ := rotated.Sign(testClaims)
+	if _, err := VerifyCertificate(newToken, keys); err != nil {
+		t.Errorf("new certificate: %v", err)
+	}
+
+	retired := NewSigner("", currentKey).KeySet()
+	if _, err := VerifyCertificate(oldToken, retired); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("old certificate after the key was retired: expected ErrUnknownKey, got %v", err)
+	}
+}
+
+// TestParseKeys tests decoding configured keys.
+func TestParseKeys(t *testing.T) {
+	key := newKey(t)
+	seed := key.Seed()
+
+	for _, encoded := range []string{
+		base64.StdEncoding.EncodeToString(seed),
+		base64.RawURLEncoding.EncodeToString(seed),
+		base64.StdEncoding.EncodeToString(key),
+	} {
+		parsed, err := ParsePrivateKey(encoded)
+		if err != nil {
+			t.Errorf("ParsePrivateKey(%q): %v", encoded, err)
+			continue
+		}
+		if !parsed.Equal(key) {
+			t.Errorf("ParsePrivateKey(%q) returned a different key", encoded)
+		}
+	}
+	if _, err := ParsePrivateKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("expected error for short private key")
+	}
+	if _, err := ParsePrivateKey("not base64!"); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+
+	public := key.Public().(ed25519.PublicKey)
+	parsed, err := ParsePublicKey(base64.StdEncoding.EncodeToString(public))
+	if err != nil || !parsed.Equal(public) {
+		t.Errorf("ParsePublicKey: %v", err)
+	}
+	if _, err := ParsePublicKey(base64.StdEncoding.EncodeToString(seed[:16])); err == nil {
+		t.Error("expected error for short public key")
+	}
+}
@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampling thins out repetitive records. Each Interval, the first Burst
+// records with the same level and message are written, then only 1 in
+// Thereafter. Errors are always written.
+type Sampling struct {
+	// Burst is how many identical records are written each interval before
+	// sampling starts
+	Burst int
+
+	// Thereafter keeps 1 in Thereafter records past the burst (0 drops
+	// them all)
+	Thereafter int
+
+	// Interval is how often the counts start over (default: 1s)
+	Interval time.Duration
+}
+
+// SamplingStats counts the records sampling let through and dropped.
+type SamplingStats struct {
+	Written uint64
+	Dropped uint64
+}
+
+// filterHandler is a slog.Handler that decides which records are written:
+// by the level of the logger's component, then by sampling. It sits on top
+// of the handler chain, so dropped records cost nothing further.
+type filterHandler struct {
+	handler slog.Handler
+	levels  *levels
+	sampler *sampler // nil without sampling
+
+	// level is the level of this handler's component
+	level slog.Level
+}
+
+// levels holds a logger's level, and those of the components that have
+// their own.
+type levels struct {
+	level      slog.Level
+	components map[string]slog.Level
+}
+
+// newLevels returns level, with the component levels named in components.
+func newLevels(level slog.Level, components map[string]string) *levels {
+	l := &levels{level: level, components: make(map[string]slog.Level, len(components))}
+	for component, name := range components {
+		l.components[strings.ToLower(strings.TrimSpace(component))] = parseLevel(name)
+	}
+	return l
+}
+
+// of returns the level of component.
+func (l *levels) of(component string) slog.Level {
+	if level, ok := l.components[strings.ToLower(component)]; ok {
+		return level
+	}
+	return l.level
+}
+
+// minLevel returns the lowest level any component logs at, which the
+// handlers below the filter must let through.
+func (l *levels) minLevel() slog.Level {
+	lowest := l.level
+	for _, level := range l.components {
+		if level < lowest {
+			lowest = level
+		}
+	}
+	return lowest
+}
+
+// newFilterHandler wraps handler with filtering by levels, and sampling
+// if it's set.
+func newFilterHandler(handler slog.Handler, levels *levels, sampling *Sampling) *filterHandler {
+	h := &filterHandler{handler: handler, levels: levels, level: levels.level}
+	if sampling != nil {
+		h.sampler = newSampler(*sampling)
+	}
+	return h
+}
+
+func (h *filterHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *filterHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.sampler != nil && !h.sampler.keep(r) {
+		return nil
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *filterHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.handler = h.handler.WithAttrs(attrs)
+	return &c
+}
+
+func (h *filterHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.handler = h.handler.WithGroup(name)
+	return &c
+}
+
+// component returns a handler for the named component: its records carry
+// the name, and are filtered at its level.
+func (h *filterHandler) component(name string) *filterHandler {
+	c := *h
+	c.handler = h.handler.WithAttrs([]slog.Attr{slog.String("component", name)})
+	c.level = h.levels.of(name)
+	return &c
+}
+
+// sampleKey identifies records that count as identical.
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+// sampler counts identical records per interval. It's shared by every
+// handler derived from one logger, so a component's records and its
+// request-scoped loggers' are counted together.
+type sampler struct {
+	Sampling
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[sampleKey]int
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func newSampler(s Sampling) *sampler {
+	if s.Interval <= 0 {
+		s.Interval = time.Second
+	}
+	return &sampler{Sampling: s, counts: make(map[sampleKey]int)}
+}
+
+// keep reports whether r should be written, counting it either way.
+func (s *sampler) keep(r slog.Record) bool {
+	if r.Level >= slog.LevelError {
+		s.written.Add(1)
+		return true
+	}
+
+	now := r.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s.mu.Lock()
+	// Starting every count over at once keeps the map to one interval's
+	// messages
+	if now.Sub(s.windowStart) >= s.Interval || now.Before(s.windowStart) {
+		clear(s.counts)
+		s.windowStart = now
+	}
+	key := sampleKey{level: r.Level, msg: r.Message}
+	s.counts[key]++
+	n := s.counts[key]
+	s.mu.Unlock()
+
+	if n <= s.Burst || (s.Thereafter > 0 && (n-s.Burst)%s.Thereafter == 0) {
+		s.written.Add(1)
+		return true
+	}
+	s.dropped.Add(1)
+	return false
+}
+
+// Component returns a Logger for the named part of the system, e.g.
+// "http" or "video". Its records carry component=name, and it logs at the
+// level Options.Levels gives the component, or the logger's own.
+// Example:
+//
+//	log := logger.NewWithOptions("info", os.Stdout, logger.Options{Levels: map[string]string{"video": "debug"}})
+//	log.Component("video").Debug("parsed container", "format", "mp4") // written
+//	log.Component("audio").Debug("parsed container", "format", "wav") // not written
+func (l *Logger) Component(name string) *Logger {
+	h, ok := l.Handler().(*filterHandler)
+	if !ok {
+		return l.With("component", name)
+	}
+	return &Logger{slog.New(h.component(name))}
+}
+
+// SamplingStats returns how many records sampling has let through and
+// dropped, across every logger derived from the same New call. It's zero
+// without sampling.
+func (l *Logger) SamplingStats() SamplingStats {
+	h, ok := l.Handler().(*filterHandler)
+	if !ok || h.sampler == nil {
+		return SamplingStats{}
+	}
+	return SamplingStats{Written: h.sampler.written.Load(), Dropped: h.sampler.dropped.Load()}
+}
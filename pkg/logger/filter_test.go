@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestComponentLevels verifies that records are routed by their
+// component's level.
+func TestComponentLevels(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions("info", &buf, Options{Format: FormatText, Levels: map[string]string{"Video": "debug", "http": "warn"}})
+
+	log.Debug("global debug")
+	log.Info("global info")
+	video := log.Component("video")
+	video.Debug("video debug")
+	video.With("job_id", "job-1").Debug("video request debug")
+	http := log.Component("http")
+	http.Info("http info")
+	http.Warn("http warn")
+	log.Component("audio").Debug("audio debug")
+	log.Component("audio").Info("audio info")
+
+	output := buf.String()
+	for _, msg := range []string{"global info", "video debug", "video request debug", "http warn", "audio info"} {
+		if !strings.Contains(output, msg) {
+			t.Errorf("%q not in output: %s", msg, output)
+		}
+	}
+	for _, msg := range []string{"global debug", "http info", "audio debug"} {
+		if strings.Contains(output, msg) {
+			t.Errorf("%q should be filtered out: %s", msg, output)
+		}
+	}
+	if !strings.Contains(output, "component=video job_id=job-1") {
+		t.Errorf("records should carry their component: %s", output)
+	}
+}
+
+// TestSampling verifies that repetitive records are thinned out after the
+// burst, and counted.
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions("info", &buf, Options{Format: FormatText, Sampling: &Sampling{Burst: 3, Thereafter: 5, Interval: time.Minute}})
+	http := log.Component("http")
+
+	for i := 0; i < 23; i++ {
+		http.With("request_id", i).Info("http request")
+	}
+	log.Info("server started")
+	log.Error("failed to store job", "job_id", "job-1")
+	log.Error("failed to store job", "job_id", "job-2")
+
+	output := buf.String()
+	// 3 in the burst, then 1 in 5 of the next 20
+	if n := strings.Count(output, `msg="http request"`); n != 7 {
+		t.Errorf("expected 7 request records, got %d: %s", n, output)
+	}
+	if !strings.Contains(output, "request_id=7\n") || strings.Contains(output, "request_id=4\n") {
+		t.Errorf("expected every 5th record after the burst: %s", output)
+	}
+	if !strings.Contains(output, "server started") || strings.Count(output, "failed to store job") != 2 {
+		t.Errorf("other records and errors should be written: %s", output)
+	}
+
+	stats := http.SamplingStats()
+	if stats != log.SamplingStats() {
+		t.Errorf("components should share counts: %+v vs %+v", stats, log.SamplingStats())
+	}
+	if stats.Written != 10 || stats.Dropped != 16 {
+		t.Errorf("stats = %+v, want 10 written and 16 dropped", stats)
+	}
+
+	t.Run("counts start over each interval", func(t *testing.T) {
+		s := newSampler(Sampling{Burst: 2, Interval: time.Second})
+		start := time.Now()
+		record := func(at time.Duration) bool {
+			return s.keep(slog.NewRecord(start.Add(at), slog.LevelInfo, "http request", 0))
+		}
+		if !record(0) || !record(time.Millisecond) || record(2*time.Millisecond) {
+			t.Error("expected the burst kept, then the rest dropped")
+		}
+		if !record(time.Second) {
+			t.Error("expected a new interval to start a new burst")
+		}
+		if s.dropped.Load() != 1 || s.written.Load() != 3 {
+			t.Errorf("written = %d, dropped = %d", s.written.Load(), s.dropped.Load())
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := NewWithWriter("info", &buf)
+		for i := 0; i < 100; i++ {
+			log.Info("http request")
+		}
+		if n := strings.Count(buf.String(), "http request"); n != 100 {
+			t.Errorf("expected every record, got %d", n)
+		}
+		if stats := log.SamplingStats(); stats != (SamplingStats{}) {
+			t.Errorf("stats = %+v, want none", stats)
+		}
+	})
+}
+
+// TestFilterEnabled verifies that a component's level is what callers see
+// before building a record.
+func TestFilterEnabled(t *testing.T) {
+	log := NewWithOptions("warn", &bytes.Buffer{}, Options{Levels: map[string]string{"video": "debug"}})
+	if log.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("expected info disabled at warn")
+	}
+	if !log.Component("video").Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected debug enabled for video")
+	}
+}
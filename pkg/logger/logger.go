@@ -15,6 +15,13 @@
 // Output (development):
 //
 //	2025/01/01 00:00:00 INFO user created user_id=123 email=user@example.com
+//
+// New and NewWithWriter pick the format from the ENV variable; callers
+// with their own configuration should set Options.Format instead.
+//
+// Values that could leak credentials or user content - api_key,
+// authorization, text and the like, and secret URL query parameters - are
+// masked on the way out, whatever logged them (see DefaultRedactKeys).
 package logger
 
 import (
@@ -23,6 +30,7 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 )
 
 // Logger is a structured logger wrapper.
@@ -41,23 +49,93 @@ func New(level string) *Logger {
 // NewWithWriter creates a new Logger that writes to the specified writer.
 // Useful for testing or writing to files.
 func NewWithWriter(level string, w io.Writer) *Logger {
-	lvl := parseLevel(level)
+	return NewWithOptions(level, w, Options{MaxValueLength: DefaultMaxValueLength})
+}
+
+// Format is how a Logger writes records.
+type Format string
+
+const (
+	// FormatAuto writes JSON when the ENV variable is "production", and
+	// text otherwise.
+	FormatAuto Format = ""
+
+	// FormatJSON writes one JSON object per record, for log aggregators.
+	FormatJSON Format = "json"
+
+	// FormatText writes key=value lines, for people.
+	FormatText Format = "text"
+)
+
+// Options configures a Logger beyond its level.
+type Options struct {
+	// Format is JSON or text (FormatAuto = decided by the ENV variable)
+	Format Format
+
+	// TimeFormat is the time.Format layout of record times, e.g.
+	// time.RFC3339Nano ("" = the slog default, RFC3339 with milliseconds)
+	TimeFormat string
+
+	// RedactKeys are attribute keys whose values are masked, in addition to
+	// DefaultRedactKeys. Keys match case-insensitively, and "-" matches "_".
+	RedactKeys []string
+
+	// MaxValueLength truncates longer string values, in bytes (0 = no limit)
+	MaxValueLength int
+
+	// Levels sets the level of components by name, e.g. "video": "debug",
+	// for loggers made with Component. Others log at the logger's level.
+	Levels map[string]string
+
+	// Sampling thins out repetitive records (nil = write them all)
+	Sampling *Sampling
+}
+
+// NewWithOptions creates a new Logger that writes to w, masking the keys
+// in opts as well as the defaults and truncating long values. Records are
+// filtered by component level and sampled before anything else is done
+// with them.
+func NewWithOptions(level string, w io.Writer, opts Options) *Logger {
+	levels := newLevels(parseLevel(level), opts.Levels)
+
+	// The filter decides levels, so the handler lets through anything it
+	// might. Source file info is left out in both formats.
+	handlerOptions := &slog.HandlerOptions{
+		Level:     levels.minLevel(),
+		AddSource: false,
+	}
+	if opts.TimeFormat != "" {
+		handlerOptions.ReplaceAttr = formatTime(opts.TimeFormat)
+	}
+
+	// Without a format, use JSON in production and text in development
+	format := opts.Format
+	if format == FormatAuto {
+		format = FormatText
+		if os.Getenv("ENV") == "production" {
+			format = FormatJSON
+		}
+	}
 
-	// Use JSON handler in production, text handler in development
 	var handler slog.Handler
-	if os.Getenv("ENV") == "production" {
-		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{
-			Level:     lvl,
-			AddSource: false, // Don't add source file info in production
-		})
+	if format == FormatJSON {
+		handler = slog.NewJSONHandler(w, handlerOptions)
 	} else {
-		handler = slog.NewTextHandler(w, &slog.HandlerOptions{
-			Level:     lvl,
-			AddSource: false,
-		})
+		handler = slog.NewTextHandler(w, handlerOptions)
 	}
 
-	return &Logger{slog.New(handler)}
+	return &Logger{slog.New(newFilterHandler(newRedactHandler(handler, opts), levels, opts.Sampling))}
+}
+
+// formatTime returns a ReplaceAttr function writing record times with
+// layout.
+func formatTime(layout string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+			a.Value = slog.StringValue(a.Value.Time().Format(layout))
+		}
+		return a
+	}
 }
 
 // parseLevel converts a string level to slog.Level.
@@ -136,6 +214,50 @@ const (
 	ContextKeyAPIKey    ContextKey = "api_key"
 )
 
+// Annotations are key-value pairs recorded about a request while it's
+// handled, for logs written by code further out that can't see them
+// otherwise, e.g. panic recovery. Safe for concurrent use.
+type Annotations struct {
+	mu   sync.Mutex
+	args []any
+}
+
+type annotationsKey struct{}
+
+// WithAnnotations returns a context that collects annotations made with
+// Annotate, and the Annotations they're collected in.
+func WithAnnotations(ctx context.Context) (context.Context, *Annotations) {
+	a := &Annotations{}
+	return context.WithValue(ctx, annotationsKey{}, a), a
+}
+
+// Annotate records key and value in ctx's Annotations, replacing an
+// earlier value for key. It does nothing if ctx has none.
+// Example: logger.Annotate(ctx, "content_hash", hash)
+func Annotate(ctx context.Context, key string, value any) {
+	a, _ := ctx.Value(annotationsKey{}).(*Annotations)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := 0; i < len(a.args); i += 2 {
+		if a.args[i] == key {
+			a.args[i+1] = value
+			return
+		}
+	}
+	a.args = append(a.args, key, value)
+}
+
+// Args returns the annotations as key-value pairs for a log call, in the
+// order they were first made.
+func (a *Annotations) Args() []any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]any(nil), a.args...)
+}
+
 // NopLogger returns a logger that discards all output.
 // Useful for testing when log output is not needed.
 func NopLogger() *Logger {
@@ -3,8 +3,10 @@ package logger
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestNew verifies logger creation at different levels.
@@ -179,6 +181,78 @@ func TestWithContextNoRequestID(t *testing.T) {
 	}
 }
 
+// TestAnnotations verifies collecting annotations through a context.
+func TestAnnotations(t *testing.T) {
+	// Without Annotations in the context, Annotate does nothing
+	Annotate(context.Background(), "ignored", 1)
+
+	ctx, annotations := WithAnnotations(context.Background())
+	Annotate(ctx, "content_type", "text")
+	Annotate(ctx, "content_hash", "abc")
+	Annotate(ctx, "content_type", "image")
+
+	var buf bytes.Buffer
+	NewWithOptions("info", &buf, Options{Format: FormatText}).Info("annotated", annotations.Args()...)
+
+	output := buf.String()
+	if !strings.Contains(output, "content_type=image content_hash=abc") {
+		t.Errorf("annotations not in output: %s", output)
+	}
+}
+
+// TestFormat verifies an explicit format wins over the ENV variable, which
+// only decides when none is given.
+func TestFormat(t *testing.T) {
+	isJSON := func(opts Options) bool {
+		var buf bytes.Buffer
+		NewWithOptions("info", &buf, opts).Info("formatted", "key", "value")
+		var line map[string]any
+		return json.Unmarshal(buf.Bytes(), &line) == nil
+	}
+
+	t.Setenv("ENV", "production")
+	if !isJSON(Options{}) {
+		t.Error("expected JSON in production without a format")
+	}
+	if isJSON(Options{Format: FormatText}) {
+		t.Error("expected text with FormatText, whatever ENV says")
+	}
+
+	t.Setenv("ENV", "development")
+	if isJSON(Options{}) {
+		t.Error("expected text in development without a format")
+	}
+	if !isJSON(Options{Format: FormatJSON}) {
+		t.Error("expected JSON with FormatJSON, whatever ENV says")
+	}
+}
+
+// TestTimeFormat verifies record times are written with the given layout.
+func TestTimeFormat(t *testing.T) {
+	var buf bytes.Buffer
+	NewWithOptions("info", &buf, Options{Format: FormatJSON, TimeFormat: time.DateOnly}).Info("timed")
+
+	var line struct {
+		Time string `json:"time"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid JSON: %v: %s", err, buf.String())
+	}
+	if _, err := time.Parse(time.DateOnly, line.Time); err != nil {
+		t.Errorf("time = %q, want the %s layout", line.Time, time.DateOnly)
+	}
+
+	// Without one, times keep the slog default
+	buf.Reset()
+	NewWithOptions("info", &buf, Options{Format: FormatJSON}).Info("timed")
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid JSON: %v: %s", err, buf.String())
+	}
+	if _, err := time.Parse(time.RFC3339, line.Time); err != nil {
+		t.Errorf("time = %q, want RFC 3339", line.Time)
+	}
+}
+
 // TestNopLogger verifies that NopLogger discards all output.
 func TestNopLogger(t *testing.T) {
 	log := NopLogger()
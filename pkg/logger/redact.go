@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultRedactKeys are the attribute keys whose values are always masked:
+// credentials, and content users submitted. URL query parameters with these
+// names are masked too, wherever a URL appears in a string value.
+var DefaultRedactKeys = []string{
+	"api_key",
+	"x_api_key",
+	"authorization",
+	"cookie",
+	"password",
+	"secret",
+	"token",
+	"access_token",
+	"signature",
+	"x_amz_signature",
+	"x_amz_credential",
+	"x_amz_security_token",
+	"text",
+}
+
+// DefaultMaxValueLength is where New and NewWithWriter cut string values;
+// long enough for a stack trace.
+const DefaultMaxValueLength = 8192
+
+// redactedValue replaces masked values.
+const redactedValue = "[REDACTED]"
+
+// urlPattern finds URLs inside string values, e.g. in error messages.
+var urlPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// redactHandler is a slog.Handler that masks sensitive values and truncates
+// long ones before passing records on. Attributes added with With are
+// cleaned once, when they're added; group names are left alone.
+type redactHandler struct {
+	handler slog.Handler
+	keys    map[string]bool
+	maxLen  int
+}
+
+// newRedactHandler wraps handler with the redaction opts describes.
+func newRedactHandler(handler slog.Handler, opts Options) *redactHandler {
+	keys := make(map[string]bool, len(DefaultRedactKeys)+len(opts.RedactKeys))
+	for _, key := range DefaultRedactKeys {
+		keys[normalizeKey(key)] = true
+	}
+	for _, key := range opts.RedactKeys {
+		keys[normalizeKey(key)] = true
+	}
+	return &redactHandler{handler: handler, keys: keys, maxLen: opts.MaxValueLength}
+}
+
+// normalizeKey folds the spellings of a key that should match each other,
+// e.g. "X-Api-Key" and "x_api_key".
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	cleaned := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		cleaned.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.handler.Handle(ctx, cleaned)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	cleaned := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		cleaned[i] = h.redact(a)
+	}
+	return &redactHandler{handler: h.handler.WithAttrs(cleaned), keys: h.keys, maxLen: h.maxLen}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{handler: h.handler.WithGroup(name), keys: h.keys, maxLen: h.maxLen}
+}
+
+// redact returns a with its value masked if its key is sensitive, and
+// otherwise with URL secrets masked and long strings cut, all the way down
+// through groups.
+func (h *redactHandler) redact(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if h.keys[normalizeKey(a.Key)] {
+		return slog.String(a.Key, redactedValue)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		cleaned := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			cleaned[i] = h.redact(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(cleaned...)}
+	case slog.KindString:
+		return slog.String(a.Key, h.clean(a.Value.String()))
+	case slog.KindAny:
+		// Errors are logged as their message anyway, and often quote the
+		// URL a request failed on
+		if err, ok := a.Value.Any().(error); ok && err != nil {
+			return slog.String(a.Key, h.clean(err.Error()))
+		}
+	}
+	return a
+}
+
+// clean masks the secrets in any URLs in s, then truncates it.
+func (h *redactHandler) clean(s string) string {
+	if strings.Contains(s, "://") {
+		s = urlPattern.ReplaceAllStringFunc(s, h.redactURL)
+	}
+	return truncate(s, h.maxLen)
+}
+
+// redactURL masks the values of raw's sensitive query parameters, and any
+// password in it.
+func (h *redactHandler) redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			rawName, _, found := strings.Cut(param, "=")
+			name := rawName
+			if unescaped, err := url.QueryUnescape(rawName); err == nil {
+				name = unescaped
+			}
+			if found && h.keys[normalizeKey(name)] {
+				params[i] = rawName + "=" + redactedValue
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	return u.String()
+}
+
+// truncate cuts s to at most limit bytes, on a rune boundary, and says it
+// did.
+func truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...[truncated]"
+}
@@ -0,0 +1,299 @@
+// Package metrics records counters, gauges and histograms and exports them
+// in the Prometheus text format.
+//
+// Instrumented code only sees the Registry interface, so metrics can go to
+// another system (StatsD, OpenTelemetry, ...) by implementing it instead of
+// using NewRegistry.
+//
+// Usage:
+//
+//	reg := metrics.NewRegistry()
+//	requests := reg.Counter("app_requests_total", "Requests served.", "method")
+//	requests.Inc("GET")
+//	reg.WritePrometheus(os.Stdout)
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Counter is a count that only goes up, e.g. requests served.
+type Counter interface {
+	// Inc adds one to the series for labelValues, given in the order of
+	// the label names the counter was registered with.
+	Inc(labelValues ...string)
+}
+
+// Gauge is a value that goes up and down, e.g. requests in flight.
+type Gauge interface {
+	// Add adds delta (which may be negative) to the series for
+	// labelValues, given in the order of the label names the gauge was
+	// registered with.
+	Add(delta float64, labelValues ...string)
+}
+
+// Histogram counts observations into buckets, e.g. request latencies.
+type Histogram interface {
+	// Observe records value in the series for labelValues, given in the
+	// order of the label names the histogram was registered with.
+	Observe(value float64, labelValues ...string)
+}
+
+// Registry creates metrics. Registering a name again returns the existing
+// metric, so independent components can share one.
+type Registry interface {
+	Counter(name, help string, labelNames ...string) Counter
+	Gauge(name, help string, labelNames ...string) Gauge
+	Histogram(name, help string, buckets []float64, labelNames ...string) Histogram
+}
+
+// Exporter writes metrics in the Prometheus text exposition format.
+type Exporter interface {
+	WritePrometheus(w io.Writer) error
+}
+
+// DurationBuckets are histogram buckets for latencies in seconds, from
+// local analysis (milliseconds) to slow provider calls.
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// ScoreBuckets are histogram buckets for 0-1 scores. The 0 bucket counts
+// scores pinned to exactly 0, e.g. by conclusive provenance.
+var ScoreBuckets = []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// Discard is a Registry whose metrics record nothing.
+var Discard Registry = discard{}
+
+type discard struct{}
+
+func (discard) Counter(string, string, ...string) Counter { return discard{} }
+
+func (discard) Gauge(string, string, ...string) Gauge { return discard{} }
+
+func (discard) Histogram(string, string, []float64, ...string) Histogram { return discard{} }
+
+func (discard) Inc(...string) {}
+
+func (discard) Add(float64, ...string) {}
+
+func (discard) Observe(float64, ...string) {}
+
+// PromRegistry is an in-memory Registry that exports in the Prometheus
+// text format. It is safe for concurrent use.
+type PromRegistry struct {
+	mu      sync.Mutex
+	metrics map[string]*metric
+}
+
+// NewRegistry creates an empty PromRegistry.
+func NewRegistry() *PromRegistry {
+	return &PromRegistry{metrics: make(map[string]*metric)}
+}
+
+// Counter registers a counter, or returns the one already registered
+// under name. It panics if name is registered as a different kind or with
+// different labels.
+func (r *PromRegistry) Counter(name, help string, labelNames ...string) Counter {
+	return r.register(name, help, "counter", nil, labelNames)
+}
+
+// Gauge registers a gauge, or returns the one already registered under
+// name. It panics if name is registered as a different kind or with
+// different labels.
+func (r *PromRegistry) Gauge(name, help string, labelNames ...string) Gauge {
+	return r.register(name, help, "gauge", nil, labelNames)
+}
+
+// Histogram registers a histogram with the given upper bucket bounds
+// (ascending; +Inf is implied), or returns the one already registered
+// under name. It panics if name is registered as a different kind or with
+// different labels.
+func (r *PromRegistry) Histogram(name, help string, buckets []float64, labelNames ...string) Histogram {
+	return r.register(name, help, "histogram", buckets, labelNames)
+}
+
+func (r *PromRegistry) register(name, help, kind string, buckets []float64, labelNames []string) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		if m.kind != kind || strings.Join(m.labelNames, ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metrics: %s already registered as a %s with labels %v", name, m.kind, m.labelNames))
+		}
+		return m
+	}
+
+	m := &metric{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.metrics[name] = m
+	return m
+}
+
+// WritePrometheus writes every metric in the Prometheus text format,
+// sorted by name and label values.
+func (r *PromRegistry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]*metric, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	var b strings.Builder
+	for _, m := range metrics {
+		m.write(&b)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metric is a registered counter, gauge or histogram and its labeled
+// series.
+type metric struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one combination of label values.
+type series struct {
+	labelValues []string
+
+	// value is the counter or gauge value
+	value float64
+
+	// counts are per-bucket (not cumulative) histogram counts, with the
+	// +Inf bucket last
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (m *metric) Inc(labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value++
+}
+
+func (m *metric) Add(delta float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value += delta
+}
+
+func (m *metric) Observe(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.get(labelValues)
+	i := sort.SearchFloat64s(m.buckets, value)
+	s.counts[i]++
+	s.sum += value
+	s.count++
+}
+
+// get returns the series for labelValues, creating it if needed.
+// The caller must hold m.mu.
+func (m *metric) get(labelValues []string) *series {
+	if len(labelValues) != len(m.labelNames) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", m.name, len(m.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if m.kind == "histogram" {
+			s.counts = make([]uint64, len(m.buckets)+1)
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// write appends m in the text exposition format.
+func (m *metric) write(b *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+		if m.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", m.name, m.labels(s, ""), formatFloat(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, n := range s.counts {
+			cumulative += n
+			le := math.Inf(1)
+			if i < len(m.buckets) {
+				le = m.buckets[i]
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", m.name, m.labels(s, formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_sum%s %s\n", m.name, m.labels(s, ""), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", m.name, m.labels(s, ""), s.count)
+	}
+}
+
+// labels formats s's labels, plus le for histogram buckets, as {a="x",...}.
+func (m *metric) labels(s *series, le string) string {
+	pairs := make([]string, 0, len(m.labelNames)+1)
+	for i, name := range m.labelNames {
+		pairs = append(pairs, name+`="`+escapeLabel(s.labelValues[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
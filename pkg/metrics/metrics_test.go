@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+// scrape returns reg's exposition output.
+func scrape(t *testing.T, reg *PromRegistry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := reg.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	return b.String()
+}
+
+// TestCounter tests counter series and output.
+func TestCounter(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.Counter("requests_total", "Requests served.", "method", "code")
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Inc("POST", "500")
+
+	want := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 2
+requests_total{method="POST",code="500"} 1
+`
+	if got := scrape(t, reg); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestGauge tests that gauges go up and down.
+func TestGauge(t *testing.T) {
+	reg := NewRegistry()
+	g := reg.Gauge("in_flight", "Requests in flight.", "pool")
+	g.Add(1, "video")
+	g.Add(1, "video")
+	g.Add(-1, "video")
+	g.Add(1, "text")
+	g.Add(-1, "text")
+
+	want := `# HELP in_flight Requests in flight.
+# TYPE in_flight gauge
+in_flight{pool="text"} 0
+in_flight{pool="video"} 1
+`
+	if got := scrape(t, reg); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestHistogram tests cumulative bucket output.
+func TestHistogram(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.Histogram("latency_seconds", "Latency.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.1) // Bounds are inclusive
+	h.Observe(3)
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 3.15
+latency_seconds_count 3
+`
+	if got := scrape(t, reg); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestRegisterTwice tests that registration is shared by name.
+func TestRegisterTwice(t *testing.T) {
+	reg := NewRegistry()
+	reg.Counter("jobs_total", "Jobs.", "queue").Inc("default")
+	reg.Counter("jobs_total", "Jobs.", "queue").Inc("default")
+
+	if out := scrape(t, reg); !strings.Contains(out, `jobs_total{queue="default"} 2`) {
+		t.Errorf("re-registering should share the counter, got:\n%s", out)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering with different labels should panic")
+		}
+	}()
+	reg.Counter("jobs_total", "Jobs.", "queue", "priority")
+}
+
+// TestLabelEscaping tests escaping of label values.
+func TestLabelEscaping(t *testing.T) {
+	reg := NewRegistry()
+	reg.Counter("errors_total", "Errors.", "message").Inc("bad \"input\"\n")
+
+	if out := scrape(t, reg); !strings.Contains(out, `errors_total{message="bad \"input\"\n"} 1`) {
+		t.Errorf("label not escaped:\n%s", out)
+	}
+}
+
+// TestDiscard tests the no-op registry.
+func TestDiscard(t *testing.T) {
+	// Must not panic on any label count
+	Discard.Counter("c", "").Inc("a", "b")
+	Discard.Histogram("h", "", DurationBuckets).Observe(1)
+}
@@ -0,0 +1,107 @@
+// Package minhash fingerprints text for near-duplicate search.
+//
+// A MinHash signature summarizes the set of word shingles (overlapping
+// three-word runs) in a text. The fraction of positions at which two
+// signatures agree estimates the Jaccard similarity of the shingle sets, so
+// lightly edited copies of the same text score close to 1 while unrelated
+// texts score close to 0.
+//
+// Usage:
+//
+//	a := minhash.Signature(original)
+//	b := minhash.Signature(edited)
+//	if minhash.Similarity(a, b) > 0.7 {
+//		// probably the same content
+//	}
+package minhash
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// Size is the number of hash functions, i.e. the signature length.
+// The similarity estimate's standard error is about 1/sqrt(Size).
+const Size = 128
+
+// ShingleWords is the number of words in each shingle.
+const ShingleWords = 3
+
+// seeds are the per-position hash seeds, fixed so signatures stay
+// comparable across processes and releases.
+var seeds = func() [Size]uint64 {
+	var s [Size]uint64
+	x := uint64(0x9E3779B97F4A7C15)
+	for i := range s {
+		x = splitmix64(x)
+		s[i] = x
+	}
+	return s
+}()
+
+// Signature returns the MinHash signature of text. Case and punctuation
+// are ignored. Returns nil if text has no words.
+func Signature(text string) []uint32 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+
+	sig := make([]uint32, Size)
+	for i := range sig {
+		sig[i] = ^uint32(0)
+	}
+
+	// Texts shorter than a shingle are one shingle
+	n := len(words) - ShingleWords + 1
+	if n < 1 {
+		n = 1
+	}
+
+	for i := 0; i < n; i++ {
+		end := i + ShingleWords
+		if end > len(words) {
+			end = len(words)
+		}
+
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		shingle := h.Sum64()
+
+		for j, seed := range seeds {
+			if v := uint32(splitmix64(shingle ^ seed)); v < sig[j] {
+				sig[j] = v
+			}
+		}
+	}
+
+	return sig
+}
+
+// Similarity estimates the Jaccard similarity (0.0-1.0) of the texts behind
+// two signatures. Signatures of different lengths aren't comparable and
+// score 0.
+func Similarity(a, b []uint32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// splitmix64 is a fast, well-mixed 64-bit hash step.
+func splitmix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,71 @@
+package minhash
+
+import (
+	"testing"
+)
+
+const essay = `Artificial intelligence has transformed the way businesses operate in the modern era.
+Organizations across industries are leveraging machine learning to streamline their workflows,
+enhance customer experiences, and unlock valuable insights from their data. Furthermore, it is
+important to note that these technologies continue to evolve at a remarkable pace, creating both
+opportunities and challenges for leaders who must navigate this rapidly changing landscape.`
+
+// paraphrase swaps a few words and the punctuation, as a user dodging an
+// exact-match check would.
+const paraphrase = `Artificial intelligence has transformed the way companies operate in the modern era.
+Organizations across industries are leveraging machine learning to streamline their workflows,
+enhance customer experiences and unlock valuable insights from their data! Furthermore, it is
+important to note that these technologies continue to evolve at a remarkable pace, creating both
+opportunities and challenges for executives who must navigate this rapidly changing landscape.`
+
+const unrelated = `We drove up to the lake on Saturday but the cabin key wasn't under the mat,
+so we ended up sleeping in the car. My brother snored the whole night and the dog kept
+barking at raccoons. Honestly it was still the best weekend we've had all summer.`
+
+// TestSimilarity tests similarity estimates for related and unrelated texts.
+func TestSimilarity(t *testing.T) {
+	base := Signature(essay)
+
+	if len(base) != Size {
+		t.Fatalf("len(Signature()) = %d, want %d", len(base), Size)
+	}
+
+	if got := Similarity(base, Signature(essay)); got != 1 {
+		t.Errorf("identical text similarity = %f, want 1", got)
+	}
+	if got := Similarity(base, Signature(paraphrase)); got <= 0.7 {
+		t.Errorf("paraphrase similarity = %f, want > 0.7", got)
+	}
+	if got := Similarity(base, Signature(unrelated)); got >= 0.2 {
+		t.Errorf("unrelated similarity = %f, want < 0.2", got)
+	}
+}
+
+// TestSignature tests normalization and edge cases.
+func TestSignature(t *testing.T) {
+	t.Run("ignores case and punctuation", func(t *testing.T) {
+		a := Signature("The quick brown fox jumps over the lazy dog.")
+		b := Signature("the QUICK brown fox -- jumps over the lazy dog")
+		if Similarity(a, b) != 1 {
+			t.Errorf("similarity = %f, want 1", Similarity(a, b))
+		}
+	})
+
+	t.Run("short text", func(t *testing.T) {
+		if sig := Signature("hello there"); len(sig) != Size {
+			t.Errorf("len = %d, want %d", len(sig), Size)
+		}
+	})
+
+	t.Run("no words", func(t *testing.T) {
+		if sig := Signature(" ... "); sig != nil {
+			t.Errorf("Signature() = %v, want nil", sig)
+		}
+	})
+
+	t.Run("mismatched lengths", func(t *testing.T) {
+		if got := Similarity(Signature(essay), Signature(essay)[:10]); got != 0 {
+			t.Errorf("similarity = %f, want 0", got)
+		}
+	})
+}
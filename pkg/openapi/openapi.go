@@ -0,0 +1,430 @@
+// Package openapi builds OpenAPI 3.1 documents. The JSON Schemas of
+// request and response bodies are generated from the Go types the handlers
+// encode, so the spec can't drift from the wire format.
+//
+// Usage:
+//
+//	doc := openapi.New(openapi.Info{Title: "HumanMark API", Version: "1.0.0"})
+//	doc.Add("GET", "/verify/{id}", &openapi.Operation{
+//		Summary: "Get a verification result",
+//		Responses: map[string]*openapi.Response{
+//			"200": doc.JSONResponse("The result", VerifyResponse{}),
+//		},
+//	})
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version documents are written in.
+const Version = "3.1.0"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+
+	// types maps component schema names to the types they were built
+	// from, so two types with the same name are caught
+	types map[string]reflect.Type
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds a path's operations by lower-case method.
+type PathItem map[string]*Operation
+
+// Operation is one method on one path.
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+// SecurityRequirement names the security schemes an operation accepts.
+type SecurityRequirement map[string][]string
+
+// Anonymous is an Operation.Security that lets requests without
+// credentials through.
+var Anonymous = []SecurityRequirement{{}}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// Query returns an optional query parameter of the given schema.
+func Query(name string, schema *Schema, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// RequestBody is an operation's request body, by media type.
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType is a body's schema and an example of it for one media type.
+type MediaType struct {
+	Schema  *Schema `json:"schema,omitempty"`
+	Example any     `json:"example,omitempty"`
+}
+
+// Response is one status code's response.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components holds the schemas referenced from the rest of the document.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way to authenticate, e.g. an API key header.
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+}
+
+// Schema is a JSON Schema, in the 2020-12 dialect OpenAPI 3.1 uses. Only
+// the keywords this package generates or validates are supported.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	ContentMediaType     string             `json:"contentMediaType,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// Types is a schema's "type": one JSON type, written as a string, or
+// several, written as an array.
+type Types []string
+
+// MarshalJSON writes a single type as a string.
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON reads a type string or array.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// String returns a string schema.
+func String(description string) *Schema {
+	return &Schema{Type: Types{"string"}, Description: description}
+}
+
+// Enum returns a string schema allowing only values.
+func Enum(description string, values ...string) *Schema {
+	s := String(description)
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+// Boolean returns a boolean schema.
+func Boolean(description string) *Schema {
+	return &Schema{Type: Types{"boolean"}, Description: description}
+}
+
+// Integer returns an integer schema bounded by min and max (inclusive).
+func Integer(description string, min, max float64) *Schema {
+	return &Schema{Type: Types{"integer"}, Description: description, Minimum: &min, Maximum: &max}
+}
+
+// Binary returns the schema of a file upload.
+func Binary(description string) *Schema {
+	return &Schema{Type: Types{"string"}, Description: description, ContentMediaType: "application/octet-stream"}
+}
+
+// Object returns an object schema with the given properties, of which
+// required must be present.
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: Types{"object"}, Properties: properties, Required: required}
+}
+
+// ArrayOf returns an array schema of items.
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: Types{"array"}, Items: items}
+}
+
+// AnyOf returns a schema matching any of schemas.
+func AnyOf(schemas ...*Schema) *Schema {
+	return &Schema{AnyOf: schemas}
+}
+
+// New returns a document with no paths.
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// Add adds an operation. Parameters for the path's {name} segments are
+// added unless op declares them.
+func (d *Document) Add(method, path string, op *Operation) {
+	for rest := path; ; {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start == -1 || end < start {
+			break
+		}
+		name := rest[start+1 : end]
+		rest = rest[end+1:]
+		if !hasParameter(op.Parameters, name, "path") {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: String("")})
+		}
+	}
+
+	item := d.Paths[path]
+	if item == nil {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// hasParameter reports whether params has a parameter name in in.
+func hasParameter(params []Parameter, name, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// Operation returns the operation for method on path, or nil.
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// JSONResponse returns a response whose application/json body has v's
+// type. v is also the example, unless it's a zero value.
+func (d *Document) JSONResponse(description string, v any) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": d.mediaType(v)},
+	}
+}
+
+// JSONBody returns a required request body of v's type, with v as the
+// example unless it's a zero value.
+func (d *Document) JSONBody(v any) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{"application/json": d.mediaType(v)},
+	}
+}
+
+// mediaType returns the schema of v's type, with v as its example.
+func (d *Document) mediaType(v any) MediaType {
+	m := MediaType{Schema: d.Schema(v)}
+	if !reflect.ValueOf(v).IsZero() {
+		m.Example = v
+	}
+	return m
+}
+
+// Schema returns the schema of v's type. Named struct types are added to
+// the components and referenced; their fields are described by their json
+// tags, with fields that aren't omitempty required (and nullable if they
+// can be nil). Fields of embedded structs are inlined. time.Time is a
+// date-time string and json.RawMessage is any value.
+//
+// Schema panics if two different types have the same name, since only one
+// can be a component.
+func (d *Document) Schema(v any) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaOf returns the schema of t.
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return d.schemaOf(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: Types{"integer"}, Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice, reflect.Array:
+		// encoding/json writes []byte as base64
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, ContentMediaType: "application/octet-stream"}
+		}
+		return ArrayOf(d.schemaOf(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return d.component(t)
+	}
+	// Interfaces and anything else can hold any value
+	return &Schema{}
+}
+
+// component adds the named struct type t to the components, if it isn't
+// there yet, and returns a reference to it.
+func (d *Document) component(t reflect.Type) *Schema {
+	name := t.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if existing, ok := d.types[name]; ok {
+		if existing != t {
+			panic(fmt.Sprintf("openapi: %s and %s are both named %s", existing.PkgPath(), t.PkgPath(), name))
+		}
+		return ref
+	}
+
+	// Registered before its fields, so a type can refer to itself
+	d.types[name] = t
+	s := &Schema{}
+	d.Components.Schemas[name] = s
+	*s = *d.structSchema(t)
+	return ref
+}
+
+// structSchema returns the object schema of the struct type t.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := Object(make(map[string]*Schema))
+	d.addFields(s, t)
+	return s
+}
+
+// addFields adds t's JSON fields to the object schema s.
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addFields(s, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := d.schemaOf(field.Type)
+		if !hasOption(options, "omitempty") {
+			s.Required = append(s.Required, name)
+			switch field.Type.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+				schema = nullable(schema)
+			}
+		}
+		s.Properties[name] = schema
+	}
+}
+
+// hasOption reports whether a json tag's comma-separated options include
+// option.
+func hasOption(options, option string) bool {
+	for options != "" {
+		var o string
+		o, options, _ = strings.Cut(options, ",")
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable returns s allowing null too.
+func nullable(s *Schema) *Schema {
+	switch {
+	case s.Ref != "" || len(s.AnyOf) > 0:
+		return AnyOf(s, &Schema{Type: Types{"null"}})
+	case len(s.Type) == 0:
+		return s // any value, null included
+	}
+	copied := *s
+	copied.Type = append(append(Types(nil), s.Type...), "null")
+	return &copied
+}
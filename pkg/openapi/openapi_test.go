@@ -0,0 +1,201 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testInner struct {
+	Name string `json:"name"`
+}
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testOuter struct {
+	testBase
+
+	Count     int              `json:"count"`
+	Score     float64          `json:"score,omitempty"`
+	Tags      []string         `json:"tags"`
+	Labels    map[string]int64 `json:"labels,omitempty"`
+	Inner     *testInner       `json:"inner,omitempty"`
+	Required  *testInner       `json:"required_inner"`
+	Items     []testInner      `json:"items,omitempty"`
+	At        time.Time        `json:"at"`
+	Raw       json.RawMessage  `json:"raw,omitempty"`
+	Skipped   string           `json:"-"`
+	unexposed string
+	Untagged  bool
+	Nested    struct{ A int }   `json:"nested"`
+	Self      *testOuter        `json:"self,omitempty"`
+	Extra     map[string]string `json:"extra"`
+}
+
+// TestSchema tests the schemas generated from Go types.
+func TestSchema(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	ref := doc.Schema(testOuter{})
+
+	if ref.Ref != "#/components/schemas/testOuter" {
+		t.Fatalf("expected a reference, got %+v", ref)
+	}
+	s := doc.Components.Schemas["testOuter"]
+	if s == nil {
+		t.Fatal("expected testOuter in components")
+	}
+
+	wantRequired := []string{"id", "count", "tags", "required_inner", "at", "Untagged", "nested", "extra"}
+	if !reflect.DeepEqual(s.Required, wantRequired) {
+		t.Errorf("required = %v, want %v", s.Required, wantRequired)
+	}
+	for _, name := range []string{"Skipped", "-", "unexposed"} {
+		if _, ok := s.Properties[name]; ok {
+			t.Errorf("unexpected property %s", name)
+		}
+	}
+
+	types := map[string]Types{
+		"id":       {"string"},
+		"count":    {"integer"},
+		"score":    {"number"},
+		"tags":     {"array", "null"},
+		"labels":   {"object"},
+		"at":       {"string"},
+		"Untagged": {"boolean"},
+		"nested":   {"object"},
+		"extra":    {"object", "null"},
+	}
+	for name, want := range types {
+		if got := s.Properties[name].Type; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: type %v, want %v", name, got, want)
+		}
+	}
+	if s.Properties["at"].Format != "date-time" {
+		t.Errorf("expected date-time format, got %q", s.Properties["at"].Format)
+	}
+	if s.Properties["inner"].Ref != "#/components/schemas/testInner" {
+		t.Errorf("expected optional pointer to reference testInner, got %+v", s.Properties["inner"])
+	}
+	if options := s.Properties["required_inner"].AnyOf; len(options) != 2 || options[1].Type[0] != "null" {
+		t.Errorf("expected required pointer to be nullable, got %+v", s.Properties["required_inner"])
+	}
+	if s.Properties["self"].Ref != "#/components/schemas/testOuter" {
+		t.Errorf("expected recursive reference, got %+v", s.Properties["self"])
+	}
+	if s.Properties["items"].Items.Ref != "#/components/schemas/testInner" {
+		t.Errorf("expected array of testInner, got %+v", s.Properties["items"])
+	}
+	if _, ok := doc.Components.Schemas["testBase"]; ok {
+		t.Error("embedded struct should be inlined, not a component")
+	}
+}
+
+// TestSchemaNameClash tests that two types with the same name are caught.
+func TestSchemaNameClash(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	doc.Schema(testInner{})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for two types named testInner")
+		}
+	}()
+	type testInner struct{}
+	doc.Schema(testInner{})
+}
+
+// TestAdd tests operations and their path parameters.
+func TestAdd(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	doc.Add("GET", "/jobs/{id}/files/{name}", &Operation{
+		Parameters: []Parameter{{Name: "name", In: "path", Required: true, Description: "File name"}},
+	})
+
+	op := doc.Operation("get", "/jobs/{id}/files/{name}")
+	if op == nil {
+		t.Fatal("expected the operation")
+	}
+	if len(op.Parameters) != 2 || op.Parameters[0].Description != "File name" || op.Parameters[1].Name != "id" {
+		t.Errorf("unexpected parameters %+v", op.Parameters)
+	}
+}
+
+// TestDocumentRoundTrip tests that a marshaled document reads back the same.
+func TestDocumentRoundTrip(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	doc.Add("POST", "/items", &Operation{
+		RequestBody: doc.JSONBody(testInner{Name: "a"}),
+		Responses:   map[string]*Response{"200": doc.JSONResponse("The item", testOuter{})},
+	})
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"type":["array","null"]`) || !strings.Contains(string(data), `"type":"string"`) {
+		t.Errorf("unexpected type encoding: %s", data)
+	}
+
+	var read Document
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	again, err := json.Marshal(&read)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("round trip changed the document:\n%s\n%s", data, again)
+	}
+}
+
+// TestValidate tests validating JSON against generated schemas.
+func TestValidate(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	schema := doc.Schema(testOuter{})
+
+	valid := `{"id":"a","count":1,"tags":null,"required_inner":{"name":"x"},"at":"2024-01-01T00:00:00Z",
+		"Untagged":false,"nested":{"A":1},"extra":{},"items":[{"name":"y"}],"unknown":true}`
+	if errs := doc.Validate(schema, []byte(valid)); len(errs) > 0 {
+		t.Errorf("expected valid, got %v", errs)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"missing field", `{"count":1,"tags":[],"required_inner":null,"at":"","Untagged":true,"nested":{"A":1},"extra":null}`, "$.id: missing"},
+		{"wrong type", `{"id":1,"count":1,"tags":[],"required_inner":null,"at":"","Untagged":true,"nested":{"A":1},"extra":null}`, "$.id: expected string"},
+		{"fractional integer", `{"id":"a","count":1.5,"tags":[],"required_inner":null,"at":"","Untagged":true,"nested":{"A":1},"extra":null}`, "$.count: expected integer"},
+		{"null not allowed", `{"id":"a","count":1,"tags":[],"required_inner":null,"at":"","Untagged":true,"nested":null,"extra":null}`, "$.nested: expected object"},
+		{"nested item", `{"id":"a","count":1,"tags":[2],"required_inner":null,"at":"","Untagged":true,"nested":{"A":1},"extra":null}`, "$.tags[0]: expected string"},
+		{"map value", `{"id":"a","count":1,"tags":[],"required_inner":null,"at":"","Untagged":true,"nested":{"A":1},"extra":null,"labels":{"a":"b"}}`, "$.labels.a: expected integer"},
+		{"referenced schema", `{"id":"a","count":1,"tags":[],"required_inner":{},"at":"","Untagged":true,"nested":{"A":1},"extra":null}`, "$.required_inner: matches none"},
+		{"not JSON", `{`, "body is not JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := doc.Validate(schema, []byte(tt.body))
+			if len(errs) == 0 || !strings.HasPrefix(strings.Join(errs, "; "), tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, errs)
+			}
+		})
+	}
+
+	t.Run("bounds and enums", func(t *testing.T) {
+		s := Object(map[string]*Schema{
+			"limit": Integer("", 1, 100),
+			"mode":  Enum("", "page"),
+		})
+		errs := doc.Validate(s, []byte(`{"limit":0,"mode":"site"}`))
+		if len(errs) != 2 {
+			t.Errorf("expected 2 errors, got %v", errs)
+		}
+	})
+}
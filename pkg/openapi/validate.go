@@ -0,0 +1,119 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Validate checks a JSON body against schema, resolving references in d,
+// and returns one message per violation. Properties a schema doesn't
+// declare are allowed, so clients can rely on the spec while fields are
+// added.
+func (d *Document) Validate(schema *Schema, body []byte) []string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{"body is not JSON: " + err.Error()}
+	}
+	var errs []string
+	d.validate(schema, value, "$", &errs)
+	return errs
+}
+
+// validate appends value's violations of s, at path, to errs.
+func (d *Document) validate(s *Schema, value any, path string, errs *[]string) {
+	if s.Ref != "" {
+		resolved := d.resolve(s.Ref)
+		if resolved == nil {
+			*errs = append(*errs, path+": unknown schema "+s.Ref)
+			return
+		}
+		s = resolved
+	}
+
+	if len(s.AnyOf) > 0 {
+		for _, option := range s.AnyOf {
+			var optionErrs []string
+			d.validate(option, value, path, &optionErrs)
+			if len(optionErrs) == 0 {
+				return
+			}
+		}
+		*errs = append(*errs, path+": matches none of the allowed schemas")
+		return
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(value, t) }) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s", path, strings.Join(s.Type, " or ")))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, value) {
+		*errs = append(*errs, fmt.Sprintf("%s: %v is not one of %v", path, value, s.Enum))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			*errs = append(*errs, fmt.Sprintf("%s: %g below minimum %g", path, v, *s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			*errs = append(*errs, fmt.Sprintf("%s: %g above maximum %g", path, v, *s.Maximum))
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, path+"."+name+": missing")
+			}
+		}
+		// Sorted, so the messages come in a stable order
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				d.validate(property, v[name], path+"."+name, errs)
+			} else if s.AdditionalProperties != nil {
+				d.validate(s.AdditionalProperties, v[name], path+"."+name, errs)
+			}
+		}
+	}
+}
+
+// resolve returns the component schema ref points to, or nil.
+func (d *Document) resolve(ref string) *Schema {
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		return nil
+	}
+	return d.Components.Schemas[name]
+}
+
+// hasType reports whether a decoded JSON value has the JSON Schema type t.
+func hasType(value any, t string) bool {
+	switch v := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case float64:
+		return t == "number" || t == "integer" && v == math.Trunc(v)
+	case string:
+		return t == "string"
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
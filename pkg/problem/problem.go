@@ -0,0 +1,101 @@
+// Package problem writes error responses as RFC 9457 problem details:
+//
+//	HTTP/1.1 429 Too Many Requests
+//	Content-Type: application/problem+json
+//
+//	{
+//	  "type": "urn:humanmark:error:rate_limited",
+//	  "title": "Too Many Requests",
+//	  "status": 429,
+//	  "detail": "rate limit exceeded",
+//	  "instance": "urn:humanmark:request:4f2c9a1b7d3e8f60",
+//	  "code": "rate_limited",
+//	  "retry_after": 12
+//	}
+//
+// "code" is the machine-readable error code clients switch on; "type" is
+// the same code as a URI, as the RFC asks for. "instance" names the
+// request by its X-Request-ID, so a report of an error can be matched to
+// the server's logs.
+//
+// Every error response in the API is written with Write, so handlers and
+// middleware share one shape.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// TypePrefix is prefixed to an error code to make a problem's type.
+const TypePrefix = "urn:humanmark:error:"
+
+// InstancePrefix is prefixed to a request ID to make a problem's instance.
+const InstancePrefix = "urn:humanmark:request:"
+
+// Problem is the body of an error response.
+type Problem struct {
+	// Type identifies the kind of error: TypePrefix and Code, or
+	// "about:blank" for errors without a code
+	Type string `json:"type"`
+
+	// Title is the HTTP status text
+	Title string `json:"title"`
+
+	// Status is the HTTP status code
+	Status int `json:"status"`
+
+	// Detail explains this occurrence of the error
+	Detail string `json:"detail,omitempty"`
+
+	// Instance identifies the request that failed (see InstancePrefix)
+	Instance string `json:"instance,omitempty"`
+
+	// Code is the machine-readable error code
+	Code string `json:"code,omitempty"`
+
+	// RetryAfter is the Retry-After header in seconds, for 429 and 503
+	// responses that set it
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// New returns the problem for an error with status, code and detail.
+func New(status int, code, detail string) *Problem {
+	typ := "about:blank"
+	if code != "" {
+		typ = TypePrefix + code
+	}
+	return &Problem{
+		Type:   typ,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Write writes p as the response. Its Instance is taken from the
+// X-Request-ID response header if it isn't set. Write overrides any
+// Content-Type already set and drops Content-Length. The error is the
+// body's write error.
+func Write(w http.ResponseWriter, p *Problem) error {
+	if p.Instance == "" {
+		if id := w.Header().Get("X-Request-ID"); id != "" {
+			p.Instance = InstancePrefix + url.PathEscape(id)
+		}
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// Error writes the problem for status, code and detail; see New and Write.
+func Error(w http.ResponseWriter, status int, code, detail string) error {
+	return Write(w, New(status, code, detail))
+}
@@ -0,0 +1,83 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWrite tests the response Write produces.
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "application/json")
+	rec.Header().Set("Content-Length", "12")
+	rec.Header().Set("X-Request-ID", "a1b2c3")
+
+	p := New(http.StatusTooManyRequests, "rate_limited", "rate limit exceeded")
+	p.RetryAfter = 30
+	if err := Write(rec, p); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %s", ct, ContentType)
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("expected Content-Length dropped, got %q", cl)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := map[string]any{
+		"type":        "urn:humanmark:error:rate_limited",
+		"title":       "Too Many Requests",
+		"status":      float64(429),
+		"detail":      "rate limit exceeded",
+		"instance":    "urn:humanmark:request:a1b2c3",
+		"code":        "rate_limited",
+		"retry_after": float64(30),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected members in %v", got)
+	}
+}
+
+// TestWrite_Defaults tests problems without a code or request ID.
+func TestWrite_Defaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := Error(rec, http.StatusInternalServerError, "", "internal server error"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := Problem{Type: "about:blank", Title: "Internal Server Error", Status: 500, Detail: "internal server error"}
+	if p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+
+	t.Run("request IDs are escaped", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("X-Request-ID", "from proxy/1")
+		Error(rec, http.StatusNotFound, "not_found", "not found")
+
+		var p Problem
+		json.Unmarshal(rec.Body.Bytes(), &p)
+		if p.Instance != InstancePrefix+"from%20proxy%2F1" {
+			t.Errorf("instance = %q", p.Instance)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+// Package oteltracing sends tracing spans to OpenTelemetry.
+//
+// It is its own module, so the OpenTelemetry modules stay out of the main
+// module's go.mod. The server uses it when built with -tags otel in a
+// workspace that includes this module:
+//
+//	go work init . ./pkg/tracing/oteltracing
+//	go build -tags otel ./cmd/api
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/humanmark/humanmark/pkg/tracing"
+)
+
+// NewFromEnv exports spans over OTLP/HTTP when an OTLP endpoint is set,
+// and returns tracing.Discard otherwise. The exporter reads the standard
+// OTEL_* environment variables itself (endpoint, headers,
+// OTEL_SERVICE_NAME, sampling, ...). The returned func flushes spans still
+// buffered at shutdown.
+func NewFromEnv(ctx context.Context) (tracing.Tracer, func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return tracing.Discard, nil, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return New(provider.Tracer("github.com/humanmark/humanmark")), provider.Shutdown, nil
+}
+
+// New returns a tracing.Tracer that starts its spans with t.
+func New(t trace.Tracer) tracing.Tracer {
+	return tracer{t: t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+// Start starts an OpenTelemetry span. A span without a local parent is
+// where a request enters the service, so it's started as a server span.
+func (t tracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	opts := []trace.SpanStartOption{trace.WithAttributes(convert(attrs)...)}
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		opts = append(opts, trace.WithSpanKind(trace.SpanKindServer))
+		if remote, ok := tracing.RemoteFromContext(ctx); ok {
+			var flags trace.TraceFlags
+			if remote.Sampled {
+				flags = trace.FlagsSampled
+			}
+			ctx = trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    remote.TraceID,
+				SpanID:     remote.SpanID,
+				TraceFlags: flags,
+				Remote:     true,
+			}))
+		}
+	}
+
+	ctx, s := t.t.Start(ctx, name, opts...)
+	sp := span{s: s}
+	return tracing.ContextWithSpan(ctx, sp), sp
+}
+
+type span struct {
+	s trace.Span
+}
+
+func (sp span) SetAttributes(attrs ...tracing.Attribute) {
+	sp.s.SetAttributes(convert(attrs)...)
+}
+
+func (sp span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	sp.s.RecordError(err)
+	sp.s.SetStatus(codes.Error, err.Error())
+}
+
+func (sp span) End() {
+	sp.s.End()
+}
+
+func (sp span) SpanContext() tracing.SpanContext {
+	sc := sp.s.SpanContext()
+	return tracing.SpanContext{
+		TraceID: sc.TraceID(),
+		SpanID:  sc.SpanID(),
+		Sampled: sc.IsSampled(),
+	}
+}
+
+// convert turns tracing attributes into OpenTelemetry ones.
+func convert(attrs []tracing.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
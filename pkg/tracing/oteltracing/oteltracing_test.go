@@ -0,0 +1,118 @@
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/humanmark/humanmark/pkg/tracing"
+)
+
+// newTestTracer returns a tracer whose ended spans go to the recorder.
+func newTestTracer() (tracing.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return New(provider.Tracer("test")), recorder
+}
+
+// TestTracer tests that spans reach OpenTelemetry with their parents,
+// kinds, attributes and errors.
+func TestTracer(t *testing.T) {
+	t.Run("spans nest", func(t *testing.T) {
+		tracer, recorder := newTestTracer()
+
+		ctx, root := tracer.Start(context.Background(), "POST /api/v1/verify", tracing.String("route", "/api/v1/verify"))
+		_, child := tracer.Start(ctx, "humanmark.detect", tracing.Int("size", 42), tracing.Float64("score", 0.5), tracing.Bool("cached", false))
+		child.End()
+		root.End()
+
+		spans := recorder.Ended()
+		if len(spans) != 2 {
+			t.Fatalf("recorded %d spans, want 2", len(spans))
+		}
+		detect, request := spans[0], spans[1]
+		if request.SpanKind() != trace.SpanKindServer {
+			t.Errorf("root span kind = %v, want server", request.SpanKind())
+		}
+		if detect.SpanKind() != trace.SpanKindInternal {
+			t.Errorf("child span kind = %v, want internal", detect.SpanKind())
+		}
+		if detect.Parent().SpanID() != request.SpanContext().SpanID() || detect.SpanContext().TraceID() != request.SpanContext().TraceID() {
+			t.Error("child span isn't under the root span")
+		}
+
+		want := map[attribute.Key]attribute.Value{
+			"size":   attribute.IntValue(42),
+			"score":  attribute.Float64Value(0.5),
+			"cached": attribute.BoolValue(false),
+		}
+		for _, kv := range detect.Attributes() {
+			if v, ok := want[kv.Key]; ok && v != kv.Value {
+				t.Errorf("attribute %s = %v, want %v", kv.Key, kv.Value.Emit(), v.Emit())
+			}
+			delete(want, kv.Key)
+		}
+		if len(want) > 0 {
+			t.Errorf("missing attributes %v", want)
+		}
+
+		sc := child.SpanContext()
+		if sc.TraceID != detect.SpanContext().TraceID() || sc.SpanID != detect.SpanContext().SpanID() || !sc.Sampled {
+			t.Errorf("SpanContext() = %+v, want the recorded span's", sc)
+		}
+	})
+
+	t.Run("remote parent is joined", func(t *testing.T) {
+		tracer, recorder := newTestTracer()
+
+		remote, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		_, span := tracer.Start(tracing.ContextWithRemote(context.Background(), remote), "GET /health")
+		span.End()
+
+		got := recorder.Ended()[0]
+		if got.SpanContext().TraceID() != remote.TraceID || got.Parent().SpanID() != remote.SpanID || !got.Parent().IsRemote() {
+			t.Errorf("span trace %s parent %s, want trace %x parent %x", got.SpanContext().TraceID(), got.Parent().SpanID(), remote.TraceID, remote.SpanID)
+		}
+		if got.SpanKind() != trace.SpanKindServer {
+			t.Errorf("span kind = %v, want server", got.SpanKind())
+		}
+	})
+
+	t.Run("errors set the status", func(t *testing.T) {
+		tracer, recorder := newTestTracer()
+
+		_, span := tracer.Start(context.Background(), "humanmark.provider")
+		span.RecordError(nil)
+		span.RecordError(errors.New("provider unreachable"))
+		span.SetAttributes(tracing.String("provider", "hive"))
+		span.End()
+
+		got := recorder.Ended()[0]
+		if got.Status().Code != codes.Error || got.Status().Description != "provider unreachable" {
+			t.Errorf("status = %+v, want error", got.Status())
+		}
+		if len(got.Events()) != 1 {
+			t.Errorf("recorded %d error events, want 1", len(got.Events()))
+		}
+	})
+}
+
+// TestNewFromEnv tests that spans are discarded without an OTLP endpoint.
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	tracer, shutdown, err := NewFromEnv(context.Background())
+	if err != nil {
+		t.Fatalf("NewFromEnv() error = %v", err)
+	}
+	if tracer != tracing.Discard || shutdown != nil {
+		t.Errorf("NewFromEnv() = %v, want tracing.Discard", tracer)
+	}
+}
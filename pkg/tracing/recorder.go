@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// Recorder is a Tracer that keeps finished spans in memory, for tests and
+// for inspecting a trace without a collector.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// RecordedSpan is a finished span kept by a Recorder.
+type RecordedSpan struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanContext // Zero for the root of a trace
+	Attributes map[string]any
+	Err        error
+	Start      time.Time
+	End        time.Time
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start starts a span that is recorded when it ends.
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent := SpanFromContext(ctx).SpanContext()
+	if !parent.IsValid() {
+		parent, _ = RemoteFromContext(ctx)
+	}
+
+	s := &recorderSpan{
+		recorder: r,
+		span: RecordedSpan{
+			Name:       name,
+			Parent:     parent,
+			Attributes: make(map[string]any, len(attrs)),
+			Start:      time.Now(),
+		},
+	}
+	s.span.Context.Sampled = true
+	if parent.IsValid() {
+		s.span.Context.TraceID = parent.TraceID
+	} else {
+		rand.Read(s.span.Context.TraceID[:])
+	}
+	rand.Read(s.span.Context.SpanID[:])
+	s.SetAttributes(attrs...)
+
+	return ContextWithSpan(ctx, s), s
+}
+
+// Spans returns the spans that have ended, in the order they ended.
+func (r *Recorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan(nil), r.spans...)
+}
+
+// recorderSpan is a Recorder span that hasn't ended yet.
+type recorderSpan struct {
+	recorder *Recorder
+	mu       sync.Mutex
+	span     RecordedSpan
+	ended    bool
+}
+
+func (s *recorderSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	for _, a := range attrs {
+		s.span.Attributes[a.Key] = a.Value
+	}
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.span.Err = err
+	}
+}
+
+func (s *recorderSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.span.End = time.Now()
+	span := s.span
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	s.recorder.spans = append(s.recorder.spans, span)
+	s.recorder.mu.Unlock()
+}
+
+func (s *recorderSpan) SpanContext() SpanContext {
+	return s.span.Context
+}
@@ -0,0 +1,189 @@
+// Package tracing records spans: timed, nested operations that follow one
+// request through the middleware, the detectors and the external providers
+// they call.
+//
+// Instrumented code only sees the Tracer and Span interfaces, so spans can
+// go to OpenTelemetry (see the oteltracing module, built with -tags otel)
+// or anywhere else without the rest of the code depending on it.
+//
+// Usage:
+//
+//	ctx = tracing.WithTracer(ctx, tracer)
+//	ctx, span := tracing.Start(ctx, "humanmark.detect", tracing.String("content_type", "text"))
+//	defer span.End()
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+// Attribute is a key/value pair describing a span, e.g. the provider called.
+type Attribute struct {
+	Key   string
+	Value any // string, int, float64 or bool
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: value} }
+
+// Float64 returns a float attribute.
+func Float64(key string, value float64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// Span is one timed operation.
+type Span interface {
+	// SetAttributes adds attributes, replacing any with the same key.
+	SetAttributes(attrs ...Attribute)
+
+	// RecordError marks the span as failed with err. A nil err is ignored.
+	RecordError(err error)
+
+	// End finishes the span. Calls after the first do nothing.
+	End()
+
+	// SpanContext identifies the span, for propagating it.
+	SpanContext() SpanContext
+}
+
+// Tracer starts spans. The new span's parent is the span in ctx, or else
+// the remote span from ContextWithRemote; without either it starts a new
+// trace. The returned context carries the new span (see ContextWithSpan).
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Discard is a Tracer whose spans record nothing.
+var Discard Tracer = discard{}
+
+type discard struct{}
+
+func (discard) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, discard{}
+}
+
+func (discard) SetAttributes(...Attribute) {}
+
+func (discard) RecordError(error) {}
+
+func (discard) End() {}
+
+func (discard) SpanContext() SpanContext { return SpanContext{} }
+
+type (
+	tracerKey struct{}
+	spanKey   struct{}
+	remoteKey struct{}
+)
+
+// WithTracer returns a context whose spans Start records with t.
+func WithTracer(ctx context.Context, t Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// Start starts a span with the tracer from WithTracer, as a child of the
+// span in ctx. Without a tracer the span records nothing, so code can be
+// instrumented whether or not tracing is set up.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok {
+		return ctx, discard{}
+	}
+	return t.Start(ctx, name, attrs...)
+}
+
+// ContextWithSpan returns a context carrying span as the current span.
+// Tracer implementations call it from Start.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span, or one that records nothing.
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return discard{}
+}
+
+// ContextWithRemote returns a context whose next span continues the trace
+// of sc, a span in another service (e.g. from a traceparent header).
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// RemoteFromContext returns the span context from ContextWithRemote.
+func RemoteFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return sc, ok
+}
+
+// SpanContext identifies a span across services.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether sc has a trace and span ID; the W3C Trace
+// Context spec forbids all-zero IDs.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value
+// ("00-<trace id>-<span id>-<flags>"). Versions after 00 are read as far
+// as 00 defines, as the spec requires; ok is false if the value is
+// malformed or its IDs are all zero.
+func ParseTraceparent(value string) (sc SpanContext, ok bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, false
+	}
+	version, err := hex.DecodeString(value[:2])
+	if err != nil || version[0] == 0xff || value[:2] != strings.ToLower(value[:2]) {
+		return SpanContext{}, false
+	}
+	if version[0] == 0 && len(value) != 55 {
+		return SpanContext{}, false
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return SpanContext{}, false
+	}
+
+	// IDs must be lowercase hex
+	if strings.ToLower(value[3:55]) != value[3:55] {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(value[3:35])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(value[36:52])); err != nil {
+		return SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(value[53:55])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestParseTraceparent tests W3C traceparent parsing.
+func TestParseTraceparent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, ok := ParseTraceparent(valid)
+	if !ok {
+		t.Fatalf("ParseTraceparent(%q) failed", valid)
+	}
+	if !sc.Sampled {
+		t.Error("expected the sampled flag")
+	}
+	if got := sc.Traceparent(); got != valid {
+		t.Errorf("Traceparent() = %q, want %q", got, valid)
+	}
+
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"", false},
+		{"garbage", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01", false},
+		{"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01", false},
+	}
+	for _, tt := range tests {
+		if _, ok := ParseTraceparent(tt.value); ok != tt.ok {
+			t.Errorf("ParseTraceparent(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+		}
+	}
+}
+
+// TestRecorder tests span nesting, attributes and errors.
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := WithTracer(ContextWithRemote(context.Background(), remote), rec)
+
+	ctx, root := Start(ctx, "root", String("route", "/verify"))
+	_, child := Start(ctx, "child", Int("n", 1))
+	child.SetAttributes(Float64("score", 0.5), Int("n", 2))
+	child.RecordError(errors.New("provider down"))
+	child.End()
+	child.End()
+	root.End()
+
+	spans := rec.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	c, r := spans[0], spans[1]
+
+	if r.Parent != remote || r.Context.TraceID != remote.TraceID {
+		t.Errorf("root should continue the remote trace: %+v", r)
+	}
+	if c.Parent != r.Context || c.Context.TraceID != r.Context.TraceID {
+		t.Errorf("child's parent = %v, want %v", c.Parent, r.Context)
+	}
+	if c.Attributes["n"] != 2 || c.Attributes["score"] != 0.5 || r.Attributes["route"] != "/verify" {
+		t.Errorf("attributes: root %v, child %v", r.Attributes, c.Attributes)
+	}
+	if c.Err == nil || r.Err != nil {
+		t.Errorf("errors: root %v, child %v", r.Err, c.Err)
+	}
+}
+
+// TestStartWithoutTracer tests that spans are no-ops without a tracer.
+func TestStartWithoutTracer(t *testing.T) {
+	ctx, span := Start(context.Background(), "untraced")
+	span.SetAttributes(String("k", "v"))
+	span.RecordError(errors.New("ignored"))
+	span.End()
+
+	if span.SpanContext().IsValid() || SpanFromContext(ctx).SpanContext().IsValid() {
+		t.Error("expected an invalid span context without a tracer")
+	}
+}
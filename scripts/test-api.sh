@@ -70,7 +70,7 @@ echo -e "${YELLOW}1. Basic Endpoints${NC}"
 echo "----------------------------------------"
 
 # Health check
-test_endpoint "Health Check" "GET" "/health" "" "200" "healthy"
+test_endpoint "Liveness Check" "GET" "/health/live" "" "200" "alive"
 
 # Index
 test_endpoint "Index Page" "GET" "/" "" "200" "HumanMark"